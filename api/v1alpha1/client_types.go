@@ -28,6 +28,11 @@ import (
 type ClientSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
+
+	// The release channel of the client, its leases only acquire the exporters on the same channel
+	// +kubebuilder:default=stable
+	// +optional
+	Channel ReleaseChannel `json:"channel,omitempty"`
 }
 
 // ClientStatus defines the observed state of Identity
@@ -36,6 +41,10 @@ type ClientStatus struct {
 	// Important: Run "make" to regenerate code after modifying this file
 	Credential *corev1.LocalObjectReference `json:"credential,omitempty"`
 	Endpoint   string                       `json:"endpoint,omitempty"`
+	// The groups of the credentials the client is evaluated with, matched by the groups of the
+	// ExporterAccessPolicies: those of the call in the controller service, those frozen onto the
+	// lease in the controllers, never persisted as the client may hold several credentials
+	Groups []string `json:"-"`
 }
 
 // +kubebuilder:object:root=true
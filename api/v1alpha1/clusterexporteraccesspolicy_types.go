@@ -0,0 +1,57 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterExporterAccessPolicySpec defines the desired state of ClusterExporterAccessPolicy
+type ClusterExporterAccessPolicySpec struct {
+	// The namespaces the policies apply to, every namespace if unset
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// The baseline access to the exporters of the selected namespaces, the ExporterAccessPolicies of a
+	// namespace only further restrict it: a client may lease an exporter only if both the
+	// ClusterExporterAccessPolicies and the ExporterAccessPolicies allow it, and the leases are limited
+	// to the shortest of their MaximumDuration
+	ExporterAccessPolicySpec `json:",inline"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+
+// ClusterExporterAccessPolicy is the Schema for the clusterexporteraccesspolicies API
+type ClusterExporterAccessPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ClusterExporterAccessPolicySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterExporterAccessPolicyList contains a list of ClusterExporterAccessPolicy
+type ClusterExporterAccessPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterExporterAccessPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterExporterAccessPolicy{}, &ClusterExporterAccessPolicyList{})
+}
@@ -28,24 +28,155 @@ import (
 type ExporterSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
+
+	// Periods during which the exporter is reserved exclusively to a client or a group of clients
+	// +optional
+	Reservations []ExporterReservation `json:"reservations,omitempty"`
+	// Cordons the exporter: new leases do not acquire it, the active ones run to their end
+	// +optional
+	Unschedulable bool `json:"unschedulable,omitempty"`
+	// Drains the exporter: new leases do not acquire it, and once its active lease ended it is
+	// disconnected and kept offline, until the drain is removed
+	// +optional
+	Drain bool `json:"drain,omitempty"`
+	// The release channel of the exporter, only the leases of clients on the same channel acquire it
+	// +kubebuilder:default=stable
+	// +optional
+	Channel ReleaseChannel `json:"channel,omitempty"`
+	// The number of leases holding the exporter at the same time, for devices serving multiple
+	// clients concurrently, e.g. a network switch or a shared instrument, defaults to 1, and only
+	// applies once the exporter reports it serves multiple leases
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxConcurrentLeases *int32 `json:"maxConcurrentLeases,omitempty"`
+}
+
+// LeaseCapacity returns the number of leases holding exporter at the same time, 1 unless the exporter
+// serves multiple leases
+func (e *Exporter) LeaseCapacity() int {
+	if !e.Status.MultipleLeases || e.Spec.MaxConcurrentLeases == nil || *e.Spec.MaxConcurrentLeases < 1 {
+		return 1
+	}
+	return int(*e.Spec.MaxConcurrentLeases)
+}
+
+// ReleaseChannel splits exporters and clients for staged rollouts, e.g. of exporter software,
+// the leases of clients on a channel only acquire the exporters on the same channel
+// +kubebuilder:validation:Enum=stable;canary
+type ReleaseChannel string
+
+const (
+	ReleaseChannelStable ReleaseChannel = "stable"
+	ReleaseChannelCanary ReleaseChannel = "canary"
+)
+
+// LabelReleaseChannel mirrors the release channel of exporters and clients,
+// so that lease selectors and ExporterAccessPolicies can select them by channel
+const LabelReleaseChannel = "release.jumpstarter.dev/channel"
+
+// OrDefault returns channel, or ReleaseChannelStable if it is empty
+func (channel ReleaseChannel) OrDefault() ReleaseChannel {
+	if channel == "" {
+		return ReleaseChannelStable
+	}
+	return channel
+}
+
+// ExporterReservation pins an exporter to a client or a group of clients for a period of time,
+// other clients cannot lease the exporter while it is reserved, and the holders bypass the
+// ExporterAccessPolicies selecting it
+// +kubebuilder:validation:XValidation:rule="has(self.clientRef) || has(self.clientSelector)",message="either clientRef or clientSelector is required"
+// +kubebuilder:validation:XValidation:rule="self.end > self.begin",message="end must be after begin"
+type ExporterReservation struct {
+	// The client the exporter is reserved to
+	// +optional
+	ClientRef *corev1.LocalObjectReference `json:"clientRef,omitempty"`
+	// The clients the exporter is reserved to, matched by label
+	// +optional
+	ClientSelector *metav1.LabelSelector `json:"clientSelector,omitempty"`
+	// Start of the reservation
+	Begin metav1.Time `json:"begin"`
+	// End of the reservation
+	End metav1.Time `json:"end"`
 }
 
 // ExporterStatus defines the observed state of Exporter
 type ExporterStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
+	// +listType=map
+	// +listMapKey=type
 	Conditions []metav1.Condition           `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
 	Credential *corev1.LocalObjectReference `json:"credential,omitempty"`
 	Devices    []Device                     `json:"devices,omitempty"`
 	LeaseRef   *corev1.LocalObjectReference `json:"leaseRef,omitempty"`
 	Endpoint   string                       `json:"endpoint,omitempty"`
+	// The active leases holding the exporter, LeaseRef is the first of them
+	LeaseRefs []corev1.LocalObjectReference `json:"leaseRefs,omitempty"`
+	// Bounded history of the label sets applied to the exporter, oldest first
+	LabelHistory []LabelSetRevision `json:"labelHistory,omitempty"`
+	// The last error of the exporter registering or reporting its status, cleared once it succeeds
+	LastError *ExporterError `json:"lastError,omitempty"`
+	// Whether the exporter serves several leases at the same time, as reported by its last Status call,
+	// exporters that do not are only assigned one lease whatever their MaxConcurrentLeases
+	MultipleLeases bool `json:"multipleLeases,omitempty"`
+}
+
+// ExporterError records the consecutive failures of an exporter calling the controller
+type ExporterError struct {
+	// The call that failed, Register or Status
+	Operation string `json:"operation"`
+	// The error of the last failure
+	Message string `json:"message"`
+	// The number of consecutive failures
+	Count int32 `json:"count"`
+	// When the last failure happened
+	Time metav1.Time `json:"time"`
+}
+
+// LabelSetRevision records a set of jumpstarter.dev/ labels applied to an exporter
+type LabelSetRevision struct {
+	// Monotonically increasing revision number
+	Revision int64 `json:"revision"`
+	// The jumpstarter.dev/ labels applied in this revision
+	Labels map[string]string `json:"labels,omitempty"`
+	// When the labels were applied
+	Time metav1.Time `json:"time"`
+	// What applied the labels, e.g. Register or Restore
+	Source string `json:"source"`
 }
 
+// ExporterLabelGroup links the exporters sharing its value, e.g. a board and its power controller,
+// they are always leased and released together
+const ExporterLabelGroup = "jumpstarter.dev/group"
+
+const (
+	// ExporterAnnotationNotes holds free-form notes about an exporter, e.g. known issues
+	ExporterAnnotationNotes = "jumpstarter.dev/notes"
+	// ExporterAnnotationLocation is the physical location of the hardware of an exporter
+	ExporterAnnotationLocation = "jumpstarter.dev/location"
+	// ExporterAnnotationClaimedBy is the lease the lease controller last assigned the exporter to,
+	// written conditionally on the resource version of the exporter before the lease references it
+	ExporterAnnotationClaimedBy = "jumpstarter.dev/claimed-by"
+	// ExporterAnnotationClaimedAt is when the exporter was claimed, in RFC 3339
+	ExporterAnnotationClaimedAt = "jumpstarter.dev/claimed-at"
+	// ExporterAnnotationPinnedLabelRevision is the label history revision the labels of an exporter
+	// were restored to, Register keeps them instead of applying the reported labels until it is removed
+	ExporterAnnotationPinnedLabelRevision = "jumpstarter.dev/pinned-label-revision"
+)
+
 type ExporterConditionType string
 
 const (
 	ExporterConditionTypeRegistered LeaseConditionType = "Registered"
 	ExporterConditionTypeOnline     LeaseConditionType = "Online"
+	// A MaintenanceWindow selecting the exporter is open
+	ExporterConditionTypeUnderMaintenance LeaseConditionType = "UnderMaintenance"
+	// The devices reported by the last registration exceeded the limits of the controller
+	// and were truncated
+	ExporterConditionTypeDevicesTruncated LeaseConditionType = "DevicesTruncated"
+	// The exporter is drained, False while its active lease has not ended yet
+	ExporterConditionTypeDrained LeaseConditionType = "Drained"
 )
 
 // +kubebuilder:object:root=true
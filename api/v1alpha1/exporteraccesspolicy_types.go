@@ -0,0 +1,143 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// From selects the clients a Policy applies to
+type From struct {
+	// The clients matching the selector
+	ClientSelector metav1.LabelSelector `json:"clientSelector,omitempty"`
+	// The clients whose credentials have any of the groups, the organizations of a client certificate
+	// or the groups claim of a token, in addition to matching the selector, every client if empty
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+}
+
+// StreamLimits are enforced by the router on the streams of a lease
+type StreamLimits struct {
+	// The maximum number of concurrent streams per lease
+	// +kubebuilder:validation:Minimum=1
+	MaxConcurrentDials int32 `json:"maxConcurrentDials,omitempty"`
+	// The maximum bandwidth of a stream per direction, in bytes per second
+	MaxBandwidth *resource.Quantity `json:"maxBandwidth,omitempty"`
+	// The maximum duration of a stream
+	MaxSessionDuration *metav1.Duration `json:"maxSessionDuration,omitempty"`
+}
+
+// PolicyEffect is whether a Policy grants or denies the selected clients access to the exporters
+// +kubebuilder:validation:Enum=Allow;Deny
+type PolicyEffect string
+
+const (
+	// PolicyEffectAllow grants access unless a deny rule matches the client
+	PolicyEffectAllow PolicyEffect = "Allow"
+	// PolicyEffectDeny denies access, whatever the priority of the policies granting it
+	PolicyEffectDeny PolicyEffect = "Deny"
+)
+
+// Policy grants the selected clients access to the exporters of an ExporterAccessPolicy
+type Policy struct {
+	// When multiple policies match a client, the one with the highest priority applies, the
+	// tie-break of the controller, MostRestrictive by default, chooses among equal priorities
+	Priority int `json:"priority,omitempty"`
+	// The clients the policy applies to
+	From []From `json:"from,omitempty"`
+	// Whether the policy grants or denies the clients access, the deny rules matching a client take
+	// precedence over every policy granting it access, and only their From clauses apply
+	// +kubebuilder:default=Allow
+	// +optional
+	Effect PolicyEffect `json:"effect,omitempty"`
+	// Limits enforced on the streams of the leases granted by the policy
+	StreamLimits *StreamLimits `json:"streamLimits,omitempty"`
+	// The maximum duration of the leases granted by the policy, including extensions
+	// +optional
+	MaximumDuration *metav1.Duration `json:"maximumDuration,omitempty"`
+	// The maximum time the leases granted by the policy may be paused in total, the countdown
+	// of a lease resumes once it is reached, unlimited if unset
+	// +optional
+	MaximumPauseDuration *metav1.Duration `json:"maximumPauseDuration,omitempty"`
+	// The maximum number of leases the policy grants at the same time, unlimited if unset
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxConcurrentLeases *int32 `json:"maxConcurrentLeases,omitempty"`
+	// The maximum number of leases the policy grants each client at the same time, so that a
+	// single client cannot hold every exporter of a shared pool, unlimited if unset
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxConcurrentLeasesPerClient *int32 `json:"maxConcurrentLeasesPerClient,omitempty"`
+}
+
+// ExporterAccessPolicySpec defines the desired state of ExporterAccessPolicy
+type ExporterAccessPolicySpec struct {
+	// The exporters the policies apply to, exporters not selected by any
+	// ExporterAccessPolicy can be leased by every client, unless an
+	// ExporterAccessPolicy of the namespace sets DefaultDeny
+	ExporterSelector metav1.LabelSelector `json:"exporterSelector"`
+	// The policies granting access to the selected exporters
+	Policies []Policy `json:"policies,omitempty"`
+	// Restricts every exporter of the namespace, the exporters not selected by any
+	// ExporterAccessPolicy cannot be leased by any client
+	// +optional
+	DefaultDeny bool `json:"defaultDeny,omitempty"`
+}
+
+// ExporterAccessPolicyStatus defines the observed state of ExporterAccessPolicy
+type ExporterAccessPolicyStatus struct {
+	// Number of exporters matching the exporter selector
+	MatchedExporters int32 `json:"matchedExporters"`
+	// The observed state of each of the policies, in the order of the spec
+	Policies []PolicyStatus `json:"policies,omitempty"`
+}
+
+// PolicyStatus defines the observed state of a Policy
+type PolicyStatus struct {
+	// Number of clients matching the client selectors of the policy, whatever the groups of their
+	// credentials
+	MatchedClients int32 `json:"matchedClients"`
+	// Number of active leases granted access to their exporter by the policy
+	ActiveLeases int32 `json:"activeLeases"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Exporters",type=integer,JSONPath=`.status.matchedExporters`
+
+// ExporterAccessPolicy is the Schema for the exporteraccesspolicies API
+type ExporterAccessPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ExporterAccessPolicySpec   `json:"spec,omitempty"`
+	Status ExporterAccessPolicyStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ExporterAccessPolicyList contains a list of ExporterAccessPolicy
+type ExporterAccessPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ExporterAccessPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ExporterAccessPolicy{}, &ExporterAccessPolicyList{})
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ExporterUpdatePolicySpec defines the desired state of ExporterUpdatePolicy
+type ExporterUpdatePolicySpec struct {
+	// The exporter software version to roll out, compared against the
+	// jumpstarter.dev/version label reported by the exporters
+	TargetVersion string `json:"targetVersion"`
+	// The exporters the policy applies to
+	Selector metav1.LabelSelector `json:"selector"`
+	// The maximum number of exporters updating at the same time
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=1
+	MaxUnavailable int32 `json:"maxUnavailable,omitempty"`
+	// The rollout halts once more than this percentage of the attempted updates failed
+	// +kubebuilder:default=20
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	MaxFailurePercentage int32 `json:"maxFailurePercentage,omitempty"`
+	// How long an exporter may take to report the target version before the update is considered failed
+	// +kubebuilder:default="10m"
+	UpdateTimeout metav1.Duration `json:"updateTimeout,omitempty"`
+}
+
+// ExporterUpdatePolicyStatus defines the observed state of ExporterUpdatePolicy
+type ExporterUpdatePolicyStatus struct {
+	// Number of exporters matching the selector
+	Total int32 `json:"total"`
+	// Number of exporters running the target version
+	Updated int32 `json:"updated"`
+	// Number of exporters instructed to update and not yet done
+	Updating int32 `json:"updating"`
+	// Number of exporters that did not report the target version within the update timeout
+	Failed     int32              `json:"failed"`
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+const (
+	// ExporterLabelVersion is the label exporters report their software version in
+	ExporterLabelVersion = "jumpstarter.dev/version"
+	// ExporterAnnotationUpdateVersion is the version an exporter has been instructed to update to
+	ExporterAnnotationUpdateVersion = "jumpstarter.dev/update-version"
+	// ExporterAnnotationUpdateDeadline is the RFC3339 time after which the update of an exporter is failed
+	ExporterAnnotationUpdateDeadline = "jumpstarter.dev/update-deadline"
+)
+
+type ExporterUpdatePolicyConditionType string
+
+const (
+	ExporterUpdatePolicyConditionTypeComplete ExporterUpdatePolicyConditionType = "Complete"
+	ExporterUpdatePolicyConditionTypeHalted   ExporterUpdatePolicyConditionType = "Halted"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Target",type=string,JSONPath=`.spec.targetVersion`
+// +kubebuilder:printcolumn:name="Updated",type=integer,JSONPath=`.status.updated`
+// +kubebuilder:printcolumn:name="Total",type=integer,JSONPath=`.status.total`
+// +kubebuilder:printcolumn:name="Failed",type=integer,JSONPath=`.status.failed`
+
+// ExporterUpdatePolicy is the Schema for the exporterupdatepolicies API
+type ExporterUpdatePolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ExporterUpdatePolicySpec   `json:"spec,omitempty"`
+	Status ExporterUpdatePolicyStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ExporterUpdatePolicyList contains a list of ExporterUpdatePolicy
+type ExporterUpdatePolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ExporterUpdatePolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ExporterUpdatePolicy{}, &ExporterUpdatePolicyList{})
+}
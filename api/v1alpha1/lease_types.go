@@ -25,16 +25,125 @@ import (
 type LeaseSpec struct {
 	// The client that is requesting the lease
 	ClientRef corev1.LocalObjectReference `json:"clientRef"`
+	// The groups of the credentials the client requested the lease with, frozen for the access
+	// policies to be evaluated against for the lifetime of the lease
+	// +optional
+	ClientGroups []string `json:"clientGroups,omitempty"`
 	// The desired duration of the lease
 	Duration metav1.Duration `json:"duration"`
 	// The selector for the exporter to be used
 	Selector metav1.LabelSelector `json:"selector"`
 	// The release flag requests the controller to end the lease now
 	Release bool `json:"release,omitempty"`
+	// Clients allowed to observe the streams of the lease in receive-only mode
+	Observers []corev1.LocalObjectReference `json:"observers,omitempty"`
+	// Share the lease read-only: every client allowed to lease its exporter may observe its
+	// streams, in addition to the Observers, without owning the lease
+	// +optional
+	Shared bool `json:"shared,omitempty"`
+	// The concurrency group of the lease, only one lease of a group holds exporters at a time,
+	// e.g. for the tests of boards sharing a single RF chamber
+	// +optional
+	ConcurrencyGroup string `json:"concurrencyGroup,omitempty"`
+	// Pause the countdown of the duration of the running lease, e.g. while waiting for a human
+	// debugging session, the lease keeps its exporters while paused
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+	// Record the router streams of the lease, if the router has recording enabled
+	Record bool `json:"record,omitempty"`
+	// How long the lease may wait for an exporter, after which it fails with the Timeout reason
+	// +optional
+	AcquireTimeout *metav1.Duration `json:"acquireTimeout,omitempty"`
+	// Keep the lease pending when no exporter could satisfy it, instead of marking it Unsatisfiable,
+	// it is retried with a backoff and when exporters are created or change
+	// +optional
+	WaitForExporter bool `json:"waitForExporter,omitempty"`
+	// When the lease should begin, if in the future the lease is a reservation: an exporter is
+	// reserved for the window starting at BeginTime and lasting Duration, and acquired at BeginTime
+	// +optional
+	BeginTime *metav1.Time `json:"beginTime,omitempty"`
+	// The LeasePriorityClass of the lease, the default class of the namespace if empty
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+	// The affinity of the lease to exporters, narrowing or ranking the exporters matching the selector
+	// +optional
+	Affinity *LeaseAffinity `json:"affinity,omitempty"`
+	// The LeaseTemplate the unset fields of the lease are defaulted from when it is created
+	// +optional
+	TemplateName string `json:"templateName,omitempty"`
+	// Additional exporters acquired together with the exporter of the lease, by role, e.g. a traffic
+	// generator for the device under test, the lease acquires either all of them or none
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Roles []LeaseRole `json:"roles,omitempty"`
+}
+
+// LeaseRole requests exporters for a role of the lease
+type LeaseRole struct {
+	// The name of the role, unique in the lease
+	Name string `json:"name"`
+	// The selector for the exporters of the role
+	Selector metav1.LabelSelector `json:"selector"`
+	// The number of exporters of the role
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Count int32 `json:"count,omitempty"`
+}
+
+// LeaseRoleExporterRef is an exporter acquired for a role of the lease
+type LeaseRoleExporterRef struct {
+	// The name of the role
+	Role string `json:"role"`
+	// The name of the exporter
+	Name string `json:"name"`
+}
+
+// LeaseAffinity attracts the lease to some exporters and keeps it away from others, similar to pod affinity
+type LeaseAffinity struct {
+	// The exporters the lease must or should be assigned to, e.g. the exporter of a previous lease
+	// +optional
+	ExporterAffinity *ExporterAffinity `json:"exporterAffinity,omitempty"`
+	// The exporters the lease must not or should not be assigned to, e.g. a known flaky exporter
+	// +optional
+	ExporterAntiAffinity *ExporterAffinity `json:"exporterAntiAffinity,omitempty"`
+}
+
+// ExporterAffinity is a set of required and preferred exporter affinity terms
+type ExporterAffinity struct {
+	// Terms that must all be satisfied, the lease waits or fails rather than violating them
+	// +optional
+	Required []ExporterAffinityTerm `json:"required,omitempty"`
+	// Terms that rank the exporters, the exporter with the greatest sum of weights of matching
+	// affinity terms, minus the weights of matching anti-affinity terms, is preferred
+	// +optional
+	Preferred []WeightedExporterAffinityTerm `json:"preferred,omitempty"`
+}
+
+// ExporterAffinityTerm matches the exporters satisfying all of its fields
+type ExporterAffinityTerm struct {
+	// The names of the exporters matched
+	// +optional
+	ExporterNames []string `json:"exporterNames,omitempty"`
+	// The labels of the exporters matched
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// WeightedExporterAffinityTerm is a preferred ExporterAffinityTerm with its weight
+type WeightedExporterAffinityTerm struct {
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	Weight int32                `json:"weight"`
+	Term   ExporterAffinityTerm `json:"term"`
 }
 
 // LeaseStatus defines the observed state of Lease
 type LeaseStatus struct {
+	// The phase of the lease in its lifecycle, written by the lease controller
+	// +optional
+	Phase LeasePhase `json:"phase,omitempty"`
 	// If the lease has been acquired an exporter name is assigned
 	// and then and then it can be used, it will be empty while still pending
 	BeginTime   *metav1.Time                 `json:"beginTime,omitempty"`
@@ -42,16 +151,101 @@ type LeaseStatus struct {
 	ExporterRef *corev1.LocalObjectReference `json:"exporterRef,omitempty"`
 	Ended       bool                         `json:"ended"`
 	Conditions  []metav1.Condition           `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+	// Ephemeral key-values exchanged by the client and the exporter while the lease is active,
+	// cleared when the lease ends
+	// +kubebuilder:validation:MaxProperties=32
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Exporters linked to the exporter by the jumpstarter.dev/group label, leased together with it
+	LinkedExporterRefs []corev1.LocalObjectReference `json:"linkedExporterRefs,omitempty"`
+	// The exporters acquired for the roles of the lease, together with the exporter
+	RoleExporterRefs []LeaseRoleExporterRef `json:"roleExporterRefs,omitempty"`
+	// The exporter reserved for a lease beginning in the future, acquired at its begin time
+	ReservedExporterRef *corev1.LocalObjectReference `json:"reservedExporterRef,omitempty"`
+	// The position of a pending lease in the queue for its matching exporters, starting at 1
+	// +optional
+	QueuePosition *int32 `json:"queuePosition,omitempty"`
+	// When a pending lease is estimated to acquire an exporter, from the end times of the
+	// leases holding the matching exporters, unset if it cannot be estimated
+	// +optional
+	EstimatedBeginTime *metav1.Time `json:"estimatedBeginTime,omitempty"`
+	// The rationale of the last allocation of an exporter to the lease
+	// +optional
+	Decision *LeaseDecision `json:"decision,omitempty"`
+	// When the current pause of the lease began, unset while its countdown runs
+	// +optional
+	PauseTime *metav1.Time `json:"pauseTime,omitempty"`
+	// How long the lease was paused before its current pause, its end is delayed by as much
+	// +optional
+	PausedDuration *metav1.Duration `json:"pausedDuration,omitempty"`
+	// When the client first dialed an exporter of the lease, unset until it does
+	// +optional
+	FirstDialTime *metav1.Time `json:"firstDialTime,omitempty"`
 }
 
+// LeaseDecision is a compact trace of an allocation of an exporter to a lease, to tell
+// why the lease got its exporter, or why it did not get any
+type LeaseDecision struct {
+	// The allocator that made the decision
+	Allocator string `json:"allocator,omitempty"`
+	// The number of exporters matching the selector of the lease considered
+	Candidates int32 `json:"candidates"`
+	// The number of exporters filtered out by each filter plugin
+	// +kubebuilder:validation:MaxProperties=32
+	// +optional
+	Filtered map[string]int32 `json:"filtered,omitempty"`
+	// The exporter selected, empty if none passed all the filters
+	// +optional
+	Exporter string `json:"exporter,omitempty"`
+	// The weighted score of the selected exporter by each score plugin
+	// +kubebuilder:validation:MaxProperties=32
+	// +optional
+	Scores map[string]int64 `json:"scores,omitempty"`
+}
+
+// LeasePhase is a state of the lifecycle of a lease
+// +kubebuilder:validation:Enum=Pending;Scheduled;Active;Ending;Ended;Failed;Preempted
+type LeasePhase string
+
+const (
+	// The lease is waiting for an exporter
+	LeasePhasePending LeasePhase = "Pending"
+	// The lease begins in the future, and may have reserved an exporter
+	LeasePhaseScheduled LeasePhase = "Scheduled"
+	// The lease holds an exporter
+	LeasePhaseActive LeasePhase = "Active"
+	// The lease is released or expired, but has not ended yet
+	LeasePhaseEnding LeasePhase = "Ending"
+	// The lease has been released or has expired
+	LeasePhaseEnded LeasePhase = "Ended"
+	// The lease ended without being used, e.g. it timed out waiting for an exporter
+	LeasePhaseFailed LeasePhase = "Failed"
+	// The lease ended because a lease with a higher priority took its exporter
+	LeasePhasePreempted LeasePhase = "Preempted"
+)
+
 type LeaseConditionType string
 
 const (
 	LeaseConditionTypePending       LeaseConditionType = "Pending"
 	LeaseConditionTypeReady         LeaseConditionType = "Ready"
 	LeaseConditionTypeUnsatisfiable LeaseConditionType = "Unsatisfiable"
+	LeaseConditionTypeFailed        LeaseConditionType = "Failed"
+	// The lease begins in the future and no matching exporter is free for its whole window
+	LeaseConditionTypeConflicted LeaseConditionType = "Conflicted"
+	// Acquiring an exporter would exceed a LeaseQuota of the client
+	LeaseConditionTypeQuotaExceeded LeaseConditionType = "QuotaExceeded"
+	// The lease is about to expire or be preempted, its holders should wind down their workloads
+	LeaseConditionTypeEndingSoon LeaseConditionType = "EndingSoon"
+	// The countdown of the duration of the lease is paused
+	LeaseConditionTypePaused LeaseConditionType = "Paused"
 )
 
+// LeaseAnnotationPreemptedBy names the lease that preempted an ended lease
+const LeaseAnnotationPreemptedBy = "jumpstarter.dev/preempted-by"
+
+// LeaseAnnotationPreemptAt is when a preempted lease ends, at the end of its preemption grace period
+const LeaseAnnotationPreemptAt = "jumpstarter.dev/preempt-at"
+
 type LeaseLabel string
 
 const (
@@ -61,6 +255,7 @@ const (
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:JSONPath=".status.phase",name=Phase,type=string
 // +kubebuilder:printcolumn:JSONPath=".status.ended",name=Ended,type=boolean
 // +kubebuilder:printcolumn:JSONPath=".spec.clientRef.name",name=Client,type=string
 // +kubebuilder:printcolumn:JSONPath=".status.exporterRef.name",name=Exporter,type=string
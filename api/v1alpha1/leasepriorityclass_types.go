@@ -0,0 +1,75 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LeasePreemptionPolicy is whether the leases of a LeasePriorityClass preempt lower priority leases
+// +kubebuilder:validation:Enum=PreemptLowerPriority;Never
+type LeasePreemptionPolicy string
+
+const (
+	// LeasePreemptLowerPriority ends the running leases of lower priority holding the exporters a lease waits for
+	LeasePreemptLowerPriority LeasePreemptionPolicy = "PreemptLowerPriority"
+	// LeasePreemptNever only orders the queue by priority
+	LeasePreemptNever LeasePreemptionPolicy = "Never"
+)
+
+// LeasePriorityClassSpec defines the desired state of LeasePriorityClass
+type LeasePriorityClassSpec struct {
+	// The priority of the leases of the class, waiting leases of higher priority acquire exporters first,
+	// it takes precedence over the priority of the ExporterAccessPolicies
+	Value int32 `json:"value"`
+	// The class of the leases not naming one, at most one class of a namespace should set it
+	// +optional
+	GlobalDefault bool `json:"globalDefault,omitempty"`
+	// Whether waiting leases of the class preempt running leases of lower priority,
+	// only applied when the Preemption feature gate is enabled
+	// +kubebuilder:default=Never
+	PreemptionPolicy LeasePreemptionPolicy `json:"preemptionPolicy,omitempty"`
+	// What the class is meant for
+	// +optional
+	Description string `json:"description,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Value",type=integer,JSONPath=`.spec.value`
+// +kubebuilder:printcolumn:name="Default",type=boolean,JSONPath=`.spec.globalDefault`
+// +kubebuilder:printcolumn:name="Preemption",type=string,JSONPath=`.spec.preemptionPolicy`
+
+// LeasePriorityClass is the Schema for the leasepriorityclasses API
+type LeasePriorityClass struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec LeasePriorityClassSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// LeasePriorityClassList contains a list of LeasePriorityClass
+type LeasePriorityClassList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []LeasePriorityClass `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&LeasePriorityClass{}, &LeasePriorityClassList{})
+}
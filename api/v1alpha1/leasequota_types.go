@@ -0,0 +1,79 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LeaseQuotaScope is whether the limits of a LeaseQuota apply to each selected client or to all of them
+// +kubebuilder:validation:Enum=Client;Group
+type LeaseQuotaScope string
+
+const (
+	// LeaseQuotaScopeClient applies the limits to each selected client on its own
+	LeaseQuotaScopeClient LeaseQuotaScope = "Client"
+	// LeaseQuotaScopeGroup applies the limits to the leases of all selected clients together
+	LeaseQuotaScopeGroup LeaseQuotaScope = "Group"
+)
+
+// LeaseQuotaSpec defines the desired state of LeaseQuota
+type LeaseQuotaSpec struct {
+	// The clients the quota applies to, an empty selector selects every client in the namespace
+	ClientSelector metav1.LabelSelector `json:"clientSelector,omitempty"`
+	// Whether the limits apply to each selected client or to the selected clients as a group
+	// +kubebuilder:default=Client
+	Scope LeaseQuotaScope `json:"scope,omitempty"`
+	// The maximum number of leases holding an exporter at the same time
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxConcurrentLeases *int32 `json:"maxConcurrentLeases,omitempty"`
+	// The maximum total duration of the leases within Period, counting the requested
+	// duration of running leases and the time ended leases held their exporter
+	// +optional
+	MaxLeasedDuration *metav1.Duration `json:"maxLeasedDuration,omitempty"`
+	// The sliding window MaxLeasedDuration is accounted over
+	// +kubebuilder:default="24h"
+	Period metav1.Duration `json:"period,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Scope",type=string,JSONPath=`.spec.scope`
+// +kubebuilder:printcolumn:name="Concurrent",type=integer,JSONPath=`.spec.maxConcurrentLeases`
+// +kubebuilder:printcolumn:name="Duration",type=string,JSONPath=`.spec.maxLeasedDuration`
+// +kubebuilder:printcolumn:name="Period",type=string,JSONPath=`.spec.period`
+
+// LeaseQuota is the Schema for the leasequotas API
+type LeaseQuota struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec LeaseQuotaSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// LeaseQuotaList contains a list of LeaseQuota
+type LeaseQuotaList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []LeaseQuota `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&LeaseQuota{}, &LeaseQuotaList{})
+}
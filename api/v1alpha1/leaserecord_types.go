@@ -0,0 +1,92 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// LeaseRecordSpec is the usage of an ended lease, written once by the lease controller
+type LeaseRecordSpec struct {
+	// The name of the lease, the record is named after it and the beginning of its UID,
+	// so that leases reusing the name of a deleted lease get their own record
+	LeaseName string `json:"leaseName"`
+	// The UID of the lease
+	LeaseUID types.UID `json:"leaseUID"`
+	// The client that requested the lease
+	ClientRef corev1.LocalObjectReference `json:"clientRef"`
+	// The exporter acquired by the lease, unset if it never acquired one
+	// +optional
+	ExporterRef *corev1.LocalObjectReference `json:"exporterRef,omitempty"`
+	// All the exporters held by the lease: its exporter, the exporters linked to it and the ones of its roles
+	// +optional
+	ExporterRefs []corev1.LocalObjectReference `json:"exporterRefs,omitempty"`
+	// The selector of the lease
+	Selector metav1.LabelSelector `json:"selector"`
+	// The LeasePriorityClass of the lease
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+	// The duration requested by the lease
+	RequestedDuration metav1.Duration `json:"requestedDuration"`
+	// When the lease asked to begin, its creation unless it was a reservation
+	RequestTime metav1.Time `json:"requestTime"`
+	// When the lease acquired its exporter, unset if it never did
+	// +optional
+	BeginTime *metav1.Time `json:"beginTime,omitempty"`
+	// When the lease ended
+	EndTime metav1.Time `json:"endTime"`
+	// How long the lease waited for its exporter, until it acquired it or ended
+	WaitTime metav1.Duration `json:"waitTime"`
+	// How long the lease held its exporter
+	Duration metav1.Duration `json:"duration"`
+	// Why the lease ended, e.g. Expired, Released, Preempted or Timeout
+	EndReason string `json:"endReason"`
+	// The final phase of the lease
+	Phase LeasePhase `json:"phase"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Client",type=string,JSONPath=`.spec.clientRef.name`
+// +kubebuilder:printcolumn:name="Exporter",type=string,JSONPath=`.spec.exporterRef.name`
+// +kubebuilder:printcolumn:name="Duration",type=string,JSONPath=`.spec.duration`
+// +kubebuilder:printcolumn:name="Wait",type=string,JSONPath=`.spec.waitTime`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.spec.endReason`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// LeaseRecord is the Schema for the leaserecords API, the history of an ended lease kept
+// for usage analysis after the lease itself is deleted
+type LeaseRecord struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec LeaseRecordSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// LeaseRecordList contains a list of LeaseRecord
+type LeaseRecordList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []LeaseRecord `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&LeaseRecord{}, &LeaseRecordList{})
+}
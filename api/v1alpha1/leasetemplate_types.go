@@ -0,0 +1,85 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LeaseTemplateSpec defines the desired state of LeaseTemplate,
+// the defaults of the leases naming the template, fields set on a lease take precedence
+type LeaseTemplateSpec struct {
+	// The selector of the leases with an empty selector
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+	// The duration of the leases without a duration
+	// +optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+	// The acquire timeout of the leases without one
+	// +optional
+	AcquireTimeout *metav1.Duration `json:"acquireTimeout,omitempty"`
+	// The LeasePriorityClass of the leases not naming one
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+	// Record the router streams of the leases
+	// +optional
+	Record bool `json:"record,omitempty"`
+	// Keep the leases pending when no exporter could satisfy them
+	// +optional
+	WaitForExporter bool `json:"waitForExporter,omitempty"`
+	// The observers of the leases without observers
+	// +optional
+	Observers []corev1.LocalObjectReference `json:"observers,omitempty"`
+	// Share the leases read-only with the clients allowed to lease their exporters
+	// +optional
+	Shared bool `json:"shared,omitempty"`
+	// The concurrency group of the leases without one
+	// +optional
+	ConcurrencyGroup string `json:"concurrencyGroup,omitempty"`
+	// The affinity of the leases without affinity
+	// +optional
+	Affinity *LeaseAffinity `json:"affinity,omitempty"`
+	// What the template is meant for
+	// +optional
+	Description string `json:"description,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Duration",type=string,JSONPath=`.spec.duration`
+// +kubebuilder:printcolumn:name="Priority Class",type=string,JSONPath=`.spec.priorityClassName`
+
+// LeaseTemplate is the Schema for the leasetemplates API
+type LeaseTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec LeaseTemplateSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// LeaseTemplateList contains a list of LeaseTemplate
+type LeaseTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []LeaseTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&LeaseTemplate{}, &LeaseTemplateList{})
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MaintenanceWindowSpec defines the desired state of MaintenanceWindow
+// +kubebuilder:validation:XValidation:rule="self.end > self.begin",message="end must be after begin"
+type MaintenanceWindowSpec struct {
+	// The exporters under maintenance
+	ExporterSelector metav1.LabelSelector `json:"exporterSelector"`
+	// Start of the maintenance
+	Begin metav1.Time `json:"begin"`
+	// End of the maintenance
+	End metav1.Time `json:"end"`
+	// Why the exporters are under maintenance, reported on their UnderMaintenance condition
+	// +optional
+	Reason string `json:"reason,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Begin",type=date,JSONPath=`.spec.begin`
+// +kubebuilder:printcolumn:name="End",type=date,JSONPath=`.spec.end`
+
+// MaintenanceWindow is the Schema for the maintenancewindows API, no new lease is assigned
+// the selected exporters while the window is open
+type MaintenanceWindow struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec MaintenanceWindowSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// MaintenanceWindowList contains a list of MaintenanceWindow
+type MaintenanceWindowList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MaintenanceWindow `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MaintenanceWindow{}, &MaintenanceWindowList{})
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RecurringLeaseConcurrencyPolicy is what a RecurringLease does when a lease is due while
+// the previous one has not ended
+// +kubebuilder:validation:Enum=Allow;Forbid;Replace
+type RecurringLeaseConcurrencyPolicy string
+
+const (
+	// RecurringLeaseAllowConcurrent creates the lease anyway
+	RecurringLeaseAllowConcurrent RecurringLeaseConcurrencyPolicy = "Allow"
+	// RecurringLeaseForbidConcurrent waits for the previous lease to end
+	RecurringLeaseForbidConcurrent RecurringLeaseConcurrencyPolicy = "Forbid"
+	// RecurringLeaseReplaceConcurrent releases the previous lease and creates the new one
+	RecurringLeaseReplaceConcurrent RecurringLeaseConcurrencyPolicy = "Replace"
+)
+
+// RecurringLeaseSpec defines the desired state of RecurringLease
+type RecurringLeaseSpec struct {
+	// The schedule of the leases in the cron format, in UTC, e.g. "0 2 * * 1-5" for 2:00 on weekdays
+	Schedule string `json:"schedule"`
+	// The spec of the leases created on the schedule
+	Template LeaseSpec `json:"template"`
+	// What to do when a lease is due while the previous one has not ended
+	// +kubebuilder:default=Forbid
+	// +optional
+	ConcurrencyPolicy RecurringLeaseConcurrencyPolicy `json:"concurrencyPolicy,omitempty"`
+	// Suspend the creation of leases, the leases already created are not affected
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+	// How late a lease may still be created after its scheduled time, the missed leases
+	// are skipped past it, unlimited if unset
+	// +optional
+	StartingDeadline *metav1.Duration `json:"startingDeadline,omitempty"`
+	// The number of ended leases to keep
+	// +kubebuilder:default=3
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	SuccessfulLeasesHistoryLimit *int32 `json:"successfulLeasesHistoryLimit,omitempty"`
+	// The number of failed leases to keep
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	FailedLeasesHistoryLimit *int32 `json:"failedLeasesHistoryLimit,omitempty"`
+}
+
+// RecurringLeaseStatus defines the observed state of RecurringLease
+type RecurringLeaseStatus struct {
+	// The leases created that have not ended
+	// +optional
+	Active []corev1.LocalObjectReference `json:"active,omitempty"`
+	// When a lease was last scheduled
+	// +optional
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+}
+
+// RecurringLeaseLabel names the RecurringLease a lease was created by
+const RecurringLeaseLabel = "jumpstarter.dev/recurring-lease"
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=`.spec.schedule`
+// +kubebuilder:printcolumn:name="Suspend",type=boolean,JSONPath=`.spec.suspend`
+// +kubebuilder:printcolumn:name="Last Schedule",type=date,JSONPath=`.status.lastScheduleTime`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// RecurringLease is the Schema for the recurringleases API, it creates leases on a cron
+// schedule for periodic automated test runs
+type RecurringLease struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RecurringLeaseSpec   `json:"spec,omitempty"`
+	Status RecurringLeaseStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// RecurringLeaseList contains a list of RecurringLease
+type RecurringLeaseList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RecurringLease `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RecurringLease{}, &RecurringLeaseList{})
+}
@@ -108,6 +108,11 @@ func (in *ClientStatus) DeepCopyInto(out *ClientStatus) {
 		*out = new(v1.LocalObjectReference)
 		**out = **in
 	}
+	if in.Groups != nil {
+		in, out := &in.Groups, &out.Groups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClientStatus.
@@ -120,6 +125,85 @@ func (in *ClientStatus) DeepCopy() *ClientStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterExporterAccessPolicy) DeepCopyInto(out *ClusterExporterAccessPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterExporterAccessPolicy.
+func (in *ClusterExporterAccessPolicy) DeepCopy() *ClusterExporterAccessPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterExporterAccessPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterExporterAccessPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterExporterAccessPolicyList) DeepCopyInto(out *ClusterExporterAccessPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterExporterAccessPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterExporterAccessPolicyList.
+func (in *ClusterExporterAccessPolicyList) DeepCopy() *ClusterExporterAccessPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterExporterAccessPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterExporterAccessPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterExporterAccessPolicySpec) DeepCopyInto(out *ClusterExporterAccessPolicySpec) {
+	*out = *in
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	in.ExporterAccessPolicySpec.DeepCopyInto(&out.ExporterAccessPolicySpec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterExporterAccessPolicySpec.
+func (in *ClusterExporterAccessPolicySpec) DeepCopy() *ClusterExporterAccessPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterExporterAccessPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Device) DeepCopyInto(out *Device) {
 	*out = *in
@@ -152,7 +236,7 @@ func (in *Exporter) DeepCopyInto(out *Exporter) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -174,6 +258,178 @@ func (in *Exporter) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExporterAccessPolicy) DeepCopyInto(out *ExporterAccessPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExporterAccessPolicy.
+func (in *ExporterAccessPolicy) DeepCopy() *ExporterAccessPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ExporterAccessPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ExporterAccessPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExporterAccessPolicyList) DeepCopyInto(out *ExporterAccessPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ExporterAccessPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExporterAccessPolicyList.
+func (in *ExporterAccessPolicyList) DeepCopy() *ExporterAccessPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(ExporterAccessPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ExporterAccessPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExporterAccessPolicySpec) DeepCopyInto(out *ExporterAccessPolicySpec) {
+	*out = *in
+	in.ExporterSelector.DeepCopyInto(&out.ExporterSelector)
+	if in.Policies != nil {
+		in, out := &in.Policies, &out.Policies
+		*out = make([]Policy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExporterAccessPolicySpec.
+func (in *ExporterAccessPolicySpec) DeepCopy() *ExporterAccessPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ExporterAccessPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExporterAccessPolicyStatus) DeepCopyInto(out *ExporterAccessPolicyStatus) {
+	*out = *in
+	if in.Policies != nil {
+		in, out := &in.Policies, &out.Policies
+		*out = make([]PolicyStatus, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExporterAccessPolicyStatus.
+func (in *ExporterAccessPolicyStatus) DeepCopy() *ExporterAccessPolicyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ExporterAccessPolicyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExporterAffinity) DeepCopyInto(out *ExporterAffinity) {
+	*out = *in
+	if in.Required != nil {
+		in, out := &in.Required, &out.Required
+		*out = make([]ExporterAffinityTerm, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Preferred != nil {
+		in, out := &in.Preferred, &out.Preferred
+		*out = make([]WeightedExporterAffinityTerm, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExporterAffinity.
+func (in *ExporterAffinity) DeepCopy() *ExporterAffinity {
+	if in == nil {
+		return nil
+	}
+	out := new(ExporterAffinity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExporterAffinityTerm) DeepCopyInto(out *ExporterAffinityTerm) {
+	*out = *in
+	if in.ExporterNames != nil {
+		in, out := &in.ExporterNames, &out.ExporterNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExporterAffinityTerm.
+func (in *ExporterAffinityTerm) DeepCopy() *ExporterAffinityTerm {
+	if in == nil {
+		return nil
+	}
+	out := new(ExporterAffinityTerm)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExporterError) DeepCopyInto(out *ExporterError) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExporterError.
+func (in *ExporterError) DeepCopy() *ExporterError {
+	if in == nil {
+		return nil
+	}
+	out := new(ExporterError)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ExporterList) DeepCopyInto(out *ExporterList) {
 	*out = *in
@@ -206,9 +462,48 @@ func (in *ExporterList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExporterReservation) DeepCopyInto(out *ExporterReservation) {
+	*out = *in
+	if in.ClientRef != nil {
+		in, out := &in.ClientRef, &out.ClientRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+	if in.ClientSelector != nil {
+		in, out := &in.ClientSelector, &out.ClientSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Begin.DeepCopyInto(&out.Begin)
+	in.End.DeepCopyInto(&out.End)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExporterReservation.
+func (in *ExporterReservation) DeepCopy() *ExporterReservation {
+	if in == nil {
+		return nil
+	}
+	out := new(ExporterReservation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ExporterSpec) DeepCopyInto(out *ExporterSpec) {
 	*out = *in
+	if in.Reservations != nil {
+		in, out := &in.Reservations, &out.Reservations
+		*out = make([]ExporterReservation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MaxConcurrentLeases != nil {
+		in, out := &in.MaxConcurrentLeases, &out.MaxConcurrentLeases
+		*out = new(int32)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExporterSpec.
@@ -248,6 +543,23 @@ func (in *ExporterStatus) DeepCopyInto(out *ExporterStatus) {
 		*out = new(v1.LocalObjectReference)
 		**out = **in
 	}
+	if in.LeaseRefs != nil {
+		in, out := &in.LeaseRefs, &out.LeaseRefs
+		*out = make([]v1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.LabelHistory != nil {
+		in, out := &in.LabelHistory, &out.LabelHistory
+		*out = make([]LabelSetRevision, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastError != nil {
+		in, out := &in.LastError, &out.LastError
+		*out = new(ExporterError)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExporterStatus.
@@ -261,7 +573,7 @@ func (in *ExporterStatus) DeepCopy() *ExporterStatus {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Lease) DeepCopyInto(out *Lease) {
+func (in *ExporterUpdatePolicy) DeepCopyInto(out *ExporterUpdatePolicy) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
@@ -269,18 +581,18 @@ func (in *Lease) DeepCopyInto(out *Lease) {
 	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Lease.
-func (in *Lease) DeepCopy() *Lease {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExporterUpdatePolicy.
+func (in *ExporterUpdatePolicy) DeepCopy() *ExporterUpdatePolicy {
 	if in == nil {
 		return nil
 	}
-	out := new(Lease)
+	out := new(ExporterUpdatePolicy)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *Lease) DeepCopyObject() runtime.Object {
+func (in *ExporterUpdatePolicy) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -288,31 +600,31 @@ func (in *Lease) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *LeaseList) DeepCopyInto(out *LeaseList) {
+func (in *ExporterUpdatePolicyList) DeepCopyInto(out *ExporterUpdatePolicyList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]Lease, len(*in))
+		*out = make([]ExporterUpdatePolicy, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LeaseList.
-func (in *LeaseList) DeepCopy() *LeaseList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExporterUpdatePolicyList.
+func (in *ExporterUpdatePolicyList) DeepCopy() *ExporterUpdatePolicyList {
 	if in == nil {
 		return nil
 	}
-	out := new(LeaseList)
+	out := new(ExporterUpdatePolicyList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *LeaseList) DeepCopyObject() runtime.Object {
+func (in *ExporterUpdatePolicyList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -320,39 +632,25 @@ func (in *LeaseList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *LeaseSpec) DeepCopyInto(out *LeaseSpec) {
+func (in *ExporterUpdatePolicySpec) DeepCopyInto(out *ExporterUpdatePolicySpec) {
 	*out = *in
-	out.ClientRef = in.ClientRef
-	out.Duration = in.Duration
 	in.Selector.DeepCopyInto(&out.Selector)
+	out.UpdateTimeout = in.UpdateTimeout
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LeaseSpec.
-func (in *LeaseSpec) DeepCopy() *LeaseSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExporterUpdatePolicySpec.
+func (in *ExporterUpdatePolicySpec) DeepCopy() *ExporterUpdatePolicySpec {
 	if in == nil {
 		return nil
 	}
-	out := new(LeaseSpec)
+	out := new(ExporterUpdatePolicySpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *LeaseStatus) DeepCopyInto(out *LeaseStatus) {
+func (in *ExporterUpdatePolicyStatus) DeepCopyInto(out *ExporterUpdatePolicyStatus) {
 	*out = *in
-	if in.BeginTime != nil {
-		in, out := &in.BeginTime, &out.BeginTime
-		*out = (*in).DeepCopy()
-	}
-	if in.EndTime != nil {
-		in, out := &in.EndTime, &out.EndTime
-		*out = (*in).DeepCopy()
-	}
-	if in.ExporterRef != nil {
-		in, out := &in.ExporterRef, &out.ExporterRef
-		*out = new(v1.LocalObjectReference)
-		**out = **in
-	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]metav1.Condition, len(*in))
@@ -362,12 +660,976 @@ func (in *LeaseStatus) DeepCopyInto(out *LeaseStatus) {
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LeaseStatus.
-func (in *LeaseStatus) DeepCopy() *LeaseStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExporterUpdatePolicyStatus.
+func (in *ExporterUpdatePolicyStatus) DeepCopy() *ExporterUpdatePolicyStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(LeaseStatus)
+	out := new(ExporterUpdatePolicyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *From) DeepCopyInto(out *From) {
+	*out = *in
+	in.ClientSelector.DeepCopyInto(&out.ClientSelector)
+	if in.Groups != nil {
+		in, out := &in.Groups, &out.Groups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new From.
+func (in *From) DeepCopy() *From {
+	if in == nil {
+		return nil
+	}
+	out := new(From)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LabelSetRevision) DeepCopyInto(out *LabelSetRevision) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LabelSetRevision.
+func (in *LabelSetRevision) DeepCopy() *LabelSetRevision {
+	if in == nil {
+		return nil
+	}
+	out := new(LabelSetRevision)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Lease) DeepCopyInto(out *Lease) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Lease.
+func (in *Lease) DeepCopy() *Lease {
+	if in == nil {
+		return nil
+	}
+	out := new(Lease)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Lease) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LeaseAffinity) DeepCopyInto(out *LeaseAffinity) {
+	*out = *in
+	if in.ExporterAffinity != nil {
+		in, out := &in.ExporterAffinity, &out.ExporterAffinity
+		*out = new(ExporterAffinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExporterAntiAffinity != nil {
+		in, out := &in.ExporterAntiAffinity, &out.ExporterAntiAffinity
+		*out = new(ExporterAffinity)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LeaseAffinity.
+func (in *LeaseAffinity) DeepCopy() *LeaseAffinity {
+	if in == nil {
+		return nil
+	}
+	out := new(LeaseAffinity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LeaseDecision) DeepCopyInto(out *LeaseDecision) {
+	*out = *in
+	if in.Filtered != nil {
+		in, out := &in.Filtered, &out.Filtered
+		*out = make(map[string]int32, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Scores != nil {
+		in, out := &in.Scores, &out.Scores
+		*out = make(map[string]int64, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LeaseDecision.
+func (in *LeaseDecision) DeepCopy() *LeaseDecision {
+	if in == nil {
+		return nil
+	}
+	out := new(LeaseDecision)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LeaseList) DeepCopyInto(out *LeaseList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Lease, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LeaseList.
+func (in *LeaseList) DeepCopy() *LeaseList {
+	if in == nil {
+		return nil
+	}
+	out := new(LeaseList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *LeaseList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LeasePriorityClass) DeepCopyInto(out *LeasePriorityClass) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LeasePriorityClass.
+func (in *LeasePriorityClass) DeepCopy() *LeasePriorityClass {
+	if in == nil {
+		return nil
+	}
+	out := new(LeasePriorityClass)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *LeasePriorityClass) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LeasePriorityClassList) DeepCopyInto(out *LeasePriorityClassList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]LeasePriorityClass, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LeasePriorityClassList.
+func (in *LeasePriorityClassList) DeepCopy() *LeasePriorityClassList {
+	if in == nil {
+		return nil
+	}
+	out := new(LeasePriorityClassList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *LeasePriorityClassList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LeasePriorityClassSpec) DeepCopyInto(out *LeasePriorityClassSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LeasePriorityClassSpec.
+func (in *LeasePriorityClassSpec) DeepCopy() *LeasePriorityClassSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LeasePriorityClassSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LeaseQuota) DeepCopyInto(out *LeaseQuota) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LeaseQuota.
+func (in *LeaseQuota) DeepCopy() *LeaseQuota {
+	if in == nil {
+		return nil
+	}
+	out := new(LeaseQuota)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *LeaseQuota) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LeaseQuotaList) DeepCopyInto(out *LeaseQuotaList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]LeaseQuota, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LeaseQuotaList.
+func (in *LeaseQuotaList) DeepCopy() *LeaseQuotaList {
+	if in == nil {
+		return nil
+	}
+	out := new(LeaseQuotaList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *LeaseQuotaList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LeaseQuotaSpec) DeepCopyInto(out *LeaseQuotaSpec) {
+	*out = *in
+	in.ClientSelector.DeepCopyInto(&out.ClientSelector)
+	if in.MaxConcurrentLeases != nil {
+		in, out := &in.MaxConcurrentLeases, &out.MaxConcurrentLeases
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxLeasedDuration != nil {
+		in, out := &in.MaxLeasedDuration, &out.MaxLeasedDuration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	out.Period = in.Period
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LeaseQuotaSpec.
+func (in *LeaseQuotaSpec) DeepCopy() *LeaseQuotaSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LeaseQuotaSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LeaseRecord) DeepCopyInto(out *LeaseRecord) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LeaseRecord.
+func (in *LeaseRecord) DeepCopy() *LeaseRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(LeaseRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *LeaseRecord) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LeaseRecordList) DeepCopyInto(out *LeaseRecordList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]LeaseRecord, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LeaseRecordList.
+func (in *LeaseRecordList) DeepCopy() *LeaseRecordList {
+	if in == nil {
+		return nil
+	}
+	out := new(LeaseRecordList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *LeaseRecordList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LeaseRecordSpec) DeepCopyInto(out *LeaseRecordSpec) {
+	*out = *in
+	out.ClientRef = in.ClientRef
+	if in.ExporterRef != nil {
+		in, out := &in.ExporterRef, &out.ExporterRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+	if in.ExporterRefs != nil {
+		in, out := &in.ExporterRefs, &out.ExporterRefs
+		*out = make([]v1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	in.Selector.DeepCopyInto(&out.Selector)
+	out.RequestedDuration = in.RequestedDuration
+	in.RequestTime.DeepCopyInto(&out.RequestTime)
+	if in.BeginTime != nil {
+		in, out := &in.BeginTime, &out.BeginTime
+		*out = (*in).DeepCopy()
+	}
+	in.EndTime.DeepCopyInto(&out.EndTime)
+	out.WaitTime = in.WaitTime
+	out.Duration = in.Duration
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LeaseRecordSpec.
+func (in *LeaseRecordSpec) DeepCopy() *LeaseRecordSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LeaseRecordSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LeaseRole) DeepCopyInto(out *LeaseRole) {
+	*out = *in
+	in.Selector.DeepCopyInto(&out.Selector)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LeaseRole.
+func (in *LeaseRole) DeepCopy() *LeaseRole {
+	if in == nil {
+		return nil
+	}
+	out := new(LeaseRole)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LeaseRoleExporterRef) DeepCopyInto(out *LeaseRoleExporterRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LeaseRoleExporterRef.
+func (in *LeaseRoleExporterRef) DeepCopy() *LeaseRoleExporterRef {
+	if in == nil {
+		return nil
+	}
+	out := new(LeaseRoleExporterRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LeaseSpec) DeepCopyInto(out *LeaseSpec) {
+	*out = *in
+	out.ClientRef = in.ClientRef
+	if in.ClientGroups != nil {
+		in, out := &in.ClientGroups, &out.ClientGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	out.Duration = in.Duration
+	in.Selector.DeepCopyInto(&out.Selector)
+	if in.Observers != nil {
+		in, out := &in.Observers, &out.Observers
+		*out = make([]v1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.AcquireTimeout != nil {
+		in, out := &in.AcquireTimeout, &out.AcquireTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.BeginTime != nil {
+		in, out := &in.BeginTime, &out.BeginTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(LeaseAffinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Roles != nil {
+		in, out := &in.Roles, &out.Roles
+		*out = make([]LeaseRole, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LeaseSpec.
+func (in *LeaseSpec) DeepCopy() *LeaseSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LeaseSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LeaseStatus) DeepCopyInto(out *LeaseStatus) {
+	*out = *in
+	if in.BeginTime != nil {
+		in, out := &in.BeginTime, &out.BeginTime
+		*out = (*in).DeepCopy()
+	}
+	if in.EndTime != nil {
+		in, out := &in.EndTime, &out.EndTime
+		*out = (*in).DeepCopy()
+	}
+	if in.ExporterRef != nil {
+		in, out := &in.ExporterRef, &out.ExporterRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Metadata != nil {
+		in, out := &in.Metadata, &out.Metadata
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.LinkedExporterRefs != nil {
+		in, out := &in.LinkedExporterRefs, &out.LinkedExporterRefs
+		*out = make([]v1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.RoleExporterRefs != nil {
+		in, out := &in.RoleExporterRefs, &out.RoleExporterRefs
+		*out = make([]LeaseRoleExporterRef, len(*in))
+		copy(*out, *in)
+	}
+	if in.ReservedExporterRef != nil {
+		in, out := &in.ReservedExporterRef, &out.ReservedExporterRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+	if in.QueuePosition != nil {
+		in, out := &in.QueuePosition, &out.QueuePosition
+		*out = new(int32)
+		**out = **in
+	}
+	if in.EstimatedBeginTime != nil {
+		in, out := &in.EstimatedBeginTime, &out.EstimatedBeginTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Decision != nil {
+		in, out := &in.Decision, &out.Decision
+		*out = new(LeaseDecision)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PauseTime != nil {
+		in, out := &in.PauseTime, &out.PauseTime
+		*out = (*in).DeepCopy()
+	}
+	if in.PausedDuration != nil {
+		in, out := &in.PausedDuration, &out.PausedDuration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.FirstDialTime != nil {
+		in, out := &in.FirstDialTime, &out.FirstDialTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LeaseStatus.
+func (in *LeaseStatus) DeepCopy() *LeaseStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(LeaseStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LeaseTemplate) DeepCopyInto(out *LeaseTemplate) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LeaseTemplate.
+func (in *LeaseTemplate) DeepCopy() *LeaseTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(LeaseTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *LeaseTemplate) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LeaseTemplateList) DeepCopyInto(out *LeaseTemplateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]LeaseTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LeaseTemplateList.
+func (in *LeaseTemplateList) DeepCopy() *LeaseTemplateList {
+	if in == nil {
+		return nil
+	}
+	out := new(LeaseTemplateList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *LeaseTemplateList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LeaseTemplateSpec) DeepCopyInto(out *LeaseTemplateSpec) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Duration != nil {
+		in, out := &in.Duration, &out.Duration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.AcquireTimeout != nil {
+		in, out := &in.AcquireTimeout, &out.AcquireTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Observers != nil {
+		in, out := &in.Observers, &out.Observers
+		*out = make([]v1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(LeaseAffinity)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LeaseTemplateSpec.
+func (in *LeaseTemplateSpec) DeepCopy() *LeaseTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LeaseTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MaintenanceWindow) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindowList) DeepCopyInto(out *MaintenanceWindowList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]MaintenanceWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindowList.
+func (in *MaintenanceWindowList) DeepCopy() *MaintenanceWindowList {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindowList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MaintenanceWindowList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindowSpec) DeepCopyInto(out *MaintenanceWindowSpec) {
+	*out = *in
+	in.ExporterSelector.DeepCopyInto(&out.ExporterSelector)
+	in.Begin.DeepCopyInto(&out.Begin)
+	in.End.DeepCopyInto(&out.End)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindowSpec.
+func (in *MaintenanceWindowSpec) DeepCopy() *MaintenanceWindowSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindowSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Policy) DeepCopyInto(out *Policy) {
+	*out = *in
+	if in.From != nil {
+		in, out := &in.From, &out.From
+		*out = make([]From, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.StreamLimits != nil {
+		in, out := &in.StreamLimits, &out.StreamLimits
+		*out = new(StreamLimits)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MaximumDuration != nil {
+		in, out := &in.MaximumDuration, &out.MaximumDuration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MaximumPauseDuration != nil {
+		in, out := &in.MaximumPauseDuration, &out.MaximumPauseDuration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MaxConcurrentLeases != nil {
+		in, out := &in.MaxConcurrentLeases, &out.MaxConcurrentLeases
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxConcurrentLeasesPerClient != nil {
+		in, out := &in.MaxConcurrentLeasesPerClient, &out.MaxConcurrentLeasesPerClient
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Policy.
+func (in *Policy) DeepCopy() *Policy {
+	if in == nil {
+		return nil
+	}
+	out := new(Policy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyStatus) DeepCopyInto(out *PolicyStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyStatus.
+func (in *PolicyStatus) DeepCopy() *PolicyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RecurringLease) DeepCopyInto(out *RecurringLease) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RecurringLease.
+func (in *RecurringLease) DeepCopy() *RecurringLease {
+	if in == nil {
+		return nil
+	}
+	out := new(RecurringLease)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RecurringLease) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RecurringLeaseList) DeepCopyInto(out *RecurringLeaseList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RecurringLease, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RecurringLeaseList.
+func (in *RecurringLeaseList) DeepCopy() *RecurringLeaseList {
+	if in == nil {
+		return nil
+	}
+	out := new(RecurringLeaseList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RecurringLeaseList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RecurringLeaseSpec) DeepCopyInto(out *RecurringLeaseSpec) {
+	*out = *in
+	in.Template.DeepCopyInto(&out.Template)
+	if in.StartingDeadline != nil {
+		in, out := &in.StartingDeadline, &out.StartingDeadline
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.SuccessfulLeasesHistoryLimit != nil {
+		in, out := &in.SuccessfulLeasesHistoryLimit, &out.SuccessfulLeasesHistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.FailedLeasesHistoryLimit != nil {
+		in, out := &in.FailedLeasesHistoryLimit, &out.FailedLeasesHistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RecurringLeaseSpec.
+func (in *RecurringLeaseSpec) DeepCopy() *RecurringLeaseSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RecurringLeaseSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RecurringLeaseStatus) DeepCopyInto(out *RecurringLeaseStatus) {
+	*out = *in
+	if in.Active != nil {
+		in, out := &in.Active, &out.Active
+		*out = make([]v1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RecurringLeaseStatus.
+func (in *RecurringLeaseStatus) DeepCopy() *RecurringLeaseStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RecurringLeaseStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StreamLimits) DeepCopyInto(out *StreamLimits) {
+	*out = *in
+	if in.MaxBandwidth != nil {
+		in, out := &in.MaxBandwidth, &out.MaxBandwidth
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.MaxSessionDuration != nil {
+		in, out := &in.MaxSessionDuration, &out.MaxSessionDuration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StreamLimits.
+func (in *StreamLimits) DeepCopy() *StreamLimits {
+	if in == nil {
+		return nil
+	}
+	out := new(StreamLimits)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WeightedExporterAffinityTerm) DeepCopyInto(out *WeightedExporterAffinityTerm) {
+	*out = *in
+	in.Term.DeepCopyInto(&out.Term)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WeightedExporterAffinityTerm.
+func (in *WeightedExporterAffinityTerm) DeepCopy() *WeightedExporterAffinityTerm {
+	if in == nil {
+		return nil
+	}
+	out := new(WeightedExporterAffinityTerm)
 	in.DeepCopyInto(out)
 	return out
 }
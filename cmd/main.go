@@ -17,18 +17,24 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
 	"os"
+	"slices"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
@@ -37,7 +43,10 @@ import (
 
 	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
 	"github.com/jumpstarter-dev/jumpstarter-controller/internal/controller"
+	"github.com/jumpstarter-dev/jumpstarter-controller/internal/features"
 	"github.com/jumpstarter-dev/jumpstarter-controller/internal/service"
+	webhookv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/internal/webhook/v1alpha1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/pkg/interceptors"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -59,6 +68,44 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var allocatorName string
+	var shadowAllocatorName string
+	var allocatorScorers string
+	var allocatorLabelWeights string
+	var allocatorUsageWindow time.Duration
+	var dashboardAddr string
+	var enableConsoleAPI bool
+	var role string
+	var restrictExporterVisibility bool
+	var offlineRetryWindow time.Duration
+	var waitForExporterMaxBackoff time.Duration
+	var waitForExporterMinBackoff, leaseQueueRetryInterval time.Duration
+	var leaseOfflineRetryInterval, leaseQuotaRetryInterval time.Duration
+	var leaseMaxConcurrentReconciles int
+	var preemptionGracePeriod, leaseEndingNotice time.Duration
+	var leaseAcquisitionTimeout time.Duration
+	var leaseRecordRetention time.Duration
+	var endedLeaseTTL time.Duration
+	var consistencyCheckInterval time.Duration
+	var consistencyCheckRepair bool
+	var keepalive service.KeepalivePolicy
+	var disabledLegacyLeaseNamespaces string
+	var recorder service.StreamRecorder
+	var registrationWebhookURL string
+	var certificateAuthConfig string
+	var controllerListenersConfig string
+	var interceptorsConfig string
+	var leaseDurationConfig string
+	var transferConfig string
+	var listExportersCacheTTL time.Duration
+	var maxDialTimeout time.Duration
+	var selfSignedCertificate service.SelfSignedCertificateConfig
+	registerLimits := service.DefaultRegisterLimits
+	policyTieBreak := controller.PolicyTieBreakMostRestrictive
+	var enableLeaseWebhook bool
+	var routerStreamWindow, routerConnectionWindow, routerMaxFrameSize int
+	var controllerDisabledEndpoints, routerDisabledEndpoints service.DisabledEndpoints
+	authExemptions := slices.Clone(service.DefaultAuthExemptions)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metric endpoint binds to. "+
 		"Use the port :8080. If not set, it will be 0 in order to disable the metrics server")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
@@ -69,6 +116,138 @@ func main() {
 		"If set the metrics endpoint is served securely")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&dashboardAddr, "dashboard-bind-address", ":8084", "The address the dashboard binds to.")
+	flag.BoolVar(&enableConsoleAPI, "enable-console-api", false,
+		"If set, the dashboard also serves the REST API of the console plugins under "+service.ConsoleAPIPrefix+
+			", authorizing the requests with the bearer tokens of the users")
+	flag.StringVar(&allocatorName, "allocator", controller.DefaultAllocatorName,
+		"The allocator used to assign exporters to leases")
+	flag.StringVar(&allocatorScorers, "allocator-scorers", "",
+		"Score plugins added to the allocator, as NAME[=WEIGHT],... among Affinity, LeastRecentlyUsed, "+
+			"Utilization and LabelWeight, e.g. LeastRecentlyUsed=2,Utilization to spread leases across exporters")
+	flag.StringVar(&allocatorLabelWeights, "allocator-label-weights", "",
+		"The exporter labels the LabelWeight score plugin prefers, as KEY=VALUE:WEIGHT,...")
+	flag.DurationVar(&allocatorUsageWindow, "allocator-usage-window", controller.DefaultUsageWindow,
+		"The exporter usage history the LeastRecentlyUsed and Utilization score plugins consider")
+	flag.StringVar(&shadowAllocatorName, "shadow-allocator", "",
+		"If set, the allocator to evaluate in shadow mode over pending leases, "+
+			"its decisions are logged and exported as metrics but never applied")
+	flag.DurationVar(&leaseRecordRetention, "lease-record-retention", 0,
+		"How long the LeaseRecords of ended leases are kept, 0 disables writing them")
+	flag.DurationVar(&endedLeaseTTL, "ended-lease-ttl", 0,
+		"How long ended leases are kept before being deleted, recorded first if LeaseRecords are enabled, "+
+			"0 keeps them forever")
+	flag.DurationVar(&offlineRetryWindow, "offline-retry-window", 5*time.Minute,
+		"How long leases whose matching exporters are all offline stay pending before they are unsatisfiable")
+	flag.DurationVar(&preemptionGracePeriod, "preemption-grace-period", 0,
+		"How long preempted leases keep their exporter before they end, 0 to end them right away")
+	flag.DurationVar(&leaseEndingNotice, "lease-ending-notice", time.Minute,
+		"How long before a lease expires or is preempted it gets the EndingSoon condition, 0 to disable")
+	flag.DurationVar(&leaseAcquisitionTimeout, "lease-acquisition-timeout", 0,
+		"How long after a lease acquired its exporter it is released if its client never dialed it, 0 to disable")
+	flag.DurationVar(&waitForExporterMaxBackoff, "wait-for-exporter-max-backoff", controller.DefaultWaitForExporterMaxBackoff,
+		"The longest backoff between the retries of the leases waiting for an exporter that could satisfy them")
+	flag.DurationVar(&waitForExporterMinBackoff, "wait-for-exporter-min-backoff",
+		controller.DefaultWaitForExporterMinBackoff,
+		"The first backoff of the retries of the leases waiting for an exporter that could satisfy them")
+	flag.DurationVar(&leaseQueueRetryInterval, "lease-queue-retry-interval", controller.DefaultQueueRetryInterval,
+		"How often the leases queued for a busy exporter are re-evaluated, on top of the releases of exporters")
+	flag.DurationVar(&leaseOfflineRetryInterval, "lease-offline-retry-interval",
+		controller.DefaultOfflineRetryInterval,
+		"How often the leases waiting for offline exporters or overlapping reservations are re-evaluated")
+	flag.DurationVar(&leaseQuotaRetryInterval, "lease-quota-retry-interval", controller.DefaultQuotaRetryInterval,
+		"How often the leases exceeding a LeaseQuota are re-evaluated")
+	flag.IntVar(&leaseMaxConcurrentReconciles, "lease-max-concurrent-reconciles", 1,
+		"The number of leases reconciled in parallel")
+	flag.DurationVar(&consistencyCheckInterval, "consistency-check-interval", 5*time.Minute,
+		"How often the invariants between leases and exporters are verified, 0 to disable the checks")
+	flag.BoolVar(&consistencyCheckRepair, "consistency-check-repair", true,
+		"If set, the invariant violations found by the consistency checks are repaired, not only reported")
+	flag.Var(features.DefaultGate, "feature-gates",
+		"Comma separated list of feature=bool pairs enabling experimental features, known features: "+
+			"Preemption")
+	flag.StringVar(&role, "role", roleAll,
+		"Comma separated list of the roles this process runs: api (controller gRPC service and dashboard), "+
+			"reconciler, router, or all. Replicas running different roles can be scaled independently")
+	flag.BoolVar(&restrictExporterVisibility, "restrict-exporter-visibility", false,
+		"If set, clients only see the exporters of their namespace the ExporterAccessPolicies allow them to lease")
+	flag.DurationVar(&keepalive.MinPingInterval, "grpc-keepalive-min-ping-interval",
+		service.DefaultKeepalivePolicy.MinPingInterval,
+		"The minimum interval between keepalive pings of gRPC clients, clients pinging more often are disconnected")
+	flag.BoolVar(&keepalive.PermitWithoutStream, "grpc-keepalive-permit-without-stream",
+		service.DefaultKeepalivePolicy.PermitWithoutStream,
+		"If set, gRPC clients may send keepalive pings without active streams")
+	flag.DurationVar(&keepalive.IdleTimeout, "grpc-idle-timeout", service.DefaultKeepalivePolicy.IdleTimeout,
+		"How long gRPC connections may stay idle before they are closed, 0 to keep them open")
+	flag.StringVar(&disabledLegacyLeaseNamespaces, "disable-legacy-lease-rpcs", "",
+		"Comma separated list of the namespaces in which the deprecated lease RPCs of the controller service "+
+			"are rejected, or * for all namespaces")
+	flag.Var(&controllerDisabledEndpoints, "controller-disable-endpoints",
+		"Comma separated list of the optional endpoints not served on the controller gRPC listener, "+
+			"e.g. reflection")
+	flag.Var(&authExemptions, "auth-exempt-methods",
+		"Comma separated list of the gRPC methods of the controller served without credentials, "+
+			"as /SERVICE/METHOD or /SERVICE/*, the health and reflection services by default")
+	flag.Var(&routerDisabledEndpoints, "router-disable-endpoints",
+		"Comma separated list of the optional endpoints not served on the router gRPC listener, "+
+			"e.g. reflection")
+	flag.IntVar(&routerStreamWindow, "router-stream-window",
+		int(service.DefaultFlowControlPolicy.StreamWindow),
+		"The HTTP/2 flow control window of each router stream in bytes, bounds the frames buffered per stream")
+	flag.IntVar(&routerConnectionWindow, "router-connection-window",
+		int(service.DefaultFlowControlPolicy.ConnectionWindow),
+		"The HTTP/2 flow control window of each router connection in bytes")
+	flag.IntVar(&routerMaxFrameSize, "router-max-frame-size", service.DefaultFlowControlPolicy.MaxFrameSize,
+		"The maximum size of a frame forwarded by the router in bytes")
+	flag.StringVar(&registrationWebhookURL, "registration-webhook-url", "",
+		"If set, the exporter registrations are posted to this URL, signed with REGISTRATION_WEBHOOK_SECRET")
+	flag.BoolVar(&enableLeaseWebhook, "enable-lease-webhook", false,
+		"If set, the API role serves the webhook defaulting the leases created with a LeaseTemplate")
+	flag.DurationVar(&listExportersCacheTTL, "list-exporters-cache-ttl", 0,
+		"How long ListExporters reuses the exporter lists of a namespace and selector, 0 to list them every time")
+	flag.DurationVar(&maxDialTimeout, "max-dial-timeout", 5*time.Minute,
+		"The longest time Dial waits for the exporters to pick up the dials, bounding the timeouts of the clients")
+	flag.IntVar(&registerLimits.MaxDevices, "register-max-devices", registerLimits.MaxDevices,
+		"The maximum number of devices an exporter may report, 0 for no limit")
+	flag.IntVar(&registerLimits.MaxDeviceLabels, "register-max-device-labels", registerLimits.MaxDeviceLabels,
+		"The maximum number of labels of a device reported by an exporter, 0 for no limit")
+	flag.IntVar(&registerLimits.MaxLabelSize, "register-max-label-size", registerLimits.MaxLabelSize,
+		"The maximum size in bytes of the key and value of a device label, 0 for no limit")
+	flag.Var(&policyTieBreak, "policy-tie-break",
+		"Which of the exporter access policies of equal priority matching a client applies, "+
+			"MostRestrictive or MostPermissive")
+	flag.Var(&registerLimits.Policy, "register-limit-policy",
+		"What to do with the registrations exceeding the register limits, Truncate or Reject")
+	flag.DurationVar(&selfSignedCertificate.RotationInterval, "self-signed-cert-rotation-interval",
+		service.DefaultSelfSignedRotationInterval,
+		"The age the self-signed serving certificates kept in Secrets are reissued at, at most 11 months")
+	flag.BoolVar(&selfSignedCertificate.ReissueOnSANChange, "self-signed-cert-reissue-on-san-change", true,
+		"If set, the self-signed serving certificates are reissued when the endpoints change, "+
+			"instead of being kept until rotated")
+	flag.StringVar(&certificateAuthConfig, "certificate-auth-config", "",
+		"If set, the configuration file of the authentication of clients and exporters by TLS client certificates")
+	flag.StringVar(&controllerListenersConfig, "controller-listeners-config", "",
+		"If set, the configuration file of the listeners the controller gRPC service is served on, "+
+			"instead of the single listener on :8082")
+	flag.StringVar(&interceptorsConfig, "interceptors-config", "",
+		"If set, the configuration file of the additional interceptors of the controller gRPC service, in order, "+
+			"among "+strings.Join(interceptors.Registered(), ", "))
+	flag.StringVar(&leaseDurationConfig, "lease-duration-config", "",
+		"If set, the configuration file of the default and maximum lease durations, "+
+			"globally and per namespace")
+	flag.StringVar(&transferConfig, "transfer-config", "",
+		"If set, the configuration file of the object stores the transfers of large artifacts are offloaded to, "+
+			"per namespace")
+	flag.StringVar(&recorder.Dir, "recording-dir", "",
+		"If set, the router records the streams of leases with recording enabled to this directory")
+	flag.StringVar(&recorder.BindAddress, "recording-bind-address", "127.0.0.1:8085",
+		"The address the stream recordings are served on.")
+	flag.Int64Var(&recorder.MaxRecordingBytes, "recording-max-bytes", 64<<20,
+		"The maximum size of a single stream recording, 0 for unbounded")
+	flag.Int64Var(&recorder.MaxTotalBytes, "recording-max-total-bytes", 1<<30,
+		"The maximum total size of the stream recordings, the oldest are removed first, 0 for unbounded")
+	flag.DurationVar(&recorder.Retention, "recording-retention", 7*24*time.Hour,
+		"How long stream recordings are kept, 0 to keep them forever")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -77,6 +256,14 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	roles, err := parseRoles(role)
+	if err != nil {
+		setupLog.Error(err, "invalid role")
+		os.Exit(1)
+	}
+	setupLog.Info("running roles", "roles", roles)
+	setupLog.Info("feature gates", "gates", features.DefaultGate.String())
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -93,12 +280,19 @@ func main() {
 		tlsOpts = append(tlsOpts, disableHTTP2)
 	}
 
-	webhookServer := webhook.NewServer(webhook.Options{
+	webhookOptions := webhook.Options{
 		TLSOpts: tlsOpts,
-	})
+	}
+	enableLeaseWebhook = enableLeaseWebhook && slices.Contains(roles, roleAPI)
+	var webhookCA []byte
+	if enableLeaseWebhook {
+		webhookOptions.CertDir, webhookCA = provisionWebhookCertificate()
+	}
+	webhookServer := webhook.NewServer(webhookOptions)
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme: scheme,
+		Cache:  managerCacheOptions(),
 		Metrics: metricsserver.Options{
 			BindAddress:   metricsAddr,
 			SecureServing: secureMetrics,
@@ -107,7 +301,7 @@ func main() {
 		WebhookServer:          webhookServer,
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "a38b78e7.jumpstarter.dev",
+		LeaderElectionID:       leaderElectionID("a38b78e7.jumpstarter.dev", roles),
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -125,71 +319,357 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err = (&controller.ExporterReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Exporter")
+	var leaseDurationLimits *controller.LeaseDurationLimits
+	if leaseDurationConfig != "" {
+		leaseDurationLimits, err = controller.LoadLeaseDurationLimits(leaseDurationConfig)
+		if err != nil {
+			setupLog.Error(err, "unable to load lease duration configuration")
+			os.Exit(1)
+		}
+	}
+
+	if slices.Contains(roles, roleReconciler) {
+		exporterReconciler := &controller.ExporterReconciler{
+			Client: mgr.GetClient(),
+			Scheme: mgr.GetScheme(),
+		}
+		if err = exporterReconciler.SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "Exporter")
+			os.Exit(1)
+		}
+		if err = (&controller.ClientReconciler{
+			Client: mgr.GetClient(),
+			Scheme: mgr.GetScheme(),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "Identity")
+			os.Exit(1)
+		}
+		allocator, err := controller.NewAllocator(allocatorName)
+		if err != nil {
+			setupLog.Error(err, "unable to create allocator", "allocator", allocatorName)
+			os.Exit(1)
+		}
+		labelWeights, err := controller.ParseLabelWeights(allocatorLabelWeights)
+		if err != nil {
+			setupLog.Error(err, "invalid allocator label weights")
+			os.Exit(1)
+		}
+		scorers, err := controller.ParseScorePlugins(allocatorScorers, controller.ScorerOptions{
+			UsageWindow:  allocatorUsageWindow,
+			LabelWeights: labelWeights,
+		})
+		if err != nil {
+			setupLog.Error(err, "invalid allocator score plugins")
+			os.Exit(1)
+		}
+		allocator.Scorers = append(allocator.Scorers, scorers...)
+		var shadowAllocator *controller.Allocator
+		if shadowAllocatorName != "" {
+			shadowAllocator, err = controller.NewAllocator(shadowAllocatorName)
+			if err != nil {
+				setupLog.Error(err, "unable to create shadow allocator", "allocator", shadowAllocatorName)
+				os.Exit(1)
+			}
+		}
+		if err = (&controller.ExporterUpdatePolicyReconciler{
+			Client: mgr.GetClient(),
+			Scheme: mgr.GetScheme(),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ExporterUpdatePolicy")
+			os.Exit(1)
+		}
+		if err = (&controller.ExporterAccessPolicyReconciler{
+			Client:               mgr.GetClient(),
+			Scheme:               mgr.GetScheme(),
+			AccessPolicyTieBreak: policyTieBreak,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ExporterAccessPolicy")
+			os.Exit(1)
+		}
+		exporterIndex := controller.NewExporterLabelIndex()
+		if err = exporterIndex.SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create exporter label index")
+			os.Exit(1)
+		}
+		leaseReconciler := &controller.LeaseReconciler{
+			Client:                    mgr.GetClient(),
+			Scheme:                    mgr.GetScheme(),
+			Allocator:                 allocator,
+			ShadowAllocator:           shadowAllocator,
+			ExporterIndex:             exporterIndex,
+			Recorder:                  mgr.GetEventRecorderFor("lease-controller"),
+			OfflineRetryWindow:        offlineRetryWindow,
+			WaitForExporterMaxBackoff: waitForExporterMaxBackoff,
+			WaitForExporterMinBackoff: waitForExporterMinBackoff,
+			QueueRetryInterval:        leaseQueueRetryInterval,
+			OfflineRetryInterval:      leaseOfflineRetryInterval,
+			QuotaRetryInterval:        leaseQuotaRetryInterval,
+			MaxConcurrentReconciles:   leaseMaxConcurrentReconciles,
+			Preemption:                features.DefaultGate.Enabled(features.Preemption),
+			AccessPolicyTieBreak:      policyTieBreak,
+			LeaseRecords:              leaseRecordRetention > 0,
+			PreemptionGracePeriod:     preemptionGracePeriod,
+			EndingNotice:              leaseEndingNotice,
+			DurationLimits:            leaseDurationLimits,
+			AcquisitionTimeout:        leaseAcquisitionTimeout,
+		}
+		if err = leaseReconciler.SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "Lease")
+			os.Exit(1)
+		}
+		if err = (&controller.RecurringLeaseReconciler{
+			Client:   mgr.GetClient(),
+			Scheme:   mgr.GetScheme(),
+			Recorder: mgr.GetEventRecorderFor("recurringlease-controller"),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "RecurringLease")
+			os.Exit(1)
+		}
+		if leaseRecordRetention > 0 {
+			if err = (&controller.LeaseRecordReconciler{
+				Client:    mgr.GetClient(),
+				Scheme:    mgr.GetScheme(),
+				Retention: leaseRecordRetention,
+			}).SetupWithManager(mgr); err != nil {
+				setupLog.Error(err, "unable to create controller", "controller", "LeaseRecord")
+				os.Exit(1)
+			}
+		}
+		if consistencyCheckInterval > 0 {
+			if err = (&controller.ConsistencyChecker{
+				Client:    mgr.GetClient(),
+				Exporters: exporterReconciler,
+				Leases:    leaseReconciler,
+				Interval:  consistencyCheckInterval,
+				Grace:     time.Minute,
+				Repair:    consistencyCheckRepair,
+			}).SetupWithManager(mgr); err != nil {
+				setupLog.Error(err, "unable to set up consistency checker")
+				os.Exit(1)
+			}
+		}
+		if endedLeaseTTL > 0 {
+			if err = (&controller.LeaseGarbageCollector{
+				Client:   mgr.GetClient(),
+				Recorder: mgr.GetEventRecorderFor("lease-garbage-collector"),
+				TTL:      endedLeaseTTL,
+				Interval: min(endedLeaseTTL, 10*time.Minute),
+				Record:   leaseRecordRetention > 0,
+			}).SetupWithManager(mgr); err != nil {
+				setupLog.Error(err, "unable to set up lease garbage collector")
+				os.Exit(1)
+			}
+		}
+	}
+	// +kubebuilder:scaffold:builder
+
+	routerKey, err := service.NewRouterKeyFromEnv()
+	if err != nil {
+		setupLog.Error(err, "unable to load router key")
 		os.Exit(1)
 	}
-	if err = (&controller.ClientReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Identity")
+	if slices.Contains(roles, roleAPI) || slices.Contains(roles, roleRouter) {
+		if err := routerKey.SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to set up router key reloading")
+			os.Exit(1)
+		}
+	}
+
+	var certificateAuth *service.CertificateAuth
+	if certificateAuthConfig != "" {
+		certificateAuth, err = service.LoadCertificateAuth(certificateAuthConfig)
+		if err != nil {
+			setupLog.Error(err, "unable to load certificate authentication configuration")
+			os.Exit(1)
+		}
+	}
+
+	if slices.Contains(roles, roleAPI) {
+		// checks whether leases are satisfiable, which the score plugins do not change
+		checkAllocator, err := controller.NewAllocator(allocatorName)
+		if err != nil {
+			setupLog.Error(err, "unable to create allocator", "allocator", allocatorName)
+			os.Exit(1)
+		}
+		controllerService := &service.ControllerService{
+			RestrictExporterVisibility: restrictExporterVisibility,
+			Keepalive:                  keepalive,
+			DisabledEndpoints:          controllerDisabledEndpoints,
+			AuthExemptions:             &authExemptions,
+			RouterKey:                  routerKey,
+			CertificateAuth:            certificateAuth,
+			SelfSignedCertificate:      selfSignedCertificate,
+			ListExportersCacheTTL:      listExportersCacheTTL,
+			MaxDialTimeout:             maxDialTimeout,
+			RegisterLimits:             registerLimits,
+			Allocator:                  checkAllocator,
+			DurationLimits:             leaseDurationLimits,
+			AccessPolicyTieBreak:       policyTieBreak,
+		}
+		if transferConfig != "" {
+			controllerService.Transfers, err = service.LoadTransferConfig(transferConfig)
+			if err != nil {
+				setupLog.Error(err, "unable to load transfer configuration")
+				os.Exit(1)
+			}
+		}
+		if registrationWebhookURL != "" {
+			controllerService.RegistrationWebhook = &service.RegistrationWebhook{
+				URL:    registrationWebhookURL,
+				Secret: []byte(os.Getenv("REGISTRATION_WEBHOOK_SECRET")),
+			}
+		}
+		if controllerListenersConfig != "" {
+			controllerService.Listeners, err = service.LoadControllerListeners(controllerListenersConfig)
+			if err != nil {
+				setupLog.Error(err, "unable to load controller listeners configuration")
+				os.Exit(1)
+			}
+		}
+		if interceptorsConfig != "" {
+			controllerService.Interceptors, err = interceptors.Load(interceptorsConfig)
+			if err != nil {
+				setupLog.Error(err, "unable to load interceptors configuration")
+				os.Exit(1)
+			}
+		}
+		if disabledLegacyLeaseNamespaces != "" {
+			controllerService.DisabledLegacyLeaseNamespaces = strings.Split(disabledLegacyLeaseNamespaces, ",")
+		}
+		setupAPI(mgr, dashboardAddr, enableConsoleAPI, controllerService)
+	}
+	if enableLeaseWebhook {
+		if err := webhookv1alpha1.SetupLeaseWebhookWithManager(mgr, leaseDurationLimits); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Lease")
+			os.Exit(1)
+		}
+		if err := mgr.Add(&webhookv1alpha1.CABundleInjector{Client: mgr.GetClient(), CA: webhookCA}); err != nil {
+			setupLog.Error(err, "unable to set up webhook CA bundle injection")
+			os.Exit(1)
+		}
+	}
+	if slices.Contains(roles, roleRouter) {
+		setupRouter(mgr, &recorder, &service.RouterService{
+			Keepalive:             keepalive,
+			DisabledEndpoints:     routerDisabledEndpoints,
+			RouterKey:             routerKey,
+			CertificateAuth:       certificateAuth,
+			SelfSignedCertificate: selfSignedCertificate,
+			FlowControl: service.FlowControlPolicy{
+				StreamWindow:     int32(routerStreamWindow),
+				ConnectionWindow: int32(routerConnectionWindow),
+				MaxFrameSize:     routerMaxFrameSize,
+			},
+		})
+	}
+
+	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
 	}
-	if err = (&controller.LeaseReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Lease")
+	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
-	// +kubebuilder:scaffold:builder
 
-	watchClient, err := client.NewWithWatch(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+	setupLog.Info("starting manager")
+	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+		setupLog.Error(err, "problem running manager")
+		os.Exit(1)
+	}
+}
+
+// provisionWebhookCertificate writes the serving certificate of the webhook server to a directory,
+// before the manager and its client exist, and returns the directory and the certificate to trust
+func provisionWebhookCertificate() (string, []byte) {
+	namespace := os.Getenv("NAMESPACE")
+	if namespace == "" {
+		setupLog.Error(nil, "the NAMESPACE environment variable is required by the lease webhook")
+		os.Exit(1)
+	}
+	c, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
 	if err != nil {
-		setupLog.Error(err, "unable to create client with watch", "service", "Controller")
+		setupLog.Error(err, "unable to create client for the webhook serving certificate")
+		os.Exit(1)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	dir, ca, err := webhookv1alpha1.ProvisionServingCertificate(ctx, c, namespace)
+	if err != nil {
+		setupLog.Error(err, "unable to provision the webhook serving certificate")
 		os.Exit(1)
 	}
+	return dir, ca
+}
 
-	if err = (&service.ControllerService{
-		Client: watchClient,
-		Scheme: mgr.GetScheme(),
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create service", "service", "Controller")
+// managerCacheOptions scopes the ConfigMaps and Secrets cached by the manager to the namespace of
+// the controller, the only ones read through its client: the router registry, the serving CA and
+// the self-signed certificates, the others are read uncached
+func managerCacheOptions() cache.Options {
+	namespace := os.Getenv("NAMESPACE")
+	if namespace == "" {
+		return cache.Options{}
+	}
+	namespaced := cache.ByObject{Namespaces: map[string]cache.Config{namespace: {}}}
+	return cache.Options{ByObject: map[client.Object]cache.ByObject{
+		&corev1.ConfigMap{}: namespaced,
+		&corev1.Secret{}:    namespaced,
+	}}
+}
+
+func setupAPI(mgr ctrl.Manager, dashboardAddr string, consoleAPI bool, controllerService *service.ControllerService) {
+	watchClient, err := client.NewWithWatch(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+	if err != nil {
+		setupLog.Error(err, "unable to create client with watch", "service", "Controller")
 		os.Exit(1)
 	}
 
-	if err = (&service.RouterService{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create service", "service", "Router")
+	controllerService.Client = watchClient
+	// a namespaced informer, instead of getting the router registry on every dial
+	controllerService.RouterRegistry = mgr.GetClient()
+	controllerService.Scheme = mgr.GetScheme()
+	controllerService.Events = mgr.GetEventRecorderFor("controller-service")
+	if err = controllerService.SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create service", "service", "Controller")
 		os.Exit(1)
 	}
 
 	if err = (&service.DashboardService{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:      mgr.GetClient(),
+		Scheme:      mgr.GetScheme(),
+		BindAddress: dashboardAddr,
+		ConsoleAPI:  consoleAPI,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create service", "service", "Dashboard")
 		os.Exit(1)
 	}
 
-	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
-		setupLog.Error(err, "unable to set up health check")
+	// only report ready once the gRPC service is accepting requests
+	if err := mgr.AddReadyzCheck("controller-grpc", controllerService.ReadyzCheck); err != nil {
+		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
-	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
-		setupLog.Error(err, "unable to set up ready check")
+}
+
+func setupRouter(mgr ctrl.Manager, recorder *service.StreamRecorder, routerService *service.RouterService) {
+	routerService.Client = mgr.GetClient()
+	routerService.Scheme = mgr.GetScheme()
+	routerService.Events = mgr.GetEventRecorderFor("router")
+	if recorder.Dir != "" {
+		if err := recorder.SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create stream recorder")
+			os.Exit(1)
+		}
+		routerService.Recorder = recorder
+	}
+	if err := routerService.SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create service", "service", "Router")
 		os.Exit(1)
 	}
 
-	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
-		setupLog.Error(err, "problem running manager")
+	// only report ready once the gRPC service is accepting requests
+	if err := mgr.AddReadyzCheck("router-grpc", routerService.ReadyzCheck); err != nil {
+		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
 }
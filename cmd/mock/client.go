@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	pb "github.com/jumpstarter-dev/jumpstarter-controller/internal/protocol/jumpstarter/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// runClient plays a client against the controller at endpoint: it requests a lease of the
+// exporters matching selector, dials the leased exporter and releases the lease after duration,
+// so that exporters can be tested without a client
+func runClient(
+	ctx context.Context,
+	endpoint string,
+	token string,
+	selector map[string]string,
+	duration time.Duration,
+) error {
+	conn, err := grpc.NewClient(endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return fmt.Errorf("runClient: %w", err)
+	}
+	defer conn.Close()
+
+	controller := pb.NewControllerServiceClient(conn)
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+
+	lease, err := controller.RequestLease(ctx, &pb.RequestLeaseRequest{
+		Duration: durationpb.New(duration),
+		Selector: &pb.LabelSelector{MatchLabels: selector},
+	})
+	if err != nil {
+		return fmt.Errorf("runClient: failed to request lease: %w", err)
+	}
+	log.Printf("client: requested lease %s", lease.Name)
+
+	// the lease is released when the client is done, whatever the outcome
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+		if _, err := controller.ReleaseLease(ctx, &pb.ReleaseLeaseRequest{Name: lease.Name}); err != nil {
+			log.Printf("client: unable to release lease %s: %s", lease.Name, err)
+			return
+		}
+		log.Printf("client: released lease %s", lease.Name)
+	}()
+
+	for {
+		status, err := controller.GetLease(ctx, &pb.GetLeaseRequest{Name: lease.Name})
+		if err != nil {
+			return fmt.Errorf("runClient: failed to get lease: %w", err)
+		}
+		if status.ExporterUuid != nil {
+			log.Printf("client: acquired exporter %s", status.GetExporterUuid())
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Second):
+		}
+	}
+
+	dial, err := controller.Dial(ctx, &pb.DialRequest{LeaseName: lease.Name}, grpc.WaitForReady(true))
+	if err != nil {
+		return fmt.Errorf("runClient: failed to dial: %w", err)
+	}
+	log.Printf("client: dialed, router endpoint %s, router token %s", dial.RouterEndpoint, dial.RouterToken)
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(duration):
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/internal/controller"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// leaseInterval is how often the mock allocates and expires leases
+const leaseInterval = time.Second
+
+// runLeases stands in for the lease and exporter controllers, which need server-side apply the
+// fake client does not support: it allocates pending leases with the default allocator and
+// ends released and expired leases, until ctx is done
+func runLeases(ctx context.Context, c client.Client) {
+	allocator := controller.NewDefaultAllocator()
+	ticker := time.NewTicker(leaseInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		var leases jumpstarterdevv1alpha1.LeaseList
+		if err := c.List(ctx, &leases, client.InNamespace(namespace), controller.MatchingActiveLeases()); err != nil {
+			log.Printf("unable to list leases: %s", err)
+			continue
+		}
+
+		for i := range leases.Items {
+			lease := &leases.Items[i]
+			var err error
+			if lease.Status.ExporterRef == nil {
+				err = allocateLease(ctx, c, allocator, lease, leases.Items)
+			} else {
+				err = expireLease(ctx, c, lease)
+			}
+			if err != nil {
+				log.Printf("unable to reconcile lease %s: %s", lease.Name, err)
+			}
+		}
+	}
+}
+
+func allocateLease(
+	ctx context.Context,
+	c client.Client,
+	allocator *controller.Allocator,
+	lease *jumpstarterdevv1alpha1.Lease,
+	leases []jumpstarterdevv1alpha1.Lease,
+) error {
+	if lease.Spec.Release {
+		return endLease(ctx, c, lease)
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(&lease.Spec.Selector)
+	if err != nil {
+		return err
+	}
+
+	var exporters jumpstarterdevv1alpha1.ExporterList
+	if err := c.List(ctx, &exporters, client.InNamespace(namespace), client.MatchingLabelsSelector{
+		Selector: selector,
+	}); err != nil {
+		return err
+	}
+
+	state := &controller.AllocationState{
+		Lease:        lease,
+		ActiveLeases: leases,
+	}
+	var leaseClient jumpstarterdevv1alpha1.Client
+	if err := c.Get(ctx, types.NamespacedName{
+		Namespace: namespace,
+		Name:      lease.Spec.ClientRef.Name,
+	}, &leaseClient); err == nil {
+		state.Client = &leaseClient
+	}
+
+	allocation, err := allocator.Allocate(ctx, state, exporters.Items)
+	if err != nil {
+		return err
+	}
+	if allocation.Exporter == nil {
+		return nil
+	}
+
+	now := metav1.Now()
+	lease.Status.ExporterRef = &corev1.LocalObjectReference{Name: allocation.Exporter.Name}
+	lease.Status.BeginTime = &now
+	meta.SetStatusCondition(&lease.Status.Conditions, metav1.Condition{
+		Type:   string(jumpstarterdevv1alpha1.LeaseConditionTypeReady),
+		Status: metav1.ConditionTrue,
+		Reason: "Ready",
+	})
+	if err := c.Status().Update(ctx, lease); err != nil {
+		return err
+	}
+
+	allocation.Exporter.Status.LeaseRef = &corev1.LocalObjectReference{Name: lease.Name}
+	if err := c.Status().Update(ctx, allocation.Exporter); err != nil {
+		return err
+	}
+
+	log.Printf("lease %s acquired exporter %s", lease.Name, allocation.Exporter.Name)
+	return nil
+}
+
+func expireLease(ctx context.Context, c client.Client, lease *jumpstarterdevv1alpha1.Lease) error {
+	if lease.Spec.Release || time.Now().After(lease.Status.BeginTime.Add(lease.Spec.Duration.Duration)) {
+		return endLease(ctx, c, lease)
+	}
+	return nil
+}
+
+func endLease(ctx context.Context, c client.Client, lease *jumpstarterdevv1alpha1.Lease) error {
+	now := metav1.Now()
+	lease.Status.Ended = true
+	lease.Status.EndTime = &now
+	meta.SetStatusCondition(&lease.Status.Conditions, metav1.Condition{
+		Type:   string(jumpstarterdevv1alpha1.LeaseConditionTypeReady),
+		Status: metav1.ConditionFalse,
+		Reason: "Expired",
+	})
+	if err := c.Status().Update(ctx, lease); err != nil {
+		return err
+	}
+
+	// ended leases are no longer listed as active
+	if lease.Labels == nil {
+		lease.Labels = map[string]string{}
+	}
+	lease.Labels[string(jumpstarterdevv1alpha1.LeaseLabelEnded)] = jumpstarterdevv1alpha1.LeaseLabelEndedValue
+	if err := c.Update(ctx, lease); err != nil {
+		return err
+	}
+
+	if lease.Status.ExporterRef != nil {
+		var exporter jumpstarterdevv1alpha1.Exporter
+		if err := c.Get(ctx, types.NamespacedName{
+			Namespace: namespace,
+			Name:      lease.Status.ExporterRef.Name,
+		}, &exporter); err != nil {
+			return err
+		}
+		exporter.Status.LeaseRef = nil
+		if err := c.Status().Update(ctx, &exporter); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("lease %s ended", lease.Name)
+	return nil
+}
@@ -1,17 +1,21 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
 	"net"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
 	"github.com/jumpstarter-dev/jumpstarter-controller/internal/controller"
 	pb "github.com/jumpstarter-dev/jumpstarter-controller/internal/protocol/jumpstarter/v1"
 	"github.com/jumpstarter-dev/jumpstarter-controller/internal/service"
 	"google.golang.org/grpc"
-	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -37,56 +41,67 @@ func init() {
 }
 
 func main() {
-	server := grpc.NewServer()
+	var scenarioPath string
+	var listenAddr string
+	var clientEndpoint string
+	var clientToken string
+	var clientSelector string
+	var clientDuration time.Duration
+	flag.StringVar(&scenarioPath, "scenario", "", "The scenario file scripting the mock cluster, see Scenario")
+	flag.StringVar(&listenAddr, "listen", ":8083", "The address the mock controller and router listen on")
+	flag.StringVar(&clientEndpoint, "client", "",
+		"If set, run as a mock client of the controller at this address instead of as a mock controller")
+	flag.StringVar(&clientToken, "token", os.Getenv("JMP_TOKEN"), "The token of the mock client")
+	flag.StringVar(&clientSelector, "selector", "", "The label selector of the mock client lease, e.g. dut=a")
+	flag.DurationVar(&clientDuration, "duration", time.Minute, "The duration of the mock client lease")
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if clientEndpoint != "" {
+		selector, err := labels.ConvertSelectorToLabelsMap(clientSelector)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := runClient(ctx, clientEndpoint, clientToken, selector, clientDuration); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
-	exporter := jumpstarterdevv1alpha1.Exporter{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "exporter-sample",
-			Namespace: namespace,
-		},
-		Status: jumpstarterdevv1alpha1.ExporterStatus{
-			Credential: &corev1.LocalObjectReference{
-				Name: "exporter-sample-token",
-			},
-		},
+	scenario, err := loadScenario(scenarioPath)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	exporterToken, err := controller.SignObjectToken(
-		"https://jumpstarter.dev/controller",
-		[]string{"https://jumpstarter.dev/controller"},
-		&exporter,
-		scheme,
-	)
-	utilruntime.Must(err)
-
-	log.Println("exporter token:", exporterToken)
-
-	client := jumpstarterdevv1alpha1.Client{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "identity-sample",
-			Namespace: namespace,
-		},
-		Status: jumpstarterdevv1alpha1.ClientStatus{
-			Credential: &corev1.LocalObjectReference{
-				Name: "identity-sample-token",
-			},
-		},
+	objects := scenario.objects()
+	for _, object := range objects {
+		token, err := controller.SignObjectToken(
+			"https://jumpstarter.dev/controller",
+			[]string{"https://jumpstarter.dev/controller"},
+			object,
+			scheme,
+		)
+		utilruntime.Must(err)
+
+		kind := "exporter"
+		if _, ok := object.(*jumpstarterdevv1alpha1.Client); ok {
+			kind = "client"
+		}
+		log.Printf("%s %s token: %s", kind, object.GetName(), token)
 	}
 
-	clientToken, err := controller.SignObjectToken(
-		"https://jumpstarter.dev/controller",
-		[]string{"https://jumpstarter.dev/controller"},
-		&client,
-		scheme,
-	)
-	utilruntime.Must(err)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).WithStatusSubresource(
+		&jumpstarterdevv1alpha1.Exporter{},
+		&jumpstarterdevv1alpha1.Client{},
+		&jumpstarterdevv1alpha1.Lease{},
+	).Build()
 
-	log.Println("client token:", clientToken)
+	go scenario.run(ctx, c)
+	go runLeases(ctx, c)
 
-	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
-		&exporter,
-		&client,
-	).WithStatusSubresource(&exporter).Build()
+	server := grpc.NewServer()
 
 	pb.RegisterControllerServiceServer(server, &service.ControllerService{
 		Client: c,
@@ -98,10 +113,17 @@ func main() {
 		Scheme: scheme,
 	})
 
-	listener, err := net.Listen("tcp", ":8083")
+	listener, err := net.Listen("tcp", listenAddr)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	log.Fatal(server.Serve(listener))
+	go func() {
+		<-ctx.Done()
+		server.Stop()
+	}()
+
+	if err := server.Serve(listener); err != nil {
+		log.Fatal(err)
+	}
 }
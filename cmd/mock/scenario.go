@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// Scenario scripts the state of the mock cluster, e.g.
+//
+//	exporters:
+//	- name: exporter-sample
+//	  labels: {dut: a}
+//	  online: true
+//	clients:
+//	- name: identity-sample
+//	steps:
+//	- after: 10s
+//	  lease: {name: lease-sample, client: identity-sample, selector: {dut: a}, duration: 30s}
+//	- after: 20s
+//	  exporter: {name: exporter-sample, online: false}
+//	- after: 25s
+//	  release: lease-sample
+type Scenario struct {
+	Exporters []ScenarioExporter `json:"exporters,omitempty"`
+	Clients   []ScenarioClient   `json:"clients,omitempty"`
+	// Steps are applied in order, each at its time since the start of the mock
+	Steps []ScenarioStep `json:"steps,omitempty"`
+}
+
+type ScenarioExporter struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+	// Whether the exporter starts registered and online, it also comes online by registering
+	Online bool `json:"online,omitempty"`
+}
+
+type ScenarioClient struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// ScenarioStep sets exactly one of Exporter, Lease and Release
+type ScenarioStep struct {
+	After metav1.Duration `json:"after"`
+	// Changes the labels or the online state of an exporter
+	Exporter *ScenarioExporterStep `json:"exporter,omitempty"`
+	// Creates a lease, as if requested by the client
+	Lease *ScenarioLeaseStep `json:"lease,omitempty"`
+	// Releases the lease with this name
+	Release string `json:"release,omitempty"`
+}
+
+type ScenarioExporterStep struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Online *bool             `json:"online,omitempty"`
+}
+
+type ScenarioLeaseStep struct {
+	Name     string            `json:"name"`
+	Client   string            `json:"client"`
+	Selector map[string]string `json:"selector,omitempty"`
+	Duration metav1.Duration   `json:"duration"`
+}
+
+// defaultScenario is the scenario of the mock without a scenario file
+var defaultScenario = Scenario{
+	Exporters: []ScenarioExporter{{Name: "exporter-sample"}},
+	Clients:   []ScenarioClient{{Name: "identity-sample"}},
+}
+
+func loadScenario(path string) (*Scenario, error) {
+	if path == "" {
+		scenario := defaultScenario
+		return &scenario, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("loadScenario: %w", err)
+	}
+
+	var scenario Scenario
+	if err := yaml.UnmarshalStrict(data, &scenario); err != nil {
+		return nil, fmt.Errorf("loadScenario: invalid scenario %s: %w", path, err)
+	}
+	return &scenario, nil
+}
+
+// objects returns the initial objects of the scenario
+func (s *Scenario) objects() []client.Object {
+	var objects []client.Object
+	for _, e := range s.Exporters {
+		exporter := &jumpstarterdevv1alpha1.Exporter{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      e.Name,
+				Namespace: namespace,
+				Labels:    e.Labels,
+			},
+			Status: jumpstarterdevv1alpha1.ExporterStatus{
+				Credential: &corev1.LocalObjectReference{
+					Name: e.Name + "-token",
+				},
+			},
+		}
+		if e.Online {
+			setExporterOnline(exporter, true)
+		}
+		objects = append(objects, exporter)
+	}
+	for _, c := range s.Clients {
+		objects = append(objects, &jumpstarterdevv1alpha1.Client{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      c.Name,
+				Namespace: namespace,
+				Labels:    c.Labels,
+			},
+			Status: jumpstarterdevv1alpha1.ClientStatus{
+				Credential: &corev1.LocalObjectReference{
+					Name: c.Name + "-token",
+				},
+			},
+		})
+	}
+	return objects
+}
+
+// run applies the steps of the scenario on their timeline, until ctx is done
+func (s *Scenario) run(ctx context.Context, c client.Client) {
+	start := time.Now()
+	for i, step := range s.Steps {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(start.Add(step.After.Duration))):
+		}
+
+		if err := step.apply(ctx, c); err != nil {
+			log.Printf("scenario step %d failed: %s", i, err)
+		}
+	}
+}
+
+func (step *ScenarioStep) apply(ctx context.Context, c client.Client) error {
+	switch {
+	case step.Exporter != nil:
+		var exporter jumpstarterdevv1alpha1.Exporter
+		if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: step.Exporter.Name}, &exporter); err != nil {
+			return err
+		}
+		if step.Exporter.Labels != nil {
+			exporter.Labels = step.Exporter.Labels
+			if err := c.Update(ctx, &exporter); err != nil {
+				return err
+			}
+		}
+		if step.Exporter.Online != nil {
+			setExporterOnline(&exporter, *step.Exporter.Online)
+			if err := c.Status().Update(ctx, &exporter); err != nil {
+				return err
+			}
+		}
+		log.Printf("scenario: updated exporter %s", exporter.Name)
+	case step.Lease != nil:
+		lease := &jumpstarterdevv1alpha1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      step.Lease.Name,
+				Namespace: namespace,
+			},
+			Spec: jumpstarterdevv1alpha1.LeaseSpec{
+				ClientRef: corev1.LocalObjectReference{Name: step.Lease.Client},
+				Duration:  step.Lease.Duration,
+				Selector:  metav1.LabelSelector{MatchLabels: step.Lease.Selector},
+			},
+		}
+		if err := c.Create(ctx, lease); err != nil {
+			return err
+		}
+		log.Printf("scenario: created lease %s", lease.Name)
+	case step.Release != "":
+		var lease jumpstarterdevv1alpha1.Lease
+		if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: step.Release}, &lease); err != nil {
+			return err
+		}
+		lease.Spec.Release = true
+		if err := c.Update(ctx, &lease); err != nil {
+			return err
+		}
+		log.Printf("scenario: released lease %s", lease.Name)
+	default:
+		return fmt.Errorf("empty step")
+	}
+	return nil
+}
+
+func setExporterOnline(exporter *jumpstarterdevv1alpha1.Exporter, online bool) {
+	status := metav1.ConditionFalse
+	if online {
+		status = metav1.ConditionTrue
+	}
+	for _, conditionType := range []jumpstarterdevv1alpha1.LeaseConditionType{
+		jumpstarterdevv1alpha1.ExporterConditionTypeRegistered,
+		jumpstarterdevv1alpha1.ExporterConditionTypeOnline,
+	} {
+		meta.SetStatusCondition(&exporter.Status.Conditions, metav1.Condition{
+			Type:   string(conditionType),
+			Status: status,
+			Reason: "Scenario",
+		})
+	}
+}
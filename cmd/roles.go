@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	// roleAPI runs the controller gRPC service and the dashboard
+	roleAPI = "api"
+	// roleReconciler runs the exporter, client and lease reconcilers
+	roleReconciler = "reconciler"
+	// roleRouter runs the router gRPC service
+	roleRouter = "router"
+	// roleAll runs every role in a single process
+	roleAll = "all"
+)
+
+var knownRoles = []string{roleAPI, roleReconciler, roleRouter}
+
+// parseRoles parses a comma separated list of roles, all expands to every known role
+func parseRoles(value string) ([]string, error) {
+	var roles []string
+	for _, role := range strings.Split(value, ",") {
+		role = strings.TrimSpace(role)
+		switch {
+		case role == roleAll:
+			roles = append(roles, knownRoles...)
+		case slices.Contains(knownRoles, role):
+			roles = append(roles, role)
+		default:
+			return nil, fmt.Errorf("parseRoles: unknown role %q, expected one of %s or %s",
+				role, strings.Join(knownRoles, ", "), roleAll)
+		}
+	}
+	slices.Sort(roles)
+	return slices.Compact(roles), nil
+}
+
+// leaderElectionID returns a distinct leader election ID per set of roles, so that
+// replicas running different roles do not compete for the same lock
+func leaderElectionID(base string, roles []string) string {
+	if len(roles) == len(knownRoles) {
+		return base
+	}
+	return strings.Join(roles, "-") + "." + base
+}
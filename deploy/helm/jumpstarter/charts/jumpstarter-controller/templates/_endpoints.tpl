@@ -1,2 +1,52 @@
 {{- define "router.endpoint" }}{{ if .Values.grpc.routerHostname }}{{ .Values.grpc.routerHostname }}{{ else }}router.{{ .Values.global.baseDomain | required "grpc.routerHostname or global.baseDomain must be set"}}{{ end }}{{- end }}
-{{- define "controller.endpoint" }}{{ if .Values.grpc.hostname }}{{ .Values.grpc.hostname }}{{ else }}grpc.{{ .Values.global.baseDomain | required "grpc.hostname or global.baseDomain must be set"}}{{ end }}{{- end }}
\ No newline at end of file
+{{- define "controller.endpoint" }}{{ if .Values.grpc.hostname }}{{ .Values.grpc.hostname }}{{ else }}grpc.{{ .Values.global.baseDomain | required "grpc.hostname or global.baseDomain must be set"}}{{ end }}{{- end }}
+{{- define "controller.tlsMode" }}{{ default .Values.grpc.tls.mode .Values.grpc.tls.controllerMode }}{{- end }}
+{{- define "router.tlsMode" }}{{ default .Values.grpc.tls.mode .Values.grpc.tls.routerMode }}{{- end }}
+{{- /* destination CA for re-encrypting routes, published by the services into a ConfigMap unless explicitly provided */}}
+{{- define "route.destinationCA" }}
+{{- $configmap := lookup "v1" "ConfigMap" (default .context.Release.Namespace .context.Values.namespace) .configmap }}
+{{- if .ca }}
+destinationCACertificate: {{ .ca | toJson }}
+{{- else if $configmap }}
+destinationCACertificate: {{ index $configmap.data "ca.crt" | toJson }}
+{{- end }}
+{{- end }}
+{{- /*
+nodeport.check fails the release when the requested NodePorts collide with each other or with
+NodePorts already allocated to other Services in the cluster, suggesting free ports instead of
+letting the Service creation fail halfway through the install
+*/}}
+{{- define "nodeport.check" }}
+{{- $namespace := default .Release.Namespace .Values.namespace }}
+{{- $requested := dict "grpc.nodeport.port" (int .Values.grpc.nodeport.port) "grpc.nodeport.routerPort" (int .Values.grpc.nodeport.routerPort) }}
+{{- if eq (int .Values.grpc.nodeport.port) (int .Values.grpc.nodeport.routerPort) }}
+{{- fail (printf "grpc.nodeport.port and grpc.nodeport.routerPort must differ, both are set to %d" (int .Values.grpc.nodeport.port)) }}
+{{- end }}
+{{- $used := dict }}
+{{- $services := lookup "v1" "Service" "" "" }}
+{{- range $service := (default (list) $services.items) }}
+{{- $own := and (eq $service.metadata.namespace $namespace) (has $service.metadata.name (list "jumpstarter-grpc" "jumpstarter-router-grpc")) }}
+{{- if not $own }}
+{{- range $port := (default (list) $service.spec.ports) }}
+{{- if $port.nodePort }}
+{{- $_ := set $used (toString $port.nodePort) (printf "%s/%s" $service.metadata.namespace $service.metadata.name) }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- $conflicts := list }}
+{{- range $key, $port := $requested }}
+{{- if hasKey $used (toString $port) }}
+{{- $conflicts = append $conflicts (printf "%s=%d is allocated to Service %s" $key $port (get $used (toString $port))) }}
+{{- end }}
+{{- end }}
+{{- if $conflicts }}
+{{- $free := list }}
+{{- range $port := untilStep 30000 32768 1 }}
+{{- if and (lt (len $free) 2) (not (hasKey $used (toString $port))) (not (has $port (values $requested))) }}
+{{- $free = append $free $port }}
+{{- end }}
+{{- end }}
+{{- fail (printf "NodePort conflict: %s, free ports: %s" (join ", " $conflicts) (join ", " $free)) }}
+{{- end }}
+{{- end }}
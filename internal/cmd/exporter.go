@@ -1,15 +1,21 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
 
 	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/internal/controller"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/cli-runtime/pkg/printers"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -21,6 +27,12 @@ func init() {
 	exporterCmd.AddCommand(exporterCreateCmd)
 	exporterCmd.AddCommand(exporterDeleteCmd)
 	exporterCmd.AddCommand(exporterListCmd)
+	exporterCmd.AddCommand(exporterImportCmd)
+	exporterCmd.AddCommand(exporterLabelsCmd)
+
+	exporterLabelsCmd.AddCommand(exporterLabelsHistoryCmd)
+	exporterLabelsCmd.AddCommand(exporterLabelsRestoreCmd)
+	exporterLabelsCmd.AddCommand(exporterLabelsUnpinCmd)
 }
 
 var exporterCmd = &cobra.Command{
@@ -48,61 +60,67 @@ var exporterCreateCmd = &cobra.Command{
 		if err := clientset.Create(ctx, &exporter); err != nil {
 			return err
 		}
-		watch, err := clientset.Watch(ctx, &jumpstarterdevv1alpha1.ExporterList{}, &client.ListOptions{
-			FieldSelector: fields.OneTermEqualSelector("metadata.name", args[0]),
-			Namespace:     namespace,
-		})
+		exporterConfig, err := waitExporterConfig(ctx, clientset, args[0])
 		if err != nil {
 			return err
 		}
-		for event := range watch.ResultChan() {
-			object := event.Object.(*jumpstarterdevv1alpha1.Exporter)
-			if object.Status.Credential == nil || object.Status.Endpoint == "" {
-				continue
-			}
-			var secret corev1.Secret
-			if err := clientset.Get(
-				ctx,
-				types.NamespacedName{Name: object.Status.Credential.Name, Namespace: namespace},
-				&secret,
-			); err != nil {
-				return err
-			}
-			if secret.Data == nil {
-				return fmt.Errorf("Empty Secret on Exporter %s/%s", namespace, args[0])
-			}
-			token, ok := secret.Data["token"]
-			if !ok {
-				return fmt.Errorf("Missing token in Secret for Exporter %s/%s", namespace, args[0])
-			}
-			exporterConfig := []yaml.MapItem{
-				{
-					Key:   "apiVersion",
-					Value: "jumpstarter.dev/v1alpha1",
-				},
-				{
-					Key:   "kind",
-					Value: "ExporterConfig",
-				},
-				{
-					Key:   "endpoint",
-					Value: object.Status.Endpoint,
-				},
-				{
-					Key:   "token",
-					Value: string(token),
-				},
-			}
-			if err := yaml.NewEncoder(os.Stdout).Encode(&exporterConfig); err != nil {
-				return err
-			}
-			watch.Stop()
-			return nil
-		}
-		return fmt.Errorf("timout waiting for controller to update status for Exporter: %s", args[0])
+		return yaml.NewEncoder(os.Stdout).Encode(&exporterConfig)
 	},
 }
 
+// waitExporterConfig waits for the controller to issue the credential of the exporter named name,
+// and returns the ExporterConfig of the exporter
+func waitExporterConfig(ctx context.Context, clientset client.WithWatch, name string) ([]yaml.MapItem, error) {
+	watch, err := clientset.Watch(ctx, &jumpstarterdevv1alpha1.ExporterList{}, &client.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("metadata.name", name),
+		Namespace:     namespace,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer watch.Stop()
+	for event := range watch.ResultChan() {
+		object, ok := event.Object.(*jumpstarterdevv1alpha1.Exporter)
+		if !ok || object.Status.Credential == nil || object.Status.Endpoint == "" {
+			continue
+		}
+		var secret corev1.Secret
+		if err := clientset.Get(
+			ctx,
+			types.NamespacedName{Name: object.Status.Credential.Name, Namespace: namespace},
+			&secret,
+		); err != nil {
+			return nil, err
+		}
+		if secret.Data == nil {
+			return nil, fmt.Errorf("Empty Secret on Exporter %s/%s", namespace, name)
+		}
+		token, ok := secret.Data["token"]
+		if !ok {
+			return nil, fmt.Errorf("Missing token in Secret for Exporter %s/%s", namespace, name)
+		}
+		return []yaml.MapItem{
+			{
+				Key:   "apiVersion",
+				Value: "jumpstarter.dev/v1alpha1",
+			},
+			{
+				Key:   "kind",
+				Value: "ExporterConfig",
+			},
+			{
+				Key:   "endpoint",
+				Value: object.Status.Endpoint,
+			},
+			{
+				Key:   "token",
+				Value: string(token),
+			},
+		}, nil
+	}
+	return nil, fmt.Errorf("timout waiting for controller to update status for Exporter: %s", name)
+}
+
 var exporterDeleteCmd = &cobra.Command{
 	Use:   "delete [NAME]",
 	Short: "Delete exporter",
@@ -142,3 +160,106 @@ var exporterListCmd = &cobra.Command{
 		return printers.NewTablePrinter(printers.PrintOptions{}).PrintObj(&exporters, os.Stdout)
 	},
 }
+
+var exporterLabelsCmd = &cobra.Command{
+	Use:   "labels",
+	Short: "Manage exporter labels",
+}
+
+var exporterLabelsHistoryCmd = &cobra.Command{
+	Use:   "history [NAME]",
+	Short: "Show the history of labels applied by the exporter",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		clientset, err := NewClient()
+		if err != nil {
+			return err
+		}
+		var exporter jumpstarterdevv1alpha1.Exporter
+		if err := clientset.Get(ctx, types.NamespacedName{
+			Namespace: namespace,
+			Name:      args[0],
+		}, &exporter); err != nil {
+			return err
+		}
+		if revision, pinned := controller.PinnedLabelRevision(&exporter); pinned {
+			fmt.Printf("labels pinned to revision %d\n", revision)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "REVISION\tTIME\tSOURCE\tLABELS")
+		for _, revision := range exporter.Status.LabelHistory {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n",
+				revision.Revision,
+				revision.Time.Format(time.RFC3339),
+				revision.Source,
+				labels.Set(revision.Labels).String(),
+			)
+		}
+		return w.Flush()
+	},
+}
+
+var exporterLabelsRestoreCmd = &cobra.Command{
+	Use:   "restore [NAME] [REVISION]",
+	Short: "Restore the labels of the exporter to a previous revision, and pin them until unpinned",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		revision, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid revision %s: %w", args[1], err)
+		}
+
+		clientset, err := NewClient()
+		if err != nil {
+			return err
+		}
+		var exporter jumpstarterdevv1alpha1.Exporter
+		if err := clientset.Get(ctx, types.NamespacedName{
+			Namespace: namespace,
+			Name:      args[0],
+		}, &exporter); err != nil {
+			return err
+		}
+
+		original := client.MergeFrom(exporter.DeepCopy())
+		if err := controller.RestoreExporterLabels(&exporter, revision); err != nil {
+			return err
+		}
+		if err := clientset.Patch(ctx, &exporter, original); err != nil {
+			return err
+		}
+
+		original = client.MergeFrom(exporter.DeepCopy())
+		controller.RecordExporterLabels(&exporter, controller.LabelSourceRestore)
+		return clientset.Status().Patch(ctx, &exporter, original)
+	},
+}
+
+var exporterLabelsUnpinCmd = &cobra.Command{
+	Use:   "unpin [NAME]",
+	Short: "Unpin the restored labels of the exporter, its next registration applies its own labels again",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		clientset, err := NewClient()
+		if err != nil {
+			return err
+		}
+		var exporter jumpstarterdevv1alpha1.Exporter
+		if err := clientset.Get(ctx, types.NamespacedName{
+			Namespace: namespace,
+			Name:      args[0],
+		}, &exporter); err != nil {
+			return err
+		}
+
+		original := client.MergeFrom(exporter.DeepCopy())
+		controller.UnpinExporterLabels(&exporter)
+		return clientset.Patch(ctx, &exporter, original)
+	},
+}
@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	jmpclient "github.com/jumpstarter-dev/jumpstarter-controller/pkg/client"
+)
+
+var (
+	attachClient             string
+	attachListen             string
+	attachDuration           time.Duration
+	attachCAFile             string
+	attachInsecureSkipVerify bool
+)
+
+func init() {
+	exporterCmd.AddCommand(exporterAttachCmd)
+
+	exporterAttachCmd.Flags().StringVar(&attachClient, "client", "",
+		"The client the lease is acquired for and the exporter dialed as")
+	exporterAttachCmd.Flags().StringVar(&attachListen, "listen", "127.0.0.1:0",
+		"The local address to accept connections on, unix:PATH for a unix socket")
+	exporterAttachCmd.Flags().DurationVar(&attachDuration, "duration", 30*time.Minute,
+		"The duration of the lease")
+	exporterAttachCmd.Flags().StringVar(&attachCAFile, "ca-file", "",
+		"PEM encoded certificates to verify the controller and the router with, the system ones if empty")
+	exporterAttachCmd.Flags().BoolVar(&attachInsecureSkipVerify, "insecure-skip-tls-verify", false,
+		"Do not verify the certificates of the controller and the router")
+	_ = exporterAttachCmd.MarkFlagRequired("client")
+}
+
+// attachTLSConfig returns the TLS configuration to connect to the controller and the router with
+func attachTLSConfig() (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: attachInsecureSkipVerify} // #nosec G402 -- opt-in flag
+	if attachCAFile != "" {
+		ca, err := os.ReadFile(attachCAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificate found in %s", attachCAFile)
+		}
+	}
+	return config, nil
+}
+
+// clientToken returns the endpoint of the controller and the token of the client named name
+func clientToken(ctx context.Context, clientset client.Client, name string) (string, string, error) {
+	var jclient jumpstarterdevv1alpha1.Client
+	if err := clientset.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &jclient); err != nil {
+		return "", "", err
+	}
+	if jclient.Status.Credential == nil || jclient.Status.Endpoint == "" {
+		return "", "", fmt.Errorf("Client %s/%s has no credential yet", namespace, name)
+	}
+	var secret corev1.Secret
+	if err := clientset.Get(
+		ctx,
+		types.NamespacedName{Name: jclient.Status.Credential.Name, Namespace: namespace},
+		&secret,
+	); err != nil {
+		return "", "", err
+	}
+	token, ok := secret.Data["token"]
+	if !ok {
+		return "", "", fmt.Errorf("Missing token in Secret for Client %s/%s", namespace, name)
+	}
+	return jclient.Status.Endpoint, string(token), nil
+}
+
+// acquireAttachLease creates a lease of the exporter for the client and waits for it to be acquired
+func acquireAttachLease(
+	ctx context.Context,
+	clientset client.Client,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+) (*jumpstarterdevv1alpha1.Lease, error) {
+	lease := &jumpstarterdevv1alpha1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "attach-" + exporter.Name + "-",
+			Namespace:    namespace,
+		},
+		Spec: jumpstarterdevv1alpha1.LeaseSpec{
+			ClientRef: corev1.LocalObjectReference{Name: attachClient},
+			Duration:  metav1.Duration{Duration: attachDuration},
+			Selector:  metav1.LabelSelector{MatchLabels: exporter.Labels},
+			// the labels of the exporter may match others, the affinity pins the lease to it
+			Affinity: &jumpstarterdevv1alpha1.LeaseAffinity{
+				ExporterAffinity: &jumpstarterdevv1alpha1.ExporterAffinity{
+					Required: []jumpstarterdevv1alpha1.ExporterAffinityTerm{{
+						ExporterNames: []string{exporter.Name},
+					}},
+				},
+			},
+		},
+	}
+	if err := clientset.Create(ctx, lease); err != nil {
+		return nil, err
+	}
+
+	err := wait.PollUntilContextCancel(ctx, time.Second, true, func(ctx context.Context) (bool, error) {
+		if err := clientset.Get(ctx, client.ObjectKeyFromObject(lease), lease); err != nil {
+			return false, err
+		}
+		if lease.Status.Ended {
+			return false, fmt.Errorf("lease %s ended before being acquired", lease.Name)
+		}
+		return lease.Status.ExporterRef != nil, nil
+	})
+	return lease, err
+}
+
+// attach dials the exporter of lease and forwards the stream to conn until either side ends
+func attach(ctx context.Context, controller *jmpclient.Client, lease *jumpstarterdevv1alpha1.Lease, conn net.Conn) error {
+	defer conn.Close()
+
+	stream, err := controller.DialStream(ctx, lease.Name)
+	if err != nil {
+		return fmt.Errorf("attach: %w", err)
+	}
+	defer stream.Close()
+	stop := context.AfterFunc(ctx, func() { stream.Close() })
+	defer stop()
+
+	errs := make(chan error, 2)
+	go func() {
+		_, err := io.Copy(stream, conn)
+		errs <- err
+	}()
+	go func() {
+		_, err := io.Copy(conn, stream)
+		errs <- err
+	}()
+	return <-errs
+}
+
+var exporterAttachCmd = &cobra.Command{
+	Use:   "attach [NAME]",
+	Short: "Lease an exporter and forward a local socket to it, for troubleshooting",
+	Long: `Lease an exporter for the given client, and forward each connection accepted on the local
+address to a new router stream to the exporter, e.g. to reach the console of a board directly.
+The lease is released when the command is interrupted, the --timeout bounds its acquisition.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		clientset, err := NewClient()
+		if err != nil {
+			return err
+		}
+
+		var exporter jumpstarterdevv1alpha1.Exporter
+		if err := clientset.Get(ctx, types.NamespacedName{Namespace: namespace, Name: args[0]}, &exporter); err != nil {
+			return err
+		}
+		endpoint, token, err := clientToken(ctx, clientset, attachClient)
+		if err != nil {
+			return err
+		}
+		tlsConfig, err := attachTLSConfig()
+		if err != nil {
+			return err
+		}
+
+		network, address := "tcp", attachListen
+		if path, ok := strings.CutPrefix(attachListen, "unix:"); ok {
+			network, address = "unix", path
+		}
+		listener, err := net.Listen(network, address)
+		if err != nil {
+			return err
+		}
+		defer listener.Close()
+
+		lease, err := acquireAttachLease(ctx, clientset, &exporter)
+		// the lease is released whatever the outcome, past the command timeout
+		if lease != nil {
+			defer func() {
+				ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+				defer cancel()
+				patch := client.MergeFrom(lease.DeepCopy())
+				lease.Spec.Release = true
+				if err := clientset.Patch(ctx, lease, patch); err != nil {
+					fmt.Fprintf(os.Stderr, "unable to release lease %s: %s\n", lease.Name, err)
+				}
+			}()
+		}
+		if err != nil {
+			return err
+		}
+
+		// the session lasts until interrupted or the lease ends, not the command timeout
+		ctx, stop := signal.NotifyContext(context.WithoutCancel(ctx), os.Interrupt)
+		defer stop()
+		ctx, cancel := context.WithTimeout(ctx, attachDuration)
+		defer cancel()
+		context.AfterFunc(ctx, func() { listener.Close() })
+
+		controller, err := jmpclient.New(endpoint, jmpclient.Options{
+			Token:     jmpclient.StaticToken(token),
+			TLSConfig: tlsConfig,
+		})
+		if err != nil {
+			return err
+		}
+		defer controller.Close()
+
+		fmt.Fprintf(os.Stderr, "lease %s acquired exporter %s, forwarding %s\n",
+			lease.Name, exporter.Name, listener.Addr())
+		for {
+			local, err := listener.Accept()
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+			go func() {
+				if err := attach(ctx, controller, lease, local); err != nil {
+					fmt.Fprintf(os.Stderr, "connection %s: %s\n", local.RemoteAddr(), err)
+				}
+			}()
+		}
+	},
+}
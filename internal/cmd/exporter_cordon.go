@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/internal/controller"
+)
+
+var drainRelease bool
+
+func init() {
+	exporterCmd.AddCommand(exporterCordonCmd)
+	exporterCmd.AddCommand(exporterUncordonCmd)
+	exporterCmd.AddCommand(exporterDrainCmd)
+
+	exporterDrainCmd.Flags().BoolVar(&drainRelease, "release", false,
+		"Release the active leases of the exporter instead of waiting for them to end")
+}
+
+// setExporterUnschedulable cordons or uncordons the exporter named name, uncordoning also removes its drain
+func setExporterUnschedulable(ctx context.Context, clientset client.Client, name string, unschedulable bool) error {
+	var exporter jumpstarterdevv1alpha1.Exporter
+	if err := clientset.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &exporter); err != nil {
+		return err
+	}
+	if exporter.Spec.Unschedulable == unschedulable && (unschedulable || !exporter.Spec.Drain) {
+		return nil
+	}
+	patch := client.MergeFrom(exporter.DeepCopy())
+	exporter.Spec.Unschedulable = unschedulable
+	if !unschedulable {
+		exporter.Spec.Drain = false
+	}
+	return clientset.Patch(ctx, &exporter, patch)
+}
+
+// drainExporter drains the exporter named name
+func drainExporter(ctx context.Context, clientset client.Client, name string) error {
+	var exporter jumpstarterdevv1alpha1.Exporter
+	if err := clientset.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &exporter); err != nil {
+		return err
+	}
+	if exporter.Spec.Drain {
+		return nil
+	}
+	patch := client.MergeFrom(exporter.DeepCopy())
+	exporter.Spec.Drain = true
+	return clientset.Patch(ctx, &exporter, patch)
+}
+
+// activeExporterLeases returns the active leases holding the exporter named name
+func activeExporterLeases(
+	ctx context.Context,
+	clientset client.Client,
+	name string,
+) ([]jumpstarterdevv1alpha1.Lease, error) {
+	var leases jumpstarterdevv1alpha1.LeaseList
+	if err := clientset.List(ctx, &leases, client.InNamespace(namespace), controller.MatchingActiveLeases()); err != nil {
+		return nil, err
+	}
+	var active []jumpstarterdevv1alpha1.Lease
+	for _, lease := range leases.Items {
+		if !lease.Status.Ended && controller.LeaseHoldsExporter(&lease, name) {
+			active = append(active, lease)
+		}
+	}
+	return active, nil
+}
+
+var exporterCordonCmd = &cobra.Command{
+	Use:   "cordon [NAME]",
+	Short: "Mark the exporter as unschedulable, new leases do not acquire it",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		clientset, err := NewClient()
+		if err != nil {
+			return err
+		}
+		return setExporterUnschedulable(cmd.Context(), clientset, args[0], true)
+	},
+}
+
+var exporterUncordonCmd = &cobra.Command{
+	Use:   "uncordon [NAME]",
+	Short: "Mark the exporter as schedulable again, and remove its drain",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		clientset, err := NewClient()
+		if err != nil {
+			return err
+		}
+		return setExporterUnschedulable(cmd.Context(), clientset, args[0], false)
+	},
+}
+
+var exporterDrainCmd = &cobra.Command{
+	Use:   "drain [NAME]",
+	Short: "Drain the exporter and wait for it to go offline",
+	Long: `Drain the exporter: new leases do not acquire it, and once its active leases ended, or were
+released with --release, it is disconnected and kept offline until uncordoned.
+The wait is bounded by --timeout, the exporter stays draining if it expires.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		clientset, err := NewClient()
+		if err != nil {
+			return err
+		}
+		if err := drainExporter(ctx, clientset, args[0]); err != nil {
+			return err
+		}
+
+		if drainRelease {
+			leases, err := activeExporterLeases(ctx, clientset, args[0])
+			if err != nil {
+				return err
+			}
+			for i := range leases {
+				patch := client.MergeFrom(leases[i].DeepCopy())
+				leases[i].Spec.Release = true
+				if err := clientset.Patch(ctx, &leases[i], patch); err != nil {
+					return err
+				}
+				fmt.Printf("lease %s released\n", leases[i].Name)
+			}
+		}
+
+		if err := wait.PollUntilContextCancel(ctx, time.Second, true, func(ctx context.Context) (bool, error) {
+			var exporter jumpstarterdevv1alpha1.Exporter
+			if err := clientset.Get(ctx, types.NamespacedName{Namespace: namespace, Name: args[0]}, &exporter); err != nil {
+				return false, err
+			}
+			return meta.IsStatusConditionTrue(exporter.Status.Conditions,
+				string(jumpstarterdevv1alpha1.ExporterConditionTypeDrained)), nil
+		}); err != nil {
+			return fmt.Errorf("exporter %s not drained: %w", args[0], err)
+		}
+		fmt.Printf("exporter %s drained\n", args[0])
+		return nil
+	},
+}
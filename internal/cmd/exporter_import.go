@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var importBundle string
+
+func init() {
+	exporterImportCmd.Flags().StringVar(&importBundle, "bundle", "exporters.tar.gz",
+		"Path of the bundle the ExporterConfigs of the imported exporters are written to")
+}
+
+// inventoryRecord is an exporter of an inventory file
+type inventoryRecord struct {
+	Name     string            `json:"name"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Location string            `json:"location,omitempty"`
+	Notes    string            `json:"notes,omitempty"`
+}
+
+var exporterImportCmd = &cobra.Command{
+	Use:   "import [FILE]",
+	Short: "Create exporters from an inventory file",
+	Long: `Create exporters from an inventory file and write their ExporterConfigs to a bundle.
+
+The inventory is either a JSON array of {"name", "labels", "location", "notes"} objects,
+or a CSV file with a name,labels,location,notes header where labels are k=v pairs
+separated by commas. Nothing is created unless every exporter is valid and new.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		records, err := readInventory(args[0])
+		if err != nil {
+			return err
+		}
+
+		clientset, err := NewClient()
+		if err != nil {
+			return err
+		}
+
+		var errs field.ErrorList
+		for i, record := range records {
+			var exporter jumpstarterdevv1alpha1.Exporter
+			err := clientset.Get(ctx, types.NamespacedName{Namespace: namespace, Name: record.Name}, &exporter)
+			if err == nil {
+				errs = append(errs, field.Duplicate(field.NewPath("exporters").Index(i).Child("name"), record.Name))
+			} else if !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+		if len(errs) > 0 {
+			return fmt.Errorf("exporters already exist in namespace %s: %w", namespace, errs.ToAggregate())
+		}
+
+		bundle, err := os.Create(importBundle)
+		if err != nil {
+			return err
+		}
+		defer bundle.Close()
+		gz := gzip.NewWriter(bundle)
+		archive := tar.NewWriter(gz)
+
+		for _, record := range records {
+			exporter := jumpstarterdevv1alpha1.Exporter{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        record.Name,
+					Namespace:   namespace,
+					Labels:      record.Labels,
+					Annotations: map[string]string{},
+				},
+			}
+			if record.Location != "" {
+				exporter.Annotations[jumpstarterdevv1alpha1.ExporterAnnotationLocation] = record.Location
+			}
+			if record.Notes != "" {
+				exporter.Annotations[jumpstarterdevv1alpha1.ExporterAnnotationNotes] = record.Notes
+			}
+			if err := clientset.Create(ctx, &exporter); err != nil {
+				return fmt.Errorf("failed to create exporter %s: %w", record.Name, err)
+			}
+
+			exporterConfig, err := waitExporterConfig(ctx, clientset, record.Name)
+			if err != nil {
+				return err
+			}
+			config, err := yaml.Marshal(&exporterConfig)
+			if err != nil {
+				return err
+			}
+			if err := archive.WriteHeader(&tar.Header{
+				Name:    record.Name + ".yaml",
+				Mode:    0o600,
+				Size:    int64(len(config)),
+				ModTime: time.Now(),
+			}); err != nil {
+				return err
+			}
+			if _, err := archive.Write(config); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "exporter %s created\n", record.Name)
+		}
+
+		if err := archive.Close(); err != nil {
+			return err
+		}
+		if err := gz.Close(); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "exporter configs written to %s\n", importBundle)
+		return nil
+	},
+}
+
+// readInventory reads and validates the inventory file at path, JSON for the .json extension, CSV otherwise
+func readInventory(path string) ([]inventoryRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []inventoryRecord
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		if err := json.NewDecoder(file).Decode(&records); err != nil {
+			return nil, fmt.Errorf("invalid inventory %s: %w", path, err)
+		}
+	} else {
+		records, err = readInventoryCSV(file)
+		if err != nil {
+			return nil, fmt.Errorf("invalid inventory %s: %w", path, err)
+		}
+	}
+
+	var errs field.ErrorList
+	names := map[string]bool{}
+	for i, record := range records {
+		path := field.NewPath("exporters").Index(i)
+		for _, msg := range validation.IsDNS1123Subdomain(record.Name) {
+			errs = append(errs, field.Invalid(path.Child("name"), record.Name, msg))
+		}
+		if names[record.Name] {
+			errs = append(errs, field.Duplicate(path.Child("name"), record.Name))
+		}
+		names[record.Name] = true
+		for key, value := range record.Labels {
+			for _, msg := range validation.IsQualifiedName(key) {
+				errs = append(errs, field.Invalid(path.Child("labels"), key, msg))
+			}
+			for _, msg := range validation.IsValidLabelValue(value) {
+				errs = append(errs, field.Invalid(path.Child("labels").Key(key), value, msg))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid inventory %s: %w", path, errs.ToAggregate())
+	}
+	return records, nil
+}
+
+func readInventoryCSV(r io.Reader) ([]inventoryRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("missing header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, fmt.Errorf("missing name column")
+	}
+	get := func(row []string, column string) string {
+		if i, ok := columns[column]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	var records []inventoryRecord
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		set, err := labels.ConvertSelectorToLabelsMap(get(row, "labels"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid labels of %s: %w", get(row, "name"), err)
+		}
+		record := inventoryRecord{
+			Name:     get(row, "name"),
+			Location: get(row, "location"),
+			Notes:    get(row, "notes"),
+		}
+		if len(set) > 0 {
+			record.Labels = set
+		}
+		records = append(records, record)
+	}
+}
@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/internal/controller"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var extendTieBreak string
+
+func init() {
+	rootCmd.AddCommand(leaseCmd)
+
+	leaseCmd.AddCommand(leaseExtendCmd)
+	leaseCmd.AddCommand(leasePauseCmd)
+	leaseCmd.AddCommand(leaseResumeCmd)
+	leaseCmd.AddCommand(leaseMetadataCmd)
+
+	leaseExtendCmd.Flags().StringVar(&extendTieBreak, "policy-tie-break", string(controller.PolicyTieBreakMostRestrictive),
+		"The tie-break of the access policies of equal priority the controller is configured with, "+
+			"MostRestrictive or MostPermissive")
+
+	leaseMetadataCmd.AddCommand(leaseMetadataListCmd)
+	leaseMetadataCmd.AddCommand(leaseMetadataGetCmd)
+	leaseMetadataCmd.AddCommand(leaseMetadataSetCmd)
+	leaseMetadataCmd.AddCommand(leaseMetadataDeleteCmd)
+}
+
+var leaseCmd = &cobra.Command{
+	Use:   "lease",
+	Short: "Manage leases",
+}
+
+var leaseMetadataCmd = &cobra.Command{
+	Use:   "metadata",
+	Short: "Manage the ephemeral metadata of active leases",
+}
+
+func getLease(ctx context.Context, clientset client.Client, name string) (*jumpstarterdevv1alpha1.Lease, error) {
+	var lease jumpstarterdevv1alpha1.Lease
+	if err := clientset.Get(ctx, types.NamespacedName{
+		Namespace: namespace,
+		Name:      name,
+	}, &lease); err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
+
+var leaseExtendCmd = &cobra.Command{
+	Use:   "extend [NAME] [DURATION]",
+	Short: "Extend the duration of a running lease",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		duration, err := time.ParseDuration(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid duration %s: %w", args[1], err)
+		}
+		var tieBreak controller.PolicyTieBreak
+		if err := tieBreak.Set(extendTieBreak); err != nil {
+			return err
+		}
+
+		clientset, err := NewClient()
+		if err != nil {
+			return err
+		}
+		lease, err := getLease(ctx, clientset, args[0])
+		if err != nil {
+			return err
+		}
+
+		var exporters []jumpstarterdevv1alpha1.Exporter
+		for _, name := range controller.LeaseExporters(lease) {
+			var exporter jumpstarterdevv1alpha1.Exporter
+			if err := clientset.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &exporter); err != nil {
+				return err
+			}
+			exporters = append(exporters, exporter)
+		}
+
+		var leaseClient *jumpstarterdevv1alpha1.Client
+		var c jumpstarterdevv1alpha1.Client
+		if err := clientset.Get(ctx, types.NamespacedName{
+			Namespace: namespace,
+			Name:      lease.Spec.ClientRef.Name,
+		}, &c); err == nil {
+			leaseClient = &c
+		} else if !apierrors.IsNotFound(err) {
+			return err
+		}
+
+		policies, err := controller.ListAccessPolicies(ctx, clientset, namespace)
+		if err != nil {
+			return err
+		}
+		var leases jumpstarterdevv1alpha1.LeaseList
+		if err := clientset.List(ctx, &leases, client.InNamespace(namespace), controller.MatchingActiveLeases()); err != nil {
+			return err
+		}
+
+		if err := controller.ExtendLease(
+			lease,
+			duration,
+			policies,
+			tieBreak,
+			leaseClient,
+			exporters,
+			leases.Items,
+			time.Now(),
+		); err != nil {
+			return err
+		}
+		// conditional on the resource version, the checks must not be made against a stale lease
+		return clientset.Update(ctx, lease)
+	},
+}
+
+var leasePauseCmd = &cobra.Command{
+	Use:   "pause [NAME]",
+	Short: "Pause the countdown of the duration of a running lease",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setLeasePaused(cmd.Context(), args[0], true)
+	},
+}
+
+var leaseResumeCmd = &cobra.Command{
+	Use:   "resume [NAME]",
+	Short: "Resume the countdown of the duration of a paused lease",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setLeasePaused(cmd.Context(), args[0], false)
+	},
+}
+
+func setLeasePaused(ctx context.Context, name string, paused bool) error {
+	clientset, err := NewClient()
+	if err != nil {
+		return err
+	}
+	lease, err := getLease(ctx, clientset, name)
+	if err != nil {
+		return err
+	}
+	if lease.Status.Ended || lease.Status.BeginTime == nil {
+		return fmt.Errorf("lease %s is not running", name)
+	}
+	original := client.MergeFrom(lease.DeepCopy())
+	lease.Spec.Paused = paused
+	return clientset.Patch(ctx, lease, original)
+}
+
+var leaseMetadataListCmd = &cobra.Command{
+	Use:   "list [NAME]",
+	Short: "List the metadata of the lease",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		clientset, err := NewClient()
+		if err != nil {
+			return err
+		}
+		lease, err := getLease(cmd.Context(), clientset, args[0])
+		if err != nil {
+			return err
+		}
+		keys := make([]string, 0, len(lease.Status.Metadata))
+		for key := range lease.Status.Metadata {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KEY\tVALUE")
+		for _, key := range keys {
+			fmt.Fprintf(w, "%s\t%s\n", key, lease.Status.Metadata[key])
+		}
+		return w.Flush()
+	},
+}
+
+var leaseMetadataGetCmd = &cobra.Command{
+	Use:   "get [NAME] [KEY]",
+	Short: "Print the value of a metadata key of the lease",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		clientset, err := NewClient()
+		if err != nil {
+			return err
+		}
+		lease, err := getLease(cmd.Context(), clientset, args[0])
+		if err != nil {
+			return err
+		}
+		value, ok := lease.Status.Metadata[args[1]]
+		if !ok {
+			return fmt.Errorf("metadata key %s not found on lease %s", args[1], args[0])
+		}
+		fmt.Println(value)
+		return nil
+	},
+}
+
+var leaseMetadataSetCmd = &cobra.Command{
+	Use:   "set [NAME] [KEY] [VALUE]",
+	Short: "Set a metadata key of the lease",
+	Args:  cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		clientset, err := NewClient()
+		if err != nil {
+			return err
+		}
+		lease, err := getLease(ctx, clientset, args[0])
+		if err != nil {
+			return err
+		}
+		original := client.MergeFrom(lease.DeepCopy())
+		if err := controller.SetLeaseMetadata(lease, args[1], args[2]); err != nil {
+			return err
+		}
+		return clientset.Status().Patch(ctx, lease, original)
+	},
+}
+
+var leaseMetadataDeleteCmd = &cobra.Command{
+	Use:   "delete [NAME] [KEY]",
+	Short: "Delete a metadata key of the lease",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		clientset, err := NewClient()
+		if err != nil {
+			return err
+		}
+		lease, err := getLease(ctx, clientset, args[0])
+		if err != nil {
+			return err
+		}
+		original := client.MergeFrom(lease.DeepCopy())
+		controller.DeleteLeaseMetadata(lease, args[1])
+		return clientset.Status().Patch(ctx, lease, original)
+	},
+}
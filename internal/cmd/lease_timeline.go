@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/internal/controller"
+)
+
+func init() {
+	leaseCmd.AddCommand(leaseTimelineCmd)
+}
+
+// timelineEntry is a point in the history of a lease
+type timelineEntry struct {
+	time    time.Time
+	source  string
+	event   string
+	details string
+}
+
+// leaseTimeline assembles the history of lease from its status, the latest transition of each of
+// its conditions, and its events: the ones of the lease controller, the dials and the stream sessions
+func leaseTimeline(lease *jumpstarterdevv1alpha1.Lease, events []corev1.Event) []timelineEntry {
+	entries := []timelineEntry{{
+		time:   lease.CreationTimestamp.Time,
+		source: "lease",
+		event:  "Created",
+		details: fmt.Sprintf("client %s, selector %s, duration %s",
+			lease.Spec.ClientRef.Name, metav1.FormatLabelSelector(&lease.Spec.Selector), lease.Spec.Duration.Duration),
+	}}
+
+	for _, condition := range lease.Status.Conditions {
+		details := condition.Reason
+		if condition.Message != "" {
+			details += ": " + condition.Message
+		}
+		entries = append(entries, timelineEntry{
+			time:    condition.LastTransitionTime.Time,
+			source:  "condition",
+			event:   fmt.Sprintf("%s=%s", condition.Type, condition.Status),
+			details: details,
+		})
+	}
+
+	if lease.Status.BeginTime != nil && lease.Status.ExporterRef != nil {
+		exporters := controller.LeaseExporters(lease)
+		entries = append(entries, timelineEntry{
+			time:    lease.Status.BeginTime.Time,
+			source:  "lease",
+			event:   "Acquired",
+			details: "exporter " + strings.Join(exporters, ", "),
+		})
+	}
+
+	if lease.Status.EndTime != nil {
+		entries = append(entries, timelineEntry{
+			time:    lease.Status.EndTime.Time,
+			source:  "lease",
+			event:   "Ended",
+			details: "phase " + string(lease.Status.Phase),
+		})
+	}
+
+	for _, event := range events {
+		at := event.LastTimestamp.Time
+		if at.IsZero() {
+			at = event.EventTime.Time
+		}
+		details := event.Message
+		if event.Count > 1 {
+			details += fmt.Sprintf(" (x%d since %s)", event.Count, event.FirstTimestamp.Format(time.RFC3339))
+		}
+		entries = append(entries, timelineEntry{
+			time:    at,
+			source:  "event/" + event.Source.Component,
+			event:   event.Reason,
+			details: details,
+		})
+	}
+
+	// stable, entries at the same time keep the order above
+	slices.SortStableFunc(entries, func(a, b timelineEntry) int {
+		return a.time.Compare(b.time)
+	})
+	return entries
+}
+
+var leaseTimelineCmd = &cobra.Command{
+	Use:   "timeline [NAME]",
+	Short: "Print the chronological history of a lease, from its status and events",
+	Long: `Print the chronological history of a lease: its creation, the latest transition of each
+of its conditions, the acquisition of its exporter, its dials and stream sessions, and its end.
+Events expire, one hour after being recorded by default, older ones are missing.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		clientset, err := NewClient()
+		if err != nil {
+			return err
+		}
+		lease, err := getLease(ctx, clientset, args[0])
+		if err != nil {
+			return err
+		}
+
+		var events corev1.EventList
+		if err := clientset.List(ctx, &events, client.InNamespace(namespace), client.MatchingFields{
+			"involvedObject.kind": "Lease",
+			"involvedObject.name": lease.Name,
+		}); err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TIME\tOFFSET\tSOURCE\tEVENT\tDETAILS")
+		for _, entry := range leaseTimeline(lease, events.Items) {
+			fmt.Fprintf(w, "%s\t+%s\t%s\t%s\t%s\n",
+				entry.time.Format(time.RFC3339), entry.time.Sub(lease.CreationTimestamp.Time).Round(time.Second),
+				entry.source, entry.event, entry.details)
+		}
+		return w.Flush()
+	},
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/jumpstarter-dev/jumpstarter-controller/internal/service"
+)
+
+func init() {
+	rootCmd.AddCommand(routerCmd)
+
+	routerCmd.AddCommand(routerListCmd)
+	routerCmd.AddCommand(routerDrainCmd)
+	routerCmd.AddCommand(routerResumeCmd)
+}
+
+var routerCmd = &cobra.Command{
+	Use:   "router",
+	Short: "Manage the router replicas, in the namespace of the controller",
+}
+
+var routerListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the registered router replicas",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		clientset, err := NewClient()
+		if err != nil {
+			return err
+		}
+		replicas, drains, err := service.RouterRegistry(cmd.Context(), clientset, namespace)
+		if err != nil {
+			return err
+		}
+
+		names := make([]string, 0, len(replicas))
+		for name := range replicas {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tENDPOINT\tSTATE\tSTREAMS\tHEARTBEAT")
+		for _, name := range names {
+			replica := replicas[name]
+			state := "Serving"
+			if replica.Draining {
+				state = "Draining"
+			} else if drains[name] {
+				state = "DrainRequested"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", name, replica.Endpoint, state, replica.ActiveStreams,
+				time.Since(replica.Heartbeat).Round(time.Second))
+		}
+		return w.Flush()
+	},
+}
+
+var routerDrainCmd = &cobra.Command{
+	Use:   "drain [NAME]",
+	Short: "Stop assigning new streams to a router replica, letting its active streams finish",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setRouterDrain(cmd.Context(), args[0], true)
+	},
+}
+
+var routerResumeCmd = &cobra.Command{
+	Use:   "resume [NAME]",
+	Short: "Assign new streams to a drained router replica again",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setRouterDrain(cmd.Context(), args[0], false)
+	},
+}
+
+func setRouterDrain(ctx context.Context, name string, drain bool) error {
+	clientset, err := NewClient()
+	if err != nil {
+		return err
+	}
+	replicas, _, err := service.RouterRegistry(ctx, clientset, namespace)
+	if err != nil {
+		return err
+	}
+	if _, ok := replicas[name]; !ok {
+		return fmt.Errorf("router replica %s is not registered in namespace %s", name, namespace)
+	}
+
+	var value *string
+	if drain {
+		value = new(string)
+		*value = "true"
+	}
+	patch, err := json.Marshal(map[string]any{
+		"data": map[string]*string{name + service.RouterDrainSuffix: value},
+	})
+	if err != nil {
+		return err
+	}
+	return clientset.Patch(ctx, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      service.RouterRegistryName,
+		},
+	}, client.RawPatch(types.MergePatchType, patch))
+}
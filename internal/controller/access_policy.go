@@ -0,0 +1,367 @@
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// AccessDecision is the outcome of evaluating the ExporterAccessPolicies for a client and an exporter
+type AccessDecision struct {
+	// Whether the client may lease the exporter
+	Allowed bool
+	// The highest priority policy granting access, nil if no ExporterAccessPolicy selects the exporter
+	// or a deny rule denies access
+	Policy *jumpstarterdevv1alpha1.Policy
+	// The name of the ExporterAccessPolicy of Policy, or of the deny rule denying access
+	PolicyName string
+	// Whether a deny rule matching the client denies access, whatever the policies granting it
+	Denied bool
+	// The highest priority ClusterExporterAccessPolicy policy granting the baseline access further
+	// restricted by Policy, nil if Policy is not restricted by any
+	Baseline *jumpstarterdevv1alpha1.Policy
+	// The name of the ClusterExporterAccessPolicy of Baseline
+	BaselineName string
+}
+
+// MaximumDuration returns the maximum duration of the leases granted, the shortest of the ones of
+// Policy and Baseline, nil if unlimited
+func (d AccessDecision) MaximumDuration() *metav1.Duration {
+	policy, _ := d.durationPolicy()
+	if policy == nil {
+		return nil
+	}
+	return policy.MaximumDuration
+}
+
+// durationPolicy returns the one of Policy and Baseline limiting the duration of the leases granted
+// and the name of its policy, Policy if neither does
+func (d AccessDecision) durationPolicy() (*jumpstarterdevv1alpha1.Policy, string) {
+	if d.Baseline != nil && d.Baseline.MaximumDuration != nil && (d.Policy.MaximumDuration == nil ||
+		d.Baseline.MaximumDuration.Duration < d.Policy.MaximumDuration.Duration) {
+		return d.Baseline, d.BaselineName
+	}
+	return d.Policy, d.PolicyName
+}
+
+// EvaluateAccessPolicies decides whether client may lease exporter, the ClusterExporterAccessPolicies
+// among the policies, as returned by ListAccessPolicies, grant the baseline access the
+// ExporterAccessPolicies of the namespace further restrict, each being evaluated on its own
+func EvaluateAccessPolicies(
+	policies []jumpstarterdevv1alpha1.ExporterAccessPolicy,
+	tieBreak PolicyTieBreak,
+	client *jumpstarterdevv1alpha1.Client,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+) (AccessDecision, error) {
+	start := time.Now()
+	defer func() { policyEvaluationSeconds.Observe(time.Since(start).Seconds()) }()
+
+	var cluster, namespaced []jumpstarterdevv1alpha1.ExporterAccessPolicy
+	for i := range policies {
+		if clusterScoped(&policies[i]) {
+			cluster = append(cluster, policies[i])
+		} else {
+			namespaced = append(namespaced, policies[i])
+		}
+	}
+	if len(cluster) == 0 {
+		return evaluateAccessPolicies(policies, tieBreak, client, exporter)
+	}
+
+	baseline, err := evaluateAccessPolicies(cluster, tieBreak, client, exporter)
+	if err != nil || !baseline.Allowed {
+		return baseline, err
+	}
+	decision, err := evaluateAccessPolicies(namespaced, tieBreak, client, exporter)
+	if err != nil || !decision.Allowed {
+		return decision, err
+	}
+	if decision.Policy == nil {
+		// no ExporterAccessPolicy of the namespace restricts the exporter
+		return baseline, nil
+	}
+	decision.Baseline = baseline.Policy
+	decision.BaselineName = baseline.PolicyName
+	return decision, nil
+}
+
+// evaluateAccessPolicies decides whether policies, all of the same scope, let client lease exporter,
+// exporters not selected by any of the policies are open to every client unless one of the policies
+// sets DefaultDeny, otherwise access is denied by any deny rule with a From clause matching the
+// client, or else granted by the highest priority policy with a From clause matching the client,
+// the policies of equal priority being tied by tieBreak
+func evaluateAccessPolicies(
+	policies []jumpstarterdevv1alpha1.ExporterAccessPolicy,
+	tieBreak PolicyTieBreak,
+	client *jumpstarterdevv1alpha1.Client,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+) (AccessDecision, error) {
+	decision := AccessDecision{Allowed: !DefaultDeny(policies)}
+
+	for i := range policies {
+		matches, err := selectorMatches(&policies[i].Spec.ExporterSelector, exporter.Labels)
+		if err != nil {
+			return AccessDecision{}, fmt.Errorf("EvaluateAccessPolicies: invalid exporter selector in %s: %w",
+				policies[i].Name, err)
+		}
+		if !matches {
+			continue
+		}
+
+		// the exporter is restricted, only the matching policies grant access
+		if decision.Policy == nil {
+			decision.Allowed = false
+		}
+
+		if client == nil {
+			continue
+		}
+
+		for j := range policies[i].Spec.Policies {
+			policy := &policies[i].Spec.Policies[j]
+			granted, err := policyGrants(policy, client)
+			if err != nil {
+				return AccessDecision{}, fmt.Errorf("EvaluateAccessPolicies: invalid client selector in %s: %w",
+					policies[i].Name, err)
+			}
+			if !granted {
+				continue
+			}
+			if policy.Effect == jumpstarterdevv1alpha1.PolicyEffectDeny {
+				return AccessDecision{Denied: true, PolicyName: policies[i].Name}, nil
+			}
+			if decision.Policy == nil ||
+				tieBreak.prefers(policy, policies[i].Name, decision.Policy, decision.PolicyName) {
+				decision.Allowed = true
+				decision.Policy = policy
+				decision.PolicyName = policies[i].Name
+			}
+		}
+	}
+
+	return decision, nil
+}
+
+// DefaultDeny reports whether one of the ExporterAccessPolicies or ClusterExporterAccessPolicies of a
+// namespace sets DefaultDeny
+func DefaultDeny(policies []jumpstarterdevv1alpha1.ExporterAccessPolicy) bool {
+	for i := range policies {
+		if policies[i].Spec.DefaultDeny {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientCanLease reports whether client may lease exporter at time now, either because the
+// exporter is reserved to the client or because the ExporterAccessPolicies allow it, the deny
+// rules matching the client deny access to the exporters reserved to it too
+func ClientCanLease(
+	policies []jumpstarterdevv1alpha1.ExporterAccessPolicy,
+	tieBreak PolicyTieBreak,
+	client *jumpstarterdevv1alpha1.Client,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+	now time.Time,
+) (bool, error) {
+	decision, err := EvaluateAccessPolicies(policies, tieBreak, client, exporter)
+	if err != nil {
+		return false, err
+	}
+	if decision.Denied {
+		return false, nil
+	}
+	return decision.Allowed || reservedFor(exporter, client, now), nil
+}
+
+// LeaseDurationAllowed reports whether the ExporterAccessPolicy granting client access to exporter
+// allows leases of duration, it returns the policy and false if its MaximumDuration is exceeded,
+// exporters currently reserved to the client are not limited
+func LeaseDurationAllowed(
+	policies []jumpstarterdevv1alpha1.ExporterAccessPolicy,
+	tieBreak PolicyTieBreak,
+	client *jumpstarterdevv1alpha1.Client,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+	duration time.Duration,
+	now time.Time,
+) (*jumpstarterdevv1alpha1.Policy, bool, error) {
+	if reservedFor(exporter, client, now) {
+		return nil, true, nil
+	}
+	decision, err := EvaluateAccessPolicies(policies, tieBreak, client, exporter)
+	if err != nil {
+		return nil, false, err
+	}
+	if decision.Policy == nil {
+		return nil, true, nil
+	}
+	policy, _ := decision.durationPolicy()
+	if policy.MaximumDuration == nil {
+		return policy, true, nil
+	}
+	return policy, duration <= policy.MaximumDuration.Duration, nil
+}
+
+// DurationViolation is a lease duration exceeding the MaximumDuration of an ExporterAccessPolicy
+type DurationViolation struct {
+	// The name of the ExporterAccessPolicy limiting the duration
+	PolicyName string
+	Maximum    time.Duration
+}
+
+func (v *DurationViolation) Error() string {
+	return fmt.Sprintf("exceeds the maximum duration %s of ExporterAccessPolicy %s", v.Maximum, v.PolicyName)
+}
+
+// LeaseDurationViolation returns the violation of the ExporterAccessPolicies limiting the duration
+// of the leases of client if none of exporters it may lease allows leases of duration, the least
+// restrictive one, nil if one of them does or if the client may lease none of them
+func LeaseDurationViolation(
+	policies []jumpstarterdevv1alpha1.ExporterAccessPolicy,
+	tieBreak PolicyTieBreak,
+	client *jumpstarterdevv1alpha1.Client,
+	exporters []jumpstarterdevv1alpha1.Exporter,
+	duration time.Duration,
+	now time.Time,
+) (*DurationViolation, error) {
+	var violation *DurationViolation
+	for i := range exporters {
+		exporter := &exporters[i]
+		if reservedFor(exporter, client, now) {
+			return nil, nil
+		}
+		decision, err := EvaluateAccessPolicies(policies, tieBreak, client, exporter)
+		if err != nil {
+			return nil, fmt.Errorf("LeaseDurationViolation: %w", err)
+		}
+		if !decision.Allowed {
+			continue
+		}
+		if decision.Policy == nil {
+			return nil, nil
+		}
+		policy, name := decision.durationPolicy()
+		if policy.MaximumDuration == nil || duration <= policy.MaximumDuration.Duration {
+			return nil, nil
+		}
+		if violation == nil || policy.MaximumDuration.Duration > violation.Maximum {
+			violation = &DurationViolation{
+				PolicyName: name,
+				Maximum:    policy.MaximumDuration.Duration,
+			}
+		}
+	}
+	return violation, nil
+}
+
+// policySelects reports whether the selector of any From clause of policy matches client, whatever
+// the groups of the credentials of the client
+func policySelects(policy *jumpstarterdevv1alpha1.Policy, client *jumpstarterdevv1alpha1.Client) (bool, error) {
+	for _, from := range policy.From {
+		matches, err := selectorMatches(&from.ClientSelector, client.Labels)
+		if err != nil || matches {
+			return matches, err
+		}
+	}
+	return false, nil
+}
+
+func policyGrants(policy *jumpstarterdevv1alpha1.Policy, client *jumpstarterdevv1alpha1.Client) (bool, error) {
+	for _, from := range policy.From {
+		matches, err := selectorMatches(&from.ClientSelector, client.Labels)
+		if err != nil {
+			return false, err
+		}
+		if matches && groupsMatch(from.Groups, client.Status.Groups) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// LeaseClient returns client as the access policies are evaluated against for lease, a shallow copy
+// to be read only, carrying the groups frozen onto lease, nil if client is
+func LeaseClient(
+	client *jumpstarterdevv1alpha1.Client,
+	lease *jumpstarterdevv1alpha1.Lease,
+) *jumpstarterdevv1alpha1.Client {
+	if client == nil {
+		return nil
+	}
+	leaseClient := *client
+	leaseClient.Status.Groups = lease.Spec.ClientGroups
+	return &leaseClient
+}
+
+// groupsMatch reports whether the client, a member of memberOf, is a member of any of groups,
+// always true if groups is empty
+func groupsMatch(groups []string, memberOf []string) bool {
+	if len(groups) == 0 {
+		return true
+	}
+	for _, group := range groups {
+		if slices.Contains(memberOf, group) {
+			return true
+		}
+	}
+	return false
+}
+
+func selectorMatches(selector *metav1.LabelSelector, set map[string]string) (bool, error) {
+	parsed, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false, err
+	}
+	return parsed.Matches(labels.Set(set)), nil
+}
+
+// deniedByPolicy returns the ExporterAccessPolicy denying the client of state access to the first of
+// exporters it cannot lease, the one of the deny rule matching the client, or else the first policy
+// selecting the exporter, "" if the exporter is denied by default
+func deniedByPolicy(state *AllocationState, exporters []jumpstarterdevv1alpha1.Exporter) string {
+	for i := range exporters {
+		decision, err := EvaluateAccessPolicies(
+			state.AccessPolicies, state.AccessPolicyTieBreak, state.Client, &exporters[i],
+		)
+		if err != nil || decision.Allowed {
+			continue
+		}
+		if decision.Denied {
+			return decision.PolicyName
+		}
+		for j := range state.AccessPolicies {
+			matches, err := selectorMatches(&state.AccessPolicies[j].Spec.ExporterSelector, exporters[i].Labels)
+			if err == nil && matches {
+				return state.AccessPolicies[j].Name
+			}
+		}
+		return ""
+	}
+	return ""
+}
+
+// grantingPolicy returns the ExporterAccessPolicy granting lease access to the first of exporters
+// it holds, "" if none does
+func grantingPolicy(
+	state *AllocationState,
+	lease *jumpstarterdevv1alpha1.Lease,
+	exporters []jumpstarterdevv1alpha1.Exporter,
+) string {
+	for i := range exporters {
+		if !LeaseHoldsExporter(lease, exporters[i].Name) {
+			continue
+		}
+		decision, err := EvaluateAccessPolicies(
+			state.AccessPolicies, state.AccessPolicyTieBreak, state.LeaseClient(lease), &exporters[i],
+		)
+		if err != nil || decision.Policy == nil {
+			return ""
+		}
+		return decision.PolicyName
+	}
+	return ""
+}
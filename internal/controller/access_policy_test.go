@@ -0,0 +1,298 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+func accessPolicy(
+	exporterLabels map[string]string,
+	policies ...jumpstarterdevv1alpha1.Policy,
+) jumpstarterdevv1alpha1.ExporterAccessPolicy {
+	return jumpstarterdevv1alpha1.ExporterAccessPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "policy",
+			Namespace: "default",
+		},
+		Spec: jumpstarterdevv1alpha1.ExporterAccessPolicySpec{
+			ExporterSelector: metav1.LabelSelector{MatchLabels: exporterLabels},
+			Policies:         policies,
+		},
+	}
+}
+
+func fromClients(priority int, clientLabels map[string]string) jumpstarterdevv1alpha1.Policy {
+	return jumpstarterdevv1alpha1.Policy{
+		Priority: priority,
+		From: []jumpstarterdevv1alpha1.From{{
+			ClientSelector: metav1.LabelSelector{MatchLabels: clientLabels},
+		}},
+	}
+}
+
+var _ = Describe("Exporter access policies", func() {
+	ciClient := &jumpstarterdevv1alpha1.Client{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"team": "ci"}},
+	}
+	devClient := &jumpstarterdevv1alpha1.Client{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"team": "dev"}},
+	}
+
+	It("should allow exporters not selected by any policy", func() {
+		policies := []jumpstarterdevv1alpha1.ExporterAccessPolicy{
+			accessPolicy(map[string]string{"dut": "b"}, fromClients(0, map[string]string{"team": "ci"})),
+		}
+		decision, err := EvaluateAccessPolicies(policies, PolicyTieBreakMostRestrictive, devClient, testExporter1DutA)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decision.Allowed).To(BeTrue())
+		Expect(decision.Policy).To(BeNil())
+	})
+
+	It("should only allow the clients selected by a policy", func() {
+		policies := []jumpstarterdevv1alpha1.ExporterAccessPolicy{
+			accessPolicy(map[string]string{"dut": "a"}, fromClients(0, map[string]string{"team": "ci"})),
+		}
+		decision, err := EvaluateAccessPolicies(policies, PolicyTieBreakMostRestrictive, ciClient, testExporter1DutA)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decision.Allowed).To(BeTrue())
+
+		decision, err = EvaluateAccessPolicies(policies, PolicyTieBreakMostRestrictive, devClient, testExporter1DutA)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decision.Allowed).To(BeFalse())
+
+		decision, err = EvaluateAccessPolicies(policies, PolicyTieBreakMostRestrictive, nil, testExporter1DutA)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decision.Allowed).To(BeFalse())
+	})
+
+	It("should apply the highest priority policy", func() {
+		policies := []jumpstarterdevv1alpha1.ExporterAccessPolicy{
+			accessPolicy(map[string]string{"dut": "a"},
+				fromClients(1, nil),
+				fromClients(10, map[string]string{"team": "ci"}),
+			),
+		}
+		decision, err := EvaluateAccessPolicies(policies, PolicyTieBreakMostRestrictive, ciClient, testExporter1DutA)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decision.Policy.Priority).To(Equal(10))
+
+		decision, err = EvaluateAccessPolicies(policies, PolicyTieBreakMostRestrictive, devClient, testExporter1DutA)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decision.Policy.Priority).To(Equal(1))
+	})
+
+	It("should deny the clients matching a deny rule whatever the priority of the allow rules", func() {
+		deny := fromClients(0, map[string]string{"team": "dev"})
+		deny.Effect = jumpstarterdevv1alpha1.PolicyEffectDeny
+		policies := []jumpstarterdevv1alpha1.ExporterAccessPolicy{
+			accessPolicy(map[string]string{"dut": "a"}, fromClients(10, nil), deny),
+		}
+		decision, err := EvaluateAccessPolicies(policies, PolicyTieBreakMostRestrictive, devClient, testExporter1DutA)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decision.Allowed).To(BeFalse())
+		Expect(decision.Denied).To(BeTrue())
+		Expect(decision.Policy).To(BeNil())
+
+		decision, err = EvaluateAccessPolicies(policies, PolicyTieBreakMostRestrictive, ciClient, testExporter1DutA)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decision.Allowed).To(BeTrue())
+		Expect(decision.Policy.Priority).To(Equal(10))
+	})
+
+	It("should only allow the clients authenticated as members of the groups selected by a policy", func() {
+		policy := fromClients(0, nil)
+		policy.From[0].Groups = []string{"lab-admins"}
+		policies := []jumpstarterdevv1alpha1.ExporterAccessPolicy{
+			accessPolicy(map[string]string{"dut": "a"}, policy),
+		}
+		decision, err := EvaluateAccessPolicies(policies, PolicyTieBreakMostRestrictive, devClient, testExporter1DutA)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decision.Allowed).To(BeFalse())
+
+		admin := devClient.DeepCopy()
+		admin.Status.Groups = []string{"developers", "lab-admins"}
+		decision, err = EvaluateAccessPolicies(policies, PolicyTieBreakMostRestrictive, admin, testExporter1DutA)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decision.Allowed).To(BeTrue())
+
+		policies[0].Spec.Policies[0].From[0].ClientSelector.MatchLabels = map[string]string{"team": "ci"}
+		decision, err = EvaluateAccessPolicies(policies, PolicyTieBreakMostRestrictive, admin, testExporter1DutA)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decision.Allowed).To(BeFalse())
+	})
+
+	It("should only let the policies of a namespace further restrict the cluster policies", func() {
+		baseline := fromClients(0, map[string]string{"team": "ci"})
+		baseline.MaximumDuration = &metav1.Duration{Duration: time.Hour}
+		restriction := fromClients(5, nil)
+		restriction.MaximumDuration = &metav1.Duration{Duration: 2 * time.Hour}
+		policies := []jumpstarterdevv1alpha1.ExporterAccessPolicy{
+			accessPolicy(map[string]string{"dut": "a"}, restriction),
+			accessPolicy(map[string]string{"dut": "a"}, baseline),
+		}
+		policies[1].Name, policies[1].Namespace = "baseline", ""
+
+		decision, err := EvaluateAccessPolicies(policies, PolicyTieBreakMostRestrictive, devClient, testExporter1DutA)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decision.Allowed).To(BeFalse())
+
+		decision, err = EvaluateAccessPolicies(policies, PolicyTieBreakMostRestrictive, ciClient, testExporter1DutA)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decision.Allowed).To(BeTrue())
+		Expect(decision.Policy.Priority).To(Equal(5))
+		Expect(decision.BaselineName).To(Equal("baseline"))
+		Expect(decision.MaximumDuration().Duration).To(Equal(time.Hour))
+
+		exporters := []jumpstarterdevv1alpha1.Exporter{*testExporter1DutA}
+		violation, err := LeaseDurationViolation(
+			policies, PolicyTieBreakMostRestrictive, ciClient, exporters, 90*time.Minute, time.Now(),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(violation.PolicyName).To(Equal("baseline"))
+	})
+
+	It("should let clients lease the exporters reserved to them", func() {
+		now := time.Now()
+		policies := []jumpstarterdevv1alpha1.ExporterAccessPolicy{
+			accessPolicy(map[string]string{"dut": "a"}, fromClients(0, map[string]string{"team": "ci"})),
+		}
+		allowed, err := ClientCanLease(policies, PolicyTieBreakMostRestrictive, testClient, testExporter1DutA, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(allowed).To(BeFalse())
+
+		reserved := reservedExporter(testExporter1DutA, testClient.Name, now.Add(-time.Hour), now.Add(time.Hour))
+		allowed, err = ClientCanLease(policies, PolicyTieBreakMostRestrictive, testClient, reserved, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(allowed).To(BeTrue())
+	})
+
+	It("should deny the exporters not selected by any policy in default deny namespaces", func() {
+		now := time.Now()
+		policies := []jumpstarterdevv1alpha1.ExporterAccessPolicy{
+			accessPolicy(map[string]string{"dut": "a"}, fromClients(0, nil)),
+		}
+		allowed, err := ClientCanLease(policies, PolicyTieBreakMostRestrictive, testClient, testExporter3DutB, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(allowed).To(BeTrue())
+
+		policies[0].Spec.DefaultDeny = true
+		allowed, err = ClientCanLease(policies, PolicyTieBreakMostRestrictive, testClient, testExporter3DutB, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(allowed).To(BeFalse())
+
+		allowed, err = ClientCanLease(policies, PolicyTieBreakMostRestrictive, testClient, testExporter1DutA, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(allowed).To(BeTrue())
+	})
+
+	It("should limit the duration of the leases granted by a policy", func() {
+		now := time.Now()
+		policy := fromClients(0, nil)
+		policy.MaximumDuration = &metav1.Duration{Duration: time.Hour}
+		policies := []jumpstarterdevv1alpha1.ExporterAccessPolicy{
+			accessPolicy(map[string]string{"dut": "a"}, policy),
+		}
+		_, allowed, err := LeaseDurationAllowed(
+			policies, PolicyTieBreakMostRestrictive, testClient, testExporter1DutA, time.Hour, now,
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(allowed).To(BeTrue())
+
+		matched, allowed, err := LeaseDurationAllowed(
+			policies, PolicyTieBreakMostRestrictive, testClient, testExporter1DutA, 2*time.Hour, now,
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(allowed).To(BeFalse())
+		Expect(matched.MaximumDuration.Duration).To(Equal(time.Hour))
+
+		_, allowed, err = LeaseDurationAllowed(
+			policies, PolicyTieBreakMostRestrictive, testClient, testExporter3DutB, 2*time.Hour, now,
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(allowed).To(BeTrue())
+	})
+
+	It("should name the least restrictive policy limiting the duration of the matching exporters", func() {
+		now := time.Now()
+		short := fromClients(0, nil)
+		short.MaximumDuration = &metav1.Duration{Duration: time.Hour}
+		long := fromClients(0, nil)
+		long.MaximumDuration = &metav1.Duration{Duration: 2 * time.Hour}
+		policies := []jumpstarterdevv1alpha1.ExporterAccessPolicy{
+			accessPolicy(map[string]string{"dut": "a"}, short),
+			accessPolicy(map[string]string{"dut": "b"}, long),
+		}
+		policies[0].Name, policies[1].Name = "short", "long"
+		exporters := []jumpstarterdevv1alpha1.Exporter{*testExporter1DutA, *testExporter3DutB}
+
+		violation, err := LeaseDurationViolation(
+			policies, PolicyTieBreakMostRestrictive, testClient, exporters, 90*time.Minute, now,
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(violation).To(BeNil())
+
+		violation, err = LeaseDurationViolation(
+			policies, PolicyTieBreakMostRestrictive, testClient, exporters, 3*time.Hour, now,
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(violation).NotTo(BeNil())
+		Expect(violation.PolicyName).To(Equal("long"))
+		Expect(violation.Maximum).To(Equal(2 * time.Hour))
+	})
+
+	It("should only report leases as satisfiable if a policy lets an exporter be assigned", func() {
+		policies := []jumpstarterdevv1alpha1.ExporterAccessPolicy{
+			accessPolicy(map[string]string{"dut": "a"}, fromClients(0, map[string]string{"team": "ci"})),
+		}
+		exporters := []jumpstarterdevv1alpha1.Exporter{*testExporter1DutA, *testExporter2DutA}
+		state := &AllocationState{Lease: leaseDutA2Sec, Client: devClient, AccessPolicies: policies}
+		Expect(LeaseSatisfiable(context.Background(), state, exporters)).To(BeFalse())
+
+		state.Client = ciClient
+		Expect(LeaseSatisfiable(context.Background(), state, exporters)).To(BeTrue())
+		Expect(LeaseSatisfiable(context.Background(), state, nil)).To(BeFalse())
+	})
+
+	When("leasing exporters restricted by a policy", func() {
+		BeforeEach(func() {
+			ctx := context.Background()
+			createExporters(ctx, testExporter1DutA, testExporter2DutA, testExporter3DutB)
+			setExporterOnlineConditions(ctx, testExporter1DutA.Name, metav1.ConditionTrue)
+			setExporterOnlineConditions(ctx, testExporter2DutA.Name, metav1.ConditionTrue)
+			policy := accessPolicy(map[string]string{"dut": "a"}, fromClients(0, map[string]string{"team": "ci"}))
+			Expect(k8sClient.Create(ctx, &policy)).To(Succeed())
+		})
+		AfterEach(func() {
+			ctx := context.Background()
+			deleteExporters(ctx, testExporter1DutA, testExporter2DutA, testExporter3DutB)
+			deleteLeases(ctx, "lease1")
+			policy := accessPolicy(nil)
+			Expect(k8sClient.Delete(ctx, &policy)).To(Succeed())
+		})
+
+		It("should be unsatisfiable for clients without access", func() {
+			ctx := context.Background()
+			denials := testutil.ToFloat64(policyLeaseDenialsTotal.WithLabelValues("default", "policy"))
+
+			lease := leaseDutA2Sec.DeepCopy()
+			Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+			_ = reconcileLease(ctx, lease)
+
+			updatedLease := getLease(ctx, lease.Name)
+			Expect(updatedLease.Status.ExporterRef).To(BeNil())
+			Expect(meta.IsStatusConditionTrue(updatedLease.Status.Conditions,
+				string(jumpstarterdevv1alpha1.LeaseConditionTypeUnsatisfiable))).To(BeTrue())
+			Expect(testutil.ToFloat64(
+				policyLeaseDenialsTotal.WithLabelValues("default", "policy"),
+			)).To(BeNumerically("==", denials+1))
+		})
+	})
+})
@@ -0,0 +1,670 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// MaxPluginScore is the upper bound of the score a ScorePlugin should return
+const MaxPluginScore int64 = 100
+
+// FilterCode is the outcome of running a FilterPlugin against an exporter
+type FilterCode int
+
+const (
+	// FilterCodeSuccess means the exporter can be assigned to the lease
+	FilterCodeSuccess FilterCode = iota
+	// FilterCodeUnavailable means the exporter cannot be assigned right now,
+	// but might become available later, e.g. it is held by another lease
+	FilterCodeUnavailable
+	// FilterCodeUnresolvable means the exporter cannot be assigned to the lease
+	// and waiting would not change that, e.g. it is offline
+	FilterCodeUnresolvable
+)
+
+// AllocationState is the snapshot of the cluster the plugins are evaluated against
+type AllocationState struct {
+	// The lease being allocated
+	Lease *jumpstarterdevv1alpha1.Lease
+	// The leases currently active in the namespace of the lease
+	ActiveLeases []jumpstarterdevv1alpha1.Lease
+	// The ended leases of the namespace not garbage collected yet, the usage history of the exporters
+	EndedLeases []jumpstarterdevv1alpha1.Lease
+	// The client holding the lease, nil if it does not exist
+	Client *jumpstarterdevv1alpha1.Client
+	// The ExporterAccessPolicies in the namespace of the lease
+	AccessPolicies []jumpstarterdevv1alpha1.ExporterAccessPolicy
+	// Which of the AccessPolicies of equal priority matching a client applies
+	AccessPolicyTieBreak PolicyTieBreak
+	// The exporters of each jumpstarter.dev/group the exporters being allocated belong to
+	LinkedExporters map[string][]jumpstarterdevv1alpha1.Exporter
+	// The exporters matching the selector of each role of the lease, by role name
+	RoleExporters map[string][]jumpstarterdevv1alpha1.Exporter
+	// The clients in the namespace of the lease by name, to rank the leases waiting for exporters,
+	// see LeaseClient
+	Clients map[string]*jumpstarterdevv1alpha1.Client
+	// The MaintenanceWindows in the namespace of the lease
+	MaintenanceWindows []jumpstarterdevv1alpha1.MaintenanceWindow
+	// The LeasePriorityClasses in the namespace of the lease
+	PriorityClasses []jumpstarterdevv1alpha1.LeasePriorityClass
+	// The exporters held by the active leases by name, to attribute the leases to the policies
+	// granting them, only set if a policy limits its concurrent leases
+	LeasedExporters map[string]*jumpstarterdevv1alpha1.Exporter
+}
+
+// LeaseClient returns the client of lease of the namespace, carrying the groups frozen onto lease,
+// nil if it does not exist
+func (s *AllocationState) LeaseClient(lease *jumpstarterdevv1alpha1.Lease) *jumpstarterdevv1alpha1.Client {
+	return LeaseClient(s.Clients[lease.Spec.ClientRef.Name], lease)
+}
+
+// Linked returns the exporters leased together with exporter, excluding itself
+func (s *AllocationState) Linked(exporter *jumpstarterdevv1alpha1.Exporter) []*jumpstarterdevv1alpha1.Exporter {
+	group, ok := exporter.Labels[jumpstarterdevv1alpha1.ExporterLabelGroup]
+	if !ok {
+		return nil
+	}
+	var linked []*jumpstarterdevv1alpha1.Exporter
+	for i := range s.LinkedExporters[group] {
+		if s.LinkedExporters[group][i].Name != exporter.Name {
+			linked = append(linked, &s.LinkedExporters[group][i])
+		}
+	}
+	return linked
+}
+
+// FilterPlugin removes exporters that cannot be assigned to a lease
+type FilterPlugin interface {
+	Name() string
+	Filter(ctx context.Context, state *AllocationState, exporter *jumpstarterdevv1alpha1.Exporter) FilterCode
+}
+
+// ScorePlugin ranks the exporters that passed all filters, higher is better
+type ScorePlugin interface {
+	Name() string
+	Score(ctx context.Context, state *AllocationState, exporter *jumpstarterdevv1alpha1.Exporter) (int64, error)
+}
+
+// WeightedScorePlugin multiplies the score of the wrapped ScorePlugin by Weight
+type WeightedScorePlugin struct {
+	ScorePlugin
+	Weight int64
+}
+
+// Allocator selects an exporter for a lease by running a pipeline of
+// filter plugins followed by score plugins, similar to the kube-scheduler framework
+type Allocator struct {
+	Name    string
+	Filters []FilterPlugin
+	Scorers []WeightedScorePlugin
+}
+
+// Allocation is the result of running the Allocator for a lease
+type Allocation struct {
+	// The selected exporter, nil if no exporter passed all filters
+	Exporter *jumpstarterdevv1alpha1.Exporter
+	// Number of exporters filtered out as FilterCodeUnavailable
+	Unavailable int
+	// Number of exporters filtered out as FilterCodeUnresolvable
+	Unresolvable int
+	// Filtered out exporter count per filter plugin name
+	Filtered map[string]int
+	// Score of the selected exporter per score plugin name, weighted
+	Scores map[string]int64
+}
+
+// Satisfiable reports whether the lease could be allocated now or by waiting
+func (a *Allocation) Satisfiable() bool {
+	return a.Exporter != nil || a.Unavailable > 0
+}
+
+// maxDecisionEntries caps the filter and score plugins recorded in a LeaseDecision
+const maxDecisionEntries = 32
+
+// Decision returns the trace of allocation to record on the lease, made by the allocator
+// named allocator over candidates exporters
+func (a *Allocation) Decision(allocator string, candidates int) *jumpstarterdevv1alpha1.LeaseDecision {
+	decision := &jumpstarterdevv1alpha1.LeaseDecision{
+		Allocator:  allocator,
+		Candidates: int32(candidates),
+	}
+	if a.Exporter != nil {
+		decision.Exporter = a.Exporter.Name
+	}
+	for _, name := range topEntries(a.Filtered) {
+		if decision.Filtered == nil {
+			decision.Filtered = map[string]int32{}
+		}
+		decision.Filtered[name] = int32(a.Filtered[name])
+	}
+	for _, name := range topEntries(a.Scores) {
+		if decision.Scores == nil {
+			decision.Scores = map[string]int64{}
+		}
+		decision.Scores[name] = a.Scores[name]
+	}
+	return decision
+}
+
+// topEntries returns the keys of the maxDecisionEntries highest values of entries
+func topEntries[V int | int64](entries map[string]V) []string {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(x, y string) int {
+		switch {
+		case entries[x] > entries[y]:
+			return -1
+		case entries[x] < entries[y]:
+			return 1
+		default:
+			return strings.Compare(x, y)
+		}
+	})
+	return keys[:min(len(keys), maxDecisionEntries)]
+}
+
+// DefaultFilters returns the filter plugins used by the default Allocator
+func DefaultFilters() []FilterPlugin {
+	return []FilterPlugin{
+		AffinityFilter{},
+		ChannelFilter{},
+		OnlineFilter{},
+		SchedulableFilter{},
+		NotLeasedFilter{},
+		ConcurrencyGroupFilter{},
+		NotUpdatingFilter{},
+		MaintenanceFilter{},
+		ReservationFilter{},
+		AccessPolicyFilter{},
+		PolicyLeaseLimitFilter{},
+		FairQueueFilter{},
+		LinkedExportersFilter{},
+		LeaseRolesFilter{},
+	}
+}
+
+// NewDefaultAllocator returns an Allocator with the default filters and the AffinityScorer,
+// which assigns the first available exporter the lease prefers most
+func NewDefaultAllocator() *Allocator {
+	return &Allocator{
+		Name:    DefaultAllocatorName,
+		Filters: DefaultFilters(),
+		Scorers: []WeightedScorePlugin{{ScorePlugin: AffinityScorer{}, Weight: 1}},
+	}
+}
+
+// DefaultAllocatorName is the name NewDefaultAllocator is registered under
+const DefaultAllocatorName = "default"
+
+var allocators = map[string]func() *Allocator{
+	DefaultAllocatorName: NewDefaultAllocator,
+}
+
+// RegisterAllocator makes an allocator available by name to NewAllocator,
+// downstream builds should call it from an init function
+func RegisterAllocator(name string, factory func() *Allocator) {
+	if _, ok := allocators[name]; ok {
+		panic(fmt.Sprintf("RegisterAllocator: allocator %s already registered", name))
+	}
+	allocators[name] = factory
+}
+
+// NewAllocator returns a new instance of the allocator registered as name
+func NewAllocator(name string) (*Allocator, error) {
+	factory, ok := allocators[name]
+	if !ok {
+		return nil, fmt.Errorf("NewAllocator: unknown allocator %s", name)
+	}
+	allocator := factory()
+	allocator.Name = name
+	return allocator, nil
+}
+
+// Allocate runs the pipeline over the exporters matching the lease selector
+func (a *Allocator) Allocate(
+	ctx context.Context,
+	state *AllocationState,
+	exporters []jumpstarterdevv1alpha1.Exporter,
+) (*Allocation, error) {
+	allocation := &Allocation{
+		Filtered: map[string]int{},
+		Scores:   map[string]int64{},
+	}
+
+	type candidate struct {
+		exporter *jumpstarterdevv1alpha1.Exporter
+		total    int64
+		scores   map[string]int64
+	}
+
+	var candidates []candidate
+	for i := range exporters {
+		exporter := &exporters[i]
+		code := FilterCodeSuccess
+		for _, filter := range a.Filters {
+			code = filter.Filter(ctx, state, exporter)
+			if code != FilterCodeSuccess {
+				allocation.Filtered[filter.Name()]++
+				break
+			}
+		}
+		switch code {
+		case FilterCodeSuccess:
+			candidates = append(candidates, candidate{exporter: exporter, scores: map[string]int64{}})
+		case FilterCodeUnavailable:
+			allocation.Unavailable++
+		default:
+			allocation.Unresolvable++
+		}
+	}
+
+	for i := range candidates {
+		for _, scorer := range a.Scorers {
+			score, err := scorer.Score(ctx, state, candidates[i].exporter)
+			if err != nil {
+				return nil, fmt.Errorf("Allocate: score plugin %s failed: %w", scorer.Name(), err)
+			}
+			score = min(max(score, 0), MaxPluginScore) * scorer.Weight
+			candidates[i].scores[scorer.Name()] = score
+			candidates[i].total += score
+		}
+	}
+
+	// stable sort keeps the listing order between exporters with equal scores
+	slices.SortStableFunc(candidates, func(x, y candidate) int {
+		switch {
+		case x.total > y.total:
+			return -1
+		case x.total < y.total:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	if len(candidates) > 0 {
+		allocation.Exporter = candidates[0].exporter
+		allocation.Scores = candidates[0].scores
+	}
+
+	return allocation, nil
+}
+
+// LeaseSatisfiable reports whether any of exporters, matching the selector of the lease of state,
+// could ever be assigned to it: exporters held, offline or updating might become available,
+// the ones the ExporterAccessPolicies do not grant the client for the lease, excluded by its
+// required affinity, or on another release channel than the client, never will
+func LeaseSatisfiable(
+	ctx context.Context,
+	state *AllocationState,
+	exporters []jumpstarterdevv1alpha1.Exporter,
+) bool {
+	for i := range exporters {
+		if (AffinityFilter{}).Filter(ctx, state, &exporters[i]) == FilterCodeSuccess &&
+			(ChannelFilter{}).Filter(ctx, state, &exporters[i]) == FilterCodeSuccess &&
+			(AccessPolicyFilter{}).Filter(ctx, state, &exporters[i]) == FilterCodeSuccess {
+			return true
+		}
+	}
+	return false
+}
+
+// OnlineFilter filters out exporters that are not registered and online
+type OnlineFilter struct{}
+
+func (OnlineFilter) Name() string {
+	return "Online"
+}
+
+func (OnlineFilter) Filter(
+	_ context.Context,
+	_ *AllocationState,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+) FilterCode {
+	if meta.IsStatusConditionTrue(
+		exporter.Status.Conditions,
+		string(jumpstarterdevv1alpha1.ExporterConditionTypeRegistered),
+	) && meta.IsStatusConditionTrue(
+		exporter.Status.Conditions,
+		string(jumpstarterdevv1alpha1.ExporterConditionTypeOnline),
+	) {
+		return FilterCodeSuccess
+	}
+	return FilterCodeUnresolvable
+}
+
+// SchedulableFilter filters out exporters cordoned or drained by an administrator, until they are
+// uncordoned or the drain is removed
+type SchedulableFilter struct{}
+
+func (SchedulableFilter) Name() string {
+	return "Schedulable"
+}
+
+func (SchedulableFilter) Filter(
+	_ context.Context,
+	_ *AllocationState,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+) FilterCode {
+	if exporter.Spec.Unschedulable || exporter.Spec.Drain {
+		return FilterCodeUnavailable
+	}
+	return FilterCodeSuccess
+}
+
+// NotLeasedFilter filters out exporters referenced or claimed by another active lease, or reserved
+// by another lease beginning in the future whose window overlaps the window of the lease, exporters
+// with a lease capacity above 1 are only filtered out once that many leases hold or reserve them
+type NotLeasedFilter struct{}
+
+func (NotLeasedFilter) Name() string {
+	return "NotLeased"
+}
+
+func (NotLeasedFilter) Filter(
+	_ context.Context,
+	state *AllocationState,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+) FilterCode {
+	now := time.Now()
+	if !LeaseScheduled(state.Lease, now) && exporterClaimedByOther(exporter, state.Lease, state.ActiveLeases, now) {
+		return FilterCodeUnavailable
+	}
+	begin, end := LeaseWindow(state.Lease, now)
+	capacity := exporter.LeaseCapacity()
+	holders := 0
+	for i := range state.ActiveLeases {
+		existingLease := &state.ActiveLeases[i]
+		if existingLease.Name == state.Lease.Name {
+			continue
+		}
+		existingBegin, existingEnd := LeaseWindow(existingLease, now)
+		// if the lease is referencing the current exporter
+		if LeaseHoldsExporter(existingLease, exporter.Name) {
+			// only reservations starting after the lease ends can take a held exporter
+			if !LeaseScheduled(state.Lease, now) || existingEnd.After(begin) {
+				holders++
+				continue
+			}
+		}
+		if LeaseReservesExporter(existingLease, exporter.Name) &&
+			existingBegin.Before(end) && begin.Before(existingEnd) {
+			holders++
+		}
+	}
+	if holders >= capacity {
+		return FilterCodeUnavailable
+	}
+	return FilterCodeSuccess
+}
+
+// ConcurrencyGroupFilter filters out every exporter while another lease of the concurrency group
+// of the lease holds exporters, only one lease of a group is active at a time
+type ConcurrencyGroupFilter struct{}
+
+func (ConcurrencyGroupFilter) Name() string {
+	return "ConcurrencyGroup"
+}
+
+func (ConcurrencyGroupFilter) Filter(
+	_ context.Context,
+	state *AllocationState,
+	_ *jumpstarterdevv1alpha1.Exporter,
+) FilterCode {
+	group := state.Lease.Spec.ConcurrencyGroup
+	if group == "" {
+		return FilterCodeSuccess
+	}
+	for i := range state.ActiveLeases {
+		other := &state.ActiveLeases[i]
+		if other.Name != state.Lease.Name && other.Spec.ConcurrencyGroup == group &&
+			!other.Status.Ended && other.Status.ExporterRef != nil {
+			return FilterCodeUnavailable
+		}
+	}
+	return FilterCodeSuccess
+}
+
+// NotUpdatingFilter filters out exporters instructed to update by an ExporterUpdatePolicy,
+// until they report the target version or the update deadline passes
+type NotUpdatingFilter struct{}
+
+func (NotUpdatingFilter) Name() string {
+	return "NotUpdating"
+}
+
+func (NotUpdatingFilter) Filter(
+	_ context.Context,
+	_ *AllocationState,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+) FilterCode {
+	if deadline, ok := ExporterUpdateDeadline(exporter); ok && time.Now().Before(deadline) {
+		return FilterCodeUnavailable
+	}
+	return FilterCodeSuccess
+}
+
+// ReservationFilter filters out exporters reserved to other clients during the lease,
+// leases of other clients must end before the reservation begins
+type ReservationFilter struct{}
+
+func (ReservationFilter) Name() string {
+	return "Reservation"
+}
+
+func (ReservationFilter) Filter(
+	_ context.Context,
+	state *AllocationState,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+) FilterCode {
+	begin, end := LeaseWindow(state.Lease, time.Now())
+	reservation := OverlappingReservation(exporter, begin, end)
+	if reservation == nil {
+		return FilterCodeSuccess
+	}
+	held, err := ReservationHeldBy(reservation, state.Client)
+	if err != nil {
+		return FilterCodeUnresolvable
+	}
+	if !held {
+		return FilterCodeUnavailable
+	}
+	return FilterCodeSuccess
+}
+
+// MaintenanceFilter filters out exporters under maintenance during the window of the lease
+type MaintenanceFilter struct{}
+
+func (MaintenanceFilter) Name() string {
+	return "Maintenance"
+}
+
+func (MaintenanceFilter) Filter(
+	_ context.Context,
+	state *AllocationState,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+) FilterCode {
+	begin, end := LeaseWindow(state.Lease, time.Now())
+	window, err := OverlappingMaintenance(state.MaintenanceWindows, exporter, begin, end)
+	if err != nil {
+		return FilterCodeUnresolvable
+	}
+	if window != nil {
+		return FilterCodeUnavailable
+	}
+	return FilterCodeSuccess
+}
+
+// AccessPolicyFilter filters out exporters the ExporterAccessPolicies do not grant the client access to,
+// or not for the duration of the lease, exporters currently reserved to the client are always granted
+type AccessPolicyFilter struct{}
+
+func (AccessPolicyFilter) Name() string {
+	return "AccessPolicy"
+}
+
+func (AccessPolicyFilter) Filter(
+	_ context.Context,
+	state *AllocationState,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+) FilterCode {
+	now := time.Now()
+	allowed, err := ClientCanLease(state.AccessPolicies, state.AccessPolicyTieBreak, state.Client, exporter, now)
+	if err != nil || !allowed {
+		return FilterCodeUnresolvable
+	}
+	_, allowed, err = LeaseDurationAllowed(
+		state.AccessPolicies, state.AccessPolicyTieBreak, state.Client, exporter, state.Lease.Spec.Duration.Duration, now,
+	)
+	if err != nil || !allowed {
+		return FilterCodeUnresolvable
+	}
+	return FilterCodeSuccess
+}
+
+// PolicyLeaseLimitFilter filters out exporters granted to the client by a policy already granting
+// its maximum number of concurrent leases, in total or to the client
+type PolicyLeaseLimitFilter struct{}
+
+func (PolicyLeaseLimitFilter) Name() string {
+	return "PolicyLeaseLimit"
+}
+
+func (PolicyLeaseLimitFilter) Filter(
+	_ context.Context,
+	state *AllocationState,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+) FilterCode {
+	decision, err := EvaluateAccessPolicies(state.AccessPolicies, state.AccessPolicyTieBreak, state.Client, exporter)
+	if err != nil {
+		return FilterCodeUnresolvable
+	}
+	if !limitsConcurrentLeases(decision.Policy) {
+		return FilterCodeSuccess
+	}
+
+	var total, perClient int32
+	for i := range state.ActiveLeases {
+		lease := &state.ActiveLeases[i]
+		if lease.Name == state.Lease.Name || lease.Status.Ended || lease.Status.ExporterRef == nil {
+			continue
+		}
+		held, ok := state.LeasedExporters[lease.Status.ExporterRef.Name]
+		if !ok {
+			continue
+		}
+		client := state.LeaseClient(lease)
+		if client == nil {
+			continue
+		}
+		granted, err := EvaluateAccessPolicies(state.AccessPolicies, state.AccessPolicyTieBreak, client, held)
+		if err != nil || granted.Policy != decision.Policy {
+			continue
+		}
+		total++
+		if lease.Spec.ClientRef.Name == state.Lease.Spec.ClientRef.Name {
+			perClient++
+		}
+	}
+
+	if limit := decision.Policy.MaxConcurrentLeases; limit != nil && total >= *limit {
+		return FilterCodeUnavailable
+	}
+	if limit := decision.Policy.MaxConcurrentLeasesPerClient; limit != nil && perClient >= *limit {
+		return FilterCodeUnavailable
+	}
+	return FilterCodeSuccess
+}
+
+// limitsConcurrentLeases reports whether policy limits the number of leases it grants at the same time
+func limitsConcurrentLeases(policy *jumpstarterdevv1alpha1.Policy) bool {
+	return policy != nil && (policy.MaxConcurrentLeases != nil || policy.MaxConcurrentLeasesPerClient != nil)
+}
+
+// linkedExporterFilters are the filters the exporters leased together with the allocated exporter must pass
+func linkedExporterFilters() []FilterPlugin {
+	return []FilterPlugin{
+		ChannelFilter{},
+		OnlineFilter{},
+		SchedulableFilter{},
+		NotLeasedFilter{},
+		NotUpdatingFilter{},
+		ReservationFilter{},
+		AccessPolicyFilter{},
+	}
+}
+
+// LinkedExportersFilter filters out exporters whose linked exporters could not be leased with them,
+// so that a group of linked exporters is either leased as a whole or not at all
+type LinkedExportersFilter struct{}
+
+func (LinkedExportersFilter) Name() string {
+	return "LinkedExporters"
+}
+
+func (LinkedExportersFilter) Filter(
+	ctx context.Context,
+	state *AllocationState,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+) FilterCode {
+	code := FilterCodeSuccess
+	for _, linked := range state.Linked(exporter) {
+		for _, filter := range linkedExporterFilters() {
+			// unresolvable takes precedence over unavailable
+			code = max(code, filter.Filter(ctx, state, linked))
+		}
+	}
+	return code
+}
+
+// FairQueueFilter keeps exporters for the leases waiting longer, an exporter is unavailable to the lease
+// while another waiting lease that could take it is ahead in the queue, see leaseQueuedBefore
+type FairQueueFilter struct{}
+
+func (FairQueueFilter) Name() string {
+	return "FairQueue"
+}
+
+func (FairQueueFilter) Filter(
+	_ context.Context,
+	state *AllocationState,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+) FilterCode {
+	now := time.Now()
+	// reservations are not queued, they are allocated their exporter in advance
+	if state.Lease.Status.ReservedExporterRef != nil || LeaseScheduled(state.Lease, now) {
+		return FilterCodeSuccess
+	}
+	priority := leasePriority(state, state.Lease, state.Client, exporter)
+	for i := range state.ActiveLeases {
+		other := &state.ActiveLeases[i]
+		if other.Name == state.Lease.Name || !leaseWaiting(other) {
+			continue
+		}
+		if matches, err := selectorMatches(&other.Spec.Selector, exporter.Labels); err != nil || !matches {
+			continue
+		}
+		client := state.LeaseClient(other)
+		// leases that could not take the exporter anyway do not hold it up
+		allowed, err := ClientCanLease(state.AccessPolicies, state.AccessPolicyTieBreak, client, exporter, now)
+		if err != nil || !allowed {
+			continue
+		}
+		if allowed, err := ExporterAffinityAllows(other, exporter); err != nil || !allowed {
+			continue
+		}
+		if leaseQueuedBefore(other, leasePriority(state, other, client, exporter), state.Lease, priority) {
+			return FilterCodeUnavailable
+		}
+	}
+	return FilterCodeSuccess
+}
@@ -0,0 +1,201 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// DefaultUsageWindow is the default history the usage based score plugins consider
+const DefaultUsageWindow = 24 * time.Hour
+
+// exporterUsage returns how long exporter was held by the leases of state during the window ending
+// at now, and when it was last released, zero if it was not held during the window
+func exporterUsage(
+	state *AllocationState,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+	window time.Duration,
+	now time.Time,
+) (time.Duration, time.Time) {
+	var used time.Duration
+	var lastUsed time.Time
+	start := now.Add(-window)
+	for _, leases := range [][]jumpstarterdevv1alpha1.Lease{state.ActiveLeases, state.EndedLeases} {
+		for i := range leases {
+			lease := &leases[i]
+			if lease.Status.BeginTime == nil || !LeaseHoldsExporter(lease, exporter.Name) {
+				continue
+			}
+			begin, end := lease.Status.BeginTime.Time, now
+			if lease.Status.EndTime != nil {
+				end = lease.Status.EndTime.Time
+			}
+			begin, end = maxTime(begin, start), minTime(end, now)
+			if !end.After(begin) {
+				continue
+			}
+			used += end.Sub(begin)
+			lastUsed = maxTime(lastUsed, end)
+		}
+	}
+	return used, lastUsed
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// LeastRecentlyUsedScorer prefers the exporters released the longest ago, so that leases are
+// spread across identical exporters, the ones not used during Window get the maximum score
+type LeastRecentlyUsedScorer struct {
+	Window time.Duration
+}
+
+func (LeastRecentlyUsedScorer) Name() string {
+	return "LeastRecentlyUsed"
+}
+
+func (s LeastRecentlyUsedScorer) Score(
+	_ context.Context,
+	state *AllocationState,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+) (int64, error) {
+	now := time.Now()
+	_, lastUsed := exporterUsage(state, exporter, s.Window, now)
+	if lastUsed.IsZero() {
+		return MaxPluginScore, nil
+	}
+	return int64(now.Sub(lastUsed)) * MaxPluginScore / int64(s.Window), nil
+}
+
+// UtilizationScorer prefers the exporters held the shortest time by leases during Window,
+// so that the utilization of identical exporters is balanced
+type UtilizationScorer struct {
+	Window time.Duration
+}
+
+func (UtilizationScorer) Name() string {
+	return "Utilization"
+}
+
+func (s UtilizationScorer) Score(
+	_ context.Context,
+	state *AllocationState,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+) (int64, error) {
+	used, _ := exporterUsage(state, exporter, s.Window, time.Now())
+	return MaxPluginScore - int64(used)*MaxPluginScore/int64(s.Window), nil
+}
+
+// LabelWeight is the score given to the exporters with a label
+type LabelWeight struct {
+	Key    string
+	Value  string
+	Weight int64
+}
+
+// LabelWeightScorer prefers the exporters with the labels of Weights, scored as the sum of
+// the weights of the labels the exporter has
+type LabelWeightScorer struct {
+	Weights []LabelWeight
+}
+
+func (LabelWeightScorer) Name() string {
+	return "LabelWeight"
+}
+
+func (s LabelWeightScorer) Score(
+	_ context.Context,
+	_ *AllocationState,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+) (int64, error) {
+	var score int64
+	for _, weight := range s.Weights {
+		if value, ok := exporter.Labels[weight.Key]; ok && value == weight.Value {
+			score += weight.Weight
+		}
+	}
+	return score, nil
+}
+
+// ScorerOptions configure the score plugins returned by ParseScorePlugins
+type ScorerOptions struct {
+	// UsageWindow is the history LeastRecentlyUsed and Utilization consider, DefaultUsageWindow if 0
+	UsageWindow time.Duration
+	// LabelWeights of LabelWeight
+	LabelWeights []LabelWeight
+}
+
+// ParseScorePlugins returns the score plugins of spec, a comma separated list of NAME[=WEIGHT],
+// e.g. "LeastRecentlyUsed=2,Utilization", the weight defaults to 1
+func ParseScorePlugins(spec string, opts ScorerOptions) ([]WeightedScorePlugin, error) {
+	if opts.UsageWindow <= 0 {
+		opts.UsageWindow = DefaultUsageWindow
+	}
+
+	var scorers []WeightedScorePlugin
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, weightValue, found := strings.Cut(item, "=")
+		weight := int64(1)
+		if found {
+			var err error
+			weight, err = strconv.ParseInt(weightValue, 10, 64)
+			if err != nil || weight < 1 {
+				return nil, fmt.Errorf("ParseScorePlugins: invalid weight of %s: %s", name, weightValue)
+			}
+		}
+
+		var scorer ScorePlugin
+		switch name {
+		case (AffinityScorer{}).Name():
+			scorer = AffinityScorer{}
+		case (LeastRecentlyUsedScorer{}).Name():
+			scorer = LeastRecentlyUsedScorer{Window: opts.UsageWindow}
+		case (UtilizationScorer{}).Name():
+			scorer = UtilizationScorer{Window: opts.UsageWindow}
+		case (LabelWeightScorer{}).Name():
+			if len(opts.LabelWeights) == 0 {
+				return nil, fmt.Errorf("ParseScorePlugins: %s requires label weights", name)
+			}
+			scorer = LabelWeightScorer{Weights: opts.LabelWeights}
+		default:
+			return nil, fmt.Errorf("ParseScorePlugins: unknown score plugin %s", name)
+		}
+		scorers = append(scorers, WeightedScorePlugin{ScorePlugin: scorer, Weight: weight})
+	}
+	return scorers, nil
+}
+
+// ParseLabelWeights returns the label weights of spec, a comma separated list of KEY=VALUE:WEIGHT,
+// e.g. "jumpstarter.dev/rack=lab1:50"
+func ParseLabelWeights(spec string) ([]LabelWeight, error) {
+	var weights []LabelWeight
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		label, weightValue, found := strings.Cut(item, ":")
+		key, value, hasValue := strings.Cut(label, "=")
+		if !found || !hasValue || key == "" {
+			return nil, fmt.Errorf("ParseLabelWeights: invalid label weight %s", item)
+		}
+		weight, err := strconv.ParseInt(weightValue, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("ParseLabelWeights: invalid weight of %s: %w", label, err)
+		}
+		weights = append(weights, LabelWeight{Key: key, Value: value, Weight: weight})
+	}
+	return weights, nil
+}
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+var _ = Describe("Allocator score plugins", func() {
+	endedLease := func(exporter string, begin, end time.Time) jumpstarterdevv1alpha1.Lease {
+		lease := leaseDutA2Sec.DeepCopy()
+		lease.Status.ExporterRef = &corev1.LocalObjectReference{Name: exporter}
+		lease.Status.BeginTime = &metav1.Time{Time: begin}
+		lease.Status.EndTime = &metav1.Time{Time: end}
+		lease.Status.Ended = true
+		return *lease
+	}
+
+	It("should prefer the least recently used exporters", func() {
+		ctx := context.Background()
+		now := time.Now()
+		state := &AllocationState{
+			Lease: leaseDutA2Sec,
+			EndedLeases: []jumpstarterdevv1alpha1.Lease{
+				endedLease(testExporter1DutA.Name, now.Add(-2*time.Hour), now.Add(-time.Hour)),
+			},
+		}
+		scorer := LeastRecentlyUsedScorer{Window: 4 * time.Hour}
+
+		Expect(scorer.Score(ctx, state, testExporter1DutA)).To(BeNumerically("~", 25, 1))
+		Expect(scorer.Score(ctx, state, testExporter2DutA)).To(Equal(MaxPluginScore))
+	})
+
+	It("should prefer the least utilized exporters", func() {
+		ctx := context.Background()
+		now := time.Now()
+		state := &AllocationState{
+			Lease: leaseDutA2Sec,
+			EndedLeases: []jumpstarterdevv1alpha1.Lease{
+				endedLease(testExporter1DutA.Name, now.Add(-6*time.Hour), now.Add(-3*time.Hour)),
+				endedLease(testExporter2DutA.Name, now.Add(-2*time.Hour), now.Add(-time.Hour)),
+			},
+		}
+		scorer := UtilizationScorer{Window: 4 * time.Hour}
+
+		Expect(scorer.Score(ctx, state, testExporter1DutA)).To(BeNumerically("~", 75, 1))
+		Expect(scorer.Score(ctx, state, testExporter2DutA)).To(BeNumerically("~", 75, 1))
+		Expect(scorer.Score(ctx, state, testExporter3DutB)).To(Equal(MaxPluginScore))
+	})
+
+	It("should parse the configured score plugins", func() {
+		weights, err := ParseLabelWeights("dut=a:30, dut=b:10")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(weights).To(Equal([]LabelWeight{
+			{Key: "dut", Value: "a", Weight: 30},
+			{Key: "dut", Value: "b", Weight: 10},
+		}))
+
+		scorers, err := ParseScorePlugins("LeastRecentlyUsed=2,LabelWeight", ScorerOptions{LabelWeights: weights})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(scorers).To(HaveLen(2))
+		Expect(scorers[0].ScorePlugin).To(Equal(LeastRecentlyUsedScorer{Window: DefaultUsageWindow}))
+		Expect(scorers[0].Weight).To(Equal(int64(2)))
+		Expect(scorers[1].Score(context.Background(), nil, testExporter3DutB)).To(Equal(int64(10)))
+
+		_, err = ParseScorePlugins("LabelWeight", ScorerOptions{})
+		Expect(err).To(HaveOccurred())
+		_, err = ParseScorePlugins("Unknown", ScorerOptions{})
+		Expect(err).To(HaveOccurred())
+		_, err = ParseLabelWeights("dut:30")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,173 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/structured-merge-diff/v4/fieldpath"
+)
+
+// The field managers the reconcilers apply the fields they own with, using server-side apply
+const (
+	exporterFieldManager = "jumpstarter-exporter-controller"
+	clientFieldManager   = "jumpstarter-client-controller"
+	leaseFieldManager    = "jumpstarter-lease-controller"
+
+	recurringLeaseFieldManager = "jumpstarter-recurringlease-controller"
+)
+
+// LegacyFieldManager is the field manager the apiserver recorded for the updates and merge patches
+// of the releases predating server-side apply, derived from the name of the binary
+var LegacyFieldManager = filepath.Base(os.Args[0])
+
+// applyStatus applies status as the status of obj with manager, status must only carry the fields
+// owned by manager, the fields it owns and omits are removed. The apply is conditional on
+// resourceVersion unless it is empty
+func applyStatus(
+	ctx context.Context,
+	c client.Client,
+	obj client.Object,
+	status any,
+	manager string,
+	resourceVersion string,
+) error {
+	gvk, err := apiutil.GVKForObject(obj, c.Scheme())
+	if err != nil {
+		return fmt.Errorf("applyStatus: %w", err)
+	}
+
+	// only the status is sent, a typed object would also apply the zero values of its spec
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(status)
+	if err != nil {
+		return fmt.Errorf("applyStatus: failed to convert status: %w", err)
+	}
+
+	patch := &unstructured.Unstructured{}
+	patch.SetGroupVersionKind(gvk)
+	patch.SetNamespace(obj.GetNamespace())
+	patch.SetName(obj.GetName())
+	patch.SetResourceVersion(resourceVersion)
+	patch.Object["status"] = content
+
+	if err := c.Status().Patch(ctx, patch, client.Apply, client.FieldOwner(manager), client.ForceOwnership); err != nil {
+		return fmt.Errorf("applyStatus: %w", err)
+	}
+	return nil
+}
+
+// AdoptLegacyFields transfers the ownership of the fields of obj under prefixes from the
+// LegacyFieldManager to manager, so that manager can remove them by omitting them,
+// subresource is the subresource the fields are written through, "" for the main resource
+func AdoptLegacyFields(
+	ctx context.Context,
+	c client.Client,
+	obj client.Object,
+	subresource string,
+	manager string,
+	prefixes ...fieldpath.Path,
+) error {
+	managed := slices.Clone(obj.GetManagedFields())
+
+	legacy := slices.IndexFunc(managed, func(entry metav1.ManagedFieldsEntry) bool {
+		return entry.Manager == LegacyFieldManager &&
+			entry.Operation == metav1.ManagedFieldsOperationUpdate &&
+			entry.Subresource == subresource
+	})
+	if legacy < 0 || managed[legacy].FieldsV1 == nil {
+		return nil
+	}
+
+	legacyFields := &fieldpath.Set{}
+	if err := legacyFields.FromJSON(bytes.NewReader(managed[legacy].FieldsV1.Raw)); err != nil {
+		return fmt.Errorf("AdoptLegacyFields: failed to decode managed fields: %w", err)
+	}
+
+	adopted := fieldpath.NewSet()
+	legacyFields.Iterate(func(path fieldpath.Path) {
+		for _, prefix := range prefixes {
+			if hasPathPrefix(path, prefix) {
+				adopted.Insert(path)
+			}
+		}
+	})
+	if adopted.Empty() {
+		return nil
+	}
+
+	applied := slices.IndexFunc(managed, func(entry metav1.ManagedFieldsEntry) bool {
+		return entry.Manager == manager &&
+			entry.Operation == metav1.ManagedFieldsOperationApply &&
+			entry.Subresource == subresource
+	})
+	if applied < 0 {
+		managed = append(managed, metav1.ManagedFieldsEntry{
+			Manager:     manager,
+			Operation:   metav1.ManagedFieldsOperationApply,
+			APIVersion:  managed[legacy].APIVersion,
+			Time:        &metav1.Time{Time: time.Now()},
+			FieldsType:  "FieldsV1",
+			FieldsV1:    &metav1.FieldsV1{Raw: []byte("{}")},
+			Subresource: subresource,
+		})
+		applied = len(managed) - 1
+	}
+
+	appliedFields := &fieldpath.Set{}
+	if err := appliedFields.FromJSON(bytes.NewReader(managed[applied].FieldsV1.Raw)); err != nil {
+		return fmt.Errorf("AdoptLegacyFields: failed to decode managed fields: %w", err)
+	}
+
+	raw, err := appliedFields.Union(adopted).ToJSON()
+	if err != nil {
+		return fmt.Errorf("AdoptLegacyFields: failed to encode managed fields: %w", err)
+	}
+	managed[applied].FieldsV1 = &metav1.FieldsV1{Raw: raw}
+
+	remaining := legacyFields.Difference(adopted)
+	if remaining.Empty() {
+		managed = slices.Delete(managed, legacy, legacy+1)
+	} else {
+		raw, err := remaining.ToJSON()
+		if err != nil {
+			return fmt.Errorf("AdoptLegacyFields: failed to encode managed fields: %w", err)
+		}
+		managed[legacy].FieldsV1 = &metav1.FieldsV1{Raw: raw}
+	}
+
+	// the resource version test rejects the patch if the managed fields changed in the meantime
+	patch, err := json.Marshal([]map[string]any{
+		{"op": "test", "path": "/metadata/resourceVersion", "value": obj.GetResourceVersion()},
+		{"op": "replace", "path": "/metadata/managedFields", "value": managed},
+	})
+	if err != nil {
+		return fmt.Errorf("AdoptLegacyFields: failed to encode patch: %w", err)
+	}
+	if err := c.Patch(ctx, obj, client.RawPatch(types.JSONPatchType, patch)); err != nil {
+		return fmt.Errorf("AdoptLegacyFields: failed to patch managed fields: %w", err)
+	}
+	return nil
+}
+
+func hasPathPrefix(path fieldpath.Path, prefix fieldpath.Path) bool {
+	if len(path) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if !path[i].Equals(prefix[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -28,6 +28,7 @@ import (
 	kclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/structured-merge-diff/v4/fieldpath"
 
 	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
 )
@@ -54,7 +55,16 @@ func (r *ClientReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		)
 	}
 
-	original := kclient.MergeFrom(client.DeepCopy())
+	if err := AdoptLegacyFields(ctx, r.Client, &client, "status", clientFieldManager,
+		fieldpath.MakePathOrDie("status", "credential"),
+		fieldpath.MakePathOrDie("status", "endpoint"),
+	); err != nil {
+		return ctrl.Result{}, fmt.Errorf("Reconcile: %w", err)
+	}
+
+	if err := applyChannelLabel(ctx, r.Client, &client, "Client", client.Spec.Channel, clientFieldManager); err != nil {
+		return ctrl.Result{}, fmt.Errorf("Reconcile: %w", err)
+	}
 
 	if err := r.reconcileStatusCredential(ctx, &client); err != nil {
 		return ctrl.Result{}, err
@@ -64,7 +74,10 @@ func (r *ClientReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		return ctrl.Result{}, err
 	}
 
-	if err := r.Status().Patch(ctx, &client, original); err != nil {
+	if err := applyStatus(ctx, r.Client, &client, &jumpstarterdevv1alpha1.ClientStatus{
+		Credential: client.Status.Credential,
+		Endpoint:   client.Status.Endpoint,
+	}, clientFieldManager, ""); err != nil {
 		return RequeueConflict(logger, ctrl.Result{}, err)
 	}
 
@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// +kubebuilder:rbac:groups=jumpstarter.dev,resources=clusterexporteraccesspolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+
+// ListAccessPolicies returns the ExporterAccessPolicies of namespace followed by the
+// ClusterExporterAccessPolicies selecting it, as ExporterAccessPolicies without a namespace
+func ListAccessPolicies(
+	ctx context.Context,
+	reader client.Reader,
+	namespace string,
+) ([]jumpstarterdevv1alpha1.ExporterAccessPolicy, error) {
+	var policies jumpstarterdevv1alpha1.ExporterAccessPolicyList
+	if err := reader.List(ctx, &policies, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("ListAccessPolicies: failed to list exporter access policies: %w", err)
+	}
+
+	var clusterPolicies jumpstarterdevv1alpha1.ClusterExporterAccessPolicyList
+	if err := reader.List(ctx, &clusterPolicies); err != nil {
+		return nil, fmt.Errorf("ListAccessPolicies: failed to list cluster exporter access policies: %w", err)
+	}
+
+	var ns *corev1.Namespace
+	for i := range clusterPolicies.Items {
+		policy := &clusterPolicies.Items[i]
+		if policy.Spec.NamespaceSelector != nil {
+			if ns == nil {
+				ns = &corev1.Namespace{}
+				if err := reader.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+					return nil, fmt.Errorf("ListAccessPolicies: failed to get namespace: %w", err)
+				}
+			}
+			matches, err := selectorMatches(policy.Spec.NamespaceSelector, ns.Labels)
+			if err != nil {
+				return nil, fmt.Errorf("ListAccessPolicies: invalid namespace selector in %s: %w", policy.Name, err)
+			}
+			if !matches {
+				continue
+			}
+		}
+		policies.Items = append(policies.Items, jumpstarterdevv1alpha1.ExporterAccessPolicy{
+			ObjectMeta: *policy.ObjectMeta.DeepCopy(),
+			Spec:       *policy.Spec.ExporterAccessPolicySpec.DeepCopy(),
+		})
+	}
+	return policies.Items, nil
+}
+
+// clusterScoped reports whether policy is a ClusterExporterAccessPolicy returned by ListAccessPolicies
+func clusterScoped(policy *jumpstarterdevv1alpha1.ExporterAccessPolicy) bool {
+	return policy.Namespace == ""
+}
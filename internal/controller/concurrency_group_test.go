@@ -0,0 +1,34 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+var _ = Describe("Lease concurrency groups", func() {
+	It("should only let one lease of a group hold exporters at a time", func() {
+		ctx := context.Background()
+		lease := leaseDutA2Sec.DeepCopy()
+		lease.Spec.ConcurrencyGroup = "rf-chamber"
+
+		holder := leaseDutA2Sec.DeepCopy()
+		holder.Name = "holder"
+		holder.Spec.ConcurrencyGroup = "rf-chamber"
+		holder.Status.ExporterRef = &corev1.LocalObjectReference{Name: testExporter3DutB.Name}
+
+		state := &AllocationState{Lease: lease, ActiveLeases: []jumpstarterdevv1alpha1.Lease{*holder}}
+		Expect((ConcurrencyGroupFilter{}).Filter(ctx, state, testExporter1DutA)).To(Equal(FilterCodeUnavailable))
+
+		state.ActiveLeases[0].Spec.ConcurrencyGroup = "other"
+		Expect((ConcurrencyGroupFilter{}).Filter(ctx, state, testExporter1DutA)).To(Equal(FilterCodeSuccess))
+
+		state.ActiveLeases[0].Spec.ConcurrencyGroup = "rf-chamber"
+		state.ActiveLeases[0].Status.Ended = true
+		Expect((ConcurrencyGroupFilter{}).Filter(ctx, state, testExporter1DutA)).To(Equal(FilterCodeSuccess))
+	})
+})
@@ -0,0 +1,191 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// The invariants verified by the ConsistencyChecker
+const (
+	// The leaseRef of an exporter names the lease holding it, if any
+	CheckExporterLeaseRef = "exporter-lease-ref"
+	// The exporter of a running lease exists
+	CheckLeaseExporterRef = "lease-exporter-ref"
+	// The ended label of a lease matches its status
+	CheckLeaseEndedLabel = "lease-ended-label"
+	// A lease past its end time has ended
+	CheckLeaseExpired = "lease-expired"
+)
+
+var consistencyViolationsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "jumpstarter_consistency_violations_total",
+		Help: "Number of invariant violations found by the consistency checker, " +
+			"by check and whether they were repaired, reported only, or failed to be repaired",
+	},
+	[]string{"check", "action"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(consistencyViolationsTotal)
+}
+
+// ConsistencyChecker periodically verifies the invariants between leases and exporters that
+// events missed by the reconcilers, e.g. during an outage, could leave broken, and repairs them
+// by reconciling the objects again, or only reports them if Repair is not set
+type ConsistencyChecker struct {
+	client.Client
+	// Exporters and Leases reconcile the objects violating an invariant
+	Exporters reconcile.Reconciler
+	Leases    reconcile.Reconciler
+	// Interval between two checks
+	Interval time.Duration
+	// Grace is how long a lease may run past its end time before it is reported as stuck
+	Grace time.Duration
+	// Repair the violations, instead of only reporting them
+	Repair bool
+}
+
+// Start checks the invariants every Interval until ctx is done
+func (c *ConsistencyChecker) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("consistency-checker")
+	ctx = ctrl.LoggerInto(ctx, logger)
+
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := c.Check(ctx); err != nil {
+			logger.Error(err, "consistency check failed")
+		}
+	}, c.Interval)
+	return nil
+}
+
+// Check verifies the invariants once
+func (c *ConsistencyChecker) Check(ctx context.Context) error {
+	var leases jumpstarterdevv1alpha1.LeaseList
+	if err := c.List(ctx, &leases); err != nil {
+		return fmt.Errorf("Check: failed to list leases: %w", err)
+	}
+	var exporters jumpstarterdevv1alpha1.ExporterList
+	if err := c.List(ctx, &exporters); err != nil {
+		return fmt.Errorf("Check: failed to list exporters: %w", err)
+	}
+
+	existingLeases := map[types.NamespacedName]*jumpstarterdevv1alpha1.Lease{}
+	for i := range leases.Items {
+		existingLeases[client.ObjectKeyFromObject(&leases.Items[i])] = &leases.Items[i]
+	}
+	existingExporters := map[types.NamespacedName]bool{}
+	for i := range exporters.Items {
+		existingExporters[client.ObjectKeyFromObject(&exporters.Items[i])] = true
+	}
+
+	now := time.Now()
+	for i := range leases.Items {
+		lease := &leases.Items[i]
+		key := client.ObjectKeyFromObject(lease)
+
+		_, labeled := lease.Labels[string(jumpstarterdevv1alpha1.LeaseLabelEnded)]
+		if labeled != lease.Status.Ended {
+			c.violation(ctx, CheckLeaseEndedLabel, key, c.Leases, nil)
+		}
+
+		if lease.Status.Ended {
+			continue
+		}
+
+		if lease.Status.ExporterRef != nil && !existingExporters[types.NamespacedName{
+			Namespace: lease.Namespace,
+			Name:      lease.Status.ExporterRef.Name,
+		}] {
+			// the lease can never be used again, end it
+			c.violation(ctx, CheckLeaseExporterRef, key, c.Leases, func() error {
+				original := client.MergeFrom(lease.DeepCopy())
+				lease.Spec.Release = true
+				return c.Patch(ctx, lease, original)
+			})
+			continue
+		}
+
+		if lease.Status.BeginTime != nil &&
+			now.After(LeaseExpiration(lease, now).Add(c.Grace)) {
+			c.violation(ctx, CheckLeaseExpired, key, c.Leases, nil)
+		}
+	}
+
+	for i := range exporters.Items {
+		exporter := &exporters.Items[i]
+
+		holders := exporterHolders(exporter, leases.Items)
+		var holder string
+		if len(holders) > 0 {
+			holder = holders[0]
+		}
+
+		var leaseRef string
+		if exporter.Status.LeaseRef != nil {
+			leaseRef = exporter.Status.LeaseRef.Name
+		}
+		leaseRefs := make([]string, 0, len(exporter.Status.LeaseRefs))
+		for _, ref := range exporter.Status.LeaseRefs {
+			leaseRefs = append(leaseRefs, ref.Name)
+		}
+
+		if leaseRef != holder || !slices.Equal(leaseRefs, holders) {
+			c.violation(ctx, CheckExporterLeaseRef, client.ObjectKeyFromObject(exporter), c.Exporters, nil)
+		}
+	}
+
+	return nil
+}
+
+// violation reports the violation of check by the object named key, and if Repair is set,
+// runs prepare if not nil and reconciles the object again with reconciler
+func (c *ConsistencyChecker) violation(
+	ctx context.Context,
+	check string,
+	key types.NamespacedName,
+	reconciler reconcile.Reconciler,
+	prepare func() error,
+) {
+	logger := log.FromContext(ctx).WithValues("check", check, "object", key)
+
+	if !c.Repair || reconciler == nil {
+		logger.Info("invariant violated")
+		consistencyViolationsTotal.WithLabelValues(check, "reported").Inc()
+		return
+	}
+
+	if prepare != nil {
+		if err := prepare(); err != nil {
+			logger.Error(err, "failed to repair invariant violation")
+			consistencyViolationsTotal.WithLabelValues(check, "failed").Inc()
+			return
+		}
+	}
+	if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+		logger.Error(err, "failed to repair invariant violation")
+		consistencyViolationsTotal.WithLabelValues(check, "failed").Inc()
+		return
+	}
+
+	logger.Info("repaired invariant violation")
+	consistencyViolationsTotal.WithLabelValues(check, "repaired").Inc()
+}
+
+// SetupWithManager runs the checker with the manager, on the leader only
+func (c *ConsistencyChecker) SetupWithManager(mgr ctrl.Manager) error {
+	return mgr.Add(c)
+}
@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+var _ = Describe("Consistency checker", func() {
+	BeforeEach(func() {
+		createExporters(context.Background(), testExporter1DutA)
+	})
+	AfterEach(func() {
+		ctx := context.Background()
+		deleteExporters(ctx, testExporter1DutA)
+		deleteLeases(ctx, "lease1")
+	})
+
+	newChecker := func(repair bool) *ConsistencyChecker {
+		return &ConsistencyChecker{
+			Client:    k8sClient,
+			Exporters: &ExporterReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()},
+			Leases:    &LeaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()},
+			Interval:  time.Minute,
+			Grace:     time.Minute,
+			Repair:    repair,
+		}
+	}
+
+	orphanLeaseRef := func(ctx context.Context) {
+		exporter := getExporter(ctx, testExporter1DutA.Name)
+		exporter.Status.LeaseRef = &corev1.LocalObjectReference{Name: "deleted-lease"}
+		Expect(k8sClient.Status().Update(ctx, exporter)).To(Succeed())
+	}
+
+	It("should report orphaned exporter lease references", func() {
+		ctx := context.Background()
+		orphanLeaseRef(ctx)
+
+		reported := testutil.ToFloat64(consistencyViolationsTotal.WithLabelValues(CheckExporterLeaseRef, "reported"))
+		Expect(newChecker(false).Check(ctx)).To(Succeed())
+		Expect(testutil.ToFloat64(
+			consistencyViolationsTotal.WithLabelValues(CheckExporterLeaseRef, "reported"),
+		)).To(Equal(reported + 1))
+		Expect(getExporter(ctx, testExporter1DutA.Name).Status.LeaseRef).NotTo(BeNil())
+	})
+
+	It("should repair orphaned exporter lease references", func() {
+		ctx := context.Background()
+		orphanLeaseRef(ctx)
+
+		Expect(newChecker(true).Check(ctx)).To(Succeed())
+		Expect(getExporter(ctx, testExporter1DutA.Name).Status.LeaseRef).To(BeNil())
+	})
+
+	It("should repair the ended label of leases", func() {
+		ctx := context.Background()
+		lease := leaseDutA2Sec.DeepCopy()
+		Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+
+		lease.Status.Ended = true
+		lease.Status.EndTime = &metav1.Time{Time: time.Now()}
+		Expect(k8sClient.Status().Update(ctx, lease)).To(Succeed())
+
+		Expect(newChecker(true).Check(ctx)).To(Succeed())
+		Expect(getLease(ctx, lease.Name).Labels).To(HaveKeyWithValue(
+			string(jumpstarterdevv1alpha1.LeaseLabelEnded), jumpstarterdevv1alpha1.LeaseLabelEndedValue,
+		))
+	})
+})
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+func setExporterUnschedulable(ctx context.Context, name string, unschedulable bool) {
+	exporter := getExporter(ctx, name)
+	patch := client.MergeFrom(exporter.DeepCopy())
+	exporter.Spec.Unschedulable = unschedulable
+	Expect(k8sClient.Patch(ctx, exporter, patch)).To(Succeed())
+}
+
+var _ = Describe("Cordoned exporters", func() {
+	BeforeEach(func() {
+		ctx := context.Background()
+		createExporters(ctx, testExporter1DutA, testExporter2DutA)
+		setExporterOnlineConditions(ctx, testExporter1DutA.Name, metav1.ConditionTrue)
+		setExporterOnlineConditions(ctx, testExporter2DutA.Name, metav1.ConditionTrue)
+	})
+	AfterEach(func() {
+		ctx := context.Background()
+		deleteExporters(ctx, testExporter1DutA, testExporter2DutA)
+		deleteLeases(ctx, "lease1")
+	})
+
+	It("should not be acquired by new leases", func() {
+		ctx := context.Background()
+		setExporterUnschedulable(ctx, testExporter1DutA.Name, true)
+
+		lease := leaseDutA2Sec.DeepCopy()
+		Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+		_ = reconcileLease(ctx, lease)
+
+		updatedLease := getLease(ctx, lease.Name)
+		Expect(updatedLease.Status.ExporterRef).NotTo(BeNil())
+		Expect(updatedLease.Status.ExporterRef.Name).To(Equal(testExporter2DutA.Name))
+	})
+
+	It("should keep leases pending while every matching exporter is cordoned", func() {
+		ctx := context.Background()
+		setExporterUnschedulable(ctx, testExporter1DutA.Name, true)
+		setExporterUnschedulable(ctx, testExporter2DutA.Name, true)
+
+		lease := leaseDutA2Sec.DeepCopy()
+		Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+		_ = reconcileLease(ctx, lease)
+		Expect(getLease(ctx, lease.Name).Status.ExporterRef).To(BeNil())
+
+		setExporterUnschedulable(ctx, testExporter2DutA.Name, false)
+		_ = reconcileLease(ctx, lease)
+
+		updatedLease := getLease(ctx, lease.Name)
+		Expect(updatedLease.Status.ExporterRef).NotTo(BeNil())
+		Expect(updatedLease.Status.ExporterRef.Name).To(Equal(testExporter2DutA.Name))
+	})
+
+	It("should be drained once their active lease ends", func() {
+		ctx := context.Background()
+
+		lease := leaseDutA2Sec.DeepCopy()
+		Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+		_ = reconcileLease(ctx, lease)
+		held := getLease(ctx, lease.Name).Status.ExporterRef
+		Expect(held).NotTo(BeNil())
+
+		exporter := getExporter(ctx, held.Name)
+		patch := client.MergeFrom(exporter.DeepCopy())
+		exporter.Spec.Drain = true
+		Expect(k8sClient.Patch(ctx, exporter, patch)).To(Succeed())
+		_ = reconcileExporter(ctx, held.Name)
+
+		drained := meta.FindStatusCondition(getExporter(ctx, held.Name).Status.Conditions,
+			string(jumpstarterdevv1alpha1.ExporterConditionTypeDrained))
+		Expect(drained).NotTo(BeNil())
+		Expect(drained.Status).To(Equal(metav1.ConditionFalse))
+		Expect(drained.Reason).To(Equal("Draining"))
+
+		updatedLease := getLease(ctx, lease.Name)
+		updatedLease.Spec.Release = true
+		Expect(k8sClient.Update(ctx, updatedLease)).To(Succeed())
+		_ = reconcileLease(ctx, lease)
+		_ = reconcileExporter(ctx, held.Name)
+
+		Expect(meta.IsStatusConditionTrue(getExporter(ctx, held.Name).Status.Conditions,
+			string(jumpstarterdevv1alpha1.ExporterConditionTypeDrained))).To(BeTrue())
+	})
+})
@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a schedule in the standard five fields cron format: minute, hour, day of
+// the month, month and day of the week, each field is *, a value, a range, a list of them,
+// or a step of a range or *, e.g. "*/15 8-18 * * 1-5", evaluated in UTC
+type CronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// the day of the month and the day of the week match either, unless one of them is *
+	domStar, dowStar bool
+}
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCronSchedule parses a cron schedule, or one of the @yearly, @monthly, @weekly,
+// @daily and @hourly descriptors
+func ParseCronSchedule(spec string) (*CronSchedule, error) {
+	if descriptor, ok := cronDescriptors[strings.TrimSpace(spec)]; ok {
+		spec = descriptor
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("ParseCronSchedule: expected 5 fields, got %d in %q", len(fields), spec)
+	}
+
+	var (
+		schedule CronSchedule
+		err      error
+	)
+	if schedule.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("ParseCronSchedule: minute: %w", err)
+	}
+	if schedule.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("ParseCronSchedule: hour: %w", err)
+	}
+	if schedule.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("ParseCronSchedule: day of month: %w", err)
+	}
+	if schedule.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("ParseCronSchedule: month: %w", err)
+	}
+	if schedule.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("ParseCronSchedule: day of week: %w", err)
+	}
+	// 7 is Sunday as well
+	if schedule.dow&(1<<7) != 0 {
+		schedule.dow |= 1
+	}
+	schedule.domStar = strings.HasPrefix(fields[2], "*")
+	schedule.dowStar = strings.HasPrefix(fields[4], "*")
+	return &schedule, nil
+}
+
+// parseCronField returns the bits of the values of field, between low and high
+func parseCronField(field string, low, high int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		value, stepValue, hasStep := strings.Cut(part, "/")
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepValue); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepValue)
+			}
+			part = value
+		}
+
+		start, end := low, high
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			first, last, _ := strings.Cut(part, "-")
+			var err error
+			if start, err = strconv.Atoi(first); err != nil {
+				return 0, fmt.Errorf("invalid value %q", first)
+			}
+			if end, err = strconv.Atoi(last); err != nil {
+				return 0, fmt.Errorf("invalid value %q", last)
+			}
+		default:
+			value, err := strconv.Atoi(part)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			start = value
+			// a single value with a step runs to the end of the range, like in crontab
+			if !hasStep {
+				end = value
+			}
+		}
+		if start < low || end > high || start > end {
+			return 0, fmt.Errorf("%q out of the range %d-%d", part, low, high)
+		}
+		for value := start; value <= end; value += step {
+			bits |= 1 << value
+		}
+	}
+	return bits, nil
+}
+
+// Next returns the first time of the schedule after t, or the zero time if there is none
+// in the next five years, e.g. for the 30th of February
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (s *CronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
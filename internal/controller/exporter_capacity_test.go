@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+var _ = Describe("Exporter lease capacity", func() {
+	var shared *jumpstarterdevv1alpha1.Exporter
+
+	BeforeEach(func() {
+		ctx := context.Background()
+		capacity := int32(2)
+		shared = testExporter1DutA.DeepCopy()
+		shared.Spec.MaxConcurrentLeases = &capacity
+		createExporters(ctx, shared)
+		setExporterOnlineConditions(ctx, shared.Name, metav1.ConditionTrue)
+		setExporterMultipleLeases(ctx, shared.Name, true)
+	})
+	AfterEach(func() {
+		ctx := context.Background()
+		deleteExporters(ctx, shared)
+		deleteLeases(ctx, "lease1", "lease2", "lease3")
+	})
+
+	It("should assign an exporter to as many leases as its capacity", func() {
+		ctx := context.Background()
+
+		for _, name := range []string{"lease1", "lease2", "lease3"} {
+			lease := leaseDutA2Sec.DeepCopy()
+			lease.Name = name
+			Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+			_ = reconcileLease(ctx, lease)
+		}
+
+		Expect(getLease(ctx, "lease1").Status.ExporterRef).NotTo(BeNil())
+		Expect(getLease(ctx, "lease2").Status.ExporterRef).NotTo(BeNil())
+		Expect(getLease(ctx, "lease3").Status.ExporterRef).To(BeNil())
+
+		_ = reconcileExporter(ctx, shared.Name)
+		exporter := getExporter(ctx, shared.Name)
+		Expect(exporter.Status.LeaseRefs).To(Equal([]corev1.LocalObjectReference{
+			{Name: "lease1"},
+			{Name: "lease2"},
+		}))
+		Expect(exporter.Status.LeaseRef.Name).To(Equal("lease1"))
+	})
+
+	It("should assign a single lease to the exporters not serving multiple leases", func() {
+		ctx := context.Background()
+		setExporterMultipleLeases(ctx, shared.Name, false)
+
+		for _, name := range []string{"lease1", "lease2"} {
+			lease := leaseDutA2Sec.DeepCopy()
+			lease.Name = name
+			Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+			_ = reconcileLease(ctx, lease)
+		}
+
+		Expect(getLease(ctx, "lease1").Status.ExporterRef).NotTo(BeNil())
+		Expect(getLease(ctx, "lease2").Status.ExporterRef).To(BeNil())
+	})
+})
+
+func setExporterMultipleLeases(ctx context.Context, name string, multiple bool) {
+	exporter := getExporter(ctx, name)
+	exporter.Status.MultipleLeases = multiple
+	Expect(k8sClient.Status().Update(ctx, exporter)).To(Succeed())
+}
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// exporterClaimTTL is how long the claim of a lease that does not reference the exporter yet is
+// honored, the lease references it right after claiming it unless its status update failed
+const exporterClaimTTL = 30 * time.Second
+
+// errExporterClaimed is returned by claimExporters when another lease claimed one of the exporters
+var errExporterClaimed = errors.New("exporter claimed by another lease")
+
+// exporterClaimedByOther reports whether exporter is claimed by another active lease than lease,
+// claims of leases holding the exporter are honored until they end, claims of leases that do not
+// reference an exporter yet for exporterClaimTTL, other claims are stale
+func exporterClaimedByOther(
+	exporter *jumpstarterdevv1alpha1.Exporter,
+	lease *jumpstarterdevv1alpha1.Lease,
+	activeLeases []jumpstarterdevv1alpha1.Lease,
+	now time.Time,
+) bool {
+	claimant := exporter.Annotations[jumpstarterdevv1alpha1.ExporterAnnotationClaimedBy]
+	if claimant == "" || claimant == lease.Name {
+		return false
+	}
+	for i := range activeLeases {
+		other := &activeLeases[i]
+		if other.Name != claimant || other.Status.Ended {
+			continue
+		}
+		// the leases holding an exporter shared by several leases are counted by the NotLeasedFilter
+		if LeaseHoldsExporter(other, exporter.Name) {
+			return exporter.LeaseCapacity() <= 1
+		}
+		if other.Status.ExporterRef != nil {
+			return false
+		}
+		claimedAt, err := time.Parse(time.RFC3339,
+			exporter.Annotations[jumpstarterdevv1alpha1.ExporterAnnotationClaimedAt])
+		return err == nil && now.Sub(claimedAt) < exporterClaimTTL
+	}
+	return false
+}
+
+// claimExporters claims the exporters named names for lease, each conditionally on the resource
+// version of the exporter, so that two reconciles allocating the same exporter concurrently cannot
+// both claim it, the claims made are released if one of the exporters cannot be claimed
+func (r *LeaseReconciler) claimExporters(
+	ctx context.Context,
+	lease *jumpstarterdevv1alpha1.Lease,
+	names []string,
+	activeLeases []jumpstarterdevv1alpha1.Lease,
+) error {
+	now := time.Now()
+	var claimed []*jumpstarterdevv1alpha1.Exporter
+	for _, name := range names {
+		err := r.claimExporter(ctx, lease, name, activeLeases, now)
+		if err == nil {
+			var exporter jumpstarterdevv1alpha1.Exporter
+			exporter.Namespace, exporter.Name = lease.Namespace, name
+			claimed = append(claimed, &exporter)
+			continue
+		}
+		for _, exporter := range claimed {
+			// best effort, the claims of leases not referencing their exporters expire anyway
+			_ = r.Patch(ctx, exporter, client.RawPatch(types.MergePatchType, []byte(fmt.Sprintf(
+				`{"metadata":{"annotations":{%q:null,%q:null}}}`,
+				jumpstarterdevv1alpha1.ExporterAnnotationClaimedBy,
+				jumpstarterdevv1alpha1.ExporterAnnotationClaimedAt,
+			))))
+		}
+		return fmt.Errorf("claimExporters: %w", err)
+	}
+	return nil
+}
+
+func (r *LeaseReconciler) claimExporter(
+	ctx context.Context,
+	lease *jumpstarterdevv1alpha1.Lease,
+	name string,
+	activeLeases []jumpstarterdevv1alpha1.Lease,
+	now time.Time,
+) error {
+	var exporter jumpstarterdevv1alpha1.Exporter
+	if err := r.Get(ctx, types.NamespacedName{Namespace: lease.Namespace, Name: name}, &exporter); err != nil {
+		return err
+	}
+	if exporterClaimedByOther(&exporter, lease, activeLeases, now) {
+		return fmt.Errorf("%w: %s", errExporterClaimed, name)
+	}
+
+	// conditional on the resource version, a stale exporter fails with a conflict
+	original := client.MergeFromWithOptions(exporter.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	if exporter.Annotations == nil {
+		exporter.Annotations = map[string]string{}
+	}
+	exporter.Annotations[jumpstarterdevv1alpha1.ExporterAnnotationClaimedBy] = lease.Name
+	exporter.Annotations[jumpstarterdevv1alpha1.ExporterAnnotationClaimedAt] = now.UTC().Format(time.RFC3339)
+	return r.Patch(ctx, &exporter, original)
+}
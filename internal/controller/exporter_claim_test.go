@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+var _ = Describe("Exporter claims", func() {
+	BeforeEach(func() {
+		ctx := context.Background()
+		createExporters(ctx, testExporter1DutA)
+		setExporterOnlineConditions(ctx, testExporter1DutA.Name, metav1.ConditionTrue)
+	})
+	AfterEach(func() {
+		ctx := context.Background()
+		deleteExporters(ctx, testExporter1DutA)
+		deleteLeases(ctx, "lease1", "lease2")
+	})
+
+	It("should only honor the claims of active leases", func() {
+		now := time.Now()
+		exporter := testExporter1DutA.DeepCopy()
+		exporter.Annotations = map[string]string{
+			jumpstarterdevv1alpha1.ExporterAnnotationClaimedBy: "lease1",
+			jumpstarterdevv1alpha1.ExporterAnnotationClaimedAt: now.UTC().Format(time.RFC3339),
+		}
+		claimant := leaseDutA2Sec.DeepCopy()
+		other := leaseDutA2Sec.DeepCopy()
+		other.Name = "lease2"
+
+		active := []jumpstarterdevv1alpha1.Lease{*claimant}
+		Expect(exporterClaimedByOther(exporter, other, active, now)).To(BeTrue())
+		Expect(exporterClaimedByOther(exporter, claimant, active, now)).To(BeFalse())
+		// the claims of pending leases expire
+		Expect(exporterClaimedByOther(exporter, other, active, now.Add(exporterClaimTTL))).To(BeFalse())
+
+		// the claims of leases holding the exporter last until they end
+		active[0].Status.ExporterRef = &corev1.LocalObjectReference{Name: exporter.Name}
+		Expect(exporterClaimedByOther(exporter, other, active, now.Add(time.Hour))).To(BeTrue())
+		active[0].Status.Ended = true
+		Expect(exporterClaimedByOther(exporter, other, active, now)).To(BeFalse())
+		Expect(exporterClaimedByOther(exporter, other, nil, now)).To(BeFalse())
+	})
+
+	It("should not let two leases claim the exporter from the same version", func() {
+		ctx := context.Background()
+		leaseReconciler := &LeaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+
+		lease1 := leaseDutA2Sec.DeepCopy()
+		Expect(k8sClient.Create(ctx, lease1)).To(Succeed())
+		lease2 := leaseDutA2Sec.DeepCopy()
+		lease2.Name = "lease2"
+		Expect(k8sClient.Create(ctx, lease2)).To(Succeed())
+		active := []jumpstarterdevv1alpha1.Lease{*lease1, *lease2}
+
+		var exporter jumpstarterdevv1alpha1.Exporter
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(testExporter1DutA), &exporter)).To(Succeed())
+		stale := exporter.DeepCopy()
+
+		Expect(leaseReconciler.claimExporters(ctx, lease1, []string{exporter.Name}, active)).To(Succeed())
+
+		// a reconcile allocating from the previous version of the exporter conflicts
+		err := (&LeaseReconciler{Client: staleReader{Client: k8sClient, exporter: stale}}).
+			claimExporters(ctx, lease2, []string{exporter.Name}, active)
+		Expect(apierrors.IsConflict(err)).To(BeTrue())
+
+		// and the claim is honored once it sees the current version
+		err = leaseReconciler.claimExporters(ctx, lease2, []string{exporter.Name}, active)
+		Expect(err).To(MatchError(errExporterClaimed))
+	})
+})
+
+// staleReader returns a previous version of an exporter, as a lagging cache would
+type staleReader struct {
+	client.Client
+	exporter *jumpstarterdevv1alpha1.Exporter
+}
+
+func (r staleReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if exporter, ok := obj.(*jumpstarterdevv1alpha1.Exporter); ok && key.Name == r.exporter.Name {
+		r.exporter.DeepCopyInto(exporter)
+		return nil
+	}
+	return r.Client.Get(ctx, key, obj, opts...)
+}
@@ -19,14 +19,21 @@ package controller
 import (
 	"context"
 	"fmt"
+	"slices"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/structured-merge-diff/v4/fieldpath"
 
 	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
 )
@@ -40,7 +47,8 @@ type ExporterReconciler struct {
 // +kubebuilder:rbac:groups=jumpstarter.dev,resources=exporters,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=jumpstarter.dev,resources=exporters/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=jumpstarter.dev,resources=exporters/finalizers,verbs=update
-// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups=jumpstarter.dev,resources=maintenancewindows,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;create;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -61,7 +69,17 @@ func (r *ExporterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		)
 	}
 
-	original := client.MergeFrom(exporter.DeepCopy())
+	if err := AdoptLegacyFields(ctx, r.Client, &exporter, "status", exporterFieldManager,
+		fieldpath.MakePathOrDie("status", "credential"),
+		fieldpath.MakePathOrDie("status", "leaseRef"),
+		fieldpath.MakePathOrDie("status", "endpoint"),
+	); err != nil {
+		return ctrl.Result{}, fmt.Errorf("Reconcile: %w", err)
+	}
+
+	if err := applyChannelLabel(ctx, r.Client, &exporter, "Exporter", exporter.Spec.Channel, exporterFieldManager); err != nil {
+		return ctrl.Result{}, fmt.Errorf("Reconcile: %w", err)
+	}
 
 	if err := r.reconcileStatusCredential(ctx, &exporter); err != nil {
 		return ctrl.Result{}, err
@@ -75,11 +93,35 @@ func (r *ExporterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		return ctrl.Result{}, err
 	}
 
-	if err := r.Status().Patch(ctx, &exporter, original); err != nil {
-		return RequeueConflict(logger, ctrl.Result{}, err)
+	var result ctrl.Result
+	if err := r.reconcileStatusMaintenance(ctx, &result, &exporter); err != nil {
+		return result, err
 	}
 
-	return ctrl.Result{}, nil
+	r.reconcileStatusDrained(&exporter)
+
+	// the other conditions are owned by the controller service
+	var conditions []metav1.Condition
+	for _, conditionType := range []jumpstarterdevv1alpha1.LeaseConditionType{
+		jumpstarterdevv1alpha1.ExporterConditionTypeUnderMaintenance,
+		jumpstarterdevv1alpha1.ExporterConditionTypeDrained,
+	} {
+		if condition := meta.FindStatusCondition(exporter.Status.Conditions, string(conditionType)); condition != nil {
+			conditions = append(conditions, *condition)
+		}
+	}
+
+	if err := applyStatus(ctx, r.Client, &exporter, &jumpstarterdevv1alpha1.ExporterStatus{
+		Conditions: conditions,
+		Credential: exporter.Status.Credential,
+		LeaseRef:   exporter.Status.LeaseRef,
+		LeaseRefs:  exporter.Status.LeaseRefs,
+		Endpoint:   exporter.Status.Endpoint,
+	}, exporterFieldManager, ""); err != nil {
+		return RequeueConflict(logger, result, err)
+	}
+
+	return result, nil
 }
 
 func (r *ExporterReconciler) reconcileStatusCredential(
@@ -120,19 +162,33 @@ func (r *ExporterReconciler) reconcileStatusLeaseRef(
 	}
 
 	exporter.Status.LeaseRef = nil
-	for _, lease := range leases.Items {
-		if !lease.Status.Ended && lease.Status.ExporterRef != nil {
-			if lease.Status.ExporterRef.Name == exporter.Name {
-				exporter.Status.LeaseRef = &corev1.LocalObjectReference{
-					Name: lease.Name,
-				}
-			}
-		}
+	exporter.Status.LeaseRefs = nil
+	for _, name := range exporterHolders(exporter, leases.Items) {
+		exporter.Status.LeaseRefs = append(exporter.Status.LeaseRefs, corev1.LocalObjectReference{
+			Name: name,
+		})
+	}
+	if len(exporter.Status.LeaseRefs) > 0 {
+		exporter.Status.LeaseRef = &exporter.Status.LeaseRefs[0]
 	}
 
 	return nil
 }
 
+// exporterHolders returns the names of the active leases holding exporter, sorted, an exporter with
+// a lease capacity above 1 is held by several leases at the same time
+func exporterHolders(exporter *jumpstarterdevv1alpha1.Exporter, leases []jumpstarterdevv1alpha1.Lease) []string {
+	var names []string
+	for i := range leases {
+		if leases[i].Namespace == exporter.Namespace && !leases[i].Status.Ended &&
+			LeaseHoldsExporter(&leases[i], exporter.Name) {
+			names = append(names, leases[i].Name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
 // nolint:unparam
 func (r *ExporterReconciler) reconcileStatusEndpoint(
 	ctx context.Context,
@@ -149,6 +205,77 @@ func (r *ExporterReconciler) reconcileStatusEndpoint(
 	return nil
 }
 
+// Manages ExporterConditionTypeUnderMaintenance, present while a MaintenanceWindow selecting the exporter is open
+func (r *ExporterReconciler) reconcileStatusMaintenance(
+	ctx context.Context,
+	result *ctrl.Result,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+) error {
+	var windows jumpstarterdevv1alpha1.MaintenanceWindowList
+	if err := r.List(ctx, &windows, client.InNamespace(exporter.Namespace)); err != nil {
+		return fmt.Errorf("reconcileStatusMaintenance: failed to list maintenance windows: %w", err)
+	}
+
+	now := time.Now()
+	window, err := OverlappingMaintenance(windows.Items, exporter, now, now.Add(time.Nanosecond))
+	if err != nil {
+		return fmt.Errorf("reconcileStatusMaintenance: %w", err)
+	}
+	if next := nextMaintenanceChange(windows.Items, exporter, now); next > 0 {
+		result.RequeueAfter = next
+	}
+
+	if window == nil {
+		meta.RemoveStatusCondition(
+			&exporter.Status.Conditions,
+			string(jumpstarterdevv1alpha1.ExporterConditionTypeUnderMaintenance),
+		)
+		return nil
+	}
+
+	message := fmt.Sprintf("%s until %s", window.Name, window.Spec.End.Format(time.RFC3339))
+	if window.Spec.Reason != "" {
+		message += ": " + window.Spec.Reason
+	}
+	meta.SetStatusCondition(&exporter.Status.Conditions, metav1.Condition{
+		Type:               string(jumpstarterdevv1alpha1.ExporterConditionTypeUnderMaintenance),
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: exporter.Generation,
+		LastTransitionTime: metav1.Time{
+			Time: now,
+		},
+		Reason:  "MaintenanceWindow",
+		Message: message,
+	})
+	return nil
+}
+
+// Manages ExporterConditionTypeDrained, present while the exporter is drained, True once its
+// active lease ended
+func (r *ExporterReconciler) reconcileStatusDrained(exporter *jumpstarterdevv1alpha1.Exporter) {
+	if !exporter.Spec.Drain {
+		meta.RemoveStatusCondition(&exporter.Status.Conditions, string(jumpstarterdevv1alpha1.ExporterConditionTypeDrained))
+		return
+	}
+
+	condition := metav1.Condition{
+		Type:               string(jumpstarterdevv1alpha1.ExporterConditionTypeDrained),
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: exporter.Generation,
+		LastTransitionTime: metav1.Time{
+			Time: time.Now(),
+		},
+		Reason:  "Drained",
+		Message: "the exporter is kept offline",
+	}
+	if exporter.Status.LeaseRef != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Draining"
+		condition.Message = fmt.Sprintf("waiting for lease %s to end", exporter.Status.LeaseRef.Name)
+	}
+	meta.SetStatusCondition(&exporter.Status.Conditions, condition)
+}
+
 func (r *ExporterReconciler) secretForExporter(exporter *jumpstarterdevv1alpha1.Exporter) (*corev1.Secret, error) {
 	token, err := SignObjectToken(
 		"https://jumpstarter.dev/controller",
@@ -182,5 +309,44 @@ func (r *ExporterReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&jumpstarterdevv1alpha1.Exporter{}).
 		Owns(&jumpstarterdevv1alpha1.Lease{}).
+		// leases are only owned by their exporter, not by the exporters linked to it or of its roles
+		Watches(&jumpstarterdevv1alpha1.Lease{}, handler.EnqueueRequestsFromMapFunc(linkedExporterRequests)).
+		Watches(&jumpstarterdevv1alpha1.MaintenanceWindow{}, handler.EnqueueRequestsFromMapFunc(r.maintenanceRequests)).
 		Complete(r)
 }
+
+// maintenanceRequests requeues every exporter in the namespace of a MaintenanceWindow,
+// the ones its selector matched before a change as well as the ones it matches now
+func (r *ExporterReconciler) maintenanceRequests(ctx context.Context, obj client.Object) []reconcile.Request {
+	var exporters jumpstarterdevv1alpha1.ExporterList
+	if err := r.List(ctx, &exporters, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "maintenanceRequests: failed to list exporters")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(exporters.Items))
+	for i := range exporters.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: client.ObjectKeyFromObject(&exporters.Items[i]),
+		})
+	}
+	return requests
+}
+
+func linkedExporterRequests(_ context.Context, obj client.Object) []reconcile.Request {
+	lease, ok := obj.(*jumpstarterdevv1alpha1.Lease)
+	if !ok {
+		return nil
+	}
+	// the exporter of the lease is its owner
+	names := LeaseExporters(lease)
+	if len(names) > 0 {
+		names = names[1:]
+	}
+	requests := make([]reconcile.Request, 0, len(names))
+	for _, name := range names {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: lease.Namespace, Name: name},
+		})
+	}
+	return requests
+}
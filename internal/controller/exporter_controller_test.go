@@ -90,5 +90,25 @@ var _ = Describe("Exporter Controller", func() {
 			// TODO(user): Add more specific assertions depending on your controller's reconciliation logic.
 			// Example: If you expect a certain status condition after reconciliation, verify it here.
 		})
+
+		It("should remove a lease reference written by an update", func() {
+			By("Setting the lease reference without server-side apply")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, exporter)).To(Succeed())
+			exporter.Status.LeaseRef = &corev1.LocalObjectReference{Name: "gone"}
+			Expect(k8sClient.Status().Update(ctx, exporter)).To(Succeed())
+
+			controllerReconciler := &ExporterReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, exporter)).To(Succeed())
+			Expect(exporter.Status.LeaseRef).To(BeNil())
+		})
 	})
 })
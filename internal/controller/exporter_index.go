@@ -0,0 +1,182 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/sets"
+	toolscache "k8s.io/client-go/tools/cache"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// ExporterLabelIndex is an inverted index of exporter labels maintained from informer
+// events, it resolves lease selectors to exporter names without evaluating the selector
+// against every exporter in the namespace
+type ExporterLabelIndex struct {
+	mu         sync.RWMutex
+	namespaces map[string]*exporterNamespaceIndex
+	synced     func() bool
+}
+
+type exporterNamespaceIndex struct {
+	// label key -> label value -> exporter names
+	values map[string]map[string]sets.Set[string]
+	// exporter name -> labels
+	labels map[string]map[string]string
+}
+
+func NewExporterLabelIndex() *ExporterLabelIndex {
+	return &ExporterLabelIndex{
+		namespaces: map[string]*exporterNamespaceIndex{},
+	}
+}
+
+// Update adds or replaces the labels of exporter in the index
+func (i *ExporterLabelIndex) Update(exporter *jumpstarterdevv1alpha1.Exporter) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.delete(exporter.Namespace, exporter.Name)
+
+	ns, ok := i.namespaces[exporter.Namespace]
+	if !ok {
+		ns = &exporterNamespaceIndex{
+			values: map[string]map[string]sets.Set[string]{},
+			labels: map[string]map[string]string{},
+		}
+		i.namespaces[exporter.Namespace] = ns
+	}
+
+	stored := make(map[string]string, len(exporter.Labels))
+	for k, v := range exporter.Labels {
+		stored[k] = v
+		if ns.values[k] == nil {
+			ns.values[k] = map[string]sets.Set[string]{}
+		}
+		if ns.values[k][v] == nil {
+			ns.values[k][v] = sets.New[string]()
+		}
+		ns.values[k][v].Insert(exporter.Name)
+	}
+	ns.labels[exporter.Name] = stored
+}
+
+// Delete removes the exporter from the index
+func (i *ExporterLabelIndex) Delete(namespace string, name string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.delete(namespace, name)
+}
+
+func (i *ExporterLabelIndex) delete(namespace string, name string) {
+	ns, ok := i.namespaces[namespace]
+	if !ok {
+		return
+	}
+	for k, v := range ns.labels[name] {
+		ns.values[k][v].Delete(name)
+		if ns.values[k][v].Len() == 0 {
+			delete(ns.values[k], v)
+		}
+		if len(ns.values[k]) == 0 {
+			delete(ns.values, k)
+		}
+	}
+	delete(ns.labels, name)
+	if len(ns.labels) == 0 {
+		delete(i.namespaces, namespace)
+	}
+}
+
+// Synced reports whether the index reflects the initial listing of exporters
+func (i *ExporterLabelIndex) Synced() bool {
+	return i.synced == nil || i.synced()
+}
+
+// Match returns the sorted names of the exporters in namespace matching selector
+func (i *ExporterLabelIndex) Match(namespace string, selector labels.Selector) []string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	ns, ok := i.namespaces[namespace]
+	if !ok {
+		return nil
+	}
+
+	requirements, selectable := selector.Requirements()
+	if !selectable {
+		return nil
+	}
+
+	// narrow down the candidates with the equality requirements, the index can answer
+	// those directly, the remaining requirements are checked against the stored labels
+	var candidates sets.Set[string]
+	for _, requirement := range requirements {
+		switch requirement.Operator() {
+		case selection.Equals, selection.DoubleEquals, selection.In:
+			matching := sets.New[string]()
+			for _, value := range requirement.Values().UnsortedList() {
+				matching = matching.Union(ns.values[requirement.Key()][value])
+			}
+			if candidates == nil {
+				candidates = matching
+			} else {
+				candidates = candidates.Intersection(matching)
+			}
+		}
+	}
+	if candidates == nil {
+		candidates = sets.KeySet(ns.labels)
+	}
+
+	var names []string
+	for name := range candidates {
+		if selector.Matches(labels.Set(ns.labels[name])) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// SetupWithManager maintains the index from the exporter informer of the manager cache
+func (i *ExporterLabelIndex) SetupWithManager(mgr ctrl.Manager) error {
+	informer, err := mgr.GetCache().GetInformer(context.Background(), &jumpstarterdevv1alpha1.Exporter{})
+	if err != nil {
+		return fmt.Errorf("SetupWithManager: failed to get exporter informer: %w", err)
+	}
+
+	registration, err := informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if exporter, ok := obj.(*jumpstarterdevv1alpha1.Exporter); ok {
+				i.Update(exporter)
+			}
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			if exporter, ok := obj.(*jumpstarterdevv1alpha1.Exporter); ok {
+				i.Update(exporter)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if exporter, ok := obj.(*jumpstarterdevv1alpha1.Exporter); ok {
+				i.Delete(exporter.Namespace, exporter.Name)
+			}
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("SetupWithManager: failed to add exporter event handler: %w", err)
+	}
+
+	i.synced = registration.HasSynced
+	return nil
+}
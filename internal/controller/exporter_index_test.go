@@ -0,0 +1,56 @@
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+var _ = Describe("Exporter label index", func() {
+	exporter := func(name string, labels map[string]string) *jumpstarterdevv1alpha1.Exporter {
+		return &jumpstarterdevv1alpha1.Exporter{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      name,
+				Labels:    labels,
+			},
+		}
+	}
+
+	match := func(index *ExporterLabelIndex, selector string) []string {
+		parsed, err := labels.Parse(selector)
+		Expect(err).NotTo(HaveOccurred())
+		return index.Match("default", parsed)
+	}
+
+	It("should match exporters by selector", func() {
+		index := NewExporterLabelIndex()
+		index.Update(exporter("a", map[string]string{"dut": "a", "rack": "1"}))
+		index.Update(exporter("b", map[string]string{"dut": "a", "rack": "2"}))
+		index.Update(exporter("c", map[string]string{"dut": "b"}))
+
+		Expect(match(index, "dut=a")).To(Equal([]string{"a", "b"}))
+		Expect(match(index, "dut=a,rack=2")).To(Equal([]string{"b"}))
+		Expect(match(index, "dut in (a,b),rack!=1")).To(Equal([]string{"b", "c"}))
+		Expect(match(index, "!rack")).To(Equal([]string{"c"}))
+		Expect(match(index, "dut=c")).To(BeEmpty())
+		Expect(index.Match("other", labels.Everything())).To(BeEmpty())
+	})
+
+	It("should follow label updates and deletions", func() {
+		index := NewExporterLabelIndex()
+		index.Update(exporter("a", map[string]string{"dut": "a"}))
+		index.Update(exporter("a", map[string]string{"dut": "b"}))
+
+		Expect(match(index, "dut=a")).To(BeEmpty())
+		Expect(match(index, "dut=b")).To(Equal([]string{"a"}))
+
+		index.Delete("default", "a")
+		Expect(match(index, "dut=b")).To(BeEmpty())
+		Expect(index.namespaces).To(BeEmpty())
+	})
+})
@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+const (
+	// ExporterLabelPrefix is the prefix of the labels managed by the exporter itself
+	ExporterLabelPrefix = "jumpstarter.dev/"
+	// MaxLabelHistory is the number of label set revisions kept on exporter status
+	MaxLabelHistory = 10
+)
+
+const (
+	LabelSourceRegister = "Register"
+	LabelSourceRestore  = "Restore"
+)
+
+// ManagedExporterLabels returns the jumpstarter.dev/ labels from labels
+func ManagedExporterLabels(labels map[string]string) map[string]string {
+	managed := make(map[string]string)
+	for k, v := range labels {
+		if strings.HasPrefix(k, ExporterLabelPrefix) {
+			managed[k] = v
+		}
+	}
+	return managed
+}
+
+// SetManagedExporterLabels replaces the jumpstarter.dev/ labels of exporter with the ones from labels
+func SetManagedExporterLabels(exporter *jumpstarterdevv1alpha1.Exporter, labels map[string]string) {
+	if exporter.Labels == nil {
+		exporter.Labels = make(map[string]string)
+	}
+
+	for k := range exporter.Labels {
+		if strings.HasPrefix(k, ExporterLabelPrefix) {
+			delete(exporter.Labels, k)
+		}
+	}
+
+	maps.Copy(exporter.Labels, ManagedExporterLabels(labels))
+}
+
+// RecordExporterLabels appends the current jumpstarter.dev/ labels of exporter to its label history,
+// unless they are identical to the latest revision, dropping the oldest revisions beyond MaxLabelHistory
+func RecordExporterLabels(exporter *jumpstarterdevv1alpha1.Exporter, source string) {
+	labels := ManagedExporterLabels(exporter.Labels)
+
+	history := exporter.Status.LabelHistory
+	revision := int64(1)
+	if len(history) > 0 {
+		latest := history[len(history)-1]
+		if maps.Equal(latest.Labels, labels) {
+			return
+		}
+		revision = latest.Revision + 1
+	}
+
+	history = append(history, jumpstarterdevv1alpha1.LabelSetRevision{
+		Revision: revision,
+		Labels:   labels,
+		Time:     metav1.Time{Time: time.Now()},
+		Source:   source,
+	})
+	if len(history) > MaxLabelHistory {
+		history = history[len(history)-MaxLabelHistory:]
+	}
+	exporter.Status.LabelHistory = history
+}
+
+// FindLabelSetRevision looks up a revision in the label history of exporter
+func FindLabelSetRevision(
+	exporter *jumpstarterdevv1alpha1.Exporter,
+	revision int64,
+) (*jumpstarterdevv1alpha1.LabelSetRevision, error) {
+	for i := range exporter.Status.LabelHistory {
+		if exporter.Status.LabelHistory[i].Revision == revision {
+			return &exporter.Status.LabelHistory[i], nil
+		}
+	}
+	return nil, fmt.Errorf("FindLabelSetRevision: revision %d not found in label history of exporter %s/%s",
+		revision, exporter.Namespace, exporter.Name)
+}
+
+// RestoreExporterLabels replaces the jumpstarter.dev/ labels of exporter with the ones of a revision of
+// its label history, and pins them to it so that Register keeps them until UnpinExporterLabels
+func RestoreExporterLabels(exporter *jumpstarterdevv1alpha1.Exporter, revision int64) error {
+	target, err := FindLabelSetRevision(exporter, revision)
+	if err != nil {
+		return fmt.Errorf("RestoreExporterLabels: %w", err)
+	}
+	SetManagedExporterLabels(exporter, target.Labels)
+	if exporter.Annotations == nil {
+		exporter.Annotations = make(map[string]string)
+	}
+	exporter.Annotations[jumpstarterdevv1alpha1.ExporterAnnotationPinnedLabelRevision] = strconv.FormatInt(revision, 10)
+	return nil
+}
+
+// UnpinExporterLabels removes the pin of the labels of exporter, the next Register applies the labels
+// reported by the exporter again
+func UnpinExporterLabels(exporter *jumpstarterdevv1alpha1.Exporter) {
+	delete(exporter.Annotations, jumpstarterdevv1alpha1.ExporterAnnotationPinnedLabelRevision)
+}
+
+// PinnedLabelRevision returns the revision the labels of exporter are pinned to, 0 if the annotation
+// does not hold a revision, and whether they are pinned at all
+func PinnedLabelRevision(exporter *jumpstarterdevv1alpha1.Exporter) (int64, bool) {
+	value, ok := exporter.Annotations[jumpstarterdevv1alpha1.ExporterAnnotationPinnedLabelRevision]
+	if !ok {
+		return 0, false
+	}
+	revision, _ := strconv.ParseInt(value, 10, 64)
+	return revision, true
+}
@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+var _ = Describe("Exporter label history", func() {
+	It("should only record changed label sets", func() {
+		exporter := &jumpstarterdevv1alpha1.Exporter{}
+		SetManagedExporterLabels(exporter, map[string]string{
+			"jumpstarter.dev/board": "rpi4",
+			"unmanaged":             "ignored",
+		})
+		RecordExporterLabels(exporter, LabelSourceRegister)
+		RecordExporterLabels(exporter, LabelSourceRegister)
+
+		Expect(exporter.Status.LabelHistory).To(HaveLen(1))
+		Expect(exporter.Status.LabelHistory[0].Revision).To(Equal(int64(1)))
+		Expect(exporter.Status.LabelHistory[0].Labels).To(Equal(map[string]string{
+			"jumpstarter.dev/board": "rpi4",
+		}))
+	})
+
+	It("should keep at most MaxLabelHistory revisions", func() {
+		exporter := &jumpstarterdevv1alpha1.Exporter{}
+		for i := 0; i < MaxLabelHistory+5; i++ {
+			SetManagedExporterLabels(exporter, map[string]string{
+				"jumpstarter.dev/index": fmt.Sprint(i),
+			})
+			RecordExporterLabels(exporter, LabelSourceRegister)
+		}
+
+		Expect(exporter.Status.LabelHistory).To(HaveLen(MaxLabelHistory))
+		Expect(exporter.Status.LabelHistory[0].Revision).To(Equal(int64(6)))
+
+		_, err := FindLabelSetRevision(exporter, 1)
+		Expect(err).To(HaveOccurred())
+
+		revision, err := FindLabelSetRevision(exporter, 6)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(revision.Labels).To(HaveKeyWithValue("jumpstarter.dev/index", "5"))
+	})
+
+	It("should pin the labels restored from a revision", func() {
+		exporter := &jumpstarterdevv1alpha1.Exporter{}
+		for _, board := range []string{"rpi4", "rpi5"} {
+			SetManagedExporterLabels(exporter, map[string]string{"jumpstarter.dev/board": board})
+			RecordExporterLabels(exporter, LabelSourceRegister)
+		}
+
+		Expect(RestoreExporterLabels(exporter, 3)).NotTo(Succeed())
+		Expect(RestoreExporterLabels(exporter, 1)).To(Succeed())
+		Expect(exporter.Labels).To(HaveKeyWithValue("jumpstarter.dev/board", "rpi4"))
+		revision, pinned := PinnedLabelRevision(exporter)
+		Expect(pinned).To(BeTrue())
+		Expect(revision).To(Equal(int64(1)))
+
+		UnpinExporterLabels(exporter)
+		_, pinned = PinnedLabelRevision(exporter)
+		Expect(pinned).To(BeFalse())
+	})
+})
@@ -0,0 +1,167 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// ExporterAccessPolicyReconciler reports in the status of the ExporterAccessPolicies the exporters
+// they select, and the clients and the active leases each of their policies grants access to
+type ExporterAccessPolicyReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// AccessPolicyTieBreak is which of the policies of equal priority matching a client applies,
+	// defaults to PolicyTieBreakMostRestrictive
+	AccessPolicyTieBreak PolicyTieBreak
+}
+
+// +kubebuilder:rbac:groups=jumpstarter.dev,resources=exporteraccesspolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=jumpstarter.dev,resources=exporteraccesspolicies/status,verbs=get;update;patch
+
+func (r *ExporterAccessPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var policies jumpstarterdevv1alpha1.ExporterAccessPolicyList
+	if err := r.List(ctx, &policies, client.InNamespace(req.Namespace)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("Reconcile: failed to list exporter access policies: %w", err)
+	}
+
+	var policy *jumpstarterdevv1alpha1.ExporterAccessPolicy
+	for i := range policies.Items {
+		if policies.Items[i].Name == req.Name {
+			policy = &policies.Items[i]
+		}
+	}
+	if policy == nil {
+		return ctrl.Result{}, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(&policy.Spec.ExporterSelector)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("Reconcile: failed to create selector from label selector: %w", err)
+	}
+
+	var exporters jumpstarterdevv1alpha1.ExporterList
+	if err := r.List(
+		ctx,
+		&exporters,
+		client.InNamespace(policy.Namespace),
+		client.MatchingLabelsSelector{Selector: selector},
+	); err != nil {
+		return ctrl.Result{}, fmt.Errorf("Reconcile: failed to list exporters matching selector: %w", err)
+	}
+
+	var clients jumpstarterdevv1alpha1.ClientList
+	if err := r.List(ctx, &clients, client.InNamespace(policy.Namespace)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("Reconcile: failed to list clients: %w", err)
+	}
+
+	var leases jumpstarterdevv1alpha1.LeaseList
+	if err := r.List(ctx, &leases, client.InNamespace(policy.Namespace), MatchingActiveLeases()); err != nil {
+		return ctrl.Result{}, fmt.Errorf("Reconcile: failed to list active leases: %w", err)
+	}
+
+	status := jumpstarterdevv1alpha1.ExporterAccessPolicyStatus{
+		MatchedExporters: int32(len(exporters.Items)),
+		Policies:         make([]jumpstarterdevv1alpha1.PolicyStatus, len(policy.Spec.Policies)),
+	}
+
+	clientsByName := map[string]*jumpstarterdevv1alpha1.Client{}
+	for i := range clients.Items {
+		client := &clients.Items[i]
+		clientsByName[client.Name] = client
+		for j := range policy.Spec.Policies {
+			selected, err := policySelects(&policy.Spec.Policies[j], client)
+			if err != nil {
+				return ctrl.Result{}, fmt.Errorf("Reconcile: invalid client selector: %w", err)
+			}
+			if selected {
+				status.Policies[j].MatchedClients++
+			}
+		}
+	}
+
+	exportersByName := map[string]*jumpstarterdevv1alpha1.Exporter{}
+	for i := range exporters.Items {
+		exportersByName[exporters.Items[i].Name] = &exporters.Items[i]
+	}
+
+	// a lease is granted by the policy the access decision of its client and exporter comes from
+	for i := range leases.Items {
+		lease := &leases.Items[i]
+		if lease.Status.ExporterRef == nil {
+			continue
+		}
+		exporter, ok := exportersByName[lease.Status.ExporterRef.Name]
+		if !ok {
+			continue
+		}
+		client, ok := clientsByName[lease.Spec.ClientRef.Name]
+		if !ok {
+			continue
+		}
+		decision, err := EvaluateAccessPolicies(
+			policies.Items, r.AccessPolicyTieBreak, LeaseClient(client, lease), exporter,
+		)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("Reconcile: %w", err)
+		}
+		if decision.PolicyName != policy.Name {
+			continue
+		}
+		for j := range policy.Spec.Policies {
+			if decision.Policy == &policy.Spec.Policies[j] {
+				status.Policies[j].ActiveLeases++
+			}
+		}
+	}
+
+	policy.Status = status
+	if err := r.Status().Update(ctx, policy); err != nil {
+		return RequeueConflict(logger, ctrl.Result{}, err)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// namespacePolicyRequests returns the ExporterAccessPolicies of the namespace of obj, the exporters,
+// clients and leases of a namespace may change the status of any of them
+func (r *ExporterAccessPolicyReconciler) namespacePolicyRequests(ctx context.Context, obj client.Object) []reconcile.Request {
+	var policies jumpstarterdevv1alpha1.ExporterAccessPolicyList
+	if err := r.List(ctx, &policies, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "namespacePolicyRequests: failed to list exporter access policies")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(policies.Items))
+	for i := range policies.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: policies.Items[i].Namespace,
+				Name:      policies.Items[i].Name,
+			},
+		})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ExporterAccessPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&jumpstarterdevv1alpha1.ExporterAccessPolicy{}).
+		Watches(&jumpstarterdevv1alpha1.Exporter{}, handler.EnqueueRequestsFromMapFunc(r.namespacePolicyRequests)).
+		Watches(&jumpstarterdevv1alpha1.Client{}, handler.EnqueueRequestsFromMapFunc(r.namespacePolicyRequests)).
+		Watches(&jumpstarterdevv1alpha1.Lease{}, handler.EnqueueRequestsFromMapFunc(r.namespacePolicyRequests)).
+		Complete(r)
+}
@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+var _ = Describe("ExporterAccessPolicy Controller", func() {
+	var policy jumpstarterdevv1alpha1.ExporterAccessPolicy
+
+	BeforeEach(func() {
+		ctx := context.Background()
+		createExporters(ctx, testExporter1DutA, testExporter2DutA, testExporter3DutB)
+		setExporterOnlineConditions(ctx, testExporter1DutA.Name, metav1.ConditionTrue)
+		setExporterOnlineConditions(ctx, testExporter2DutA.Name, metav1.ConditionTrue)
+		policy = accessPolicy(map[string]string{"dut": "a"},
+			fromClients(10, nil),
+			fromClients(0, map[string]string{"team": "ci"}),
+		)
+		Expect(k8sClient.Create(ctx, &policy)).To(Succeed())
+	})
+	AfterEach(func() {
+		ctx := context.Background()
+		deleteExporters(ctx, testExporter1DutA, testExporter2DutA, testExporter3DutB)
+		deleteLeases(ctx, "lease1")
+		Expect(k8sClient.Delete(ctx, &policy)).To(Succeed())
+	})
+
+	It("should report the exporters, clients and active leases of each policy", func() {
+		ctx := context.Background()
+
+		lease := leaseDutA2Sec.DeepCopy()
+		Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+		_ = reconcileLease(ctx, lease)
+		Expect(getLease(ctx, lease.Name).Status.ExporterRef).NotTo(BeNil())
+
+		policyReconciler := &ExporterAccessPolicyReconciler{
+			Client: k8sClient,
+			Scheme: k8sClient.Scheme(),
+		}
+		_, err := policyReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&policy)})
+		Expect(err).NotTo(HaveOccurred())
+
+		var updated jumpstarterdevv1alpha1.ExporterAccessPolicy
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(&policy), &updated)).To(Succeed())
+		Expect(updated.Status.MatchedExporters).To(Equal(int32(2)))
+		Expect(updated.Status.Policies).To(Equal([]jumpstarterdevv1alpha1.PolicyStatus{
+			{MatchedClients: 1, ActiveLeases: 1},
+			{MatchedClients: 0, ActiveLeases: 0},
+		}))
+	})
+})
@@ -0,0 +1,216 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// ExporterUpdatePolicyReconciler rolls out exporter software updates, instructing idle
+// exporters to update a few at a time and halting when too many updates fail
+type ExporterUpdatePolicyReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// exporterUpdateState is the state of a single exporter in a rollout
+type exporterUpdateState int
+
+const (
+	exporterUpdatePending exporterUpdateState = iota
+	exporterUpdateInProgress
+	exporterUpdateDone
+	exporterUpdateFailed
+)
+
+// ExporterUpdateDeadline returns the deadline of the update the exporter has been instructed
+// to perform, and whether such an update exists
+func ExporterUpdateDeadline(exporter *jumpstarterdevv1alpha1.Exporter) (time.Time, bool) {
+	version, ok := exporter.Annotations[jumpstarterdevv1alpha1.ExporterAnnotationUpdateVersion]
+	if !ok || exporter.Labels[jumpstarterdevv1alpha1.ExporterLabelVersion] == version {
+		return time.Time{}, false
+	}
+	deadline, err := time.Parse(time.RFC3339,
+		exporter.Annotations[jumpstarterdevv1alpha1.ExporterAnnotationUpdateDeadline])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return deadline, true
+}
+
+func exporterUpdateStateOf(
+	exporter *jumpstarterdevv1alpha1.Exporter,
+	target string,
+	now time.Time,
+) exporterUpdateState {
+	if exporter.Labels[jumpstarterdevv1alpha1.ExporterLabelVersion] == target {
+		return exporterUpdateDone
+	}
+	if exporter.Annotations[jumpstarterdevv1alpha1.ExporterAnnotationUpdateVersion] != target {
+		return exporterUpdatePending
+	}
+	if deadline, ok := ExporterUpdateDeadline(exporter); ok && now.Before(deadline) {
+		return exporterUpdateInProgress
+	}
+	return exporterUpdateFailed
+}
+
+// exporterIdle reports whether the exporter is online and not leased
+func exporterIdle(exporter *jumpstarterdevv1alpha1.Exporter) bool {
+	return exporter.Status.LeaseRef == nil && meta.IsStatusConditionTrue(
+		exporter.Status.Conditions,
+		string(jumpstarterdevv1alpha1.ExporterConditionTypeOnline),
+	)
+}
+
+// +kubebuilder:rbac:groups=jumpstarter.dev,resources=exporterupdatepolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=jumpstarter.dev,resources=exporterupdatepolicies/status,verbs=get;update;patch
+
+func (r *ExporterUpdatePolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var policy jumpstarterdevv1alpha1.ExporterUpdatePolicy
+	if err := r.Get(ctx, req.NamespacedName, &policy); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(
+			fmt.Errorf("Reconcile: unable to get exporter update policy: %w", err),
+		)
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(&policy.Spec.Selector)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("Reconcile: failed to create selector from label selector: %w", err)
+	}
+
+	var exporters jumpstarterdevv1alpha1.ExporterList
+	if err := r.List(
+		ctx,
+		&exporters,
+		client.InNamespace(policy.Namespace),
+		client.MatchingLabelsSelector{Selector: selector},
+	); err != nil {
+		return ctrl.Result{}, fmt.Errorf("Reconcile: failed to list exporters matching selector: %w", err)
+	}
+
+	now := time.Now()
+	target := policy.Spec.TargetVersion
+
+	var updated, updating, failed, attempted int32
+	var pending []*jumpstarterdevv1alpha1.Exporter
+	for i := range exporters.Items {
+		exporter := &exporters.Items[i]
+		// only the updates instructed by the rollout count towards the failure rate
+		if exporter.Annotations[jumpstarterdevv1alpha1.ExporterAnnotationUpdateVersion] == target {
+			attempted++
+		}
+		switch exporterUpdateStateOf(exporter, target, now) {
+		case exporterUpdateDone:
+			updated++
+		case exporterUpdateInProgress:
+			updating++
+		case exporterUpdateFailed:
+			failed++
+		default:
+			pending = append(pending, exporter)
+		}
+	}
+
+	halted := attempted > 0 && failed*100 > policy.Spec.MaxFailurePercentage*attempted
+
+	if !halted {
+		for _, exporter := range pending {
+			if updating >= max(policy.Spec.MaxUnavailable, 1) {
+				break
+			}
+			if !exporterIdle(exporter) {
+				continue
+			}
+			logger.Info("Reconcile: instructing exporter to update",
+				"exporter", exporter.Name, "version", target)
+			original := client.MergeFrom(exporter.DeepCopy())
+			if exporter.Annotations == nil {
+				exporter.Annotations = make(map[string]string)
+			}
+			exporter.Annotations[jumpstarterdevv1alpha1.ExporterAnnotationUpdateVersion] = target
+			exporter.Annotations[jumpstarterdevv1alpha1.ExporterAnnotationUpdateDeadline] =
+				now.Add(policy.Spec.UpdateTimeout.Duration).UTC().Format(time.RFC3339)
+			if err := r.Patch(ctx, exporter, original); err != nil {
+				return RequeueConflict(logger, ctrl.Result{}, fmt.Errorf("Reconcile: failed to patch exporter: %w", err))
+			}
+			updating++
+		}
+	}
+
+	policy.Status.Total = int32(len(exporters.Items))
+	policy.Status.Updated = updated
+	policy.Status.Updating = updating
+	policy.Status.Failed = failed
+
+	complete := updated == policy.Status.Total
+	meta.SetStatusCondition(&policy.Status.Conditions, exporterUpdatePolicyCondition(
+		&policy, jumpstarterdevv1alpha1.ExporterUpdatePolicyConditionTypeComplete, complete, "Rollout"))
+	meta.SetStatusCondition(&policy.Status.Conditions, exporterUpdatePolicyCondition(
+		&policy, jumpstarterdevv1alpha1.ExporterUpdatePolicyConditionTypeHalted, halted, "FailureRate"))
+
+	if err := r.Status().Update(ctx, &policy); err != nil {
+		return RequeueConflict(logger, ctrl.Result{}, err)
+	}
+
+	// keep polling for exporters becoming idle or reaching their deadline
+	if complete {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+}
+
+func exporterUpdatePolicyCondition(
+	policy *jumpstarterdevv1alpha1.ExporterUpdatePolicy,
+	conditionType jumpstarterdevv1alpha1.ExporterUpdatePolicyConditionType,
+	value bool,
+	reason string,
+) metav1.Condition {
+	status := metav1.ConditionFalse
+	if value {
+		status = metav1.ConditionTrue
+	}
+	return metav1.Condition{
+		Type:               string(conditionType),
+		Status:             status,
+		ObservedGeneration: policy.Generation,
+		LastTransitionTime: metav1.Time{
+			Time: time.Now(),
+		},
+		Reason: reason,
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ExporterUpdatePolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&jumpstarterdevv1alpha1.ExporterUpdatePolicy{}).
+		Complete(r)
+}
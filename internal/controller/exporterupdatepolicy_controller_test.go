@@ -0,0 +1,165 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+var testUpdatePolicyDutA = &jumpstarterdevv1alpha1.ExporterUpdatePolicy{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "update-dut-a",
+		Namespace: "default",
+	},
+	Spec: jumpstarterdevv1alpha1.ExporterUpdatePolicySpec{
+		TargetVersion: "v2",
+		Selector: metav1.LabelSelector{
+			MatchLabels: map[string]string{
+				"dut": "a",
+			},
+		},
+		MaxUnavailable:       1,
+		MaxFailurePercentage: 20,
+		UpdateTimeout:        metav1.Duration{Duration: 10 * time.Minute},
+	},
+}
+
+var _ = Describe("ExporterUpdatePolicy Controller", func() {
+	BeforeEach(func() {
+		ctx := context.Background()
+		createExporters(ctx, testExporter1DutA, testExporter2DutA, testExporter3DutB)
+		setExporterOnlineConditions(ctx, testExporter1DutA.Name, metav1.ConditionTrue)
+		setExporterOnlineConditions(ctx, testExporter2DutA.Name, metav1.ConditionTrue)
+		setExporterOnlineConditions(ctx, testExporter3DutB.Name, metav1.ConditionTrue)
+		Expect(k8sClient.Create(ctx, testUpdatePolicyDutA.DeepCopy())).To(Succeed())
+	})
+	AfterEach(func() {
+		ctx := context.Background()
+		deleteExporters(ctx, testExporter1DutA, testExporter2DutA, testExporter3DutB)
+		deleteLeases(ctx, "lease1")
+		Expect(k8sClient.Delete(ctx, testUpdatePolicyDutA.DeepCopy())).To(Succeed())
+	})
+
+	It("should update idle exporters one at a time", func() {
+		ctx := context.Background()
+
+		policy := reconcileUpdatePolicy(ctx)
+		Expect(policy.Status.Total).To(Equal(int32(2)))
+		Expect(policy.Status.Updating).To(Equal(int32(1)))
+
+		first := updatingExporters(ctx)
+		Expect(first).To(HaveLen(1))
+		Expect(getExporter(ctx, testExporter3DutB.Name).Annotations).To(BeEmpty())
+
+		By("the first exporter reporting the target version")
+		setExporterVersion(ctx, first[0], "v2")
+
+		policy = reconcileUpdatePolicy(ctx)
+		Expect(policy.Status.Updated).To(Equal(int32(1)))
+		Expect(policy.Status.Updating).To(Equal(int32(1)))
+
+		second := updatingExporters(ctx)
+		Expect(second).To(HaveLen(1))
+		Expect(second[0]).NotTo(Equal(first[0]))
+
+		setExporterVersion(ctx, second[0], "v2")
+		policy = reconcileUpdatePolicy(ctx)
+		Expect(policy.Status.Updated).To(Equal(int32(2)))
+		Expect(meta.IsStatusConditionTrue(policy.Status.Conditions,
+			string(jumpstarterdevv1alpha1.ExporterUpdatePolicyConditionTypeComplete))).To(BeTrue())
+	})
+
+	It("should halt when too many updates fail", func() {
+		ctx := context.Background()
+
+		_ = reconcileUpdatePolicy(ctx)
+		updating := updatingExporters(ctx)
+		Expect(updating).To(HaveLen(1))
+
+		By("the update deadline passing")
+		exporter := getExporter(ctx, updating[0])
+		exporter.Annotations[jumpstarterdevv1alpha1.ExporterAnnotationUpdateDeadline] =
+			time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+		Expect(k8sClient.Update(ctx, exporter)).To(Succeed())
+
+		policy := reconcileUpdatePolicy(ctx)
+		Expect(policy.Status.Failed).To(Equal(int32(1)))
+		Expect(policy.Status.Updating).To(Equal(int32(0)))
+		Expect(meta.IsStatusConditionTrue(policy.Status.Conditions,
+			string(jumpstarterdevv1alpha1.ExporterUpdatePolicyConditionTypeHalted))).To(BeTrue())
+		Expect(updatingExporters(ctx)).To(BeEmpty())
+	})
+
+	It("should not lease exporters being updated", func() {
+		ctx := context.Background()
+
+		_ = reconcileUpdatePolicy(ctx)
+		updating := updatingExporters(ctx)
+		Expect(updating).To(HaveLen(1))
+
+		lease := leaseDutA2Sec.DeepCopy()
+		Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+		_ = reconcileLease(ctx, lease)
+
+		updatedLease := getLease(ctx, lease.Name)
+		Expect(updatedLease.Status.ExporterRef).NotTo(BeNil())
+		Expect(updatedLease.Status.ExporterRef.Name).NotTo(Equal(updating[0]))
+	})
+})
+
+func reconcileUpdatePolicy(ctx context.Context) *jumpstarterdevv1alpha1.ExporterUpdatePolicy {
+	reconciler := &ExporterUpdatePolicyReconciler{
+		Client: k8sClient,
+		Scheme: k8sClient.Scheme(),
+	}
+
+	key := types.NamespacedName{Namespace: "default", Name: testUpdatePolicyDutA.Name}
+	_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+	Expect(err).NotTo(HaveOccurred())
+
+	policy := &jumpstarterdevv1alpha1.ExporterUpdatePolicy{}
+	Expect(k8sClient.Get(ctx, key, policy)).To(Succeed())
+	return policy
+}
+
+// updatingExporters returns the names of the exporters with an update in progress
+func updatingExporters(ctx context.Context) []string {
+	var names []string
+	for _, name := range []string{testExporter1DutA.Name, testExporter2DutA.Name, testExporter3DutB.Name} {
+		deadline, ok := ExporterUpdateDeadline(getExporter(ctx, name))
+		if ok && time.Now().Before(deadline) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func setExporterVersion(ctx context.Context, name string, version string) {
+	exporter := getExporter(ctx, name)
+	exporter.Labels[jumpstarterdevv1alpha1.ExporterLabelVersion] = version
+	Expect(k8sClient.Update(ctx, exporter)).To(Succeed())
+}
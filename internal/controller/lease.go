@@ -1,6 +1,9 @@
 package controller
 
 import (
+	"slices"
+	"time"
+
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/selection"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
@@ -9,6 +12,60 @@ import (
 	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
 )
 
+// LeaseHoldsExporter reports whether lease holds the exporter named name,
+// either as its exporter, one of the exporters linked to it or an exporter of its roles
+func LeaseHoldsExporter(lease *jumpstarterdevv1alpha1.Lease, name string) bool {
+	return slices.Contains(LeaseExporters(lease), name)
+}
+
+// LeaseExporters returns the names of the exporters held by lease: its exporter first,
+// then the exporters linked to it and the exporters of its roles
+func LeaseExporters(lease *jumpstarterdevv1alpha1.Lease) []string {
+	if lease.Status.ExporterRef == nil {
+		return nil
+	}
+	names := []string{lease.Status.ExporterRef.Name}
+	for _, ref := range lease.Status.LinkedExporterRefs {
+		names = append(names, ref.Name)
+	}
+	for _, ref := range lease.Status.RoleExporterRefs {
+		names = append(names, ref.Name)
+	}
+	return names
+}
+
+// LeaseReservesExporter reports whether lease, beginning in the future, reserves the exporter named name
+func LeaseReservesExporter(lease *jumpstarterdevv1alpha1.Lease, name string) bool {
+	return lease.Status.ExporterRef == nil &&
+		lease.Status.ReservedExporterRef != nil &&
+		lease.Status.ReservedExporterRef.Name == name
+}
+
+// LeaseScheduled reports whether lease is a reservation that has not begun yet at now
+func LeaseScheduled(lease *jumpstarterdevv1alpha1.Lease, now time.Time) bool {
+	return lease.Status.BeginTime == nil && lease.Spec.BeginTime != nil && now.Before(lease.Spec.BeginTime.Time)
+}
+
+// LeaseWindow returns the period [begin, end) lease holds or reserves its exporter for,
+// starting when the lease began, at its requested begin time, or now, whichever applies first
+func LeaseWindow(lease *jumpstarterdevv1alpha1.Lease, now time.Time) (time.Time, time.Time) {
+	begin := now
+	if lease.Status.BeginTime != nil {
+		begin = lease.Status.BeginTime.Time
+	} else if LeaseScheduled(lease, now) {
+		begin = lease.Spec.BeginTime.Time
+	}
+	return begin, begin.Add(lease.Spec.Duration.Duration + LeasePausedDuration(lease, now))
+}
+
+// leaseRequestedBegin is when lease asked to begin, its creation unless it is a reservation
+func leaseRequestedBegin(lease *jumpstarterdevv1alpha1.Lease) time.Time {
+	if lease.Spec.BeginTime != nil && lease.Spec.BeginTime.After(lease.CreationTimestamp.Time) {
+		return lease.Spec.BeginTime.Time
+	}
+	return lease.CreationTimestamp.Time
+}
+
 func MatchingActiveLeases() client.ListOption {
 	// TODO: use field selector once KEP-4358 is stabilized
 	// Reference: https://github.com/kubernetes/kubernetes/pull/122717
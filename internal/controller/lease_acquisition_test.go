@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+var _ = Describe("Lease acquisition timeout", func() {
+	BeforeEach(func() {
+		ctx := context.Background()
+		createExporters(ctx, testExporter1DutA)
+		setExporterOnlineConditions(ctx, testExporter1DutA.Name, metav1.ConditionTrue)
+	})
+	AfterEach(func() {
+		ctx := context.Background()
+		deleteExporters(ctx, testExporter1DutA)
+		deleteLeases(ctx, "lease1")
+	})
+
+	It("should release leases whose client never dialed", func() {
+		ctx := context.Background()
+		leaseReconciler := &LeaseReconciler{
+			Client:             k8sClient,
+			Scheme:             k8sClient.Scheme(),
+			AcquisitionTimeout: time.Second,
+		}
+
+		lease := leaseDutA2Sec.DeepCopy()
+		lease.Spec.Duration.Duration = time.Hour
+		Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+		_ = reconcileLease(ctx, lease)
+		Expect(getLease(ctx, lease.Name).Status.BeginTime).NotTo(BeNil())
+
+		time.Sleep(2 * time.Second)
+		_, err := leaseReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(lease)})
+		Expect(err).NotTo(HaveOccurred())
+
+		updatedLease := getLease(ctx, lease.Name)
+		Expect(updatedLease.Status.Ended).To(BeTrue())
+		condition := meta.FindStatusCondition(updatedLease.Status.Conditions,
+			string(jumpstarterdevv1alpha1.LeaseConditionTypeFailed))
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Reason).To(Equal("AcquisitionTimeout"))
+	})
+
+	It("should keep leases whose client dialed", func() {
+		ctx := context.Background()
+		leaseReconciler := &LeaseReconciler{
+			Client:             k8sClient,
+			Scheme:             k8sClient.Scheme(),
+			AcquisitionTimeout: time.Second,
+		}
+
+		lease := leaseDutA2Sec.DeepCopy()
+		lease.Spec.Duration.Duration = time.Hour
+		Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+		_ = reconcileLease(ctx, lease)
+
+		updatedLease := getLease(ctx, lease.Name)
+		original := client.MergeFrom(updatedLease.DeepCopy())
+		updatedLease.Status.FirstDialTime = &metav1.Time{Time: time.Now()}
+		Expect(k8sClient.Status().Patch(ctx, updatedLease, original)).To(Succeed())
+
+		time.Sleep(2 * time.Second)
+		_, err := leaseReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(lease)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(getLease(ctx, lease.Name).Status.Ended).To(BeFalse())
+	})
+})
@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"context"
+	"slices"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// exporterAffinityTermMatches reports whether exporter satisfies all the fields of term
+func exporterAffinityTermMatches(
+	term *jumpstarterdevv1alpha1.ExporterAffinityTerm,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+) (bool, error) {
+	if len(term.ExporterNames) > 0 && !slices.Contains(term.ExporterNames, exporter.Name) {
+		return false, nil
+	}
+	if term.Selector != nil {
+		return selectorMatches(term.Selector, exporter.Labels)
+	}
+	return true, nil
+}
+
+// ExporterAffinityAllows reports whether exporter satisfies the required affinity
+// and anti-affinity terms of lease
+func ExporterAffinityAllows(
+	lease *jumpstarterdevv1alpha1.Lease,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+) (bool, error) {
+	affinity := lease.Spec.Affinity
+	if affinity == nil {
+		return true, nil
+	}
+	if affinity.ExporterAffinity != nil {
+		for i := range affinity.ExporterAffinity.Required {
+			matches, err := exporterAffinityTermMatches(&affinity.ExporterAffinity.Required[i], exporter)
+			if err != nil || !matches {
+				return false, err
+			}
+		}
+	}
+	if affinity.ExporterAntiAffinity != nil {
+		for i := range affinity.ExporterAntiAffinity.Required {
+			matches, err := exporterAffinityTermMatches(&affinity.ExporterAntiAffinity.Required[i], exporter)
+			if err != nil || matches {
+				return false, err
+			}
+		}
+	}
+	return true, nil
+}
+
+// exporterAffinityScore scales the sum of the weights of the preferred affinity terms of lease
+// exporter matches, minus the ones of the preferred anti-affinity terms, to [0, MaxPluginScore]
+func exporterAffinityScore(
+	lease *jumpstarterdevv1alpha1.Lease,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+) (int64, error) {
+	affinity := lease.Spec.Affinity
+	if affinity == nil {
+		return 0, nil
+	}
+
+	// the lowest sum, every anti-affinity term matching, scores 0
+	var sum, lowest, highest int64
+	for _, terms := range []struct {
+		affinity *jumpstarterdevv1alpha1.ExporterAffinity
+		sign     int64
+	}{
+		{affinity.ExporterAffinity, 1},
+		{affinity.ExporterAntiAffinity, -1},
+	} {
+		if terms.affinity == nil {
+			continue
+		}
+		for i := range terms.affinity.Preferred {
+			preferred := &terms.affinity.Preferred[i]
+			weight := terms.sign * int64(preferred.Weight)
+			lowest = min(lowest, lowest+weight)
+			highest = max(highest, highest+weight)
+			matches, err := exporterAffinityTermMatches(&preferred.Term, exporter)
+			if err != nil {
+				return 0, err
+			}
+			if matches {
+				sum += weight
+			}
+		}
+	}
+	if highest == lowest {
+		return 0, nil
+	}
+	return (sum - lowest) * MaxPluginScore / (highest - lowest), nil
+}
+
+// AffinityFilter filters out exporters violating the required affinity terms of the lease,
+// like the exporters not matching its selector they will never be assigned to it
+type AffinityFilter struct{}
+
+func (AffinityFilter) Name() string {
+	return "Affinity"
+}
+
+func (AffinityFilter) Filter(
+	_ context.Context,
+	state *AllocationState,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+) FilterCode {
+	allowed, err := ExporterAffinityAllows(state.Lease, exporter)
+	if err != nil || !allowed {
+		return FilterCodeUnresolvable
+	}
+	return FilterCodeSuccess
+}
+
+// AffinityScorer prefers the exporters matching the preferred affinity terms of the lease,
+// and avoids the ones matching its preferred anti-affinity terms
+type AffinityScorer struct{}
+
+func (AffinityScorer) Name() string {
+	return "Affinity"
+}
+
+func (AffinityScorer) Score(
+	_ context.Context,
+	state *AllocationState,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+) (int64, error) {
+	return exporterAffinityScore(state.Lease, exporter)
+}
@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+var _ = Describe("Lease exporter affinity", func() {
+	It("should score the exporters by their preferred terms", func() {
+		lease := leaseDutA2Sec.DeepCopy()
+		lease.Spec.Affinity = &jumpstarterdevv1alpha1.LeaseAffinity{
+			ExporterAffinity: &jumpstarterdevv1alpha1.ExporterAffinity{
+				Preferred: []jumpstarterdevv1alpha1.WeightedExporterAffinityTerm{{
+					Weight: 30,
+					Term: jumpstarterdevv1alpha1.ExporterAffinityTerm{
+						ExporterNames: []string{testExporter2DutA.Name},
+					},
+				}},
+			},
+			ExporterAntiAffinity: &jumpstarterdevv1alpha1.ExporterAffinity{
+				Preferred: []jumpstarterdevv1alpha1.WeightedExporterAffinityTerm{{
+					Weight: 10,
+					Term: jumpstarterdevv1alpha1.ExporterAffinityTerm{
+						Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"dut": "b"}},
+					},
+				}},
+			},
+		}
+
+		Expect(exporterAffinityScore(lease, testExporter1DutA)).To(Equal(int64(25)))
+		Expect(exporterAffinityScore(lease, testExporter2DutA)).To(Equal(MaxPluginScore))
+		Expect(exporterAffinityScore(lease, testExporter3DutB)).To(Equal(int64(0)))
+		Expect(exporterAffinityScore(leaseDutA2Sec, testExporter1DutA)).To(Equal(int64(0)))
+	})
+
+	When("exporters are online", func() {
+		BeforeEach(func() {
+			ctx := context.Background()
+			createExporters(ctx, testExporter1DutA, testExporter2DutA)
+			setExporterOnlineConditions(ctx, testExporter1DutA.Name, metav1.ConditionTrue)
+			setExporterOnlineConditions(ctx, testExporter2DutA.Name, metav1.ConditionTrue)
+		})
+		AfterEach(func() {
+			ctx := context.Background()
+			deleteExporters(ctx, testExporter1DutA, testExporter2DutA)
+			deleteLeases(ctx, "lease1")
+		})
+
+		It("should not assign an exporter the lease is required to avoid", func() {
+			ctx := context.Background()
+			lease := leaseDutA2Sec.DeepCopy()
+			lease.Spec.Affinity = &jumpstarterdevv1alpha1.LeaseAffinity{
+				ExporterAntiAffinity: &jumpstarterdevv1alpha1.ExporterAffinity{
+					Required: []jumpstarterdevv1alpha1.ExporterAffinityTerm{{
+						ExporterNames: []string{testExporter1DutA.Name},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+			_ = reconcileLease(ctx, lease)
+
+			updatedLease := getLease(ctx, lease.Name)
+			Expect(updatedLease.Status.ExporterRef).NotTo(BeNil())
+			Expect(updatedLease.Status.ExporterRef.Name).To(Equal(testExporter2DutA.Name))
+		})
+
+		It("should assign the preferred exporter", func() {
+			ctx := context.Background()
+			lease := leaseDutA2Sec.DeepCopy()
+			lease.Spec.Affinity = &jumpstarterdevv1alpha1.LeaseAffinity{
+				ExporterAffinity: &jumpstarterdevv1alpha1.ExporterAffinity{
+					Preferred: []jumpstarterdevv1alpha1.WeightedExporterAffinityTerm{{
+						Weight: 50,
+						Term: jumpstarterdevv1alpha1.ExporterAffinityTerm{
+							ExporterNames: []string{testExporter2DutA.Name},
+						},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+			_ = reconcileLease(ctx, lease)
+
+			updatedLease := getLease(ctx, lease.Name)
+			Expect(updatedLease.Status.ExporterRef).NotTo(BeNil())
+			Expect(updatedLease.Status.ExporterRef.Name).To(Equal(testExporter2DutA.Name))
+		})
+
+		It("should fail a lease whose required affinity no exporter satisfies", func() {
+			ctx := context.Background()
+			lease := leaseDutA2Sec.DeepCopy()
+			lease.Spec.Affinity = &jumpstarterdevv1alpha1.LeaseAffinity{
+				ExporterAffinity: &jumpstarterdevv1alpha1.ExporterAffinity{
+					Required: []jumpstarterdevv1alpha1.ExporterAffinityTerm{{
+						ExporterNames: []string{"exporter-gone"},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+			_ = reconcileLease(ctx, lease)
+
+			updatedLease := getLease(ctx, lease.Name)
+			Expect(updatedLease.Status.ExporterRef).To(BeNil())
+			condition := meta.FindStatusCondition(updatedLease.Status.Conditions,
+				string(jumpstarterdevv1alpha1.LeaseConditionTypeUnsatisfiable))
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal("Affinity"))
+		})
+	})
+})
@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// LeaseCheckResult is what would happen to a lease if it was created
+type LeaseCheckResult string
+
+const (
+	// LeaseCheckImmediate means the lease would acquire an exporter right away
+	LeaseCheckImmediate LeaseCheckResult = "Immediate"
+	// LeaseCheckQueued means the lease would wait for an exporter, or for its quotas to free up
+	LeaseCheckQueued LeaseCheckResult = "Queued"
+	// LeaseCheckUnsatisfiable means no exporter could ever satisfy the lease
+	LeaseCheckUnsatisfiable LeaseCheckResult = "Unsatisfiable"
+)
+
+// LeaseCheck is the outcome of the dry run of the allocation of a lease
+type LeaseCheck struct {
+	Result LeaseCheckResult `json:"result"`
+	// Why the lease would be queued or unsatisfiable, the reason of the condition it would get
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+	// The exporter the lease would acquire right away
+	Exporter string `json:"exporter,omitempty"`
+	// The position of the queued lease in the queue for exporters
+	QueuePosition *int32 `json:"queuePosition,omitempty"`
+	// When the queued lease is estimated to acquire an exporter
+	EstimatedBeginTime *metav1.Time `json:"estimatedBeginTime,omitempty"`
+}
+
+// CheckLease evaluates the lease, not created yet, against the exporters, ExporterAccessPolicies
+// and LeaseQuotas of its namespace as of now, the way it would be allocated, without creating it
+func (r *LeaseReconciler) CheckLease(ctx context.Context, lease *jumpstarterdevv1alpha1.Lease) (*LeaseCheck, error) {
+	now := time.Now()
+	lease = lease.DeepCopy()
+	// queued behind the leases already waiting
+	lease.CreationTimestamp = metav1.Time{Time: now}
+
+	selector, err := metav1.LabelSelectorAsSelector(&lease.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("CheckLease: invalid selector: %w", err)
+	}
+
+	matchingExporters, err := r.matchingExporters(ctx, lease.Namespace, selector)
+	if err != nil {
+		return nil, fmt.Errorf("CheckLease: failed to list exporters matching selector: %w", err)
+	}
+
+	state, err := r.allocationState(ctx, lease, matchingExporters)
+	if err != nil {
+		return nil, fmt.Errorf("CheckLease: %w", err)
+	}
+
+	allocation, err := r.allocator().Allocate(ctx, state, matchingExporters)
+	if err != nil {
+		return nil, fmt.Errorf("CheckLease: failed to allocate exporter: %w", err)
+	}
+
+	if !allocation.Satisfiable() {
+		reason := unsatisfiableReason(allocation)
+		return &LeaseCheck{
+			Result: LeaseCheckUnsatisfiable,
+			Reason: reason,
+			Message: fmt.Sprintf("none of the %d exporters matching %s could satisfy the lease (%s)",
+				len(matchingExporters), selector, reason),
+		}, nil
+	}
+
+	if !LeaseScheduled(lease, now) {
+		violation, err := r.leaseQuotaViolation(ctx, state, now)
+		if err != nil {
+			return nil, fmt.Errorf("CheckLease: %w", err)
+		}
+		if violation != nil {
+			return &LeaseCheck{
+				Result:  LeaseCheckQueued,
+				Reason:  "QuotaExceeded",
+				Message: violation.Message,
+			}, nil
+		}
+	}
+
+	if allocation.Exporter == nil {
+		position, estimate := leaseQueueEstimate(state, matchingExporters, now)
+		return &LeaseCheck{
+			Result:             LeaseCheckQueued,
+			Reason:             "NotAvailable",
+			Message:            fmt.Sprintf("position %d in the queue", position),
+			QueuePosition:      &position,
+			EstimatedBeginTime: estimate,
+		}, nil
+	}
+
+	return &LeaseCheck{
+		Result:   LeaseCheckImmediate,
+		Exporter: allocation.Exporter.Name,
+	}, nil
+}
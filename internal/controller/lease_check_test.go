@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+var _ = Describe("Lease check", func() {
+	BeforeEach(func() {
+		ctx := context.Background()
+		createExporters(ctx, testExporter1DutA)
+		setExporterOnlineConditions(ctx, testExporter1DutA.Name, metav1.ConditionTrue)
+	})
+	AfterEach(func() {
+		ctx := context.Background()
+		deleteExporters(ctx, testExporter1DutA)
+		deleteLeases(ctx, "lease1")
+	})
+
+	It("should check leases without creating them", func() {
+		ctx := context.Background()
+		checker := &LeaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+
+		candidate := leaseDutA2Sec.DeepCopy()
+		candidate.Name = "candidate"
+		check, err := checker.CheckLease(ctx, candidate)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(check.Result).To(Equal(LeaseCheckImmediate))
+		Expect(check.Exporter).To(Equal(testExporter1DutA.Name))
+
+		// the only matching exporter is held by another lease
+		lease := leaseDutA2Sec.DeepCopy()
+		Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+		_ = reconcileLease(ctx, lease)
+		Expect(getLease(ctx, lease.Name).Status.ExporterRef).NotTo(BeNil())
+
+		check, err = checker.CheckLease(ctx, candidate)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(check.Result).To(Equal(LeaseCheckQueued))
+		Expect(check.Reason).To(Equal("NotAvailable"))
+		Expect(check.QueuePosition).NotTo(BeNil())
+
+		candidate.Spec.Selector.MatchLabels = map[string]string{"dut": "missing"}
+		check, err = checker.CheckLease(ctx, candidate)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(check.Result).To(Equal(LeaseCheckUnsatisfiable))
+		Expect(check.Reason).To(Equal("NoExporter"))
+
+		var leases jumpstarterdevv1alpha1.LeaseList
+		Expect(k8sClient.List(ctx, &leases)).To(Succeed())
+		Expect(leases.Items).To(HaveLen(1))
+	})
+})
@@ -18,31 +18,108 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"slices"
 	"time"
 
 	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/structured-merge-diff/v4/fieldpath"
 )
 
 // LeaseReconciler reconciles a Lease object
 type LeaseReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// Allocator selects exporters for pending leases, defaults to NewDefaultAllocator
+	Allocator *Allocator
+	// ShadowAllocator, if set, is evaluated alongside Allocator for pending leases,
+	// its decisions are only logged and counted in metrics, never written
+	ShadowAllocator *Allocator
+	// ExporterIndex, if set, is used to resolve lease selectors instead of listing
+	// and matching every exporter in the namespace
+	ExporterIndex *ExporterLabelIndex
+	// Recorder, if set, receives the events of the leases
+	Recorder record.EventRecorder
+	// OfflineRetryWindow is how long after its creation a lease whose matching exporters
+	// are all offline stays pending, waiting for them to come back, before it is unsatisfiable
+	OfflineRetryWindow time.Duration
+	// WaitForExporterMaxBackoff bounds the backoff of the retries of the leases waiting for exporters
+	// that could satisfy them, defaults to DefaultWaitForExporterMaxBackoff
+	WaitForExporterMaxBackoff time.Duration
+	// WaitForExporterMinBackoff is the first retry of the leases waiting for exporters that could
+	// satisfy them, defaults to DefaultWaitForExporterMinBackoff
+	WaitForExporterMinBackoff time.Duration
+	// QueueRetryInterval is how often leases queued for a busy exporter are re-evaluated, on top of
+	// the releases of exporters, defaults to DefaultQueueRetryInterval
+	QueueRetryInterval time.Duration
+	// OfflineRetryInterval is how often leases waiting for offline exporters, or for reservations
+	// to free up, are re-evaluated, defaults to DefaultOfflineRetryInterval
+	OfflineRetryInterval time.Duration
+	// QuotaRetryInterval is how often leases exceeding a LeaseQuota are re-evaluated,
+	// defaults to DefaultQuotaRetryInterval
+	QuotaRetryInterval time.Duration
+	// MaxConcurrentReconciles is the number of leases reconciled in parallel, defaults to 1
+	MaxConcurrentReconciles int
+	// Preemption lets waiting leases preempt running leases of lower priority,
+	// as set by the PreemptionPolicy of their LeasePriorityClass
+	Preemption bool
+	// AccessPolicyTieBreak is which of the access policies of equal priority matching a client applies,
+	// defaults to PolicyTieBreakMostRestrictive
+	AccessPolicyTieBreak PolicyTieBreak
+	// LeaseRecords writes a LeaseRecord of each lease when it ends
+	LeaseRecords bool
+	// PreemptionGracePeriod is how long preempted leases keep their exporter before they end,
+	// 0 ends them right away
+	PreemptionGracePeriod time.Duration
+	// EndingNotice is how long before a running lease expires or is preempted it gets the
+	// EndingSoon condition, 0 disables the notice
+	EndingNotice time.Duration
+	// DurationLimits, if set, keeps the leases exceeding the maximum duration of their namespace
+	// unsatisfiable
+	DurationLimits *LeaseDurationLimits
+	// AcquisitionTimeout is how long after it acquired its exporter a lease whose client never
+	// dialed it is released, 0 keeps such leases until they expire
+	AcquisitionTimeout time.Duration
 }
 
+const (
+	// DefaultQueueRetryInterval is the default of LeaseReconciler.QueueRetryInterval
+	DefaultQueueRetryInterval = time.Second
+	// DefaultOfflineRetryInterval is the default of LeaseReconciler.OfflineRetryInterval
+	DefaultOfflineRetryInterval = 5 * time.Second
+	// DefaultQuotaRetryInterval is the default of LeaseReconciler.QuotaRetryInterval
+	DefaultQuotaRetryInterval = 30 * time.Second
+	// DefaultWaitForExporterMinBackoff is the default of LeaseReconciler.WaitForExporterMinBackoff
+	DefaultWaitForExporterMinBackoff = 5 * time.Second
+	// DefaultWaitForExporterMaxBackoff is the default of LeaseReconciler.WaitForExporterMaxBackoff
+	DefaultWaitForExporterMaxBackoff = 5 * time.Minute
+)
+
 // +kubebuilder:rbac:groups=jumpstarter.dev,resources=leases,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=jumpstarter.dev,resources=leases/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=jumpstarter.dev,resources=leases/finalizers,verbs=update
+// +kubebuilder:rbac:groups=jumpstarter.dev,resources=exporteraccesspolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=jumpstarter.dev,resources=leasequotas,verbs=get;list;watch
+// +kubebuilder:rbac:groups=jumpstarter.dev,resources=maintenancewindows,verbs=get;list;watch
+// +kubebuilder:rbac:groups=jumpstarter.dev,resources=leasepriorityclasses,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -64,28 +141,103 @@ func (r *LeaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 		)
 	}
 
+	if err := AdoptLegacyFields(ctx, r.Client, &lease, "status", leaseFieldManager,
+		fieldpath.MakePathOrDie("status", "beginTime"),
+		fieldpath.MakePathOrDie("status", "endTime"),
+		fieldpath.MakePathOrDie("status", "exporterRef"),
+		fieldpath.MakePathOrDie("status", "linkedExporterRefs"),
+		fieldpath.MakePathOrDie("status", "ended"),
+		fieldpath.MakePathOrDie("status", "conditions"),
+	); err != nil {
+		return ctrl.Result{}, fmt.Errorf("Reconcile: %w", err)
+	}
+
 	var result ctrl.Result
-	if err := r.reconcileStatusExporterRef(ctx, &result, &lease); err != nil {
+	// every step observes the same instant, so that they agree with each other and with the phase
+	now := time.Now()
+	if err := r.reconcileStatusAcquireTimeout(ctx, &result, &lease, now); err != nil {
+		return result, err
+	}
+
+	if err := r.reconcileStatusExporterRef(ctx, &result, &lease, now); err != nil {
+		return result, err
+	}
+
+	if err := r.reconcileStatusBeginTime(ctx, &lease, now); err != nil {
 		return result, err
 	}
 
-	if err := r.reconcileStatusBeginTime(ctx, &lease); err != nil {
+	if err := r.reconcileStatusAcquisitionTimeout(ctx, &result, &lease, now); err != nil {
 		return result, err
 	}
 
-	if err := r.reconcileStatusEnded(ctx, &result, &lease); err != nil {
+	if err := r.reconcileStatusPaused(ctx, &result, &lease, now); err != nil {
 		return result, err
 	}
 
-	if err := r.Status().Update(ctx, &lease); err != nil {
+	if err := r.reconcileStatusEnded(ctx, &result, &lease, now); err != nil {
+		return result, err
+	}
+
+	previousPhase := lease.Status.Phase
+	phase := LeasePhaseOf(&lease, now)
+	if err := validateLeaseTransition(previousPhase, phase); err != nil {
+		r.recordInvalidLeaseTransition(ctx, &lease, previousPhase, phase, err)
+	}
+
+	// conditional on the resource version, allocations must not be made from a stale lease,
+	// this is the only writer of the status fields owned by the lease controller
+	if err := applyStatus(ctx, r.Client, &lease, &jumpstarterdevv1alpha1.LeaseStatus{
+		Phase:               phase,
+		BeginTime:           lease.Status.BeginTime,
+		EndTime:             lease.Status.EndTime,
+		ExporterRef:         lease.Status.ExporterRef,
+		LinkedExporterRefs:  lease.Status.LinkedExporterRefs,
+		RoleExporterRefs:    lease.Status.RoleExporterRefs,
+		ReservedExporterRef: lease.Status.ReservedExporterRef,
+		QueuePosition:       lease.Status.QueuePosition,
+		EstimatedBeginTime:  lease.Status.EstimatedBeginTime,
+		Decision:            lease.Status.Decision,
+		PauseTime:           lease.Status.PauseTime,
+		PausedDuration:      lease.Status.PausedDuration,
+		Ended:               lease.Status.Ended,
+		Conditions:          lease.Status.Conditions,
+	}, leaseFieldManager, lease.ResourceVersion); err != nil {
 		return RequeueConflict(logger, result, err)
 	}
+	recordLeaseTransition(previousPhase, phase)
+	lease.Status.Phase = phase
+
+	if r.LeaseRecords && lease.Status.Ended {
+		if err := recordLease(ctx, r.Client, &lease); err != nil {
+			return result, fmt.Errorf("Reconcile: %w", err)
+		}
+	}
+
+	// lease metadata is only kept while the lease is active, it is owned by its writers
+	if lease.Status.Ended && lease.Status.Metadata != nil {
+		if err := r.Status().Patch(ctx, &lease, client.RawPatch(
+			types.MergePatchType,
+			[]byte(`{"status":{"metadata":null}}`),
+		)); err != nil {
+			return RequeueConflict(logger, result, fmt.Errorf("Reconcile: failed to clear lease metadata: %w", err))
+		}
+	}
 
-	if lease.Labels == nil {
-		lease.Labels = make(map[string]string)
+	leaseMetadata := &metav1.PartialObjectMetadata{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: jumpstarterdevv1alpha1.GroupVersion.String(),
+			Kind:       "Lease",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: lease.Namespace,
+			Name:      lease.Name,
+		},
 	}
 	if lease.Status.Ended {
-		lease.Labels[string(jumpstarterdevv1alpha1.LeaseLabelEnded)] = jumpstarterdevv1alpha1.LeaseLabelEndedValue
+		leaseMetadata.Labels = map[string]string{
+			string(jumpstarterdevv1alpha1.LeaseLabelEnded): jumpstarterdevv1alpha1.LeaseLabelEndedValue,
+		}
 	}
 
 	if lease.Status.ExporterRef != nil {
@@ -93,16 +245,24 @@ func (r *LeaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 		if err := r.Get(ctx, types.NamespacedName{
 			Namespace: lease.Namespace,
 			Name:      lease.Status.ExporterRef.Name,
-		}, &exporter); err != nil {
+		}, &exporter); err == nil {
+			if err := controllerutil.SetControllerReference(&exporter, leaseMetadata, r.Scheme); err != nil {
+				return result, fmt.Errorf("Reconcile: failed to update lease controller reference: %w", err)
+			}
+		} else if !apierrors.IsNotFound(err) {
 			return result, err
 		}
-		if err := controllerutil.SetControllerReference(&exporter, &lease, r.Scheme); err != nil {
-			return result, fmt.Errorf("Reconcile: failed to update lease controller reference: %w", err)
-		}
+		// leases of deleted exporters keep their ended label in sync, and are released by the ConsistencyChecker
 	}
 
-	if err := r.Update(ctx, &lease); err != nil {
-		return RequeueConflict(logger, result, fmt.Errorf("Reconcile: failed to update lease metadata: %w", err))
+	if err := r.Patch(
+		ctx,
+		leaseMetadata,
+		client.Apply,
+		client.FieldOwner(leaseFieldManager),
+		client.ForceOwnership,
+	); err != nil {
+		return RequeueConflict(logger, result, fmt.Errorf("Reconcile: failed to apply lease metadata: %w", err))
 	}
 
 	return result, nil
@@ -114,47 +274,37 @@ func (r *LeaseReconciler) reconcileStatusEnded(
 	ctx context.Context,
 	result *ctrl.Result,
 	lease *jumpstarterdevv1alpha1.Lease,
+	now time.Time,
 ) error {
 	logger := log.FromContext(ctx)
 
-	now := time.Now()
 	if !lease.Status.Ended {
-		if lease.Spec.Release {
+		_, preempted := lease.Annotations[jumpstarterdevv1alpha1.LeaseAnnotationPreemptedBy]
+		preemptAt, graced := leasePreemptAt(lease)
+		if preempted && (lease.Spec.Release || graced && !now.Before(preemptAt)) {
+			logger.Info("reconcileStatusEndTime: lease preempted")
+			endLease(lease, "Preempted", now)
+			return nil
+		} else if lease.Spec.Release {
 			logger.Info("reconcileStatusEndTime: force releasing lease")
-			meta.SetStatusCondition(&lease.Status.Conditions, metav1.Condition{
-				Type:               string(jumpstarterdevv1alpha1.LeaseConditionTypeReady),
-				Status:             metav1.ConditionFalse,
-				ObservedGeneration: lease.Generation,
-				LastTransitionTime: metav1.Time{
-					Time: now,
-				},
-				Reason: "Released",
-			})
-			lease.Status.Ended = true
-			lease.Status.EndTime = &metav1.Time{
-				Time: now,
-			}
+			endLease(lease, "Released", now)
 			return nil
 		} else if lease.Status.BeginTime != nil {
-			expiration := lease.Status.BeginTime.Add(lease.Spec.Duration.Duration)
+			expiration := LeaseExpiration(lease, now)
 			if expiration.Before(now) {
 				logger.Info("reconcileStatusEndTime: lease expired")
-				meta.SetStatusCondition(&lease.Status.Conditions, metav1.Condition{
-					Type:               string(jumpstarterdevv1alpha1.LeaseConditionTypeReady),
-					Status:             metav1.ConditionFalse,
-					ObservedGeneration: lease.Generation,
-					LastTransitionTime: metav1.Time{
-						Time: time.Now(),
-					},
-					Reason: "Expired",
-				})
-				lease.Status.Ended = true
-				lease.Status.EndTime = &metav1.Time{
-					Time: now,
-				}
+				endLease(lease, "Expired", now)
 				return nil
 			} else {
-				result.RequeueAfter = expiration.Sub(now)
+				end, reason := expiration, "Expiring"
+				if graced && preemptAt.Before(end) {
+					end, reason = preemptAt, "Preempted"
+				}
+				requeueBefore(result, end.Sub(now))
+				// the end of a paused lease is not known until it resumes
+				if lease.Status.PauseTime == nil || reason == "Preempted" {
+					r.reconcileEndingNotice(result, lease, end, reason, now)
+				}
 				return nil
 			}
 		}
@@ -163,14 +313,151 @@ func (r *LeaseReconciler) reconcileStatusEnded(
 	return nil
 }
 
+// reconcileEndingNotice manages LeaseConditionTypeEndingSoon of the running lease ending at end
+// for reason, set EndingNotice before end so that its holders can wind down their workloads
+func (r *LeaseReconciler) reconcileEndingNotice(
+	result *ctrl.Result,
+	lease *jumpstarterdevv1alpha1.Lease,
+	end time.Time,
+	reason string,
+	now time.Time,
+) {
+	if r.EndingNotice <= 0 {
+		return
+	}
+
+	notice := end.Add(-r.EndingNotice)
+	if now.Before(notice) {
+		requeueBefore(result, notice.Sub(now))
+		return
+	}
+
+	// the first notice is kept, with the time remaining when it was given
+	if condition := meta.FindStatusCondition(
+		lease.Status.Conditions,
+		string(jumpstarterdevv1alpha1.LeaseConditionTypeEndingSoon),
+	); condition != nil && condition.Status == metav1.ConditionTrue && condition.Reason == reason {
+		return
+	}
+
+	remaining := end.Sub(now).Round(time.Second)
+	meta.SetStatusCondition(&lease.Status.Conditions, metav1.Condition{
+		Type:               string(jumpstarterdevv1alpha1.LeaseConditionTypeEndingSoon),
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: lease.Generation,
+		LastTransitionTime: metav1.Time{
+			Time: now,
+		},
+		Reason:  reason,
+		Message: fmt.Sprintf("the lease ends in %s, at %s", remaining, end.UTC().Format(time.RFC3339)),
+	})
+	if r.Recorder != nil {
+		r.Recorder.Eventf(lease, corev1.EventTypeWarning, "EndingSoon",
+			"Lease ends in %s (%s)", remaining, reason)
+	}
+}
+
+// Ends leases still waiting for an exporter after their AcquireTimeout,
+// also manages LeaseConditionTypeFailed
+func (r *LeaseReconciler) reconcileStatusAcquireTimeout(
+	ctx context.Context,
+	result *ctrl.Result,
+	lease *jumpstarterdevv1alpha1.Lease,
+	now time.Time,
+) error {
+	logger := log.FromContext(ctx)
+
+	if lease.Status.ExporterRef != nil || lease.Status.Ended || lease.Spec.AcquireTimeout == nil {
+		return nil
+	}
+
+	deadline := leaseRequestedBegin(lease).Add(lease.Spec.AcquireTimeout.Duration)
+	if now.Before(deadline) {
+		requeueBefore(result, deadline.Sub(now))
+		return nil
+	}
+
+	logger.Info("reconcileStatusAcquireTimeout: lease acquire timed out")
+	meta.SetStatusCondition(&lease.Status.Conditions, metav1.Condition{
+		Type:               string(jumpstarterdevv1alpha1.LeaseConditionTypePending),
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: lease.Generation,
+		LastTransitionTime: metav1.Time{
+			Time: now,
+		},
+		Reason: "Timeout",
+	})
+	meta.SetStatusCondition(&lease.Status.Conditions, metav1.Condition{
+		Type:               string(jumpstarterdevv1alpha1.LeaseConditionTypeFailed),
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: lease.Generation,
+		LastTransitionTime: metav1.Time{
+			Time: now,
+		},
+		Reason:  "Timeout",
+		Message: fmt.Sprintf("no exporter acquired within %s", lease.Spec.AcquireTimeout.Duration),
+	})
+	endLease(lease, "Timeout", now)
+
+	if r.Recorder != nil {
+		r.Recorder.Eventf(lease, corev1.EventTypeWarning, "Timeout",
+			"No exporter acquired within %s", lease.Spec.AcquireTimeout.Duration)
+	}
+
+	return nil
+}
+
+// Ends running leases whose client did not dial their exporter within AcquisitionTimeout
+// of their begin time, also manages LeaseConditionTypeFailed
+// nolint:unparam
+func (r *LeaseReconciler) reconcileStatusAcquisitionTimeout(
+	ctx context.Context,
+	result *ctrl.Result,
+	lease *jumpstarterdevv1alpha1.Lease,
+	now time.Time,
+) error {
+	logger := log.FromContext(ctx)
+
+	if r.AcquisitionTimeout <= 0 || lease.Status.BeginTime == nil || lease.Status.Ended ||
+		lease.Status.FirstDialTime != nil {
+		return nil
+	}
+
+	deadline := lease.Status.BeginTime.Add(r.AcquisitionTimeout)
+	if now.Before(deadline) {
+		requeueBefore(result, deadline.Sub(now))
+		return nil
+	}
+
+	logger.Info("reconcileStatusAcquisitionTimeout: lease never dialed")
+	meta.SetStatusCondition(&lease.Status.Conditions, metav1.Condition{
+		Type:               string(jumpstarterdevv1alpha1.LeaseConditionTypeFailed),
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: lease.Generation,
+		LastTransitionTime: metav1.Time{
+			Time: now,
+		},
+		Reason:  "AcquisitionTimeout",
+		Message: fmt.Sprintf("the client did not connect to the exporter within %s", r.AcquisitionTimeout),
+	})
+	endLease(lease, "AcquisitionTimeout", now)
+
+	if r.Recorder != nil {
+		r.Recorder.Eventf(lease, corev1.EventTypeWarning, "AcquisitionTimeout",
+			"Client did not connect to the exporter within %s, lease released", r.AcquisitionTimeout)
+	}
+
+	return nil
+}
+
 // nolint:unparam
 func (r *LeaseReconciler) reconcileStatusBeginTime(
 	ctx context.Context,
 	lease *jumpstarterdevv1alpha1.Lease,
+	now time.Time,
 ) error {
 	logger := log.FromContext(ctx)
 
-	now := time.Now()
 	if lease.Status.BeginTime == nil && lease.Status.ExporterRef != nil {
 		logger.Info("reconcileStatusBeginTime: updating begin time")
 		meta.SetStatusCondition(&lease.Status.Conditions, metav1.Condition{
@@ -190,15 +477,43 @@ func (r *LeaseReconciler) reconcileStatusBeginTime(
 	return nil
 }
 
-// Also manages LeaseConditionTypeUnsatisfiable and LeaseConditionTypePending
+// Also manages ReservedExporterRef, LeaseConditionTypeUnsatisfiable, LeaseConditionTypePending
+// and LeaseConditionTypeConflicted
 func (r *LeaseReconciler) reconcileStatusExporterRef(
 	ctx context.Context,
 	result *ctrl.Result,
 	lease *jumpstarterdevv1alpha1.Lease,
+	now time.Time,
 ) error {
 	logger := log.FromContext(ctx)
 
-	if lease.Status.ExporterRef == nil {
+	// only kept while the lease is waiting
+	lease.Status.QueuePosition = nil
+	lease.Status.EstimatedBeginTime = nil
+
+	if lease.Status.ExporterRef == nil && !lease.Status.Ended {
+		scheduled := LeaseScheduled(lease, now)
+		if scheduled && lease.Status.ReservedExporterRef != nil {
+			// the exporter is acquired at the begin time of the reservation
+			requeueBefore(result, lease.Spec.BeginTime.Sub(now))
+			return nil
+		}
+
+		if limit := r.DurationLimits.For(lease.Namespace); limit.Exceeded(lease.Spec.Duration.Duration) {
+			meta.SetStatusCondition(&lease.Status.Conditions, metav1.Condition{
+				Type:               string(jumpstarterdevv1alpha1.LeaseConditionTypeUnsatisfiable),
+				Status:             metav1.ConditionTrue,
+				ObservedGeneration: lease.Generation,
+				LastTransitionTime: metav1.Time{
+					Time: now,
+				},
+				Reason: "DurationExceeded",
+				Message: fmt.Sprintf("duration %s exceeds the maximum lease duration %s",
+					lease.Spec.Duration.Duration, limit.Maximum.Duration),
+			})
+			return nil
+		}
+
 		logger.Info("reconcileStatusExporterRef: looking for matching exporter")
 
 		selector, err := metav1.LabelSelectorAsSelector(&lease.Spec.Selector)
@@ -206,83 +521,165 @@ func (r *LeaseReconciler) reconcileStatusExporterRef(
 			return fmt.Errorf("reconcileStatusExporterRef: failed to create selector from label selector: %w", err)
 		}
 
-		// List all Exporter matching selector
-		var matchingExporters jumpstarterdevv1alpha1.ExporterList
-		if err := r.List(
-			ctx,
-			&matchingExporters,
-			client.InNamespace(lease.Namespace),
-			client.MatchingLabelsSelector{Selector: selector},
-		); err != nil {
+		matchingExporters, err := r.matchingExporters(ctx, lease.Namespace, selector)
+		if err != nil {
 			return fmt.Errorf("reconcileStatusExporterRef: failed to list exporters matching selector: %w", err)
 		}
 
-		// Filter out offline exporters
-		onlineExporters := slices.DeleteFunc(
-			matchingExporters.Items,
-			func(exporter jumpstarterdevv1alpha1.Exporter) bool {
-				return !(true &&
-					meta.IsStatusConditionTrue(
-						exporter.Status.Conditions,
-						string(jumpstarterdevv1alpha1.ExporterConditionTypeRegistered),
-					) &&
-					meta.IsStatusConditionTrue(
-						exporter.Status.Conditions,
-						string(jumpstarterdevv1alpha1.ExporterConditionTypeOnline),
-					))
-			},
-		)
+		// a reservation that has begun acquires the exporter it reserved
+		if lease.Status.ReservedExporterRef != nil {
+			matchingExporters = slices.DeleteFunc(matchingExporters, func(exporter jumpstarterdevv1alpha1.Exporter) bool {
+				return exporter.Name != lease.Status.ReservedExporterRef.Name
+			})
+		}
+
+		state, err := r.allocationState(ctx, lease, matchingExporters)
+		if err != nil {
+			return fmt.Errorf("reconcileStatusExporterRef: %w", err)
+		}
+
+		if !scheduled {
+			exceeded, err := r.reconcileLeaseQuotas(ctx, result, state, now)
+			if err != nil || exceeded {
+				return err
+			}
+		}
 
-		// No matching exporter online, lease unsatisfiable
-		if len(onlineExporters) == 0 {
+		allocation, err := r.allocator().Allocate(ctx, state, matchingExporters)
+		if err != nil {
+			return fmt.Errorf("reconcileStatusExporterRef: failed to allocate exporter: %w", err)
+		}
+
+		if r.ShadowAllocator != nil {
+			r.shadowAllocate(ctx, state, matchingExporters, allocation)
+		}
+
+		lease.Status.Decision = allocation.Decision(r.allocator().Name, len(matchingExporters))
+
+		// No matching exporter could ever be assigned, lease unsatisfiable
+		if !allocation.Satisfiable() {
+			reason := unsatisfiableReason(allocation)
+			if reason == "Offline" {
+				// matching exporters might come back online, keep the lease pending for a while
+				deadline := leaseRequestedBegin(lease).Add(r.OfflineRetryWindow)
+				if now.Before(deadline) {
+					meta.SetStatusCondition(&lease.Status.Conditions, metav1.Condition{
+						Type:               string(jumpstarterdevv1alpha1.LeaseConditionTypePending),
+						Status:             metav1.ConditionTrue,
+						ObservedGeneration: lease.Generation,
+						LastTransitionTime: metav1.Time{
+							Time: now,
+						},
+						Reason: reason,
+					})
+					requeueLease(result, "offline", min(orDefault(r.OfflineRetryInterval, DefaultOfflineRetryInterval),
+						deadline.Sub(now)))
+					return nil
+				}
+			}
+			if lease.Spec.WaitForExporter {
+				meta.SetStatusCondition(&lease.Status.Conditions, metav1.Condition{
+					Type:               string(jumpstarterdevv1alpha1.LeaseConditionTypePending),
+					Status:             metav1.ConditionTrue,
+					ObservedGeneration: lease.Generation,
+					LastTransitionTime: metav1.Time{
+						Time: now,
+					},
+					Reason:  "WaitingForExporter",
+					Message: fmt.Sprintf("no exporter could satisfy the lease yet (%s)", reason),
+				})
+				requeueLease(result, "wait-for-exporter", r.waitForExporterBackoff(now.Sub(leaseRequestedBegin(lease))))
+				return nil
+			}
+			if reason == "AccessDenied" && !meta.IsStatusConditionTrue(lease.Status.Conditions,
+				string(jumpstarterdevv1alpha1.LeaseConditionTypeUnsatisfiable)) {
+				policyLeaseDenialsTotal.WithLabelValues(lease.Namespace, deniedByPolicy(state, matchingExporters)).Inc()
+			}
 			meta.SetStatusCondition(&lease.Status.Conditions, metav1.Condition{
 				Type:               string(jumpstarterdevv1alpha1.LeaseConditionTypeUnsatisfiable),
 				Status:             metav1.ConditionTrue,
 				ObservedGeneration: lease.Generation,
 				LastTransitionTime: metav1.Time{
-					Time: time.Now(),
+					Time: now,
 				},
-				Reason: "NoExporter",
+				Reason:  reason,
+				Message: unsatisfiableMessage(state, reason),
 			})
 			return nil
 		}
 
-		var leases jumpstarterdevv1alpha1.LeaseList
-		if err := r.List(
-			ctx,
-			&leases,
-			client.InNamespace(lease.Namespace),
-			MatchingActiveLeases(),
-		); err != nil {
-			return fmt.Errorf("reconcileStatusExporterRef: failed to list active leases: %w", err)
+		if scheduled {
+			return r.reserveExporter(ctx, result, lease, allocation.Exporter, now)
 		}
 
-		availableExporters := slices.DeleteFunc(onlineExporters, func(exporter jumpstarterdevv1alpha1.Exporter) bool {
-			for _, existingLease := range leases.Items {
-				// if the lease is referencing the current exporter
-				if existingLease.Status.ExporterRef != nil && existingLease.Status.ExporterRef.Name == exporter.Name {
-					return true
+		if allocation.Exporter == nil {
+			if r.Preemption {
+				if err := r.preemptLease(ctx, state, matchingExporters, now); err != nil {
+					return fmt.Errorf("reconcileStatusExporterRef: %w", err)
 				}
 			}
-			return false
-		})
-
-		if len(availableExporters) == 0 {
+			position, estimate := leaseQueueEstimate(state, matchingExporters, now)
+			lease.Status.QueuePosition = &position
+			lease.Status.EstimatedBeginTime = estimate
+			message := fmt.Sprintf("position %d in the queue", position)
+			if estimate != nil {
+				message += fmt.Sprintf(", estimated to begin at %s", estimate.Format(time.RFC3339))
+			}
 			meta.SetStatusCondition(&lease.Status.Conditions, metav1.Condition{
 				Type:               string(jumpstarterdevv1alpha1.LeaseConditionTypePending),
 				Status:             metav1.ConditionTrue,
 				ObservedGeneration: lease.Generation,
 				LastTransitionTime: metav1.Time{
-					Time: time.Now(),
+					Time: now,
 				},
-				Reason: "NotAvailable",
+				Reason:  "NotAvailable",
+				Message: message,
 			})
-			result.RequeueAfter = time.Second
+			requeueLease(result, "queued", orDefault(r.QueueRetryInterval, DefaultQueueRetryInterval))
 			return nil
 		} else {
+			// the roles were assigned by the LeaseRolesFilter from the same state, this does not fail
+			roleExporterRefs, _ := state.AssignRoles(ctx, allocation.Exporter)
+			var linkedExporterRefs []corev1.LocalObjectReference
+			for _, linked := range state.Linked(allocation.Exporter) {
+				linkedExporterRefs = append(linkedExporterRefs, corev1.LocalObjectReference{Name: linked.Name})
+			}
+
+			// the exporters are claimed before the lease references them, another reconcile
+			// allocating one of them concurrently fails to claim it and retries
+			names := []string{allocation.Exporter.Name}
+			for _, ref := range linkedExporterRefs {
+				names = append(names, ref.Name)
+			}
+			for _, ref := range roleExporterRefs {
+				if !slices.Contains(names, ref.Name) {
+					names = append(names, ref.Name)
+				}
+			}
+			if err := r.claimExporters(ctx, lease, names, state.ActiveLeases); err != nil {
+				if !errors.Is(err, errExporterClaimed) && !apierrors.IsConflict(err) {
+					return fmt.Errorf("reconcileStatusExporterRef: %w", err)
+				}
+				logger.Info("reconcileStatusExporterRef: exporter claimed concurrently", "error", err.Error())
+				requeueLease(result, "claimed", orDefault(r.QueueRetryInterval, DefaultQueueRetryInterval))
+				return nil
+			}
+
+			logger.Info("reconcileStatusExporterRef: allocated exporter",
+				"exporter", allocation.Exporter.Name, "scores", allocation.Scores)
+			leaseAssignmentSeconds.WithLabelValues(r.allocator().Name).
+				Observe(time.Since(leaseRequestedBegin(lease)).Seconds())
+			if decision, err := EvaluateAccessPolicies(
+				state.AccessPolicies, state.AccessPolicyTieBreak, state.Client, allocation.Exporter,
+			); err == nil {
+				policyGrantedLeasePriority.WithLabelValues(lease.Namespace, decision.PolicyName).
+					Observe(float64(leasePriority(state, lease, state.Client, allocation.Exporter)))
+			}
 			lease.Status.ExporterRef = &corev1.LocalObjectReference{
-				Name: availableExporters[0].Name,
+				Name: allocation.Exporter.Name,
 			}
+			lease.Status.LinkedExporterRefs = linkedExporterRefs
+			lease.Status.RoleExporterRefs = roleExporterRefs
 			return nil
 		}
 	}
@@ -290,9 +687,607 @@ func (r *LeaseReconciler) reconcileStatusExporterRef(
 	return nil
 }
 
+// allocationState returns the snapshot of the namespace of lease to allocate it one of matchingExporters
+func (r *LeaseReconciler) allocationState(
+	ctx context.Context,
+	lease *jumpstarterdevv1alpha1.Lease,
+	matchingExporters []jumpstarterdevv1alpha1.Exporter,
+) (*AllocationState, error) {
+	var leases jumpstarterdevv1alpha1.LeaseList
+	if err := r.List(
+		ctx,
+		&leases,
+		client.InNamespace(lease.Namespace),
+		MatchingActiveLeases(),
+	); err != nil {
+		return nil, fmt.Errorf("allocationState: failed to list active leases: %w", err)
+	}
+
+	var endedLeases jumpstarterdevv1alpha1.LeaseList
+	if err := r.List(
+		ctx,
+		&endedLeases,
+		client.InNamespace(lease.Namespace),
+		client.MatchingLabels{string(jumpstarterdevv1alpha1.LeaseLabelEnded): jumpstarterdevv1alpha1.LeaseLabelEndedValue},
+	); err != nil {
+		return nil, fmt.Errorf("allocationState: failed to list ended leases: %w", err)
+	}
+
+	policies, err := ListAccessPolicies(ctx, r.Client, lease.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("allocationState: %w", err)
+	}
+
+	var clients jumpstarterdevv1alpha1.ClientList
+	if err := r.List(ctx, &clients, client.InNamespace(lease.Namespace)); err != nil {
+		return nil, fmt.Errorf("allocationState: failed to list clients: %w", err)
+	}
+
+	var windows jumpstarterdevv1alpha1.MaintenanceWindowList
+	if err := r.List(ctx, &windows, client.InNamespace(lease.Namespace)); err != nil {
+		return nil, fmt.Errorf("allocationState: failed to list maintenance windows: %w", err)
+	}
+
+	var classes jumpstarterdevv1alpha1.LeasePriorityClassList
+	if err := r.List(ctx, &classes, client.InNamespace(lease.Namespace)); err != nil {
+		return nil, fmt.Errorf("allocationState: failed to list lease priority classes: %w", err)
+	}
+
+	state := &AllocationState{
+		Lease:                lease,
+		ActiveLeases:         leases.Items,
+		EndedLeases:          endedLeases.Items,
+		AccessPolicies:       policies,
+		AccessPolicyTieBreak: r.AccessPolicyTieBreak,
+		Clients:              map[string]*jumpstarterdevv1alpha1.Client{},
+		MaintenanceWindows:   windows.Items,
+		PriorityClasses:      classes.Items,
+	}
+	for i := range clients.Items {
+		state.Clients[clients.Items[i].Name] = &clients.Items[i]
+	}
+
+	state.LinkedExporters, err = r.linkedExporters(ctx, lease.Namespace, matchingExporters)
+	if err != nil {
+		return nil, fmt.Errorf("allocationState: %w", err)
+	}
+
+	state.RoleExporters, err = r.roleExporters(ctx, lease)
+	if err != nil {
+		return nil, fmt.Errorf("allocationState: %w", err)
+	}
+
+	state.LeasedExporters, err = r.leasedExporters(ctx, lease.Namespace, policies)
+	if err != nil {
+		return nil, fmt.Errorf("allocationState: %w", err)
+	}
+
+	// the client is needed to evaluate access policies and reservations
+	var leaseClient jumpstarterdevv1alpha1.Client
+	if err := r.Get(ctx, types.NamespacedName{
+		Namespace: lease.Namespace,
+		Name:      lease.Spec.ClientRef.Name,
+	}, &leaseClient); err == nil {
+		state.Client = LeaseClient(&leaseClient, lease)
+	} else if !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("allocationState: failed to get client: %w", err)
+	}
+
+	return state, nil
+}
+
+// reconcileLeaseQuotas reports whether acquiring an exporter for the lease of state would exceed
+// one of the LeaseQuotas of its client, and manages LeaseConditionTypeQuotaExceeded
+func (r *LeaseReconciler) reconcileLeaseQuotas(
+	ctx context.Context,
+	result *ctrl.Result,
+	state *AllocationState,
+	now time.Time,
+) (bool, error) {
+	lease := state.Lease
+
+	violation, err := r.leaseQuotaViolation(ctx, state, now)
+	if err != nil {
+		return false, fmt.Errorf("reconcileLeaseQuotas: %w", err)
+	}
+
+	if violation == nil {
+		if meta.FindStatusCondition(lease.Status.Conditions,
+			string(jumpstarterdevv1alpha1.LeaseConditionTypeQuotaExceeded)) != nil {
+			meta.SetStatusCondition(&lease.Status.Conditions, metav1.Condition{
+				Type:               string(jumpstarterdevv1alpha1.LeaseConditionTypeQuotaExceeded),
+				Status:             metav1.ConditionFalse,
+				ObservedGeneration: lease.Generation,
+				LastTransitionTime: metav1.Time{
+					Time: now,
+				},
+				Reason: "WithinQuota",
+			})
+		}
+		return false, nil
+	}
+
+	log.FromContext(ctx).Info("reconcileLeaseQuotas: lease quota exceeded",
+		"quota", violation.Quota.Name, "reason", violation.Reason)
+	meta.SetStatusCondition(&lease.Status.Conditions, metav1.Condition{
+		Type:               string(jumpstarterdevv1alpha1.LeaseConditionTypeQuotaExceeded),
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: lease.Generation,
+		LastTransitionTime: metav1.Time{
+			Time: now,
+		},
+		Reason:  violation.Reason,
+		Message: violation.Message,
+	})
+	meta.SetStatusCondition(&lease.Status.Conditions, metav1.Condition{
+		Type:               string(jumpstarterdevv1alpha1.LeaseConditionTypePending),
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: lease.Generation,
+		LastTransitionTime: metav1.Time{
+			Time: now,
+		},
+		Reason:  "QuotaExceeded",
+		Message: violation.Message,
+	})
+	// the leased duration frees up as time passes, not only when leases end
+	requeueLease(result, "quota", orDefault(r.QuotaRetryInterval, DefaultQuotaRetryInterval))
+	return true, nil
+}
+
+// leaseQuotaViolation returns the LeaseQuota acquiring an exporter for the lease of state would
+// exceed, nil if none
+func (r *LeaseReconciler) leaseQuotaViolation(
+	ctx context.Context,
+	state *AllocationState,
+	now time.Time,
+) (*QuotaViolation, error) {
+	lease := state.Lease
+
+	var quotas jumpstarterdevv1alpha1.LeaseQuotaList
+	if err := r.List(ctx, &quotas, client.InNamespace(lease.Namespace)); err != nil {
+		return nil, fmt.Errorf("leaseQuotaViolation: failed to list lease quotas: %w", err)
+	}
+	if len(quotas.Items) == 0 {
+		return nil, nil
+	}
+
+	// ended leases are accounted in the leased duration
+	var leases jumpstarterdevv1alpha1.LeaseList
+	if err := r.List(ctx, &leases, client.InNamespace(lease.Namespace)); err != nil {
+		return nil, fmt.Errorf("leaseQuotaViolation: failed to list leases: %w", err)
+	}
+
+	violation, err := EvaluateLeaseQuotas(quotas.Items, lease, state.Client, state.Clients, leases.Items, now)
+	if err != nil {
+		return nil, fmt.Errorf("leaseQuotaViolation: %w", err)
+	}
+	return violation, nil
+}
+
+// reserveExporter records exporter as reserved by the lease beginning in the future,
+// or the lease as conflicted if exporter is nil, no matching exporter being free for its window
+// nolint:unparam
+func (r *LeaseReconciler) reserveExporter(
+	ctx context.Context,
+	result *ctrl.Result,
+	lease *jumpstarterdevv1alpha1.Lease,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+	now time.Time,
+) error {
+	logger := log.FromContext(ctx)
+
+	if exporter == nil {
+		meta.SetStatusCondition(&lease.Status.Conditions, metav1.Condition{
+			Type:               string(jumpstarterdevv1alpha1.LeaseConditionTypeConflicted),
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: lease.Generation,
+			LastTransitionTime: metav1.Time{
+				Time: now,
+			},
+			Reason: "Overlapping",
+			Message: fmt.Sprintf("every matching exporter is leased or reserved between %s and %s",
+				lease.Spec.BeginTime.Format(time.RFC3339),
+				lease.Spec.BeginTime.Add(lease.Spec.Duration.Duration).Format(time.RFC3339)),
+		})
+		// the conflicting leases might be released or rescheduled
+		requeueLease(result, "overlapping", min(orDefault(r.OfflineRetryInterval, DefaultOfflineRetryInterval),
+			lease.Spec.BeginTime.Sub(now)))
+		return nil
+	}
+
+	logger.Info("reserveExporter: reserved exporter", "exporter", exporter.Name, "beginTime", lease.Spec.BeginTime)
+	lease.Status.ReservedExporterRef = &corev1.LocalObjectReference{
+		Name: exporter.Name,
+	}
+	meta.SetStatusCondition(&lease.Status.Conditions, metav1.Condition{
+		Type:               string(jumpstarterdevv1alpha1.LeaseConditionTypeConflicted),
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: lease.Generation,
+		LastTransitionTime: metav1.Time{
+			Time: now,
+		},
+		Reason: "Reserved",
+	})
+	meta.SetStatusCondition(&lease.Status.Conditions, metav1.Condition{
+		Type:               string(jumpstarterdevv1alpha1.LeaseConditionTypePending),
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: lease.Generation,
+		LastTransitionTime: metav1.Time{
+			Time: now,
+		},
+		Reason: "Scheduled",
+	})
+	requeueBefore(result, lease.Spec.BeginTime.Sub(now))
+	return nil
+}
+
+// leaseWaiting reports whether lease is queued for an exporter, reservations and
+// leases held back by a LeaseQuota are not
+func leaseWaiting(lease *jumpstarterdevv1alpha1.Lease) bool {
+	return leaseAwaitingExporter(lease) &&
+		!meta.IsStatusConditionTrue(lease.Status.Conditions, string(jumpstarterdevv1alpha1.LeaseConditionTypeQuotaExceeded))
+}
+
+// leaseAwaitingExporter reports whether lease still has to acquire an exporter, and might
+func leaseAwaitingExporter(lease *jumpstarterdevv1alpha1.Lease) bool {
+	return lease.Status.ExporterRef == nil &&
+		lease.Status.ReservedExporterRef == nil &&
+		!LeaseScheduled(lease, time.Now()) &&
+		!lease.Status.Ended &&
+		!lease.Spec.Release &&
+		!meta.IsStatusConditionTrue(lease.Status.Conditions, string(jumpstarterdevv1alpha1.LeaseConditionTypeUnsatisfiable))
+}
+
+// leaseQueuedBefore reports whether the waiting lease a is served before b, by priority first,
+// then by creation time, the names break the ties so that every reconciler agrees on the order
+func leaseQueuedBefore(
+	a *jumpstarterdevv1alpha1.Lease,
+	aPriority int,
+	b *jumpstarterdevv1alpha1.Lease,
+	bPriority int,
+) bool {
+	if aPriority != bPriority {
+		return aPriority > bPriority
+	}
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	return a.Name < b.Name
+}
+
+// leaseQueueEstimate returns the position of the waiting lease of state in the queue for exporters,
+// counting the waiting leases served before it for any of them, and when it is estimated to acquire
+// one, assuming the leases holding exporters end in time and hand them down the queue in order,
+// nil if fewer exporters are held than leases are ahead
+func leaseQueueEstimate(
+	state *AllocationState,
+	exporters []jumpstarterdevv1alpha1.Exporter,
+	now time.Time,
+) (int32, *metav1.Time) {
+	position := int32(1)
+	for i := range state.ActiveLeases {
+		other := &state.ActiveLeases[i]
+		if other.Name == state.Lease.Name || !leaseWaiting(other) {
+			continue
+		}
+		client := state.LeaseClient(other)
+		for j := range exporters {
+			exporter := &exporters[j]
+			if matches, err := selectorMatches(&other.Spec.Selector, exporter.Labels); err != nil || !matches {
+				continue
+			}
+			if leaseQueuedBefore(
+				other, leasePriority(state, other, client, exporter),
+				state.Lease, leasePriority(state, state.Lease, state.Client, exporter),
+			) {
+				position++
+				break
+			}
+		}
+	}
+
+	var ends []time.Time
+	for i := range exporters {
+		for j := range state.ActiveLeases {
+			holder := &state.ActiveLeases[j]
+			if holder.Status.BeginTime == nil || !LeaseHoldsExporter(holder, exporters[i].Name) {
+				continue
+			}
+			_, end := LeaseWindow(holder, now)
+			ends = append(ends, maxTime(end, now))
+			break
+		}
+	}
+	if int(position) > len(ends) {
+		return position, nil
+	}
+	slices.SortFunc(ends, func(a, b time.Time) int { return a.Compare(b) })
+	return position, &metav1.Time{Time: ends[position-1]}
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// waitingLeaseRequests requeues the leases waiting in the namespace of a lease that ended,
+// so that the exporters and quota it frees are handed to the head of the queue right away
+func (r *LeaseReconciler) waitingLeaseRequests(ctx context.Context, obj client.Object) []reconcile.Request {
+	lease, ok := obj.(*jumpstarterdevv1alpha1.Lease)
+	if !ok || !lease.Status.Ended || (lease.Status.ExporterRef == nil && lease.Status.ReservedExporterRef == nil) {
+		return nil
+	}
+
+	var leases jumpstarterdevv1alpha1.LeaseList
+	if err := r.List(ctx, &leases, client.InNamespace(lease.Namespace), MatchingActiveLeases()); err != nil {
+		log.FromContext(ctx).Error(err, "waitingLeaseRequests: failed to list active leases")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range leases.Items {
+		if leaseAwaitingExporter(&leases.Items[i]) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: client.ObjectKeyFromObject(&leases.Items[i]),
+			})
+		}
+	}
+	return requests
+}
+
+// waitForExporterBackoff is when a lease waiting for exporters that could satisfy it for waited is retried,
+// the retries double the time waited so far, between WaitForExporterMinBackoff and WaitForExporterMaxBackoff
+func (r *LeaseReconciler) waitForExporterBackoff(waited time.Duration) time.Duration {
+	minBackoff := orDefault(r.WaitForExporterMinBackoff, DefaultWaitForExporterMinBackoff)
+	maxBackoff := orDefault(r.WaitForExporterMaxBackoff, DefaultWaitForExporterMaxBackoff)
+	return min(max(waited, minBackoff), maxBackoff)
+}
+
+// orDefault returns interval, or fallback if it is not positive
+func orDefault(interval time.Duration, fallback time.Duration) time.Duration {
+	if interval <= 0 {
+		return fallback
+	}
+	return interval
+}
+
+// exporterWaitingLeaseRequests requeues the leases waiting for exporters that could satisfy them
+// whose selector matches an exporter that was created or changed, instead of their next backoff
+func (r *LeaseReconciler) exporterWaitingLeaseRequests(ctx context.Context, obj client.Object) []reconcile.Request {
+	var leases jumpstarterdevv1alpha1.LeaseList
+	if err := r.List(ctx, &leases, client.InNamespace(obj.GetNamespace()), MatchingActiveLeases()); err != nil {
+		log.FromContext(ctx).Error(err, "exporterWaitingLeaseRequests: failed to list active leases")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range leases.Items {
+		lease := &leases.Items[i]
+		if !lease.Spec.WaitForExporter || !leaseAwaitingExporter(lease) {
+			continue
+		}
+		if matches, err := selectorMatches(&lease.Spec.Selector, obj.GetLabels()); err != nil || !matches {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(lease)})
+	}
+	return requests
+}
+
+// requeueLease requeues the waiting lease before after, counting the requeue for reason
+func requeueLease(result *ctrl.Result, reason string, after time.Duration) {
+	leaseRequeuesTotal.WithLabelValues(reason).Inc()
+	requeueBefore(result, after)
+}
+
+// requeueBefore makes sure result requeues within after, keeping an earlier requeue
+func requeueBefore(result *ctrl.Result, after time.Duration) {
+	if result.RequeueAfter == 0 || after < result.RequeueAfter {
+		result.RequeueAfter = after
+	}
+}
+
+// matchingExporters returns the exporters in namespace matching selector, from the
+// ExporterIndex when it is set and synced, by listing all exporters otherwise
+func (r *LeaseReconciler) matchingExporters(
+	ctx context.Context,
+	namespace string,
+	selector labels.Selector,
+) ([]jumpstarterdevv1alpha1.Exporter, error) {
+	if r.ExporterIndex == nil || !r.ExporterIndex.Synced() {
+		var exporters jumpstarterdevv1alpha1.ExporterList
+		if err := r.List(
+			ctx,
+			&exporters,
+			client.InNamespace(namespace),
+			client.MatchingLabelsSelector{Selector: selector},
+		); err != nil {
+			return nil, fmt.Errorf("matchingExporters: failed to list exporters: %w", err)
+		}
+		return exporters.Items, nil
+	}
+
+	var exporters []jumpstarterdevv1alpha1.Exporter
+	for _, name := range r.ExporterIndex.Match(namespace, selector) {
+		var exporter jumpstarterdevv1alpha1.Exporter
+		if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &exporter); err != nil {
+			// the index may be ahead of the cache for exporters deleted in the meantime
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("matchingExporters: failed to get exporter: %w", err)
+		}
+		exporters = append(exporters, exporter)
+	}
+	return exporters, nil
+}
+
+// roleExporters returns the exporters matching the selector of each role of lease, by role name
+func (r *LeaseReconciler) roleExporters(
+	ctx context.Context,
+	lease *jumpstarterdevv1alpha1.Lease,
+) (map[string][]jumpstarterdevv1alpha1.Exporter, error) {
+	exporters := map[string][]jumpstarterdevv1alpha1.Exporter{}
+	for _, role := range lease.Spec.Roles {
+		selector, err := metav1.LabelSelectorAsSelector(&role.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("roleExporters: failed to create selector of role %s: %w", role.Name, err)
+		}
+		exporters[role.Name], err = r.matchingExporters(ctx, lease.Namespace, selector)
+		if err != nil {
+			return nil, fmt.Errorf("roleExporters: %w", err)
+		}
+	}
+	return exporters, nil
+}
+
+// linkedExporters returns the exporters of each jumpstarter.dev/group the exporters belong to
+func (r *LeaseReconciler) linkedExporters(
+	ctx context.Context,
+	namespace string,
+	exporters []jumpstarterdevv1alpha1.Exporter,
+) (map[string][]jumpstarterdevv1alpha1.Exporter, error) {
+	groups := map[string][]jumpstarterdevv1alpha1.Exporter{}
+	for _, exporter := range exporters {
+		group, ok := exporter.Labels[jumpstarterdevv1alpha1.ExporterLabelGroup]
+		if !ok {
+			continue
+		}
+		if _, ok := groups[group]; ok {
+			continue
+		}
+		var linked jumpstarterdevv1alpha1.ExporterList
+		if err := r.List(
+			ctx,
+			&linked,
+			client.InNamespace(namespace),
+			client.MatchingLabels{jumpstarterdevv1alpha1.ExporterLabelGroup: group},
+		); err != nil {
+			return nil, fmt.Errorf("linkedExporters: failed to list exporters of group %s: %w", group, err)
+		}
+		groups[group] = linked.Items
+	}
+	return groups, nil
+}
+
+// leasedExporters returns the exporters of namespace by name, only if one of the policies limits
+// the number of leases it grants at the same time, attributing the leases to policies is unneeded otherwise
+func (r *LeaseReconciler) leasedExporters(
+	ctx context.Context,
+	namespace string,
+	policies []jumpstarterdevv1alpha1.ExporterAccessPolicy,
+) (map[string]*jumpstarterdevv1alpha1.Exporter, error) {
+	limited := false
+	for i := range policies {
+		for j := range policies[i].Spec.Policies {
+			limited = limited || limitsConcurrentLeases(&policies[i].Spec.Policies[j])
+		}
+	}
+	if !limited {
+		return nil, nil
+	}
+
+	var exporters jumpstarterdevv1alpha1.ExporterList
+	if err := r.List(ctx, &exporters, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("leasedExporters: failed to list exporters: %w", err)
+	}
+	leased := make(map[string]*jumpstarterdevv1alpha1.Exporter, len(exporters.Items))
+	for i := range exporters.Items {
+		leased[exporters.Items[i].Name] = &exporters.Items[i]
+	}
+	return leased, nil
+}
+
+// shadowAllocate runs the ShadowAllocator over the same snapshot as the active allocation
+// and reports whether both would have made the same decision
+func (r *LeaseReconciler) shadowAllocate(
+	ctx context.Context,
+	state *AllocationState,
+	exporters []jumpstarterdevv1alpha1.Exporter,
+	actual *Allocation,
+) {
+	logger := log.FromContext(ctx).WithValues("shadowAllocator", r.ShadowAllocator.Name)
+
+	shadow, err := r.ShadowAllocator.Allocate(ctx, state, exporters)
+	if err != nil {
+		logger.Error(err, "shadowAllocate: shadow allocator failed")
+		shadowAllocationsTotal.WithLabelValues(r.ShadowAllocator.Name, "error").Inc()
+		return
+	}
+
+	result := "match"
+	if allocationDecision(shadow) != allocationDecision(actual) {
+		result = "mismatch"
+	}
+	shadowAllocationsTotal.WithLabelValues(r.ShadowAllocator.Name, result).Inc()
+
+	logger.Info("shadowAllocate: shadow allocation evaluated",
+		"result", result,
+		"actual", allocationDecision(actual),
+		"shadow", allocationDecision(shadow),
+		"shadowScores", shadow.Scores,
+	)
+}
+
+// unsatisfiableReason returns why no exporter could ever satisfy the lease of the unsatisfiable allocation
+func unsatisfiableReason(allocation *Allocation) string {
+	switch {
+	case allocation.Filtered[OnlineFilter{}.Name()] > 0:
+		return "Offline"
+	case allocation.Filtered[LeaseRolesFilter{}.Name()] > 0:
+		return "Roles"
+	case allocation.Filtered[AffinityFilter{}.Name()] > 0:
+		return "Affinity"
+	case allocation.Filtered[AccessPolicyFilter{}.Name()] > 0:
+		return "AccessDenied"
+	default:
+		return "NoExporter"
+	}
+}
+
+// unsatisfiableMessage explains the reason the lease of state is unsatisfiable, if not obvious
+func unsatisfiableMessage(state *AllocationState, reason string) string {
+	if reason != "AccessDenied" {
+		return ""
+	}
+	message := fmt.Sprintf("no ExporterAccessPolicy permits client %s to lease the matching exporters for %s",
+		state.Lease.Spec.ClientRef.Name, state.Lease.Spec.Duration.Duration)
+	if DefaultDeny(state.AccessPolicies) {
+		message += ", the exporters not selected by any policy are denied"
+	}
+	return message
+}
+
+// allocationDecision summarizes an Allocation as the exporter name, or the resulting lease condition
+func allocationDecision(allocation *Allocation) string {
+	switch {
+	case allocation.Exporter != nil:
+		return allocation.Exporter.Name
+	case allocation.Satisfiable():
+		return string(jumpstarterdevv1alpha1.LeaseConditionTypePending)
+	default:
+		return string(jumpstarterdevv1alpha1.LeaseConditionTypeUnsatisfiable)
+	}
+}
+
+func (r *LeaseReconciler) allocator() *Allocator {
+	if r.Allocator == nil {
+		return NewDefaultAllocator()
+	}
+	return r.Allocator
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *LeaseReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := metrics.Registry.Register(&leasePhaseCollector{reader: mgr.GetClient()}); err != nil {
+		return fmt.Errorf("SetupWithManager: failed to register lease phase metrics: %w", err)
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&jumpstarterdevv1alpha1.Lease{}).
+		WithOptions(ctrlcontroller.Options{MaxConcurrentReconciles: max(r.MaxConcurrentReconciles, 1)}).
+		Watches(&jumpstarterdevv1alpha1.Lease{}, handler.EnqueueRequestsFromMapFunc(r.waitingLeaseRequests)).
+		Watches(&jumpstarterdevv1alpha1.Exporter{}, handler.EnqueueRequestsFromMapFunc(r.exporterWaitingLeaseRequests)).
 		Complete(r)
 }
@@ -23,10 +23,12 @@ import (
 	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
@@ -129,6 +131,47 @@ var _ = Describe("Lease Controller", func() {
 		})
 	})
 
+	When("waiting for an exporter that does not exist yet", func() {
+		It("should stay pending until a matching exporter is created", func() {
+			lease := leaseDutA2Sec.DeepCopy()
+			lease.Spec.Selector.MatchLabels["dut"] = "c"
+			lease.Spec.WaitForExporter = true
+
+			ctx := context.Background()
+			Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+			result := reconcileLease(ctx, lease)
+			Expect(result.RequeueAfter).To(Equal(DefaultWaitForExporterMinBackoff))
+
+			updatedLease := getLease(ctx, lease.Name)
+			Expect(updatedLease.Status.ExporterRef).To(BeNil())
+			Expect(meta.IsStatusConditionTrue(
+				updatedLease.Status.Conditions,
+				string(jumpstarterdevv1alpha1.LeaseConditionTypeUnsatisfiable),
+			)).To(BeFalse())
+			Expect(meta.FindStatusCondition(
+				updatedLease.Status.Conditions,
+				string(jumpstarterdevv1alpha1.LeaseConditionTypePending),
+			).Reason).To(Equal("WaitingForExporter"))
+
+			exporter := testExporter3DutB.DeepCopy()
+			exporter.Name = "exporter-dut-c"
+			exporter.Labels = map[string]string{"dut": "c"}
+			createExporters(ctx, exporter)
+			defer deleteExporters(ctx, exporter)
+			setExporterOnlineConditions(ctx, exporter.Name, metav1.ConditionTrue)
+
+			requests := (&LeaseReconciler{Client: k8sClient}).exporterWaitingLeaseRequests(ctx, exporter)
+			Expect(requests).To(ConsistOf(reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: lease.Namespace, Name: lease.Name},
+			}))
+
+			_ = reconcileLease(ctx, lease)
+			updatedLease = getLease(ctx, lease.Name)
+			Expect(updatedLease.Status.ExporterRef).NotTo(BeNil())
+			Expect(updatedLease.Status.ExporterRef.Name).To(Equal(exporter.Name))
+		})
+	})
+
 	When("trying to lease an offline exporter", func() {
 		It("should fail right away", func() {
 			lease := leaseDutA2Sec.DeepCopy()
@@ -149,6 +192,51 @@ var _ = Describe("Lease Controller", func() {
 				string(jumpstarterdevv1alpha1.LeaseConditionTypeUnsatisfiable),
 			)).To(BeTrue())
 		})
+
+		It("should stay pending within the offline retry window", func() {
+			lease := leaseDutA2Sec.DeepCopy()
+
+			ctx := context.Background()
+
+			setExporterOnlineConditions(ctx, testExporter1DutA.Name, metav1.ConditionFalse)
+			setExporterOnlineConditions(ctx, testExporter2DutA.Name, metav1.ConditionFalse)
+
+			Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+
+			leaseReconciler := &LeaseReconciler{
+				Client:             k8sClient,
+				Scheme:             k8sClient.Scheme(),
+				OfflineRetryWindow: time.Minute,
+			}
+			request := reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: lease.Namespace, Name: lease.Name},
+			}
+			result, err := leaseReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+
+			updatedLease := getLease(ctx, lease.Name)
+			Expect(updatedLease.Status.ExporterRef).To(BeNil())
+			pending := meta.FindStatusCondition(
+				updatedLease.Status.Conditions,
+				string(jumpstarterdevv1alpha1.LeaseConditionTypePending),
+			)
+			Expect(pending).NotTo(BeNil())
+			Expect(pending.Reason).To(Equal("Offline"))
+			Expect(meta.IsStatusConditionTrue(
+				updatedLease.Status.Conditions,
+				string(jumpstarterdevv1alpha1.LeaseConditionTypeUnsatisfiable),
+			)).To(BeFalse())
+
+			// the exporter comes back online
+			setExporterOnlineConditions(ctx, testExporter2DutA.Name, metav1.ConditionTrue)
+			_, err = leaseReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			updatedLease = getLease(ctx, lease.Name)
+			Expect(updatedLease.Status.ExporterRef).NotTo(BeNil())
+			Expect(updatedLease.Status.ExporterRef.Name).To(Equal(testExporter2DutA.Name))
+		})
 	})
 
 	When("trying to lease exporters, and some matching exporters are online and while others are offline", func() {
@@ -243,6 +331,204 @@ var _ = Describe("Lease Controller", func() {
 			Expect(updatedLease.Status.ExporterRef).NotTo(BeNil())
 
 		})
+
+		It("should be acquired by the lease waiting the longest", func() {
+			lease := leaseDutA2Sec.DeepCopy()
+			lease.Spec.Selector.MatchLabels["dut"] = "b"
+
+			ctx := context.Background()
+			Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+			_ = reconcileLease(ctx, lease)
+
+			// two leases queue for the only dut b exporter
+			lease2 := leaseDutA2Sec.DeepCopy()
+			lease2.Name = "lease2"
+			lease2.Spec.Selector.MatchLabels["dut"] = "b"
+			Expect(k8sClient.Create(ctx, lease2)).To(Succeed())
+			_ = reconcileLease(ctx, lease2)
+
+			lease3 := leaseDutA2Sec.DeepCopy()
+			lease3.Name = "lease3"
+			lease3.Spec.Selector.MatchLabels["dut"] = "b"
+			Expect(k8sClient.Create(ctx, lease3)).To(Succeed())
+			_ = reconcileLease(ctx, lease3)
+
+			// release the exporter
+			updatedLease := getLease(ctx, lease.Name)
+			updatedLease.Spec.Release = true
+			Expect(k8sClient.Update(ctx, updatedLease)).To(Succeed())
+			_ = reconcileLease(ctx, lease)
+
+			// the lease queued last is reconciled first, but must keep waiting
+			_ = reconcileLease(ctx, lease3)
+			updatedLease = getLease(ctx, lease3.Name)
+			Expect(updatedLease.Status.ExporterRef).To(BeNil())
+			pending := meta.FindStatusCondition(
+				updatedLease.Status.Conditions,
+				string(jumpstarterdevv1alpha1.LeaseConditionTypePending),
+			)
+			Expect(pending).NotTo(BeNil())
+			Expect(pending.Reason).To(Equal("NotAvailable"))
+
+			_ = reconcileLease(ctx, lease2)
+			updatedLease = getLease(ctx, lease2.Name)
+			Expect(updatedLease.Status.ExporterRef).NotTo(BeNil())
+			Expect(updatedLease.Status.ExporterRef.Name).To(Equal(testExporter3DutB.Name))
+		})
+
+		It("should report the queue position and estimated begin time", func() {
+			lease := leaseDutA2Sec.DeepCopy()
+			lease.Spec.Selector.MatchLabels["dut"] = "b"
+
+			ctx := context.Background()
+			Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+			_ = reconcileLease(ctx, lease)
+			holder := getLease(ctx, lease.Name)
+			Expect(holder.Status.ExporterRef).NotTo(BeNil())
+			Expect(holder.Status.QueuePosition).To(BeNil())
+
+			lease2 := leaseDutA2Sec.DeepCopy()
+			lease2.Name = "lease2"
+			lease2.Spec.Selector.MatchLabels["dut"] = "b"
+			Expect(k8sClient.Create(ctx, lease2)).To(Succeed())
+			_ = reconcileLease(ctx, lease2)
+
+			lease3 := leaseDutA2Sec.DeepCopy()
+			lease3.Name = "lease3"
+			lease3.Spec.Selector.MatchLabels["dut"] = "b"
+			Expect(k8sClient.Create(ctx, lease3)).To(Succeed())
+			_ = reconcileLease(ctx, lease3)
+
+			updatedLease := getLease(ctx, lease2.Name)
+			Expect(updatedLease.Status.QueuePosition).To(HaveValue(BeEquivalentTo(1)))
+			Expect(updatedLease.Status.EstimatedBeginTime).NotTo(BeNil())
+			Expect(updatedLease.Status.EstimatedBeginTime.Time).To(BeTemporally("~",
+				holder.Status.BeginTime.Add(holder.Spec.Duration.Duration), time.Second))
+
+			// a single exporter is held, the second lease in the queue cannot be estimated
+			updatedLease = getLease(ctx, lease3.Name)
+			Expect(updatedLease.Status.QueuePosition).To(HaveValue(BeEquivalentTo(2)))
+			Expect(updatedLease.Status.EstimatedBeginTime).To(BeNil())
+		})
+
+		It("should fail when no exporter is acquired within the acquire timeout", func() {
+			lease := leaseDutA2Sec.DeepCopy()
+			lease.Spec.Selector.MatchLabels["dut"] = "b"
+
+			ctx := context.Background()
+			Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+			_ = reconcileLease(ctx, lease)
+
+			// create another lease that waits for the only dut b exporter
+			lease2 := leaseDutA2Sec.DeepCopy()
+			lease2.Name = "lease2"
+			lease2.Spec.Selector.MatchLabels["dut"] = "b"
+			lease2.Spec.AcquireTimeout = &metav1.Duration{Duration: 2 * time.Second}
+			Expect(k8sClient.Create(ctx, lease2)).To(Succeed())
+
+			recorder := record.NewFakeRecorder(1)
+			leaseReconciler := &LeaseReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+			}
+			request := reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: lease2.Namespace, Name: lease2.Name},
+			}
+
+			result, err := leaseReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+
+			updatedLease := getLease(ctx, lease2.Name)
+			Expect(updatedLease.Status.Ended).To(BeFalse())
+
+			time.Sleep(2 * time.Second)
+			_, err = leaseReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			updatedLease = getLease(ctx, lease2.Name)
+			Expect(updatedLease.Status.ExporterRef).To(BeNil())
+			Expect(updatedLease.Status.Ended).To(BeTrue())
+			Expect(updatedLease.Labels).To(HaveKey(string(jumpstarterdevv1alpha1.LeaseLabelEnded)))
+			failed := meta.FindStatusCondition(
+				updatedLease.Status.Conditions,
+				string(jumpstarterdevv1alpha1.LeaseConditionTypeFailed),
+			)
+			Expect(failed).NotTo(BeNil())
+			Expect(failed.Status).To(Equal(metav1.ConditionTrue))
+			Expect(failed.Reason).To(Equal("Timeout"))
+			Expect(recorder.Events).To(Receive(HavePrefix("Warning Timeout")))
+		})
+	})
+
+	When("trying to lease with a custom allocator scorer", func() {
+		It("should acquire the exporter with the highest score", func() {
+			lease := leaseDutA2Sec.DeepCopy()
+
+			ctx := context.Background()
+			Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+
+			leaseReconciler := &LeaseReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				Allocator: &Allocator{
+					Filters: DefaultFilters(),
+					Scorers: []WeightedScorePlugin{{
+						ScorePlugin: preferExporterScorer{name: testExporter2DutA.Name},
+						Weight:      1,
+					}},
+				},
+			}
+			_, err := leaseReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: lease.Namespace, Name: lease.Name},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			updatedLease := getLease(ctx, lease.Name)
+			Expect(updatedLease.Status.ExporterRef).NotTo(BeNil())
+			Expect(updatedLease.Status.ExporterRef.Name).To(Equal(testExporter2DutA.Name))
+
+			decision := updatedLease.Status.Decision
+			Expect(decision).NotTo(BeNil())
+			Expect(decision.Exporter).To(Equal(testExporter2DutA.Name))
+			Expect(decision.Candidates).To(BeNumerically(">=", 2))
+			Expect(decision.Scores).To(HaveKeyWithValue("PreferExporter", MaxPluginScore))
+		})
+	})
+
+	When("trying to lease with a shadow allocator", func() {
+		It("should only apply the decision of the active allocator", func() {
+			lease := leaseDutA2Sec.DeepCopy()
+
+			ctx := context.Background()
+			Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+
+			shadow := &Allocator{
+				Name:    "shadow-test",
+				Filters: DefaultFilters(),
+				Scorers: []WeightedScorePlugin{{
+					ScorePlugin: preferExporterScorer{name: testExporter2DutA.Name},
+					Weight:      1,
+				}},
+			}
+			leaseReconciler := &LeaseReconciler{
+				Client:          k8sClient,
+				Scheme:          k8sClient.Scheme(),
+				ShadowAllocator: shadow,
+			}
+			_, err := leaseReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: lease.Namespace, Name: lease.Name},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			updatedLease := getLease(ctx, lease.Name)
+			Expect(updatedLease.Status.ExporterRef).NotTo(BeNil())
+			Expect(updatedLease.Status.ExporterRef.Name).To(Equal(testExporter1DutA.Name))
+			Expect(testutil.ToFloat64(
+				shadowAllocationsTotal.WithLabelValues(shadow.Name, "mismatch"),
+			)).To(BeNumerically("==", 1))
+		})
 	})
 
 	When("releasing a lease early", func() {
@@ -275,6 +561,131 @@ var _ = Describe("Lease Controller", func() {
 			Expect(updatedExporter.Status.LeaseRef).To(BeNil())
 		})
 	})
+
+	When("extending a lease near its end", func() {
+		It("should keep the lease running even if its phase was already Ending", func() {
+			lease := leaseDutA2Sec.DeepCopy()
+
+			ctx := context.Background()
+			Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+			_ = reconcileLease(ctx, lease)
+
+			// an earlier reconcile observed the lease past its end before it was extended
+			updatedLease := getLease(ctx, lease.Name)
+			Expect(updatedLease.Status.BeginTime).NotTo(BeNil())
+			updatedLease.Status.Phase = jumpstarterdevv1alpha1.LeasePhaseEnding
+			Expect(k8sClient.Status().Update(ctx, updatedLease)).To(Succeed())
+
+			updatedLease = getLease(ctx, lease.Name)
+			updatedLease.Spec.Duration.Duration = time.Hour
+			Expect(k8sClient.Update(ctx, updatedLease)).To(Succeed())
+
+			invalid := leaseInvalidTransitionsTotal.WithLabelValues(
+				string(jumpstarterdevv1alpha1.LeasePhaseEnding),
+				string(jumpstarterdevv1alpha1.LeasePhaseActive),
+			)
+			before := testutil.ToFloat64(invalid)
+			_ = reconcileLease(ctx, updatedLease)
+
+			updatedLease = getLease(ctx, lease.Name)
+			Expect(updatedLease.Status.Ended).To(BeFalse())
+			Expect(updatedLease.Status.Phase).To(Equal(jumpstarterdevv1alpha1.LeasePhaseActive))
+			Expect(testutil.ToFloat64(invalid)).To(Equal(before + 1))
+		})
+	})
+
+	When("trying to lease an exporter in the future", func() {
+		It("should reserve the exporter until the lease begins", func() {
+			lease := leaseDutA2Sec.DeepCopy()
+			lease.Spec.Selector.MatchLabels["dut"] = "b"
+			lease.Spec.BeginTime = &metav1.Time{Time: time.Now().Add(time.Hour)}
+			lease.Spec.Duration.Duration = time.Hour
+
+			ctx := context.Background()
+			Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+			_ = reconcileLease(ctx, lease)
+
+			updatedLease := getLease(ctx, lease.Name)
+			Expect(updatedLease.Status.ExporterRef).To(BeNil())
+			Expect(updatedLease.Status.ReservedExporterRef).NotTo(BeNil())
+			Expect(updatedLease.Status.ReservedExporterRef.Name).To(Equal(testExporter3DutB.Name))
+			Expect(getExporter(ctx, testExporter3DutB.Name).Status.LeaseRef).To(BeNil())
+
+			// a lease ending before the reservation begins can acquire the exporter
+			lease2 := leaseDutA2Sec.DeepCopy()
+			lease2.Name = "lease2"
+			lease2.Spec.Selector.MatchLabels["dut"] = "b"
+			Expect(k8sClient.Create(ctx, lease2)).To(Succeed())
+			_ = reconcileLease(ctx, lease2)
+
+			updatedLease = getLease(ctx, lease2.Name)
+			Expect(updatedLease.Status.ExporterRef).NotTo(BeNil())
+			Expect(updatedLease.Status.ExporterRef.Name).To(Equal(testExporter3DutB.Name))
+		})
+
+		It("should not give the exporter to leases overlapping the reservation", func() {
+			lease := leaseDutA2Sec.DeepCopy()
+			lease.Spec.Selector.MatchLabels["dut"] = "b"
+			lease.Spec.BeginTime = &metav1.Time{Time: time.Now().Add(time.Hour)}
+			lease.Spec.Duration.Duration = time.Hour
+
+			ctx := context.Background()
+			Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+			_ = reconcileLease(ctx, lease)
+
+			lease2 := leaseDutA2Sec.DeepCopy()
+			lease2.Name = "lease2"
+			lease2.Spec.Selector.MatchLabels["dut"] = "b"
+			lease2.Spec.Duration.Duration = 2 * time.Hour
+			Expect(k8sClient.Create(ctx, lease2)).To(Succeed())
+			_ = reconcileLease(ctx, lease2)
+
+			updatedLease := getLease(ctx, lease2.Name)
+			Expect(updatedLease.Status.ExporterRef).To(BeNil())
+			Expect(meta.IsStatusConditionTrue(
+				updatedLease.Status.Conditions,
+				string(jumpstarterdevv1alpha1.LeaseConditionTypePending),
+			)).To(BeTrue())
+
+			// another reservation overlapping the first one is conflicted
+			lease3 := leaseDutA2Sec.DeepCopy()
+			lease3.Name = "lease3"
+			lease3.Spec.Selector.MatchLabels["dut"] = "b"
+			lease3.Spec.BeginTime = &metav1.Time{Time: time.Now().Add(90 * time.Minute)}
+			lease3.Spec.Duration.Duration = time.Hour
+			Expect(k8sClient.Create(ctx, lease3)).To(Succeed())
+			_ = reconcileLease(ctx, lease3)
+
+			updatedLease = getLease(ctx, lease3.Name)
+			Expect(updatedLease.Status.ReservedExporterRef).To(BeNil())
+			Expect(meta.IsStatusConditionTrue(
+				updatedLease.Status.Conditions,
+				string(jumpstarterdevv1alpha1.LeaseConditionTypeConflicted),
+			)).To(BeTrue())
+		})
+
+		It("should acquire the reserved exporter when the lease begins", func() {
+			lease := leaseDutA2Sec.DeepCopy()
+			lease.Spec.BeginTime = &metav1.Time{Time: time.Now().Add(time.Second)}
+
+			ctx := context.Background()
+			Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+			_ = reconcileLease(ctx, lease)
+
+			updatedLease := getLease(ctx, lease.Name)
+			Expect(updatedLease.Status.ExporterRef).To(BeNil())
+			Expect(updatedLease.Status.ReservedExporterRef).NotTo(BeNil())
+			reserved := updatedLease.Status.ReservedExporterRef.Name
+
+			time.Sleep(time.Until(lease.Spec.BeginTime.Time))
+			_ = reconcileLease(ctx, lease)
+
+			updatedLease = getLease(ctx, lease.Name)
+			Expect(updatedLease.Status.ExporterRef).NotTo(BeNil())
+			Expect(updatedLease.Status.ExporterRef.Name).To(Equal(reserved))
+			Expect(updatedLease.Status.BeginTime).NotTo(BeNil())
+		})
+	})
 })
 
 var testExporter1DutA = &jumpstarterdevv1alpha1.Exporter{
@@ -307,6 +718,25 @@ var testExporter3DutB = &jumpstarterdevv1alpha1.Exporter{
 	},
 }
 
+type preferExporterScorer struct {
+	name string
+}
+
+func (preferExporterScorer) Name() string {
+	return "PreferExporter"
+}
+
+func (p preferExporterScorer) Score(
+	_ context.Context,
+	_ *AllocationState,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+) (int64, error) {
+	if exporter.Name == p.name {
+		return MaxPluginScore, nil
+	}
+	return 0, nil
+}
+
 func setExporterOnlineConditions(ctx context.Context, name string, status metav1.ConditionStatus) {
 	exporter := getExporter(ctx, name)
 	meta.SetStatusCondition(&exporter.Status.Conditions, metav1.Condition{
@@ -345,12 +775,17 @@ func reconcileLease(ctx context.Context, lease *jumpstarterdevv1alpha1.Lease) re
 	})
 	Expect(err).NotTo(HaveOccurred())
 
-	for _, owner := range getLease(ctx, lease.Name).OwnerReferences {
+	updatedLease := getLease(ctx, lease.Name)
+	for _, owner := range updatedLease.OwnerReferences {
 		_, err := exporterReconciler.Reconcile(ctx, reconcile.Request{
 			NamespacedName: types.NamespacedName{Namespace: lease.Namespace, Name: owner.Name},
 		})
 		Expect(err).NotTo(HaveOccurred())
 	}
+	for _, request := range linkedExporterRequests(ctx, updatedLease) {
+		_, err := exporterReconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+	}
 
 	return res
 }
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// LeaseDurationLimit is the default and maximum duration of leases, unset if zero
+type LeaseDurationLimit struct {
+	// The duration of the leases requested without one
+	Default metav1.Duration `json:"default,omitempty"`
+	// The longest duration of a lease, regardless of the ExporterAccessPolicies
+	Maximum metav1.Duration `json:"maximum,omitempty"`
+}
+
+// LeaseDurationLimits are the limits of the durations of the leases of every namespace, and
+// the limits of specific namespaces overriding them
+type LeaseDurationLimits struct {
+	LeaseDurationLimit `json:",inline"`
+	// The limits of namespaces, their unset fields fall back to the global limits
+	Namespaces map[string]LeaseDurationLimit `json:"namespaces,omitempty"`
+}
+
+// LoadLeaseDurationLimits reads LeaseDurationLimits from path
+func LoadLeaseDurationLimits(path string) (*LeaseDurationLimits, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("LoadLeaseDurationLimits: %w", err)
+	}
+	var limits LeaseDurationLimits
+	if err := yaml.UnmarshalStrict(content, &limits); err != nil {
+		return nil, fmt.Errorf("LoadLeaseDurationLimits: invalid configuration: %w", err)
+	}
+	if err := limits.LeaseDurationLimit.validate(); err != nil {
+		return nil, fmt.Errorf("LoadLeaseDurationLimits: %w", err)
+	}
+	for namespace := range limits.Namespaces {
+		if err := limits.For(namespace).validate(); err != nil {
+			return nil, fmt.Errorf("LoadLeaseDurationLimits: namespace %s: %w", namespace, err)
+		}
+	}
+	return &limits, nil
+}
+
+// For returns the limits of the leases of namespace, no limit if l is nil
+func (l *LeaseDurationLimits) For(namespace string) LeaseDurationLimit {
+	if l == nil {
+		return LeaseDurationLimit{}
+	}
+	limit := l.LeaseDurationLimit
+	if override, ok := l.Namespaces[namespace]; ok {
+		if override.Default.Duration != 0 {
+			limit.Default = override.Default
+		}
+		if override.Maximum.Duration != 0 {
+			limit.Maximum = override.Maximum
+		}
+	}
+	return limit
+}
+
+// Exceeded reports whether leases of duration exceed the maximum
+func (l LeaseDurationLimit) Exceeded(duration time.Duration) bool {
+	return l.Maximum.Duration > 0 && duration > l.Maximum.Duration
+}
+
+func (l LeaseDurationLimit) validate() error {
+	if l.Default.Duration < 0 || l.Maximum.Duration < 0 {
+		return fmt.Errorf("negative lease duration")
+	}
+	if l.Exceeded(l.Default.Duration) {
+		return fmt.Errorf("default lease duration %s exceeds the maximum %s", l.Default.Duration, l.Maximum.Duration)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+var _ = Describe("Lease duration limits", func() {
+	It("should override the global limits per namespace", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "limits.yaml")
+		Expect(os.WriteFile(path, []byte(`
+default: 1h
+maximum: 8h
+namespaces:
+  short:
+    maximum: 2h
+`), 0o600)).To(Succeed())
+
+		limits, err := LoadLeaseDurationLimits(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(limits.For("default").Maximum.Duration).To(Equal(8 * time.Hour))
+		Expect(limits.For("short").Default.Duration).To(Equal(time.Hour))
+		Expect(limits.For("short").Exceeded(3 * time.Hour)).To(BeTrue())
+		Expect(limits.For("default").Exceeded(3 * time.Hour)).To(BeFalse())
+
+		var unset *LeaseDurationLimits
+		Expect(unset.For("default").Exceeded(1000 * time.Hour)).To(BeFalse())
+	})
+
+	It("should reject defaults exceeding the maximum", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "limits.yaml")
+		Expect(os.WriteFile(path, []byte("maximum: 1h\nnamespaces:\n  long:\n    default: 2h\n"), 0o600)).To(Succeed())
+		_, err := LoadLeaseDurationLimits(path)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should keep the leases exceeding the maximum duration unsatisfiable", func() {
+		ctx := context.Background()
+		createExporters(ctx, testExporter1DutA)
+		setExporterOnlineConditions(ctx, testExporter1DutA.Name, metav1.ConditionTrue)
+		defer deleteExporters(ctx, testExporter1DutA)
+		defer deleteLeases(ctx, "lease1")
+
+		limits := &LeaseDurationLimits{}
+		limits.Maximum.Duration = time.Second
+		leaseReconciler := &LeaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), DurationLimits: limits}
+
+		lease := leaseDutA2Sec.DeepCopy()
+		Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+		_, err := leaseReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(lease)})
+		Expect(err).NotTo(HaveOccurred())
+
+		updatedLease := getLease(ctx, lease.Name)
+		Expect(updatedLease.Status.ExporterRef).To(BeNil())
+		condition := meta.FindStatusCondition(updatedLease.Status.Conditions,
+			string(jumpstarterdevv1alpha1.LeaseConditionTypeUnsatisfiable))
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Reason).To(Equal("DurationExceeded"))
+	})
+})
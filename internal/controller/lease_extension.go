@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// ExtendLease sets the duration of a running lease to duration, if the ExporterAccessPolicies
+// granting the client access to the exporters of the lease allow it, and no other lease
+// or reservation of the exporters begins before the extended lease ends
+func ExtendLease(
+	lease *jumpstarterdevv1alpha1.Lease,
+	duration time.Duration,
+	policies []jumpstarterdevv1alpha1.ExporterAccessPolicy,
+	tieBreak PolicyTieBreak,
+	client *jumpstarterdevv1alpha1.Client,
+	exporters []jumpstarterdevv1alpha1.Exporter,
+	leases []jumpstarterdevv1alpha1.Lease,
+	now time.Time,
+) error {
+	if lease.Status.Ended || lease.Status.BeginTime == nil {
+		return fmt.Errorf("ExtendLease: lease %s/%s is not running", lease.Namespace, lease.Name)
+	}
+	if duration <= lease.Spec.Duration.Duration {
+		return fmt.Errorf("ExtendLease: duration %s does not extend the duration %s of lease %s/%s",
+			duration, lease.Spec.Duration.Duration, lease.Namespace, lease.Name)
+	}
+
+	begin := lease.Status.BeginTime.Time
+	end := begin.Add(duration)
+	for i := range exporters {
+		exporter := &exporters[i]
+
+		violation, err := LeaseDurationViolation(policies, tieBreak, client, exporters[i:i+1], duration, now)
+		if err != nil {
+			return fmt.Errorf("ExtendLease: %w", err)
+		}
+		if violation != nil {
+			return fmt.Errorf("ExtendLease: duration %s of exporter %s %w", duration, exporter.Name, violation)
+		}
+
+		if reservation := OverlappingReservation(exporter, begin, end); reservation != nil {
+			held, err := ReservationHeldBy(reservation, client)
+			if err != nil {
+				return fmt.Errorf("ExtendLease: %w", err)
+			}
+			if !held {
+				return fmt.Errorf("ExtendLease: exporter %s is reserved from %s",
+					exporter.Name, reservation.Begin.Format(time.RFC3339))
+			}
+		}
+
+		for j := range leases {
+			other := &leases[j]
+			if other.Name == lease.Name || !LeaseReservesExporter(other, exporter.Name) {
+				continue
+			}
+			if otherBegin, _ := LeaseWindow(other, now); otherBegin.Before(end) {
+				return fmt.Errorf("ExtendLease: exporter %s is reserved by lease %s from %s",
+					exporter.Name, other.Name, otherBegin.Format(time.RFC3339))
+			}
+		}
+	}
+
+	lease.Spec.Duration.Duration = duration
+	return nil
+}
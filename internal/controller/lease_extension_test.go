@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+var _ = Describe("Lease extension", func() {
+	now := time.Now()
+
+	runningLease := func() *jumpstarterdevv1alpha1.Lease {
+		lease := leaseDutA2Sec.DeepCopy()
+		lease.Spec.Duration.Duration = time.Hour
+		lease.Status.BeginTime = &metav1.Time{Time: now}
+		lease.Status.ExporterRef = &corev1.LocalObjectReference{Name: testExporter1DutA.Name}
+		return lease
+	}
+
+	It("should extend running leases", func() {
+		lease := runningLease()
+		Expect(ExtendLease(lease, 2*time.Hour, nil, PolicyTieBreakMostRestrictive, testClient,
+			[]jumpstarterdevv1alpha1.Exporter{*testExporter1DutA}, nil, now)).To(Succeed())
+		Expect(lease.Spec.Duration.Duration).To(Equal(2 * time.Hour))
+
+		Expect(ExtendLease(lease, time.Hour, nil, PolicyTieBreakMostRestrictive, testClient,
+			[]jumpstarterdevv1alpha1.Exporter{*testExporter1DutA}, nil, now)).NotTo(Succeed())
+
+		lease.Status.Ended = true
+		Expect(ExtendLease(lease, 3*time.Hour, nil, PolicyTieBreakMostRestrictive, testClient,
+			[]jumpstarterdevv1alpha1.Exporter{*testExporter1DutA}, nil, now)).NotTo(Succeed())
+	})
+
+	It("should not exceed the maximum duration of the access policy", func() {
+		policy := fromClients(0, nil)
+		policy.MaximumDuration = &metav1.Duration{Duration: 90 * time.Minute}
+		policies := []jumpstarterdevv1alpha1.ExporterAccessPolicy{
+			accessPolicy(map[string]string{"dut": "a"}, policy),
+		}
+
+		lease := runningLease()
+		Expect(ExtendLease(lease, 2*time.Hour, policies, PolicyTieBreakMostRestrictive, testClient,
+			[]jumpstarterdevv1alpha1.Exporter{*testExporter1DutA}, nil, now)).NotTo(Succeed())
+		Expect(lease.Spec.Duration.Duration).To(Equal(time.Hour))
+
+		Expect(ExtendLease(lease, 90*time.Minute, policies, PolicyTieBreakMostRestrictive, testClient,
+			[]jumpstarterdevv1alpha1.Exporter{*testExporter1DutA}, nil, now)).To(Succeed())
+	})
+
+	It("should not overlap upcoming reservations", func() {
+		reserved := reservedExporter(testExporter1DutA, "other-client", now.Add(90*time.Minute), now.Add(3*time.Hour))
+		lease := runningLease()
+		Expect(ExtendLease(lease, 2*time.Hour, nil, PolicyTieBreakMostRestrictive, testClient,
+			[]jumpstarterdevv1alpha1.Exporter{*reserved}, nil, now)).NotTo(Succeed())
+
+		reservation := leaseDutA2Sec.DeepCopy()
+		reservation.Name = "lease2"
+		reservation.Spec.Duration.Duration = time.Hour
+		reservation.Spec.BeginTime = &metav1.Time{Time: now.Add(90 * time.Minute)}
+		reservation.Status.ReservedExporterRef = &corev1.LocalObjectReference{Name: testExporter1DutA.Name}
+		leases := []jumpstarterdevv1alpha1.Lease{*lease, *reservation}
+		Expect(ExtendLease(lease, 2*time.Hour, nil, PolicyTieBreakMostRestrictive, testClient,
+			[]jumpstarterdevv1alpha1.Exporter{*testExporter1DutA}, leases, now)).NotTo(Succeed())
+		Expect(ExtendLease(lease, 80*time.Minute, nil, PolicyTieBreakMostRestrictive, testClient,
+			[]jumpstarterdevv1alpha1.Exporter{*testExporter1DutA}, leases, now)).To(Succeed())
+	})
+})
@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// LeaseGarbageCollector deletes the leases ended for longer than TTL
+type LeaseGarbageCollector struct {
+	client.Client
+	// Recorder, if set, receives an event for each deleted lease
+	Recorder record.EventRecorder
+	// TTL is how long ended leases are kept after their end
+	TTL time.Duration
+	// Interval between two collections
+	Interval time.Duration
+	// Record writes the LeaseRecord of the leases before deleting them, if it is missing
+	Record bool
+}
+
+// Start collects the ended leases every Interval until ctx is done
+func (g *LeaseGarbageCollector) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("lease-garbage-collector")
+	ctx = ctrl.LoggerInto(ctx, logger)
+
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := g.Collect(ctx, time.Now()); err != nil {
+			logger.Error(err, "lease garbage collection failed")
+		}
+	}, g.Interval)
+	return nil
+}
+
+// Collect deletes the leases ended for longer than TTL at now
+func (g *LeaseGarbageCollector) Collect(ctx context.Context, now time.Time) error {
+	logger := log.FromContext(ctx)
+
+	var leases jumpstarterdevv1alpha1.LeaseList
+	if err := g.List(ctx, &leases, client.MatchingLabels{
+		string(jumpstarterdevv1alpha1.LeaseLabelEnded): jumpstarterdevv1alpha1.LeaseLabelEndedValue,
+	}); err != nil {
+		return fmt.Errorf("Collect: failed to list ended leases: %w", err)
+	}
+
+	for i := range leases.Items {
+		lease := &leases.Items[i]
+		if !lease.Status.Ended {
+			continue
+		}
+		end := lease.CreationTimestamp.Time
+		if lease.Status.EndTime != nil {
+			end = lease.Status.EndTime.Time
+		}
+		if now.Sub(end) < g.TTL {
+			continue
+		}
+
+		if g.Record {
+			if err := recordLease(ctx, g.Client, lease); err != nil {
+				logger.Error(err, "unable to record lease, not deleting it", "lease", client.ObjectKeyFromObject(lease))
+				continue
+			}
+		}
+		if err := g.Delete(ctx, lease, client.Preconditions{UID: &lease.UID}); err != nil {
+			if !apierrors.IsNotFound(err) && !apierrors.IsConflict(err) {
+				logger.Error(err, "unable to delete ended lease", "lease", client.ObjectKeyFromObject(lease))
+			}
+			continue
+		}
+		logger.Info("deleted ended lease", "lease", client.ObjectKeyFromObject(lease))
+		if g.Recorder != nil {
+			g.Recorder.Eventf(lease, corev1.EventTypeNormal, "GarbageCollected",
+				"Deleted lease ended at %s, after %s", end.Format(time.RFC3339), g.TTL)
+		}
+	}
+	return nil
+}
+
+// SetupWithManager runs the garbage collector with the manager, on the leader only
+func (g *LeaseGarbageCollector) SetupWithManager(mgr ctrl.Manager) error {
+	return mgr.Add(g)
+}
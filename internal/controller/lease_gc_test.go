@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+var _ = Describe("Lease garbage collector", func() {
+	AfterEach(func() {
+		ctx := context.Background()
+		deleteLeases(ctx, "lease1")
+		Expect(k8sClient.DeleteAllOf(ctx, &jumpstarterdevv1alpha1.LeaseRecord{},
+			client.InNamespace("default"))).To(Succeed())
+	})
+
+	endLease := func(ctx context.Context, end time.Time) *jumpstarterdevv1alpha1.Lease {
+		lease := leaseDutA2Sec.DeepCopy()
+		Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+
+		lease.Status.Ended = true
+		lease.Status.EndTime = &metav1.Time{Time: end}
+		Expect(k8sClient.Status().Update(ctx, lease)).To(Succeed())
+
+		lease = getLease(ctx, lease.Name)
+		lease.Labels = map[string]string{
+			string(jumpstarterdevv1alpha1.LeaseLabelEnded): jumpstarterdevv1alpha1.LeaseLabelEndedValue,
+		}
+		Expect(k8sClient.Update(ctx, lease)).To(Succeed())
+		return lease
+	}
+
+	It("should keep the leases ended for less than the TTL", func() {
+		ctx := context.Background()
+		lease := endLease(ctx, time.Now())
+
+		gc := &LeaseGarbageCollector{Client: k8sClient, TTL: time.Hour, Interval: time.Minute}
+		Expect(gc.Collect(ctx, time.Now())).To(Succeed())
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(lease), lease)).To(Succeed())
+	})
+
+	It("should record and delete the leases ended for longer than the TTL", func() {
+		ctx := context.Background()
+		lease := endLease(ctx, time.Now().Add(-2*time.Hour))
+
+		gc := &LeaseGarbageCollector{Client: k8sClient, TTL: time.Hour, Interval: time.Minute, Record: true}
+		Expect(gc.Collect(ctx, time.Now())).To(Succeed())
+
+		var record jumpstarterdevv1alpha1.LeaseRecord
+		Expect(k8sClient.Get(ctx, client.ObjectKey{
+			Namespace: lease.Namespace,
+			Name:      LeaseRecordName(lease),
+		}, &record)).To(Succeed())
+		Expect(record.Spec.LeaseUID).To(Equal(lease.UID))
+
+		err := k8sClient.Get(ctx, client.ObjectKeyFromObject(lease), lease)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+})
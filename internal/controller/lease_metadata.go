@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+const (
+	// MaxLeaseMetadataKeys is the maximum number of keys in the metadata of a lease
+	MaxLeaseMetadataKeys = 32
+	// MaxLeaseMetadataBytes is the maximum total size of the keys and values in the metadata of a lease
+	MaxLeaseMetadataBytes = 16 * 1024
+)
+
+// ErrLeaseMetadataLimit is wrapped by the errors of SetLeaseMetadata exceeding the size limits
+var ErrLeaseMetadataLimit = errors.New("lease metadata limit exceeded")
+
+// SetLeaseMetadata sets key to value in the metadata of an active lease
+func SetLeaseMetadata(lease *jumpstarterdevv1alpha1.Lease, key string, value string) error {
+	if lease.Status.Ended {
+		return fmt.Errorf("SetLeaseMetadata: lease %s/%s has ended", lease.Namespace, lease.Name)
+	}
+
+	if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+		return fmt.Errorf("SetLeaseMetadata: invalid key %s: %s", key, strings.Join(errs, ", "))
+	}
+
+	metadata := make(map[string]string, len(lease.Status.Metadata)+1)
+	for k, v := range lease.Status.Metadata {
+		metadata[k] = v
+	}
+	metadata[key] = value
+
+	if len(metadata) > MaxLeaseMetadataKeys {
+		return fmt.Errorf("SetLeaseMetadata: lease %s/%s already has %d metadata keys: %w",
+			lease.Namespace, lease.Name, MaxLeaseMetadataKeys, ErrLeaseMetadataLimit)
+	}
+
+	size := 0
+	for k, v := range metadata {
+		size += len(k) + len(v)
+	}
+	if size > MaxLeaseMetadataBytes {
+		return fmt.Errorf("SetLeaseMetadata: metadata of lease %s/%s would exceed %d bytes: %w",
+			lease.Namespace, lease.Name, MaxLeaseMetadataBytes, ErrLeaseMetadataLimit)
+	}
+
+	lease.Status.Metadata = metadata
+	return nil
+}
+
+// DeleteLeaseMetadata removes key from the metadata of lease
+func DeleteLeaseMetadata(lease *jumpstarterdevv1alpha1.Lease, key string) {
+	delete(lease.Status.Metadata, key)
+	if len(lease.Status.Metadata) == 0 {
+		lease.Status.Metadata = nil
+	}
+}
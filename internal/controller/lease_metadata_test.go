@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+var _ = Describe("Lease metadata", func() {
+	It("should set and delete keys of active leases", func() {
+		lease := &jumpstarterdevv1alpha1.Lease{}
+		Expect(SetLeaseMetadata(lease, "dut.jumpstarter.dev/ip", "192.0.2.1")).To(Succeed())
+		Expect(lease.Status.Metadata).To(Equal(map[string]string{"dut.jumpstarter.dev/ip": "192.0.2.1"}))
+
+		DeleteLeaseMetadata(lease, "dut.jumpstarter.dev/ip")
+		Expect(lease.Status.Metadata).To(BeNil())
+	})
+
+	It("should reject invalid keys and ended leases", func() {
+		lease := &jumpstarterdevv1alpha1.Lease{}
+		Expect(SetLeaseMetadata(lease, "not a key", "value")).NotTo(Succeed())
+
+		lease.Status.Ended = true
+		Expect(SetLeaseMetadata(lease, "ip", "192.0.2.1")).NotTo(Succeed())
+	})
+
+	It("should enforce the size limits", func() {
+		lease := &jumpstarterdevv1alpha1.Lease{}
+		for i := 0; i < MaxLeaseMetadataKeys; i++ {
+			Expect(SetLeaseMetadata(lease, fmt.Sprintf("key-%d", i), "value")).To(Succeed())
+		}
+		Expect(SetLeaseMetadata(lease, "one-too-many", "value")).To(MatchError(ErrLeaseMetadataLimit))
+		Expect(lease.Status.Metadata).To(HaveLen(MaxLeaseMetadataKeys))
+
+		lease = &jumpstarterdevv1alpha1.Lease{}
+		Expect(SetLeaseMetadata(lease, "large", string(make([]byte, MaxLeaseMetadataBytes)))).
+			To(MatchError(ErrLeaseMetadataLimit))
+	})
+})
@@ -0,0 +1,181 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// LeasePausedDuration returns how long lease has been paused in total at now, the end of the
+// lease is delayed by as much
+func LeasePausedDuration(lease *jumpstarterdevv1alpha1.Lease, now time.Time) time.Duration {
+	var paused time.Duration
+	if lease.Status.PausedDuration != nil {
+		paused = lease.Status.PausedDuration.Duration
+	}
+	if lease.Status.PauseTime != nil {
+		paused += max(now.Sub(lease.Status.PauseTime.Time), 0)
+	}
+	return paused
+}
+
+// LeaseExpiration returns when the running lease expires as of now, after its duration and pauses
+func LeaseExpiration(lease *jumpstarterdevv1alpha1.Lease, now time.Time) time.Time {
+	return lease.Status.BeginTime.Add(lease.Spec.Duration.Duration + LeasePausedDuration(lease, now))
+}
+
+// MaximumLeasePause returns the maximum pause allowed to lease of client by the ExporterAccessPolicies
+// granting client access to exporters, the least of their MaximumPauseDuration, nil if unlimited
+func MaximumLeasePause(
+	policies []jumpstarterdevv1alpha1.ExporterAccessPolicy,
+	tieBreak PolicyTieBreak,
+	client *jumpstarterdevv1alpha1.Client,
+	exporters []jumpstarterdevv1alpha1.Exporter,
+) (*time.Duration, error) {
+	var maximum *time.Duration
+	for i := range exporters {
+		decision, err := EvaluateAccessPolicies(policies, tieBreak, client, &exporters[i])
+		if err != nil {
+			return nil, fmt.Errorf("MaximumLeasePause: %w", err)
+		}
+		if decision.Policy == nil || decision.Policy.MaximumPauseDuration == nil {
+			continue
+		}
+		if limit := decision.Policy.MaximumPauseDuration.Duration; maximum == nil || limit < *maximum {
+			maximum = &limit
+		}
+	}
+	return maximum, nil
+}
+
+// reconcileStatusPaused pauses and resumes the countdown of the running lease as requested by
+// its spec, until the maximum pause of its policies, also manages LeaseConditionTypePaused
+func (r *LeaseReconciler) reconcileStatusPaused(
+	ctx context.Context,
+	result *ctrl.Result,
+	lease *jumpstarterdevv1alpha1.Lease,
+	now time.Time,
+) error {
+	logger := log.FromContext(ctx)
+
+	if lease.Status.BeginTime == nil {
+		return nil
+	}
+
+	if !lease.Spec.Paused || lease.Spec.Release || lease.Status.Ended {
+		if lease.Status.PauseTime != nil {
+			logger.Info("reconcileStatusPaused: resuming lease")
+			r.resumeLease(lease, "Resumed", "the countdown of the lease resumed", now)
+		}
+		return nil
+	}
+
+	maximum, err := r.maximumLeasePause(ctx, lease)
+	if err != nil {
+		return fmt.Errorf("reconcileStatusPaused: %w", err)
+	}
+
+	paused := LeasePausedDuration(lease, now)
+	if maximum != nil && paused >= *maximum {
+		message := fmt.Sprintf("the lease was paused for the maximum of %s", *maximum)
+		if lease.Status.PauseTime != nil {
+			logger.Info("reconcileStatusPaused: maximum pause reached, resuming lease")
+			r.resumeLease(lease, "MaximumPauseExceeded", message, now)
+		} else {
+			meta.SetStatusCondition(&lease.Status.Conditions, metav1.Condition{
+				Type:               string(jumpstarterdevv1alpha1.LeaseConditionTypePaused),
+				Status:             metav1.ConditionFalse,
+				ObservedGeneration: lease.Generation,
+				LastTransitionTime: metav1.Time{Time: now},
+				Reason:             "MaximumPauseExceeded",
+				Message:            message,
+			})
+		}
+		return nil
+	}
+
+	if lease.Status.PauseTime == nil {
+		logger.Info("reconcileStatusPaused: pausing lease")
+		lease.Status.PauseTime = &metav1.Time{Time: now}
+		meta.SetStatusCondition(&lease.Status.Conditions, metav1.Condition{
+			Type:               string(jumpstarterdevv1alpha1.LeaseConditionTypePaused),
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: lease.Generation,
+			LastTransitionTime: metav1.Time{Time: now},
+			Reason:             "Paused",
+			Message:            "the countdown of the lease is paused",
+		})
+		// the notice is given again once the lease resumes
+		meta.RemoveStatusCondition(&lease.Status.Conditions, string(jumpstarterdevv1alpha1.LeaseConditionTypeEndingSoon))
+		if r.Recorder != nil {
+			r.Recorder.Event(lease, corev1.EventTypeNormal, "Paused", "Lease paused")
+		}
+	}
+	if maximum != nil {
+		requeueBefore(result, *maximum-paused)
+	}
+	return nil
+}
+
+// resumeLease resumes the countdown of the paused lease at now for reason
+func (r *LeaseReconciler) resumeLease(lease *jumpstarterdevv1alpha1.Lease, reason string, message string, now time.Time) {
+	lease.Status.PausedDuration = &metav1.Duration{Duration: LeasePausedDuration(lease, now)}
+	lease.Status.PauseTime = nil
+	meta.SetStatusCondition(&lease.Status.Conditions, metav1.Condition{
+		Type:               string(jumpstarterdevv1alpha1.LeaseConditionTypePaused),
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: lease.Generation,
+		LastTransitionTime: metav1.Time{Time: now},
+		Reason:             reason,
+		Message:            message,
+	})
+	if r.Recorder != nil {
+		r.Recorder.Eventf(lease, corev1.EventTypeNormal, "Resumed", "Lease resumed (%s)", reason)
+	}
+}
+
+// maximumLeasePause returns the maximum pause of the running lease, nil if unlimited
+func (r *LeaseReconciler) maximumLeasePause(
+	ctx context.Context,
+	lease *jumpstarterdevv1alpha1.Lease,
+) (*time.Duration, error) {
+	policies, err := ListAccessPolicies(ctx, r.Client, lease.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("maximumLeasePause: %w", err)
+	}
+	if len(policies) == 0 {
+		return nil, nil
+	}
+
+	var jclient jumpstarterdevv1alpha1.Client
+	if err := r.Get(ctx, client.ObjectKey{
+		Namespace: lease.Namespace,
+		Name:      lease.Spec.ClientRef.Name,
+	}, &jclient); err != nil {
+		return nil, fmt.Errorf("maximumLeasePause: failed to get client: %w", err)
+	}
+
+	var exporters []jumpstarterdevv1alpha1.Exporter
+	for _, name := range LeaseExporters(lease) {
+		var exporter jumpstarterdevv1alpha1.Exporter
+		if err := r.Get(ctx, client.ObjectKey{Namespace: lease.Namespace, Name: name}, &exporter); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("maximumLeasePause: failed to get exporter: %w", err)
+		}
+		exporters = append(exporters, exporter)
+	}
+
+	return MaximumLeasePause(policies, r.AccessPolicyTieBreak, LeaseClient(&jclient, lease), exporters)
+}
@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+var _ = Describe("Lease pause", func() {
+	BeforeEach(func() {
+		ctx := context.Background()
+		createExporters(ctx, testExporter1DutA)
+		setExporterOnlineConditions(ctx, testExporter1DutA.Name, metav1.ConditionTrue)
+	})
+	AfterEach(func() {
+		ctx := context.Background()
+		deleteExporters(ctx, testExporter1DutA)
+		deleteLeases(ctx, "lease1")
+	})
+
+	It("should delay the expiration of leases by their pauses", func() {
+		now := time.Now()
+		lease := leaseDutA2Sec.DeepCopy()
+		lease.Spec.Duration.Duration = time.Hour
+		lease.Status.BeginTime = &metav1.Time{Time: now.Add(-30 * time.Minute)}
+		Expect(LeaseExpiration(lease, now)).To(Equal(now.Add(30 * time.Minute)))
+
+		lease.Status.PausedDuration = &metav1.Duration{Duration: 10 * time.Minute}
+		lease.Status.PauseTime = &metav1.Time{Time: now.Add(-5 * time.Minute)}
+		Expect(LeasePausedDuration(lease, now)).To(Equal(15 * time.Minute))
+		Expect(LeaseExpiration(lease, now)).To(Equal(now.Add(45 * time.Minute)))
+	})
+
+	It("should limit the pauses to the least maximum of the access policies", func() {
+		maximum, err := MaximumLeasePause(
+			nil, PolicyTieBreakMostRestrictive, testClient, []jumpstarterdevv1alpha1.Exporter{*testExporter1DutA},
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(maximum).To(BeNil())
+
+		policy := fromClients(0, nil)
+		policy.MaximumPauseDuration = &metav1.Duration{Duration: 10 * time.Minute}
+		policies := []jumpstarterdevv1alpha1.ExporterAccessPolicy{
+			accessPolicy(map[string]string{"dut": "a"}, policy),
+		}
+		maximum, err = MaximumLeasePause(
+			policies, PolicyTieBreakMostRestrictive, testClient, []jumpstarterdevv1alpha1.Exporter{*testExporter1DutA},
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(maximum).NotTo(BeNil())
+		Expect(*maximum).To(Equal(10 * time.Minute))
+	})
+
+	It("should pause and resume the countdown of running leases", func() {
+		ctx := context.Background()
+		leaseReconciler := &LeaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+
+		lease := leaseDutA2Sec.DeepCopy()
+		Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+		_ = reconcileLease(ctx, lease)
+
+		updatedLease := getLease(ctx, lease.Name)
+		Expect(updatedLease.Status.BeginTime).NotTo(BeNil())
+		updatedLease.Spec.Paused = true
+		Expect(k8sClient.Update(ctx, updatedLease)).To(Succeed())
+		_, err := leaseReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(lease)})
+		Expect(err).NotTo(HaveOccurred())
+
+		updatedLease = getLease(ctx, lease.Name)
+		Expect(updatedLease.Status.PauseTime).NotTo(BeNil())
+		Expect(meta.IsStatusConditionTrue(updatedLease.Status.Conditions,
+			string(jumpstarterdevv1alpha1.LeaseConditionTypePaused))).To(BeTrue())
+
+		// the lease outlives its duration while paused
+		time.Sleep(3 * time.Second)
+		_, err = leaseReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(lease)})
+		Expect(err).NotTo(HaveOccurred())
+		updatedLease = getLease(ctx, lease.Name)
+		Expect(updatedLease.Status.Ended).To(BeFalse())
+		Expect(updatedLease.Status.Phase).To(Equal(jumpstarterdevv1alpha1.LeasePhaseActive))
+
+		updatedLease.Spec.Paused = false
+		Expect(k8sClient.Update(ctx, updatedLease)).To(Succeed())
+		_, err = leaseReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(lease)})
+		Expect(err).NotTo(HaveOccurred())
+
+		updatedLease = getLease(ctx, lease.Name)
+		Expect(updatedLease.Status.PauseTime).To(BeNil())
+		Expect(updatedLease.Status.PausedDuration).NotTo(BeNil())
+		Expect(updatedLease.Status.PausedDuration.Duration).To(BeNumerically(">=", 3*time.Second))
+		Expect(meta.IsStatusConditionFalse(updatedLease.Status.Conditions,
+			string(jumpstarterdevv1alpha1.LeaseConditionTypePaused))).To(BeTrue())
+	})
+})
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+var _ = Describe("Policy concurrent lease limits", func() {
+	var policy jumpstarterdevv1alpha1.ExporterAccessPolicy
+
+	BeforeEach(func() {
+		ctx := context.Background()
+		createExporters(ctx, testExporter1DutA, testExporter2DutA)
+		setExporterOnlineConditions(ctx, testExporter1DutA.Name, metav1.ConditionTrue)
+		setExporterOnlineConditions(ctx, testExporter2DutA.Name, metav1.ConditionTrue)
+	})
+	AfterEach(func() {
+		ctx := context.Background()
+		deleteExporters(ctx, testExporter1DutA, testExporter2DutA)
+		deleteLeases(ctx, "lease1", "lease2")
+		Expect(k8sClient.Delete(ctx, &policy)).To(Succeed())
+	})
+
+	It("should keep leases pending while the client holds its maximum number of leases", func() {
+		ctx := context.Background()
+		granted := fromClients(0, nil)
+		limit := int32(1)
+		granted.MaxConcurrentLeasesPerClient = &limit
+		policy = accessPolicy(map[string]string{"dut": "a"}, granted)
+		Expect(k8sClient.Create(ctx, &policy)).To(Succeed())
+
+		lease1 := leaseDutA2Sec.DeepCopy()
+		Expect(k8sClient.Create(ctx, lease1)).To(Succeed())
+		_ = reconcileLease(ctx, lease1)
+		Expect(getLease(ctx, lease1.Name).Status.ExporterRef).NotTo(BeNil())
+
+		lease2 := leaseDutA2Sec.DeepCopy()
+		lease2.Name = "lease2"
+		Expect(k8sClient.Create(ctx, lease2)).To(Succeed())
+		_ = reconcileLease(ctx, lease2)
+		updatedLease := getLease(ctx, lease2.Name)
+		Expect(updatedLease.Status.ExporterRef).To(BeNil())
+		Expect(updatedLease.Status.Ended).To(BeFalse())
+	})
+
+	It("should assign exporters while the policy grants fewer leases than its maximum", func() {
+		ctx := context.Background()
+		granted := fromClients(0, nil)
+		limit := int32(2)
+		granted.MaxConcurrentLeases = &limit
+		policy = accessPolicy(map[string]string{"dut": "a"}, granted)
+		Expect(k8sClient.Create(ctx, &policy)).To(Succeed())
+
+		lease1 := leaseDutA2Sec.DeepCopy()
+		Expect(k8sClient.Create(ctx, lease1)).To(Succeed())
+		_ = reconcileLease(ctx, lease1)
+		Expect(getLease(ctx, lease1.Name).Status.ExporterRef).NotTo(BeNil())
+
+		lease2 := leaseDutA2Sec.DeepCopy()
+		lease2.Name = "lease2"
+		Expect(k8sClient.Create(ctx, lease2)).To(Succeed())
+		_ = reconcileLease(ctx, lease2)
+		Expect(getLease(ctx, lease2.Name).Status.ExporterRef).NotTo(BeNil())
+	})
+})
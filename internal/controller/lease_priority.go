@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// LeasePriorityClassOf returns the LeasePriorityClass of lease among classes, the one it names,
+// or the default class if it names none, nil if it has none
+func LeasePriorityClassOf(
+	classes []jumpstarterdevv1alpha1.LeasePriorityClass,
+	lease *jumpstarterdevv1alpha1.Lease,
+) *jumpstarterdevv1alpha1.LeasePriorityClass {
+	for i := range classes {
+		class := &classes[i]
+		if lease.Spec.PriorityClassName == "" && class.Spec.GlobalDefault ||
+			lease.Spec.PriorityClassName != "" && class.Name == lease.Spec.PriorityClassName {
+			return class
+		}
+	}
+	return nil
+}
+
+// leasePriority is the priority of lease of client for exporter, the value of its LeasePriorityClass,
+// or without one the priority of the ExporterAccessPolicy granting the access, 0 if none does
+func leasePriority(
+	state *AllocationState,
+	lease *jumpstarterdevv1alpha1.Lease,
+	client *jumpstarterdevv1alpha1.Client,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+) int {
+	if class := LeasePriorityClassOf(state.PriorityClasses, lease); class != nil {
+		return int(class.Spec.Value)
+	}
+	decision, err := EvaluateAccessPolicies(state.AccessPolicies, state.AccessPolicyTieBreak, client, exporter)
+	if err != nil || decision.Policy == nil {
+		return 0
+	}
+	return decision.Policy.Priority
+}
+
+// preemptionVictim returns the running lease of lowest priority, the latest begun among equals,
+// holding one of exporters that the waiting lease of state could take, if its LeasePriorityClass
+// preempts lower priorities and it has a higher priority, nil otherwise or while a lease it
+// preempted has not ended yet
+func preemptionVictim(
+	state *AllocationState,
+	exporters []jumpstarterdevv1alpha1.Exporter,
+	now time.Time,
+) *jumpstarterdevv1alpha1.Lease {
+	lease := state.Lease
+	class := LeasePriorityClassOf(state.PriorityClasses, lease)
+	if class == nil || class.Spec.PreemptionPolicy != jumpstarterdevv1alpha1.LeasePreemptLowerPriority {
+		return nil
+	}
+
+	// one lease at a time, the exporter it frees goes to the head of the queue
+	for i := range state.ActiveLeases {
+		other := &state.ActiveLeases[i]
+		if !other.Status.Ended && other.Annotations[jumpstarterdevv1alpha1.LeaseAnnotationPreemptedBy] == lease.Name {
+			return nil
+		}
+	}
+
+	var victim *jumpstarterdevv1alpha1.Lease
+	var victimPriority int
+	for i := range exporters {
+		exporter := &exporters[i]
+		allowed, err := ClientCanLease(state.AccessPolicies, state.AccessPolicyTieBreak, state.Client, exporter, now)
+		if err != nil || !allowed {
+			continue
+		}
+		if allowed, err := ExporterAffinityAllows(lease, exporter); err != nil || !allowed {
+			continue
+		}
+		priority := leasePriority(state, lease, state.Client, exporter)
+		for j := range state.ActiveLeases {
+			holder := &state.ActiveLeases[j]
+			if holder.Status.Ended || holder.Spec.Release || holder.Status.BeginTime == nil ||
+				holder.Annotations[jumpstarterdevv1alpha1.LeaseAnnotationPreemptedBy] != "" ||
+				!LeaseHoldsExporter(holder, exporter.Name) {
+				continue
+			}
+			holderPriority := leasePriority(state, holder, state.LeaseClient(holder), exporter)
+			if holderPriority >= priority {
+				continue
+			}
+			if victim == nil || holderPriority < victimPriority ||
+				holderPriority == victimPriority && holder.Status.BeginTime.After(victim.Status.BeginTime.Time) {
+				victim = holder
+				victimPriority = holderPriority
+			}
+		}
+	}
+	return victim
+}
+
+// preemptLease releases the running lease the waiting lease of state preempts, if any,
+// the victim ends with the Preempted reason when it is reconciled, or at the end of the
+// PreemptionGracePeriod if set
+func (r *LeaseReconciler) preemptLease(
+	ctx context.Context,
+	state *AllocationState,
+	exporters []jumpstarterdevv1alpha1.Exporter,
+	now time.Time,
+) error {
+	victim := preemptionVictim(state, exporters, now)
+	if victim == nil {
+		return nil
+	}
+
+	log.FromContext(ctx).Info("preemptLease: preempting lease", "victim", victim.Name)
+	original := client.MergeFrom(victim.DeepCopy())
+	if victim.Annotations == nil {
+		victim.Annotations = map[string]string{}
+	}
+	victim.Annotations[jumpstarterdevv1alpha1.LeaseAnnotationPreemptedBy] = state.Lease.Name
+	if r.PreemptionGracePeriod > 0 {
+		victim.Annotations[jumpstarterdevv1alpha1.LeaseAnnotationPreemptAt] =
+			now.Add(r.PreemptionGracePeriod).UTC().Format(time.RFC3339)
+	} else {
+		victim.Spec.Release = true
+	}
+	if err := r.Patch(ctx, victim, original); err != nil {
+		return fmt.Errorf("preemptLease: failed to release lease %s: %w", victim.Name, err)
+	}
+
+	policyPreemptionsTotal.WithLabelValues(victim.Namespace, grantingPolicy(state, victim, exporters)).Inc()
+
+	if r.Recorder != nil {
+		r.Recorder.Eventf(victim, corev1.EventTypeWarning, "Preempted",
+			"Preempted by lease %s of higher priority", state.Lease.Name)
+	}
+	return nil
+}
+
+// leasePreemptAt returns when the preempted lease ends at the end of its grace period,
+// false if it was not preempted with a grace period
+func leasePreemptAt(lease *jumpstarterdevv1alpha1.Lease) (time.Time, bool) {
+	if _, preempted := lease.Annotations[jumpstarterdevv1alpha1.LeaseAnnotationPreemptedBy]; !preempted {
+		return time.Time{}, false
+	}
+	value, ok := lease.Annotations[jumpstarterdevv1alpha1.LeaseAnnotationPreemptAt]
+	if !ok {
+		return time.Time{}, false
+	}
+	preemptAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		// a mangled annotation must not keep the preempting lease waiting forever
+		return time.Time{}, true
+	}
+	return preemptAt, true
+}
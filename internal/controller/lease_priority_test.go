@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+func leasePriorityClass(
+	name string,
+	value int32,
+	policy jumpstarterdevv1alpha1.LeasePreemptionPolicy,
+) *jumpstarterdevv1alpha1.LeasePriorityClass {
+	return &jumpstarterdevv1alpha1.LeasePriorityClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: jumpstarterdevv1alpha1.LeasePriorityClassSpec{
+			Value:            value,
+			PreemptionPolicy: policy,
+		},
+	}
+}
+
+var _ = Describe("Lease priority classes", func() {
+	It("should resolve the class of a lease", func() {
+		defaultClass := leasePriorityClass("default", 1, jumpstarterdevv1alpha1.LeasePreemptNever)
+		defaultClass.Spec.GlobalDefault = true
+		classes := []jumpstarterdevv1alpha1.LeasePriorityClass{
+			*leasePriorityClass("urgent", 100, jumpstarterdevv1alpha1.LeasePreemptLowerPriority),
+			*defaultClass,
+		}
+
+		lease := leaseDutA2Sec.DeepCopy()
+		Expect(LeasePriorityClassOf(classes, lease).Name).To(Equal("default"))
+
+		lease.Spec.PriorityClassName = "urgent"
+		Expect(LeasePriorityClassOf(classes, lease).Name).To(Equal("urgent"))
+
+		lease.Spec.PriorityClassName = "unknown"
+		Expect(LeasePriorityClassOf(classes, lease)).To(BeNil())
+	})
+
+	When("leases wait for an exporter", func() {
+		BeforeEach(func() {
+			ctx := context.Background()
+			createExporters(ctx, testExporter1DutA)
+			setExporterOnlineConditions(ctx, testExporter1DutA.Name, metav1.ConditionTrue)
+			Expect(k8sClient.Create(ctx, leasePriorityClass(
+				"batch", 1, jumpstarterdevv1alpha1.LeasePreemptNever))).To(Succeed())
+			Expect(k8sClient.Create(ctx, leasePriorityClass(
+				"urgent", 100, jumpstarterdevv1alpha1.LeasePreemptLowerPriority))).To(Succeed())
+		})
+		AfterEach(func() {
+			ctx := context.Background()
+			Expect(k8sClient.Delete(ctx, leasePriorityClass("batch", 0, ""))).To(Succeed())
+			Expect(k8sClient.Delete(ctx, leasePriorityClass("urgent", 0, ""))).To(Succeed())
+			deleteExporters(ctx, testExporter1DutA)
+			deleteLeases(ctx, "lease1", "lease2", "lease3")
+		})
+
+		createLease := func(ctx context.Context, name string, class string) *jumpstarterdevv1alpha1.Lease {
+			lease := leaseDutA2Sec.DeepCopy()
+			lease.Name = name
+			lease.Spec.Duration.Duration = time.Hour
+			lease.Spec.PriorityClassName = class
+			Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+			return lease
+		}
+
+		It("should hand the exporter to the waiting lease of highest priority", func() {
+			ctx := context.Background()
+			lease1 := createLease(ctx, "lease1", "")
+			_ = reconcileLease(ctx, lease1)
+			Expect(getLease(ctx, lease1.Name).Status.ExporterRef).NotTo(BeNil())
+
+			lease2 := createLease(ctx, "lease2", "batch")
+			_ = reconcileLease(ctx, lease2)
+			lease3 := createLease(ctx, "lease3", "urgent")
+			_ = reconcileLease(ctx, lease3)
+
+			released := getLease(ctx, lease1.Name)
+			released.Spec.Release = true
+			Expect(k8sClient.Update(ctx, released)).To(Succeed())
+			_ = reconcileLease(ctx, released)
+
+			_ = reconcileLease(ctx, lease2)
+			Expect(getLease(ctx, lease2.Name).Status.ExporterRef).To(BeNil())
+			_ = reconcileLease(ctx, lease3)
+			Expect(getLease(ctx, lease3.Name).Status.ExporterRef).NotTo(BeNil())
+		})
+
+		It("should preempt running leases of lower priority", func() {
+			ctx := context.Background()
+			lease1 := createLease(ctx, "lease1", "batch")
+			_ = reconcileLease(ctx, lease1)
+			Expect(getLease(ctx, lease1.Name).Status.ExporterRef).NotTo(BeNil())
+
+			preemptingReconciler := &LeaseReconciler{
+				Client:     k8sClient,
+				Scheme:     k8sClient.Scheme(),
+				Preemption: true,
+			}
+			lease2 := createLease(ctx, "lease2", "urgent")
+			_, err := preemptingReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: lease2.Namespace, Name: lease2.Name},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			preempted := getLease(ctx, lease1.Name)
+			Expect(preempted.Annotations).To(HaveKeyWithValue(
+				jumpstarterdevv1alpha1.LeaseAnnotationPreemptedBy, lease2.Name))
+			_ = reconcileLease(ctx, preempted)
+			Expect(getLease(ctx, lease1.Name).Status.Phase).To(Equal(jumpstarterdevv1alpha1.LeasePhasePreempted))
+
+			_ = reconcileLease(ctx, lease2)
+			Expect(getLease(ctx, lease2.Name).Status.ExporterRef).NotTo(BeNil())
+		})
+
+		It("should give preempted leases notice before they end", func() {
+			ctx := context.Background()
+			lease1 := createLease(ctx, "lease1", "batch")
+			_ = reconcileLease(ctx, lease1)
+			Expect(getLease(ctx, lease1.Name).Status.ExporterRef).NotTo(BeNil())
+
+			preemptingReconciler := &LeaseReconciler{
+				Client:                k8sClient,
+				Scheme:                k8sClient.Scheme(),
+				Preemption:            true,
+				PreemptionGracePeriod: time.Minute,
+				EndingNotice:          2 * time.Minute,
+			}
+			lease2 := createLease(ctx, "lease2", "urgent")
+			_, err := preemptingReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: lease2.Namespace, Name: lease2.Name},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			preempted := getLease(ctx, lease1.Name)
+			Expect(preempted.Spec.Release).To(BeFalse())
+			Expect(preempted.Annotations).To(HaveKey(jumpstarterdevv1alpha1.LeaseAnnotationPreemptAt))
+			_, err = preemptingReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: preempted.Namespace, Name: preempted.Name},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			preempted = getLease(ctx, lease1.Name)
+			Expect(preempted.Status.Phase).To(Equal(jumpstarterdevv1alpha1.LeasePhaseActive))
+			endingSoon := meta.FindStatusCondition(
+				preempted.Status.Conditions,
+				string(jumpstarterdevv1alpha1.LeaseConditionTypeEndingSoon),
+			)
+			Expect(endingSoon).NotTo(BeNil())
+			Expect(endingSoon.Reason).To(Equal("Preempted"))
+			Expect(getLease(ctx, lease2.Name).Status.ExporterRef).To(BeNil())
+		})
+
+		It("should not preempt leases without the preemption feature", func() {
+			ctx := context.Background()
+			lease1 := createLease(ctx, "lease1", "batch")
+			_ = reconcileLease(ctx, lease1)
+			lease2 := createLease(ctx, "lease2", "urgent")
+			_ = reconcileLease(ctx, lease2)
+
+			Expect(getLease(ctx, lease1.Name).Spec.Release).To(BeFalse())
+			Expect(getLease(ctx, lease2.Name).Status.ExporterRef).To(BeNil())
+		})
+	})
+})
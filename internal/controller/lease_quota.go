@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// QuotaViolation is a LeaseQuota that acquiring an exporter for a lease would exceed
+type QuotaViolation struct {
+	Quota *jumpstarterdevv1alpha1.LeaseQuota
+	// The limit exceeded, ConcurrentLeases or LeasedDuration
+	Reason  string
+	Message string
+}
+
+// EvaluateLeaseQuotas returns the first of quotas applying to client that acquiring an exporter
+// for lease would exceed, accounting the other leases in leases, nil if none is exceeded,
+// clients are the clients in the namespace by name, to account the leases of a group
+func EvaluateLeaseQuotas(
+	quotas []jumpstarterdevv1alpha1.LeaseQuota,
+	lease *jumpstarterdevv1alpha1.Lease,
+	client *jumpstarterdevv1alpha1.Client,
+	clients map[string]*jumpstarterdevv1alpha1.Client,
+	leases []jumpstarterdevv1alpha1.Lease,
+	now time.Time,
+) (*QuotaViolation, error) {
+	// quotas select clients by their labels, leases of deleted clients are not accounted
+	if client == nil {
+		return nil, nil
+	}
+
+	for i := range quotas {
+		quota := &quotas[i]
+		matches, err := selectorMatches(&quota.Spec.ClientSelector, client.Labels)
+		if err != nil {
+			return nil, fmt.Errorf("EvaluateLeaseQuotas: invalid client selector in %s: %w", quota.Name, err)
+		}
+		if !matches {
+			continue
+		}
+
+		var concurrent int32
+		leased := lease.Spec.Duration.Duration
+		windowBegin := now.Add(-quota.Spec.Period.Duration)
+		for j := range leases {
+			other := &leases[j]
+			if other.Name == lease.Name || other.Namespace != lease.Namespace {
+				continue
+			}
+			accounted, err := leaseQuotaAccounts(quota, client, clients, other)
+			if err != nil {
+				return nil, fmt.Errorf("EvaluateLeaseQuotas: %w", err)
+			}
+			if !accounted || other.Status.BeginTime == nil {
+				continue
+			}
+
+			begin, end := LeaseWindow(other, now)
+			if other.Status.Ended {
+				if other.Status.EndTime == nil {
+					continue
+				}
+				end = other.Status.EndTime.Time
+			} else if other.Status.ExporterRef != nil {
+				concurrent++
+			}
+			if end.After(windowBegin) {
+				leased += end.Sub(maxTime(begin, windowBegin))
+			}
+		}
+
+		if limit := quota.Spec.MaxConcurrentLeases; limit != nil && concurrent >= *limit {
+			return &QuotaViolation{
+				Quota:   quota,
+				Reason:  "ConcurrentLeases",
+				Message: fmt.Sprintf("%d of %d concurrent leases allowed by %s in use", concurrent, *limit, quota.Name),
+			}, nil
+		}
+		if limit := quota.Spec.MaxLeasedDuration; limit != nil && leased > limit.Duration {
+			return &QuotaViolation{
+				Quota:  quota,
+				Reason: "LeasedDuration",
+				Message: fmt.Sprintf("%s leased within %s, including this lease, exceeds the %s allowed by %s",
+					leased, quota.Spec.Period.Duration, limit.Duration, quota.Name),
+			}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// leaseQuotaAccounts reports whether lease counts against quota when evaluated for client,
+// the leases of client itself, or of every client selected by quota if it applies to a group
+func leaseQuotaAccounts(
+	quota *jumpstarterdevv1alpha1.LeaseQuota,
+	client *jumpstarterdevv1alpha1.Client,
+	clients map[string]*jumpstarterdevv1alpha1.Client,
+	lease *jumpstarterdevv1alpha1.Lease,
+) (bool, error) {
+	if quota.Spec.Scope != jumpstarterdevv1alpha1.LeaseQuotaScopeGroup {
+		return lease.Spec.ClientRef.Name == client.Name, nil
+	}
+	holder, ok := clients[lease.Spec.ClientRef.Name]
+	if !ok {
+		return false, nil
+	}
+	return selectorMatches(&quota.Spec.ClientSelector, holder.Labels)
+}
@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+func leaseQuota(spec jumpstarterdevv1alpha1.LeaseQuotaSpec) *jumpstarterdevv1alpha1.LeaseQuota {
+	return &jumpstarterdevv1alpha1.LeaseQuota{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "quota",
+			Namespace: "default",
+		},
+		Spec: spec,
+	}
+}
+
+var _ = Describe("Lease quotas", func() {
+	now := time.Now()
+
+	endedLease := func(name, client string, begin, end time.Time) jumpstarterdevv1alpha1.Lease {
+		lease := leaseDutA2Sec.DeepCopy()
+		lease.Name = name
+		lease.Spec.ClientRef.Name = client
+		lease.Spec.Duration.Duration = end.Sub(begin)
+		lease.Status.BeginTime = &metav1.Time{Time: begin}
+		lease.Status.EndTime = &metav1.Time{Time: end}
+		lease.Status.Ended = true
+		return *lease
+	}
+
+	It("should limit the leased duration within the period", func() {
+		quotas := []jumpstarterdevv1alpha1.LeaseQuota{*leaseQuota(jumpstarterdevv1alpha1.LeaseQuotaSpec{
+			MaxLeasedDuration: &metav1.Duration{Duration: 2 * time.Hour},
+			Period:            metav1.Duration{Duration: 24 * time.Hour},
+		})}
+		lease := leaseDutA2Sec.DeepCopy()
+		lease.Spec.Duration.Duration = time.Hour
+
+		leases := []jumpstarterdevv1alpha1.Lease{
+			endedLease("old", testClient.Name, now.Add(-48*time.Hour), now.Add(-47*time.Hour)),
+			endedLease("recent", testClient.Name, now.Add(-3*time.Hour), now.Add(-2*time.Hour)),
+		}
+		violation, err := EvaluateLeaseQuotas(quotas, lease, testClient, nil, leases, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(violation).To(BeNil())
+
+		leases = append(leases, endedLease("another", testClient.Name, now.Add(-time.Hour), now))
+		violation, err = EvaluateLeaseQuotas(quotas, lease, testClient, nil, leases, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(violation).NotTo(BeNil())
+		Expect(violation.Reason).To(Equal("LeasedDuration"))
+	})
+
+	It("should account the leases of all the clients of a group quota", func() {
+		ciClient := &jumpstarterdevv1alpha1.Client{
+			ObjectMeta: metav1.ObjectMeta{Name: "ci", Labels: map[string]string{"team": "ci"}},
+		}
+		otherClient := &jumpstarterdevv1alpha1.Client{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Labels: map[string]string{"team": "ci"}},
+		}
+		clients := map[string]*jumpstarterdevv1alpha1.Client{ciClient.Name: ciClient, otherClient.Name: otherClient}
+		quota := leaseQuota(jumpstarterdevv1alpha1.LeaseQuotaSpec{
+			ClientSelector:    metav1.LabelSelector{MatchLabels: map[string]string{"team": "ci"}},
+			Scope:             jumpstarterdevv1alpha1.LeaseQuotaScopeClient,
+			MaxLeasedDuration: &metav1.Duration{Duration: time.Hour},
+			Period:            metav1.Duration{Duration: 24 * time.Hour},
+		})
+		lease := leaseDutA2Sec.DeepCopy()
+		lease.Spec.ClientRef.Name = ciClient.Name
+		leases := []jumpstarterdevv1alpha1.Lease{
+			endedLease("other", otherClient.Name, now.Add(-time.Hour), now),
+		}
+
+		violation, err := EvaluateLeaseQuotas(
+			[]jumpstarterdevv1alpha1.LeaseQuota{*quota}, lease, ciClient, clients, leases, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(violation).To(BeNil())
+
+		quota.Spec.Scope = jumpstarterdevv1alpha1.LeaseQuotaScopeGroup
+		violation, err = EvaluateLeaseQuotas(
+			[]jumpstarterdevv1alpha1.LeaseQuota{*quota}, lease, ciClient, clients, leases, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(violation).NotTo(BeNil())
+	})
+
+	When("reconciling leases exceeding a quota", func() {
+		BeforeEach(func() {
+			ctx := context.Background()
+			createExporters(ctx, testExporter1DutA, testExporter2DutA)
+			setExporterOnlineConditions(ctx, testExporter1DutA.Name, metav1.ConditionTrue)
+			setExporterOnlineConditions(ctx, testExporter2DutA.Name, metav1.ConditionTrue)
+			maxConcurrentLeases := int32(1)
+			Expect(k8sClient.Create(ctx, leaseQuota(jumpstarterdevv1alpha1.LeaseQuotaSpec{
+				MaxConcurrentLeases: &maxConcurrentLeases,
+			}))).To(Succeed())
+		})
+		AfterEach(func() {
+			ctx := context.Background()
+			Expect(k8sClient.Delete(ctx, leaseQuota(jumpstarterdevv1alpha1.LeaseQuotaSpec{}))).To(Succeed())
+			deleteExporters(ctx, testExporter1DutA, testExporter2DutA)
+			deleteLeases(ctx, "lease1", "lease2")
+		})
+
+		It("should hold back leases over the concurrent lease limit", func() {
+			ctx := context.Background()
+			lease := leaseDutA2Sec.DeepCopy()
+			lease.Spec.Duration.Duration = time.Hour
+			Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+			_ = reconcileLease(ctx, lease)
+			Expect(getLease(ctx, lease.Name).Status.ExporterRef).NotTo(BeNil())
+
+			lease2 := lease.DeepCopy()
+			lease2.ObjectMeta = metav1.ObjectMeta{Name: "lease2", Namespace: lease.Namespace}
+			Expect(k8sClient.Create(ctx, lease2)).To(Succeed())
+			_ = reconcileLease(ctx, lease2)
+
+			updatedLease := getLease(ctx, lease2.Name)
+			Expect(updatedLease.Status.ExporterRef).To(BeNil())
+			condition := meta.FindStatusCondition(updatedLease.Status.Conditions,
+				string(jumpstarterdevv1alpha1.LeaseConditionTypeQuotaExceeded))
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal("ConcurrentLeases"))
+
+			released := getLease(ctx, lease.Name)
+			released.Spec.Release = true
+			Expect(k8sClient.Update(ctx, released)).To(Succeed())
+			_ = reconcileLease(ctx, released)
+			_ = reconcileLease(ctx, lease2)
+
+			updatedLease = getLease(ctx, lease2.Name)
+			Expect(updatedLease.Status.ExporterRef).NotTo(BeNil())
+			Expect(meta.IsStatusConditionFalse(updatedLease.Status.Conditions,
+				string(jumpstarterdevv1alpha1.LeaseConditionTypeQuotaExceeded))).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"context"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// leaseRoleCount is the number of exporters requested for role, at least one
+func leaseRoleCount(role *jumpstarterdevv1alpha1.LeaseRole) int {
+	return max(int(role.Count), 1)
+}
+
+// AssignRoles selects the exporters for the roles of the lease of state, to be acquired together
+// with exporter, in the order of the roles and of the exporters matching them. The exporters of
+// the roles must pass the filters of the linked exporters, their own linked exporters are not
+// acquired with them. The code is unavailable if waiting could free enough exporters for every
+// role, and unresolvable otherwise
+func (s *AllocationState) AssignRoles(
+	ctx context.Context,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+) ([]jumpstarterdevv1alpha1.LeaseRoleExporterRef, FilterCode) {
+	taken := map[string]bool{exporter.Name: true}
+	for _, linked := range s.Linked(exporter) {
+		taken[linked.Name] = true
+	}
+
+	var refs []jumpstarterdevv1alpha1.LeaseRoleExporterRef
+	code := FilterCodeSuccess
+	for i := range s.Lease.Spec.Roles {
+		role := &s.Lease.Spec.Roles[i]
+		need, waiting := leaseRoleCount(role), 0
+		for j := range s.RoleExporters[role.Name] {
+			candidate := &s.RoleExporters[role.Name][j]
+			if need == 0 {
+				break
+			}
+			if taken[candidate.Name] {
+				continue
+			}
+			candidateCode := FilterCodeSuccess
+			for _, filter := range linkedExporterFilters() {
+				if candidateCode = filter.Filter(ctx, s, candidate); candidateCode != FilterCodeSuccess {
+					break
+				}
+			}
+			switch candidateCode {
+			case FilterCodeSuccess:
+				taken[candidate.Name] = true
+				refs = append(refs, jumpstarterdevv1alpha1.LeaseRoleExporterRef{Role: role.Name, Name: candidate.Name})
+				need--
+			case FilterCodeUnavailable:
+				waiting++
+			}
+		}
+		if need > waiting {
+			return nil, FilterCodeUnresolvable
+		}
+		if need > 0 {
+			code = FilterCodeUnavailable
+		}
+	}
+	if code != FilterCodeSuccess {
+		return nil, code
+	}
+	return refs, FilterCodeSuccess
+}
+
+// LeaseRolesFilter filters out exporters that could not be leased with exporters for every role
+// of the lease, so that the exporters of a lease are either acquired as a whole or not at all
+type LeaseRolesFilter struct{}
+
+func (LeaseRolesFilter) Name() string {
+	return "Roles"
+}
+
+func (LeaseRolesFilter) Filter(
+	ctx context.Context,
+	state *AllocationState,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+) FilterCode {
+	if len(state.Lease.Spec.Roles) == 0 {
+		return FilterCodeSuccess
+	}
+	_, code := state.AssignRoles(ctx, exporter)
+	return code
+}
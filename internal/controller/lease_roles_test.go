@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+func leaseWithRoles(name string, roles ...jumpstarterdevv1alpha1.LeaseRole) *jumpstarterdevv1alpha1.Lease {
+	lease := leaseDutA2Sec.DeepCopy()
+	lease.Name = name
+	lease.Spec.Roles = roles
+	return lease
+}
+
+func leaseRole(name string, dut string, count int32) jumpstarterdevv1alpha1.LeaseRole {
+	return jumpstarterdevv1alpha1.LeaseRole{
+		Name:     name,
+		Selector: metav1.LabelSelector{MatchLabels: map[string]string{"dut": dut}},
+		Count:    count,
+	}
+}
+
+var _ = Describe("Lease roles", func() {
+	BeforeEach(func() {
+		ctx := context.Background()
+		createExporters(ctx, testExporter1DutA, testExporter2DutA, testExporter3DutB)
+		setExporterOnlineConditions(ctx, testExporter1DutA.Name, metav1.ConditionTrue)
+		setExporterOnlineConditions(ctx, testExporter2DutA.Name, metav1.ConditionTrue)
+		setExporterOnlineConditions(ctx, testExporter3DutB.Name, metav1.ConditionTrue)
+	})
+	AfterEach(func() {
+		ctx := context.Background()
+		deleteExporters(ctx, testExporter1DutA, testExporter2DutA, testExporter3DutB)
+		deleteLeases(ctx, "lease1", "lease2")
+	})
+
+	It("should acquire an exporter for every role together with the exporter", func() {
+		ctx := context.Background()
+		lease := leaseWithRoles("lease1", leaseRole("peer", "a", 1), leaseRole("generator", "b", 1))
+		Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+		_ = reconcileLease(ctx, lease)
+
+		updatedLease := getLease(ctx, lease.Name)
+		Expect(updatedLease.Status.ExporterRef).NotTo(BeNil())
+		Expect(updatedLease.Status.ExporterRef.Name).To(Equal(testExporter1DutA.Name))
+		Expect(updatedLease.Status.RoleExporterRefs).To(Equal([]jumpstarterdevv1alpha1.LeaseRoleExporterRef{
+			{Role: "peer", Name: testExporter2DutA.Name},
+			{Role: "generator", Name: testExporter3DutB.Name},
+		}))
+		Expect(LeaseHoldsExporter(updatedLease, testExporter3DutB.Name)).To(BeTrue())
+
+		updatedExporter := getExporter(ctx, testExporter3DutB.Name)
+		Expect(updatedExporter.Status.LeaseRef).NotTo(BeNil())
+		Expect(updatedExporter.Status.LeaseRef.Name).To(Equal(lease.Name))
+	})
+
+	It("should wait while the exporters of a role are held by another lease", func() {
+		ctx := context.Background()
+		holder := leaseWithRoles("lease1", leaseRole("generator", "b", 1))
+		Expect(k8sClient.Create(ctx, holder)).To(Succeed())
+		_ = reconcileLease(ctx, holder)
+
+		lease := leaseWithRoles("lease2", leaseRole("generator", "b", 1))
+		Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+		_ = reconcileLease(ctx, lease)
+
+		updatedLease := getLease(ctx, lease.Name)
+		Expect(updatedLease.Status.ExporterRef).To(BeNil())
+		Expect(updatedLease.Status.RoleExporterRefs).To(BeEmpty())
+		Expect(meta.IsStatusConditionTrue(updatedLease.Status.Conditions,
+			string(jumpstarterdevv1alpha1.LeaseConditionTypePending))).To(BeTrue())
+	})
+
+	It("should fail a lease whose roles request more exporters than match", func() {
+		ctx := context.Background()
+		lease := leaseWithRoles("lease1", leaseRole("generator", "b", 2))
+		Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+		_ = reconcileLease(ctx, lease)
+
+		updatedLease := getLease(ctx, lease.Name)
+		Expect(updatedLease.Status.ExporterRef).To(BeNil())
+		condition := meta.FindStatusCondition(updatedLease.Status.Conditions,
+			string(jumpstarterdevv1alpha1.LeaseConditionTypeUnsatisfiable))
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Reason).To(Equal("Roles"))
+	})
+})
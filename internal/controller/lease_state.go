@@ -0,0 +1,198 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// leaseTransitions are the phases each phase of a lease can move to, besides staying the same,
+// the terminal phases have none
+var leaseTransitions = map[jumpstarterdevv1alpha1.LeasePhase][]jumpstarterdevv1alpha1.LeasePhase{
+	jumpstarterdevv1alpha1.LeasePhasePending: {
+		jumpstarterdevv1alpha1.LeasePhaseScheduled,
+		jumpstarterdevv1alpha1.LeasePhaseActive,
+		jumpstarterdevv1alpha1.LeasePhaseEnding,
+		jumpstarterdevv1alpha1.LeasePhaseEnded,
+		jumpstarterdevv1alpha1.LeasePhaseFailed,
+	},
+	jumpstarterdevv1alpha1.LeasePhaseScheduled: {
+		// the reserved exporter is not available when the reservation begins
+		jumpstarterdevv1alpha1.LeasePhasePending,
+		jumpstarterdevv1alpha1.LeasePhaseActive,
+		jumpstarterdevv1alpha1.LeasePhaseEnding,
+		jumpstarterdevv1alpha1.LeasePhaseEnded,
+		jumpstarterdevv1alpha1.LeasePhaseFailed,
+	},
+	jumpstarterdevv1alpha1.LeasePhaseActive: {
+		jumpstarterdevv1alpha1.LeasePhaseEnding,
+		jumpstarterdevv1alpha1.LeasePhaseEnded,
+		jumpstarterdevv1alpha1.LeasePhaseFailed,
+		jumpstarterdevv1alpha1.LeasePhasePreempted,
+	},
+	jumpstarterdevv1alpha1.LeasePhaseEnding: {
+		jumpstarterdevv1alpha1.LeasePhaseEnded,
+		jumpstarterdevv1alpha1.LeasePhaseFailed,
+		jumpstarterdevv1alpha1.LeasePhasePreempted,
+	},
+}
+
+var leaseTransitionsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "jumpstarter_lease_transitions_total",
+		Help: "Number of lease phase transitions written by the lease controller, by source and target phase",
+	},
+	[]string{"from", "to"},
+)
+
+var leaseInvalidTransitionsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "jumpstarter_lease_invalid_transitions_total",
+		Help: "Number of lease phase transitions outside of the expected ones, written anyway, by source and target phase",
+	},
+	[]string{"from", "to"},
+)
+
+var leasesDesc = prometheus.NewDesc(
+	"jumpstarter_leases",
+	"Number of leases, by namespace and phase",
+	[]string{"namespace", "phase"},
+	nil,
+)
+
+func init() {
+	metrics.Registry.MustRegister(leaseTransitionsTotal, leaseInvalidTransitionsTotal)
+}
+
+// leasePhaseCollector exports the number of leases in each phase from the cache of the manager
+type leasePhaseCollector struct {
+	reader client.Reader
+}
+
+func (c *leasePhaseCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- leasesDesc
+}
+
+func (c *leasePhaseCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var leases jumpstarterdevv1alpha1.LeaseList
+	if err := c.reader.List(ctx, &leases); err != nil {
+		ch <- prometheus.NewInvalidMetric(leasesDesc, err)
+		return
+	}
+
+	type key struct {
+		namespace string
+		phase     jumpstarterdevv1alpha1.LeasePhase
+	}
+	counts := map[key]int{}
+	for i := range leases.Items {
+		counts[key{leases.Items[i].Namespace, leases.Items[i].Status.Phase}]++
+	}
+	for k, count := range counts {
+		ch <- prometheus.MustNewConstMetric(leasesDesc, prometheus.GaugeValue, float64(count),
+			k.namespace, string(k.phase))
+	}
+}
+
+// LeasePhaseOf derives the phase of lease from its spec and status
+func LeasePhaseOf(lease *jumpstarterdevv1alpha1.Lease, now time.Time) jumpstarterdevv1alpha1.LeasePhase {
+	switch {
+	case lease.Status.Ended && meta.IsStatusConditionTrue(
+		lease.Status.Conditions,
+		string(jumpstarterdevv1alpha1.LeaseConditionTypeFailed),
+	):
+		return jumpstarterdevv1alpha1.LeasePhaseFailed
+	case lease.Status.Ended && leaseEndReason(lease) == "Preempted":
+		return jumpstarterdevv1alpha1.LeasePhasePreempted
+	case lease.Status.Ended:
+		return jumpstarterdevv1alpha1.LeasePhaseEnded
+	case lease.Spec.Release:
+		return jumpstarterdevv1alpha1.LeasePhaseEnding
+	case lease.Status.BeginTime != nil:
+		if now.After(LeaseExpiration(lease, now)) {
+			return jumpstarterdevv1alpha1.LeasePhaseEnding
+		}
+		return jumpstarterdevv1alpha1.LeasePhaseActive
+	case LeaseScheduled(lease, now):
+		return jumpstarterdevv1alpha1.LeasePhaseScheduled
+	default:
+		return jumpstarterdevv1alpha1.LeasePhasePending
+	}
+}
+
+// validateLeaseTransition returns an error if a lease cannot move from phase from to phase to
+func validateLeaseTransition(from, to jumpstarterdevv1alpha1.LeasePhase) error {
+	// leases written before phases were introduced have none
+	if from == "" || from == to {
+		return nil
+	}
+	for _, allowed := range leaseTransitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("validateLeaseTransition: invalid lease phase transition from %s to %s", from, to)
+}
+
+// recordInvalidLeaseTransition reports the unexpected transition of lease from phase from to phase
+// to, the phase derived from the spec and status of the lease is still written, as refusing to
+// write the status would leave it stuck with stale fields
+func (r *LeaseReconciler) recordInvalidLeaseTransition(
+	ctx context.Context,
+	lease *jumpstarterdevv1alpha1.Lease,
+	from, to jumpstarterdevv1alpha1.LeasePhase,
+	err error,
+) {
+	log.FromContext(ctx).Error(err, "recordInvalidLeaseTransition: writing unexpected lease phase transition")
+	leaseInvalidTransitionsTotal.WithLabelValues(string(from), string(to)).Inc()
+	if r.Recorder != nil {
+		r.Recorder.Eventf(lease, corev1.EventTypeWarning, "InvalidTransition",
+			"Unexpected phase transition from %s to %s", from, to)
+	}
+}
+
+// recordLeaseTransition counts the transition of a lease from phase from to phase to, once written
+func recordLeaseTransition(from, to jumpstarterdevv1alpha1.LeasePhase) {
+	if from != to {
+		leaseTransitionsTotal.WithLabelValues(string(from), string(to)).Inc()
+	}
+}
+
+// leaseEndReason is the reason of the Ready condition of lease, empty if it has none
+func leaseEndReason(lease *jumpstarterdevv1alpha1.Lease) string {
+	ready := meta.FindStatusCondition(lease.Status.Conditions, string(jumpstarterdevv1alpha1.LeaseConditionTypeReady))
+	if ready == nil {
+		return ""
+	}
+	return ready.Reason
+}
+
+// endLease moves lease to its terminal phase for reason, the reason of its Ready condition
+func endLease(lease *jumpstarterdevv1alpha1.Lease, reason string, now time.Time) {
+	meta.SetStatusCondition(&lease.Status.Conditions, metav1.Condition{
+		Type:               string(jumpstarterdevv1alpha1.LeaseConditionTypeReady),
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: lease.Generation,
+		LastTransitionTime: metav1.Time{
+			Time: now,
+		},
+		Reason: reason,
+	})
+	lease.Status.Ended = true
+	lease.Status.EndTime = &metav1.Time{
+		Time: now,
+	}
+}
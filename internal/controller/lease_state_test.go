@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+var _ = Describe("Lease phases", func() {
+	now := time.Now()
+
+	It("should derive the phase from the lease", func() {
+		lease := leaseDutA2Sec.DeepCopy()
+		Expect(LeasePhaseOf(lease, now)).To(Equal(jumpstarterdevv1alpha1.LeasePhasePending))
+
+		lease.Spec.BeginTime = &metav1.Time{Time: now.Add(time.Hour)}
+		Expect(LeasePhaseOf(lease, now)).To(Equal(jumpstarterdevv1alpha1.LeasePhaseScheduled))
+
+		lease.Status.ExporterRef = &corev1.LocalObjectReference{Name: testExporter1DutA.Name}
+		lease.Status.BeginTime = &metav1.Time{Time: now}
+		Expect(LeasePhaseOf(lease, now)).To(Equal(jumpstarterdevv1alpha1.LeasePhaseActive))
+		Expect(LeasePhaseOf(lease, now.Add(time.Minute))).To(Equal(jumpstarterdevv1alpha1.LeasePhaseEnding))
+
+		endLease(lease, "Expired", now)
+		Expect(LeasePhaseOf(lease, now)).To(Equal(jumpstarterdevv1alpha1.LeasePhaseEnded))
+
+		meta.SetStatusCondition(&lease.Status.Conditions, metav1.Condition{
+			Type:   string(jumpstarterdevv1alpha1.LeaseConditionTypeFailed),
+			Status: metav1.ConditionTrue,
+			Reason: "Timeout",
+		})
+		Expect(LeasePhaseOf(lease, now)).To(Equal(jumpstarterdevv1alpha1.LeasePhaseFailed))
+	})
+
+	It("should only allow the transitions of the lifecycle", func() {
+		Expect(validateLeaseTransition("", jumpstarterdevv1alpha1.LeasePhaseActive)).To(Succeed())
+		Expect(validateLeaseTransition(
+			jumpstarterdevv1alpha1.LeasePhasePending, jumpstarterdevv1alpha1.LeasePhaseActive,
+		)).To(Succeed())
+		Expect(validateLeaseTransition(
+			jumpstarterdevv1alpha1.LeasePhaseActive, jumpstarterdevv1alpha1.LeasePhasePending,
+		)).NotTo(Succeed())
+		Expect(validateLeaseTransition(
+			jumpstarterdevv1alpha1.LeasePhaseEnded, jumpstarterdevv1alpha1.LeasePhaseActive,
+		)).NotTo(Succeed())
+		Expect(validateLeaseTransition(
+			jumpstarterdevv1alpha1.LeasePhaseEnded, jumpstarterdevv1alpha1.LeasePhaseEnded,
+		)).To(Succeed())
+	})
+
+	When("reconciling a lease", func() {
+		BeforeEach(func() {
+			createExporters(context.Background(), testExporter1DutA)
+			setExporterOnlineConditions(context.Background(), testExporter1DutA.Name, metav1.ConditionTrue)
+		})
+		AfterEach(func() {
+			ctx := context.Background()
+			deleteExporters(ctx, testExporter1DutA)
+			deleteLeases(ctx, "lease1")
+		})
+
+		It("should write the phase of the lease", func() {
+			ctx := context.Background()
+			lease := leaseDutA2Sec.DeepCopy()
+			Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+			_ = reconcileLease(ctx, lease)
+			Expect(getLease(ctx, lease.Name).Status.Phase).To(Equal(jumpstarterdevv1alpha1.LeasePhaseActive))
+
+			updatedLease := getLease(ctx, lease.Name)
+			updatedLease.Spec.Release = true
+			Expect(k8sClient.Update(ctx, updatedLease)).To(Succeed())
+			_ = reconcileLease(ctx, lease)
+			Expect(getLease(ctx, lease.Name).Status.Phase).To(Equal(jumpstarterdevv1alpha1.LeasePhaseEnded))
+		})
+	})
+})
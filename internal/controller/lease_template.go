@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// ErrLeaseTemplateNotFound is returned by DefaultLeaseFromTemplate when the template of the lease does not exist
+var ErrLeaseTemplateNotFound = errors.New("lease template not found")
+
+// ApplyLeaseTemplate sets the unset fields of lease to the defaults of template
+func ApplyLeaseTemplate(lease *jumpstarterdevv1alpha1.Lease, template *jumpstarterdevv1alpha1.LeaseTemplate) {
+	spec := &template.Spec
+	if spec.Selector != nil && len(lease.Spec.Selector.MatchLabels) == 0 && len(lease.Spec.Selector.MatchExpressions) == 0 {
+		lease.Spec.Selector = *spec.Selector.DeepCopy()
+	}
+	if spec.Duration != nil && lease.Spec.Duration.Duration == 0 {
+		lease.Spec.Duration = *spec.Duration
+	}
+	if spec.AcquireTimeout != nil && lease.Spec.AcquireTimeout == nil {
+		lease.Spec.AcquireTimeout = spec.AcquireTimeout.DeepCopy()
+	}
+	if lease.Spec.PriorityClassName == "" {
+		lease.Spec.PriorityClassName = spec.PriorityClassName
+	}
+	if spec.Record {
+		lease.Spec.Record = true
+	}
+	if spec.WaitForExporter {
+		lease.Spec.WaitForExporter = true
+	}
+	if spec.Shared {
+		lease.Spec.Shared = true
+	}
+	if lease.Spec.ConcurrencyGroup == "" {
+		lease.Spec.ConcurrencyGroup = spec.ConcurrencyGroup
+	}
+	if len(lease.Spec.Observers) == 0 && len(spec.Observers) > 0 {
+		lease.Spec.Observers = append(lease.Spec.Observers, spec.Observers...)
+	}
+	if spec.Affinity != nil && lease.Spec.Affinity == nil {
+		lease.Spec.Affinity = spec.Affinity.DeepCopy()
+	}
+}
+
+// DefaultLeaseFromTemplate applies the LeaseTemplate named by lease, if any, to it
+func DefaultLeaseFromTemplate(ctx context.Context, c client.Reader, lease *jumpstarterdevv1alpha1.Lease) error {
+	if lease.Spec.TemplateName == "" {
+		return nil
+	}
+	var template jumpstarterdevv1alpha1.LeaseTemplate
+	if err := c.Get(ctx, types.NamespacedName{
+		Namespace: lease.Namespace,
+		Name:      lease.Spec.TemplateName,
+	}, &template); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("DefaultLeaseFromTemplate: %w: %s", ErrLeaseTemplateNotFound, lease.Spec.TemplateName)
+		}
+		return fmt.Errorf("DefaultLeaseFromTemplate: failed to get lease template: %w", err)
+	}
+	ApplyLeaseTemplate(lease, &template)
+	return nil
+}
@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+var _ = Describe("Lease templates", func() {
+	template := &jumpstarterdevv1alpha1.LeaseTemplate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "nightly",
+			Namespace: "default",
+		},
+		Spec: jumpstarterdevv1alpha1.LeaseTemplateSpec{
+			Selector:          &metav1.LabelSelector{MatchLabels: map[string]string{"dut": "b"}},
+			Duration:          &metav1.Duration{Duration: time.Hour},
+			AcquireTimeout:    &metav1.Duration{Duration: time.Minute},
+			PriorityClassName: "nightly",
+			Record:            true,
+			Shared:            true,
+			Observers:         []corev1.LocalObjectReference{{Name: "observer"}},
+		},
+	}
+
+	It("should only default the unset fields of the lease", func() {
+		lease := leaseDutA2Sec.DeepCopy()
+		ApplyLeaseTemplate(lease, template)
+
+		Expect(lease.Spec.Selector.MatchLabels).To(Equal(map[string]string{"dut": "a"}))
+		Expect(lease.Spec.Duration.Duration).To(Equal(2 * time.Second))
+		Expect(lease.Spec.AcquireTimeout.Duration).To(Equal(time.Minute))
+		Expect(lease.Spec.PriorityClassName).To(Equal("nightly"))
+		Expect(lease.Spec.Record).To(BeTrue())
+		Expect(lease.Spec.Shared).To(BeTrue())
+		Expect(lease.Spec.Observers).To(Equal([]corev1.LocalObjectReference{{Name: "observer"}}))
+
+		empty := &jumpstarterdevv1alpha1.Lease{}
+		ApplyLeaseTemplate(empty, template)
+		Expect(empty.Spec.Selector.MatchLabels).To(Equal(map[string]string{"dut": "b"}))
+		Expect(empty.Spec.Duration.Duration).To(Equal(time.Hour))
+	})
+
+	When("the template exists", func() {
+		BeforeEach(func() {
+			Expect(k8sClient.Create(context.Background(), template.DeepCopy())).To(Succeed())
+		})
+		AfterEach(func() {
+			Expect(k8sClient.Delete(context.Background(), template.DeepCopy())).To(Succeed())
+		})
+
+		It("should default the leases naming it", func() {
+			ctx := context.Background()
+			lease := &jumpstarterdevv1alpha1.Lease{
+				ObjectMeta: metav1.ObjectMeta{Name: "lease1", Namespace: "default"},
+				Spec:       jumpstarterdevv1alpha1.LeaseSpec{TemplateName: template.Name},
+			}
+			Expect(DefaultLeaseFromTemplate(ctx, k8sClient, lease)).To(Succeed())
+			Expect(lease.Spec.Duration.Duration).To(Equal(time.Hour))
+
+			lease.Spec.TemplateName = "unknown"
+			Expect(DefaultLeaseFromTemplate(ctx, k8sClient, lease)).To(MatchError(ErrLeaseTemplateNotFound))
+		})
+	})
+})
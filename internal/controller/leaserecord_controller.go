@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// +kubebuilder:rbac:groups=jumpstarter.dev,resources=leaserecords,verbs=get;list;watch;create;delete
+
+// LeaseRecordReconciler deletes the LeaseRecords older than the retention period
+type LeaseRecordReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// Retention is how long the LeaseRecords are kept after the end of their lease
+	Retention time.Duration
+}
+
+// LeaseRecordName returns the name of the LeaseRecord of lease
+func LeaseRecordName(lease *jumpstarterdevv1alpha1.Lease) string {
+	uid := string(lease.UID)
+	return lease.Name + "-" + uid[:min(len(uid), 8)]
+}
+
+// NewLeaseRecord returns the LeaseRecord of the ended lease
+func NewLeaseRecord(lease *jumpstarterdevv1alpha1.Lease) *jumpstarterdevv1alpha1.LeaseRecord {
+	requested := leaseRequestedBegin(lease)
+	end := lease.CreationTimestamp
+	if lease.Status.EndTime != nil {
+		end = *lease.Status.EndTime
+	}
+
+	record := &jumpstarterdevv1alpha1.LeaseRecord{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: lease.Namespace,
+			Name:      LeaseRecordName(lease),
+		},
+		Spec: jumpstarterdevv1alpha1.LeaseRecordSpec{
+			LeaseName:         lease.Name,
+			LeaseUID:          lease.UID,
+			ClientRef:         lease.Spec.ClientRef,
+			Selector:          *lease.Spec.Selector.DeepCopy(),
+			PriorityClassName: lease.Spec.PriorityClassName,
+			RequestedDuration: lease.Spec.Duration,
+			RequestTime:       metav1.NewTime(requested),
+			EndTime:           end,
+			WaitTime:          metav1.Duration{Duration: max(end.Sub(requested), 0)},
+			Phase:             lease.Status.Phase,
+		},
+	}
+	if lease.Status.ExporterRef != nil {
+		record.Spec.ExporterRef = lease.Status.ExporterRef.DeepCopy()
+	}
+	for _, name := range LeaseExporters(lease) {
+		record.Spec.ExporterRefs = append(record.Spec.ExporterRefs, corev1.LocalObjectReference{Name: name})
+	}
+	if lease.Status.BeginTime != nil {
+		record.Spec.BeginTime = lease.Status.BeginTime.DeepCopy()
+		record.Spec.WaitTime.Duration = max(lease.Status.BeginTime.Sub(requested), 0)
+		record.Spec.Duration.Duration = max(end.Sub(lease.Status.BeginTime.Time), 0)
+	}
+	if condition := meta.FindStatusCondition(lease.Status.Conditions,
+		string(jumpstarterdevv1alpha1.LeaseConditionTypeReady)); condition != nil {
+		record.Spec.EndReason = condition.Reason
+	}
+	return record
+}
+
+// recordLease writes the LeaseRecord of the ended lease, unless it already exists
+func recordLease(ctx context.Context, c client.Client, lease *jumpstarterdevv1alpha1.Lease) error {
+	var existing jumpstarterdevv1alpha1.LeaseRecord
+	err := c.Get(ctx, client.ObjectKey{Namespace: lease.Namespace, Name: LeaseRecordName(lease)}, &existing)
+	if err == nil {
+		return nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return fmt.Errorf("recordLease: failed to get lease record: %w", err)
+	}
+	if err := c.Create(ctx, NewLeaseRecord(lease)); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("recordLease: failed to create lease record: %w", err)
+	}
+	return nil
+}
+
+// Reconcile deletes the LeaseRecord once past the retention period, or requeues it until then
+func (r *LeaseRecordReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var record jumpstarterdevv1alpha1.LeaseRecord
+	if err := r.Get(ctx, req.NamespacedName, &record); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(
+			fmt.Errorf("Reconcile: unable to get lease record: %w", err),
+		)
+	}
+
+	expiration := record.Spec.EndTime.Add(r.Retention)
+	if now := time.Now(); now.Before(expiration) {
+		return ctrl.Result{RequeueAfter: expiration.Sub(now)}, nil
+	}
+
+	log.FromContext(ctx).Info("deleting expired lease record")
+	if err := r.Delete(ctx, &record); err != nil && !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, fmt.Errorf("Reconcile: failed to delete lease record: %w", err)
+	}
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *LeaseRecordReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&jumpstarterdevv1alpha1.LeaseRecord{}).
+		Complete(r)
+}
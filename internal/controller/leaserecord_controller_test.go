@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+var _ = Describe("LeaseRecord Controller", func() {
+	BeforeEach(func() {
+		ctx := context.Background()
+		createExporters(ctx, testExporter1DutA)
+		setExporterOnlineConditions(ctx, testExporter1DutA.Name, metav1.ConditionTrue)
+	})
+	AfterEach(func() {
+		ctx := context.Background()
+		deleteExporters(ctx, testExporter1DutA)
+		deleteLeases(ctx, "lease1")
+		Expect(k8sClient.DeleteAllOf(ctx, &jumpstarterdevv1alpha1.LeaseRecord{},
+			client.InNamespace("default"))).To(Succeed())
+	})
+
+	It("should record the lease when it ends and delete the record after the retention", func() {
+		ctx := context.Background()
+		leaseReconciler := &LeaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), LeaseRecords: true}
+
+		lease := leaseDutA2Sec.DeepCopy()
+		Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+		_ = reconcileLease(ctx, lease)
+
+		updatedLease := getLease(ctx, lease.Name)
+		updatedLease.Spec.Release = true
+		Expect(k8sClient.Update(ctx, updatedLease)).To(Succeed())
+		_, err := leaseReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(lease)})
+		Expect(err).NotTo(HaveOccurred())
+
+		updatedLease = getLease(ctx, lease.Name)
+		var record jumpstarterdevv1alpha1.LeaseRecord
+		Expect(k8sClient.Get(ctx, client.ObjectKey{
+			Namespace: lease.Namespace,
+			Name:      LeaseRecordName(updatedLease),
+		}, &record)).To(Succeed())
+		Expect(record.Spec.LeaseUID).To(Equal(updatedLease.UID))
+		Expect(record.Spec.ClientRef.Name).To(Equal(lease.Spec.ClientRef.Name))
+		Expect(record.Spec.ExporterRef).NotTo(BeNil())
+		Expect(record.Spec.ExporterRef.Name).To(Equal(testExporter1DutA.Name))
+		Expect(record.Spec.BeginTime).NotTo(BeNil())
+		Expect(record.Spec.EndReason).To(Equal("Released"))
+		Expect(record.Spec.Phase).To(Equal(jumpstarterdevv1alpha1.LeasePhaseEnded))
+
+		recordReconciler := &LeaseRecordReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Retention: time.Hour}
+		result, err := recordReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&record)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeNumerically(">", 59*time.Minute))
+
+		recordReconciler.Retention = 0
+		_, err = recordReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&record)})
+		Expect(err).NotTo(HaveOccurred())
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(&record), &record)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+})
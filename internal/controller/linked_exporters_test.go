@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+var testExporterPowerGroup1 = &jumpstarterdevv1alpha1.Exporter{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "exporter-power-group1",
+		Namespace: "default",
+		Labels: map[string]string{
+			jumpstarterdevv1alpha1.ExporterLabelGroup: "group1",
+		},
+	},
+}
+
+func linkedExporter(exporter *jumpstarterdevv1alpha1.Exporter, group string) *jumpstarterdevv1alpha1.Exporter {
+	linked := exporter.DeepCopy()
+	linked.Labels[jumpstarterdevv1alpha1.ExporterLabelGroup] = group
+	return linked
+}
+
+var _ = Describe("Linked exporters", func() {
+	BeforeEach(func() {
+		ctx := context.Background()
+		createExporters(ctx,
+			linkedExporter(testExporter1DutA, "group1"),
+			testExporter2DutA,
+			testExporter3DutB,
+			testExporterPowerGroup1,
+		)
+		setExporterOnlineConditions(ctx, testExporter1DutA.Name, metav1.ConditionTrue)
+		setExporterOnlineConditions(ctx, testExporter2DutA.Name, metav1.ConditionTrue)
+	})
+
+	AfterEach(func() {
+		ctx := context.Background()
+		deleteExporters(ctx, testExporter1DutA, testExporter2DutA, testExporter3DutB, testExporterPowerGroup1)
+		deleteLeases(ctx, "lease1")
+	})
+
+	It("should lease the whole group of linked exporters", func() {
+		ctx := context.Background()
+		setExporterOnlineConditions(ctx, testExporterPowerGroup1.Name, metav1.ConditionTrue)
+
+		lease := leaseDutA2Sec.DeepCopy()
+		Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+		_ = reconcileLease(ctx, lease)
+
+		updatedLease := getLease(ctx, lease.Name)
+		Expect(updatedLease.Status.ExporterRef).NotTo(BeNil())
+		Expect(updatedLease.Status.ExporterRef.Name).To(Equal(testExporter1DutA.Name))
+		Expect(updatedLease.Status.LinkedExporterRefs).To(HaveLen(1))
+		Expect(updatedLease.Status.LinkedExporterRefs[0].Name).To(Equal(testExporterPowerGroup1.Name))
+		Expect(LeaseHoldsExporter(updatedLease, testExporterPowerGroup1.Name)).To(BeTrue())
+
+		updatedExporter := getExporter(ctx, testExporterPowerGroup1.Name)
+		Expect(updatedExporter.Status.LeaseRef).NotTo(BeNil())
+		Expect(updatedExporter.Status.LeaseRef.Name).To(Equal(lease.Name))
+	})
+
+	It("should not lease an exporter if a linked exporter is offline", func() {
+		ctx := context.Background()
+		setExporterOnlineConditions(ctx, testExporterPowerGroup1.Name, metav1.ConditionFalse)
+
+		lease := leaseDutA2Sec.DeepCopy()
+		Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+		_ = reconcileLease(ctx, lease)
+
+		updatedLease := getLease(ctx, lease.Name)
+		Expect(updatedLease.Status.ExporterRef).NotTo(BeNil())
+		Expect(updatedLease.Status.ExporterRef.Name).To(Equal(testExporter2DutA.Name))
+		Expect(updatedLease.Status.LinkedExporterRefs).To(BeEmpty())
+	})
+})
@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// OverlappingMaintenance returns the first of windows selecting exporter and overlapping [begin, end), nil if none
+func OverlappingMaintenance(
+	windows []jumpstarterdevv1alpha1.MaintenanceWindow,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+	begin time.Time,
+	end time.Time,
+) (*jumpstarterdevv1alpha1.MaintenanceWindow, error) {
+	for i := range windows {
+		window := &windows[i]
+		if !window.Spec.Begin.Time.Before(end) || !begin.Before(window.Spec.End.Time) {
+			continue
+		}
+		matches, err := selectorMatches(&window.Spec.ExporterSelector, exporter.Labels)
+		if err != nil {
+			return nil, fmt.Errorf("OverlappingMaintenance: invalid exporter selector in %s: %w", window.Name, err)
+		}
+		if matches {
+			return window, nil
+		}
+	}
+	return nil, nil
+}
+
+// nextMaintenanceChange returns how long until one of windows selecting exporter opens or closes,
+// 0 if none will
+func nextMaintenanceChange(
+	windows []jumpstarterdevv1alpha1.MaintenanceWindow,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+	now time.Time,
+) time.Duration {
+	var next time.Duration
+	for i := range windows {
+		window := &windows[i]
+		matches, err := selectorMatches(&window.Spec.ExporterSelector, exporter.Labels)
+		if err != nil || !matches {
+			continue
+		}
+		for _, change := range []time.Time{window.Spec.Begin.Time, window.Spec.End.Time} {
+			if after := change.Sub(now); after > 0 && (next == 0 || after < next) {
+				next = after
+			}
+		}
+	}
+	return next
+}
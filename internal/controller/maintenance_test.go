@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+func maintenanceWindow(exporterLabels map[string]string, begin, end time.Time) *jumpstarterdevv1alpha1.MaintenanceWindow {
+	return &jumpstarterdevv1alpha1.MaintenanceWindow{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "maintenance",
+			Namespace: "default",
+		},
+		Spec: jumpstarterdevv1alpha1.MaintenanceWindowSpec{
+			ExporterSelector: metav1.LabelSelector{MatchLabels: exporterLabels},
+			Begin:            metav1.Time{Time: begin},
+			End:              metav1.Time{Time: end},
+			Reason:           "rewiring",
+		},
+	}
+}
+
+func reconcileExporter(ctx context.Context, name string) reconcile.Result {
+	exporterReconciler := &ExporterReconciler{
+		Client: k8sClient,
+		Scheme: k8sClient.Scheme(),
+	}
+	result, err := exporterReconciler.Reconcile(ctx, reconcile.Request{
+		NamespacedName: types.NamespacedName{Namespace: "default", Name: name},
+	})
+	Expect(err).NotTo(HaveOccurred())
+	return result
+}
+
+var _ = Describe("Maintenance windows", func() {
+	now := time.Now()
+
+	It("should only report windows selecting the exporter and overlapping the period", func() {
+		windows := []jumpstarterdevv1alpha1.MaintenanceWindow{
+			*maintenanceWindow(map[string]string{"dut": "a"}, now.Add(time.Hour), now.Add(2*time.Hour)),
+		}
+		window, err := OverlappingMaintenance(windows, testExporter1DutA, now, now.Add(time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(window).To(BeNil())
+
+		window, err = OverlappingMaintenance(windows, testExporter1DutA, now, now.Add(90*time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(window).NotTo(BeNil())
+
+		window, err = OverlappingMaintenance(windows, testExporter3DutB, now, now.Add(90*time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(window).To(BeNil())
+
+		Expect(nextMaintenanceChange(windows, testExporter1DutA, now)).To(Equal(time.Hour))
+		Expect(nextMaintenanceChange(windows, testExporter3DutB, now)).To(BeZero())
+	})
+
+	When("a maintenance window is open", func() {
+		BeforeEach(func() {
+			ctx := context.Background()
+			createExporters(ctx, testExporter1DutA, testExporter3DutB)
+			setExporterOnlineConditions(ctx, testExporter1DutA.Name, metav1.ConditionTrue)
+			setExporterOnlineConditions(ctx, testExporter3DutB.Name, metav1.ConditionTrue)
+			Expect(k8sClient.Create(ctx, maintenanceWindow(
+				map[string]string{"dut": "a"}, now.Add(-time.Minute), now.Add(time.Hour),
+			))).To(Succeed())
+		})
+		AfterEach(func() {
+			ctx := context.Background()
+			_ = k8sClient.Delete(ctx, maintenanceWindow(nil, now, now))
+			deleteExporters(ctx, testExporter1DutA, testExporter3DutB)
+			deleteLeases(ctx, "lease1")
+		})
+
+		It("should report the selected exporters under maintenance", func() {
+			ctx := context.Background()
+			result := reconcileExporter(ctx, testExporter1DutA.Name)
+			Expect(result.RequeueAfter).To(BeNumerically("~", time.Hour, time.Minute))
+
+			condition := meta.FindStatusCondition(getExporter(ctx, testExporter1DutA.Name).Status.Conditions,
+				string(jumpstarterdevv1alpha1.ExporterConditionTypeUnderMaintenance))
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Message).To(ContainSubstring("rewiring"))
+
+			reconcileExporter(ctx, testExporter3DutB.Name)
+			Expect(meta.FindStatusCondition(getExporter(ctx, testExporter3DutB.Name).Status.Conditions,
+				string(jumpstarterdevv1alpha1.ExporterConditionTypeUnderMaintenance))).To(BeNil())
+
+			Expect(k8sClient.Delete(ctx, maintenanceWindow(nil, now, now))).To(Succeed())
+			reconcileExporter(ctx, testExporter1DutA.Name)
+			Expect(meta.FindStatusCondition(getExporter(ctx, testExporter1DutA.Name).Status.Conditions,
+				string(jumpstarterdevv1alpha1.ExporterConditionTypeUnderMaintenance))).To(BeNil())
+		})
+
+		It("should not assign leases to the exporters under maintenance", func() {
+			ctx := context.Background()
+			lease := leaseDutA2Sec.DeepCopy()
+			Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+			_ = reconcileLease(ctx, lease)
+
+			updatedLease := getLease(ctx, lease.Name)
+			Expect(updatedLease.Status.ExporterRef).To(BeNil())
+			Expect(meta.IsStatusConditionTrue(updatedLease.Status.Conditions,
+				string(jumpstarterdevv1alpha1.LeaseConditionTypePending))).To(BeTrue())
+		})
+	})
+})
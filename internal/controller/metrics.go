@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	shadowAllocationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "jumpstarter_lease_shadow_allocations_total",
+			Help: "Number of pending lease allocations evaluated by the shadow allocator, " +
+				"by whether its decision matched the active allocator",
+		},
+		[]string{"allocator", "result"},
+	)
+	leaseRequeuesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "jumpstarter_lease_requeues_total",
+			Help: "Number of requeues of waiting leases by the lease controller, by what they wait for",
+		},
+		[]string{"reason"},
+	)
+	leaseAssignmentSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "jumpstarter_lease_assignment_duration_seconds",
+			Help:    "Time from the requested begin of leases to the allocation of their exporter, by allocator",
+			Buckets: prometheus.ExponentialBuckets(0.1, 4, 10),
+		},
+		[]string{"allocator"},
+	)
+	policyLeaseDenialsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "jumpstarter_policy_lease_denials_total",
+			Help: "Number of leases unsatisfiable because no ExporterAccessPolicy grants access, by the policy " +
+				"denying access to the matching exporters, empty if they are denied by default",
+		},
+		[]string{"namespace", "policy"},
+	)
+	policyPreemptionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "jumpstarter_policy_preemptions_total",
+			Help: "Number of leases preempted by leases of higher priority, by the ExporterAccessPolicy " +
+				"granting the preempted lease, empty if none does",
+		},
+		[]string{"namespace", "policy"},
+	)
+	policyGrantedLeasePriority = prometheus.NewSummaryVec(
+		prometheus.SummaryOpts{
+			Name: "jumpstarter_policy_granted_lease_priority",
+			Help: "Priority of the leases assigned an exporter, by the ExporterAccessPolicy granting them, " +
+				"empty if none does",
+		},
+		[]string{"namespace", "policy"},
+	)
+	policyEvaluationSeconds = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "jumpstarter_policy_evaluation_duration_seconds",
+			Help:    "Time to evaluate the ExporterAccessPolicies of a namespace for a client and an exporter",
+			Buckets: prometheus.ExponentialBuckets(0.000001, 4, 10),
+		},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		shadowAllocationsTotal,
+		leaseRequeuesTotal,
+		leaseAssignmentSeconds,
+		policyLeaseDenialsTotal,
+		policyPreemptionsTotal,
+		policyGrantedLeasePriority,
+		policyEvaluationSeconds,
+	)
+}
@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// PolicyTieBreak is which of the policies of equal priority matching a client applies, the limits are
+// compared in turn, unset limits being the most permissive: MaximumDuration, MaxConcurrentLeasesPerClient,
+// MaxConcurrentLeases, MaximumPauseDuration, and then the StreamLimits MaxConcurrentDials and
+// MaxSessionDuration. The policies with identical limits are tied by the name of their
+// ExporterAccessPolicy, and then by their order in it. The zero value is PolicyTieBreakMostRestrictive
+type PolicyTieBreak string
+
+const (
+	// PolicyTieBreakMostRestrictive applies the policy with the most restrictive limits
+	PolicyTieBreakMostRestrictive PolicyTieBreak = "MostRestrictive"
+	// PolicyTieBreakMostPermissive applies the policy with the most permissive limits
+	PolicyTieBreakMostPermissive PolicyTieBreak = "MostPermissive"
+)
+
+func (t *PolicyTieBreak) String() string {
+	return string(*t)
+}
+
+func (t *PolicyTieBreak) Set(value string) error {
+	switch tieBreak := PolicyTieBreak(value); tieBreak {
+	case PolicyTieBreakMostRestrictive, PolicyTieBreakMostPermissive:
+		*t = tieBreak
+		return nil
+	default:
+		return fmt.Errorf("unknown policy tie-break %s, expected %s or %s",
+			value, PolicyTieBreakMostRestrictive, PolicyTieBreakMostPermissive)
+	}
+}
+
+// prefers reports whether policy of the ExporterAccessPolicy name applies rather than the current
+// policy of the ExporterAccessPolicy currentName, policies of a same ExporterAccessPolicy being
+// considered in order
+func (t PolicyTieBreak) prefers(
+	policy *jumpstarterdevv1alpha1.Policy,
+	name string,
+	current *jumpstarterdevv1alpha1.Policy,
+	currentName string,
+) bool {
+	if policy.Priority != current.Priority {
+		return policy.Priority > current.Priority
+	}
+	restrictiveness := comparePolicyLimits(policy, current)
+	if t == PolicyTieBreakMostPermissive {
+		restrictiveness = -restrictiveness
+	}
+	if restrictiveness != 0 {
+		return restrictiveness < 0
+	}
+	return name < currentName
+}
+
+// comparePolicyLimits returns -1 if the limits of a are more restrictive than the ones of b,
+// +1 if they are more permissive, 0 if they are identical
+func comparePolicyLimits(a, b *jumpstarterdevv1alpha1.Policy) int {
+	return cmp.Or(
+		cmp.Compare(durationLimit(a.MaximumDuration), durationLimit(b.MaximumDuration)),
+		cmp.Compare(countLimit(a.MaxConcurrentLeasesPerClient), countLimit(b.MaxConcurrentLeasesPerClient)),
+		cmp.Compare(countLimit(a.MaxConcurrentLeases), countLimit(b.MaxConcurrentLeases)),
+		cmp.Compare(durationLimit(a.MaximumPauseDuration), durationLimit(b.MaximumPauseDuration)),
+		cmp.Compare(streamDialsLimit(a.StreamLimits), streamDialsLimit(b.StreamLimits)),
+		cmp.Compare(streamSessionLimit(a.StreamLimits), streamSessionLimit(b.StreamLimits)),
+	)
+}
+
+// durationLimit returns the limit set by d, the largest duration if unset
+func durationLimit(d *metav1.Duration) int64 {
+	if d == nil {
+		return math.MaxInt64
+	}
+	return int64(d.Duration)
+}
+
+// countLimit returns the limit set by n, the largest count if unset
+func countLimit(n *int32) int64 {
+	if n == nil {
+		return math.MaxInt64
+	}
+	return int64(*n)
+}
+
+func streamDialsLimit(limits *jumpstarterdevv1alpha1.StreamLimits) int64 {
+	if limits == nil || limits.MaxConcurrentDials == 0 {
+		return math.MaxInt64
+	}
+	return int64(limits.MaxConcurrentDials)
+}
+
+func streamSessionLimit(limits *jumpstarterdevv1alpha1.StreamLimits) int64 {
+	if limits == nil {
+		return math.MaxInt64
+	}
+	return durationLimit(limits.MaxSessionDuration)
+}
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+var _ = Describe("Access policy tie-break", func() {
+	short := fromClients(0, nil)
+	short.MaximumDuration = &metav1.Duration{Duration: time.Hour}
+	long := fromClients(0, nil)
+	long.MaximumDuration = &metav1.Duration{Duration: 2 * time.Hour}
+	unlimited := fromClients(0, nil)
+
+	evaluate := func(
+		tieBreak PolicyTieBreak,
+		policies ...jumpstarterdevv1alpha1.ExporterAccessPolicy,
+	) *jumpstarterdevv1alpha1.Policy {
+		decision, err := EvaluateAccessPolicies(policies, tieBreak, testClient, testExporter1DutA)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decision.Allowed).To(BeTrue())
+		return decision.Policy
+	}
+
+	It("should apply the highest priority policy whatever the tie-break", func() {
+		prioritized := fromClients(1, nil)
+		for _, tieBreak := range []PolicyTieBreak{PolicyTieBreakMostRestrictive, PolicyTieBreakMostPermissive} {
+			policy := evaluate(tieBreak, accessPolicy(map[string]string{"dut": "a"}, short, prioritized))
+			Expect(policy.Priority).To(Equal(1))
+		}
+	})
+
+	It("should apply the most restrictive policy of equal priority", func() {
+		policy := evaluate(PolicyTieBreakMostRestrictive, accessPolicy(map[string]string{"dut": "a"}, unlimited, long, short))
+		Expect(policy.MaximumDuration.Duration).To(Equal(time.Hour))
+	})
+
+	It("should apply the most permissive policy of equal priority", func() {
+		policy := evaluate(PolicyTieBreakMostPermissive, accessPolicy(map[string]string{"dut": "a"}, short, unlimited, long))
+		Expect(policy.MaximumDuration).To(BeNil())
+
+		policy = evaluate(PolicyTieBreakMostPermissive, accessPolicy(map[string]string{"dut": "a"}, short, long))
+		Expect(policy.MaximumDuration.Duration).To(Equal(2 * time.Hour))
+	})
+
+	It("should default to the most restrictive policy", func() {
+		var tieBreak PolicyTieBreak
+		policy := evaluate(tieBreak, accessPolicy(map[string]string{"dut": "a"}, long, short))
+		Expect(policy.MaximumDuration.Duration).To(Equal(time.Hour))
+	})
+
+	It("should compare the next limits of the policies with the same maximum duration", func() {
+		perClient := int32(1)
+		limited := long
+		limited.MaxConcurrentLeasesPerClient = &perClient
+		policy := evaluate(PolicyTieBreakMostRestrictive, accessPolicy(map[string]string{"dut": "a"}, long, limited))
+		Expect(policy.MaxConcurrentLeasesPerClient).NotTo(BeNil())
+	})
+
+	It("should tie the policies with identical limits by name and then by order", func() {
+		first := fromClients(0, map[string]string{"team": "ci"})
+		second := fromClients(0, nil)
+		policies := []jumpstarterdevv1alpha1.ExporterAccessPolicy{
+			accessPolicy(map[string]string{"dut": "a"}, second),
+			accessPolicy(map[string]string{"dut": "a"}, first),
+		}
+		policies[0].Name, policies[1].Name = "b", "a"
+		ciClient := testClient.DeepCopy()
+		ciClient.Labels = map[string]string{"team": "ci"}
+
+		decision, err := EvaluateAccessPolicies(policies, PolicyTieBreakMostRestrictive, ciClient, testExporter1DutA)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decision.PolicyName).To(Equal("a"))
+
+		policies = []jumpstarterdevv1alpha1.ExporterAccessPolicy{
+			accessPolicy(map[string]string{"dut": "a"}, first, second),
+		}
+		decision, err = EvaluateAccessPolicies(policies, PolicyTieBreakMostRestrictive, ciClient, testExporter1DutA)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decision.Policy).To(BeIdenticalTo(&policies[0].Spec.Policies[0]))
+	})
+})
@@ -0,0 +1,269 @@
+package controller
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// +kubebuilder:rbac:groups=jumpstarter.dev,resources=recurringleases,verbs=get;list;watch
+// +kubebuilder:rbac:groups=jumpstarter.dev,resources=recurringleases/status,verbs=get;update;patch
+
+// RecurringLeaseReconciler creates the leases of the RecurringLeases on their schedule, and
+// deletes their ended leases past the history limits
+type RecurringLeaseReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+// The defaults of the history limits, for the RecurringLeases created before the CRD defaults
+const (
+	defaultSuccessfulLeasesHistoryLimit = 3
+	defaultFailedLeasesHistoryLimit     = 1
+)
+
+// RecurringLeaseName returns the name of the lease of recurring scheduled at scheduled
+func RecurringLeaseName(recurring *jumpstarterdevv1alpha1.RecurringLease, scheduled time.Time) string {
+	return fmt.Sprintf("%s-%d", recurring.Name, scheduled.Unix()/60)
+}
+
+// Reconcile creates the lease of the RecurringLease when one is due, and requeues it until the next one
+func (r *RecurringLeaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var recurring jumpstarterdevv1alpha1.RecurringLease
+	if err := r.Get(ctx, req.NamespacedName, &recurring); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(
+			fmt.Errorf("Reconcile: unable to get recurring lease: %w", err),
+		)
+	}
+
+	var leases jumpstarterdevv1alpha1.LeaseList
+	if err := r.List(ctx, &leases,
+		client.InNamespace(recurring.Namespace),
+		client.MatchingLabels{jumpstarterdevv1alpha1.RecurringLeaseLabel: recurring.Name},
+	); err != nil {
+		return ctrl.Result{}, fmt.Errorf("Reconcile: failed to list leases: %w", err)
+	}
+
+	var active, successful, failed []*jumpstarterdevv1alpha1.Lease
+	for i := range leases.Items {
+		lease := &leases.Items[i]
+		switch {
+		case !lease.Status.Ended:
+			active = append(active, lease)
+		case lease.Status.Phase == jumpstarterdevv1alpha1.LeasePhaseFailed:
+			failed = append(failed, lease)
+		default:
+			successful = append(successful, lease)
+		}
+	}
+
+	successfulLimit := int32(defaultSuccessfulLeasesHistoryLimit)
+	if recurring.Spec.SuccessfulLeasesHistoryLimit != nil {
+		successfulLimit = *recurring.Spec.SuccessfulLeasesHistoryLimit
+	}
+	failedLimit := int32(defaultFailedLeasesHistoryLimit)
+	if recurring.Spec.FailedLeasesHistoryLimit != nil {
+		failedLimit = *recurring.Spec.FailedLeasesHistoryLimit
+	}
+	if err := r.pruneHistory(ctx, successful, int(successfulLimit)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("Reconcile: %w", err)
+	}
+	if err := r.pruneHistory(ctx, failed, int(failedLimit)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("Reconcile: %w", err)
+	}
+
+	schedule, err := ParseCronSchedule(recurring.Spec.Schedule)
+	if err != nil {
+		// the schedule is not retried until the RecurringLease changes
+		logger.Error(err, "invalid schedule", "schedule", recurring.Spec.Schedule)
+		r.Recorder.Eventf(&recurring, corev1.EventTypeWarning, "InvalidSchedule", "invalid schedule: %s", err)
+		return ctrl.Result{}, r.applyStatus(ctx, &recurring, active)
+	}
+
+	now := time.Now()
+	lastSchedule := recurring.CreationTimestamp.Time
+	if recurring.Status.LastScheduleTime != nil {
+		lastSchedule = recurring.Status.LastScheduleTime.Time
+	}
+	// the leases missed past the starting deadline are skipped
+	if deadline := recurring.Spec.StartingDeadline; deadline != nil && now.Sub(lastSchedule) > deadline.Duration {
+		lastSchedule = now.Add(-deadline.Duration)
+	}
+	scheduled := lastMissedSchedule(schedule, lastSchedule, now)
+
+	if !scheduled.IsZero() && !recurring.Spec.Suspend {
+		switch recurring.Spec.ConcurrencyPolicy {
+		case jumpstarterdevv1alpha1.RecurringLeaseAllowConcurrent:
+		case jumpstarterdevv1alpha1.RecurringLeaseReplaceConcurrent:
+			for _, lease := range active {
+				if err := r.releaseLease(ctx, lease); err != nil {
+					return ctrl.Result{}, fmt.Errorf("Reconcile: %w", err)
+				}
+			}
+		default:
+			// the lease is created once the active ones end, which requeues the RecurringLease
+			if len(active) > 0 {
+				logger.Info("postponing the lease until the active ones end", "scheduled", scheduled)
+				scheduled = time.Time{}
+			}
+		}
+	}
+
+	if !scheduled.IsZero() && !recurring.Spec.Suspend {
+		lease, err := r.createLease(ctx, &recurring, scheduled)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("Reconcile: %w", err)
+		}
+		if lease != nil {
+			active = append(active, lease)
+		}
+	}
+	// the leases missed while suspended are skipped
+	if !scheduled.IsZero() {
+		recurring.Status.LastScheduleTime = &metav1.Time{Time: scheduled}
+	}
+
+	if err := r.applyStatus(ctx, &recurring, active); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	next := schedule.Next(now)
+	if next.IsZero() {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{RequeueAfter: next.Sub(now)}, nil
+}
+
+// lastMissedSchedule returns the last time of schedule after last and until now, or the zero time
+func lastMissedSchedule(schedule *CronSchedule, last time.Time, now time.Time) time.Time {
+	var missed time.Time
+	for next := schedule.Next(last); !next.IsZero() && !next.After(now); next = schedule.Next(next) {
+		missed = next
+	}
+	return missed
+}
+
+// createLease creates the lease of recurring scheduled at scheduled, it returns nil if the lease
+// already exists
+func (r *RecurringLeaseReconciler) createLease(
+	ctx context.Context,
+	recurring *jumpstarterdevv1alpha1.RecurringLease,
+	scheduled time.Time,
+) (*jumpstarterdevv1alpha1.Lease, error) {
+	lease := &jumpstarterdevv1alpha1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: recurring.Namespace,
+			Name:      RecurringLeaseName(recurring, scheduled),
+			Labels: map[string]string{
+				jumpstarterdevv1alpha1.RecurringLeaseLabel: recurring.Name,
+			},
+		},
+		Spec: *recurring.Spec.Template.DeepCopy(),
+	}
+	// the lease controller sets the exporter of the lease as its controller
+	if err := controllerutil.SetOwnerReference(recurring, lease, r.Scheme); err != nil {
+		return nil, fmt.Errorf("createLease: failed to set owner reference: %w", err)
+	}
+
+	if err := r.Create(ctx, lease); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return nil, nil
+		}
+		r.Recorder.Eventf(recurring, corev1.EventTypeWarning, "FailedCreate", "failed to create lease %s: %s", lease.Name, err)
+		return nil, fmt.Errorf("createLease: failed to create lease: %w", err)
+	}
+	log.FromContext(ctx).Info("created scheduled lease", "lease", lease.Name, "scheduled", scheduled)
+	r.Recorder.Eventf(recurring, corev1.EventTypeNormal, "SuccessfulCreate", "created lease %s", lease.Name)
+	return lease, nil
+}
+
+// releaseLease requests the release of the active lease replaced by the next one
+func (r *RecurringLeaseReconciler) releaseLease(ctx context.Context, lease *jumpstarterdevv1alpha1.Lease) error {
+	if lease.Spec.Release {
+		return nil
+	}
+	original := client.MergeFrom(lease.DeepCopy())
+	lease.Spec.Release = true
+	if err := r.Patch(ctx, lease, original); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("releaseLease: failed to release lease %s: %w", lease.Name, err)
+	}
+	return nil
+}
+
+// pruneHistory deletes the oldest of the ended leases beyond limit
+func (r *RecurringLeaseReconciler) pruneHistory(
+	ctx context.Context,
+	leases []*jumpstarterdevv1alpha1.Lease,
+	limit int,
+) error {
+	if len(leases) <= limit {
+		return nil
+	}
+	slices.SortFunc(leases, func(a, b *jumpstarterdevv1alpha1.Lease) int {
+		return a.CreationTimestamp.Compare(b.CreationTimestamp.Time)
+	})
+	for _, lease := range leases[:len(leases)-limit] {
+		if err := r.Delete(ctx, lease, client.Preconditions{UID: &lease.UID}); err != nil &&
+			!apierrors.IsNotFound(err) && !apierrors.IsConflict(err) {
+			return fmt.Errorf("pruneHistory: failed to delete lease %s: %w", lease.Name, err)
+		}
+	}
+	return nil
+}
+
+// applyStatus applies the status of recurring with its active leases
+func (r *RecurringLeaseReconciler) applyStatus(
+	ctx context.Context,
+	recurring *jumpstarterdevv1alpha1.RecurringLease,
+	active []*jumpstarterdevv1alpha1.Lease,
+) error {
+	status := jumpstarterdevv1alpha1.RecurringLeaseStatus{
+		LastScheduleTime: recurring.Status.LastScheduleTime,
+	}
+	for _, lease := range active {
+		status.Active = append(status.Active, corev1.LocalObjectReference{Name: lease.Name})
+	}
+	slices.SortFunc(status.Active, func(a, b corev1.LocalObjectReference) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	if err := applyStatus(ctx, r.Client, recurring, &status, recurringLeaseFieldManager, ""); err != nil {
+		return fmt.Errorf("Reconcile: failed to update recurring lease status: %w", err)
+	}
+	return nil
+}
+
+// recurringLeaseRequests maps a lease to the RecurringLease that created it
+func recurringLeaseRequests(_ context.Context, obj client.Object) []reconcile.Request {
+	name, ok := obj.GetLabels()[jumpstarterdevv1alpha1.RecurringLeaseLabel]
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: client.ObjectKey{Namespace: obj.GetNamespace(), Name: name}}}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *RecurringLeaseReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&jumpstarterdevv1alpha1.RecurringLease{}).
+		Watches(&jumpstarterdevv1alpha1.Lease{}, handler.EnqueueRequestsFromMapFunc(recurringLeaseRequests)).
+		Complete(r)
+}
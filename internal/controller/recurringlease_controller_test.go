@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+var _ = Describe("RecurringLease Controller", func() {
+	AfterEach(func() {
+		ctx := context.Background()
+		Expect(k8sClient.DeleteAllOf(ctx, &jumpstarterdevv1alpha1.RecurringLease{},
+			client.InNamespace("default"))).To(Succeed())
+		Expect(k8sClient.DeleteAllOf(ctx, &jumpstarterdevv1alpha1.Lease{},
+			client.InNamespace("default"))).To(Succeed())
+	})
+
+	It("should parse cron schedules", func() {
+		base := time.Date(2024, time.October, 1, 8, 7, 30, 0, time.UTC) // a Tuesday
+
+		schedule, err := ParseCronSchedule("*/15 8-18 * * 1-5")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(schedule.Next(base)).To(Equal(time.Date(2024, time.October, 1, 8, 15, 0, 0, time.UTC)))
+		Expect(schedule.Next(time.Date(2024, time.October, 4, 18, 45, 0, 0, time.UTC))).
+			To(Equal(time.Date(2024, time.October, 7, 8, 0, 0, 0, time.UTC)))
+
+		schedule, err = ParseCronSchedule("@daily")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(schedule.Next(base)).To(Equal(time.Date(2024, time.October, 2, 0, 0, 0, 0, time.UTC)))
+
+		// the day of the month and the day of the week match either
+		schedule, err = ParseCronSchedule("0 0 13 * 5")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(schedule.Next(base)).To(Equal(time.Date(2024, time.October, 4, 0, 0, 0, 0, time.UTC)))
+
+		schedule, err = ParseCronSchedule("0 0 30 2 *")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(schedule.Next(base).IsZero()).To(BeTrue())
+
+		for _, spec := range []string{"* * * *", "60 * * * *", "* * * * 8", "*/0 * * * *", "a * * * *"} {
+			_, err = ParseCronSchedule(spec)
+			Expect(err).To(HaveOccurred(), spec)
+		}
+	})
+
+	It("should create the leases on the schedule and keep the history limits", func() {
+		ctx := context.Background()
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &RecurringLeaseReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Recorder: recorder}
+
+		recurring := &jumpstarterdevv1alpha1.RecurringLease{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      "nightly",
+			},
+			Spec: jumpstarterdevv1alpha1.RecurringLeaseSpec{
+				Schedule: "* * * * *",
+				Template: jumpstarterdevv1alpha1.LeaseSpec{
+					ClientRef: corev1.LocalObjectReference{Name: testClient.Name},
+					Duration:  metav1.Duration{Duration: time.Hour},
+					Selector:  metav1.LabelSelector{MatchLabels: map[string]string{"dut": "a"}},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, recurring)).To(Succeed())
+		recurring.Status.LastScheduleTime = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+		Expect(k8sClient.Status().Update(ctx, recurring)).To(Succeed())
+
+		request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(recurring)}
+		result, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeNumerically("<=", time.Minute))
+
+		Expect(k8sClient.Get(ctx, request.NamespacedName, recurring)).To(Succeed())
+		Expect(recurring.Status.LastScheduleTime).NotTo(BeNil())
+		Expect(recurring.Status.Active).To(HaveLen(1))
+		first := recurring.Status.Active[0].Name
+		Expect(first).To(Equal(RecurringLeaseName(recurring, recurring.Status.LastScheduleTime.Time)))
+
+		var lease jumpstarterdevv1alpha1.Lease
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: first}, &lease)).To(Succeed())
+		Expect(lease.Labels[jumpstarterdevv1alpha1.RecurringLeaseLabel]).To(Equal(recurring.Name))
+		Expect(lease.Spec.Duration.Duration).To(Equal(time.Hour))
+		Expect(lease.OwnerReferences).To(HaveLen(1))
+
+		// the next lease waits for the active one to end with the Forbid policy
+		recurring.Status.LastScheduleTime = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+		Expect(k8sClient.Status().Update(ctx, recurring)).To(Succeed())
+		_, err = reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+		var leases jumpstarterdevv1alpha1.LeaseList
+		Expect(k8sClient.List(ctx, &leases, client.InNamespace("default"))).To(Succeed())
+		Expect(leases.Items).To(HaveLen(1))
+
+		// the ended leases beyond the history limit are deleted
+		lease.Status.Ended = true
+		lease.Status.Phase = jumpstarterdevv1alpha1.LeasePhaseEnded
+		Expect(k8sClient.Status().Update(ctx, &lease)).To(Succeed())
+		Expect(k8sClient.Get(ctx, request.NamespacedName, recurring)).To(Succeed())
+		recurring.Spec.SuccessfulLeasesHistoryLimit = new(int32)
+		Expect(k8sClient.Update(ctx, recurring)).To(Succeed())
+
+		_, err = reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.List(ctx, &leases, client.InNamespace("default"))).To(Succeed())
+		Expect(leases.Items).To(HaveLen(1))
+		Expect(leases.Items[0].Name).NotTo(Equal(first))
+	})
+})
@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// ClientChannel returns the release channel of client, a missing client is on the stable channel
+func ClientChannel(client *jumpstarterdevv1alpha1.Client) jumpstarterdevv1alpha1.ReleaseChannel {
+	if client == nil {
+		return jumpstarterdevv1alpha1.ReleaseChannelStable
+	}
+	return client.Spec.Channel.OrDefault()
+}
+
+// applyChannelLabel applies the LabelReleaseChannel label of obj, of kind, to channel with manager
+func applyChannelLabel(
+	ctx context.Context,
+	c client.Client,
+	obj client.Object,
+	kind string,
+	channel jumpstarterdevv1alpha1.ReleaseChannel,
+	manager string,
+) error {
+	if obj.GetLabels()[jumpstarterdevv1alpha1.LabelReleaseChannel] == string(channel.OrDefault()) {
+		return nil
+	}
+
+	objMetadata := &metav1.PartialObjectMetadata{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: jumpstarterdevv1alpha1.GroupVersion.String(),
+			Kind:       kind,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: obj.GetNamespace(),
+			Name:      obj.GetName(),
+			Labels: map[string]string{
+				jumpstarterdevv1alpha1.LabelReleaseChannel: string(channel.OrDefault()),
+			},
+		},
+	}
+	if err := c.Patch(ctx, objMetadata, client.Apply, client.FieldOwner(manager), client.ForceOwnership); err != nil {
+		return fmt.Errorf("applyChannelLabel: failed to apply channel label: %w", err)
+	}
+	return nil
+}
+
+// ChannelFilter filters out exporters on another release channel than the client of the lease
+type ChannelFilter struct{}
+
+func (ChannelFilter) Name() string {
+	return "Channel"
+}
+
+func (ChannelFilter) Filter(
+	_ context.Context,
+	state *AllocationState,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+) FilterCode {
+	if exporter.Spec.Channel.OrDefault() != ClientChannel(state.Client) {
+		return FilterCodeUnresolvable
+	}
+	return FilterCodeSuccess
+}
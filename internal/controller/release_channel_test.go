@@ -0,0 +1,29 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+var _ = Describe("Release channels", func() {
+	It("should only let leases acquire exporters on the channel of their client", func() {
+		ctx := context.Background()
+		stable := testExporter1DutA.DeepCopy()
+		canary := testExporter2DutA.DeepCopy()
+		canary.Spec.Channel = jumpstarterdevv1alpha1.ReleaseChannelCanary
+
+		state := &AllocationState{Lease: leaseDutA2Sec}
+		Expect((ChannelFilter{}).Filter(ctx, state, stable)).To(Equal(FilterCodeSuccess))
+		Expect((ChannelFilter{}).Filter(ctx, state, canary)).To(Equal(FilterCodeUnresolvable))
+
+		state.Client = &jumpstarterdevv1alpha1.Client{
+			Spec: jumpstarterdevv1alpha1.ClientSpec{Channel: jumpstarterdevv1alpha1.ReleaseChannelCanary},
+		}
+		Expect((ChannelFilter{}).Filter(ctx, state, stable)).To(Equal(FilterCodeUnresolvable))
+		Expect((ChannelFilter{}).Filter(ctx, state, canary)).To(Equal(FilterCodeSuccess))
+	})
+})
@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// OverlappingReservation returns the first reservation of exporter overlapping [begin, end), nil if none
+func OverlappingReservation(
+	exporter *jumpstarterdevv1alpha1.Exporter,
+	begin time.Time,
+	end time.Time,
+) *jumpstarterdevv1alpha1.ExporterReservation {
+	for i := range exporter.Spec.Reservations {
+		reservation := &exporter.Spec.Reservations[i]
+		if reservation.Begin.Time.Before(end) && begin.Before(reservation.End.Time) {
+			return reservation
+		}
+	}
+	return nil
+}
+
+// ReservationHeldBy reports whether client is one of the holders of reservation
+func ReservationHeldBy(
+	reservation *jumpstarterdevv1alpha1.ExporterReservation,
+	client *jumpstarterdevv1alpha1.Client,
+) (bool, error) {
+	if client == nil {
+		return false, nil
+	}
+	if reservation.ClientRef != nil && reservation.ClientRef.Name == client.Name {
+		return true, nil
+	}
+	if reservation.ClientSelector != nil {
+		matches, err := selectorMatches(reservation.ClientSelector, client.Labels)
+		if err != nil {
+			return false, fmt.Errorf("ReservationHeldBy: invalid client selector: %w", err)
+		}
+		return matches, nil
+	}
+	return false, nil
+}
+
+// reservedFor reports whether exporter is currently reserved to client
+func reservedFor(exporter *jumpstarterdevv1alpha1.Exporter, client *jumpstarterdevv1alpha1.Client, now time.Time) bool {
+	reservation := OverlappingReservation(exporter, now, now.Add(time.Nanosecond))
+	if reservation == nil {
+		return false
+	}
+	held, err := ReservationHeldBy(reservation, client)
+	return err == nil && held
+}
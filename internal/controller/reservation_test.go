@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+func reservedExporter(
+	exporter *jumpstarterdevv1alpha1.Exporter,
+	clientName string,
+	begin time.Time,
+	end time.Time,
+) *jumpstarterdevv1alpha1.Exporter {
+	reserved := exporter.DeepCopy()
+	reserved.Spec.Reservations = []jumpstarterdevv1alpha1.ExporterReservation{{
+		ClientRef: &corev1.LocalObjectReference{Name: clientName},
+		Begin:     metav1.NewTime(begin),
+		End:       metav1.NewTime(end),
+	}}
+	return reserved
+}
+
+var _ = Describe("Exporter reservations", func() {
+	now := time.Now()
+
+	It("should find the reservations overlapping a period", func() {
+		exporter := reservedExporter(testExporter1DutA, testClient.Name, now.Add(time.Hour), now.Add(2*time.Hour))
+		Expect(OverlappingReservation(exporter, now, now.Add(time.Minute))).To(BeNil())
+		Expect(OverlappingReservation(exporter, now, now.Add(90*time.Minute))).NotTo(BeNil())
+		Expect(OverlappingReservation(exporter, now.Add(2*time.Hour), now.Add(3*time.Hour))).To(BeNil())
+	})
+
+	It("should match the holders by name or label", func() {
+		reservation := &jumpstarterdevv1alpha1.ExporterReservation{
+			ClientSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "ci"}},
+		}
+		held, err := ReservationHeldBy(reservation, &jumpstarterdevv1alpha1.Client{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"team": "ci"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(held).To(BeTrue())
+
+		held, err = ReservationHeldBy(reservation, testClient)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(held).To(BeFalse())
+
+		reservation.ClientRef = &corev1.LocalObjectReference{Name: testClient.Name}
+		held, err = ReservationHeldBy(reservation, testClient)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(held).To(BeTrue())
+	})
+
+	When("leasing reserved exporters", func() {
+		AfterEach(func() {
+			ctx := context.Background()
+			deleteExporters(ctx, testExporter1DutA, testExporter2DutA, testExporter3DutB)
+			deleteLeases(ctx, "lease1")
+		})
+
+		It("should not assign exporters reserved to other clients", func() {
+			ctx := context.Background()
+			createExporters(ctx,
+				reservedExporter(testExporter1DutA, "other", now.Add(-time.Hour), now.Add(time.Hour)),
+				reservedExporter(testExporter2DutA, "other", now.Add(time.Second), now.Add(time.Hour)),
+				testExporter3DutB,
+			)
+			setExporterOnlineConditions(ctx, testExporter1DutA.Name, metav1.ConditionTrue)
+			setExporterOnlineConditions(ctx, testExporter2DutA.Name, metav1.ConditionTrue)
+
+			lease := leaseDutA2Sec.DeepCopy()
+			Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+			_ = reconcileLease(ctx, lease)
+
+			updatedLease := getLease(ctx, lease.Name)
+			Expect(updatedLease.Status.ExporterRef).To(BeNil())
+			Expect(meta.IsStatusConditionTrue(updatedLease.Status.Conditions,
+				string(jumpstarterdevv1alpha1.LeaseConditionTypePending))).To(BeTrue())
+		})
+
+		It("should assign exporters reserved to the client regardless of access policies", func() {
+			ctx := context.Background()
+			createExporters(ctx,
+				reservedExporter(testExporter1DutA, testClient.Name, now.Add(-time.Hour), now.Add(time.Hour)),
+				testExporter2DutA,
+				testExporter3DutB,
+			)
+			setExporterOnlineConditions(ctx, testExporter1DutA.Name, metav1.ConditionTrue)
+			setExporterOnlineConditions(ctx, testExporter2DutA.Name, metav1.ConditionTrue)
+
+			policy := accessPolicy(map[string]string{"dut": "a"}, fromClients(0, map[string]string{"team": "ci"}))
+			Expect(k8sClient.Create(ctx, &policy)).To(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(ctx, &policy)).To(Succeed())
+			}()
+
+			lease := leaseDutA2Sec.DeepCopy()
+			Expect(k8sClient.Create(ctx, lease)).To(Succeed())
+			_ = reconcileLease(ctx, lease)
+
+			updatedLease := getLease(ctx, lease.Name)
+			Expect(updatedLease.Status.ExporterRef).NotTo(BeNil())
+			Expect(updatedLease.Status.ExporterRef.Name).To(Equal(testExporter1DutA.Name))
+		})
+	})
+})
@@ -23,6 +23,9 @@ type JumpstarterClaims struct {
 	Name       string    `json:"kubernetes.io/name,omitempty"`
 	UID        types.UID `json:"kubernetes.io/uid,omitempty"`
 	APIVersion string    `json:"kubernetes.io/api_version,omitempty"`
+	// The groups the holder of the token is a member of, matched by the groups of the
+	// ExporterAccessPolicies
+	Groups []string `json:"groups,omitempty"`
 }
 
 func KeyFunc(_ *jwt.Token) (interface{}, error) {
@@ -77,13 +80,8 @@ type Object[T any] interface {
 	*T
 }
 
-func VerifyObjectToken[T any, PT Object[T]](
-	ctx context.Context,
-	token string,
-	issuer string,
-	audience string,
-	client client.Client,
-) (*T, error) {
+// ParseObjectToken verifies the signature, issuer and audience of token and returns its claims
+func ParseObjectToken(token string, issuer string, audience string) (*JumpstarterClaims, error) {
 	parsed, err := jwt.ParseWithClaims(
 		token,
 		&JumpstarterClaims{},
@@ -100,25 +98,52 @@ func VerifyObjectToken[T any, PT Object[T]](
 	if err != nil {
 		return nil, err
 	} else if claims, ok := parsed.Claims.(*JumpstarterClaims); ok {
-		var object T
-		err = client.Get(
-			ctx,
-			types.NamespacedName{
-				Namespace: claims.Namespace,
-				Name:      claims.Name,
-			},
-			PT(&object),
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		if PT(&object).GetUID() != claims.UID {
-			return nil, fmt.Errorf("VerifyObjectToken: UID mismatch")
-		}
-
-		return &object, nil
+		return claims, nil
 	} else {
 		return nil, fmt.Errorf("%T is not a JumpstarterClaims", parsed.Claims)
 	}
 }
+
+func VerifyObjectToken[T any, PT Object[T]](
+	ctx context.Context,
+	token string,
+	issuer string,
+	audience string,
+	client client.Client,
+) (*T, error) {
+	object, _, err := VerifyObjectTokenClaims[T, PT](ctx, token, issuer, audience, client)
+	return object, err
+}
+
+// VerifyObjectTokenClaims verifies token as VerifyObjectToken does, also returning its claims
+func VerifyObjectTokenClaims[T any, PT Object[T]](
+	ctx context.Context,
+	token string,
+	issuer string,
+	audience string,
+	client client.Client,
+) (*T, *JumpstarterClaims, error) {
+	claims, err := ParseObjectToken(token, issuer, audience)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var object T
+	err = client.Get(
+		ctx,
+		types.NamespacedName{
+			Namespace: claims.Namespace,
+			Name:      claims.Name,
+		},
+		PT(&object),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if PT(&object).GetUID() != claims.UID {
+		return nil, nil, fmt.Errorf("VerifyObjectToken: UID mismatch")
+	}
+
+	return &object, claims, nil
+}
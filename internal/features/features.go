@@ -0,0 +1,150 @@
+// Package features implements feature gates for the experimental subsystems of the controller,
+// in the spirit of the Kubernetes feature gates
+package features
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Feature is the name of a feature gate
+type Feature string
+
+// Stage is the maturity of a feature
+type Stage string
+
+const (
+	Alpha Stage = "ALPHA"
+	Beta  Stage = "BETA"
+	GA    Stage = "GA"
+)
+
+// FeatureSpec describes a feature gate
+type FeatureSpec struct {
+	// Whether the feature is enabled when the gate is not set
+	Default bool
+	// The maturity of the feature
+	Stage Stage
+}
+
+const (
+	// Preemption lets higher priority leases preempt the leases holding the exporters they need
+	Preemption Feature = "Preemption"
+)
+
+var defaultFeatures = map[Feature]FeatureSpec{
+	Preemption: {Default: false, Stage: Alpha},
+}
+
+var enabledGauge = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "jumpstarter_feature_enabled",
+		Help: "Whether a feature gate is enabled (1) or disabled (0), by feature name and stage",
+	},
+	[]string{"name", "stage"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(enabledGauge)
+	DefaultGate.report()
+}
+
+// Gate tracks which features are enabled, it implements flag.Value
+type Gate struct {
+	mu      sync.RWMutex
+	known   map[Feature]FeatureSpec
+	enabled map[Feature]bool
+}
+
+// DefaultGate is the feature gate of the controller, set by the --feature-gates flag
+var DefaultGate = NewGate(defaultFeatures)
+
+// NewGate returns a Gate for the known features, all set to their default
+func NewGate(known map[Feature]FeatureSpec) *Gate {
+	return &Gate{
+		known:   known,
+		enabled: map[Feature]bool{},
+	}
+}
+
+// Enabled reports whether feature is enabled, unknown features are disabled
+func (g *Gate) Enabled(feature Feature) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if enabled, ok := g.enabled[feature]; ok {
+		return enabled
+	}
+	return g.known[feature].Default
+}
+
+// Known returns the known features, sorted by name
+func (g *Gate) Known() []Feature {
+	features := make([]Feature, 0, len(g.known))
+	for feature := range g.known {
+		features = append(features, feature)
+	}
+	slices.Sort(features)
+	return features
+}
+
+// Set parses a comma separated list of feature=bool pairs, e.g. "Preemption=true"
+func (g *Gate) Set(value string) error {
+	enabled := map[Feature]bool{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, raw, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("Set: missing value for feature gate %s", name)
+		}
+		feature := Feature(strings.TrimSpace(name))
+		if _, ok := g.known[feature]; !ok {
+			return fmt.Errorf("Set: unknown feature gate %s", feature)
+		}
+		value, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("Set: invalid value for feature gate %s: %w", feature, err)
+		}
+		enabled[feature] = value
+	}
+
+	g.mu.Lock()
+	for feature, value := range enabled {
+		g.enabled[feature] = value
+	}
+	g.mu.Unlock()
+
+	g.report()
+	return nil
+}
+
+// String returns the state of every known feature as comma separated feature=bool pairs
+func (g *Gate) String() string {
+	if g == nil {
+		return ""
+	}
+	pairs := make([]string, 0, len(g.known))
+	for _, feature := range g.Known() {
+		pairs = append(pairs, fmt.Sprintf("%s=%t", feature, g.Enabled(feature)))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// report exports the state of the known features as metrics
+func (g *Gate) report() {
+	for _, feature := range g.Known() {
+		value := 0.0
+		if g.Enabled(feature) {
+			value = 1
+		}
+		enabledGauge.WithLabelValues(string(feature), string(g.known[feature].Stage)).Set(value)
+	}
+}
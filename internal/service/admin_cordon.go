@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/internal/controller"
+	"github.com/jumpstarter-dev/jumpstarter-controller/pkg/api"
+)
+
+// CordonExporter marks an exporter as unschedulable, new leases do not acquire it
+func (a adminService) CordonExporter(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	return a.scheduleExporter(ctx, in, func(exporter *jumpstarterdevv1alpha1.Exporter) {
+		exporter.Spec.Unschedulable = true
+	})
+}
+
+// UncordonExporter marks an exporter as schedulable again, and removes its drain
+func (a adminService) UncordonExporter(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	return a.scheduleExporter(ctx, in, func(exporter *jumpstarterdevv1alpha1.Exporter) {
+		exporter.Spec.Unschedulable = false
+		exporter.Spec.Drain = false
+	})
+}
+
+// DrainExporter drains an exporter, new leases do not acquire it and once its active leases ended,
+// or were released with release, it is disconnected and kept offline until uncordoned
+func (a adminService) DrainExporter(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	var req api.DrainExporterRequest
+	if err := decodeStruct(in, &req); err != nil {
+		return nil, err
+	}
+	exporter, err := a.exporter(ctx, "patch", req.Namespace, req.Exporter)
+	if err != nil {
+		return nil, err
+	}
+	if req.Release {
+		if err := a.authorize(ctx, "patch", "leases", req.Namespace, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := a.patchExporterSpec(ctx, exporter, func(exporter *jumpstarterdevv1alpha1.Exporter) {
+		exporter.Spec.Drain = true
+	}); err != nil {
+		return nil, err
+	}
+
+	response := exporterSchedulingResponse(exporter)
+	if req.Release {
+		released, err := a.releaseExporterLeases(ctx, exporter)
+		if err != nil {
+			return nil, err
+		}
+		response.ReleasedLeases = released
+	}
+	return encodeStruct(response)
+}
+
+// scheduleExporter applies update to the spec of the exporter of the ExporterRequest in
+func (a adminService) scheduleExporter(
+	ctx context.Context,
+	in *structpb.Struct,
+	update func(exporter *jumpstarterdevv1alpha1.Exporter),
+) (*structpb.Struct, error) {
+	var req api.ExporterRequest
+	if err := decodeStruct(in, &req); err != nil {
+		return nil, err
+	}
+	exporter, err := a.exporter(ctx, "patch", req.Namespace, req.Exporter)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.patchExporterSpec(ctx, exporter, update); err != nil {
+		return nil, err
+	}
+	return encodeStruct(exporterSchedulingResponse(exporter))
+}
+
+// patchExporterSpec applies update to the scheduling of exporter, the exporter is not written if unchanged
+func (a adminService) patchExporterSpec(
+	ctx context.Context,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+	update func(exporter *jumpstarterdevv1alpha1.Exporter),
+) error {
+	if err := a.s.retryWrite(ctx, exporter, func() error {
+		original := exporter.DeepCopy()
+		update(exporter)
+		if exporter.Spec.Unschedulable == original.Spec.Unschedulable && exporter.Spec.Drain == original.Spec.Drain {
+			return nil
+		}
+		return a.s.Client.Patch(ctx, exporter, client.MergeFrom(original))
+	}); err != nil {
+		return adminWriteError(ctx, err, "unable to update exporter")
+	}
+	log.FromContext(ctx).Info("updated exporter scheduling", "exporter", client.ObjectKeyFromObject(exporter),
+		"unschedulable", exporter.Spec.Unschedulable, "drain", exporter.Spec.Drain)
+	return nil
+}
+
+// releaseExporterLeases releases the active leases holding exporter, returning their names
+func (a adminService) releaseExporterLeases(
+	ctx context.Context,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+) ([]string, error) {
+	var leases jumpstarterdevv1alpha1.LeaseList
+	if err := a.s.Client.List(ctx, &leases,
+		client.InNamespace(exporter.Namespace),
+		controller.MatchingActiveLeases(),
+	); err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to list leases: %s", err)
+	}
+
+	var released []string
+	for i := range leases.Items {
+		lease := &leases.Items[i]
+		if lease.Status.Ended || !controller.LeaseHoldsExporter(lease, exporter.Name) {
+			continue
+		}
+		if err := a.s.retryWrite(ctx, lease, func() error {
+			if lease.Spec.Release {
+				return nil
+			}
+			original := client.MergeFrom(lease.DeepCopy())
+			lease.Spec.Release = true
+			return a.s.Client.Patch(ctx, lease, original)
+		}); err != nil {
+			return nil, adminWriteError(ctx, err, "unable to release lease")
+		}
+		released = append(released, lease.Name)
+	}
+	return released, nil
+}
+
+// exporterSchedulingResponse returns whether exporter is cordoned or drained
+func exporterSchedulingResponse(exporter *jumpstarterdevv1alpha1.Exporter) api.ExporterSchedulingResponse {
+	return api.ExporterSchedulingResponse{
+		Unschedulable: exporter.Spec.Unschedulable,
+		Drain:         exporter.Spec.Drain,
+		Drained:       exporterDrained(exporter),
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/pkg/api"
+)
+
+var _ = Describe("Admin service cordons", func() {
+	var s *ControllerService
+	var allowed bool
+
+	BeforeEach(func() {
+		allowed = true
+		exporter := &jumpstarterdevv1alpha1.Exporter{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "exporter",
+		}}
+		s = newAdminTestService(&allowed, exporter, newTestLease("lease", "client", "exporter"),
+			newTestLease("other", "client", "other-exporter"))
+	})
+
+	// call calls method of the admin service with req
+	call := func(method func(context.Context, *structpb.Struct) (*structpb.Struct, error),
+		req map[string]any) (*api.ExporterSchedulingResponse, error) {
+		in, err := structpb.NewStruct(req)
+		Expect(err).NotTo(HaveOccurred())
+		out, err := method(adminContext, in)
+		if err != nil {
+			return nil, err
+		}
+		var response api.ExporterSchedulingResponse
+		Expect(decodeStruct(out, &response)).To(Succeed())
+		return &response, nil
+	}
+
+	// get returns the stored object named name
+	get := func(obj client.Object, name string) {
+		Expect(s.Client.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: name}, obj)).
+			To(Succeed())
+	}
+
+	exporterRequest := map[string]any{"namespace": "default", "exporter": "exporter"}
+
+	It("should require the permission to patch the exporter", func() {
+		allowed = false
+		_, err := call(adminService{s}.CordonExporter, exporterRequest)
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+	})
+
+	It("should cordon and uncordon an exporter", func() {
+		response, err := call(adminService{s}.CordonExporter, exporterRequest)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.Unschedulable).To(BeTrue())
+
+		var exporter jumpstarterdevv1alpha1.Exporter
+		get(&exporter, "exporter")
+		Expect(exporter.Spec.Unschedulable).To(BeTrue())
+
+		_, err = call(adminService{s}.DrainExporter, exporterRequest)
+		Expect(err).NotTo(HaveOccurred())
+		response, err = call(adminService{s}.UncordonExporter, exporterRequest)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.Unschedulable).To(BeFalse())
+		Expect(response.Drain).To(BeFalse())
+
+		get(&exporter, "exporter")
+		Expect(exporter.Spec.Unschedulable).To(BeFalse())
+		Expect(exporter.Spec.Drain).To(BeFalse())
+	})
+
+	It("should drain an exporter without releasing its leases", func() {
+		response, err := call(adminService{s}.DrainExporter, exporterRequest)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.Drain).To(BeTrue())
+		Expect(response.ReleasedLeases).To(BeEmpty())
+
+		var lease jumpstarterdevv1alpha1.Lease
+		get(&lease, "lease")
+		Expect(lease.Spec.Release).To(BeFalse())
+	})
+
+	It("should release the active leases of a drained exporter on request", func() {
+		response, err := call(adminService{s}.DrainExporter, map[string]any{
+			"namespace": "default", "exporter": "exporter", "release": true,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.ReleasedLeases).To(Equal([]string{"lease"}))
+
+		var lease jumpstarterdevv1alpha1.Lease
+		get(&lease, "lease")
+		Expect(lease.Spec.Release).To(BeTrue())
+		get(&lease, "other")
+		Expect(lease.Spec.Release).To(BeFalse())
+	})
+})
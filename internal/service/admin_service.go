@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/internal/controller"
+	"github.com/jumpstarter-dev/jumpstarter-controller/pkg/api"
+)
+
+type adminServer interface {
+	ListExporterLabelHistory(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	RestoreExporterLabels(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	UnpinExporterLabels(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	CordonExporter(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	UncordonExporter(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	DrainExporter(context.Context, *structpb.Struct) (*structpb.Struct, error)
+}
+
+var adminServiceDesc = grpc.ServiceDesc{
+	ServiceName: api.AdminServiceName,
+	HandlerType: (*adminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		structMethod(api.AdminServiceName, "ListExporterLabelHistory", adminServer.ListExporterLabelHistory),
+		structMethod(api.AdminServiceName, "RestoreExporterLabels", adminServer.RestoreExporterLabels),
+		structMethod(api.AdminServiceName, "UnpinExporterLabels", adminServer.UnpinExporterLabels),
+		structMethod(api.AdminServiceName, "CordonExporter", adminServer.CordonExporter),
+		structMethod(api.AdminServiceName, "UncordonExporter", adminServer.UncordonExporter),
+		structMethod(api.AdminServiceName, "DrainExporter", adminServer.DrainExporter),
+	},
+	Metadata: "admin",
+}
+
+// adminService serves the AdminService of s to the cluster users, authenticated by their Kubernetes
+// bearer token and authorized on the exporters they act on like the console API
+type adminService struct {
+	s *ControllerService
+}
+
+// authorize authenticates the caller by its bearer token with a TokenReview, and fails with
+// PERMISSION_DENIED unless it may verb the jumpstarter resource named name in namespace, all of
+// them if name is empty
+func (a adminService) authorize(
+	ctx context.Context,
+	verb string,
+	resource string,
+	namespace string,
+	name string,
+) error {
+	token, err := BearerTokenFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	review := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}
+	if err := a.s.Client.Create(ctx, review); err != nil {
+		log.FromContext(ctx).Error(err, "unable to review bearer token")
+		return status.Errorf(codes.Internal, "unable to review bearer token")
+	}
+	if !review.Status.Authenticated {
+		return status.Errorf(codes.Unauthenticated, "invalid bearer token")
+	}
+
+	user := review.Status.User
+	extra := map[string]authorizationv1.ExtraValue{}
+	for key, values := range user.Extra {
+		extra[key] = authorizationv1.ExtraValue(values)
+	}
+	access := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			UID:    user.UID,
+			Groups: user.Groups,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      verb,
+				Group:     jumpstarterdevv1alpha1.GroupVersion.Group,
+				Resource:  resource,
+				Name:      name,
+			},
+		},
+	}
+	if err := a.s.Client.Create(ctx, access); err != nil {
+		log.FromContext(ctx).Error(err, "unable to review access")
+		return status.Errorf(codes.Internal, "unable to review access")
+	}
+	if !access.Status.Allowed {
+		return status.Errorf(codes.PermissionDenied, "%s may not %s %s in namespace %s",
+			user.Username, verb, resource, namespace)
+	}
+	return nil
+}
+
+// exporter authorizes the caller to verb the exporter named name in namespace, and returns it
+func (a adminService) exporter(
+	ctx context.Context,
+	verb string,
+	namespace string,
+	name string,
+) (*jumpstarterdevv1alpha1.Exporter, error) {
+	if namespace == "" || name == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "empty exporter namespace or name")
+	}
+	if err := a.authorize(ctx, verb, "exporters", namespace, name); err != nil {
+		return nil, err
+	}
+
+	var exporter jumpstarterdevv1alpha1.Exporter
+	if err := a.s.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &exporter); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, status.Errorf(codes.NotFound, "exporter %s/%s not found", namespace, name)
+		}
+		return nil, status.Errorf(codes.Internal, "unable to get exporter: %s", err)
+	}
+	return &exporter, nil
+}
+
+// ListExporterLabelHistory returns the label history of an exporter
+func (a adminService) ListExporterLabelHistory(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	var req api.ExporterRequest
+	if err := decodeStruct(in, &req); err != nil {
+		return nil, err
+	}
+	exporter, err := a.exporter(ctx, "get", req.Namespace, req.Exporter)
+	if err != nil {
+		return nil, err
+	}
+	return encodeStruct(exporterLabelsResponse(exporter))
+}
+
+// RestoreExporterLabels restores the jumpstarter.dev/ labels of an exporter to a revision of its label
+// history and pins them, its registrations keep them until they are unpinned
+func (a adminService) RestoreExporterLabels(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	var req api.RestoreExporterLabelsRequest
+	if err := decodeStruct(in, &req); err != nil {
+		return nil, err
+	}
+	exporter, err := a.exporter(ctx, "patch", req.Namespace, req.Exporter)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.s.retryWrite(ctx, exporter, func() error {
+		original := client.MergeFrom(exporter.DeepCopy())
+		if err := controller.RestoreExporterLabels(exporter, req.Revision); err != nil {
+			return status.Errorf(codes.NotFound, "%s", err)
+		}
+		return a.s.Client.Patch(ctx, exporter, original)
+	}); err != nil {
+		return nil, adminWriteError(ctx, err, "unable to restore exporter labels")
+	}
+
+	if err := a.s.retryWrite(ctx, exporter, func() error {
+		original := client.MergeFrom(exporter.DeepCopy())
+		controller.RecordExporterLabels(exporter, controller.LabelSourceRestore)
+		return a.s.Client.Status().Patch(ctx, exporter, original)
+	}); err != nil {
+		return nil, adminWriteError(ctx, err, "unable to record exporter labels")
+	}
+
+	log.FromContext(ctx).Info("restored exporter labels", "exporter", client.ObjectKeyFromObject(exporter),
+		"revision", req.Revision)
+	return encodeStruct(exporterLabelsResponse(exporter))
+}
+
+// UnpinExporterLabels unpins the labels of an exporter, its next registration applies its own labels
+func (a adminService) UnpinExporterLabels(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	var req api.ExporterRequest
+	if err := decodeStruct(in, &req); err != nil {
+		return nil, err
+	}
+	exporter, err := a.exporter(ctx, "patch", req.Namespace, req.Exporter)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.s.retryWrite(ctx, exporter, func() error {
+		if _, pinned := controller.PinnedLabelRevision(exporter); !pinned {
+			return nil
+		}
+		original := client.MergeFrom(exporter.DeepCopy())
+		controller.UnpinExporterLabels(exporter)
+		return a.s.Client.Patch(ctx, exporter, original)
+	}); err != nil {
+		return nil, adminWriteError(ctx, err, "unable to unpin exporter labels")
+	}
+	return encodeStruct(exporterLabelsResponse(exporter))
+}
+
+// adminWriteError returns the status error of a failed write, logging the other errors as message
+func adminWriteError(ctx context.Context, err error, message string) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	log.FromContext(ctx).Error(err, message)
+	return status.Errorf(codes.Internal, "%s", message)
+}
+
+// exporterLabelsResponse returns the labels and the label history of exporter
+func exporterLabelsResponse(exporter *jumpstarterdevv1alpha1.Exporter) api.ExporterLabelsResponse {
+	response := api.ExporterLabelsResponse{
+		Labels:    exporter.Labels,
+		Revisions: []api.LabelSetRevision{},
+	}
+	for _, revision := range exporter.Status.LabelHistory {
+		response.Revisions = append(response.Revisions, api.LabelSetRevision{
+			Revision: revision.Revision,
+			Labels:   revision.Labels,
+			Time:     revision.Time,
+			Source:   revision.Source,
+		})
+	}
+	response.PinnedRevision, response.Pinned = controller.PinnedLabelRevision(exporter)
+	return response
+}
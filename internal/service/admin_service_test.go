@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	pb "github.com/jumpstarter-dev/jumpstarter-controller/internal/protocol/jumpstarter/v1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/pkg/api"
+)
+
+// newAdminTestService returns a ControllerService on a fake API server holding objects, authenticating
+// the token admin-token and authorizing it as allowed
+func newAdminTestService(allowed *bool, objects ...client.Object) *ControllerService {
+	s := newTestService(objects...)
+	s.Client = interceptor.NewClient(s.Client, interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			switch review := obj.(type) {
+			case *authenticationv1.TokenReview:
+				review.Status.Authenticated = review.Spec.Token == "admin-token"
+				review.Status.User = authenticationv1.UserInfo{Username: "admin"}
+				return nil
+			case *authorizationv1.SubjectAccessReview:
+				review.Status.Allowed = *allowed
+				return nil
+			}
+			return c.Create(ctx, obj, opts...)
+		},
+		// the fake client does not support server-side apply, the status applied by Register is dropped
+		SubResourcePatch: func(
+			ctx context.Context,
+			c client.Client,
+			subResourceName string,
+			obj client.Object,
+			patch client.Patch,
+			opts ...client.SubResourcePatchOption,
+		) error {
+			if patch.Type() == types.ApplyPatchType {
+				return nil
+			}
+			return c.SubResource(subResourceName).Patch(ctx, obj, patch, opts...)
+		},
+	})
+	return s
+}
+
+// adminContext authenticates with the token admin-token
+var adminContext = metadata.NewIncomingContext(context.Background(),
+	metadata.Pairs("authorization", "Bearer admin-token"))
+
+var _ = Describe("Admin service", func() {
+	var s *ControllerService
+	var allowed bool
+
+	BeforeEach(func() {
+		allowed = true
+		exporter := &jumpstarterdevv1alpha1.Exporter{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      "exporter",
+				UID:       "exporter-uid",
+				Labels:    map[string]string{"jumpstarter.dev/board": "rpi5"},
+			},
+			Status: jumpstarterdevv1alpha1.ExporterStatus{
+				LabelHistory: []jumpstarterdevv1alpha1.LabelSetRevision{{
+					Revision: 1,
+					Labels:   map[string]string{"jumpstarter.dev/board": "rpi4"},
+					Time:     metav1.Time{Time: time.Now().Add(-time.Hour)},
+					Source:   "Register",
+				}, {
+					Revision: 2,
+					Labels:   map[string]string{"jumpstarter.dev/board": "rpi5"},
+					Time:     metav1.Time{Time: time.Now()},
+					Source:   "Register",
+				}},
+			},
+		}
+		s = newAdminTestService(&allowed, exporter)
+	})
+
+	// call calls method of the admin service with req as ctx
+	call := func(ctx context.Context, method func(context.Context, *structpb.Struct) (*structpb.Struct, error),
+		req map[string]any) (*api.ExporterLabelsResponse, error) {
+		in, err := structpb.NewStruct(req)
+		Expect(err).NotTo(HaveOccurred())
+		out, err := method(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		var response api.ExporterLabelsResponse
+		Expect(decodeStruct(out, &response)).To(Succeed())
+		return &response, nil
+	}
+
+	// register registers the exporter with labels and returns its stored labels
+	register := func(labels map[string]string) map[string]string {
+		var exporter jumpstarterdevv1alpha1.Exporter
+		Expect(s.Client.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "exporter"},
+			&exporter)).To(Succeed())
+		_, err := s.Register(tokenContext(context.Background(), s, &exporter), &pb.RegisterRequest{Labels: labels})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.Client.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "exporter"},
+			&exporter)).To(Succeed())
+		return exporter.Labels
+	}
+
+	exporterRequest := map[string]any{"namespace": "default", "exporter": "exporter"}
+
+	It("should authenticate and authorize the caller", func() {
+		anonymous := metadata.NewIncomingContext(context.Background(), metadata.MD{})
+		_, err := call(anonymous, adminService{s}.ListExporterLabelHistory, exporterRequest)
+		Expect(status.Code(err)).To(Equal(codes.Unauthenticated))
+
+		invalid := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer other"))
+		_, err = call(invalid, adminService{s}.ListExporterLabelHistory, exporterRequest)
+		Expect(status.Code(err)).To(Equal(codes.Unauthenticated))
+
+		allowed = false
+		_, err = call(adminContext, adminService{s}.ListExporterLabelHistory, exporterRequest)
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+	})
+
+	It("should list the label history of an exporter", func() {
+		response, err := call(adminContext, adminService{s}.ListExporterLabelHistory, exporterRequest)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.Revisions).To(HaveLen(2))
+		Expect(response.Pinned).To(BeFalse())
+	})
+
+	It("should fail to restore a revision missing from the label history", func() {
+		_, err := call(adminContext, adminService{s}.RestoreExporterLabels, map[string]any{
+			"namespace": "default", "exporter": "exporter", "revision": 7,
+		})
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+	})
+
+	It("should keep the restored labels across registrations until unpinned", func() {
+		response, err := call(adminContext, adminService{s}.RestoreExporterLabels, map[string]any{
+			"namespace": "default", "exporter": "exporter", "revision": 1,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.Labels).To(HaveKeyWithValue("jumpstarter.dev/board", "rpi4"))
+		Expect(response.Pinned).To(BeTrue())
+		Expect(response.PinnedRevision).To(Equal(int64(1)))
+		Expect(response.Revisions).To(HaveLen(3))
+		Expect(response.Revisions[2].Source).To(Equal("Restore"))
+
+		Expect(register(map[string]string{"jumpstarter.dev/board": "rpi5"})).
+			To(HaveKeyWithValue("jumpstarter.dev/board", "rpi4"))
+
+		response, err = call(adminContext, adminService{s}.UnpinExporterLabels, exporterRequest)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.Pinned).To(BeFalse())
+
+		Expect(register(map[string]string{"jumpstarter.dev/board": "rpi5"})).
+			To(HaveKeyWithValue("jumpstarter.dev/board", "rpi5"))
+	})
+})
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/jumpstarter-dev/jumpstarter-controller/pkg/interceptors"
+)
+
+var unauthenticatedCallsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "jumpstarter_unauthenticated_calls_total",
+		Help: "Number of calls to the controller service without credentials, by method and outcome, " +
+			"exempt or rejected",
+	},
+	[]string{"method", "outcome"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(unauthenticatedCallsTotal)
+}
+
+// AuthExemptions are the gRPC methods served without credentials, as full method names, e.g.
+// /grpc.health.v1.Health/Check, or as every method of a service, e.g. /grpc.health.v1.Health/*.
+// It implements flag.Value, parsing a comma separated list of methods
+type AuthExemptions []string
+
+// DefaultAuthExemptions are the health and the reflection services
+var DefaultAuthExemptions = AuthExemptions{
+	"/grpc.health.v1.Health/*",
+	"/grpc.reflection.v1.ServerReflection/*",
+	"/grpc.reflection.v1alpha.ServerReflection/*",
+}
+
+func (e *AuthExemptions) String() string {
+	if e == nil {
+		return ""
+	}
+	return strings.Join(*e, ",")
+}
+
+func (e *AuthExemptions) Set(value string) error {
+	exemptions := AuthExemptions{}
+	for _, method := range strings.Split(value, ",") {
+		method = strings.TrimSpace(method)
+		if method == "" {
+			continue
+		}
+		if err := interceptors.ValidateMethodPattern(method); err != nil {
+			return err
+		}
+		exemptions = append(exemptions, method)
+	}
+	*e = exemptions
+	return nil
+}
+
+// exempt reports whether the method fullMethod is served without credentials
+func (e AuthExemptions) exempt(fullMethod string) bool {
+	return interceptors.MatchesMethod(e, fullMethod)
+}
+
+// interceptors reject the calls without credentials, bearer tokens or client certificates, to the
+// methods not exempted, the handlers still verify the credentials of the calls they receive
+func (e AuthExemptions) interceptors() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(
+			ctx context.Context,
+			req any,
+			info *grpc.UnaryServerInfo,
+			handler grpc.UnaryHandler,
+		) (any, error) {
+			if err := e.authorize(ctx, info.FullMethod); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(
+			srv any,
+			ss grpc.ServerStream,
+			info *grpc.StreamServerInfo,
+			handler grpc.StreamHandler,
+		) error {
+			if err := e.authorize(ss.Context(), info.FullMethod); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	}
+}
+
+// authorize returns UNAUTHENTICATED if the call of ctx to fullMethod carries no credentials and
+// the method is not exempted
+func (e AuthExemptions) authorize(ctx context.Context, fullMethod string) error {
+	if hasCredentials(ctx) {
+		return nil
+	}
+	if e.exempt(fullMethod) {
+		unauthenticatedCallsTotal.WithLabelValues(fullMethod, "exempt").Inc()
+		return nil
+	}
+	unauthenticatedCallsTotal.WithLabelValues(fullMethod, "rejected").Inc()
+	return status.Errorf(codes.Unauthenticated, "%s requires authentication", fullMethod)
+}
+
+// hasCredentials reports whether the call of ctx carries an authorization header or a client certificate
+func hasCredentials(ctx context.Context) bool {
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("authorization")) > 0 {
+		return true
+	}
+	return peerCertificate(ctx) != nil
+}
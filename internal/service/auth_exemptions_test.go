@@ -0,0 +1,59 @@
+package service
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AuthExemptions", func() {
+	Describe("Set", func() {
+		It("should parse a comma separated list of methods", func() {
+			var exemptions AuthExemptions
+			Expect(exemptions.Set(
+				" /grpc.health.v1.Health/* ,,/jumpstarter.v1.ControllerService/ListExporters",
+			)).To(Succeed())
+			Expect(exemptions).To(Equal(AuthExemptions{
+				"/grpc.health.v1.Health/*",
+				"/jumpstarter.v1.ControllerService/ListExporters",
+			}))
+		})
+
+		It("should replace the default exemptions", func() {
+			exemptions := DefaultAuthExemptions
+			Expect(exemptions.Set("")).To(Succeed())
+			Expect(exemptions).To(BeEmpty())
+			Expect(DefaultAuthExemptions).NotTo(BeEmpty())
+		})
+
+		DescribeTable("should reject invalid methods",
+			func(method string) {
+				var exemptions AuthExemptions
+				Expect(exemptions.Set(method)).NotTo(Succeed())
+			},
+			Entry("without leading slash", "grpc.health.v1.Health/Check"),
+			Entry("without method", "/grpc.health.v1.Health"),
+			Entry("with empty method", "/grpc.health.v1.Health/"),
+			Entry("with empty service", "//Check"),
+			Entry("with nested method", "/grpc.health.v1.Health/Check/More"),
+		)
+	})
+
+	DescribeTable("exempt",
+		func(exemptions AuthExemptions, method string, exempt bool) {
+			Expect(exemptions.exempt(method)).To(Equal(exempt))
+		},
+		Entry("full method", AuthExemptions{"/grpc.health.v1.Health/Check"},
+			"/grpc.health.v1.Health/Check", true),
+		Entry("other method of the service", AuthExemptions{"/grpc.health.v1.Health/Check"},
+			"/grpc.health.v1.Health/Watch", false),
+		Entry("every method of the service", AuthExemptions{"/grpc.health.v1.Health/*"},
+			"/grpc.health.v1.Health/Watch", true),
+		Entry("service sharing a prefix", AuthExemptions{"/grpc.health.v1.Health/*"},
+			"/grpc.health.v1.HealthCheck/Check", false),
+		Entry("default reflection", DefaultAuthExemptions,
+			"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo", true),
+		Entry("default controller service", DefaultAuthExemptions,
+			"/jumpstarter.v1.ControllerService/ListExporters", false),
+		Entry("no exemptions", AuthExemptions{}, "/grpc.health.v1.Health/Check", false),
+	)
+})
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/jumpstarter-dev/jumpstarter-controller/internal/controller"
+)
+
+// CertificateAuthMode is whether the gRPC listeners require client certificates
+type CertificateAuthMode string
+
+const (
+	// CertificateAuthOptional authenticates the peers presenting a certificate with it,
+	// and the others with their bearer token
+	CertificateAuthOptional CertificateAuthMode = "Optional"
+	// CertificateAuthRequired rejects the connections without a valid client certificate
+	CertificateAuthRequired CertificateAuthMode = "Required"
+)
+
+// CertificateIdentityRule maps the client certificates with a matching SAN to a Client or an Exporter,
+// in the patterns {namespace} and {name} match the namespace and the name of the object
+type CertificateIdentityRule struct {
+	// The kind of the object identified, Client or Exporter
+	Kind string `json:"kind"`
+	// Pattern matched against the URI SANs, e.g. spiffe://lab.example.com/ns/{namespace}/exporter/{name}
+	URI string `json:"uri,omitempty"`
+	// Pattern matched against the DNS SANs, e.g. {name}.{namespace}.clients.lab.example.com
+	DNS string `json:"dns,omitempty"`
+	// The namespace of the objects, for patterns without {namespace}
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// CertificateAuthConfig is the configuration file of the client certificate authentication
+type CertificateAuthConfig struct {
+	// Defaults to Optional
+	Mode CertificateAuthMode `json:"mode,omitempty"`
+	// PEM bundle of the CAs client certificates are verified against
+	ClientCAFile string `json:"clientCAFile"`
+	// The first matching rule identifies the peer
+	Rules []CertificateIdentityRule `json:"rules"`
+}
+
+// CertificateAuth authenticates clients and exporters by their TLS client certificates
+type CertificateAuth struct {
+	Mode  CertificateAuthMode
+	pool  *x509.CertPool
+	rules []certificateIdentityMatcher
+}
+
+type certificateIdentityMatcher struct {
+	kind      string
+	uri       *regexp.Regexp
+	dns       *regexp.Regexp
+	namespace string
+}
+
+// LoadCertificateAuth reads a CertificateAuthConfig from path
+func LoadCertificateAuth(path string) (*CertificateAuth, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("LoadCertificateAuth: %w", err)
+	}
+	var config CertificateAuthConfig
+	if err := yaml.UnmarshalStrict(content, &config); err != nil {
+		return nil, fmt.Errorf("LoadCertificateAuth: invalid configuration: %w", err)
+	}
+	return NewCertificateAuth(config)
+}
+
+// NewCertificateAuth validates config and loads its client CAs
+func NewCertificateAuth(config CertificateAuthConfig) (*CertificateAuth, error) {
+	auth := &CertificateAuth{Mode: config.Mode, pool: x509.NewCertPool()}
+	switch auth.Mode {
+	case "":
+		auth.Mode = CertificateAuthOptional
+	case CertificateAuthOptional, CertificateAuthRequired:
+	default:
+		return nil, fmt.Errorf("NewCertificateAuth: unknown mode %s, expected %s or %s",
+			config.Mode, CertificateAuthOptional, CertificateAuthRequired)
+	}
+
+	bundle, err := os.ReadFile(config.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("NewCertificateAuth: %w", err)
+	}
+	if !auth.pool.AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("NewCertificateAuth: no certificate found in %s", config.ClientCAFile)
+	}
+
+	for i, rule := range config.Rules {
+		if rule.Kind != "Client" && rule.Kind != "Exporter" {
+			return nil, fmt.Errorf("NewCertificateAuth: rule %d: unknown kind %s, expected Client or Exporter", i, rule.Kind)
+		}
+		if (rule.URI == "") == (rule.DNS == "") {
+			return nil, fmt.Errorf("NewCertificateAuth: rule %d: exactly one of uri and dns is required", i)
+		}
+		matcher := certificateIdentityMatcher{kind: rule.Kind, namespace: rule.Namespace}
+		pattern := rule.URI + rule.DNS
+		compiled, err := compileIdentityPattern(pattern, rule.Namespace != "")
+		if err != nil {
+			return nil, fmt.Errorf("NewCertificateAuth: rule %d: %w", i, err)
+		}
+		if rule.URI != "" {
+			matcher.uri = compiled
+		} else {
+			matcher.dns = compiled
+		}
+		auth.rules = append(auth.rules, matcher)
+	}
+	return auth, nil
+}
+
+// compileIdentityPattern turns a pattern into an anchored regular expression capturing {namespace} and {name}
+func compileIdentityPattern(pattern string, fixedNamespace bool) (*regexp.Regexp, error) {
+	if strings.Count(pattern, "{name}") != 1 {
+		return nil, fmt.Errorf("pattern %s must contain {name} once", pattern)
+	}
+	namespaces := strings.Count(pattern, "{namespace}")
+	if namespaces > 1 || namespaces == 0 && !fixedNamespace || namespaces == 1 && fixedNamespace {
+		return nil, fmt.Errorf("pattern %s must contain {namespace} once, unless the rule sets namespace", pattern)
+	}
+
+	expression := regexp.QuoteMeta(pattern)
+	expression = strings.Replace(expression, regexp.QuoteMeta("{name}"),
+		`(?P<name>[a-z0-9]([-a-z0-9.]*[a-z0-9])?)`, 1)
+	expression = strings.Replace(expression, regexp.QuoteMeta("{namespace}"),
+		`(?P<namespace>[a-z0-9]([-a-z0-9]*[a-z0-9])?)`, 1)
+	return regexp.Compile("^" + expression + "$")
+}
+
+// identify returns the object of kind the certificate maps to, false if no rule matches
+func (a *CertificateAuth) identify(certificate *x509.Certificate, kind string) (types.NamespacedName, bool) {
+	for _, rule := range a.rules {
+		if rule.kind != kind {
+			continue
+		}
+		var sans []string
+		pattern := rule.dns
+		if rule.uri != nil {
+			pattern = rule.uri
+			for _, uri := range certificate.URIs {
+				sans = append(sans, uri.String())
+			}
+		} else {
+			sans = certificate.DNSNames
+		}
+		for _, san := range sans {
+			match := pattern.FindStringSubmatch(san)
+			if match == nil {
+				continue
+			}
+			key := types.NamespacedName{Namespace: rule.namespace}
+			for i, group := range pattern.SubexpNames() {
+				switch group {
+				case "name":
+					key.Name = match[i]
+				case "namespace":
+					key.Namespace = match[i]
+				}
+			}
+			return key, true
+		}
+	}
+	return types.NamespacedName{}, false
+}
+
+// tlsConfig returns the server TLS configuration serving cert and verifying the client certificates
+func (a *CertificateAuth) tlsConfig(cert *tls.Certificate) *tls.Config {
+	config := &tls.Config{
+		Certificates: []tls.Certificate{*cert},
+		ClientCAs:    a.pool,
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+	}
+	if a.Mode == CertificateAuthRequired {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config
+}
+
+// serverCredentials returns the transport credentials of a listener serving cert,
+// verifying client certificates if auth is set
+func serverCredentials(auth *CertificateAuth, cert *tls.Certificate) credentials.TransportCredentials {
+	if auth == nil {
+		return credentials.NewServerTLSFromCert(cert)
+	}
+	return credentials.NewTLS(auth.tlsConfig(cert))
+}
+
+// peerCertificate returns the verified client certificate of the peer of ctx, nil if it presented none
+func peerCertificate(ctx context.Context) *x509.Certificate {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return info.State.VerifiedChains[0][0]
+}
+
+// authenticateCertificate returns the object of kind identified by the client certificate of the peer
+// of ctx, nil if auth is not set or the peer presented no certificate and may use a bearer token instead
+func authenticateCertificate[T any, PT controller.Object[T]](
+	ctx context.Context,
+	auth *CertificateAuth,
+	c client.Client,
+	kind string,
+) (*T, error) {
+	if auth == nil {
+		return nil, nil
+	}
+	certificate := peerCertificate(ctx)
+	if certificate == nil {
+		if auth.Mode == CertificateAuthRequired {
+			return nil, status.Errorf(codes.Unauthenticated, "client certificate required")
+		}
+		return nil, nil
+	}
+
+	key, ok := auth.identify(certificate, kind)
+	if !ok {
+		return nil, status.Errorf(codes.PermissionDenied, "client certificate does not identify a %s", kind)
+	}
+
+	var object T
+	if err := c.Get(ctx, key, PT(&object)); err != nil {
+		return nil, status.Errorf(codes.PermissionDenied, "unknown %s %s", kind, key)
+	}
+	return &object, nil
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+var _ = Describe("Certificate authentication", func() {
+	DescribeTable("compileIdentityPattern",
+		func(pattern string, fixedNamespace bool, san string, expected *types.NamespacedName) {
+			compiled, err := compileIdentityPattern(pattern, fixedNamespace)
+			Expect(err).NotTo(HaveOccurred())
+			match := compiled.FindStringSubmatch(san)
+			if expected == nil {
+				Expect(match).To(BeNil())
+				return
+			}
+			Expect(match).NotTo(BeNil())
+			Expect(match[compiled.SubexpIndex("name")]).To(Equal(expected.Name))
+			if expected.Namespace != "" {
+				Expect(match[compiled.SubexpIndex("namespace")]).To(Equal(expected.Namespace))
+			}
+		},
+		Entry("URI with namespace", "spiffe://lab.example.com/ns/{namespace}/exporter/{name}", false,
+			"spiffe://lab.example.com/ns/lab/exporter/board-1", &types.NamespacedName{Namespace: "lab", Name: "board-1"}),
+		Entry("DNS with namespace", "{name}.{namespace}.clients.lab.example.com", false,
+			"ci.lab.clients.lab.example.com", &types.NamespacedName{Namespace: "lab", Name: "ci"}),
+		Entry("fixed namespace", "{name}.clients.lab.example.com", true,
+			"ci.clients.lab.example.com", &types.NamespacedName{Name: "ci"}),
+		Entry("anchored at the start", "{name}.clients.lab.example.com", true,
+			"evil.example.com/ci.clients.lab.example.com", nil),
+		Entry("anchored at the end", "spiffe://lab.example.com/exporter/{name}", true,
+			"spiffe://lab.example.com/exporter/board-1/extra", nil),
+		Entry("dots quoted", "{name}.clients.lab.example.com", true,
+			"ci.clientsXlab.example.com", nil),
+		Entry("namespace without dots", "{name}.{namespace}.clients.lab.example.com", false,
+			"ci.a.b.clients.lab.example.com", &types.NamespacedName{Namespace: "b", Name: "ci.a"}),
+		Entry("uppercase names", "{name}.clients.lab.example.com", true,
+			"CI.clients.lab.example.com", nil),
+	)
+
+	DescribeTable("compileIdentityPattern should reject",
+		func(pattern string, fixedNamespace bool) {
+			_, err := compileIdentityPattern(pattern, fixedNamespace)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("no name", "{namespace}.clients.lab.example.com", false),
+		Entry("two names", "{name}.{name}.{namespace}.example.com", false),
+		Entry("no namespace without a fixed namespace", "{name}.clients.lab.example.com", false),
+		Entry("namespace together with a fixed namespace", "{name}.{namespace}.example.com", true),
+		Entry("two namespaces", "{name}.{namespace}.{namespace}.example.com", false),
+	)
+
+	Describe("identify", func() {
+		mustCompile := func(pattern string, fixedNamespace bool) certificateIdentityMatcher {
+			compiled, err := compileIdentityPattern(pattern, fixedNamespace)
+			Expect(err).NotTo(HaveOccurred())
+			return certificateIdentityMatcher{dns: compiled}
+		}
+		newAuth := func() *CertificateAuth {
+			exporters := mustCompile("{name}.{namespace}.exporters.lab.example.com", false)
+			exporters.kind = "Exporter"
+			fixed := mustCompile("{name}.clients.lab.example.com", true)
+			fixed.kind, fixed.namespace = "Client", "ci"
+			spiffe, err := compileIdentityPattern("spiffe://lab.example.com/ns/{namespace}/client/{name}", false)
+			Expect(err).NotTo(HaveOccurred())
+			return &CertificateAuth{rules: []certificateIdentityMatcher{
+				exporters,
+				fixed,
+				{kind: "Client", uri: spiffe},
+			}}
+		}
+
+		It("should use the first rule of the kind matching any SAN", func() {
+			certificate := &x509.Certificate{DNSNames: []string{
+				"unrelated.example.com",
+				"board-1.lab.exporters.lab.example.com",
+			}}
+			key, ok := newAuth().identify(certificate, "Exporter")
+			Expect(ok).To(BeTrue())
+			Expect(key).To(Equal(types.NamespacedName{Namespace: "lab", Name: "board-1"}))
+
+			_, ok = newAuth().identify(certificate, "Client")
+			Expect(ok).To(BeFalse())
+		})
+
+		It("should use the namespace of the rule for patterns without {namespace}", func() {
+			certificate := &x509.Certificate{DNSNames: []string{"runner.clients.lab.example.com"}}
+			key, ok := newAuth().identify(certificate, "Client")
+			Expect(ok).To(BeTrue())
+			Expect(key).To(Equal(types.NamespacedName{Namespace: "ci", Name: "runner"}))
+		})
+
+		It("should match the URI rules against the URI SANs only", func() {
+			uri, err := url.Parse("spiffe://lab.example.com/ns/dev/client/alice")
+			Expect(err).NotTo(HaveOccurred())
+			certificate := &x509.Certificate{URIs: []*url.URL{uri}}
+			key, ok := newAuth().identify(certificate, "Client")
+			Expect(ok).To(BeTrue())
+			Expect(key).To(Equal(types.NamespacedName{Namespace: "dev", Name: "alice"}))
+
+			certificate = &x509.Certificate{DNSNames: []string{"spiffe://lab.example.com/ns/dev/client/alice"}}
+			_, ok = newAuth().identify(certificate, "Client")
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("authenticateCertificate", func() {
+		var kube *fake.ClientBuilder
+
+		BeforeEach(func() {
+			scheme := runtime.NewScheme()
+			Expect(jumpstarterdevv1alpha1.AddToScheme(scheme)).To(Succeed())
+			kube = fake.NewClientBuilder().WithScheme(scheme).WithObjects(&jumpstarterdevv1alpha1.Client{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ci", Name: "runner"},
+			})
+		})
+
+		withCertificate := func(certificate *x509.Certificate) context.Context {
+			return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{
+				State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{certificate}}},
+			}})
+		}
+		newAuth := func(mode CertificateAuthMode) *CertificateAuth {
+			compiled, err := compileIdentityPattern("{name}.clients.lab.example.com", true)
+			Expect(err).NotTo(HaveOccurred())
+			return &CertificateAuth{Mode: mode, rules: []certificateIdentityMatcher{
+				{kind: "Client", dns: compiled, namespace: "ci"},
+			}}
+		}
+
+		It("should reject the peers without certificate in the Required mode", func() {
+			_, err := authenticateCertificate[jumpstarterdevv1alpha1.Client](
+				context.Background(), newAuth(CertificateAuthRequired), kube.Build(), "Client")
+			Expect(status.Code(err)).To(Equal(codes.Unauthenticated))
+		})
+
+		It("should leave the peers without certificate to the bearer tokens in the Optional mode", func() {
+			object, err := authenticateCertificate[jumpstarterdevv1alpha1.Client](
+				context.Background(), newAuth(CertificateAuthOptional), kube.Build(), "Client")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(object).To(BeNil())
+		})
+
+		It("should return the object identified by the certificate", func() {
+			ctx := withCertificate(&x509.Certificate{DNSNames: []string{"runner.clients.lab.example.com"}})
+			object, err := authenticateCertificate[jumpstarterdevv1alpha1.Client](
+				ctx, newAuth(CertificateAuthRequired), kube.Build(), "Client")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(object.Name).To(Equal("runner"))
+		})
+
+		It("should deny the certificates not identifying an existing object", func() {
+			for _, san := range []string{"runner.example.com", "unknown.clients.lab.example.com"} {
+				ctx := withCertificate(&x509.Certificate{DNSNames: []string{san}})
+				_, err := authenticateCertificate[jumpstarterdevv1alpha1.Client](
+					ctx, newAuth(CertificateAuthOptional), kube.Build(), "Client")
+				Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+			}
+		})
+	})
+
+	Describe("NewCertificateAuth", func() {
+		var caFile string
+
+		BeforeEach(func() {
+			cert, err := NewSelfSignedCertificate("ca", nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+			caFile = filepath.Join(GinkgoT().TempDir(), "ca.pem")
+			Expect(os.WriteFile(caFile,
+				pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0o600),
+			).To(Succeed())
+		})
+
+		It("should default to the Optional mode", func() {
+			auth, err := NewCertificateAuth(CertificateAuthConfig{ClientCAFile: caFile})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(auth.Mode).To(Equal(CertificateAuthOptional))
+		})
+
+		DescribeTable("should reject invalid rules",
+			func(rule CertificateIdentityRule) {
+				_, err := NewCertificateAuth(CertificateAuthConfig{
+					ClientCAFile: caFile,
+					Rules:        []CertificateIdentityRule{rule},
+				})
+				Expect(err).To(HaveOccurred())
+			},
+			Entry("unknown kind", CertificateIdentityRule{Kind: "Lease", DNS: "{name}.{namespace}.example.com"}),
+			Entry("both uri and dns", CertificateIdentityRule{
+				Kind: "Client", URI: "spiffe://{namespace}/{name}", DNS: "{name}.{namespace}.example.com",
+			}),
+			Entry("neither uri nor dns", CertificateIdentityRule{Kind: "Client", Namespace: "ci"}),
+			Entry("{namespace} with a fixed namespace", CertificateIdentityRule{
+				Kind: "Client", DNS: "{name}.{namespace}.example.com", Namespace: "ci",
+			}),
+		)
+	})
+})
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"slices"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/internal/controller"
+)
+
+// certificateGroups returns the groups the peer of ctx authenticated as a member of with its client
+// certificate, its organizations, nil if it presented none
+func certificateGroups(ctx context.Context) []string {
+	certificate := peerCertificate(ctx)
+	if certificate == nil {
+		return nil
+	}
+	return normalizeGroups(certificate.Subject.Organization)
+}
+
+// tokenGroups returns the groups claimed by the verified token claims
+func tokenGroups(claims *controller.JumpstarterClaims) []string {
+	return normalizeGroups(claims.Groups)
+}
+
+func normalizeGroups(groups []string) []string {
+	if len(groups) == 0 {
+		return nil
+	}
+	groups = slices.Clone(groups)
+	slices.Sort(groups)
+	return slices.Compact(groups)
+}
+
+// withClientGroups sets the Groups of jclient to the groups of the credentials it authenticated the
+// call with, the call is evaluated against them while the leases it requests freeze them, they are
+// never recorded on the client as the groups of other credentials of the client must not apply
+func withClientGroups(jclient *jumpstarterdevv1alpha1.Client, groups []string) *jumpstarterdevv1alpha1.Client {
+	jclient.Status.Groups = groups
+	return jclient
+}
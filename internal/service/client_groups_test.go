@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/internal/controller"
+	pb "github.com/jumpstarter-dev/jumpstarter-controller/internal/protocol/jumpstarter/v1"
+)
+
+var _ = Describe("Client groups", func() {
+	var s *ControllerService
+	jclient := &jumpstarterdevv1alpha1.Client{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "default",
+		Name:      "client",
+		UID:       "client-uid",
+	}}
+
+	// groupsTokenContext authenticates as jclient with a token claiming groups
+	groupsTokenContext := func(groups ...string) context.Context {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, controller.JumpstarterClaims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:   "https://jumpstarter.dev/controller",
+				Audience: jwt.ClaimStrings{"https://jumpstarter.dev/controller"},
+				Subject:  string(jclient.UID),
+				IssuedAt: jwt.NewNumericDate(time.Now()),
+			},
+			Kind:       "Client",
+			Namespace:  jclient.Namespace,
+			Name:       jclient.Name,
+			UID:        jclient.UID,
+			APIVersion: jumpstarterdevv1alpha1.GroupVersion.String(),
+			Groups:     groups,
+		}).SignedString([]byte("test-key"))
+		Expect(err).NotTo(HaveOccurred())
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+	}
+
+	// certificateContext authenticates as jclient with a client certificate of organizations
+	certificateContext := func(organizations ...string) context.Context {
+		compiled, err := compileIdentityPattern("{name}.{namespace}.clients.lab.example.com", false)
+		Expect(err).NotTo(HaveOccurred())
+		s.CertificateAuth = &CertificateAuth{Mode: CertificateAuthOptional, rules: []certificateIdentityMatcher{
+			{kind: "Client", dns: compiled},
+		}}
+		certificate := &x509.Certificate{
+			Subject:  pkix.Name{Organization: organizations},
+			DNSNames: []string{"client.default.clients.lab.example.com"},
+		}
+		return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{
+			State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{certificate}}},
+		}})
+	}
+
+	// requestLease requests a lease as the client of ctx and returns it as stored
+	requestLease := func(ctx context.Context) *jumpstarterdevv1alpha1.Lease {
+		response, err := s.RequestLease(ctx, &pb.RequestLeaseRequest{
+			Duration: durationpb.New(time.Hour),
+			Selector: &pb.LabelSelector{MatchLabels: map[string]string{"board": "rpi"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		var lease jumpstarterdevv1alpha1.Lease
+		Expect(s.Client.Get(context.Background(), client.ObjectKey{
+			Namespace: jclient.Namespace,
+			Name:      response.Name,
+		}, &lease)).To(Succeed())
+		return &lease
+	}
+
+	BeforeEach(func() {
+		s = newTestService(jclient.DeepCopy())
+	})
+
+	It("should take the groups of a token from its claims", func() {
+		authenticated, err := s.authenticateClient(groupsTokenContext("lab-admins", "developers", "lab-admins"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(authenticated.Status.Groups).To(Equal([]string{"developers", "lab-admins"}))
+	})
+
+	It("should take the groups of a client certificate from its organizations", func() {
+		authenticated, err := s.authenticateClient(certificateContext("lab-admins", "developers"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(authenticated.Status.Groups).To(Equal([]string{"developers", "lab-admins"}))
+	})
+
+	It("should freeze the groups of a token onto the leases requested with it", func() {
+		lease := requestLease(groupsTokenContext("developers"))
+		Expect(lease.Spec.ClientGroups).To(Equal([]string{"developers"}))
+	})
+
+	It("should freeze the groups of a client certificate onto the leases requested with it", func() {
+		lease := requestLease(certificateContext("lab-admins"))
+		Expect(lease.Spec.ClientGroups).To(Equal([]string{"lab-admins"}))
+	})
+
+	Describe("evaluating a lease", func() {
+		policy := &jumpstarterdevv1alpha1.ExporterAccessPolicy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "policy"},
+			Spec: jumpstarterdevv1alpha1.ExporterAccessPolicySpec{
+				Policies: []jumpstarterdevv1alpha1.Policy{{
+					Priority:        10,
+					From:            []jumpstarterdevv1alpha1.From{{Groups: []string{"lab-admins"}}},
+					MaximumDuration: &metav1.Duration{Duration: 4 * time.Hour},
+				}, {
+					From:            []jumpstarterdevv1alpha1.From{{}},
+					MaximumDuration: &metav1.Duration{Duration: time.Hour},
+				}},
+			},
+		}
+
+		// extend extends a lease frozen with groups to 2h with the credentials of ctx
+		extend := func(ctx context.Context, groups ...string) error {
+			lease := newTestLease("lease", "client", "exporter")
+			lease.Spec.ClientGroups = groups
+			lease.Status.BeginTime = &metav1.Time{Time: time.Now().Add(-10 * time.Minute)}
+			exporter := &jumpstarterdevv1alpha1.Exporter{ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      "exporter",
+			}}
+			s = newTestService(jclient.DeepCopy(), policy.DeepCopy(), lease, exporter)
+			in, err := structpb.NewStruct(map[string]any{"lease": lease.Name, "duration": "2h"})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = s.ExtendLease(ctx, in)
+			return err
+		}
+
+		It("should use the groups frozen onto the lease, not the ones of the token", func() {
+			Expect(extend(groupsTokenContext(), "lab-admins")).To(Succeed())
+			err := extend(groupsTokenContext("lab-admins"))
+			Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+		})
+	})
+})
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/internal/controller"
+	"github.com/jumpstarter-dev/jumpstarter-controller/pkg/api"
+)
+
+type clientServer interface {
+	ListLeasableExporters(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	ResolveSelector(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	CheckLease(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	PauseLease(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	ResumeLease(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	ExtendLease(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	ListLeaseMetadata(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	GetLeaseMetadata(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	SetLeaseMetadata(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	DeleteLeaseMetadata(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	GetServerInfo(context.Context, *structpb.Struct) (*structpb.Struct, error)
+}
+
+var clientServiceDesc = grpc.ServiceDesc{
+	ServiceName: api.ClientServiceName,
+	HandlerType: (*clientServer)(nil),
+	Methods: []grpc.MethodDesc{
+		structMethod(api.ClientServiceName, "ListLeasableExporters", clientServer.ListLeasableExporters),
+		structMethod(api.ClientServiceName, "ResolveSelector", clientServer.ResolveSelector),
+		structMethod(api.ClientServiceName, "CheckLease", clientServer.CheckLease),
+		structMethod(api.ClientServiceName, "PauseLease", clientServer.PauseLease),
+		structMethod(api.ClientServiceName, "ResumeLease", clientServer.ResumeLease),
+		structMethod(api.ClientServiceName, "ExtendLease", clientServer.ExtendLease),
+		structMethod(api.ClientServiceName, "ListLeaseMetadata", clientServer.ListLeaseMetadata),
+		structMethod(api.ClientServiceName, "GetLeaseMetadata", clientServer.GetLeaseMetadata),
+		structMethod(api.ClientServiceName, "SetLeaseMetadata", clientServer.SetLeaseMetadata),
+		structMethod(api.ClientServiceName, "DeleteLeaseMetadata", clientServer.DeleteLeaseMetadata),
+		structMethod(api.ClientServiceName, "GetServerInfo", clientServer.GetServerInfo),
+	},
+	Metadata: "client",
+}
+
+// structMethod describes the unary method of service taking and returning a google.protobuf.Struct
+func structMethod[S any](
+	service string,
+	name string,
+	call func(S, context.Context, *structpb.Struct) (*structpb.Struct, error),
+) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(
+			srv any,
+			ctx context.Context,
+			dec func(any) error,
+			interceptor grpc.UnaryServerInterceptor,
+		) (any, error) {
+			in := new(structpb.Struct)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			if interceptor == nil {
+				return call(srv.(S), ctx, in)
+			}
+			info := &grpc.UnaryServerInfo{
+				Server:     srv,
+				FullMethod: "/" + service + "/" + name,
+			}
+			return interceptor(ctx, in, info, func(ctx context.Context, req any) (any, error) {
+				return call(srv.(S), ctx, req.(*structpb.Struct))
+			})
+		},
+	}
+}
+
+// decodeStruct decodes the JSON request in into req
+func decodeStruct(in *structpb.Struct, req any) error {
+	content, err := json.Marshal(in.AsMap())
+	if err == nil {
+		err = json.Unmarshal(content, req)
+	}
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid request: %s", err)
+	}
+	return nil
+}
+
+// encodeStruct encodes the JSON response resp
+func encodeStruct(resp any) (*structpb.Struct, error) {
+	content, err := json.Marshal(resp)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to encode response")
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(content, &fields); err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to encode response")
+	}
+	return structpb.NewStruct(fields)
+}
+
+// ListLeasableExporters returns the exporters of its namespace the caller may lease, with the policy
+// the leases would be granted by, so that access problems show before a lease is left pending
+func (s *ControllerService) ListLeasableExporters(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	logger := log.FromContext(ctx)
+
+	jclient, err := s.authenticateClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var req api.LeasableExportersRequest
+	if err := decodeStruct(in, &req); err != nil {
+		return nil, err
+	}
+
+	selector, err := labels.Parse(req.Selector)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid selector: %s", err)
+	}
+
+	exporters, err := s.listExporters(ctx, jclient.Namespace, selector)
+	if err != nil {
+		return nil, listExportersError(ctx, err)
+	}
+
+	policies, err := controller.ListAccessPolicies(ctx, s.Client, jclient.Namespace)
+	if err != nil {
+		logger.Error(err, "unable to list exporter access policies")
+		return nil, status.Errorf(codes.Internal, "unable to list exporter access policies")
+	}
+
+	now := time.Now()
+	response := api.LeasableExportersResponse{Exporters: []api.LeasableExporter{}}
+	for i := range exporters {
+		leasable, err := s.leasableExporter(policies, jclient, &exporters[i], now)
+		if err != nil {
+			logger.Error(err, "unable to evaluate exporter access policies")
+			return nil, status.Errorf(codes.Internal, "unable to evaluate exporter access policies")
+		}
+		if leasable != nil {
+			response.Exporters = append(response.Exporters, *leasable)
+		}
+	}
+
+	return encodeStruct(response)
+}
+
+// leasableExporter returns how the leases of jclient on exporter are granted, with the lease
+// duration limits of its namespace, nil if jclient may not lease exporter
+func (s *ControllerService) leasableExporter(
+	policies []jumpstarterdevv1alpha1.ExporterAccessPolicy,
+	jclient *jumpstarterdevv1alpha1.Client,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+	now time.Time,
+) (*api.LeasableExporter, error) {
+	allowed, err := controller.ClientCanLease(policies, s.AccessPolicyTieBreak, jclient, exporter, now)
+	if err != nil {
+		return nil, fmt.Errorf("leasableExporter: %w", err)
+	}
+	if !allowed {
+		return nil, nil
+	}
+	decision, err := controller.EvaluateAccessPolicies(policies, s.AccessPolicyTieBreak, jclient, exporter)
+	if err != nil {
+		return nil, fmt.Errorf("leasableExporter: %w", err)
+	}
+
+	leasable := &api.LeasableExporter{
+		Name:   exporter.Name,
+		Labels: exporter.Labels,
+		// the reservations of the exporters grant access the policies do not
+		Reserved: !decision.Allowed,
+	}
+	var maximum time.Duration
+	if s.DurationLimits != nil {
+		maximum = s.DurationLimits.For(jclient.Namespace).Maximum.Duration
+	}
+	if decision.Allowed && decision.Policy != nil {
+		leasable.Policy = decision.PolicyName
+		leasable.Priority = decision.Policy.Priority
+		if policyMaximum := decision.MaximumDuration(); policyMaximum != nil &&
+			(maximum == 0 || policyMaximum.Duration < maximum) {
+			maximum = policyMaximum.Duration
+		}
+	}
+	if maximum > 0 {
+		leasable.MaximumDuration = &metav1.Duration{Duration: maximum}
+	}
+	return leasable, nil
+}
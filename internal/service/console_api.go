@@ -0,0 +1,285 @@
+package service
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// +kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+// ConsoleAPIPrefix is the path the REST API of the console plugins is served under, the requests
+// carry the Kubernetes bearer token of the user, e.g. as proxied by the OpenShift console, and
+// are authorized as the user would be to read the jumpstarter resources
+const ConsoleAPIPrefix = "/api/console/v1alpha1"
+
+// ConsoleFleetSummary counts the exporters and leases of a namespace, or of the cluster
+type ConsoleFleetSummary struct {
+	Exporters ConsoleExporterCounts `json:"exporters"`
+	// The number of leases in each phase
+	Leases map[jumpstarterdevv1alpha1.LeasePhase]int `json:"leases"`
+}
+
+// ConsoleExporterCounts counts the exporters by state, an exporter may be counted in several states
+type ConsoleExporterCounts struct {
+	Total            int `json:"total"`
+	Online           int `json:"online"`
+	Leased           int `json:"leased"`
+	Cordoned         int `json:"cordoned"`
+	Drained          int `json:"drained"`
+	UnderMaintenance int `json:"underMaintenance"`
+}
+
+// ConsoleLease is a lease as listed by the console plugins
+type ConsoleLease struct {
+	Name      string                            `json:"name"`
+	Namespace string                            `json:"namespace"`
+	Client    string                            `json:"client"`
+	Selector  string                            `json:"selector"`
+	Duration  string                            `json:"duration"`
+	Phase     jumpstarterdevv1alpha1.LeasePhase `json:"phase,omitempty"`
+	Exporter  string                            `json:"exporter,omitempty"`
+	BeginTime *metav1.Time                      `json:"beginTime,omitempty"`
+	EndTime   *metav1.Time                      `json:"endTime,omitempty"`
+	CreatedAt metav1.Time                       `json:"createdAt"`
+}
+
+// ConsoleExporter is the detail of an exporter shown by the console plugins
+type ConsoleExporter struct {
+	Name       string                          `json:"name"`
+	Namespace  string                          `json:"namespace"`
+	Labels     map[string]string               `json:"labels,omitempty"`
+	Notes      string                          `json:"notes,omitempty"`
+	Location   string                          `json:"location,omitempty"`
+	Online     bool                            `json:"online"`
+	Cordoned   bool                            `json:"cordoned"`
+	Drained    bool                            `json:"drained"`
+	Lease      string                          `json:"lease,omitempty"`
+	Devices    []jumpstarterdevv1alpha1.Device `json:"devices,omitempty"`
+	Conditions []metav1.Condition              `json:"conditions,omitempty"`
+	// The leases of the exporter, most recent first
+	Leases []ConsoleLease `json:"leases"`
+}
+
+// consoleAPI serves the REST API of the console plugins
+type consoleAPI struct {
+	client client.Client
+}
+
+const consoleUserKey = "jumpstarter-console-user"
+
+// register adds the routes of the console API to r
+func (a *consoleAPI) register(r *gin.Engine) {
+	group := r.Group(ConsoleAPIPrefix, a.authenticate)
+	group.GET("/summary", a.summary)
+	group.GET("/leases", a.leases)
+	group.GET("/namespaces/:namespace/exporters/:name", a.exporter)
+}
+
+// authenticate identifies the user by its bearer token with a TokenReview
+func (a *consoleAPI) authenticate(c *gin.Context) {
+	token, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !found || token == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "bearer token required"})
+		return
+	}
+
+	review := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}
+	if err := a.client.Create(c.Request.Context(), review); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !review.Status.Authenticated {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid bearer token"})
+		return
+	}
+	c.Set(consoleUserKey, review.Status.User)
+	c.Next()
+}
+
+// authorize reports whether the user of c may verb the jumpstarter resource named name in namespace,
+// all of them if name is empty, in every namespace if namespace is empty, writing 403 if not
+func (a *consoleAPI) authorize(c *gin.Context, verb string, resource string, namespace string, name string) bool {
+	user := c.MustGet(consoleUserKey).(authenticationv1.UserInfo)
+
+	extra := map[string]authorizationv1.ExtraValue{}
+	for key, values := range user.Extra {
+		extra[key] = authorizationv1.ExtraValue(values)
+	}
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			UID:    user.UID,
+			Groups: user.Groups,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      verb,
+				Group:     jumpstarterdevv1alpha1.GroupVersion.Group,
+				Resource:  resource,
+				Name:      name,
+			},
+		},
+	}
+	if err := a.client.Create(c.Request.Context(), review); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if !review.Status.Allowed {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden: cannot " + verb + " " + resource})
+		return false
+	}
+	return true
+}
+
+// summary serves the ConsoleFleetSummary of the namespace query parameter, or of every namespace
+func (a *consoleAPI) summary(c *gin.Context) {
+	namespace := c.Query("namespace")
+	if !a.authorize(c, "list", "exporters", namespace, "") || !a.authorize(c, "list", "leases", namespace, "") {
+		return
+	}
+	ctx := c.Request.Context()
+
+	var exporters jumpstarterdevv1alpha1.ExporterList
+	if err := a.client.List(ctx, &exporters, client.InNamespace(namespace)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var leases jumpstarterdevv1alpha1.LeaseList
+	if err := a.client.List(ctx, &leases, client.InNamespace(namespace)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	summary := ConsoleFleetSummary{Leases: map[jumpstarterdevv1alpha1.LeasePhase]int{}}
+	for i := range exporters.Items {
+		exporter := &exporters.Items[i]
+		summary.Exporters.Total++
+		if exporterOnline(exporter) {
+			summary.Exporters.Online++
+		}
+		if exporter.Status.LeaseRef != nil {
+			summary.Exporters.Leased++
+		}
+		if exporter.Spec.Unschedulable || exporter.Spec.Drain {
+			summary.Exporters.Cordoned++
+		}
+		if exporterDrained(exporter) {
+			summary.Exporters.Drained++
+		}
+		if meta.IsStatusConditionTrue(exporter.Status.Conditions,
+			string(jumpstarterdevv1alpha1.ExporterConditionTypeUnderMaintenance)) {
+			summary.Exporters.UnderMaintenance++
+		}
+	}
+	for i := range leases.Items {
+		summary.Leases[leases.Items[i].Status.Phase]++
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+// leases serves the leases of the namespace query parameter, or of every namespace, most recent first
+func (a *consoleAPI) leases(c *gin.Context) {
+	namespace := c.Query("namespace")
+	if !a.authorize(c, "list", "leases", namespace, "") {
+		return
+	}
+
+	var leases jumpstarterdevv1alpha1.LeaseList
+	if err := a.client.List(c.Request.Context(), &leases, client.InNamespace(namespace)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, consoleLeases(leases.Items, ""))
+}
+
+// exporter serves the ConsoleExporter of the exporter of the path
+func (a *consoleAPI) exporter(c *gin.Context) {
+	namespace, name := c.Param("namespace"), c.Param("name")
+	if !a.authorize(c, "get", "exporters", namespace, name) || !a.authorize(c, "list", "leases", namespace, "") {
+		return
+	}
+	ctx := c.Request.Context()
+
+	var exporter jumpstarterdevv1alpha1.Exporter
+	if err := a.client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &exporter); err != nil {
+		if apierrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	var leases jumpstarterdevv1alpha1.LeaseList
+	if err := a.client.List(ctx, &leases, client.InNamespace(namespace)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	detail := ConsoleExporter{
+		Name:       exporter.Name,
+		Namespace:  exporter.Namespace,
+		Labels:     exporter.Labels,
+		Notes:      exporter.Annotations[jumpstarterdevv1alpha1.ExporterAnnotationNotes],
+		Location:   exporter.Annotations[jumpstarterdevv1alpha1.ExporterAnnotationLocation],
+		Online:     exporterOnline(&exporter),
+		Cordoned:   exporter.Spec.Unschedulable || exporter.Spec.Drain,
+		Drained:    exporterDrained(&exporter),
+		Devices:    exporter.Status.Devices,
+		Conditions: exporter.Status.Conditions,
+		Leases:     consoleLeases(leases.Items, exporter.Name),
+	}
+	if exporter.Status.LeaseRef != nil {
+		detail.Lease = exporter.Status.LeaseRef.Name
+	}
+	c.JSON(http.StatusOK, detail)
+}
+
+// consoleLeases returns leases as listed by the console plugins, most recent first, only the
+// leases that acquired the exporter named exporter if not empty
+func consoleLeases(leases []jumpstarterdevv1alpha1.Lease, exporter string) []ConsoleLease {
+	listed := []ConsoleLease{}
+	for i := range leases {
+		lease := &leases[i]
+		if exporter != "" && (lease.Status.ExporterRef == nil || lease.Status.ExporterRef.Name != exporter) {
+			continue
+		}
+		item := ConsoleLease{
+			Name:      lease.Name,
+			Namespace: lease.Namespace,
+			Client:    lease.Spec.ClientRef.Name,
+			Selector:  metav1.FormatLabelSelector(&lease.Spec.Selector),
+			Duration:  lease.Spec.Duration.Duration.String(),
+			Phase:     lease.Status.Phase,
+			BeginTime: lease.Status.BeginTime,
+			EndTime:   lease.Status.EndTime,
+			CreatedAt: lease.CreationTimestamp,
+		}
+		if lease.Status.ExporterRef != nil {
+			item.Exporter = lease.Status.ExporterRef.Name
+		}
+		listed = append(listed, item)
+	}
+	slices.SortStableFunc(listed, func(a, b ConsoleLease) int {
+		return b.CreatedAt.Compare(a.CreatedAt.Time)
+	})
+	return listed
+}
+
+// exporterOnline reports whether exporter is connected to the controller
+func exporterOnline(exporter *jumpstarterdevv1alpha1.Exporter) bool {
+	return meta.IsStatusConditionTrue(exporter.Status.Conditions, string(jumpstarterdevv1alpha1.ExporterConditionTypeOnline))
+}
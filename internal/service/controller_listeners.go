@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sigs.k8s.io/yaml"
+)
+
+// ListenerAuthentication is a method clients and exporters may authenticate with on a listener
+type ListenerAuthentication string
+
+const (
+	// ListenerAuthenticationToken accepts bearer tokens
+	ListenerAuthenticationToken ListenerAuthentication = "token"
+	// ListenerAuthenticationCertificate accepts TLS client certificates, with CertificateAuth
+	ListenerAuthenticationCertificate ListenerAuthentication = "certificate"
+)
+
+// ControllerListener is a listener the ControllerService is served on
+type ControllerListener struct {
+	// Name identifies the listener in the logs
+	Name string `json:"name"`
+	// The TCP address to listen on, e.g. :8082
+	Address string `json:"address"`
+	// Set to edge to serve cleartext gRPC behind a proxy or a mesh terminating TLS, the controller
+	// terminates TLS with its self-signed certificate otherwise
+	TLSTermination string `json:"tlsTermination,omitempty"`
+	// The methods clients and exporters may authenticate with, all of them if empty
+	Authentication []ListenerAuthentication `json:"authentication,omitempty"`
+	// The optional endpoints not served on the listener, on top of the ones disabled everywhere
+	DisabledEndpoints []Endpoint `json:"disabledEndpoints,omitempty"`
+}
+
+// ControllerListenersConfig is the configuration file of the listeners of the ControllerService
+type ControllerListenersConfig struct {
+	Listeners []ControllerListener `json:"listeners"`
+}
+
+// DefaultControllerListeners returns the single listener on :8082 the ControllerService is served
+// on when not configured, terminating TLS as set by GRPC_TLS_TERMINATION
+func DefaultControllerListeners() []ControllerListener {
+	return []ControllerListener{{
+		Name:           "default",
+		Address:        ":8082",
+		TLSTermination: controllerTLSTermination(),
+	}}
+}
+
+// LoadControllerListeners reads a ControllerListenersConfig from path
+func LoadControllerListeners(path string) ([]ControllerListener, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("LoadControllerListeners: %w", err)
+	}
+	var config ControllerListenersConfig
+	if err := yaml.UnmarshalStrict(content, &config); err != nil {
+		return nil, fmt.Errorf("LoadControllerListeners: invalid configuration: %w", err)
+	}
+	if len(config.Listeners) == 0 {
+		return nil, fmt.Errorf("LoadControllerListeners: no listener configured")
+	}
+
+	names := map[string]bool{}
+	for _, listener := range config.Listeners {
+		if listener.Name == "" || listener.Address == "" {
+			return nil, fmt.Errorf("LoadControllerListeners: listeners require a name and an address")
+		}
+		if names[listener.Name] {
+			return nil, fmt.Errorf("LoadControllerListeners: duplicate listener %s", listener.Name)
+		}
+		names[listener.Name] = true
+		if listener.TLSTermination != "" && listener.TLSTermination != tlsTerminationEdge {
+			return nil, fmt.Errorf("LoadControllerListeners: unknown TLS termination %s of listener %s",
+				listener.TLSTermination, listener.Name)
+		}
+		for _, method := range listener.Authentication {
+			if method != ListenerAuthenticationToken && method != ListenerAuthenticationCertificate {
+				return nil, fmt.Errorf("LoadControllerListeners: unknown authentication %s of listener %s",
+					method, listener.Name)
+			}
+		}
+		for _, endpoint := range listener.DisabledEndpoints {
+			if !slices.Contains(knownEndpoints, endpoint) {
+				return nil, fmt.Errorf("LoadControllerListeners: unknown endpoint %s of listener %s",
+					endpoint, listener.Name)
+			}
+		}
+	}
+	return config.Listeners, nil
+}
+
+// accepts reports whether clients and exporters may authenticate with method on the listener
+func (l *ControllerListener) accepts(method ListenerAuthentication) bool {
+	return len(l.Authentication) == 0 || slices.Contains(l.Authentication, method)
+}
+
+// validate checks the listener can be served with the client certificate authentication auth,
+// the listeners terminating TLS at the edge must not accept certificates
+func (l *ControllerListener) validate(auth *CertificateAuth) error {
+	if l.TLSTermination == tlsTerminationEdge && auth != nil && l.accepts(ListenerAuthenticationCertificate) {
+		return fmt.Errorf("listener %s: client certificate authentication requires the TLS "+
+			"to be terminated by the controller", l.Name)
+	}
+	if auth == nil && !l.accepts(ListenerAuthenticationToken) {
+		return fmt.Errorf("listener %s: client certificate authentication is not configured", l.Name)
+	}
+	return nil
+}
+
+// disabledEndpoints returns the optional endpoints not served on the listener
+func (l *ControllerListener) disabledEndpoints(disabled DisabledEndpoints) DisabledEndpoints {
+	merged := DisabledEndpoints{}
+	for endpoint, ok := range disabled {
+		merged[endpoint] = ok
+	}
+	for _, endpoint := range l.DisabledEndpoints {
+		merged[endpoint] = true
+	}
+	return merged
+}
+
+type listenerContextKey struct{}
+
+// interceptors record the listener in the context of every call it receives
+func (l *ControllerListener) interceptors() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(
+			ctx context.Context,
+			req any,
+			_ *grpc.UnaryServerInfo,
+			handler grpc.UnaryHandler,
+		) (any, error) {
+			return handler(context.WithValue(ctx, listenerContextKey{}, l), req)
+		}),
+		grpc.ChainStreamInterceptor(func(
+			srv any,
+			ss grpc.ServerStream,
+			_ *grpc.StreamServerInfo,
+			handler grpc.StreamHandler,
+		) error {
+			return handler(srv, &listenerServerStream{ServerStream: ss, listener: l})
+		}),
+	}
+}
+
+type listenerServerStream struct {
+	grpc.ServerStream
+	listener *ControllerListener
+}
+
+func (s *listenerServerStream) Context() context.Context {
+	return context.WithValue(s.ServerStream.Context(), listenerContextKey{}, s.listener)
+}
+
+// listenerAccepts returns UNAUTHENTICATED if the listener the call of ctx was received on does not
+// accept the authentication method
+func listenerAccepts(ctx context.Context, method ListenerAuthentication) error {
+	listener, ok := ctx.Value(listenerContextKey{}).(*ControllerListener)
+	if !ok || listener.accepts(method) {
+		return nil
+	}
+	return status.Errorf(codes.Unauthenticated, "%s authentication not accepted on listener %s", method, listener.Name)
+}
+
+// listenerCertificateAuth returns auth if the listener the call of ctx was received on accepts
+// client certificates, nil otherwise
+func listenerCertificateAuth(ctx context.Context, auth *CertificateAuth) *CertificateAuth {
+	if listenerAccepts(ctx, ListenerAuthenticationCertificate) != nil {
+		return nil
+	}
+	return auth
+}
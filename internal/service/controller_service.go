@@ -20,22 +20,22 @@ import (
 	"context"
 	"fmt"
 	"net"
-	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	pb "github.com/jumpstarter-dev/jumpstarter-controller/internal/protocol/jumpstarter/v1"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
-	"google.golang.org/grpc/reflection"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
@@ -44,38 +44,134 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/structured-merge-diff/v4/fieldpath"
 
 	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
 	"github.com/jumpstarter-dev/jumpstarter-controller/internal/controller"
+	"github.com/jumpstarter-dev/jumpstarter-controller/pkg/api"
+	"github.com/jumpstarter-dev/jumpstarter-controller/pkg/interceptors"
 )
 
 // ControlerService exposes a gRPC service
 type ControllerService struct {
 	pb.UnimplementedControllerServiceServer
-	Client       client.WithWatch
-	Scheme       *runtime.Scheme
-	listenQueues sync.Map
+	Client client.WithWatch
+	Scheme *runtime.Scheme
+	// If set, ListExporters only returns the exporters of the namespace of the client the
+	// ExporterAccessPolicies allow it to lease, instead of the exporters of every namespace
+	RestrictExporterVisibility bool
+	// Keepalive is the keepalive policy enforced on clients, defaults to DefaultKeepalivePolicy
+	Keepalive KeepalivePolicy
+	// DisabledLegacyLeaseNamespaces are the namespaces in which the deprecated lease RPCs
+	// are rejected, AllNamespaces disables them everywhere
+	DisabledLegacyLeaseNamespaces []string
+	// DisabledEndpoints are the optional endpoints not served on the listener
+	DisabledEndpoints DisabledEndpoints
+	// RouterKey signs the router tokens, nil for the ROUTER_KEY environment variable
+	RouterKey *RouterKey
+	// RegistrationWebhook, if set, is notified of the registrations of the exporters
+	RegistrationWebhook *RegistrationWebhook
+	// CertificateAuth, if set, authenticates clients and exporters by their TLS client certificates
+	CertificateAuth *CertificateAuth
+	// SelfSignedCertificate is how the serving certificate is kept across restarts
+	SelfSignedCertificate SelfSignedCertificateConfig
+	// Events, if set, receives the dial events of the leases
+	Events record.EventRecorder
+	// ListExportersCacheTTL is how long exporter lists are reused by ListExporters, 0 disables caching
+	ListExportersCacheTTL time.Duration
+	// RegisterLimits bound the devices reported by the exporters, not enforced if zero
+	RegisterLimits RegisterLimits
+	// Listeners the service is served on, defaults to DefaultControllerListeners
+	Listeners []ControllerListener
+	// Allocator evaluates the leases checked with CheckLease, defaults to NewDefaultAllocator
+	Allocator *controller.Allocator
+	// AccessPolicyTieBreak is which of the access policies of equal priority matching a client applies,
+	// defaults to controller.PolicyTieBreakMostRestrictive
+	AccessPolicyTieBreak controller.PolicyTieBreak
+	// DurationLimits, if set, default and bound the durations of the requested leases
+	DurationLimits *controller.LeaseDurationLimits
+	// AuthExemptions are the methods served without credentials, defaults to DefaultAuthExemptions
+	AuthExemptions *AuthExemptions
+	// Interceptors run, in order, after the ones recording the listener and checking the credentials
+	Interceptors []interceptors.Interceptor
+	// RouterRegistry reads the router registry, through a cache scoped to the namespace of the
+	// controller, defaults to Client
+	RouterRegistry client.Reader
+	// MaxDialTimeout bounds the timeouts set with DialTimeoutHeader, defaults to 5m
+	MaxDialTimeout time.Duration
+	// Transfers, if set, are the object stores the TransferService offloads the transfers to
+	Transfers     *TransferConfig
+	listenQueues  listenQueues
+	exporterLists exporterListCache
+	dialCache     dialCache
+	// latest dialedStream per lease, for observers to attach to
+	streams sync.Map
+	grpcHealth
+}
+
+// routerRegistry returns the reader of the router registry
+func (s *ControllerService) routerRegistry() client.Reader {
+	if s.RouterRegistry == nil {
+		return s.Client
+	}
+	return s.RouterRegistry
+}
+
+// authExemptions returns the methods served without credentials
+func (s *ControllerService) authExemptions() AuthExemptions {
+	if s.AuthExemptions == nil {
+		return DefaultAuthExemptions
+	}
+	return *s.AuthExemptions
 }
 
 func (s *ControllerService) authenticateClient(ctx context.Context) (*jumpstarterdevv1alpha1.Client, error) {
+	if object, err := authenticateCertificate[jumpstarterdevv1alpha1.Client](
+		ctx, listenerCertificateAuth(ctx, s.CertificateAuth), s.Client, "Client",
+	); err != nil || object != nil {
+		if object != nil {
+			object = withClientGroups(object, certificateGroups(ctx))
+		}
+		return object, err
+	}
+
+	if err := listenerAccepts(ctx, ListenerAuthenticationToken); err != nil {
+		return nil, err
+	}
+
 	token, err := BearerTokenFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return controller.VerifyObjectToken[jumpstarterdevv1alpha1.Client](
+	jclient, claims, err := controller.VerifyObjectTokenClaims[jumpstarterdevv1alpha1.Client](
 		ctx,
 		token,
 		"https://jumpstarter.dev/controller",
 		"https://jumpstarter.dev/controller",
 		s.Client,
 	)
+	if err != nil {
+		return nil, err
+	}
+	return withClientGroups(jclient, tokenGroups(claims)), nil
 }
 
 func (s *ControllerService) authenticateExporter(ctx context.Context) (*jumpstarterdevv1alpha1.Exporter, error) {
+	if object, err := authenticateCertificate[jumpstarterdevv1alpha1.Exporter](
+		ctx, listenerCertificateAuth(ctx, s.CertificateAuth), s.Client, "Exporter",
+	); err != nil || object != nil {
+		return object, err
+	}
+
+	if err := listenerAccepts(ctx, ListenerAuthenticationToken); err != nil {
+		return nil, err
+	}
+
 	token, err := BearerTokenFromContext(ctx)
 	if err != nil {
 		return nil, err
@@ -96,6 +192,7 @@ func (s *ControllerService) Register(ctx context.Context, req *pb.RegisterReques
 	exporter, err := s.authenticateExporter(ctx)
 	if err != nil {
 		logger.Error(err, "unable to authenticate exporter")
+		s.recordAuthenticationError(ctx, "Register", err)
 		return nil, err
 	}
 
@@ -106,32 +203,54 @@ func (s *ControllerService) Register(ctx context.Context, req *pb.RegisterReques
 
 	logger.Info("Registering exporter")
 
-	original := client.MergeFrom(exporter.DeepCopy())
-
-	if exporter.Labels == nil {
-		exporter.Labels = make(map[string]string)
+	devices := []jumpstarterdevv1alpha1.Device{}
+	for _, device := range req.Reports {
+		devices = append(devices, jumpstarterdevv1alpha1.Device{
+			Uuid:       device.Uuid,
+			ParentUuid: device.ParentUuid,
+			Labels:     device.Labels,
+		})
 	}
 
-	for k := range exporter.Labels {
-		if strings.HasPrefix(k, "jumpstarter.dev/") {
-			delete(exporter.Labels, k)
+	devices, exceeded := s.RegisterLimits.enforce(devices)
+	if len(exceeded) > 0 {
+		s.RegisterLimits.record(exporter, exceeded)
+		logger.Info("exporter registration exceeded the register limits", "limits", exceeded)
+		if s.RegisterLimits.policy() == RegisterLimitPolicyReject {
+			err := status.Errorf(codes.ResourceExhausted,
+				"the reported devices exceed the limits of the controller on %s", strings.Join(exceeded, ", "))
+			s.recordExporterError(ctx, exporter, "Register", err)
+			return nil, err
 		}
 	}
 
-	for k, v := range req.Labels {
-		if strings.HasPrefix(k, "jumpstarter.dev/") {
-			exporter.Labels[k] = v
+	if err := s.retryWrite(ctx, exporter, func() error {
+		// the labels restored by an administrator are kept until they unpin them
+		if revision, pinned := controller.PinnedLabelRevision(exporter); pinned {
+			logger.Info("keeping the pinned labels of the exporter", "revision", revision)
+			return nil
 		}
-	}
-
-	if err := s.Client.Patch(ctx, exporter, original); err != nil {
+		original := client.MergeFrom(exporter.DeepCopy())
+		controller.SetManagedExporterLabels(exporter, req.Labels)
+		return s.Client.Patch(ctx, exporter, original)
+	}); err != nil {
 		logger.Error(err, "unable to update exporter")
+		s.recordExporterError(ctx, exporter, "Register", err)
 		return nil, status.Errorf(codes.Internal, "unable to update exporter: %s", err)
 	}
 
-	original = client.MergeFrom(exporter.DeepCopy())
+	if err := s.retryWrite(ctx, exporter, func() error {
+		return controller.AdoptLegacyFields(ctx, s.Client, exporter, "status", registerFieldManager,
+			fieldpath.MakePathOrDie("status", "devices"),
+			fieldpath.MakePathOrDie("status", "labelHistory"),
+		)
+	}); err != nil {
+		logger.Error(err, "unable to adopt exporter status fields")
+		s.recordExporterError(ctx, exporter, "Register", err)
+		return nil, status.Errorf(codes.Internal, "unable to update exporter status: %s", err)
+	}
 
-	meta.SetStatusCondition(&exporter.Status.Conditions, metav1.Condition{
+	registered := exporterCondition(exporter, metav1.Condition{
 		Type:               string(jumpstarterdevv1alpha1.ExporterConditionTypeRegistered),
 		Status:             metav1.ConditionTrue,
 		ObservedGeneration: exporter.Generation,
@@ -140,22 +259,26 @@ func (s *ControllerService) Register(ctx context.Context, req *pb.RegisterReques
 		},
 		Reason: "Register",
 	})
+	truncated := s.RegisterLimits.truncatedCondition(exporter, exceeded)
 
-	devices := []jumpstarterdevv1alpha1.Device{}
-	for _, device := range req.Reports {
-		devices = append(devices, jumpstarterdevv1alpha1.Device{
-			Uuid:       device.Uuid,
-			ParentUuid: device.ParentUuid,
-			Labels:     device.Labels,
-		})
-	}
-	exporter.Status.Devices = devices
+	controller.RecordExporterLabels(exporter, controller.LabelSourceRegister)
 
-	if err := s.Client.Status().Patch(ctx, exporter, original); err != nil {
+	if err := s.retryWrite(ctx, exporter, func() error {
+		return s.applyExporterStatus(ctx, exporter, registerFieldManager, jumpstarterdevv1alpha1.ExporterStatus{
+			Conditions:   []metav1.Condition{registered, truncated},
+			Devices:      devices,
+			LabelHistory: exporter.Status.LabelHistory,
+		})
+	}); err != nil {
 		logger.Error(err, "unable to update exporter status")
+		s.recordExporterError(ctx, exporter, "Register", err)
 		return nil, status.Errorf(codes.Internal, "unable to update exporter status: %s", err)
 	}
 
+	s.clearExporterError(ctx, exporter)
+
+	s.RegistrationWebhook.notify(ctx, RegistrationEventRegister, exporter, devices, "")
+
 	return &pb.RegisterResponse{
 		Uuid: string(exporter.UID),
 	}, nil
@@ -181,8 +304,7 @@ func (s *ControllerService) Unregister(
 		Name:      exporter.Name,
 	})
 
-	original := client.MergeFrom(exporter.DeepCopy())
-	meta.SetStatusCondition(&exporter.Status.Conditions, metav1.Condition{
+	unregistered := exporterCondition(exporter, metav1.Condition{
 		Type:               string(jumpstarterdevv1alpha1.ExporterConditionTypeRegistered),
 		Status:             metav1.ConditionFalse,
 		ObservedGeneration: exporter.Generation,
@@ -193,13 +315,22 @@ func (s *ControllerService) Unregister(
 		Message: req.GetReason(),
 	})
 
-	if err := s.Client.Status().Patch(ctx, exporter, original); err != nil {
+	// the devices and the label history are kept, they are only replaced by the next registration
+	if err := s.retryWrite(ctx, exporter, func() error {
+		return s.applyExporterStatus(ctx, exporter, registerFieldManager, jumpstarterdevv1alpha1.ExporterStatus{
+			Conditions:   []metav1.Condition{unregistered},
+			Devices:      exporter.Status.Devices,
+			LabelHistory: exporter.Status.LabelHistory,
+		})
+	}); err != nil {
 		logger.Error(err, "unable to update exporter status")
 		return nil, status.Errorf(codes.Internal, "unable to update exporter status: %s", err)
 	}
 
 	logger.Info("exporter unregistered, updated as unavailable")
 
+	s.RegistrationWebhook.notify(ctx, RegistrationEventUnregister, exporter, exporter.Status.Devices, req.GetReason())
+
 	return &pb.UnregisterResponse{}, nil
 }
 
@@ -207,10 +338,26 @@ func (s *ControllerService) ListExporters(
 	ctx context.Context,
 	req *pb.ListExportersRequest,
 ) (*pb.ListExportersResponse, error) {
-	// FIXME: authenticate client
-
 	logger := log.FromContext(ctx)
 
+	jclient, err := s.authenticateClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := ExporterQueryFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var compiled *exporterQuery
+	if query != "" {
+		compiled, err = compileExporterQuery(query)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	var exporters jumpstarterdevv1alpha1.ExporterList
 
 	selector := labels.Everything()
@@ -224,28 +371,32 @@ func (s *ControllerService) ListExporters(
 		selector = selector.Add(*requirement)
 	}
 
-	if err := s.Client.List(ctx, &exporters, &client.ListOptions{
-		LabelSelector: selector,
-	}); err != nil {
-		logger.Error(err, "unable to list exporters")
-		return nil, status.Errorf(codes.Internal, "unable to list exporters")
+	if !s.RestrictExporterVisibility {
+		exporters.Items, err = s.listExporters(ctx, "", selector)
+		if err != nil {
+			return nil, listExportersError(ctx, err)
+		}
+	} else {
+		exporters.Items, err = s.listExporters(ctx, jclient.Namespace, selector)
+		if err != nil {
+			return nil, listExportersError(ctx, err)
+		}
+
+		exporters.Items, err = s.visibleExporters(ctx, jclient, exporters.Items)
+		if err != nil {
+			logger.Error(err, "unable to filter exporters by access policies")
+			return nil, status.Errorf(codes.Internal, "unable to filter exporters by access policies")
+		}
+	}
+
+	if compiled != nil {
+		exporters.Items = compiled.filter(exporters.Items)
 	}
 
 	results := make([]*pb.GetReportResponse, len(exporters.Items))
 
-	for i, exporter := range exporters.Items {
-		reports := []*pb.DriverInstanceReport{}
-		for _, device := range exporter.Status.Devices {
-			reports = append(reports, &pb.DriverInstanceReport{
-				Uuid:       device.Uuid,
-				ParentUuid: device.ParentUuid,
-				Labels:     device.Labels,
-			})
-		}
-		results[i] = &pb.GetReportResponse{
-			Labels:  exporter.GetLabels(),
-			Reports: reports,
-		}
+	for i := range exporters.Items {
+		results[i] = exporterReport(&exporters.Items[i])
 	}
 
 	return &pb.ListExportersResponse{
@@ -253,6 +404,22 @@ func (s *ControllerService) ListExporters(
 	}, nil
 }
 
+// exporterReport returns the report of exporter listed to clients
+func exporterReport(exporter *jumpstarterdevv1alpha1.Exporter) *pb.GetReportResponse {
+	reports := []*pb.DriverInstanceReport{}
+	for _, device := range exporter.Status.Devices {
+		reports = append(reports, &pb.DriverInstanceReport{
+			Uuid:       device.Uuid,
+			ParentUuid: device.ParentUuid,
+			Labels:     device.Labels,
+		})
+	}
+	return &pb.GetReportResponse{
+		Labels:  exporter.GetLabels(),
+		Reports: reports,
+	}
+}
+
 func (s *ControllerService) Listen(req *pb.ListenRequest, stream pb.ControllerService_ListenServer) error {
 	ctx := stream.Context()
 	logger := log.FromContext(ctx)
@@ -289,21 +456,36 @@ func (s *ControllerService) Listen(req *pb.ListenRequest, stream pb.ControllerSe
 		return err
 	}
 
-	if lease.Status.ExporterRef == nil || lease.Status.ExporterRef.Name != exporter.Name {
+	if !controller.LeaseHoldsExporter(&lease, exporter.Name) {
 		err := fmt.Errorf("permission denied")
 		logger.Error(err, "lease not held by exporter")
 		return err
 	}
 
-	queue, _ := s.listenQueues.LoadOrStore(leaseName, make(chan *pb.ListenResponse, 8))
+	if lease.Spec.Release || lease.Status.Ended {
+		return status.Errorf(codes.FailedPrecondition, "lease %s has ended", leaseName)
+	}
+
+	queue, ok := s.listenQueues.get(lease.UID, exporter.Name)
+	if !ok {
+		return leaseEndedError(leaseName)
+	}
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
-		case msg := <-queue.(chan *pb.ListenResponse):
-			if err := stream.Send(msg); err != nil {
+		case <-queue.invalidated:
+			return leaseEndedError(leaseName)
+		case dial := <-queue.dials:
+			select {
+			case <-dial.abandoned:
+				continue
+			default:
+			}
+			if err := stream.Send(dial.response); err != nil {
 				return err
 			}
+			close(dial.delivered)
 		}
 	}
 }
@@ -314,6 +496,7 @@ func (s *ControllerService) Status(req *pb.StatusRequest, stream pb.ControllerSe
 
 	exporter, err := s.authenticateExporter(ctx)
 	if err != nil {
+		s.recordAuthenticationError(ctx, "Status", err)
 		return err
 	}
 
@@ -322,8 +505,13 @@ func (s *ControllerService) Status(req *pb.StatusRequest, stream pb.ControllerSe
 		Name:      exporter.Name,
 	})
 
-	original := client.MergeFrom(exporter.DeepCopy())
-	meta.SetStatusCondition(&exporter.Status.Conditions, metav1.Condition{
+	// drained exporters are kept offline until the drain is removed
+	if exporterDrained(exporter) {
+		return status.Errorf(codes.FailedPrecondition, "exporter %s is drained", exporter.Name)
+	}
+
+	multiple := multipleLeasesRequested(ctx)
+	online := exporterCondition(exporter, metav1.Condition{
 		Type:               string(jumpstarterdevv1alpha1.ExporterConditionTypeOnline),
 		Status:             metav1.ConditionTrue,
 		ObservedGeneration: exporter.Generation,
@@ -332,8 +520,16 @@ func (s *ControllerService) Status(req *pb.StatusRequest, stream pb.ControllerSe
 		},
 		Reason: "Connect",
 	})
-	if err = s.Client.Status().Patch(ctx, exporter, original); err != nil {
+	if err = s.retryWrite(ctx, exporter, func() error {
+		return s.applyExporterStatus(ctx, exporter, statusFieldManager, jumpstarterdevv1alpha1.ExporterStatus{
+			Conditions:     []metav1.Condition{online},
+			MultipleLeases: multiple,
+		})
+	}); err != nil {
 		logger.Error(err, "unable to update exporter status")
+		s.recordExporterError(ctx, exporter, "Status", err)
+	} else {
+		s.clearExporterError(ctx, exporter)
 	}
 
 	defer func() {
@@ -347,17 +543,25 @@ func (s *ControllerService) Status(req *pb.StatusRequest, stream pb.ControllerSe
 		); err != nil {
 			logger.Error(err, "unable to refresh exporter status, continuing anyway")
 		}
-		original := client.MergeFrom(exporter.DeepCopy())
-		meta.SetStatusCondition(&exporter.Status.Conditions, metav1.Condition{
+		reason := "Disconnect"
+		if exporterDrained(exporter) {
+			reason = "Drained"
+		}
+		offline := exporterCondition(exporter, metav1.Condition{
 			Type:               string(jumpstarterdevv1alpha1.ExporterConditionTypeOnline),
 			Status:             metav1.ConditionFalse,
 			ObservedGeneration: exporter.Generation,
 			LastTransitionTime: metav1.Time{
 				Time: time.Now(),
 			},
-			Reason: "Disconnect",
+			Reason: reason,
 		})
-		if err = s.Client.Status().Patch(ctx, exporter, original); err != nil {
+		if err = s.retryWrite(ctx, exporter, func() error {
+			return s.applyExporterStatus(ctx, exporter, statusFieldManager, jumpstarterdevv1alpha1.ExporterStatus{
+				Conditions:     []metav1.Condition{offline},
+				MultipleLeases: exporter.Status.MultipleLeases,
+			})
+		}); err != nil {
 			logger.Error(err, "unable to update exporter status, continuing anyway")
 		}
 		cancel()
@@ -372,11 +576,37 @@ func (s *ControllerService) Status(req *pb.StatusRequest, stream pb.ControllerSe
 		return err
 	}
 
+	var held []string
+	reported := false
+
 	defer watcher.Stop()
 	for result := range watcher.ResultChan() {
 		switch result.Type {
 		case watch.Added, watch.Modified, watch.Deleted:
 			exporter = result.Object.(*jumpstarterdevv1alpha1.Exporter)
+			if exporterDrained(exporter) {
+				logger.Info("disconnecting drained exporter")
+				return status.Errorf(codes.FailedPrecondition, "exporter %s is drained", exporter.Name)
+			}
+			if multiple {
+				var responses []*pb.StatusResponse
+				responses, held, err = s.leaseStatusChanges(ctx, exporter, held)
+				if err != nil {
+					logger.Error(err, "failed to report leases of exporter")
+					return err
+				}
+				// the first response tells the exporter it is not leased
+				if !reported && len(responses) == 0 {
+					responses = append(responses, &pb.StatusResponse{Leased: false})
+				}
+				reported = true
+				for _, response := range responses {
+					if err := stream.Send(response); err != nil {
+						return err
+					}
+				}
+				continue
+			}
 			leased := exporter.Status.LeaseRef != nil
 			leaseName := (*string)(nil)
 			clientName := (*string)(nil)
@@ -443,47 +673,233 @@ func (s *ControllerService) Dial(ctx context.Context, req *pb.DialRequest) (*pb.
 		return nil, err
 	}
 
+	dialMode, err := DialModeFromContext(ctx)
+	if err != nil {
+		logger.Error(err, "invalid dial mode")
+		return nil, err
+	}
+
+	if dialMode == DialModeObserve {
+		allowed, err := s.canObserve(ctx, client, &lease)
+		if err != nil {
+			logger.Error(err, "unable to authorize observer")
+			return nil, status.Errorf(codes.Internal, "unable to authorize observer")
+		}
+		if !allowed {
+			err := fmt.Errorf("permission denied")
+			logger.Error(err, "client not an observer of lease")
+			return nil, err
+		}
+		return s.observe(log.IntoContext(ctx, logger), client, &lease)
+	}
+
 	if lease.Spec.ClientRef.Name != client.Name {
 		err := fmt.Errorf("permission denied")
 		logger.Error(err, "lease not held by client")
 		return nil, err
 	}
 
-	stream := uuid.NewUUID()
+	exporterName, err := ExporterFromContext(ctx)
+	if err != nil {
+		logger.Error(err, "invalid exporter")
+		return nil, err
+	}
+	if exporterName == "" && lease.Status.ExporterRef != nil {
+		exporterName = lease.Status.ExporterRef.Name
+	}
+	if !controller.LeaseHoldsExporter(&lease, exporterName) {
+		err := status.Errorf(codes.FailedPrecondition, "exporter not held by lease")
+		logger.Error(err, "unable to dial exporter", "exporter", exporterName)
+		return nil, err
+	}
 
-	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Issuer:    "https://jumpstarter.dev/stream",
-		Subject:   string(stream),
-		Audience:  []string{"https://jumpstarter.dev/router"},
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute * 30)),
-		NotBefore: jwt.NewNumericDate(time.Now()),
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
-		ID:        string(uuid.NewUUID()),
-	}).SignedString([]byte(os.Getenv("ROUTER_KEY")))
+	logger = logger.WithValues("exporter", exporterName)
+	ctx = log.IntoContext(ctx, logger)
+
+	timeout, err := DialTimeoutFromContext(ctx)
+	if err != nil {
+		logger.Error(err, "invalid dial timeout")
+		return nil, err
+	}
+	timeout = min(timeout, s.maxDialTimeout())
 
+	idempotencyKey, err := IdempotencyKeyFromContext(ctx)
+	if err != nil {
+		logger.Error(err, "invalid idempotency key")
+		return nil, err
+	}
+	if idempotencyKey == "" {
+		return s.dial(ctx, client, &lease, exporterName, timeout)
+	}
+
+	response, cached, err := s.dialCache.do(
+		ctx,
+		client.Namespace+"/"+client.Name+"/"+leaseName+"/"+string(lease.UID)+"/"+exporterName+"/"+idempotencyKey,
+		s.maxDialTimeout(),
+		func(ctx context.Context) (*pb.DialResponse, error) {
+			return s.dial(ctx, client, &lease, exporterName, timeout)
+		},
+	)
+	if cached {
+		logger.Info("Client dial deduplicated by idempotency key", "key", idempotencyKey)
+	}
+	return response, err
+}
+
+// dial queues a new stream for the exporter listening on lease
+func (s *ControllerService) dial(
+	ctx context.Context,
+	client *jumpstarterdevv1alpha1.Client,
+	lease *jumpstarterdevv1alpha1.Lease,
+	exporter string,
+	timeout time.Duration,
+) (*pb.DialResponse, error) {
+	logger := log.FromContext(ctx)
+
+	limits, err := s.streamLimits(ctx, client, lease)
+	if err != nil {
+		logger.Error(err, "unable to evaluate stream limits")
+		return nil, status.Errorf(codes.Internal, "unable to evaluate stream limits")
+	}
+
+	stream := string(uuid.NewUUID())
+	claims := StreamClaims{
+		Lease:     types.NamespacedName{Namespace: lease.Namespace, Name: lease.Name}.String(),
+		Namespace: lease.Namespace,
+		Exporter:  exporter,
+		Client:    client.Name,
+		Record:    lease.Spec.Record,
+		Limits:    limits,
+	}
+
+	// each side gets its own token, so the router can tell them apart
+	clientToken, err := s.RouterKey.newStreamToken(stream, api.PeerClient, claims)
 	if err != nil {
 		logger.Error(err, "unable to sign token")
 		return nil, status.Errorf(codes.Internal, "unable to sign token")
 	}
 
-	// TODO: find best router from list
-	endpoint := routerEndpoint()
+	exporterToken, err := s.RouterKey.newStreamToken(stream, api.PeerExporter, claims)
+	if err != nil {
+		logger.Error(err, "unable to sign token")
+		return nil, status.Errorf(codes.Internal, "unable to sign token")
+	}
+
+	endpoint, err := selectRouter(ctx, s.routerRegistry(), time.Now())
+	if err != nil {
+		logger.Error(err, "unable to select router")
+		return nil, err
+	}
 
 	response := &pb.ListenResponse{
 		RouterEndpoint: endpoint,
-		RouterToken:    token,
+		RouterToken:    exporterToken,
+	}
+
+	if lease.Spec.Release || lease.Status.Ended {
+		return nil, status.Errorf(codes.FailedPrecondition, "lease %s has ended", lease.Name)
+	}
+
+	dial := &queuedDial{
+		response:  response,
+		delivered: make(chan struct{}),
+	}
+	waitCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+		dial.abandoned = waitCtx.Done()
 	}
 
-	queue, _ := s.listenQueues.LoadOrStore(leaseName, make(chan *pb.ListenResponse, 8))
+	queue, ok := s.listenQueues.get(lease.UID, exporter)
+	if !ok {
+		return nil, leaseEndedError(lease.Name)
+	}
 	select {
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	case queue.(chan *pb.ListenResponse) <- response:
+	case <-waitCtx.Done():
+		return nil, dialWaitError(ctx, exporter, timeout)
+	case <-queue.invalidated:
+		return nil, leaseEndedError(lease.Name)
+	case queue.dials <- dial:
+	}
+
+	// the dial is only waited for if the client set a timeout
+	if timeout > 0 {
+		select {
+		case <-waitCtx.Done():
+			return nil, dialWaitError(ctx, exporter, timeout)
+		case <-queue.invalidated:
+			return nil, leaseEndedError(lease.Name)
+		case <-dial.delivered:
+		}
 	}
 
+	s.streams.Store(claims.Lease, dialedStream{name: stream, exporter: exporter, endpoint: endpoint})
+	s.recordFirstDial(ctx, lease)
+
 	logger.Info("Client dial assigned stream", "stream", stream)
+	if s.Events != nil {
+		s.Events.Eventf(lease, corev1.EventTypeNormal, "Dialed",
+			"Client %s dialed exporter %s, stream %s through router %s", client.Name, exporter, stream, endpoint)
+	}
 	return &pb.DialResponse{
 		RouterEndpoint: endpoint,
+		RouterToken:    clientToken,
+	}, nil
+}
+
+// recordFirstDial sets the FirstDialTime of lease, the lease controller releases the leases
+// never dialed within the acquisition timeout
+func (s *ControllerService) recordFirstDial(ctx context.Context, lease *jumpstarterdevv1alpha1.Lease) {
+	if lease.Status.FirstDialTime != nil {
+		return
+	}
+	original := client.MergeFrom(lease.DeepCopy())
+	lease.Status.FirstDialTime = &metav1.Time{Time: time.Now()}
+	if err := s.Client.Status().Patch(ctx, lease, original); err != nil {
+		log.FromContext(ctx).Error(err, "unable to record the first dial of lease")
+	}
+}
+
+// dialedStream is the latest stream dialed on a lease
+type dialedStream struct {
+	name     string
+	exporter string
+	// the router replica forwarding the stream
+	endpoint string
+}
+
+// observe issues a receive-only token for the latest stream dialed on lease
+func (s *ControllerService) observe(
+	ctx context.Context,
+	client *jumpstarterdevv1alpha1.Client,
+	lease *jumpstarterdevv1alpha1.Lease,
+) (*pb.DialResponse, error) {
+	logger := log.FromContext(ctx)
+
+	leaseRef := types.NamespacedName{Namespace: lease.Namespace, Name: lease.Name}.String()
+
+	value, ok := s.streams.Load(leaseRef)
+	if !ok {
+		return nil, status.Errorf(codes.FailedPrecondition, "no stream to observe on lease")
+	}
+	stream := value.(dialedStream)
+
+	token, err := s.RouterKey.newStreamToken(stream.name, api.PeerObserver, StreamClaims{
+		Lease:     leaseRef,
+		Namespace: lease.Namespace,
+		Exporter:  stream.exporter,
+		Client:    client.Name,
+	})
+	if err != nil {
+		logger.Error(err, "unable to sign token")
+		return nil, status.Errorf(codes.Internal, "unable to sign token")
+	}
+
+	logger.Info("Client observing stream", "stream", stream.name)
+	return &pb.DialResponse{
+		RouterEndpoint: stream.endpoint,
 		RouterToken:    token,
 	}, nil
 }
@@ -497,6 +913,10 @@ func (s *ControllerService) GetLease(
 		return nil, err
 	}
 
+	if err := s.legacyLeaseRPC("GetLease", client); err != nil {
+		return nil, err
+	}
+
 	var lease jumpstarterdevv1alpha1.Lease
 	if err := s.Client.Get(ctx, types.NamespacedName{
 		Namespace: client.Namespace,
@@ -509,6 +929,19 @@ func (s *ControllerService) GetLease(
 		return nil, fmt.Errorf("GetLease permission denied")
 	}
 
+	// the queue is not part of the protocol yet
+	queue := metadata.MD{}
+	if lease.Status.QueuePosition != nil {
+		queue.Set(api.QueuePositionHeader, strconv.Itoa(int(*lease.Status.QueuePosition)))
+	}
+	if lease.Status.EstimatedBeginTime != nil {
+		queue.Set(api.EstimatedBeginTimeHeader, lease.Status.EstimatedBeginTime.UTC().Format(time.RFC3339))
+	}
+	if len(queue) > 0 {
+		_ = grpc.SetHeader(ctx, queue)
+	}
+	setRoleExportersHeader(ctx, &lease)
+
 	var matchExpressions []*pb.LabelSelectorRequirement
 	for _, exp := range lease.Spec.Selector.MatchExpressions {
 		matchExpressions = append(matchExpressions, &pb.LabelSelectorRequirement{
@@ -524,7 +957,7 @@ func (s *ControllerService) GetLease(
 	}
 	var endTime *timestamppb.Timestamp
 	if lease.Status.EndTime != nil {
-		beginTime = timestamppb.New(lease.Status.EndTime.Time)
+		endTime = timestamppb.New(lease.Status.EndTime.Time)
 	}
 	var exporterUuid *string
 	if lease.Status.ExporterRef != nil {
@@ -573,6 +1006,10 @@ func (s *ControllerService) RequestLease(
 		return nil, err
 	}
 
+	if err := s.legacyLeaseRPC("RequestLease", client); err != nil {
+		return nil, err
+	}
+
 	var matchLabels map[string]string
 	var matchExpressions []metav1.LabelSelectorRequirement
 	if req.Selector != nil {
@@ -586,6 +1023,21 @@ func (s *ControllerService) RequestLease(
 		}
 	}
 
+	priorityClassName, err := s.priorityClassFromContext(ctx, client.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := leaseRolesFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	shared, err := SharedLeaseFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var lease jumpstarterdevv1alpha1.Lease = jumpstarterdevv1alpha1.Lease{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: client.Namespace,
@@ -595,14 +1047,44 @@ func (s *ControllerService) RequestLease(
 			ClientRef: corev1.LocalObjectReference{
 				Name: client.Name,
 			},
-			Duration: metav1.Duration{Duration: req.Duration.AsDuration()},
+			ClientGroups: client.Status.Groups,
+			Duration:     metav1.Duration{Duration: req.Duration.AsDuration()},
 			Selector: metav1.LabelSelector{
 				MatchLabels:      matchLabels,
 				MatchExpressions: matchExpressions,
 			},
+			PriorityClassName: priorityClassName,
+			Roles:             roles,
+			Shared:            shared,
 		},
 	}
-	if err := s.Client.Create(ctx, &lease); err != nil {
+
+	if err := s.applyLeaseTemplate(ctx, &lease); err != nil {
+		return nil, err
+	}
+
+	clamp, err := ClampDurationFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.enforceLeaseDuration(ctx, client, &lease, clamp); err != nil {
+		return nil, err
+	}
+
+	failIfUnsatisfiable, err := FailIfUnsatisfiableFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if failIfUnsatisfiable {
+		if err := s.checkLeaseSatisfiable(ctx, client, &lease); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.retryCreate(ctx, &lease, func(existing runtime.Object) bool {
+		stored, ok := existing.(*jumpstarterdevv1alpha1.Lease)
+		return ok && stored.Spec.ClientRef.Name == lease.Spec.ClientRef.Name
+	}); err != nil {
 		return nil, err
 	}
 
@@ -620,6 +1102,10 @@ func (s *ControllerService) ReleaseLease(
 		return nil, err
 	}
 
+	if err := s.legacyLeaseRPC("ReleaseLease", jclient); err != nil {
+		return nil, err
+	}
+
 	var lease jumpstarterdevv1alpha1.Lease
 	if err := s.Client.Get(ctx, types.NamespacedName{
 		Namespace: jclient.Namespace,
@@ -632,10 +1118,11 @@ func (s *ControllerService) ReleaseLease(
 		return nil, fmt.Errorf("ReleaseLease permission denied")
 	}
 
-	original := client.MergeFrom(lease.DeepCopy())
-	lease.Spec.Release = true
-
-	if err := s.Client.Patch(ctx, &lease, original); err != nil {
+	if err := s.retryWrite(ctx, &lease, func() error {
+		original := client.MergeFrom(lease.DeepCopy())
+		lease.Spec.Release = true
+		return s.Client.Patch(ctx, &lease, original)
+	}); err != nil {
 		return nil, err
 	}
 
@@ -651,6 +1138,10 @@ func (s *ControllerService) ListLeases(
 		return nil, err
 	}
 
+	if err := s.legacyLeaseRPC("ListLeases", jclient); err != nil {
+		return nil, err
+	}
+
 	var leases jumpstarterdevv1alpha1.LeaseList
 	if err := s.Client.List(
 		ctx,
@@ -676,40 +1167,110 @@ func (s *ControllerService) ListLeases(
 func (s *ControllerService) Start(ctx context.Context) error {
 	logger := log.FromContext(ctx)
 
-	dnsnames, ipaddresses, err := endpointToSAN(controllerEndpoint())
-	if err != nil {
-		return err
+	listeners := s.Listeners
+	if len(listeners) == 0 {
+		listeners = DefaultControllerListeners()
+	}
+	for i := range listeners {
+		if err := listeners[i].validate(s.CertificateAuth); err != nil {
+			return fmt.Errorf("Start: %w", err)
+		}
 	}
 
-	cert, err := NewSelfSignedCertificate("jumpstarter controller", dnsnames, ipaddresses)
-	if err != nil {
-		return err
+	var creds credentials.TransportCredentials
+	if slices.ContainsFunc(listeners, func(listener ControllerListener) bool {
+		return listener.TLSTermination != tlsTerminationEdge
+	}) {
+		dnsnames, ipaddresses, err := endpointToSAN(controllerEndpoint())
+		if err != nil {
+			return err
+		}
+
+		cert, err := s.SelfSignedCertificate.Certificate(ctx, s.Client,
+			"jumpstarter-controller-serving-cert", "jumpstarter controller", dnsnames, ipaddresses)
+		if err != nil {
+			return fmt.Errorf("Start: %w", err)
+		}
+
+		if err := publishServingCA(ctx, s.Client, "jumpstarter-controller-serving-ca", cert); err != nil {
+			return err
+		}
+
+		creds = serverCredentials(s.CertificateAuth, cert)
 	}
 
-	server := grpc.NewServer(grpc.Creds(credentials.NewServerTLSFromCert(cert)))
+	servers := make([]*grpc.Server, 0, len(listeners))
+	netListeners := make([]net.Listener, 0, len(listeners))
+	for i := range listeners {
+		listener := &listeners[i]
 
-	pb.RegisterControllerServiceServer(server, s)
+		var opts []grpc.ServerOption
+		if listener.TLSTermination == tlsTerminationEdge {
+			logger.Info("TLS terminated by edge proxy, serving cleartext gRPC", "listener", listener.Name)
+		} else {
+			opts = append(opts, grpc.Creds(creds))
+		}
 
-	// Register reflection service on gRPC server.
-	reflection.Register(server)
+		opts = append(opts, s.Keepalive.serverOptions()...)
+		opts = append(opts, headerInterceptors(s.Keepalive.metadata())...)
+		opts = append(opts, listener.interceptors()...)
+		opts = append(opts, s.authExemptions().interceptors()...)
+		opts = append(opts, interceptors.ServerOptions(s.Interceptors)...)
 
-	listener, err := net.Listen("tcp", ":8082")
-	if err != nil {
-		return err
+		server := grpc.NewServer(opts...)
+
+		pb.RegisterControllerServiceServer(server, s)
+		server.RegisterService(&clientServiceDesc, s)
+		server.RegisterService(&exporterServiceDesc, exporterService{s})
+		server.RegisterService(&adminServiceDesc, adminService{s})
+		if s.Transfers != nil {
+			server.RegisterService(&transferServiceDesc, s)
+		}
+		healthpb.RegisterHealthServer(server, s.healthServer())
+
+		listener.disabledEndpoints(s.DisabledEndpoints).register(server)
+
+		netListener, err := net.Listen("tcp", listener.Address)
+		if err != nil {
+			for _, l := range netListeners {
+				_ = l.Close()
+			}
+			return fmt.Errorf("Start: listener %s: %w", listener.Name, err)
+		}
+		servers = append(servers, server)
+		netListeners = append(netListeners, netListener)
 	}
 
 	logger.Info("Starting Controller grpc service")
+	s.healthServer().Resume()
 
 	go func() {
 		<-ctx.Done()
 		logger.Info("Stopping Controller gRPC service")
-		server.Stop()
+		s.healthServer().Shutdown()
+		for _, server := range servers {
+			server.Stop()
+		}
 	}()
 
-	return server.Serve(listener)
+	// the first listener to fail stops the others
+	errs := make(chan error, len(servers))
+	for i := range servers {
+		go func() {
+			errs <- servers[i].Serve(netListeners[i])
+		}()
+	}
+	err := <-errs
+	for _, server := range servers {
+		server.Stop()
+	}
+	return err
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (s *ControllerService) SetupWithManager(mgr ctrl.Manager) error {
+	if err := s.watchLeases(mgr); err != nil {
+		return err
+	}
 	return mgr.Add(s)
 }
@@ -36,6 +36,10 @@ var fs embed.FS
 type DashboardService struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// Address the dashboard listens on, defaults to :8084
+	BindAddress string
+	// Serve the REST API of the console plugins under ConsoleAPIPrefix
+	ConsoleAPI bool
 }
 
 func (s *DashboardService) Start(ctx context.Context) error {
@@ -69,7 +73,16 @@ func (s *DashboardService) Start(ctx context.Context) error {
 		})
 	})
 
-	return r.Run(":8084")
+	if s.ConsoleAPI {
+		(&consoleAPI{client: s.Client}).register(r)
+	}
+
+	address := s.BindAddress
+	if address == "" {
+		address = ":8084"
+	}
+
+	return r.Run(address)
 }
 
 // SetupWithManager sets up the controller with the Manager.
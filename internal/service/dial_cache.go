@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	pb "github.com/jumpstarter-dev/jumpstarter-controller/internal/protocol/jumpstarter/v1"
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// IdempotencyKeyHeader is the metadata key clients set on Dial so that retries
+// of the same request return the original response instead of queueing a new stream
+const IdempotencyKeyHeader = "x-jumpstarter-idempotency-key"
+
+// dialCacheTTL is how long Dial responses are kept for deduplication
+const dialCacheTTL = 5 * time.Minute
+
+// IdempotencyKeyFromContext returns the idempotency key of the request, or "" if not set
+func IdempotencyKeyFromContext(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", nil
+	}
+
+	keys := md.Get(IdempotencyKeyHeader)
+	if len(keys) > 1 {
+		return "", status.Errorf(codes.InvalidArgument, "multiple idempotency key headers")
+	}
+	if len(keys) == 0 {
+		return "", nil
+	}
+	return keys[0], nil
+}
+
+type dialCacheEntry struct {
+	response *pb.DialResponse
+	expires  time.Time
+}
+
+// dialCache deduplicates Dial requests carrying the same idempotency key
+type dialCache struct {
+	mu      sync.Mutex
+	entries map[string]dialCacheEntry
+	group   singleflight.Group
+}
+
+func (c *dialCache) get(key string, now time.Time) *pb.DialResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+
+	if entry, ok := c.entries[key]; ok {
+		return entry.response
+	}
+	return nil
+}
+
+func (c *dialCache) put(key string, response *pb.DialResponse, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]dialCacheEntry)
+	}
+	c.entries[key] = dialCacheEntry{
+		response: response,
+		expires:  now.Add(dialCacheTTL),
+	}
+}
+
+// do returns the cached response for key, or calls dial once for concurrent
+// requests with the same key and caches its response, dial is detached from
+// the context of any one request and bounded by timeout instead, so that the
+// request which started it going away does not fail the others
+func (c *dialCache) do(
+	ctx context.Context,
+	key string,
+	timeout time.Duration,
+	dial func(context.Context) (*pb.DialResponse, error),
+) (*pb.DialResponse, bool, error) {
+	if response := c.get(key, time.Now()); response != nil {
+		return response, true, nil
+	}
+
+	results := c.group.DoChan(key, func() (interface{}, error) {
+		if response := c.get(key, time.Now()); response != nil {
+			return response, nil
+		}
+		dialCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+		defer cancel()
+		response, err := dial(dialCtx)
+		if err != nil {
+			return nil, err
+		}
+		c.put(key, response, time.Now())
+		return response, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, false, status.FromContextError(ctx.Err()).Err()
+	case result := <-results:
+		if result.Err != nil {
+			return nil, result.Shared, result.Err
+		}
+		return result.Val.(*pb.DialResponse), result.Shared, nil
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/jumpstarter-dev/jumpstarter-controller/internal/protocol/jumpstarter/v1"
+)
+
+var _ = Describe("Dial cache", func() {
+	var cache *dialCache
+	var dials atomic.Int32
+
+	BeforeEach(func() {
+		cache = &dialCache{}
+		dials.Store(0)
+	})
+
+	// dial returns a new response once release is closed or its context is done
+	dial := func(release <-chan struct{}) func(context.Context) (*pb.DialResponse, error) {
+		return func(ctx context.Context) (*pb.DialResponse, error) {
+			n := dials.Add(1)
+			select {
+			case <-release:
+				return &pb.DialResponse{RouterToken: strconv.Itoa(int(n))}, nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	}
+
+	It("should dial once for concurrent requests with the same key", func() {
+		release := make(chan struct{})
+		responses := make(chan *pb.DialResponse, 2)
+		for range 2 {
+			go func() {
+				defer GinkgoRecover()
+				response, _, err := cache.do(context.Background(), "key", time.Minute, dial(release))
+				Expect(err).NotTo(HaveOccurred())
+				responses <- response
+			}()
+		}
+		Eventually(dials.Load).Should(BeEquivalentTo(1))
+		close(release)
+
+		first, second := <-responses, <-responses
+		Expect(first.RouterToken).To(Equal(second.RouterToken))
+
+		response, cached, err := cache.do(context.Background(), "key", time.Minute, dial(release))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cached).To(BeTrue())
+		Expect(response.RouterToken).To(Equal(first.RouterToken))
+		Expect(dials.Load()).To(BeEquivalentTo(1))
+	})
+
+	It("should dial again once the cached response expired", func() {
+		release := make(chan struct{})
+		close(release)
+		cache.put("key", &pb.DialResponse{RouterToken: "expired"}, time.Now().Add(-dialCacheTTL-time.Second))
+
+		response, cached, err := cache.do(context.Background(), "key", time.Minute, dial(release))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cached).To(BeFalse())
+		Expect(response.RouterToken).NotTo(Equal("expired"))
+		Expect(dials.Load()).To(BeEquivalentTo(1))
+	})
+
+	It("should not fail the retries when the first request is cancelled", func() {
+		release := make(chan struct{})
+		ctx, cancel := context.WithCancel(context.Background())
+		errs := make(chan error, 1)
+		go func() {
+			_, _, err := cache.do(ctx, "key", time.Minute, dial(release))
+			errs <- err
+		}()
+		Eventually(dials.Load).Should(BeEquivalentTo(1))
+
+		cancel()
+		Expect(status.Code(<-errs)).To(Equal(codes.Canceled))
+
+		responses := make(chan *pb.DialResponse, 1)
+		go func() {
+			defer GinkgoRecover()
+			response, _, err := cache.do(context.Background(), "key", time.Minute, dial(release))
+			Expect(err).NotTo(HaveOccurred())
+			responses <- response
+		}()
+		close(release)
+
+		Eventually(responses).Should(Receive(Not(BeNil())))
+		Expect(dials.Load()).To(BeEquivalentTo(1))
+	})
+
+	It("should bound the shared dial by its timeout", func() {
+		_, _, err := cache.do(context.Background(), "key", 50*time.Millisecond, dial(make(chan struct{})))
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+})
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// DialTimeoutHeader makes Dial wait, as long as the duration it holds, e.g. 30s, for the exporter
+// to pick up the dial from its Listen stream, instead of returning once the dial is queued
+const DialTimeoutHeader = "x-jumpstarter-dial-timeout"
+
+// DialTimeoutReason is the reason of the ErrorInfo detail of the DEADLINE_EXCEEDED errors returned
+// by Dial when the exporter did not pick up the dial in time, the dial can be retried
+const DialTimeoutReason = "DIAL_TIMEOUT"
+
+// defaultMaxDialTimeout bounds the dial timeouts when not configured
+const defaultMaxDialTimeout = 5 * time.Minute
+
+// DialTimeoutFromContext returns the dial timeout of the request, or 0 if not set
+func DialTimeoutFromContext(ctx context.Context) (time.Duration, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return 0, nil
+	}
+
+	values := md.Get(DialTimeoutHeader)
+	if len(values) > 1 {
+		return 0, status.Errorf(codes.InvalidArgument, "multiple %s headers", DialTimeoutHeader)
+	}
+	if len(values) == 0 {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(values[0])
+	if err != nil {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid %s header: %s", DialTimeoutHeader, err)
+	}
+	if timeout < 0 {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid %s header: negative timeout", DialTimeoutHeader)
+	}
+	return timeout, nil
+}
+
+// maxDialTimeout returns the longest timeout Dial waits for the exporters
+func (s *ControllerService) maxDialTimeout() time.Duration {
+	if s.MaxDialTimeout <= 0 {
+		return defaultMaxDialTimeout
+	}
+	return s.MaxDialTimeout
+}
+
+// dialTimeoutError returns the DEADLINE_EXCEEDED error of a dial to exporter not picked up within timeout
+func dialTimeoutError(exporter string, timeout time.Duration) error {
+	st := status.Newf(codes.DeadlineExceeded, "exporter %s did not pick up the dial within %s", exporter, timeout)
+	detailed, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason: DialTimeoutReason,
+		Domain: "jumpstarter.dev",
+		Metadata: map[string]string{
+			"exporter": exporter,
+			"timeout":  timeout.String(),
+		},
+	})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// dialWaitError returns the error of a dial to exporter that stopped waiting, DEADLINE_EXCEEDED if
+// its timeout or the deadline of its call expired, CANCELLED if its call was cancelled
+func dialWaitError(ctx context.Context, exporter string, timeout time.Duration) error {
+	switch ctx.Err() {
+	case nil:
+		return dialTimeoutError(exporter, timeout)
+	case context.Canceled:
+		return status.Errorf(codes.Canceled, "dial of exporter %s cancelled", exporter)
+	default:
+		return status.Errorf(codes.DeadlineExceeded, "exporter %s did not pick up the dial", exporter)
+	}
+}
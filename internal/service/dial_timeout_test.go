@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+var _ = Describe("Dial timeout", func() {
+	var s *ControllerService
+	var lease *jumpstarterdevv1alpha1.Lease
+	jclient := &jumpstarterdevv1alpha1.Client{}
+
+	BeforeEach(func() {
+		lease = newTestLease("lease", "client", "exporter")
+		s = newTestService(lease)
+		jclient.Namespace, jclient.Name = "default", "client"
+	})
+
+	// dial dials the exporter of the lease with the dial timeout header set to timeout
+	dial := func(ctx context.Context, timeout string) error {
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(DialTimeoutHeader, timeout))
+		parsed, err := DialTimeoutFromContext(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = s.dial(ctx, jclient, lease, "exporter", parsed)
+		return err
+	}
+
+	It("should return DEADLINE_EXCEEDED with the DIAL_TIMEOUT reason when the exporter does not listen", func() {
+		err := dial(context.Background(), "50ms")
+		Expect(status.Code(err)).To(Equal(codes.DeadlineExceeded))
+		details := status.Convert(err).Details()
+		Expect(details).To(HaveLen(1))
+		Expect(details[0].(*errdetails.ErrorInfo).Reason).To(Equal(DialTimeoutReason))
+	})
+
+	It("should return DEADLINE_EXCEEDED when the deadline of the call expires first", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		err := dial(ctx, "1m")
+		Expect(status.Code(err)).To(Equal(codes.DeadlineExceeded))
+		Expect(status.Convert(err).Details()).To(BeEmpty())
+	})
+
+	It("should return CANCELLED when the call is cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+		err := dial(ctx, "1m")
+		Expect(status.Code(err)).To(Equal(codes.Canceled))
+	})
+
+	It("should return once the exporter picks up the dial", func() {
+		go func() {
+			defer GinkgoRecover()
+			queue, ok := s.listenQueues.get(lease.UID, "exporter")
+			Expect(ok).To(BeTrue())
+			queued := <-queue.dials
+			close(queued.delivered)
+		}()
+		Expect(dial(context.Background(), "1m")).To(Succeed())
+	})
+})
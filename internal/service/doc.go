@@ -0,0 +1,70 @@
+// Package service implements the gRPC services of the controller and of the routers.
+//
+// # Protocol extensions
+//
+// The controller serves the ControllerService and the RouterService of jumpstarter-protocol as
+// generated in internal/protocol. What the protocol has no room for yet is served without
+// changing its messages, so that existing clients and exporters keep working, either as request
+// and response headers of the protocol calls, all prefixed with x-jumpstarter-, or as additional
+// services, whose messages are google.protobuf.Struct holding JSON request and response types
+// until they are generated from jumpstarter-protocol. Clients discover them with GetServerInfo,
+// the ones they use are defined in pkg/api.
+//
+// Additional services:
+//
+//	api.ClientServiceName    ListLeasableExporters, ResolveSelector, CheckLease, PauseLease,
+//	                         ResumeLease, ExtendLease, GetServerInfo, and the lease metadata
+//	                         methods for the leases of the client
+//	api.ExporterServiceName  ListLeaseMetadata, GetLeaseMetadata, SetLeaseMetadata and
+//	                         DeleteLeaseMetadata for the leases holding the exporter
+//	api.TransferServiceName  NegotiateTransfer, when the transfers are offloaded to object stores
+//	api.AdminServiceName     ListExporterLabelHistory, RestoreExporterLabels, UnpinExporterLabels,
+//	                         CordonExporter, UncordonExporter and DrainExporter for the cluster
+//	                         users allowed to get or patch the exporter
+//
+// Request headers of RequestLease:
+//
+//	api.PriorityClassHeader        the LeasePriorityClass of the lease
+//	LeaseTemplateHeader            the LeaseTemplate the lease is defaulted from
+//	LeaseRoleHeader                a role of the lease, once per role
+//	api.SharedLeaseHeader          whether the lease may be observed by the clients allowed to lease
+//	api.FailIfUnsatisfiableHeader  fails instead of creating an unsatisfiable lease
+//	api.ClampDurationHeader        shortens the duration to the maximum instead of failing
+//
+// Response headers of GetLease:
+//
+//	api.QueuePositionHeader       the position of a pending lease in the queue
+//	api.EstimatedBeginTimeHeader  when a pending lease is estimated to acquire an exporter
+//	RoleExporterHeader            the exporter of a role of the lease, once per exporter
+//
+// Headers of ListExporters:
+//
+//	ResourceVersionHeader  the resourceVersion of the list, in the request and the response
+//	ExporterQueryHeader    a CEL expression the exporters are filtered with
+//
+// Request headers of Dial:
+//
+//	DialModeHeader        DialModeObserve for a receive-only token
+//	ExporterHeader        the exporter linked to the leased exporter to connect to
+//	IdempotencyKeyHeader  returns the original response to the retries of a dial
+//	DialTimeoutHeader     waits for the exporter to pick up the dial
+//
+// Request headers of Status:
+//
+//	MultipleLeasesHeader  the exporter serves several leases at the same time
+//
+// Headers of the router Stream:
+//
+//	api.PeerHeader          the side of the stream
+//	api.LeaseHeader         the lease the stream belongs to
+//	api.OptionHeaderPrefix  the stream options offered by a side, and agreed on
+//	api.ObserveHeader       the side whose frames an observer receives
+//	RouterEndpointHeader    the replica adopting a handed off stream, with UNAVAILABLE
+//	ResumedHeader           a handed off stream is paired again
+//
+// Response headers of every call:
+//
+//	KeepaliveMinPingIntervalHeader      the minimum interval between client pings
+//	KeepalivePermitWithoutStreamHeader  whether clients may ping without active streams
+//	KeepaliveIdleTimeoutHeader          how long a connection may stay idle
+package service
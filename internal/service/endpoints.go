@@ -21,6 +21,18 @@ func routerEndpoint() string {
 	return ep
 }
 
+// TLS is terminated in front of the service by an edge proxy,
+// which forwards the requests as cleartext HTTP/2 (h2c)
+const tlsTerminationEdge = "edge"
+
+func controllerTLSTermination() string {
+	return os.Getenv("GRPC_TLS_TERMINATION")
+}
+
+func routerTLSTermination() string {
+	return os.Getenv("GRPC_ROUTER_TLS_TERMINATION")
+}
+
 func endpointToSAN(endpoint string) ([]string, []net.IP, error) {
 	host, _, err := net.SplitHostPort(endpoint)
 	if err != nil {
@@ -33,3 +45,22 @@ func endpointToSAN(endpoint string) ([]string, []net.IP, error) {
 		return []string{host}, []net.IP{}, nil
 	}
 }
+
+// routerReplicaEndpoint is the endpoint reaching this router replica specifically,
+// the shared router endpoint if the replicas are not addressable on their own
+func routerReplicaEndpoint() string {
+	ep := os.Getenv("GRPC_ROUTER_REPLICA_ENDPOINT")
+	if ep == "" {
+		return routerEndpoint()
+	}
+	return ep
+}
+
+// routerReplicaName identifies this router replica in the router registry
+func routerReplicaName() string {
+	if name := os.Getenv("POD_NAME"); name != "" {
+		return name
+	}
+	name, _ := os.Hostname()
+	return name
+}
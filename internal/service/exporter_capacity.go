@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"google.golang.org/grpc/metadata"
+	"k8s.io/apimachinery/pkg/types"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	pb "github.com/jumpstarter-dev/jumpstarter-controller/internal/protocol/jumpstarter/v1"
+)
+
+// MultipleLeasesHeader is set by the exporters serving several leases at the same time on their
+// Status call, recorded as the MultipleLeases of their status, only these exporters are assigned
+// up to their MaxConcurrentLeases. The stream then reports each lease beginning as a leased
+// StatusResponse and each lease ending as an unleased StatusResponse naming it, instead of only
+// the first active lease
+const MultipleLeasesHeader = "x-jumpstarter-multiple-leases"
+
+// multipleLeasesRequested reports whether the Status call asks for every lease of the exporter
+func multipleLeasesRequested(ctx context.Context) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+	values := md.Get(MultipleLeasesHeader)
+	return len(values) == 1 && values[0] == "true"
+}
+
+// exporterLeaseNames returns the names of the active leases holding exporter, LeaseRef alone
+// until the exporter controller reported LeaseRefs
+func exporterLeaseNames(exporter *jumpstarterdevv1alpha1.Exporter) []string {
+	var names []string
+	for _, ref := range exporter.Status.LeaseRefs {
+		names = append(names, ref.Name)
+	}
+	if len(names) == 0 && exporter.Status.LeaseRef != nil {
+		names = append(names, exporter.Status.LeaseRef.Name)
+	}
+	return names
+}
+
+// leaseStatus returns the leased StatusResponse of the lease of exporter named name
+func (s *ControllerService) leaseStatus(
+	ctx context.Context,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+	name string,
+) (*pb.StatusResponse, error) {
+	var lease jumpstarterdevv1alpha1.Lease
+	if err := s.Client.Get(
+		ctx,
+		types.NamespacedName{Namespace: exporter.Namespace, Name: name},
+		&lease,
+	); err != nil {
+		return nil, fmt.Errorf("leaseStatus: failed to get lease on exporter: %w", err)
+	}
+	return &pb.StatusResponse{
+		Leased:     true,
+		LeaseName:  &lease.Name,
+		ClientName: &lease.Spec.ClientRef.Name,
+	}, nil
+}
+
+// leaseStatusChanges returns the StatusResponses reporting the leases of exporter beginning and
+// ending since the leases named held, and the names of the leases now holding the exporter
+func (s *ControllerService) leaseStatusChanges(
+	ctx context.Context,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+	held []string,
+) ([]*pb.StatusResponse, []string, error) {
+	names := exporterLeaseNames(exporter)
+
+	var responses []*pb.StatusResponse
+	for _, name := range held {
+		if !slices.Contains(names, name) {
+			responses = append(responses, &pb.StatusResponse{
+				Leased:    false,
+				LeaseName: &name,
+			})
+		}
+	}
+	for _, name := range names {
+		if slices.Contains(held, name) {
+			continue
+		}
+		response, err := s.leaseStatus(ctx, exporter, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		responses = append(responses, response)
+	}
+	return responses, names, nil
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/internal/controller"
+)
+
+// maxExporterErrorLength bounds the messages recorded in the exporter status
+const maxExporterErrorLength = 1024
+
+// recordExporterError records err as the last error of exporter failing operation, counting the
+// consecutive failures, the recording itself failing is only logged
+func (s *ControllerService) recordExporterError(
+	ctx context.Context,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+	operation string,
+	err error,
+) {
+	count := int32(1)
+	if last := exporter.Status.LastError; last != nil {
+		count = last.Count + 1
+	}
+	message := err.Error()
+	if len(message) > maxExporterErrorLength {
+		message = message[:maxExporterErrorLength]
+	}
+	lastError := &jumpstarterdevv1alpha1.ExporterError{
+		Operation: operation,
+		Message:   message,
+		Count:     count,
+		Time:      metav1.Time{Time: time.Now()},
+	}
+	if err := s.applyExporterStatus(ctx, exporter, errorFieldManager, jumpstarterdevv1alpha1.ExporterStatus{
+		LastError: lastError,
+	}); err != nil {
+		log.FromContext(ctx).Error(err, "unable to record exporter error")
+		return
+	}
+	exporter.Status.LastError = lastError
+}
+
+// clearExporterError removes the last error of exporter once it registered or connected again
+func (s *ControllerService) clearExporterError(ctx context.Context, exporter *jumpstarterdevv1alpha1.Exporter) {
+	if exporter.Status.LastError == nil {
+		return
+	}
+	if err := s.applyExporterStatus(ctx, exporter, errorFieldManager,
+		jumpstarterdevv1alpha1.ExporterStatus{}); err != nil {
+		log.FromContext(ctx).Error(err, "unable to clear exporter error")
+		return
+	}
+	exporter.Status.LastError = nil
+}
+
+// recordAuthenticationError records err as the last error of the exporter the bearer token of ctx
+// was issued to, only for tokens signed by the controller, e.g. issued to a recreated exporter
+func (s *ControllerService) recordAuthenticationError(ctx context.Context, operation string, err error) {
+	token, tokenErr := BearerTokenFromContext(ctx)
+	if tokenErr != nil {
+		return
+	}
+	claims, tokenErr := controller.ParseObjectToken(
+		token,
+		"https://jumpstarter.dev/controller",
+		"https://jumpstarter.dev/controller",
+	)
+	if tokenErr != nil || claims.Kind != "Exporter" {
+		return
+	}
+	var exporter jumpstarterdevv1alpha1.Exporter
+	if err := s.Client.Get(ctx, types.NamespacedName{
+		Namespace: claims.Namespace,
+		Name:      claims.Name,
+	}, &exporter); err != nil {
+		return
+	}
+	s.recordExporterError(ctx, &exporter, operation, err)
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// ResourceVersionHeader is the metadata key of the resourceVersion of the exporter list,
+// returned in the ListExporters response headers, and set by clients on ListExporters:
+//
+//	unset  a list at most ListExportersCacheTTL old
+//	"0"    any list, possibly stale, served by the cache or the API server watch cache
+//	other  the consistent snapshot at exactly that resourceVersion, e.g. to page through
+//	       the same list again, failing with OutOfRange once the API server compacted it
+const ResourceVersionHeader = "x-jumpstarter-resource-version"
+
+// ResourceVersionFromContext returns the resourceVersion requested, or "" if not set
+func ResourceVersionFromContext(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", nil
+	}
+
+	versions := md.Get(ResourceVersionHeader)
+	if len(versions) > 1 {
+		return "", status.Errorf(codes.InvalidArgument, "multiple resource version headers")
+	}
+	if len(versions) == 0 {
+		return "", nil
+	}
+	return versions[0], nil
+}
+
+type exporterListCacheEntry struct {
+	list    *jumpstarterdevv1alpha1.ExporterList
+	expires time.Time
+}
+
+// exporterListCache keeps the recent exporter lists by namespace and label selector,
+// so that dashboards refreshing every few seconds do not list every exporter each time
+type exporterListCache struct {
+	mu      sync.Mutex
+	entries map[string]exporterListCacheEntry
+	group   singleflight.Group
+}
+
+func (c *exporterListCache) get(key string, resourceVersion string, now time.Time) *jumpstarterdevv1alpha1.ExporterList {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	// resourceVersions are opaque, only equal ones denote the same snapshot
+	if resourceVersion != "" && resourceVersion != "0" && resourceVersion != entry.list.ResourceVersion {
+		return nil
+	}
+	return entry.list
+}
+
+func (c *exporterListCache) put(key string, list *jumpstarterdevv1alpha1.ExporterList, expires time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]exporterListCacheEntry)
+	}
+	c.entries[key] = exporterListCacheEntry{
+		list:    list,
+		expires: expires,
+	}
+}
+
+// listExporters lists the exporters of namespace, all namespaces if empty, matching selector,
+// at the resourceVersion of the request, from the cache if ListExportersCacheTTL is set,
+// and sets the resourceVersion of the list in the response headers
+func (s *ControllerService) listExporters(
+	ctx context.Context,
+	namespace string,
+	selector labels.Selector,
+) ([]jumpstarterdevv1alpha1.Exporter, error) {
+	resourceVersion, err := ResourceVersionFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list := func() (*jumpstarterdevv1alpha1.ExporterList, error) {
+		var exporters jumpstarterdevv1alpha1.ExporterList
+		opts := &client.ListOptions{
+			Namespace:     namespace,
+			LabelSelector: selector,
+			Raw:           &metav1.ListOptions{ResourceVersion: resourceVersion},
+		}
+		if resourceVersion != "" && resourceVersion != "0" {
+			opts.Raw.ResourceVersionMatch = metav1.ResourceVersionMatchExact
+		}
+		if err := s.Client.List(ctx, &exporters, opts); err != nil {
+			if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+				return nil, status.Errorf(codes.OutOfRange, "resource version %s is too old", resourceVersion)
+			}
+			return nil, fmt.Errorf("listExporters: %w", err)
+		}
+		return &exporters, nil
+	}
+
+	var exporters *jumpstarterdevv1alpha1.ExporterList
+	if s.ListExportersCacheTTL <= 0 {
+		exporters, err = list()
+	} else {
+		key := namespace + "/" + selector.String()
+		exporters = s.exporterLists.get(key, resourceVersion, time.Now())
+		if exporters == nil {
+			var shared any
+			shared, err, _ = s.exporterLists.group.Do(key+"@"+resourceVersion, func() (any, error) {
+				if cached := s.exporterLists.get(key, resourceVersion, time.Now()); cached != nil {
+					return cached, nil
+				}
+				listed, err := list()
+				if err != nil {
+					return nil, err
+				}
+				// only the latest list is reused, not the snapshots requested by resourceVersion
+				if resourceVersion == "" {
+					s.exporterLists.put(key, listed, time.Now().Add(s.ListExportersCacheTTL))
+				}
+				return listed, nil
+			})
+			if err == nil {
+				exporters = shared.(*jumpstarterdevv1alpha1.ExporterList)
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := grpc.SetHeader(ctx, metadata.Pairs(ResourceVersionHeader, exporters.ResourceVersion)); err != nil {
+		return nil, fmt.Errorf("listExporters: %w", err)
+	}
+	// the cached items are shared, callers only filter them into new slices
+	return exporters.Items, nil
+}
+
+// listExportersError returns the status errors of listExporters as is, and hides the others
+func listExportersError(ctx context.Context, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	log.FromContext(ctx).Error(err, "unable to list exporters")
+	return status.Errorf(codes.Internal, "unable to list exporters")
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// ExporterQueryHeader is the metadata key clients set on ListExporters to filter the exporters
+// with a CEL expression, evaluated against the variables:
+//
+//	name     string
+//	labels   map(string, string)
+//	notes    string, the jumpstarter.dev/notes annotation
+//	devices  list(map(string, dyn)), each with uuid, parent_uuid and labels
+//	cordoned bool, whether new leases do not acquire the exporter, cordoned or drained
+//	drained  bool, whether the exporter is drained, kept offline
+//
+// e.g. labels["board-type"] == "rpi4" && devices.exists(d, d.labels["iface"] == "can")
+const ExporterQueryHeader = "x-jumpstarter-exporter-query"
+
+// exporterQueryCostLimit bounds the evaluation cost of a query against a single exporter
+const exporterQueryCostLimit = 100000
+
+// ExporterQueryFromContext returns the exporter query of the request, or "" if not set
+func ExporterQueryFromContext(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", nil
+	}
+
+	queries := md.Get(ExporterQueryHeader)
+	if len(queries) > 1 {
+		return "", status.Errorf(codes.InvalidArgument, "multiple exporter query headers")
+	}
+	if len(queries) == 0 {
+		return "", nil
+	}
+	return queries[0], nil
+}
+
+var exporterQueryEnv = func() *cel.Env {
+	env, err := cel.NewEnv(
+		cel.Variable("name", cel.StringType),
+		cel.Variable("labels", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("notes", cel.StringType),
+		cel.Variable("devices", cel.ListType(cel.MapType(cel.StringType, cel.DynType))),
+		cel.Variable("cordoned", cel.BoolType),
+		cel.Variable("drained", cel.BoolType),
+	)
+	if err != nil {
+		panic(err)
+	}
+	return env
+}()
+
+// exporterQuery is a compiled exporter query
+type exporterQuery struct {
+	program cel.Program
+}
+
+// compileExporterQuery compiles query, returning an InvalidArgument error if it is not a valid
+// boolean expression
+func compileExporterQuery(query string) (*exporterQuery, error) {
+	ast, issues := exporterQueryEnv.Compile(query)
+	if issues != nil && issues.Err() != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid exporter query: %s", issues.Err())
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"invalid exporter query: must evaluate to bool, not %s", ast.OutputType())
+	}
+
+	program, err := exporterQueryEnv.Program(ast, cel.CostLimit(exporterQueryCostLimit))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid exporter query: %s", err)
+	}
+	return &exporterQuery{program: program}, nil
+}
+
+// matches reports whether exporter matches the query, an expression failing to evaluate,
+// e.g. on a missing label, does not match
+func (q *exporterQuery) matches(exporter *jumpstarterdevv1alpha1.Exporter) bool {
+	devices := make([]map[string]any, 0, len(exporter.Status.Devices))
+	for _, device := range exporter.Status.Devices {
+		labels := device.Labels
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		var parentUuid string
+		if device.ParentUuid != nil {
+			parentUuid = *device.ParentUuid
+		}
+		devices = append(devices, map[string]any{
+			"uuid":        device.Uuid,
+			"parent_uuid": parentUuid,
+			"labels":      labels,
+		})
+	}
+
+	labels := exporter.Labels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+
+	out, _, err := q.program.Eval(map[string]any{
+		"name":     exporter.Name,
+		"labels":   labels,
+		"notes":    exporter.Annotations[jumpstarterdevv1alpha1.ExporterAnnotationNotes],
+		"devices":  devices,
+		"cordoned": exporter.Spec.Unschedulable || exporter.Spec.Drain,
+		"drained":  exporterDrained(exporter),
+	})
+	if err != nil {
+		return false
+	}
+	return out == types.True
+}
+
+// filter returns the exporters matching the query
+func (q *exporterQuery) filter(exporters []jumpstarterdevv1alpha1.Exporter) []jumpstarterdevv1alpha1.Exporter {
+	matched := make([]jumpstarterdevv1alpha1.Exporter, 0, len(exporters))
+	for i := range exporters {
+		if q.matches(&exporters[i]) {
+			matched = append(matched, exporters[i])
+		}
+	}
+	return matched
+}
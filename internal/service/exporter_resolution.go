@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/internal/controller"
+	"github.com/jumpstarter-dev/jumpstarter-controller/pkg/api"
+)
+
+// ResolveSelector returns the exporters a lease of the caller with the selector of the request,
+// defaulted from its LeaseTemplate if any, could acquire, with their availability and how the
+// leases of the caller on them are granted
+func (s *ControllerService) ResolveSelector(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	logger := log.FromContext(ctx)
+
+	jclient, err := s.authenticateClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var req api.ResolveSelectorRequest
+	if err := decodeStruct(in, &req); err != nil {
+		return nil, err
+	}
+
+	leaseSelector, err := metav1.ParseToLabelSelector(req.Selector)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid selector: %s", err)
+	}
+	lease := &jumpstarterdevv1alpha1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: jclient.Namespace,
+		},
+		Spec: jumpstarterdevv1alpha1.LeaseSpec{
+			ClientRef:    corev1.LocalObjectReference{Name: jclient.Name},
+			ClientGroups: jclient.Status.Groups,
+			Selector:     *leaseSelector,
+		},
+	}
+	if req.Template != "" {
+		if err := s.defaultLeaseFromTemplate(ctx, lease, req.Template); err != nil {
+			return nil, err
+		}
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(&lease.Spec.Selector)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid selector: %s", err)
+	}
+
+	exporters, err := s.listExporters(ctx, jclient.Namespace, selector)
+	if err != nil {
+		return nil, listExportersError(ctx, err)
+	}
+
+	state := &controller.AllocationState{
+		Lease:                lease,
+		Client:               jclient,
+		AccessPolicyTieBreak: s.AccessPolicyTieBreak,
+	}
+	policies, err := controller.ListAccessPolicies(ctx, s.Client, jclient.Namespace)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to list exporter access policies: %s", err)
+	}
+	state.AccessPolicies = policies
+	var windows jumpstarterdevv1alpha1.MaintenanceWindowList
+	if err := s.Client.List(ctx, &windows, client.InNamespace(jclient.Namespace)); err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to list maintenance windows: %s", err)
+	}
+	state.MaintenanceWindows = windows.Items
+	var classes jumpstarterdevv1alpha1.LeasePriorityClassList
+	if err := s.Client.List(ctx, &classes, client.InNamespace(jclient.Namespace)); err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to list lease priority classes: %s", err)
+	}
+	state.PriorityClasses = classes.Items
+	class := controller.LeasePriorityClassOf(state.PriorityClasses, lease)
+
+	now := time.Now()
+	response := api.ResolveSelectorResponse{Exporters: []api.ResolvedExporter{}}
+	for i := range exporters {
+		exporter := &exporters[i]
+		leasable, err := s.leasableExporter(state.AccessPolicies, jclient, exporter, now)
+		if err != nil {
+			logger.Error(err, "unable to evaluate exporter access policies")
+			return nil, status.Errorf(codes.Internal, "unable to evaluate exporter access policies")
+		} else if leasable == nil {
+			continue
+		}
+		if allowed, err := controller.ExporterAffinityAllows(lease, exporter); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid affinity: %s", err)
+		} else if !allowed {
+			continue
+		}
+
+		resolved := api.ResolvedExporter{LeasableExporter: *leasable}
+		resolved.Available, resolved.Reason = exporterAvailability(ctx, state, exporter)
+		if class != nil {
+			resolved.Priority = int(class.Spec.Value)
+		}
+		response.Exporters = append(response.Exporters, resolved)
+	}
+
+	return encodeStruct(response)
+}
+
+// exporterAvailability returns whether the lease of state could acquire exporter right now, and
+// why not otherwise
+func exporterAvailability(
+	ctx context.Context,
+	state *controller.AllocationState,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+) (bool, string) {
+	for _, filter := range []controller.FilterPlugin{
+		controller.OnlineFilter{},
+		controller.SchedulableFilter{},
+		controller.NotUpdatingFilter{},
+		controller.MaintenanceFilter{},
+	} {
+		if filter.Filter(ctx, state, exporter) != controller.FilterCodeSuccess {
+			return false, filter.Name()
+		}
+	}
+	if len(exporterLeaseNames(exporter)) >= exporter.LeaseCapacity() {
+		return false, "Leased"
+	}
+	return true, ""
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// The controller service writes the exporter status with server-side apply, each path under
+// its own field manager, so concurrent writers only ever replace the fields they own
+const (
+	// owns the Registered condition, the devices and the label history
+	registerFieldManager = "jumpstarter-controller-register"
+	// owns the Online condition
+	statusFieldManager = "jumpstarter-controller-status"
+	// owns the last error
+	errorFieldManager = "jumpstarter-controller-errors"
+)
+
+// applyExporterStatus applies status to exporter as manager, status must only contain
+// the fields owned by manager, the fields it previously set and omits are removed
+func (s *ControllerService) applyExporterStatus(
+	ctx context.Context,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+	manager string,
+	status jumpstarterdevv1alpha1.ExporterStatus,
+) error {
+	patch := &jumpstarterdevv1alpha1.Exporter{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: jumpstarterdevv1alpha1.GroupVersion.String(),
+			Kind:       "Exporter",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      exporter.Name,
+			Namespace: exporter.Namespace,
+		},
+		Status: status,
+	}
+	return s.Client.Status().Patch(ctx, patch, client.Apply, client.FieldOwner(manager), client.ForceOwnership)
+}
+
+// exporterDrained reports whether exporter is drained, disconnected and kept offline
+func exporterDrained(exporter *jumpstarterdevv1alpha1.Exporter) bool {
+	return meta.IsStatusConditionTrue(exporter.Status.Conditions, string(jumpstarterdevv1alpha1.ExporterConditionTypeDrained))
+}
+
+// exporterCondition returns condition as it would be set on exporter,
+// keeping the last transition time when the status does not change
+func exporterCondition(exporter *jumpstarterdevv1alpha1.Exporter, condition metav1.Condition) metav1.Condition {
+	conditions := slices.Clone(exporter.Status.Conditions)
+	meta.SetStatusCondition(&conditions, condition)
+	return *meta.FindStatusCondition(conditions, condition.Type)
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/internal/controller"
+)
+
+// visibleExporters filters exporters down to the ones jclient is allowed to lease
+// according to the ExporterAccessPolicies and reservations in its namespace
+func (s *ControllerService) visibleExporters(
+	ctx context.Context,
+	jclient *jumpstarterdevv1alpha1.Client,
+	exporters []jumpstarterdevv1alpha1.Exporter,
+) ([]jumpstarterdevv1alpha1.Exporter, error) {
+	policies, err := controller.ListAccessPolicies(ctx, s.Client, jclient.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("visibleExporters: %w", err)
+	}
+
+	now := time.Now()
+	visible := make([]jumpstarterdevv1alpha1.Exporter, 0, len(exporters))
+	for i := range exporters {
+		allowed, err := controller.ClientCanLease(policies, s.AccessPolicyTieBreak, jclient, &exporters[i], now)
+		if err != nil {
+			return nil, fmt.Errorf("visibleExporters: %w", err)
+		}
+		if allowed {
+			visible = append(visible, exporters[i])
+		}
+	}
+	return visible, nil
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// grpcHealth holds the gRPC health service of a server, it reports NOT_SERVING
+// until the server is listening, and is used to back the manager readiness checks
+type grpcHealth struct {
+	once   sync.Once
+	server *health.Server
+}
+
+func (h *grpcHealth) healthServer() *health.Server {
+	h.once.Do(func() {
+		h.server = health.NewServer()
+		h.server.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	})
+	return h.server
+}
+
+// ReadyzCheck implements healthz.Checker on top of the gRPC health service
+func (h *grpcHealth) ReadyzCheck(req *http.Request) error {
+	resp, err := h.healthServer().Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return err
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("gRPC service is %s", resp.GetStatus())
+	}
+	return nil
+}
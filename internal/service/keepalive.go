@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+)
+
+// The keepalive policy is sent in the response headers of every call, so clients
+// can configure keepalives the server does not answer with GOAWAY too_many_pings
+const (
+	KeepaliveMinPingIntervalHeader     = "x-jumpstarter-keepalive-min-ping-interval"
+	KeepalivePermitWithoutStreamHeader = "x-jumpstarter-keepalive-permit-without-stream"
+	KeepaliveIdleTimeoutHeader         = "x-jumpstarter-keepalive-idle-timeout"
+)
+
+// KeepalivePolicy is the keepalive policy the gRPC services enforce on clients
+type KeepalivePolicy struct {
+	// The minimum interval between client pings, clients pinging more often are disconnected
+	MinPingInterval time.Duration
+	// Whether clients may ping without active streams
+	PermitWithoutStream bool
+	// How long a connection may stay idle before it is closed, 0 for forever
+	IdleTimeout time.Duration
+}
+
+// DefaultKeepalivePolicy is the keepalive policy of services not configured otherwise
+var DefaultKeepalivePolicy = KeepalivePolicy{
+	MinPingInterval:     10 * time.Second,
+	PermitWithoutStream: true,
+}
+
+func (p KeepalivePolicy) withDefaults() KeepalivePolicy {
+	if p == (KeepalivePolicy{}) {
+		return DefaultKeepalivePolicy
+	}
+	return p
+}
+
+func (p KeepalivePolicy) serverOptions() []grpc.ServerOption {
+	p = p.withDefaults()
+	opts := []grpc.ServerOption{
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             p.MinPingInterval,
+			PermitWithoutStream: p.PermitWithoutStream,
+		}),
+	}
+	if p.IdleTimeout > 0 {
+		opts = append(opts, grpc.KeepaliveParams(keepalive.ServerParameters{
+			MaxConnectionIdle: p.IdleTimeout,
+		}))
+	}
+	return opts
+}
+
+// metadata returns the policy as response headers, durations in milliseconds
+func (p KeepalivePolicy) metadata() metadata.MD {
+	p = p.withDefaults()
+	return metadata.Pairs(
+		KeepaliveMinPingIntervalHeader, strconv.FormatInt(p.MinPingInterval.Milliseconds(), 10),
+		KeepalivePermitWithoutStreamHeader, strconv.FormatBool(p.PermitWithoutStream),
+		KeepaliveIdleTimeoutHeader, strconv.FormatInt(p.IdleTimeout.Milliseconds(), 10),
+	)
+}
+
+// headerInterceptors send md in the response headers of every call
+func headerInterceptors(md metadata.MD) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(
+			ctx context.Context,
+			req any,
+			_ *grpc.UnaryServerInfo,
+			handler grpc.UnaryHandler,
+		) (any, error) {
+			_ = grpc.SetHeader(ctx, md)
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(
+			srv any,
+			ss grpc.ServerStream,
+			_ *grpc.StreamServerInfo,
+			handler grpc.StreamHandler,
+		) error {
+			_ = ss.SetHeader(md)
+			return handler(srv, ss)
+		}),
+	}
+}
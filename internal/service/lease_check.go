@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/internal/controller"
+	"github.com/jumpstarter-dev/jumpstarter-controller/pkg/api"
+)
+
+// CheckLease evaluates the lease of the request against the exporters, access policies and quotas
+// of the namespace of the caller without creating it
+func (s *ControllerService) CheckLease(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	jclient, err := s.authenticateClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var req api.CheckLeaseRequest
+	if err := decodeStruct(in, &req); err != nil {
+		return nil, err
+	}
+
+	selector, err := metav1.ParseToLabelSelector(req.Selector)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid selector: %s", err)
+	}
+	priorityClassName, err := s.checkPriorityClass(ctx, jclient.Namespace, req.PriorityClassName)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := parseLeaseRoles(req.Roles)
+	if err != nil {
+		return nil, err
+	}
+
+	lease := &jumpstarterdevv1alpha1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: jclient.Namespace,
+		},
+		Spec: jumpstarterdevv1alpha1.LeaseSpec{
+			ClientRef:         corev1.LocalObjectReference{Name: jclient.Name},
+			ClientGroups:      jclient.Status.Groups,
+			Duration:          req.Duration,
+			Selector:          *selector,
+			PriorityClassName: priorityClassName,
+			Roles:             roles,
+			Shared:            req.Shared,
+		},
+	}
+	if req.Template != "" {
+		if err := s.defaultLeaseFromTemplate(ctx, lease, req.Template); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.enforceLeaseDuration(ctx, jclient, lease, req.ClampDuration); err != nil {
+		return nil, err
+	}
+
+	checker := &controller.LeaseReconciler{
+		Client:               s.Client,
+		Scheme:               s.Scheme,
+		Allocator:            s.Allocator,
+		AccessPolicyTieBreak: s.AccessPolicyTieBreak,
+	}
+	check, err := checker.CheckLease(ctx, lease)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to check lease: %s", err)
+	}
+
+	return encodeStruct(api.CheckLeaseResponse{
+		LeaseCheck: api.LeaseCheck{
+			Result:             api.LeaseCheckResult(check.Result),
+			Reason:             check.Reason,
+			Message:            check.Message,
+			Exporter:           check.Exporter,
+			QueuePosition:      check.QueuePosition,
+			EstimatedBeginTime: check.EstimatedBeginTime,
+		},
+		Duration: lease.Spec.Duration,
+	})
+}
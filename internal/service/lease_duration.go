@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/internal/controller"
+	"github.com/jumpstarter-dev/jumpstarter-controller/pkg/api"
+)
+
+// ClampDurationFromContext reports whether the request set api.ClampDurationHeader
+func ClampDurationFromContext(ctx context.Context) (bool, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false, nil
+	}
+
+	values := md.Get(api.ClampDurationHeader)
+	if len(values) > 1 {
+		return false, status.Errorf(codes.InvalidArgument, "multiple %s headers", api.ClampDurationHeader)
+	}
+	if len(values) == 0 {
+		return false, nil
+	}
+	enabled, err := strconv.ParseBool(values[0])
+	if err != nil {
+		return false, status.Errorf(codes.InvalidArgument, "invalid %s header: %s", api.ClampDurationHeader, err)
+	}
+	return enabled, nil
+}
+
+// enforceLeaseDuration defaults the duration of the lease to the default lease duration of its
+// namespace, and returns INVALID_ARGUMENT if it exceeds the maximum lease duration of the namespace,
+// or naming the ExporterAccessPolicy if none of the exporters matching the lease the client may
+// lease allows it, it shortens the duration to the maximum instead if clamp is set
+func (s *ControllerService) enforceLeaseDuration(
+	ctx context.Context,
+	jclient *jumpstarterdevv1alpha1.Client,
+	lease *jumpstarterdevv1alpha1.Lease,
+	clamp bool,
+) error {
+	limit := s.DurationLimits.For(lease.Namespace)
+	if lease.Spec.Duration.Duration == 0 {
+		lease.Spec.Duration = limit.Default
+	}
+	if limit.Exceeded(lease.Spec.Duration.Duration) {
+		if !clamp {
+			return status.Errorf(codes.InvalidArgument, "duration %s exceeds the maximum lease duration %s",
+				lease.Spec.Duration.Duration, limit.Maximum.Duration)
+		}
+		log.FromContext(ctx).Info("clamping lease duration", "duration", lease.Spec.Duration.Duration,
+			"maximum", limit.Maximum.Duration)
+		lease.Spec.Duration = limit.Maximum
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(&lease.Spec.Selector)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid selector: %s", err)
+	}
+
+	var exporters jumpstarterdevv1alpha1.ExporterList
+	if err := s.Client.List(
+		ctx,
+		&exporters,
+		client.InNamespace(lease.Namespace),
+		client.MatchingLabelsSelector{Selector: selector},
+	); err != nil {
+		return err
+	}
+
+	policies, err := controller.ListAccessPolicies(ctx, s.Client, lease.Namespace)
+	if err != nil {
+		return err
+	}
+
+	violation, err := controller.LeaseDurationViolation(
+		policies, s.AccessPolicyTieBreak, jclient, exporters.Items, lease.Spec.Duration.Duration, time.Now())
+	if err != nil {
+		return status.Errorf(codes.Internal, "%s", err)
+	}
+	if violation == nil {
+		return nil
+	}
+	if !clamp {
+		return status.Errorf(codes.InvalidArgument, "duration %s %s", lease.Spec.Duration.Duration, violation)
+	}
+	log.FromContext(ctx).Info("clamping lease duration", "duration", lease.Spec.Duration.Duration,
+		"maximum", violation.Maximum, "policy", violation.PolicyName)
+	lease.Spec.Duration.Duration = violation.Maximum
+	return nil
+}
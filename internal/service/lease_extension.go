@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/internal/controller"
+	"github.com/jumpstarter-dev/jumpstarter-controller/pkg/api"
+)
+
+// ExtendLease sets the duration of a running lease of the caller, if the access policies granting
+// it allow the longer duration, and no other lease or reservation of its exporters begins before
+// the extended lease ends
+func (s *ControllerService) ExtendLease(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	logger := log.FromContext(ctx)
+
+	jclient, err := s.authenticateClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var req api.ExtendLeaseRequest
+	if err := decodeStruct(in, &req); err != nil {
+		return nil, err
+	}
+	if req.Lease == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "empty lease name")
+	}
+	if req.Duration.Duration <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "duration must be positive")
+	}
+
+	var lease jumpstarterdevv1alpha1.Lease
+	if err := s.Client.Get(ctx, types.NamespacedName{
+		Namespace: jclient.Namespace,
+		Name:      req.Lease,
+	}, &lease); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, status.Errorf(codes.NotFound, "lease %s not found", req.Lease)
+		}
+		return nil, status.Errorf(codes.Internal, "unable to get lease: %s", err)
+	}
+	if lease.Spec.ClientRef.Name != jclient.Name {
+		return nil, status.Errorf(codes.PermissionDenied, "lease %s is not held by the client", lease.Name)
+	}
+
+	var exporters []jumpstarterdevv1alpha1.Exporter
+	for _, name := range controller.LeaseExporters(&lease) {
+		var exporter jumpstarterdevv1alpha1.Exporter
+		if err := s.Client.Get(ctx, types.NamespacedName{Namespace: lease.Namespace, Name: name}, &exporter); err != nil {
+			logger.Error(err, "unable to get exporter of lease", "exporter", name)
+			return nil, status.Errorf(codes.Internal, "unable to get exporter %s", name)
+		}
+		exporters = append(exporters, exporter)
+	}
+
+	policies, err := controller.ListAccessPolicies(ctx, s.Client, lease.Namespace)
+	if err != nil {
+		logger.Error(err, "unable to list exporter access policies")
+		return nil, status.Errorf(codes.Internal, "unable to list exporter access policies")
+	}
+	var leases jumpstarterdevv1alpha1.LeaseList
+	if err := s.Client.List(
+		ctx, &leases, client.InNamespace(lease.Namespace), controller.MatchingActiveLeases(),
+	); err != nil {
+		logger.Error(err, "unable to list active leases")
+		return nil, status.Errorf(codes.Internal, "unable to list active leases")
+	}
+
+	if err := controller.ExtendLease(
+		&lease,
+		req.Duration.Duration,
+		policies,
+		s.AccessPolicyTieBreak,
+		controller.LeaseClient(jclient, &lease),
+		exporters,
+		leases.Items,
+		time.Now(),
+	); err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "unable to extend lease: %s", err)
+	}
+
+	// conditional on the resource version, the checks must not be made against a stale lease
+	if err := s.Client.Update(ctx, &lease); err != nil {
+		if apierrors.IsConflict(err) {
+			return nil, status.Errorf(codes.Aborted, "lease %s changed while being extended, retry", lease.Name)
+		}
+		logger.Error(err, "unable to update lease")
+		return nil, status.Errorf(codes.Internal, "unable to update lease")
+	}
+
+	logger.Info("Extended lease", "lease", lease.Name, "duration", lease.Spec.Duration.Duration)
+	return encodeStruct(api.ExtendLeaseResponse{Duration: lease.Spec.Duration})
+}
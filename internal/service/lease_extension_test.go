@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+var _ = Describe("ExtendLease", func() {
+	var s *ControllerService
+	var lease *jumpstarterdevv1alpha1.Lease
+	var exporter *jumpstarterdevv1alpha1.Exporter
+	var objects []client.Object
+	jclient := &jumpstarterdevv1alpha1.Client{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "default",
+		Name:      "client",
+		UID:       "client-uid",
+	}}
+	policy := &jumpstarterdevv1alpha1.ExporterAccessPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "policy"},
+		Spec: jumpstarterdevv1alpha1.ExporterAccessPolicySpec{
+			Policies: []jumpstarterdevv1alpha1.Policy{{
+				From:            []jumpstarterdevv1alpha1.From{{}},
+				MaximumDuration: &metav1.Duration{Duration: 2 * time.Hour},
+			}},
+		},
+	}
+
+	BeforeEach(func() {
+		lease = newTestLease("lease", "client", "exporter")
+		lease.Status.BeginTime = &metav1.Time{Time: time.Now().Add(-10 * time.Minute)}
+		exporter = &jumpstarterdevv1alpha1.Exporter{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "exporter",
+		}}
+		objects = []client.Object{jclient.DeepCopy(), policy.DeepCopy()}
+	})
+
+	// extend extends the lease to duration as its client
+	extend := func(duration time.Duration) error {
+		s = newTestService(append(objects, lease, exporter)...)
+		in, err := structpb.NewStruct(map[string]any{"lease": lease.Name, "duration": duration.String()})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = s.ExtendLease(tokenContext(context.Background(), s, jclient), in)
+		return err
+	}
+
+	// duration returns the duration of the lease stored by the service
+	duration := func() time.Duration {
+		var stored jumpstarterdevv1alpha1.Lease
+		Expect(s.Client.Get(context.Background(), client.ObjectKeyFromObject(lease), &stored)).To(Succeed())
+		return stored.Spec.Duration.Duration
+	}
+
+	It("should extend the lease within the maximum duration of its policy", func() {
+		Expect(extend(90 * time.Minute)).To(Succeed())
+		Expect(duration()).To(Equal(90 * time.Minute))
+	})
+
+	It("should reject a duration over the maximum duration of its policy", func() {
+		err := extend(3 * time.Hour)
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+		Expect(duration()).To(Equal(time.Hour))
+	})
+
+	It("should reject a duration overlapping an upcoming reservation of the exporter", func() {
+		exporter.Spec.Reservations = []jumpstarterdevv1alpha1.ExporterReservation{{
+			ClientRef: &corev1.LocalObjectReference{Name: "other"},
+			Begin:     metav1.Time{Time: time.Now().Add(time.Hour)},
+			End:       metav1.Time{Time: time.Now().Add(2 * time.Hour)},
+		}}
+		err := extend(90 * time.Minute)
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+		Expect(duration()).To(Equal(time.Hour))
+	})
+
+	It("should reject a duration overlapping an upcoming lease of the exporter", func() {
+		objects = append(objects, &jumpstarterdevv1alpha1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "upcoming"},
+			Spec: jumpstarterdevv1alpha1.LeaseSpec{
+				ClientRef: corev1.LocalObjectReference{Name: "other"},
+				Duration:  metav1.Duration{Duration: time.Hour},
+				BeginTime: &metav1.Time{Time: time.Now().Add(time.Hour)},
+			},
+			Status: jumpstarterdevv1alpha1.LeaseStatus{
+				ReservedExporterRef: &corev1.LocalObjectReference{Name: "exporter"},
+			},
+		})
+		err := extend(90 * time.Minute)
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+		Expect(duration()).To(Equal(time.Hour))
+	})
+
+	It("should reject the leases of other clients", func() {
+		lease.Spec.ClientRef.Name = "other"
+		Expect(status.Code(extend(90 * time.Minute))).To(Equal(codes.PermissionDenied))
+	})
+
+	It("should reject the leases not running", func() {
+		lease.Status.Ended = true
+		Expect(status.Code(extend(90 * time.Minute))).To(Equal(codes.FailedPrecondition))
+	})
+
+	It("should be served on the client service", func() {
+		Expect(clientServiceDesc.Methods).To(ContainElement(HaveField("MethodName", "ExtendLease")))
+	})
+})
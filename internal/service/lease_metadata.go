@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/internal/controller"
+	"github.com/jumpstarter-dev/jumpstarter-controller/pkg/api"
+)
+
+type exporterServer interface {
+	ListLeaseMetadata(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	GetLeaseMetadata(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	SetLeaseMetadata(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	DeleteLeaseMetadata(context.Context, *structpb.Struct) (*structpb.Struct, error)
+}
+
+var exporterServiceDesc = grpc.ServiceDesc{
+	ServiceName: api.ExporterServiceName,
+	HandlerType: (*exporterServer)(nil),
+	Methods: []grpc.MethodDesc{
+		structMethod(api.ExporterServiceName, "ListLeaseMetadata", exporterServer.ListLeaseMetadata),
+		structMethod(api.ExporterServiceName, "GetLeaseMetadata", exporterServer.GetLeaseMetadata),
+		structMethod(api.ExporterServiceName, "SetLeaseMetadata", exporterServer.SetLeaseMetadata),
+		structMethod(api.ExporterServiceName, "DeleteLeaseMetadata", exporterServer.DeleteLeaseMetadata),
+	},
+	Metadata: "exporter",
+}
+
+// exporterService serves the ExporterService of s, whose methods share their names with the
+// methods of the ClientService served by s itself
+type exporterService struct {
+	s *ControllerService
+}
+
+// leaseMetadataCaller is the client or the exporter calling a lease metadata method
+type leaseMetadataCaller struct {
+	namespace string
+	// holds fails with PERMISSION_DENIED for the leases the caller may not access the metadata of
+	holds func(lease *jumpstarterdevv1alpha1.Lease) error
+}
+
+// clientCaller authenticates the client calling, it may access the metadata of its own leases
+func (s *ControllerService) clientCaller(ctx context.Context) (*leaseMetadataCaller, error) {
+	jclient, err := s.authenticateClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &leaseMetadataCaller{
+		namespace: jclient.Namespace,
+		holds: func(lease *jumpstarterdevv1alpha1.Lease) error {
+			if lease.Spec.ClientRef.Name != jclient.Name {
+				return status.Errorf(codes.PermissionDenied, "lease %s is not held by the client", lease.Name)
+			}
+			return nil
+		},
+	}, nil
+}
+
+// exporterCaller authenticates the exporter calling, it may access the metadata of the leases
+// holding it
+func (s *ControllerService) exporterCaller(ctx context.Context) (*leaseMetadataCaller, error) {
+	exporter, err := s.authenticateExporter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &leaseMetadataCaller{
+		namespace: exporter.Namespace,
+		holds: func(lease *jumpstarterdevv1alpha1.Lease) error {
+			if !controller.LeaseHoldsExporter(lease, exporter.Name) {
+				return status.Errorf(codes.PermissionDenied, "lease %s does not hold the exporter", lease.Name)
+			}
+			return nil
+		},
+	}, nil
+}
+
+// ListLeaseMetadata returns the metadata of a lease of the caller
+func (s *ControllerService) ListLeaseMetadata(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	return s.listLeaseMetadata(ctx, in, s.clientCaller)
+}
+
+// GetLeaseMetadata returns a metadata key of a lease of the caller
+func (s *ControllerService) GetLeaseMetadata(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	return s.getLeaseMetadata(ctx, in, s.clientCaller)
+}
+
+// SetLeaseMetadata sets a metadata key of an active lease of the caller
+func (s *ControllerService) SetLeaseMetadata(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	return s.setLeaseMetadata(ctx, in, s.clientCaller)
+}
+
+// DeleteLeaseMetadata deletes a metadata key of an active lease of the caller
+func (s *ControllerService) DeleteLeaseMetadata(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	return s.deleteLeaseMetadata(ctx, in, s.clientCaller)
+}
+
+// ListLeaseMetadata returns the metadata of a lease holding the caller
+func (e exporterService) ListLeaseMetadata(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	return e.s.listLeaseMetadata(ctx, in, e.s.exporterCaller)
+}
+
+// GetLeaseMetadata returns a metadata key of a lease holding the caller
+func (e exporterService) GetLeaseMetadata(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	return e.s.getLeaseMetadata(ctx, in, e.s.exporterCaller)
+}
+
+// SetLeaseMetadata sets a metadata key of an active lease holding the caller
+func (e exporterService) SetLeaseMetadata(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	return e.s.setLeaseMetadata(ctx, in, e.s.exporterCaller)
+}
+
+// DeleteLeaseMetadata deletes a metadata key of an active lease holding the caller
+func (e exporterService) DeleteLeaseMetadata(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	return e.s.deleteLeaseMetadata(ctx, in, e.s.exporterCaller)
+}
+
+func (s *ControllerService) listLeaseMetadata(
+	ctx context.Context,
+	in *structpb.Struct,
+	authenticate func(context.Context) (*leaseMetadataCaller, error),
+) (*structpb.Struct, error) {
+	_, lease, err := s.leaseMetadataRequest(ctx, in, authenticate)
+	if err != nil {
+		return nil, err
+	}
+	return encodeStruct(leaseMetadataResponse(lease))
+}
+
+func (s *ControllerService) getLeaseMetadata(
+	ctx context.Context,
+	in *structpb.Struct,
+	authenticate func(context.Context) (*leaseMetadataCaller, error),
+) (*structpb.Struct, error) {
+	req, lease, err := s.leaseMetadataRequest(ctx, in, authenticate)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := lease.Status.Metadata[req.Key]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "metadata key %s not found on lease %s", req.Key, lease.Name)
+	}
+	return encodeStruct(api.LeaseMetadataResponse{Metadata: map[string]string{req.Key: value}})
+}
+
+func (s *ControllerService) setLeaseMetadata(
+	ctx context.Context,
+	in *structpb.Struct,
+	authenticate func(context.Context) (*leaseMetadataCaller, error),
+) (*structpb.Struct, error) {
+	req, lease, err := s.leaseMetadataRequest(ctx, in, authenticate)
+	if err != nil {
+		return nil, err
+	}
+	return s.writeLeaseMetadata(ctx, lease, func() error {
+		if err := controller.SetLeaseMetadata(lease, req.Key, req.Value); err != nil {
+			if errors.Is(err, controller.ErrLeaseMetadataLimit) {
+				return status.Errorf(codes.ResourceExhausted, "%s", err)
+			}
+			return status.Errorf(codes.InvalidArgument, "%s", err)
+		}
+		return nil
+	})
+}
+
+func (s *ControllerService) deleteLeaseMetadata(
+	ctx context.Context,
+	in *structpb.Struct,
+	authenticate func(context.Context) (*leaseMetadataCaller, error),
+) (*structpb.Struct, error) {
+	req, lease, err := s.leaseMetadataRequest(ctx, in, authenticate)
+	if err != nil {
+		return nil, err
+	}
+	return s.writeLeaseMetadata(ctx, lease, func() error {
+		controller.DeleteLeaseMetadata(lease, req.Key)
+		return nil
+	})
+}
+
+// leaseMetadataRequest authenticates the caller and decodes in, returning the lease it applies to
+func (s *ControllerService) leaseMetadataRequest(
+	ctx context.Context,
+	in *structpb.Struct,
+	authenticate func(context.Context) (*leaseMetadataCaller, error),
+) (*api.LeaseMetadataRequest, *jumpstarterdevv1alpha1.Lease, error) {
+	caller, err := authenticate(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var req api.LeaseMetadataRequest
+	if err := decodeStruct(in, &req); err != nil {
+		return nil, nil, err
+	}
+	if req.Lease == "" {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "empty lease name")
+	}
+
+	var lease jumpstarterdevv1alpha1.Lease
+	if err := s.Client.Get(ctx, types.NamespacedName{
+		Namespace: caller.namespace,
+		Name:      req.Lease,
+	}, &lease); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil, status.Errorf(codes.NotFound, "lease %s not found", req.Lease)
+		}
+		return nil, nil, status.Errorf(codes.Internal, "unable to get lease: %s", err)
+	}
+	if err := caller.holds(&lease); err != nil {
+		return nil, nil, err
+	}
+	return &req, &lease, nil
+}
+
+// writeLeaseMetadata applies update to the metadata of the active lease, conditional on its
+// resource version, so that the size limits are checked against the metadata it replaces
+func (s *ControllerService) writeLeaseMetadata(
+	ctx context.Context,
+	lease *jumpstarterdevv1alpha1.Lease,
+	update func() error,
+) (*structpb.Struct, error) {
+	if err := s.retryWrite(ctx, lease, func() error {
+		if lease.Spec.Release || lease.Status.Ended {
+			return status.Errorf(codes.FailedPrecondition, "lease %s has ended", lease.Name)
+		}
+		original := client.MergeFromWithOptions(lease.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		if err := update(); err != nil {
+			return err
+		}
+		return s.Client.Status().Patch(ctx, lease, original)
+	}); err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		log.FromContext(ctx).Error(err, "unable to update lease metadata")
+		return nil, status.Errorf(codes.Internal, "unable to update lease metadata")
+	}
+	return encodeStruct(leaseMetadataResponse(lease))
+}
+
+// leaseMetadataResponse returns the metadata of lease
+func leaseMetadataResponse(lease *jumpstarterdevv1alpha1.Lease) api.LeaseMetadataResponse {
+	metadata := map[string]string{}
+	for key, value := range lease.Status.Metadata {
+		metadata[key] = value
+	}
+	return api.LeaseMetadataResponse{Metadata: metadata}
+}
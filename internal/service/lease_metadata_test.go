@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/internal/controller"
+)
+
+var _ = Describe("Lease metadata", func() {
+	var s *ControllerService
+	var lease *jumpstarterdevv1alpha1.Lease
+	objectMeta := func(name string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Namespace: "default", Name: name, UID: types.UID(name + "-uid")}
+	}
+	jclient := &jumpstarterdevv1alpha1.Client{ObjectMeta: objectMeta("client")}
+	other := &jumpstarterdevv1alpha1.Client{ObjectMeta: objectMeta("other")}
+	exporter := &jumpstarterdevv1alpha1.Exporter{ObjectMeta: objectMeta("exporter")}
+	otherExporter := &jumpstarterdevv1alpha1.Exporter{ObjectMeta: objectMeta("other-exporter")}
+
+	BeforeEach(func() {
+		lease = newTestLease("lease", "client", "exporter")
+		lease.Status.Metadata = map[string]string{"dut.jumpstarter.dev/ip": "192.0.2.1"}
+		s = newTestService(lease, jclient.DeepCopy(), other.DeepCopy(), exporter.DeepCopy(), otherExporter.DeepCopy())
+	})
+
+	// request returns the request of the metadata methods for key and value on the lease
+	request := func(key string, value string) *structpb.Struct {
+		in, err := structpb.NewStruct(map[string]any{"lease": lease.Name, "key": key, "value": value})
+		Expect(err).NotTo(HaveOccurred())
+		return in
+	}
+
+	// stored returns the metadata of the lease stored by the service
+	stored := func() map[string]string {
+		var stored jumpstarterdevv1alpha1.Lease
+		Expect(s.Client.Get(context.Background(), client.ObjectKeyFromObject(lease), &stored)).To(Succeed())
+		return stored.Status.Metadata
+	}
+
+	metadata := func(out *structpb.Struct) map[string]any {
+		return out.AsMap()["metadata"].(map[string]any)
+	}
+
+	Context("on the client service", func() {
+		var ctx context.Context
+
+		BeforeEach(func() {
+			ctx = tokenContext(context.Background(), s, jclient)
+		})
+
+		It("should list, get, set and delete the metadata of the leases of the client", func() {
+			out, err := s.ListLeaseMetadata(ctx, request("", ""))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(metadata(out)).To(Equal(map[string]any{"dut.jumpstarter.dev/ip": "192.0.2.1"}))
+
+			_, err = s.SetLeaseMetadata(ctx, request("dut.jumpstarter.dev/serial", "/dev/ttyUSB0"))
+			Expect(err).NotTo(HaveOccurred())
+			out, err = s.GetLeaseMetadata(ctx, request("dut.jumpstarter.dev/serial", ""))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(metadata(out)).To(Equal(map[string]any{"dut.jumpstarter.dev/serial": "/dev/ttyUSB0"}))
+
+			_, err = s.DeleteLeaseMetadata(ctx, request("dut.jumpstarter.dev/ip", ""))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stored()).To(Equal(map[string]string{"dut.jumpstarter.dev/serial": "/dev/ttyUSB0"}))
+
+			_, err = s.GetLeaseMetadata(ctx, request("dut.jumpstarter.dev/ip", ""))
+			Expect(status.Code(err)).To(Equal(codes.NotFound))
+		})
+
+		It("should deny the other clients", func() {
+			ctx = tokenContext(context.Background(), s, other)
+			_, err := s.ListLeaseMetadata(ctx, request("", ""))
+			Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+			_, err = s.SetLeaseMetadata(ctx, request("key", "value"))
+			Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+			Expect(stored()).NotTo(HaveKey("key"))
+		})
+
+		It("should enforce the size limit", func() {
+			_, err := s.SetLeaseMetadata(ctx, request("large", strings.Repeat("x", controller.MaxLeaseMetadataBytes)))
+			Expect(status.Code(err)).To(Equal(codes.ResourceExhausted))
+			Expect(stored()).NotTo(HaveKey("large"))
+		})
+
+		It("should reject invalid keys", func() {
+			_, err := s.SetLeaseMetadata(ctx, request("not a key", "value"))
+			Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		})
+
+		It("should only write the metadata of active leases", func() {
+			lease.Spec.Release = true
+			Expect(s.Client.Update(context.Background(), lease)).To(Succeed())
+
+			_, err := s.SetLeaseMetadata(ctx, request("key", "value"))
+			Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+			_, err = s.DeleteLeaseMetadata(ctx, request("dut.jumpstarter.dev/ip", ""))
+			Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+			Expect(stored()).To(HaveKey("dut.jumpstarter.dev/ip"))
+		})
+	})
+
+	Context("on the exporter service", func() {
+		It("should let the exporters of the lease set its metadata", func() {
+			ctx := tokenContext(context.Background(), s, exporter)
+			_, err := exporterService{s}.SetLeaseMetadata(ctx, request("dut.jumpstarter.dev/ip", "192.0.2.2"))
+			Expect(err).NotTo(HaveOccurred())
+			out, err := exporterService{s}.ListLeaseMetadata(ctx, request("", ""))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(metadata(out)).To(Equal(map[string]any{"dut.jumpstarter.dev/ip": "192.0.2.2"}))
+		})
+
+		It("should deny the exporters not held by the lease", func() {
+			ctx := tokenContext(context.Background(), s, otherExporter)
+			_, err := exporterService{s}.GetLeaseMetadata(ctx, request("dut.jumpstarter.dev/ip", ""))
+			Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+			_, err = exporterService{s}.DeleteLeaseMetadata(ctx, request("dut.jumpstarter.dev/ip", ""))
+			Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+			Expect(stored()).To(HaveKey("dut.jumpstarter.dev/ip"))
+		})
+	})
+})
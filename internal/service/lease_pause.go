@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/pkg/api"
+)
+
+// PauseLease pauses the countdown of the duration of a running lease of the caller, the lease
+// keeps its exporters until it is resumed or reaches the maximum pause of its policies
+func (s *ControllerService) PauseLease(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	return s.setLeasePaused(ctx, in, true)
+}
+
+// ResumeLease resumes the countdown of the duration of a paused lease of the caller
+func (s *ControllerService) ResumeLease(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	return s.setLeasePaused(ctx, in, false)
+}
+
+func (s *ControllerService) setLeasePaused(
+	ctx context.Context,
+	in *structpb.Struct,
+	paused bool,
+) (*structpb.Struct, error) {
+	jclient, err := s.authenticateClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var req api.PauseLeaseRequest
+	if err := decodeStruct(in, &req); err != nil {
+		return nil, err
+	}
+	if req.Lease == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "empty lease name")
+	}
+
+	var lease jumpstarterdevv1alpha1.Lease
+	if err := s.Client.Get(ctx, types.NamespacedName{
+		Namespace: jclient.Namespace,
+		Name:      req.Lease,
+	}, &lease); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, status.Errorf(codes.NotFound, "lease %s not found", req.Lease)
+		}
+		return nil, status.Errorf(codes.Internal, "unable to get lease: %s", err)
+	}
+	if lease.Spec.ClientRef.Name != jclient.Name {
+		return nil, status.Errorf(codes.PermissionDenied, "lease %s is not held by the client", lease.Name)
+	}
+	if lease.Status.Ended || lease.Status.BeginTime == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "lease %s is not running", lease.Name)
+	}
+
+	if err := s.retryWrite(ctx, &lease, func() error {
+		original := client.MergeFrom(lease.DeepCopy())
+		lease.Spec.Paused = paused
+		return s.Client.Patch(ctx, &lease, original)
+	}); err != nil {
+		return nil, err
+	}
+
+	return encodeStruct(api.PauseLeaseResponse{})
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"strconv"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/internal/controller"
+	"github.com/jumpstarter-dev/jumpstarter-controller/pkg/api"
+)
+
+// FailIfUnsatisfiableFromContext reports whether the request set api.FailIfUnsatisfiableHeader
+func FailIfUnsatisfiableFromContext(ctx context.Context) (bool, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false, nil
+	}
+
+	values := md.Get(api.FailIfUnsatisfiableHeader)
+	if len(values) > 1 {
+		return false, status.Errorf(codes.InvalidArgument, "multiple %s headers", api.FailIfUnsatisfiableHeader)
+	}
+	if len(values) == 0 {
+		return false, nil
+	}
+	enabled, err := strconv.ParseBool(values[0])
+	if err != nil {
+		return false, status.Errorf(codes.InvalidArgument, "invalid %s header: %s", api.FailIfUnsatisfiableHeader, err)
+	}
+	return enabled, nil
+}
+
+// checkLeaseSatisfiable returns FAILED_PRECONDITION if no exporter in the namespace of the lease
+// could ever be assigned to it, considering the ExporterAccessPolicies
+func (s *ControllerService) checkLeaseSatisfiable(
+	ctx context.Context,
+	jclient *jumpstarterdevv1alpha1.Client,
+	lease *jumpstarterdevv1alpha1.Lease,
+) error {
+	selector, err := metav1.LabelSelectorAsSelector(&lease.Spec.Selector)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid selector: %s", err)
+	}
+
+	var exporters jumpstarterdevv1alpha1.ExporterList
+	if err := s.Client.List(
+		ctx,
+		&exporters,
+		client.InNamespace(lease.Namespace),
+		client.MatchingLabelsSelector{Selector: selector},
+	); err != nil {
+		return err
+	}
+
+	policies, err := controller.ListAccessPolicies(ctx, s.Client, lease.Namespace)
+	if err != nil {
+		return err
+	}
+
+	if !controller.LeaseSatisfiable(ctx, &controller.AllocationState{
+		Lease:                lease,
+		Client:               jclient,
+		AccessPolicies:       policies,
+		AccessPolicyTieBreak: s.AccessPolicyTieBreak,
+	}, exporters.Items) {
+		return status.Errorf(codes.FailedPrecondition,
+			"no exporter matching %s could ever satisfy the lease", selector)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/pkg/api"
+)
+
+// priorityClassFromContext returns the LeasePriorityClass named by api.PriorityClassHeader,
+// empty if the request did not set it, and checks that the class exists in namespace
+func (s *ControllerService) priorityClassFromContext(ctx context.Context, namespace string) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", nil
+	}
+
+	values := md.Get(api.PriorityClassHeader)
+	if len(values) > 1 {
+		return "", status.Errorf(codes.InvalidArgument, "multiple %s headers", api.PriorityClassHeader)
+	}
+	if len(values) == 0 {
+		return "", nil
+	}
+
+	return s.checkPriorityClass(ctx, namespace, values[0])
+}
+
+// checkPriorityClass returns name if the LeasePriorityClass exists in namespace, or if name is empty
+func (s *ControllerService) checkPriorityClass(ctx context.Context, namespace string, name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	var class jumpstarterdevv1alpha1.LeasePriorityClass
+	if err := s.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &class); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", status.Errorf(codes.InvalidArgument, "unknown lease priority class %s", name)
+		}
+		return "", err
+	}
+	return class.Name, nil
+}
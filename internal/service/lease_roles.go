@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+const (
+	// LeaseRoleHeader requests a role of the lease requested by RequestLease, once per role,
+	// as NAME:COUNT:SELECTOR, e.g. "generator:1:type=traffic-generator,site=lab1"
+	LeaseRoleHeader = "x-jumpstarter-lease-role"
+	// RoleExporterHeader is sent by GetLease for the leases with roles, as ROLE=EXPORTER once per
+	// exporter of the roles, the exporter being dialed by naming it in the ExporterHeader
+	RoleExporterHeader = "x-jumpstarter-role-exporter"
+)
+
+// leaseRolesFromContext returns the roles requested by LeaseRoleHeader, nil if the request did not set it
+func leaseRolesFromContext(ctx context.Context) ([]jumpstarterdevv1alpha1.LeaseRole, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, nil
+	}
+
+	return parseLeaseRoles(md.Get(LeaseRoleHeader))
+}
+
+// parseLeaseRoles parses the roles of a lease formatted as NAME:COUNT:SELECTOR, nil if there are none
+func parseLeaseRoles(values []string) ([]jumpstarterdevv1alpha1.LeaseRole, error) {
+	var roles []jumpstarterdevv1alpha1.LeaseRole
+	for _, value := range values {
+		parts := strings.SplitN(value, ":", 3)
+		if len(parts) != 3 || parts[0] == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid lease role %s", value)
+		}
+		count, err := strconv.ParseInt(parts[1], 10, 32)
+		if err != nil || count < 1 {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid count of lease role %s", parts[0])
+		}
+		selector, err := metav1.ParseToLabelSelector(parts[2])
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid selector of lease role %s: %s", parts[0], err)
+		}
+		for _, role := range roles {
+			if role.Name == parts[0] {
+				return nil, status.Errorf(codes.InvalidArgument, "duplicate lease role %s", parts[0])
+			}
+		}
+		roles = append(roles, jumpstarterdevv1alpha1.LeaseRole{
+			Name:     parts[0],
+			Selector: *selector,
+			Count:    int32(count),
+		})
+	}
+	return roles, nil
+}
+
+// setRoleExportersHeader sends the exporters of the roles of lease to the client, the protocol
+// only has room for the exporter of the lease
+func setRoleExportersHeader(ctx context.Context, lease *jumpstarterdevv1alpha1.Lease) {
+	if len(lease.Status.RoleExporterRefs) == 0 {
+		return
+	}
+	md := metadata.MD{}
+	for _, ref := range lease.Status.RoleExporterRefs {
+		md.Append(RoleExporterHeader, ref.Role+"="+ref.Name)
+	}
+	_ = grpc.SetHeader(ctx, md)
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/internal/controller"
+	"github.com/jumpstarter-dev/jumpstarter-controller/pkg/api"
+)
+
+// SharedLeaseFromContext reports whether the request set api.SharedLeaseHeader
+func SharedLeaseFromContext(ctx context.Context) (bool, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false, nil
+	}
+
+	values := md.Get(api.SharedLeaseHeader)
+	if len(values) > 1 {
+		return false, status.Errorf(codes.InvalidArgument, "multiple %s headers", api.SharedLeaseHeader)
+	}
+	if len(values) == 0 {
+		return false, nil
+	}
+	shared, err := strconv.ParseBool(values[0])
+	if err != nil {
+		return false, status.Errorf(codes.InvalidArgument, "invalid %s header: %s", api.SharedLeaseHeader, err)
+	}
+	return shared, nil
+}
+
+// canObserve reports whether jclient may observe the streams of lease, as one of its Observers,
+// or because the lease is shared and the ExporterAccessPolicies allow jclient to lease its exporter
+func (s *ControllerService) canObserve(
+	ctx context.Context,
+	jclient *jumpstarterdevv1alpha1.Client,
+	lease *jumpstarterdevv1alpha1.Lease,
+) (bool, error) {
+	if slices.ContainsFunc(lease.Spec.Observers, func(ref corev1.LocalObjectReference) bool {
+		return ref.Name == jclient.Name
+	}) {
+		return true, nil
+	}
+	if !lease.Spec.Shared || lease.Status.ExporterRef == nil {
+		return false, nil
+	}
+
+	var exporter jumpstarterdevv1alpha1.Exporter
+	if err := s.Client.Get(ctx, types.NamespacedName{
+		Namespace: lease.Namespace,
+		Name:      lease.Status.ExporterRef.Name,
+	}, &exporter); err != nil {
+		return false, fmt.Errorf("canObserve: failed to get exporter: %w", err)
+	}
+
+	policies, err := controller.ListAccessPolicies(ctx, s.Client, lease.Namespace)
+	if err != nil {
+		return false, fmt.Errorf("canObserve: %w", err)
+	}
+
+	allowed, err := controller.ClientCanLease(policies, s.AccessPolicyTieBreak, jclient, &exporter, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("canObserve: %w", err)
+	}
+	return allowed, nil
+}
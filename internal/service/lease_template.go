@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/internal/controller"
+)
+
+// LeaseTemplateHeader names the LeaseTemplate the lease requested by RequestLease is defaulted from,
+// the fields of the request take precedence over the ones of the template
+const LeaseTemplateHeader = "x-jumpstarter-lease-template"
+
+// applyLeaseTemplate defaults lease from the LeaseTemplate named by LeaseTemplateHeader, if any,
+// without relying on the lease webhook being deployed
+func (s *ControllerService) applyLeaseTemplate(ctx context.Context, lease *jumpstarterdevv1alpha1.Lease) error {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	values := md.Get(LeaseTemplateHeader)
+	if len(values) > 1 {
+		return status.Errorf(codes.InvalidArgument, "multiple %s headers", LeaseTemplateHeader)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	return s.defaultLeaseFromTemplate(ctx, lease, values[0])
+}
+
+// defaultLeaseFromTemplate defaults lease from the LeaseTemplate named name
+func (s *ControllerService) defaultLeaseFromTemplate(
+	ctx context.Context,
+	lease *jumpstarterdevv1alpha1.Lease,
+	name string,
+) error {
+	lease.Spec.TemplateName = name
+	if err := controller.DefaultLeaseFromTemplate(ctx, s.Client, lease); err != nil {
+		if errors.Is(err, controller.ErrLeaseTemplateNotFound) {
+			return status.Errorf(codes.InvalidArgument, "unknown lease template %s", name)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// AllNamespaces disables the legacy lease RPCs in every namespace
+const AllNamespaces = "*"
+
+var legacyLeaseCallsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "jumpstarter_legacy_lease_rpc_calls_total",
+		Help: "Number of calls to the deprecated lease RPCs of the controller service, by method and caller",
+	},
+	[]string{"method", "namespace", "client"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(legacyLeaseCallsTotal)
+}
+
+// legacyLeaseRPC records a call to a deprecated lease RPC by client, and rejects it
+// when the deprecated lease RPCs are disabled in the namespace of the client
+func (s *ControllerService) legacyLeaseRPC(method string, client *jumpstarterdevv1alpha1.Client) error {
+	legacyLeaseCallsTotal.WithLabelValues(method, client.Namespace, client.Name).Inc()
+
+	if slices.Contains(s.DisabledLegacyLeaseNamespaces, AllNamespaces) ||
+		slices.Contains(s.DisabledLegacyLeaseNamespaces, client.Namespace) {
+		return status.Errorf(codes.Unimplemented, "%s is deprecated and disabled in namespace %s",
+			method, client.Namespace)
+	}
+	return nil
+}
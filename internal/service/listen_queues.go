@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/types"
+	toolscache "k8s.io/client-go/tools/cache"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	pb "github.com/jumpstarter-dev/jumpstarter-controller/internal/protocol/jumpstarter/v1"
+)
+
+// listenQueueSize is how many dials are queued for an exporter not listening yet
+const listenQueueSize = 8
+
+// queuedDial is a dial waiting for the exporter to listen
+type queuedDial struct {
+	response *pb.ListenResponse
+	// closed once the response is sent to the exporter
+	delivered chan struct{}
+	// closed once the dialing client stopped waiting, the response is dropped, nil if it does not wait
+	abandoned <-chan struct{}
+}
+
+// listenQueue holds the dials to an exporter of a lease until the exporter listens
+type listenQueue struct {
+	dials chan *queuedDial
+	// closed once the lease is released or ended, the queued dials are dropped
+	invalidated chan struct{}
+}
+
+// invalidatedLeaseTTL is how long the invalidated leases are remembered, so that the dials and
+// listens which read a lease before it ended do not queue on it again
+const invalidatedLeaseTTL = 10 * time.Minute
+
+// listenQueues are the queues of the dials to the exporters of the active leases, keyed by the
+// UID of the lease, so that a later lease of the same name never shares them
+type listenQueues struct {
+	mu sync.Mutex
+	// the queues of the exporters of each lease, linked exporters listen on the same lease
+	queues map[types.UID]map[string]*listenQueue
+	// when each invalidated lease was invalidated
+	invalidated map[types.UID]time.Time
+}
+
+// get returns the queue of the dials to exporter on lease, creating it if needed,
+// false if lease was invalidated
+func (q *listenQueues) get(lease types.UID, exporter string) (*listenQueue, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.invalidated[lease]; ok {
+		return nil, false
+	}
+	if q.queues == nil {
+		q.queues = map[types.UID]map[string]*listenQueue{}
+	}
+	if q.queues[lease] == nil {
+		q.queues[lease] = map[string]*listenQueue{}
+	}
+	queue, ok := q.queues[lease][exporter]
+	if !ok {
+		queue = &listenQueue{
+			dials:       make(chan *queuedDial, listenQueueSize),
+			invalidated: make(chan struct{}),
+		}
+		q.queues[lease][exporter] = queue
+	}
+	return queue, true
+}
+
+// invalidate drops the queues of every exporter of lease and refuses new ones, the dials
+// and listens waiting on the queues are cancelled, returns how many queued dials were dropped
+func (q *listenQueues) invalidate(lease types.UID, now time.Time) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.invalidated == nil {
+		q.invalidated = map[types.UID]time.Time{}
+	}
+	for uid, invalidated := range q.invalidated {
+		if now.Sub(invalidated) > invalidatedLeaseTTL {
+			delete(q.invalidated, uid)
+		}
+	}
+	if _, ok := q.invalidated[lease]; !ok {
+		q.invalidated[lease] = now
+	}
+
+	dropped := 0
+	for _, queue := range q.queues[lease] {
+		dropped += len(queue.dials)
+		close(queue.invalidated)
+	}
+	delete(q.queues, lease)
+	return dropped
+}
+
+// leaseEndedError is the error of the dials and listens on a lease that ended while they waited
+func leaseEndedError(lease string) error {
+	return status.Errorf(codes.Aborted, "lease %s has ended", lease)
+}
+
+// invalidateLease invalidates the listen queues of lease if it is released, ended or deleted
+func (s *ControllerService) invalidateLease(lease *jumpstarterdevv1alpha1.Lease, deleted bool) {
+	if !deleted && !lease.Spec.Release && !lease.Status.Ended {
+		return
+	}
+	if dropped := s.listenQueues.invalidate(lease.UID, time.Now()); dropped > 0 {
+		ctrl.Log.WithName("controller-service").Info("Dropped queued dials of ended lease",
+			"lease", lease.Namespace+"/"+lease.Name, "dials", dropped)
+	}
+}
+
+// watchLeases invalidates the listen queues of the leases from the lease informer of the manager cache
+func (s *ControllerService) watchLeases(mgr ctrl.Manager) error {
+	informer, err := mgr.GetCache().GetInformer(context.Background(), &jumpstarterdevv1alpha1.Lease{})
+	if err != nil {
+		return fmt.Errorf("watchLeases: failed to get lease informer: %w", err)
+	}
+
+	if _, err := informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if lease, ok := obj.(*jumpstarterdevv1alpha1.Lease); ok {
+				s.invalidateLease(lease, false)
+			}
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			if lease, ok := obj.(*jumpstarterdevv1alpha1.Lease); ok {
+				s.invalidateLease(lease, false)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if lease, ok := obj.(*jumpstarterdevv1alpha1.Lease); ok {
+				s.invalidateLease(lease, true)
+			}
+		},
+	}); err != nil {
+		return fmt.Errorf("watchLeases: failed to add lease event handler: %w", err)
+	}
+	return nil
+}
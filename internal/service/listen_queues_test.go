@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	pb "github.com/jumpstarter-dev/jumpstarter-controller/internal/protocol/jumpstarter/v1"
+)
+
+// listenStream is a Listen stream collecting the responses sent to the exporter
+type listenStream struct {
+	grpc.ServerStream
+	ctx       context.Context
+	responses chan *pb.ListenResponse
+}
+
+func (l *listenStream) Context() context.Context {
+	return l.ctx
+}
+
+func (l *listenStream) Send(response *pb.ListenResponse) error {
+	l.responses <- response
+	return nil
+}
+
+var _ = Describe("Listen queues", func() {
+	var s *ControllerService
+	var lease *jumpstarterdevv1alpha1.Lease
+	var exporter *jumpstarterdevv1alpha1.Exporter
+	jclient := &jumpstarterdevv1alpha1.Client{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "client"}}
+
+	BeforeEach(func() {
+		lease = newTestLease("lease", "client", "exporter")
+		exporter = &jumpstarterdevv1alpha1.Exporter{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "exporter",
+			UID:       "exporter-uid",
+		}}
+		s = newTestService(lease, exporter)
+	})
+
+	// listen listens on the lease as the exporter until ctx is done, returning its error on errs
+	listen := func(ctx context.Context) (*listenStream, <-chan error) {
+		stream := &listenStream{ctx: tokenContext(ctx, s, exporter), responses: make(chan *pb.ListenResponse, 1)}
+		errs := make(chan error, 1)
+		go func() {
+			errs <- s.Listen(&pb.ListenRequest{LeaseName: lease.Name}, stream)
+		}()
+		return stream, errs
+	}
+
+	It("should cancel the dials waiting on an invalidated lease", func() {
+		errs := make(chan error, 1)
+		go func() {
+			_, err := s.dial(context.Background(), jclient, lease, "exporter", time.Minute)
+			errs <- err
+		}()
+		Eventually(func() int {
+			queue, _ := s.listenQueues.get(lease.UID, "exporter")
+			return len(queue.dials)
+		}).Should(Equal(1))
+
+		Expect(s.listenQueues.invalidate(lease.UID, time.Now())).To(Equal(1))
+		Eventually(errs).Should(Receive(WithTransform(status.Code, Equal(codes.Aborted))))
+	})
+
+	It("should return from Listen once the lease is invalidated", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		_, errs := listen(ctx)
+		Consistently(errs, 100*time.Millisecond).ShouldNot(Receive())
+
+		s.listenQueues.invalidate(lease.UID, time.Now())
+		Eventually(errs).Should(Receive(WithTransform(status.Code, Equal(codes.Aborted))))
+	})
+
+	It("should refuse the queues of an invalidated lease", func() {
+		s.listenQueues.invalidate(lease.UID, time.Now())
+
+		_, ok := s.listenQueues.get(lease.UID, "exporter")
+		Expect(ok).To(BeFalse())
+		_, err := s.dial(context.Background(), jclient, lease, "exporter", 0)
+		Expect(status.Code(err)).To(Equal(codes.Aborted))
+		_, errs := listen(context.Background())
+		Eventually(errs).Should(Receive(WithTransform(status.Code, Equal(codes.Aborted))))
+	})
+
+	It("should forget the invalidated leases after a while", func() {
+		s.listenQueues.invalidate(lease.UID, time.Now().Add(-invalidatedLeaseTTL-time.Second))
+		s.listenQueues.invalidate("other", time.Now())
+
+		_, ok := s.listenQueues.get(lease.UID, "exporter")
+		Expect(ok).To(BeTrue())
+	})
+
+	It("should start a new lease of the same name with an empty queue", func() {
+		_, err := s.dial(context.Background(), jclient, lease, "exporter", 0)
+		Expect(err).NotTo(HaveOccurred())
+		s.listenQueues.invalidate(lease.UID, time.Now())
+
+		Expect(s.Client.Delete(context.Background(), lease)).To(Succeed())
+		lease = newTestLease("lease", "client", "exporter")
+		lease.UID = types.UID("new-lease-uid")
+		Expect(s.Client.Create(context.Background(), lease)).To(Succeed())
+
+		queue, ok := s.listenQueues.get(lease.UID, "exporter")
+		Expect(ok).To(BeTrue())
+		Expect(queue.dials).To(BeEmpty())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		stream, _ := listen(ctx)
+		Consistently(stream.responses, 100*time.Millisecond).ShouldNot(Receive())
+	})
+})
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+// Endpoint is an optional endpoint served on a gRPC listener next to the jumpstarter services
+type Endpoint string
+
+const (
+	// EndpointReflection is the gRPC server reflection service
+	EndpointReflection Endpoint = "reflection"
+)
+
+var knownEndpoints = []Endpoint{EndpointReflection}
+
+// DisabledEndpoints is the set of optional endpoints not served on a gRPC listener, all of them are
+// served when empty. It implements flag.Value, parsing a comma separated list of endpoints
+type DisabledEndpoints map[Endpoint]bool
+
+func (d *DisabledEndpoints) String() string {
+	if d == nil {
+		return ""
+	}
+	names := make([]string, 0, len(*d))
+	for endpoint, disabled := range *d {
+		if disabled {
+			names = append(names, string(endpoint))
+		}
+	}
+	slices.Sort(names)
+	return strings.Join(names, ",")
+}
+
+func (d *DisabledEndpoints) Set(value string) error {
+	disabled := DisabledEndpoints{}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(knownEndpoints, Endpoint(name)) {
+			return fmt.Errorf("unknown endpoint %q, known endpoints are %v", name, knownEndpoints)
+		}
+		disabled[Endpoint(name)] = true
+	}
+	*d = disabled
+	return nil
+}
+
+// register registers the optional endpoints not disabled on server
+func (d DisabledEndpoints) register(server *grpc.Server) {
+	if !d[EndpointReflection] {
+		reflection.Register(server)
+	}
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	pb "github.com/jumpstarter-dev/jumpstarter-controller/internal/protocol/jumpstarter/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const recordingSuffix = ".jsonl"
+
+// StreamRecorder tees the router streams of leases with recording enabled to files
+// in Dir, and serves them for download on BindAddress
+type StreamRecorder struct {
+	// Directory the recordings are written to
+	Dir string
+	// Frames beyond this size are not recorded, 0 means unbounded
+	MaxRecordingBytes int64
+	// Recordings older than this are removed, 0 keeps them forever
+	Retention time.Duration
+	// The oldest recordings are removed when their total size exceeds this, 0 means unbounded
+	MaxTotalBytes int64
+	// Address the recordings are served on
+	BindAddress string
+}
+
+// Recording describes a stored stream recording
+type Recording struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// recordedFrame is a single line of a recording
+type recordedFrame struct {
+	Time      time.Time `json:"time"`
+	Side      string    `json:"side,omitempty"`
+	FrameType string    `json:"frameType"`
+	Payload   []byte    `json:"payload,omitempty"`
+}
+
+type recording struct {
+	mu      sync.Mutex
+	file    *os.File
+	written int64
+	max     int64
+}
+
+// open creates the recording of stream, lease is the namespaced name of the lease
+func (r *StreamRecorder) open(lease string, stream string) (*recording, error) {
+	if err := os.MkdirAll(r.Dir, 0o750); err != nil {
+		return nil, fmt.Errorf("open: failed to create recording directory: %w", err)
+	}
+
+	name := strings.ReplaceAll(lease, "/", "_") + "_" + stream + recordingSuffix
+	file, err := os.OpenFile(filepath.Join(r.Dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("open: failed to create recording: %w", err)
+	}
+
+	return &recording{file: file, max: r.MaxRecordingBytes}, nil
+}
+
+func (rec *recording) record(side string, msg *pb.StreamResponse) {
+	line, err := json.Marshal(recordedFrame{
+		Time:      time.Now(),
+		Side:      side,
+		FrameType: msg.GetFrameType().String(),
+		Payload:   msg.GetPayload(),
+	})
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if rec.max > 0 && rec.written+int64(len(line)) > rec.max {
+		return
+	}
+	n, _ := rec.file.Write(line)
+	rec.written += int64(n)
+}
+
+// tap returns a tap recording the frames sent by side, before passing them to next
+func (rec *recording) tap(side string, next func(*pb.StreamResponse)) func(*pb.StreamResponse) {
+	return func(msg *pb.StreamResponse) {
+		rec.record(side, msg)
+		if next != nil {
+			next(msg)
+		}
+	}
+}
+
+func (rec *recording) Close() error {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	return rec.file.Close()
+}
+
+// List returns the stored recordings, oldest first
+func (r *StreamRecorder) List() ([]Recording, error) {
+	entries, err := os.ReadDir(r.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("List: failed to read recording directory: %w", err)
+	}
+
+	var recordings []Recording
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), recordingSuffix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		recordings = append(recordings, Recording{
+			Name:    entry.Name(),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+	}
+
+	slices.SortFunc(recordings, func(a, b Recording) int {
+		return a.ModTime.Compare(b.ModTime)
+	})
+	return recordings, nil
+}
+
+// prune applies the retention policy
+func (r *StreamRecorder) prune(now time.Time) error {
+	recordings, err := r.List()
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for _, recording := range recordings {
+		total += recording.Size
+	}
+
+	for _, recording := range recordings {
+		expired := r.Retention > 0 && now.Sub(recording.ModTime) > r.Retention
+		oversize := r.MaxTotalBytes > 0 && total > r.MaxTotalBytes
+		if !expired && !oversize {
+			continue
+		}
+		if err := os.Remove(filepath.Join(r.Dir, recording.Name)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("prune: failed to remove recording: %w", err)
+		}
+		total -= recording.Size
+	}
+	return nil
+}
+
+func (r *StreamRecorder) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx)
+
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			if err := r.prune(time.Now()); err != nil {
+				logger.Error(err, "unable to prune stream recordings")
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	e := gin.Default()
+
+	e.GET("/recordings", func(c *gin.Context) {
+		recordings, err := r.List()
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, recordings)
+	})
+
+	e.GET("/recordings/:name", func(c *gin.Context) {
+		name := c.Param("name")
+		if name != filepath.Base(name) || !strings.HasSuffix(name, recordingSuffix) {
+			c.String(http.StatusBadRequest, "invalid recording name")
+			return
+		}
+		c.FileAttachment(filepath.Join(r.Dir, name), name)
+	})
+
+	server := &http.Server{
+		Addr:    r.BindAddress,
+		Handler: e,
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+
+	logger.Info("Serving stream recordings", "address", r.BindAddress, "dir", r.Dir)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// SetupWithManager sets up the recorder with the Manager.
+func (r *StreamRecorder) SetupWithManager(mgr ctrl.Manager) error {
+	return mgr.Add(r)
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// RegisterLimitPolicy is what Register does with the registrations exceeding the RegisterLimits
+type RegisterLimitPolicy string
+
+const (
+	// RegisterLimitPolicyTruncate drops the devices and labels exceeding the limits
+	RegisterLimitPolicyTruncate RegisterLimitPolicy = "Truncate"
+	// RegisterLimitPolicyReject rejects the registration
+	RegisterLimitPolicyReject RegisterLimitPolicy = "Reject"
+)
+
+func (p *RegisterLimitPolicy) String() string {
+	return string(*p)
+}
+
+func (p *RegisterLimitPolicy) Set(value string) error {
+	switch policy := RegisterLimitPolicy(value); policy {
+	case RegisterLimitPolicyTruncate, RegisterLimitPolicyReject:
+		*p = policy
+		return nil
+	default:
+		return fmt.Errorf("unknown register limit policy %s, expected %s or %s",
+			value, RegisterLimitPolicyTruncate, RegisterLimitPolicyReject)
+	}
+}
+
+// RegisterLimits bound the devices an exporter reports in Register, so that a buggy exporter
+// cannot bloat its Exporter object, the limits set to 0 are not enforced
+type RegisterLimits struct {
+	// MaxDevices is the maximum number of devices of an exporter
+	MaxDevices int
+	// MaxDeviceLabels is the maximum number of labels of a device
+	MaxDeviceLabels int
+	// MaxLabelSize is the maximum size in bytes of the key and value of a device label
+	MaxLabelSize int
+	// Policy applied to the registrations exceeding the limits, RegisterLimitPolicyTruncate if empty
+	Policy RegisterLimitPolicy
+}
+
+// DefaultRegisterLimits are the register limits of the controller unless configured otherwise
+var DefaultRegisterLimits = RegisterLimits{
+	MaxDevices:      1000,
+	MaxDeviceLabels: 64,
+	MaxLabelSize:    1024,
+	Policy:          RegisterLimitPolicyTruncate,
+}
+
+var registerLimitsExceededTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "jumpstarter_register_limits_exceeded_total",
+		Help: "Number of registrations exceeding the register limits, by namespace, limit and policy",
+	},
+	[]string{"namespace", "limit", "policy"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(registerLimitsExceededTotal)
+}
+
+// enforce returns devices within the limits, and the limits devices exceeded
+func (l RegisterLimits) enforce(devices []jumpstarterdevv1alpha1.Device) ([]jumpstarterdevv1alpha1.Device, []string) {
+	var exceeded []string
+	if l.MaxDevices > 0 && len(devices) > l.MaxDevices {
+		devices = devices[:l.MaxDevices]
+		exceeded = append(exceeded, "devices")
+	}
+
+	var labelsExceeded, labelSizeExceeded bool
+	for i := range devices {
+		if len(devices[i].Labels) == 0 {
+			continue
+		}
+		// the keys are sorted for the same labels to be kept at every registration
+		keys := make([]string, 0, len(devices[i].Labels))
+		for key := range devices[i].Labels {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		labels := make(map[string]string, len(keys))
+		for _, key := range keys {
+			value := devices[i].Labels[key]
+			if l.MaxLabelSize > 0 && len(key)+len(value) > l.MaxLabelSize {
+				labelSizeExceeded = true
+				continue
+			}
+			if l.MaxDeviceLabels > 0 && len(labels) == l.MaxDeviceLabels {
+				labelsExceeded = true
+				break
+			}
+			labels[key] = value
+		}
+		devices[i].Labels = labels
+	}
+	if labelsExceeded {
+		exceeded = append(exceeded, "deviceLabels")
+	}
+	if labelSizeExceeded {
+		exceeded = append(exceeded, "labelSize")
+	}
+	return devices, exceeded
+}
+
+// record counts the registration of exporter exceeding the limits exceeded
+func (l RegisterLimits) record(exporter *jumpstarterdevv1alpha1.Exporter, exceeded []string) {
+	for _, limit := range exceeded {
+		registerLimitsExceededTotal.WithLabelValues(exporter.Namespace, limit, string(l.policy())).Inc()
+	}
+}
+
+func (l RegisterLimits) policy() RegisterLimitPolicy {
+	if l.Policy == "" {
+		return RegisterLimitPolicyTruncate
+	}
+	return l.Policy
+}
+
+// truncatedCondition returns the DevicesTruncated condition of a registration of exporter
+// exceeding the limits exceeded, if any
+func (l RegisterLimits) truncatedCondition(
+	exporter *jumpstarterdevv1alpha1.Exporter,
+	exceeded []string,
+) metav1.Condition {
+	condition := metav1.Condition{
+		Type:               string(jumpstarterdevv1alpha1.ExporterConditionTypeDevicesTruncated),
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: exporter.Generation,
+		LastTransitionTime: metav1.Time{
+			Time: time.Now(),
+		},
+		Reason: "WithinLimits",
+	}
+	if len(exceeded) > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "LimitsExceeded"
+		condition.Message = fmt.Sprintf("the reported devices exceeded the limits on %s (max %d devices, "+
+			"%d labels per device, %d bytes per label) and were truncated",
+			strings.Join(exceeded, ", "), l.MaxDevices, l.MaxDeviceLabels, l.MaxLabelSize)
+	}
+	return exporterCondition(exporter, condition)
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+)
+
+// RegistrationSignatureHeader carries the hex encoded HMAC-SHA256 of the webhook body,
+// keyed with the webhook secret and prefixed with sha256=
+const RegistrationSignatureHeader = "X-Jumpstarter-Signature"
+
+// registrationWebhookTimeout bounds the delivery of a single event
+const registrationWebhookTimeout = 10 * time.Second
+
+// The types of the registration events
+const (
+	RegistrationEventRegister   = "Register"
+	RegistrationEventUnregister = "Unregister"
+)
+
+// RegistrationEvent is the body of the registration webhook requests
+type RegistrationEvent struct {
+	Type     string                          `json:"type"`
+	Time     time.Time                       `json:"time"`
+	Exporter RegistrationEventExporter       `json:"exporter"`
+	Devices  []jumpstarterdevv1alpha1.Device `json:"devices,omitempty"`
+	// Reason is the reason given by the exporter when unregistering
+	Reason string `json:"reason,omitempty"`
+}
+
+// RegistrationEventExporter identifies the exporter of a RegistrationEvent
+type RegistrationEventExporter struct {
+	Namespace string            `json:"namespace"`
+	Name      string            `json:"name"`
+	UID       string            `json:"uid"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+// RegistrationWebhook posts the registrations of the exporters to an external inventory system
+type RegistrationWebhook struct {
+	URL string
+	// Secret signs the requests, unsigned if empty
+	Secret []byte
+	// Client defaults to http.DefaultClient
+	Client *http.Client
+}
+
+// notify delivers the event of exporter in the background, failures are logged and not retried,
+// registration never waits for the inventory system
+func (w *RegistrationWebhook) notify(
+	ctx context.Context,
+	eventType string,
+	exporter *jumpstarterdevv1alpha1.Exporter,
+	devices []jumpstarterdevv1alpha1.Device,
+	reason string,
+) {
+	if w == nil || w.URL == "" {
+		return
+	}
+
+	logger := log.FromContext(ctx)
+
+	event := RegistrationEvent{
+		Type: eventType,
+		Time: time.Now(),
+		Exporter: RegistrationEventExporter{
+			Namespace: exporter.Namespace,
+			Name:      exporter.Name,
+			UID:       string(exporter.UID),
+			Labels:    exporter.Labels,
+		},
+		Devices: devices,
+		Reason:  reason,
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), registrationWebhookTimeout)
+		defer cancel()
+
+		if err := w.send(ctx, event); err != nil {
+			logger.Error(err, "unable to deliver registration webhook", "type", eventType)
+		}
+	}()
+}
+
+func (w *RegistrationWebhook) send(ctx context.Context, event RegistrationEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("send: failed to marshal event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("send: failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(w.Secret) > 0 {
+		mac := hmac.New(sha256.New, w.Secret)
+		mac.Write(body)
+		req.Header.Set(RegistrationSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("send: unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// writeBackoff paces the retries of the API writes of the service handlers,
+// about 1.5s in total so that the RPCs still answer promptly
+var writeBackoff = wait.Backoff{
+	Steps:    5,
+	Duration: 50 * time.Millisecond,
+	Factor:   2,
+	Jitter:   0.5,
+}
+
+// retriableWriteError reports whether a write failing with err may succeed when retried
+func retriableWriteError(err error) bool {
+	return apierrors.IsConflict(err) ||
+		apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) ||
+		apierrors.IsTooManyRequests(err) ||
+		apierrors.IsInternalError(err) ||
+		apierrors.IsServiceUnavailable(err)
+}
+
+// retryWrite calls write until it succeeds, fails permanently, writeBackoff is exhausted or ctx
+// is done. On conflicts obj is refreshed before retrying, so write must reapply its changes to obj
+func (s *ControllerService) retryWrite(ctx context.Context, obj client.Object, write func() error) error {
+	return retry(ctx, write, func(err error) error {
+		if apierrors.IsConflict(err) {
+			if err := s.Client.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+				return fmt.Errorf("retryWrite: failed to refresh object: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// retryCreate creates obj, retrying like retryWrite. A retried create failing as obj already exists
+// succeeds if stored, called with the existing object, reports it was stored by an earlier attempt
+// whose response was lost
+func (s *ControllerService) retryCreate(
+	ctx context.Context,
+	obj client.Object,
+	stored func(existing runtime.Object) bool,
+) error {
+	retried := false
+	return retry(ctx, func() error {
+		err := s.Client.Create(ctx, obj)
+		if retried && apierrors.IsAlreadyExists(err) {
+			existing, ok := obj.DeepCopyObject().(client.Object)
+			if !ok {
+				return err
+			}
+			if getErr := s.Client.Get(ctx, client.ObjectKeyFromObject(obj), existing); getErr != nil {
+				return fmt.Errorf("retryCreate: failed to get existing object: %w", getErr)
+			}
+			if stored(existing) {
+				return nil
+			}
+		}
+		return err
+	}, func(error) error {
+		retried = true
+		return nil
+	})
+}
+
+// retry calls write until it succeeds, fails permanently, writeBackoff is exhausted or ctx is done,
+// calling refresh with the error before every retry
+func retry(ctx context.Context, write func() error, refresh func(err error) error) error {
+	backoff := writeBackoff
+	for {
+		err := write()
+		if err == nil || !retriableWriteError(err) || backoff.Steps <= 1 {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff.Step()):
+		}
+
+		if err := refresh(err); err != nil {
+			return err
+		}
+	}
+}
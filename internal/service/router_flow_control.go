@@ -0,0 +1,43 @@
+package service
+
+import (
+	"google.golang.org/grpc"
+)
+
+// FlowControlPolicy bounds the memory the router buffers per stream. Forwarding is synchronous,
+// the next frame is only received once the previous one is sent, so a slow receiver stops the
+// router from granting HTTP/2 window to the sender and the back-pressure reaches the sending side
+type FlowControlPolicy struct {
+	// The HTTP/2 flow control window of each stream in bytes, at least 64KiB
+	StreamWindow int32
+	// The HTTP/2 flow control window of each connection in bytes, at least 64KiB
+	ConnectionWindow int32
+	// The maximum size of a single frame in bytes, larger frames are rejected
+	MaxFrameSize int
+}
+
+// DefaultFlowControlPolicy is the flow control policy of routers not configured otherwise
+var DefaultFlowControlPolicy = FlowControlPolicy{
+	StreamWindow:     1 << 20,
+	ConnectionWindow: 4 << 20,
+	MaxFrameSize:     4 << 20,
+}
+
+func (p FlowControlPolicy) withDefaults() FlowControlPolicy {
+	if p == (FlowControlPolicy{}) {
+		return DefaultFlowControlPolicy
+	}
+	return p
+}
+
+// serverOptions fixes the windows, disabling their dynamic growth by bandwidth estimation,
+// which would let a fast sender fill the router with frames a slow receiver has yet to read
+func (p FlowControlPolicy) serverOptions() []grpc.ServerOption {
+	p = p.withDefaults()
+	return []grpc.ServerOption{
+		grpc.InitialWindowSize(p.StreamWindow),
+		grpc.InitialConnWindowSize(p.ConnectionWindow),
+		grpc.MaxRecvMsgSize(p.MaxFrameSize),
+		grpc.MaxSendMsgSize(p.MaxFrameSize),
+	}
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// StreamOptionResumption is the stream option offered by the peers implementing the resumption
+// protocol, replaying the frames their other side did not acknowledge after a reconnection,
+// the streams both sides negotiated it for are handed off to a peer replica when their router
+// replica dies, and the peers resume them by reconnecting with their router tokens
+const StreamOptionResumption = "resumption"
+
+const (
+	// RouterEndpointHeader is sent with the UNAVAILABLE errors of the router replicas receiving
+	// a half of a stream handed off to another replica, the endpoint of the replica adopting it
+	RouterEndpointHeader = "x-jumpstarter-router-endpoint"
+	// ResumedHeader is sent to both sides of a handed off stream once paired again
+	ResumedHeader = "x-jumpstarter-resumed"
+)
+
+// routerHandoffWindow is how long after its last heartbeat the streams of a dead replica can be
+// resumed on the replica adopting them, after which its registration is removed
+const routerHandoffWindow = 2 * time.Minute
+
+// maxHandoffStreams bounds the streams a replica publishes in the registry, the streams started
+// beyond it are forwarded but not handed off
+const maxHandoffStreams = 1000
+
+// RouterStream is the handoff record of a stream forwarded by a router replica
+type RouterStream struct {
+	// The namespaced name of the stream
+	Name string `json:"name"`
+	// The IDs of the router tokens of its two sides, the only tokens it can be resumed with
+	TokenIDs []string `json:"tokenIDs"`
+}
+
+// streamHandoffs are the resumable streams of the replica, and the streams of the dead replicas
+type streamHandoffs struct {
+	mu sync.Mutex
+	// resumable streams forwarded by the replica, published in its registration
+	local map[string]RouterStream
+	// streams of dead replicas adopted by the replica
+	adopted map[string]RouterStream
+	// endpoints of the replicas adopting the streams of dead replicas
+	redirects map[string]string
+	// nudge triggers a registration as soon as a resumable stream starts
+	nudge chan struct{}
+}
+
+func (h *streamHandoffs) init() {
+	if h.local == nil {
+		h.local = map[string]RouterStream{}
+		h.nudge = make(chan struct{}, 1)
+	}
+}
+
+// add records the resumable stream name forwarded with the tokens tokenIDs
+func (h *streamHandoffs) add(name string, tokenIDs ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.init()
+
+	if len(h.local) >= maxHandoffStreams {
+		return
+	}
+	h.local[name] = RouterStream{Name: name, TokenIDs: tokenIDs}
+	select {
+	case h.nudge <- struct{}{}:
+	default:
+	}
+}
+
+func (h *streamHandoffs) remove(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.local, name)
+}
+
+// streams returns the resumable streams of the replica, sorted by name
+func (h *streamHandoffs) streams() []RouterStream {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	streams := make([]RouterStream, 0, len(h.local))
+	for _, stream := range h.local {
+		streams = append(streams, stream)
+	}
+	slices.SortFunc(streams, func(a, b RouterStream) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return streams
+}
+
+// nudged returns the channel receiving a value when a resumable stream starts
+func (h *streamHandoffs) nudged() <-chan struct{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.init()
+	return h.nudge
+}
+
+// adoptedToken reports whether the stream name was adopted by the replica and can be
+// resumed with the token tokenID
+func (h *streamHandoffs) adoptedToken(name string, tokenID string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	stream, ok := h.adopted[name]
+	return ok && tokenID != "" && slices.Contains(stream.TokenIDs, tokenID)
+}
+
+// resumed forgets the adopted stream name once both of its sides reconnected
+func (h *streamHandoffs) resumed(name string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, ok := h.adopted[name]
+	delete(h.adopted, name)
+	return ok
+}
+
+// redirect returns the endpoint of the replica adopting the stream name, if another one does
+func (h *streamHandoffs) redirect(name string) (string, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	endpoint, ok := h.redirects[name]
+	return endpoint, ok
+}
+
+// update assigns the streams of the dead replicas among replicas to their adopters at now, and
+// returns the dead replicas past the handoff window whose registrations self must remove
+func (h *streamHandoffs) update(
+	self string,
+	replicas map[string]RouterReplica,
+	drains map[string]bool,
+	now time.Time,
+) []string {
+	adopted := map[string]RouterStream{}
+	redirects := map[string]string{}
+	var expired []string
+	for name, replica := range replicas {
+		silence := now.Sub(replica.Heartbeat)
+		if name == self || silence <= 3*routerHeartbeatInterval {
+			continue
+		}
+		adopter := adoptingReplica(replicas, drains, name, now)
+		if silence > 3*routerHeartbeatInterval+routerHandoffWindow {
+			if adopter == self {
+				expired = append(expired, name)
+			}
+			continue
+		}
+		for _, stream := range replica.Streams {
+			switch adopter {
+			case "":
+			case self:
+				adopted[stream.Name] = stream
+			default:
+				redirects[stream.Name] = replicas[adopter].Endpoint
+			}
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// the streams already resumed are not adopted again until their replica is removed
+	for name := range adopted {
+		if _, ok := h.local[name]; ok {
+			delete(adopted, name)
+		}
+	}
+	h.adopted, h.redirects = adopted, redirects
+	return expired
+}
+
+// adoptingReplica returns the replica adopting the streams of the dead replica, the first live
+// replica not draining following it in the order of their names, "" if there is none
+func adoptingReplica(replicas map[string]RouterReplica, drains map[string]bool, dead string, now time.Time) string {
+	names := make([]string, 0, len(replicas))
+	for name := range replicas {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	start, _ := slices.BinarySearch(names, dead)
+	for i := 1; i < len(names); i++ {
+		name := names[(start+i)%len(names)]
+		replica := replicas[name]
+		if !replica.Draining && !drains[name] && now.Sub(replica.Heartbeat) <= 3*routerHeartbeatInterval {
+			return name
+		}
+	}
+	return ""
+}
+
+// redirectHandedOff rejects the half of a stream handed off to another replica, sending the
+// peer the endpoint of the replica adopting it
+func (s *RouterService) redirectHandedOff(ctx context.Context, streamName string, stream interface {
+	SetHeader(metadata.MD) error
+}) error {
+	endpoint, ok := s.handoffs.redirect(streamName)
+	if !ok {
+		return nil
+	}
+	log.FromContext(ctx).Info("redirecting handed off stream", "adopter", endpoint)
+	_ = stream.SetHeader(metadata.Pairs(RouterEndpointHeader, endpoint))
+	return status.Errorf(codes.Unavailable, "stream handed off to another router replica")
+}
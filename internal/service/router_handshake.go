@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/util/uuid"
+
+	"github.com/jumpstarter-dev/jumpstarter-controller/pkg/api"
+)
+
+// DialModeHeader is the metadata key selecting the kind of token Dial returns
+const DialModeHeader = "x-jumpstarter-dial-mode"
+
+// DialModeObserve requests a receive-only token for the latest stream of the lease,
+// the client must be listed in the observers of the lease
+const DialModeObserve = "observe"
+
+// DialModeFromContext returns the dial mode of the request, or "" for a regular dial
+func DialModeFromContext(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", nil
+	}
+
+	modes := md.Get(DialModeHeader)
+	switch {
+	case len(modes) == 0:
+		return "", nil
+	case len(modes) > 1 || modes[0] != DialModeObserve:
+		return "", status.Errorf(codes.InvalidArgument, "invalid dial mode")
+	default:
+		return modes[0], nil
+	}
+}
+
+// ExporterHeader is the metadata key selecting which exporter of the lease Dial connects to,
+// one of the exporters linked to the leased exporter, defaults to the leased exporter
+const ExporterHeader = "x-jumpstarter-exporter"
+
+// ExporterFromContext returns the exporter of the lease the request dials, or "" for the leased exporter
+func ExporterFromContext(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", nil
+	}
+
+	exporters := md.Get(ExporterHeader)
+	switch {
+	case len(exporters) == 0:
+		return "", nil
+	case len(exporters) > 1:
+		return "", status.Errorf(codes.InvalidArgument, "multiple exporters requested")
+	default:
+		return exporters[0], nil
+	}
+}
+
+// StreamClaims are the claims of the router tokens issued by Dial
+type StreamClaims struct {
+	jwt.RegisteredClaims
+	// Lease is the namespaced name of the lease the stream belongs to
+	Lease string `json:"lease,omitempty"`
+	// Namespace, Exporter and Client identify the lease, the exporter and the client of the stream
+	// in the router logs, for observers Client is the observing client
+	Namespace string `json:"namespace,omitempty"`
+	Exporter  string `json:"exporter,omitempty"`
+	Client    string `json:"client,omitempty"`
+	// Peer is the side of the stream the token was issued to
+	Peer string `json:"peer,omitempty"`
+	// Record requests the router to record the stream
+	Record bool `json:"record,omitempty"`
+	// Limits enforced by the router on the streams of the lease
+	Limits *StreamLimitClaims `json:"limits,omitempty"`
+}
+
+// StreamLimitClaims are the stream limits of the access policy granting the lease, enforced by the router
+type StreamLimitClaims struct {
+	MaxConcurrentDials int32 `json:"maxConcurrentDials,omitempty"`
+	// Bytes per second per direction
+	MaxBandwidth int64 `json:"maxBandwidth,omitempty"`
+	// Seconds
+	MaxSessionDuration int64 `json:"maxSessionDuration,omitempty"`
+}
+
+// streamSubject is the token subject of stream, scoped to the namespace of its lease
+func streamSubject(namespace string, stream string) string {
+	if namespace == "" {
+		return stream
+	}
+	return namespace + "/" + stream
+}
+
+// stream returns the namespace and the id of the stream of the token, tokens issued before the
+// subject was namespaced are scoped to their namespace claim
+func (c *StreamClaims) stream() (string, string, error) {
+	namespace, id, found := strings.Cut(c.Subject, "/")
+	if !found {
+		return c.Namespace, c.Subject, nil
+	}
+	if namespace != c.Namespace {
+		return "", "", status.Errorf(codes.InvalidArgument, "stream namespace does not match token namespace")
+	}
+	return namespace, id, nil
+}
+
+// newStreamToken signs a router token for the peer side of stream, claims carries the lease scoped claims
+func (k *RouterKey) newStreamToken(stream string, peer string, claims StreamClaims) (string, error) {
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		Issuer:    "https://jumpstarter.dev/stream",
+		Subject:   streamSubject(claims.Namespace, stream),
+		Audience:  []string{"https://jumpstarter.dev/router"},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(streamTokenLifetime)),
+		NotBefore: jwt.NewNumericDate(time.Now()),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		ID:        string(uuid.NewUUID()),
+	}
+	claims.Peer = peer
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(k.signingKey())
+}
+
+// streamPeer is the handshake of one side of a stream
+type streamPeer struct {
+	peer    string
+	lease   string
+	options map[string]string
+	// side received by observers
+	observe string
+}
+
+// peerFromContext reads the handshake from the request metadata and validates it
+// against the token claims, tokens and requests without a handshake are accepted
+// as anonymous peers for compatibility with older clients
+func peerFromContext(ctx context.Context, claims *StreamClaims) (streamPeer, error) {
+	sp := streamPeer{
+		peer:    claims.Peer,
+		lease:   claims.Lease,
+		options: map[string]string{},
+		observe: api.PeerExporter,
+	}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return sp, nil
+	}
+
+	for key, values := range md {
+		if !strings.HasPrefix(key, api.OptionHeaderPrefix) || len(values) != 1 {
+			continue
+		}
+		sp.options[strings.TrimPrefix(key, api.OptionHeaderPrefix)] = values[0]
+	}
+
+	if peers := md.Get(api.PeerHeader); len(peers) > 0 {
+		if len(peers) > 1 || (peers[0] != api.PeerClient && peers[0] != api.PeerExporter && peers[0] != api.PeerObserver) {
+			return sp, status.Errorf(codes.InvalidArgument, "invalid peer header")
+		}
+		if claims.Peer != "" && claims.Peer != peers[0] {
+			return sp, status.Errorf(codes.PermissionDenied, "peer does not match token")
+		}
+		sp.peer = peers[0]
+	}
+
+	if leases := md.Get(api.LeaseHeader); len(leases) > 0 {
+		if len(leases) > 1 {
+			return sp, status.Errorf(codes.InvalidArgument, "multiple lease headers")
+		}
+		if claims.Lease != "" && claims.Lease != leases[0] {
+			return sp, status.Errorf(codes.PermissionDenied, "lease does not match token")
+		}
+		sp.lease = leases[0]
+	}
+
+	if observes := md.Get(api.ObserveHeader); len(observes) > 0 {
+		if len(observes) > 1 || (observes[0] != api.PeerClient && observes[0] != api.PeerExporter) {
+			return sp, status.Errorf(codes.InvalidArgument, "invalid observe header")
+		}
+		sp.observe = observes[0]
+	}
+
+	return sp, nil
+}
+
+// pairable reports whether two peers can be the two sides of the same stream
+func (a streamPeer) pairable(b streamPeer) error {
+	if a.peer != "" && a.peer == b.peer {
+		return status.Errorf(codes.AlreadyExists, "%s side of the stream already connected", a.peer)
+	}
+	if a.lease != "" && b.lease != "" && a.lease != b.lease {
+		return status.Errorf(codes.PermissionDenied, "stream sides belong to different leases")
+	}
+	return nil
+}
+
+// negotiate returns the response metadata sent to both sides once paired,
+// an option is only enabled when both sides offered the same value
+func negotiate(a streamPeer, b streamPeer) metadata.MD {
+	md := metadata.MD{}
+	for key, value := range a.options {
+		if b.options[key] == value {
+			md.Set(api.OptionHeaderPrefix+key, value)
+		}
+	}
+	return md
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/golang-jwt/jwt/v5"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// streamTokenLifetime is how long router tokens are valid, and so how long a rotated out
+// router key keeps being accepted
+const streamTokenLifetime = 30 * time.Minute
+
+// RouterKey is the key router tokens are signed with. It is read from File, a key of a mounted
+// Secret, and reloaded whenever the Secret is updated. The previous key keeps being accepted for
+// the lifetime of the tokens it signed, so the key rotates without restarts or failed streams.
+// A nil RouterKey, or one without File, uses the ROUTER_KEY environment variable
+type RouterKey struct {
+	File string
+
+	mu       sync.RWMutex
+	current  []byte
+	previous []byte
+	// the previous key is accepted until
+	expires time.Time
+}
+
+// NewRouterKeyFromEnv returns a RouterKey reading the file named by ROUTER_KEY_FILE, if set
+func NewRouterKeyFromEnv() (*RouterKey, error) {
+	k := &RouterKey{File: os.Getenv("ROUTER_KEY_FILE")}
+	if k.File == "" {
+		return k, nil
+	}
+	if err := k.load(time.Now()); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+func (k *RouterKey) load(now time.Time) error {
+	key, err := os.ReadFile(k.File)
+	if err != nil {
+		return fmt.Errorf("load: failed to read router key: %w", err)
+	}
+	key = bytes.TrimSpace(key)
+	if len(key) == 0 {
+		return fmt.Errorf("load: router key %s is empty", k.File)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if bytes.Equal(key, k.current) {
+		return nil
+	}
+	if k.current != nil {
+		k.previous = k.current
+		k.expires = now.Add(streamTokenLifetime)
+	}
+	k.current = key
+	return nil
+}
+
+// signingKey returns the key new tokens are signed with
+func (k *RouterKey) signingKey() []byte {
+	if k == nil || k.File == "" {
+		return []byte(os.Getenv("ROUTER_KEY"))
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.current
+}
+
+// verificationKeys returns the keys tokens are accepted with, for jwt.Keyfunc
+func (k *RouterKey) verificationKeys(now time.Time) jwt.VerificationKeySet {
+	if k == nil || k.File == "" {
+		return jwt.VerificationKeySet{Keys: []jwt.VerificationKey{[]byte(os.Getenv("ROUTER_KEY"))}}
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	keys := jwt.VerificationKeySet{Keys: []jwt.VerificationKey{k.current}}
+	if k.previous != nil && now.Before(k.expires) {
+		keys.Keys = append(keys.Keys, k.previous)
+	}
+	return keys
+}
+
+// Start reloads the key whenever its file changes, until ctx is done
+func (k *RouterKey) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx)
+
+	if k.File == "" {
+		<-ctx.Done()
+		return nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("Start: failed to create watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	// Secret volumes are updated by swapping a symlink in the directory, not by writing the file
+	if err := watcher.Add(filepath.Dir(k.File)); err != nil {
+		return fmt.Errorf("Start: failed to watch router key: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if err := k.load(time.Now()); err != nil {
+				logger.Error(err, "unable to reload router key")
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			logger.Error(err, "router key watcher failed")
+		}
+	}
+}
+
+// SetupWithManager sets up the key reloading with the Manager.
+func (k *RouterKey) SetupWithManager(mgr ctrl.Manager) error {
+	return mgr.Add(k)
+}
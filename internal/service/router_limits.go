@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// activeStreams counts the forwarded streams per lease, to enforce MaxConcurrentDials
+type activeStreams struct {
+	mu     sync.Mutex
+	counts map[string]int32
+}
+
+// acquire registers a new stream of lease, unless limit streams are already active
+func (a *activeStreams) acquire(lease string, limit int32) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.counts == nil {
+		a.counts = make(map[string]int32)
+	}
+	if limit > 0 && a.counts[lease] >= limit {
+		return false
+	}
+	a.counts[lease]++
+	return true
+}
+
+func (a *activeStreams) release(lease string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.counts[lease]--
+	if a.counts[lease] <= 0 {
+		delete(a.counts, lease)
+	}
+}
+
+// total is the number of streams active on all leases
+func (a *activeStreams) total() int32 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var total int32
+	for _, count := range a.counts {
+		total += count
+	}
+	return total
+}
+
+// withStreamLimits applies the session duration limit to ctx and returns the forwarding
+// options enforcing the bandwidth limit
+func withStreamLimits(
+	ctx context.Context,
+	limits *StreamLimitClaims,
+) (context.Context, context.CancelFunc, ForwardOptions) {
+	if limits == nil {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, cancel, ForwardOptions{}
+	}
+
+	opts := ForwardOptions{BytesPerSecond: limits.MaxBandwidth}
+	if limits.MaxSessionDuration > 0 {
+		ctx, cancel := context.WithTimeout(ctx, time.Duration(limits.MaxSessionDuration)*time.Second)
+		return ctx, cancel, opts
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return ctx, cancel, opts
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	pb "github.com/jumpstarter-dev/jumpstarter-controller/internal/protocol/jumpstarter/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// observerBuffer is how many frames an observer may lag behind the stream before being detached
+const observerBuffer = 64
+
+// errObserverBehind detaches the observers failing to keep up with the stream
+var errObserverBehind = status.Errorf(codes.ResourceExhausted, "observer fell behind the stream")
+
+type observer struct {
+	side   string
+	frames chan *pb.StreamResponse
+	cancel context.CancelCauseFunc
+}
+
+// observerSet holds the receive-only parties attached to a stream
+type observerSet struct {
+	mu        sync.Mutex
+	observers map[*observer]struct{}
+	// closed once the stream is over or the last observer left, the set is not in use anymore
+	closed bool
+}
+
+// add attaches an observer of side, false if the set is closed
+func (o *observerSet) add(side string, cancel context.CancelCauseFunc) (*observer, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return nil, false
+	}
+	if o.observers == nil {
+		o.observers = make(map[*observer]struct{})
+	}
+	obs := &observer{side: side, frames: make(chan *pb.StreamResponse, observerBuffer), cancel: cancel}
+	o.observers[obs] = struct{}{}
+	return obs, true
+}
+
+// remove detaches obs, closing the set if it was the last observer
+func (o *observerSet) remove(obs *observer) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	delete(o.observers, obs)
+	if len(o.observers) == 0 {
+		o.closed = true
+	}
+	return o.closed
+}
+
+// broadcast queues a frame received from side to the observers of that side without blocking,
+// observers failing to keep up are detached
+func (o *observerSet) broadcast(side string, msg *pb.StreamResponse) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for obs := range o.observers {
+		if obs.side != side {
+			continue
+		}
+		select {
+		case obs.frames <- msg:
+		default:
+			obs.cancel(errObserverBehind)
+			delete(o.observers, obs)
+		}
+	}
+}
+
+// close detaches all observers
+func (o *observerSet) close() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.closed = true
+	for obs := range o.observers {
+		obs.cancel(nil)
+		delete(o.observers, obs)
+	}
+}
+
+// attachObserver adds an observer of side to the set of streamName, creating the set if needed
+func (s *RouterService) attachObserver(
+	streamName string,
+	side string,
+	cancel context.CancelCauseFunc,
+) (*observerSet, *observer) {
+	for {
+		set, _ := s.observers.LoadOrStore(streamName, &observerSet{})
+		if obs, ok := set.(*observerSet).add(side, cancel); ok {
+			return set.(*observerSet), obs
+		}
+		// the set was closed meanwhile, drop it so that the next attempt creates a new one
+		s.observers.CompareAndDelete(streamName, set)
+	}
+}
+
+// detachObserver removes obs from set, and set from streamName once it has no observers left
+func (s *RouterService) detachObserver(streamName string, set *observerSet, obs *observer) {
+	if set.remove(obs) {
+		s.observers.CompareAndDelete(streamName, set)
+	}
+}
+
+// tap returns the function mirroring the frames sent by side of streamName to its observers
+func (s *RouterService) tap(streamName string, side string) func(*pb.StreamResponse) {
+	return func(msg *pb.StreamResponse) {
+		if set, ok := s.observers.Load(streamName); ok {
+			set.(*observerSet).broadcast(side, msg)
+		}
+	}
+}
+
+// closeObservers detaches the observers of streamName once the stream is over
+func (s *RouterService) closeObservers(streamName string) {
+	if set, ok := s.observers.LoadAndDelete(streamName); ok {
+		set.(*observerSet).close()
+	}
+}
+
+// observe attaches stream to streamName as a receive-only party until either ends, the frames are
+// sent from their own goroutine so that a stalled observer never holds up the stream
+func (s *RouterService) observe(
+	ctx context.Context,
+	streamName string,
+	stream pb.RouterService_StreamServer,
+	peer streamPeer,
+) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	set, obs := s.attachObserver(streamName, peer.observe, cancel)
+	defer s.detachObserver(streamName, set, obs)
+
+	errs := make(chan error, 2)
+	go func() {
+		_, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		if err == nil {
+			err = status.Errorf(codes.PermissionDenied, "observers are receive-only")
+		}
+		errs <- err
+	}()
+	go func() {
+		// a pending Send fails once the call returns
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg := <-obs.frames:
+				if err := stream.Send(msg); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		if errors.Is(context.Cause(ctx), errObserverBehind) {
+			return errObserverBehind
+		}
+		return nil
+	case err := <-errs:
+		return err
+	}
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// RouterRegistryName is the ConfigMap, in the namespace of the controller, the router replicas
+// register in, under their name, the key of a replica suffixed with RouterDrainSuffix is set
+// to "true" by administrators to drain it
+const RouterRegistryName = "jumpstarter-routers"
+
+// RouterDrainSuffix is appended to the name of a router replica for the registry key draining it
+const RouterDrainSuffix = ".drain"
+
+// routerHeartbeatInterval is how often router replicas refresh their registration,
+// replicas missing three heartbeats are no longer assigned streams
+const routerHeartbeatInterval = 10 * time.Second
+
+// RouterReplica is the registration of a router replica
+type RouterReplica struct {
+	// The endpoint reaching the replica
+	Endpoint string `json:"endpoint"`
+	// Whether the replica is draining, rejecting new streams while the active ones finish
+	Draining bool `json:"draining,omitempty"`
+	// The number of streams being forwarded by the replica
+	ActiveStreams int32 `json:"activeStreams"`
+	// When the replica last refreshed its registration
+	Heartbeat time.Time `json:"heartbeat"`
+	// The resumable streams forwarded by the replica, adopted by a peer replica if it dies
+	Streams []RouterStream `json:"streams,omitempty"`
+}
+
+// RouterRegistry returns the router replicas registered in namespace by name, and which of them
+// administrators requested to drain
+func RouterRegistry(
+	ctx context.Context,
+	c client.Reader,
+	namespace string,
+) (map[string]RouterReplica, map[string]bool, error) {
+	var configmap corev1.ConfigMap
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: RouterRegistryName}, &configmap); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("RouterRegistry: failed to get router registry: %w", err)
+	}
+
+	replicas := map[string]RouterReplica{}
+	drains := map[string]bool{}
+	for key, value := range configmap.Data {
+		if name, ok := strings.CutSuffix(key, RouterDrainSuffix); ok {
+			drains[name] = value == "true"
+			continue
+		}
+		var replica RouterReplica
+		if err := json.Unmarshal([]byte(value), &replica); err != nil {
+			return nil, nil, fmt.Errorf("RouterRegistry: invalid registration of router %s: %w", key, err)
+		}
+		replicas[key] = replica
+	}
+	return replicas, drains, nil
+}
+
+// selectRouter returns the endpoint of the router replica to assign a new stream to, the live
+// replica not draining with the fewest active streams, or the shared router endpoint if no
+// replica is registered
+func selectRouter(ctx context.Context, c client.Reader, now time.Time) (string, error) {
+	namespace := os.Getenv("NAMESPACE")
+	if namespace == "" {
+		return routerEndpoint(), nil
+	}
+
+	replicas, drains, err := RouterRegistry(ctx, c, namespace)
+	if err != nil {
+		return "", err
+	}
+	if len(replicas) == 0 {
+		return routerEndpoint(), nil
+	}
+
+	names := make([]string, 0, len(replicas))
+	for name := range replicas {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var selected *RouterReplica
+	for _, name := range names {
+		replica := replicas[name]
+		if replica.Draining || drains[name] || now.Sub(replica.Heartbeat) > 3*routerHeartbeatInterval {
+			continue
+		}
+		if selected == nil || replica.ActiveStreams < selected.ActiveStreams {
+			selected = &replica
+		}
+	}
+	if selected == nil {
+		return "", status.Errorf(codes.Unavailable, "no router available, all replicas are draining or down")
+	}
+	return selected.Endpoint, nil
+}
+
+// Draining reports whether the router replica is draining, rejecting new streams
+func (s *RouterService) Draining() bool {
+	return s.draining.Load()
+}
+
+// runRegistration keeps the registration of the router replica up to date until ctx is done,
+// picking up the drain requests of the administrators, then removes it
+func (s *RouterService) runRegistration(ctx context.Context) {
+	namespace := os.Getenv("NAMESPACE")
+	if namespace == "" {
+		return
+	}
+	logger := log.FromContext(ctx).WithValues("replica", routerReplicaName())
+
+	// the registration is also refreshed as soon as a resumable stream starts, to hand it off
+	ticker := time.NewTicker(routerHeartbeatInterval)
+	defer ticker.Stop()
+	for ctx.Err() == nil {
+		if err := s.register(ctx, namespace); err != nil {
+			logger.Error(err, "unable to register router replica")
+		}
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		case <-s.handoffs.nudged():
+		}
+	}
+
+	// deregister under a fresh context, ctx is done
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.patchRegistry(ctx, namespace, routerReplicaName(), nil); err != nil {
+		logger.Error(err, "unable to deregister router replica")
+	}
+}
+
+func (s *RouterService) register(ctx context.Context, namespace string) error {
+	name := routerReplicaName()
+
+	replicas, drains, err := RouterRegistry(ctx, s.Client, namespace)
+	if err != nil {
+		return err
+	}
+	if draining := drains[name]; draining != s.draining.Swap(draining) {
+		log.FromContext(ctx).Info("router replica drain state changed", "draining", draining)
+	}
+
+	now := time.Now()
+	for _, expired := range s.handoffs.update(name, replicas, drains, now) {
+		log.FromContext(ctx).Info("removing the registration of a dead router replica", "dead", expired)
+		if err := s.patchRegistry(ctx, namespace, expired, nil); err != nil {
+			return err
+		}
+	}
+
+	registration, err := json.Marshal(RouterReplica{
+		Endpoint:      routerReplicaEndpoint(),
+		Draining:      s.Draining(),
+		ActiveStreams: s.active.total(),
+		Heartbeat:     now,
+		Streams:       s.handoffs.streams(),
+	})
+	if err != nil {
+		return fmt.Errorf("register: %w", err)
+	}
+	value := string(registration)
+	return s.patchRegistry(ctx, namespace, name, &value)
+}
+
+// patchRegistry sets the registration of the router replica name to value, or removes it if nil,
+// leaving the registrations of the other replicas and the drain requests alone
+func (s *RouterService) patchRegistry(ctx context.Context, namespace string, name string, value *string) error {
+	patch, err := json.Marshal(map[string]any{
+		"data": map[string]*string{name: value},
+	})
+	if err != nil {
+		return fmt.Errorf("patchRegistry: %w", err)
+	}
+
+	configmap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      RouterRegistryName,
+		},
+	}
+	err = s.Client.Patch(ctx, configmap, client.RawPatch(types.MergePatchType, patch))
+	if apierrors.IsNotFound(err) {
+		if value == nil {
+			return nil
+		}
+		configmap.Data = map[string]string{name: *value}
+		err = s.Client.Create(ctx, configmap)
+	}
+	if err != nil {
+		return fmt.Errorf("patchRegistry: %w", err)
+	}
+	return nil
+}
@@ -18,21 +18,30 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net"
-	"os"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	pb "github.com/jumpstarter-dev/jumpstarter-controller/internal/protocol/jumpstarter/v1"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/credentials"
-	"google.golang.org/grpc/reflection"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/pkg/api"
 )
 
 // RouterService exposes a gRPC service
@@ -41,23 +50,49 @@ type RouterService struct {
 	client.Client
 	Scheme  *runtime.Scheme
 	pending sync.Map
+	// Recorder, if set, records the streams of leases with recording enabled
+	Recorder *StreamRecorder
+	// Events, if set, receives the stream session events of the leases
+	Events record.EventRecorder
+	// Keepalive is the keepalive policy enforced on clients, defaults to DefaultKeepalivePolicy
+	Keepalive KeepalivePolicy
+	// DisabledEndpoints are the optional endpoints not served on the listener
+	DisabledEndpoints DisabledEndpoints
+	// FlowControl bounds the buffering of the forwarded streams, defaults to DefaultFlowControlPolicy
+	FlowControl FlowControlPolicy
+	// RouterKey verifies the router tokens, nil for the ROUTER_KEY environment variable
+	RouterKey *RouterKey
+	// CertificateAuth, if set, verifies the TLS client certificates of the peers,
+	// the streams are still authorized by their router tokens
+	CertificateAuth *CertificateAuth
+	// SelfSignedCertificate is how the serving certificate is kept across restarts
+	SelfSignedCertificate SelfSignedCertificateConfig
+	// observer sets per stream name
+	observers sync.Map
+	active    activeStreams
+	draining  atomic.Bool
+	handoffs  streamHandoffs
+	grpcHealth
 }
 
 type streamContext struct {
-	cancel context.CancelFunc
-	stream pb.RouterService_StreamServer
+	cancel  context.CancelFunc
+	stream  pb.RouterService_StreamServer
+	peer    streamPeer
+	tokenID string
 }
 
-func (s *RouterService) authenticate(ctx context.Context) (string, error) {
+func (s *RouterService) authenticate(ctx context.Context) (*StreamClaims, error) {
 	token, err := BearerTokenFromContext(ctx)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
+	claims := &StreamClaims{}
 	parsed, err := jwt.ParseWithClaims(
 		token,
-		&jwt.RegisteredClaims{},
-		func(t *jwt.Token) (interface{}, error) { return []byte(os.Getenv("ROUTER_KEY")), nil },
+		claims,
+		func(t *jwt.Token) (interface{}, error) { return s.RouterKey.verificationKeys(time.Now()), nil },
 		jwt.WithIssuer("https://jumpstarter.dev/stream"),
 		jwt.WithAudience("https://jumpstarter.dev/router"),
 		jwt.WithIssuedAt(),
@@ -69,45 +104,178 @@ func (s *RouterService) authenticate(ctx context.Context) (string, error) {
 		}),
 	)
 
+	// the tokens of the streams handed off to the replica resume them even once expired,
+	// for as long as the streams of a dead replica can be handed off
+	if errors.Is(err, jwt.ErrTokenExpired) && !errors.Is(err, jwt.ErrTokenSignatureInvalid) &&
+		claims.ExpiresAt != nil && time.Since(claims.ExpiresAt.Time) <= routerHandoffWindow {
+		if namespace, id, serr := claims.stream(); serr == nil &&
+			s.handoffs.adoptedToken(streamSubject(namespace, id), claims.ID) {
+			return claims, nil
+		}
+	}
+
 	if err != nil || !parsed.Valid {
-		return "", status.Errorf(codes.InvalidArgument, "invalid jwt token")
+		return nil, status.Errorf(codes.InvalidArgument, "invalid jwt token")
 	}
 
-	return parsed.Claims.GetSubject()
+	return claims, nil
 }
 
 func (s *RouterService) Stream(stream pb.RouterService_StreamServer) error {
 	ctx := stream.Context()
 	logger := log.FromContext(ctx)
 
-	streamName, err := s.authenticate(ctx)
+	claims, err := s.authenticate(ctx)
 	if err != nil {
 		logger.Error(err, "failed to authenticate")
 		return err
 	}
 
-	logger.Info("streaming", "stream", streamName)
+	// pending streams are keyed by namespace, so that streams of different tenants never pair
+	namespace, streamID, err := claims.stream()
+	if err != nil {
+		logger.Error(err, "invalid stream token")
+		return err
+	}
+	streamName := streamSubject(namespace, streamID)
+
+	// correlate the stream with the lease, the exporter and the client without the controller logs
+	logger = logger.WithValues(
+		"stream", streamName,
+		"lease", claims.Lease,
+		"namespace", claims.Namespace,
+		"exporter", claims.Exporter,
+		"client", claims.Client,
+	)
+	ctx = log.IntoContext(ctx, logger)
+
+	peer, err := peerFromContext(ctx, claims)
+	if err != nil {
+		logger.Error(err, "invalid stream handshake")
+		return err
+	}
+
+	logger.Info("streaming", "peer", peer.peer)
+
+	if peer.peer == api.PeerObserver {
+		logger.Info("observing", "side", peer.observe)
+		return s.observe(ctx, streamName, stream, peer)
+	}
+
+	if err := s.redirectHandedOff(ctx, streamName, stream); err != nil {
+		return err
+	}
 
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	sctx := streamContext{
-		cancel: cancel,
-		stream: stream,
+	sctx := &streamContext{
+		cancel:  cancel,
+		stream:  stream,
+		peer:    peer,
+		tokenID: claims.ID,
+	}
+
+	// a draining replica still pairs the streams whose other side already reached it
+	if _, paired := s.pending.Load(streamName); !paired && s.Draining() &&
+		!s.handoffs.adoptedToken(streamName, claims.ID) {
+		logger.Info("rejecting new stream, router draining")
+		return status.Errorf(codes.Unavailable, "router draining")
 	}
 
 	actual, loaded := s.pending.LoadOrStore(streamName, sctx)
 	if loaded {
-		defer actual.(streamContext).cancel()
-		logger.Info("forwarding", "stream", streamName)
-		return Forward(ctx, stream, actual.(streamContext).stream)
+		other := actual.(*streamContext)
+		if err := peer.pairable(other.peer); err != nil {
+			logger.Error(err, "unable to pair stream")
+			return err
+		}
+		// the waiting side is paired with one stream only, a later side waits for a new one
+		if !s.pending.CompareAndDelete(streamName, other) {
+			return status.Errorf(codes.Aborted, "other side of the stream left")
+		}
+		defer other.cancel()
+
+		var maxConcurrentDials int32
+		if claims.Limits != nil {
+			maxConcurrentDials = claims.Limits.MaxConcurrentDials
+		}
+		if !s.active.acquire(claims.Lease, maxConcurrentDials) {
+			logger.Info("rejecting stream over the concurrent dial limit")
+			return status.Errorf(codes.ResourceExhausted, "maximum concurrent streams for lease reached")
+		}
+		defer s.active.release(claims.Lease)
+
+		s.streamEvent(claims, "StreamStarted", "Stream %s between client %s and exporter %s started",
+			streamID, claims.Client, claims.Exporter)
+		defer func(begin time.Time) {
+			s.streamEvent(claims, "StreamEnded", "Stream %s between client %s and exporter %s ended after %s",
+				streamID, claims.Client, claims.Exporter, time.Since(begin).Round(time.Second))
+		}(time.Now())
+
+		// the waiting side is blocked until canceled, sending its header here does not race
+		negotiated := negotiate(peer, other.peer)
+		if s.handoffs.resumed(streamName) {
+			logger.Info("resuming handed off stream")
+			negotiated.Set(ResumedHeader, "true")
+		}
+		if len(negotiated.Get(api.OptionHeaderPrefix+StreamOptionResumption)) > 0 {
+			s.handoffs.add(streamName, claims.ID, other.tokenID)
+			defer s.handoffs.remove(streamName)
+		}
+		if err := other.stream.SendHeader(negotiated); err != nil {
+			return err
+		}
+		if err := stream.SendHeader(negotiated); err != nil {
+			return err
+		}
+
+		defer s.closeObservers(streamName)
+
+		tap, otherTap := s.tap(streamName, peer.peer), s.tap(streamName, other.peer.peer)
+		if s.Recorder != nil && claims.Record {
+			recording, err := s.Recorder.open(claims.Lease, streamID)
+			if err != nil {
+				logger.Error(err, "unable to record stream")
+			} else {
+				defer recording.Close()
+				tap, otherTap = recording.tap(peer.peer, tap), recording.tap(other.peer.peer, otherTap)
+			}
+		}
+
+		ctx, cancel, opts := withStreamLimits(ctx, claims.Limits)
+		defer cancel()
+		opts.TapA, opts.TapB = tap, otherTap
+
+		logger.Info("forwarding")
+		err := ForwardWithOptions(ctx, stream, other.stream, opts)
+		if errors.Is(err, context.DeadlineExceeded) {
+			return status.Errorf(codes.DeadlineExceeded, "maximum session duration reached")
+		}
+		return err
 	} else {
-		logger.Info("waiting for the other side", "stream", streamName)
+		logger.Info("waiting for the other side")
 		<-ctx.Done()
+		s.pending.CompareAndDelete(streamName, sctx)
 		return nil
 	}
 }
 
+// streamEvent records an event on the lease of the stream, the router does not get the lease,
+// the event refers to it by name only
+func (s *RouterService) streamEvent(claims *StreamClaims, reason string, messageFmt string, args ...interface{}) {
+	if s.Events == nil {
+		return
+	}
+	namespace, name, ok := strings.Cut(claims.Lease, "/")
+	if !ok {
+		return
+	}
+	s.Events.Eventf(&jumpstarterdevv1alpha1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+	}, corev1.EventTypeNormal, reason, messageFmt, args...)
+}
+
 func (s *RouterService) Start(ctx context.Context) error {
 	log := log.FromContext(ctx)
 
@@ -115,26 +283,64 @@ func (s *RouterService) Start(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	if replicaEndpoint := routerReplicaEndpoint(); replicaEndpoint != routerEndpoint() {
+		replicaDNSNames, replicaIPAddresses, err := endpointToSAN(replicaEndpoint)
+		if err != nil {
+			return err
+		}
+		dnsnames = append(dnsnames, replicaDNSNames...)
+		ipaddresses = append(ipaddresses, replicaIPAddresses...)
+	}
 
-	cert, err := NewSelfSignedCertificate("jumpstarter router", dnsnames, ipaddresses)
-	if err != nil {
-		return err
+	var opts []grpc.ServerOption
+	if routerTLSTermination() == tlsTerminationEdge {
+		if s.CertificateAuth != nil {
+			return errors.New("Start: client certificate authentication requires the TLS to be terminated by the router")
+		}
+		log.Info("TLS terminated by edge proxy, serving cleartext gRPC")
+	} else {
+		// the replicas addressable on their own are issued certificates of their own
+		secret := "jumpstarter-router-serving-cert"
+		if routerReplicaEndpoint() != routerEndpoint() {
+			secret += "-" + routerReplicaName()
+		}
+		cert, err := s.SelfSignedCertificate.Certificate(ctx, s.Client,
+			secret, "jumpstarter router", dnsnames, ipaddresses)
+		if err != nil {
+			return fmt.Errorf("Start: %w", err)
+		}
+
+		if err := publishServingCA(ctx, s.Client, "jumpstarter-router-serving-ca", cert); err != nil {
+			return err
+		}
+
+		opts = append(opts, grpc.Creds(serverCredentials(s.CertificateAuth, cert)))
 	}
 
-	server := grpc.NewServer(grpc.Creds(credentials.NewServerTLSFromCert(cert)))
+	opts = append(opts, s.Keepalive.serverOptions()...)
+	opts = append(opts, s.FlowControl.serverOptions()...)
+	opts = append(opts, headerInterceptors(s.Keepalive.metadata())...)
+
+	server := grpc.NewServer(opts...)
 
 	pb.RegisterRouterServiceServer(server, s)
+	healthpb.RegisterHealthServer(server, s.healthServer())
 
-	reflection.Register(server)
+	s.DisabledEndpoints.register(server)
 	listener, err := net.Listen("tcp", ":8083")
 	if err != nil {
 		return err
 	}
 
 	log.Info("Starting grpc router service")
+	s.healthServer().Resume()
+
+	go s.runRegistration(ctx)
+
 	go func() {
 		<-ctx.Done()
 		log.Info("Stopping grpc router service")
+		s.healthServer().Shutdown()
 		server.Stop()
 	}()
 
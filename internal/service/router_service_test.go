@@ -0,0 +1,384 @@
+package service
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/jumpstarter-dev/jumpstarter-controller/internal/protocol/jumpstarter/v1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/pkg/api"
+)
+
+// routerStream is a side of a router stream, the frames sent to in are received by the router,
+// the frames the router sends are received on out
+type routerStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	in     chan *pb.StreamRequest
+	out    chan *pb.StreamResponse
+	mu     sync.Mutex
+	header metadata.MD
+}
+
+func newRouterStream(ctx context.Context) *routerStream {
+	return &routerStream{ctx: ctx, in: make(chan *pb.StreamRequest), out: make(chan *pb.StreamResponse)}
+}
+
+func (r *routerStream) Context() context.Context {
+	return r.ctx
+}
+
+func (r *routerStream) Recv() (*pb.StreamRequest, error) {
+	select {
+	case msg, ok := <-r.in:
+		if !ok {
+			return nil, io.EOF
+		}
+		return msg, nil
+	case <-r.ctx.Done():
+		return nil, status.FromContextError(r.ctx.Err()).Err()
+	}
+}
+
+func (r *routerStream) Send(msg *pb.StreamResponse) error {
+	select {
+	case r.out <- msg:
+		return nil
+	case <-r.ctx.Done():
+		return status.FromContextError(r.ctx.Err()).Err()
+	}
+}
+
+func (r *routerStream) SetHeader(md metadata.MD) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.header = metadata.Join(r.header, md)
+	return nil
+}
+
+func (r *routerStream) SendHeader(md metadata.MD) error {
+	return r.SetHeader(md)
+}
+
+func (r *routerStream) headers() metadata.MD {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.header.Copy()
+}
+
+var _ = Describe("Router streams", func() {
+	const streamName = "default/stream"
+	var s *RouterService
+	// the stream the tokens are signed for, and the limits they carry
+	var subject string
+	var limits *StreamLimitClaims
+
+	BeforeEach(func() {
+		s = &RouterService{RouterKey: &RouterKey{File: "test", current: []byte("test-key")}}
+		subject, limits = streamName, nil
+	})
+
+	// token signs the token of the peer side of the stream expiring at expires, returning its ID
+	token := func(peer string, expires time.Time) (string, string) {
+		issued := time.Now()
+		if expires.Before(issued) {
+			issued = expires.Add(-streamTokenLifetime)
+		}
+		claims := StreamClaims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    "https://jumpstarter.dev/stream",
+				Subject:   subject,
+				Audience:  []string{"https://jumpstarter.dev/router"},
+				ExpiresAt: jwt.NewNumericDate(expires),
+				IssuedAt:  jwt.NewNumericDate(issued),
+				ID:        peer + "-" + subject,
+			},
+			Lease:     "default/lease",
+			Namespace: "default",
+			Peer:      peer,
+			Limits:    limits,
+		}
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.RouterKey.signingKey())
+		Expect(err).NotTo(HaveOccurred())
+		return signed, claims.ID
+	}
+
+	// connect streams the peer side with a token expiring at expires until the returned stream
+	// is cancelled, returning the error of the router on errs
+	connect := func(peer string, expires time.Time, headers ...string) (*routerStream, context.CancelFunc, <-chan error) {
+		signed, _ := token(peer, expires)
+		md := metadata.Pairs(append(headers, "authorization", "Bearer "+signed)...)
+		ctx, cancel := context.WithCancel(metadata.NewIncomingContext(context.Background(), md))
+		DeferCleanup(cancel)
+		stream := newRouterStream(ctx)
+		errs := make(chan error, 1)
+		go func() {
+			errs <- s.Stream(stream)
+		}()
+		return stream, cancel, errs
+	}
+
+	pending := func() bool {
+		_, ok := s.pending.Load(streamName)
+		return ok
+	}
+
+	It("should forget the waiting side once it leaves", func() {
+		_, cancel, errs := connect(api.PeerClient, time.Now().Add(time.Hour))
+		Eventually(pending).Should(BeTrue())
+
+		cancel()
+		Eventually(errs).Should(Receive(BeNil()))
+		Expect(pending()).To(BeFalse())
+	})
+
+	It("should forget the waiting side once paired and forward the frames", func() {
+		client, _, _ := connect(api.PeerClient, time.Now().Add(time.Hour))
+		Eventually(pending).Should(BeTrue())
+		exporter, _, _ := connect(api.PeerExporter, time.Now().Add(time.Hour))
+		Eventually(pending).Should(BeFalse())
+
+		client.in <- &pb.StreamRequest{Payload: []byte("hello")}
+		Eventually(exporter.out).Should(Receive(HaveField("Payload", []byte("hello"))))
+		exporter.in <- &pb.StreamRequest{Payload: []byte("world")}
+		Eventually(client.out).Should(Receive(HaveField("Payload", []byte("world"))))
+	})
+
+	It("should reject new streams while draining but pair the waiting ones", func() {
+		_, cancel, errs := connect(api.PeerClient, time.Now().Add(time.Hour))
+		Eventually(pending).Should(BeTrue())
+		s.draining.Store(true)
+
+		exporter, _, _ := connect(api.PeerExporter, time.Now().Add(time.Hour))
+		Eventually(pending).Should(BeFalse())
+		Expect(exporter.headers()).NotTo(BeNil())
+		cancel()
+		Eventually(errs).Should(Receive(BeNil()))
+
+		_, _, errs = connect(api.PeerClient, time.Now().Add(time.Hour))
+		Eventually(errs).Should(Receive(WithTransform(status.Code, Equal(codes.Unavailable))))
+	})
+
+	It("should not let a stream whose waiting side left past a draining router", func() {
+		_, cancel, errs := connect(api.PeerClient, time.Now().Add(time.Hour))
+		Eventually(pending).Should(BeTrue())
+		cancel()
+		Eventually(errs).Should(Receive(BeNil()))
+		s.draining.Store(true)
+
+		_, _, errs = connect(api.PeerExporter, time.Now().Add(time.Hour))
+		Eventually(errs).Should(Receive(WithTransform(status.Code, Equal(codes.Unavailable))))
+	})
+
+	Context("handed off", func() {
+		resumption := []string{api.OptionHeaderPrefix + StreamOptionResumption, "1"}
+
+		BeforeEach(func() {
+			_, clientID := token(api.PeerClient, time.Now())
+			_, exporterID := token(api.PeerExporter, time.Now())
+			s.handoffs.adopted = map[string]RouterStream{
+				streamName: {Name: streamName, TokenIDs: []string{clientID, exporterID}},
+			}
+		})
+
+		It("should resume an adopted stream with expired tokens", func() {
+			s.draining.Store(true)
+			expired := time.Now().Add(-time.Minute)
+			client, _, _ := connect(api.PeerClient, expired, resumption...)
+			Eventually(pending).Should(BeTrue())
+			exporter, _, _ := connect(api.PeerExporter, expired, resumption...)
+			Eventually(pending).Should(BeFalse())
+
+			Eventually(client.headers).Should(HaveKeyWithValue(ResumedHeader, []string{"true"}))
+			Expect(exporter.headers()).To(HaveKeyWithValue(ResumedHeader, []string{"true"}))
+			Expect(s.handoffs.streams()).To(ConsistOf(HaveField("Name", streamName)))
+
+			client.in <- &pb.StreamRequest{Payload: []byte("resumed")}
+			Eventually(exporter.out).Should(Receive(HaveField("Payload", []byte("resumed"))))
+		})
+
+		It("should refuse tokens expired for longer than the handoff window", func() {
+			_, _, errs := connect(api.PeerClient, time.Now().Add(-routerHandoffWindow-time.Minute), resumption...)
+			Eventually(errs).Should(Receive(WithTransform(status.Code, Equal(codes.InvalidArgument))))
+		})
+
+		It("should redirect the streams adopted by another replica", func() {
+			s.handoffs.redirects = map[string]string{streamName: "router-1:443"}
+			client, _, errs := connect(api.PeerClient, time.Now().Add(time.Hour))
+			Eventually(errs).Should(Receive(WithTransform(status.Code, Equal(codes.Unavailable))))
+			Expect(client.headers()).To(HaveKeyWithValue(RouterEndpointHeader, []string{"router-1:443"}))
+		})
+	})
+
+	Context("limited", func() {
+		BeforeEach(func() {
+			limits = &StreamLimitClaims{MaxConcurrentDials: 1}
+		})
+
+		// pair pairs the two sides of a new stream, returning them and the error of the pairing side
+		pair := func(name string) (*routerStream, *routerStream, context.CancelFunc, <-chan error) {
+			subject = "default/" + name
+			client, _, _ := connect(api.PeerClient, time.Now().Add(time.Hour))
+			Eventually(func() bool {
+				_, ok := s.pending.Load(subject)
+				return ok
+			}).Should(BeTrue())
+			exporter, cancel, errs := connect(api.PeerExporter, time.Now().Add(time.Hour))
+			return client, exporter, cancel, errs
+		}
+
+		active := func() int32 {
+			s.active.mu.Lock()
+			defer s.active.mu.Unlock()
+			return s.active.counts["default/lease"]
+		}
+
+		// forwarding waits until the stream of client and exporter forwards frames
+		forwarding := func(client *routerStream, exporter *routerStream) {
+			client.in <- &pb.StreamRequest{Payload: []byte("ping")}
+			Eventually(exporter.out).Should(Receive())
+		}
+
+		It("should reject the streams over the concurrent limit", func() {
+			client, exporter, _, _ := pair("first")
+			forwarding(client, exporter)
+			Expect(active()).To(BeEquivalentTo(1))
+
+			_, _, _, errs := pair("second")
+			Eventually(errs).Should(Receive(WithTransform(status.Code, Equal(codes.ResourceExhausted))))
+			Expect(active()).To(BeEquivalentTo(1))
+		})
+
+		It("should release the limit once both sides end", func() {
+			client, exporter, _, errs := pair("first")
+			forwarding(client, exporter)
+			close(client.in)
+			close(exporter.in)
+			Eventually(errs).Should(Receive(BeNil()))
+			Expect(active()).To(BeEquivalentTo(0))
+
+			client, exporter, _, _ = pair("second")
+			forwarding(client, exporter)
+		})
+
+		It("should release the limit once a side is cancelled", func() {
+			client, exporter, cancel, errs := pair("first")
+			forwarding(client, exporter)
+			cancel()
+			Eventually(errs).Should(Receive(HaveOccurred()))
+			Expect(active()).To(BeEquivalentTo(0))
+		})
+
+		It("should release the limit once the maximum session duration is reached", func() {
+			limits.MaxSessionDuration = 1
+			client, exporter, _, errs := pair("first")
+			forwarding(client, exporter)
+			Eventually(errs, 3*time.Second).Should(Receive(WithTransform(status.Code, Equal(codes.DeadlineExceeded))))
+			Expect(active()).To(BeEquivalentTo(0))
+		})
+	})
+
+	Context("handshake", func() {
+		It("should send both sides the options they offered alike", func() {
+			client, _, _ := connect(api.PeerClient, time.Now().Add(time.Hour),
+				api.OptionHeaderPrefix+"compression", "zstd", api.OptionHeaderPrefix+"version", "2")
+			Eventually(pending).Should(BeTrue())
+			exporter, _, _ := connect(api.PeerExporter, time.Now().Add(time.Hour),
+				api.OptionHeaderPrefix+"compression", "zstd", api.OptionHeaderPrefix+"version", "1")
+
+			for _, side := range []*routerStream{client, exporter} {
+				Eventually(side.headers).Should(HaveKeyWithValue(api.OptionHeaderPrefix+"compression", []string{"zstd"}))
+				Expect(side.headers()).NotTo(HaveKey(api.OptionHeaderPrefix + "version"))
+			}
+		})
+
+		It("should not pair two sides of the same peer", func() {
+			connect(api.PeerClient, time.Now().Add(time.Hour))
+			Eventually(pending).Should(BeTrue())
+			_, _, errs := connect(api.PeerClient, time.Now().Add(time.Hour))
+			Eventually(errs).Should(Receive(WithTransform(status.Code, Equal(codes.AlreadyExists))))
+			Expect(pending()).To(BeTrue())
+		})
+
+		It("should refuse a peer header not matching the token", func() {
+			_, _, errs := connect(api.PeerClient, time.Now().Add(time.Hour), api.PeerHeader, api.PeerExporter)
+			Eventually(errs).Should(Receive(WithTransform(status.Code, Equal(codes.PermissionDenied))))
+		})
+
+		It("should refuse a lease header not matching the token", func() {
+			_, _, errs := connect(api.PeerClient, time.Now().Add(time.Hour), api.LeaseHeader, "default/other")
+			Eventually(errs).Should(Receive(WithTransform(status.Code, Equal(codes.PermissionDenied))))
+		})
+	})
+
+	Context("observed", func() {
+		// observe attaches an observer of side to the stream
+		observe := func(side string) (*routerStream, <-chan error) {
+			observer, _, errs := connect(api.PeerObserver, time.Now().Add(time.Hour), api.ObserveHeader, side)
+			Eventually(func() bool {
+				_, ok := s.observers.Load(streamName)
+				return ok
+			}).Should(BeTrue())
+			return observer, errs
+		}
+
+		// pairStream pairs the client and the exporter of the stream
+		pairStream := func() (*routerStream, *routerStream, <-chan error) {
+			client, _, _ := connect(api.PeerClient, time.Now().Add(time.Hour))
+			Eventually(pending).Should(BeTrue())
+			exporter, _, errs := connect(api.PeerExporter, time.Now().Add(time.Hour))
+			Eventually(pending).Should(BeFalse())
+			return client, exporter, errs
+		}
+
+		It("should mirror the frames of the observed side only", func() {
+			observer, _ := observe(api.PeerExporter)
+			client, exporter, _ := pairStream()
+
+			client.in <- &pb.StreamRequest{Payload: []byte("request")}
+			Eventually(exporter.out).Should(Receive())
+			exporter.in <- &pb.StreamRequest{Payload: []byte("response")}
+			Eventually(client.out).Should(Receive())
+
+			Eventually(observer.out).Should(Receive(HaveField("Payload", []byte("response"))))
+			Consistently(observer.out, 100*time.Millisecond).ShouldNot(Receive())
+		})
+
+		It("should refuse the frames of the observers", func() {
+			observer, errs := observe(api.PeerClient)
+			observer.in <- &pb.StreamRequest{Payload: []byte("injected")}
+			Eventually(errs).Should(Receive(WithTransform(status.Code, Equal(codes.PermissionDenied))))
+		})
+
+		It("should detach the observers once the stream ends", func() {
+			_, observed := observe(api.PeerClient)
+			client, exporter, errs := pairStream()
+
+			close(client.in)
+			close(exporter.in)
+			Eventually(errs).Should(Receive(BeNil()))
+			Eventually(observed).Should(Receive(BeNil()))
+		})
+
+		It("should detach the observers falling behind without holding up the stream", func() {
+			_, observed := observe(api.PeerClient)
+			client, exporter, _ := pairStream()
+
+			// the observer never reads, one frame is held by its sender on top of its buffer
+			for range observerBuffer + 2 {
+				client.in <- &pb.StreamRequest{Payload: []byte("frame")}
+				Eventually(exporter.out).Should(Receive())
+			}
+			Eventually(observed).Should(Receive(Equal(errObserverBehind)))
+		})
+	})
+})
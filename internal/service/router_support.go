@@ -4,43 +4,140 @@ import (
 	"context"
 	"errors"
 	"io"
+	"time"
 
 	pb "github.com/jumpstarter-dev/jumpstarter-controller/internal/protocol/jumpstarter/v1"
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc/status"
 )
 
-func pipe(a pb.RouterService_StreamServer, b pb.RouterService_StreamServer) error {
+// ForwardOptions tune the forwarding of frames between two streams
+type ForwardOptions struct {
+	// Called with the frames sent by the first and the second stream, may be nil, must not block
+	TapA, TapB func(*pb.StreamResponse)
+	// Limits the payload bytes per second in each direction, 0 means unlimited
+	BytesPerSecond int64
+}
+
+// forwardDrainTimeout bounds how long the pipes are waited for once the forwarding is over,
+// a pipe blocked sending to a peer not reading only returns once the streams are torn down
+const forwardDrainTimeout = time.Second
+
+// received is a frame received from a stream, or the error ending it
+type received struct {
+	msg *pb.StreamRequest
+	err error
+}
+
+// receive receives the frames of a until it ends, handing them over unbuffered so that at most one
+// frame is received ahead of the one being forwarded, a Recv still blocked once ctx is done returns
+// once the stream is torn down and its frame is dropped
+func receive(ctx context.Context, a pb.RouterService_StreamServer) <-chan received {
+	frames := make(chan received)
+	go func() {
+		for {
+			msg, err := a.Recv()
+			select {
+			case frames <- received{msg: msg, err: err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return frames
+}
+
+// pipe forwards the frames of a to b until a ends or ctx is done, holding at most one frame at
+// a time so that a slow b applies back-pressure to a through the gRPC flow control
+func pipe(
+	ctx context.Context,
+	a pb.RouterService_StreamServer,
+	b pb.RouterService_StreamServer,
+	tap func(*pb.StreamResponse),
+	limiter *rate.Limiter,
+) error {
+	frames := receive(ctx, a)
 	for {
-		msg, err := a.Recv()
-		if errors.Is(err, io.EOF) {
+		var frame received
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case frame = <-frames:
+		}
+		if errors.Is(frame.err, io.EOF) {
 			return nil
 		}
-		if err != nil {
-			return err
+		if frame.err != nil {
+			return frame.err
 		}
-		err = b.Send(&pb.StreamResponse{
+		msg := frame.msg
+		if limiter != nil {
+			// frames larger than the burst are paced in burst sized chunks
+			for remaining := len(msg.GetPayload()); remaining > 0; remaining -= limiter.Burst() {
+				if err := limiter.WaitN(ctx, min(remaining, limiter.Burst())); err != nil {
+					return err
+				}
+			}
+		}
+		response := &pb.StreamResponse{
 			Payload:   msg.GetPayload(),
 			FrameType: msg.GetFrameType(),
-		})
-		if err != nil {
+		}
+		if err := b.Send(response); err != nil {
 			return err
 		}
+		if tap != nil {
+			tap(response)
+		}
+	}
+}
+
+func newLimiter(bytesPerSecond int64) *rate.Limiter {
+	if bytesPerSecond <= 0 {
+		return nil
 	}
+	return rate.NewLimiter(rate.Limit(bytesPerSecond), int(bytesPerSecond))
 }
 
 func Forward(ctx context.Context, a pb.RouterService_StreamServer, b pb.RouterService_StreamServer) error {
+	return ForwardWithOptions(ctx, a, b, ForwardOptions{})
+}
+
+// ForwardWithOptions forwards frames between a and b until either side ends or ctx is done, the
+// pipes of both directions are drained before it returns, so that neither touches the streams
+// once their handlers returned, but for the ones blocked sending past forwardDrainTimeout
+func ForwardWithOptions(
+	ctx context.Context,
+	a pb.RouterService_StreamServer,
+	b pb.RouterService_StreamServer,
+	opts ForwardOptions,
+) error {
+	parent := ctx
+	// the derived context is cancelled by the first pipe failing, or by Wait returning
 	g, ctx := errgroup.WithContext(ctx)
-	g.Go(func() error { return pipe(a, b) })
-	g.Go(func() error { return pipe(b, a) })
-	// In case both tasks return nil
-	// Reference: https://pkg.go.dev/golang.org/x/sync/errgroup#WithContext
-	// The derived Context is canceled the first time a function
-	// passed to Go returns a non-nil error or the first time
-	// Wait returns, whichever occurs first.
+	g.Go(func() error { return pipe(ctx, a, b, opts.TapA, newLimiter(opts.BytesPerSecond)) })
+	g.Go(func() error { return pipe(ctx, b, a, opts.TapB, newLimiter(opts.BytesPerSecond)) })
+	done := make(chan error, 1)
 	go func() {
-		_ = g.Wait()
+		done <- g.Wait()
 	}()
-	// Return on first error
-	<-ctx.Done()
-	return g.Wait()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		select {
+		case err = <-done:
+		case <-time.After(forwardDrainTimeout):
+			err = context.Cause(ctx)
+		}
+	}
+	if errors.Is(parent.Err(), context.DeadlineExceeded) {
+		return parent.Err()
+	}
+	return err
 }
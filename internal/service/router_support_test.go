@@ -0,0 +1,100 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/jumpstarter-dev/jumpstarter-controller/internal/protocol/jumpstarter/v1"
+)
+
+var _ = Describe("ForwardWithOptions", func() {
+	var a, b *routerStream
+	var cancelA context.CancelFunc
+
+	BeforeEach(func() {
+		var ctx context.Context
+		ctx, cancelA = context.WithCancel(context.Background())
+		DeferCleanup(cancelA)
+		a = newRouterStream(ctx)
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		b = newRouterStream(ctx)
+	})
+
+	// forward forwards between a and b with opts until ctx is done, returning its error on errs
+	forward := func(ctx context.Context, opts ForwardOptions) <-chan error {
+		errs := make(chan error, 1)
+		go func() {
+			errs <- ForwardWithOptions(ctx, a, b, opts)
+		}()
+		return errs
+	}
+
+	It("should forward both directions until both sides end", func() {
+		errs := forward(context.Background(), ForwardOptions{})
+
+		a.in <- &pb.StreamRequest{Payload: []byte("request")}
+		Eventually(b.out).Should(Receive(HaveField("Payload", []byte("request"))))
+		close(a.in)
+		b.in <- &pb.StreamRequest{Payload: []byte("response")}
+		Eventually(a.out).Should(Receive(HaveField("Payload", []byte("response"))))
+		Consistently(errs, 100*time.Millisecond).ShouldNot(Receive())
+
+		close(b.in)
+		Eventually(errs).Should(Receive(BeNil()))
+	})
+
+	It("should pace the frames to the rate limit", func() {
+		errs := forward(context.Background(), ForwardOptions{BytesPerSecond: 1000})
+
+		begin := time.Now()
+		go func() {
+			for range 3 {
+				a.in <- &pb.StreamRequest{Payload: bytes.Repeat([]byte("x"), 500)}
+			}
+		}()
+		for range 3 {
+			Eventually(b.out, 2*time.Second).Should(Receive())
+		}
+		// the first 1000 bytes are the burst, the last 500 wait half a second
+		Expect(time.Since(begin)).To(BeNumerically(">=", 400*time.Millisecond))
+
+		close(a.in)
+		close(b.in)
+		Eventually(errs).Should(Receive(BeNil()))
+	})
+
+	It("should stop forwarding once the maximum session duration is reached", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		errs := forward(ctx, ForwardOptions{})
+
+		Eventually(errs).Should(Receive(MatchError(context.DeadlineExceeded)))
+		a.in <- &pb.StreamRequest{Payload: []byte("late")}
+		Consistently(b.out, 100*time.Millisecond).ShouldNot(Receive())
+	})
+
+	It("should return past the drain timeout when a side does not read", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		errs := forward(ctx, ForwardOptions{})
+
+		a.in <- &pb.StreamRequest{Payload: []byte("unread")}
+		Eventually(errs, forwardDrainTimeout+time.Second).Should(Receive(MatchError(context.DeadlineExceeded)))
+	})
+
+	It("should end both directions once a side fails", func() {
+		errs := forward(context.Background(), ForwardOptions{})
+
+		cancelA()
+		Eventually(errs).Should(Receive(WithTransform(status.Code, Equal(codes.Canceled))))
+		b.in <- &pb.StreamRequest{Payload: []byte("late")}
+		Consistently(a.out, 100*time.Millisecond).ShouldNot(Receive())
+	})
+})
@@ -1,23 +1,40 @@
 package service
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
 	"math/big"
 	"net"
+	"os"
+	"slices"
 	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
+// selfSignedValidity is how long the self-signed certificates are valid
+const selfSignedValidity = 365 * 24 * time.Hour
+
+// DefaultSelfSignedRotationInterval reissues the self-signed certificates a month before they expire
+const DefaultSelfSignedRotationInterval = selfSignedValidity - 30*24*time.Hour
+
 func NewSelfSignedCertificate(commonName string, dnsnames []string, ipaddresses []net.IP) (*tls.Certificate, error) {
 	template := x509.Certificate{
 		SerialNumber:          big.NewInt(1),
 		Subject:               pkix.Name{CommonName: commonName},
 		Issuer:                pkix.Name{CommonName: commonName},
 		NotBefore:             time.Now(),
-		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		NotAfter:              time.Now().Add(selfSignedValidity),
 		BasicConstraintsValid: true,
 		DNSNames:              dnsnames,
 		IPAddresses:           ipaddresses,
@@ -38,3 +55,132 @@ func NewSelfSignedCertificate(commonName string, dnsnames []string, ipaddresses
 		PrivateKey:  priv,
 	}, nil
 }
+
+// SelfSignedCertificateConfig is how the self-signed serving certificates of the controller and the
+// router are kept in Secrets of the namespace of the controller, so that the peers pinning them
+// keep trusting them across restarts
+type SelfSignedCertificateConfig struct {
+	// The certificates are reissued once they are older, defaults to DefaultSelfSignedRotationInterval
+	RotationInterval time.Duration
+	// Whether the certificates are reissued when the SANs of the endpoints change, instead of being
+	// kept until rotated
+	ReissueOnSANChange bool
+}
+
+// rotationInterval returns the age the certificates are reissued at
+func (c SelfSignedCertificateConfig) rotationInterval() time.Duration {
+	if c.RotationInterval <= 0 || c.RotationInterval > DefaultSelfSignedRotationInterval {
+		return DefaultSelfSignedRotationInterval
+	}
+	return c.RotationInterval
+}
+
+// Certificate returns the self-signed certificate stored in the Secret name, a new one is issued and
+// stored if it is missing, due for rotation or, if configured, not issued for the SANs anymore,
+// a new one is issued every time if the namespace of the controller is unknown
+func (c SelfSignedCertificateConfig) Certificate(
+	ctx context.Context,
+	kube client.Client,
+	name string,
+	commonName string,
+	dnsnames []string,
+	ipaddresses []net.IP,
+) (*tls.Certificate, error) {
+	namespace := os.Getenv("NAMESPACE")
+	if namespace == "" {
+		return NewSelfSignedCertificate(commonName, dnsnames, ipaddresses)
+	}
+
+	secret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+	}
+	err := kube.Get(ctx, client.ObjectKeyFromObject(&secret), &secret)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("Certificate: failed to get secret %s: %w", name, err)
+	}
+	if err == nil {
+		cert, reason := c.reusable(&secret, dnsnames, ipaddresses, time.Now())
+		if cert != nil {
+			return cert, nil
+		}
+		log.FromContext(ctx).Info("reissuing self-signed certificate", "secret", name, "reason", reason)
+	}
+
+	cert, err := NewSelfSignedCertificate(commonName, dnsnames, ipaddresses)
+	if err != nil {
+		return nil, fmt.Errorf("Certificate: %w", err)
+	}
+	key, ok := cert.PrivateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("Certificate: unexpected private key type %T", cert.PrivateKey)
+	}
+	secret.Type = corev1.SecretTypeTLS
+	secret.Data = map[string][]byte{
+		corev1.TLSCertKey: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}),
+		corev1.TLSPrivateKeyKey: pem.EncodeToMemory(
+			&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)},
+		),
+	}
+	if secret.ResourceVersion == "" {
+		err = kube.Create(ctx, &secret)
+	} else {
+		err = kube.Update(ctx, &secret)
+	}
+	if apierrors.IsAlreadyExists(err) || apierrors.IsConflict(err) {
+		// another replica stored its certificate first
+		if err := kube.Get(ctx, client.ObjectKeyFromObject(&secret), &secret); err != nil {
+			return nil, fmt.Errorf("Certificate: failed to get secret %s: %w", name, err)
+		}
+		pair, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+		if err != nil {
+			return nil, fmt.Errorf("Certificate: invalid certificate in secret %s: %w", name, err)
+		}
+		return &pair, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Certificate: failed to store secret %s: %w", name, err)
+	}
+	return cert, nil
+}
+
+// reusable returns the certificate stored in secret if it can still be served at now, or else why not
+func (c SelfSignedCertificateConfig) reusable(
+	secret *corev1.Secret,
+	dnsnames []string,
+	ipaddresses []net.IP,
+	now time.Time,
+) (*tls.Certificate, string) {
+	pair, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return nil, "invalid"
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, "invalid"
+	}
+	if !now.Before(leaf.NotBefore.Add(c.rotationInterval())) {
+		return nil, "rotation"
+	}
+	if c.ReissueOnSANChange && !sameSANs(leaf, dnsnames, ipaddresses) {
+		return nil, "SANs changed"
+	}
+	return &pair, ""
+}
+
+// sameSANs reports whether leaf was issued for exactly dnsnames and ipaddresses
+func sameSANs(leaf *x509.Certificate, dnsnames []string, ipaddresses []net.IP) bool {
+	issued := slices.Clone(leaf.DNSNames)
+	wanted := slices.Clone(dnsnames)
+	for _, ip := range leaf.IPAddresses {
+		issued = append(issued, ip.String())
+	}
+	for _, ip := range ipaddresses {
+		wanted = append(wanted, ip.String())
+	}
+	slices.Sort(issued)
+	slices.Sort(wanted)
+	return slices.Equal(slices.Compact(issued), slices.Compact(wanted))
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"runtime/debug"
+
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/jumpstarter-dev/jumpstarter-controller/internal/features"
+	"github.com/jumpstarter-dev/jumpstarter-controller/pkg/api"
+)
+
+// ProtocolVersions are the versions of the jumpstarter protocol the controller serves
+var ProtocolVersions = []string{"v1"}
+
+// GetServerInfo returns the version and the enabled features of the controller, to clients and
+// exporters alike
+func (s *ControllerService) GetServerInfo(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	var req api.ServerInfoRequest
+	if err := decodeStruct(in, &req); err != nil {
+		return nil, err
+	}
+	return encodeStruct(s.ServerInfo())
+}
+
+// ServerInfo returns the version and the enabled features of the controller
+func (s *ControllerService) ServerInfo() api.ServerInfo {
+	enabled := []string{
+		// x-jumpstarter-dial-mode
+		"observe",
+		// x-jumpstarter-idempotency-key
+		"idempotent-dial",
+		// x-jumpstarter-exporter
+		"linked-exporters",
+		// x-jumpstarter-lease-role
+		"lease-roles",
+		// api.ClientServiceName ResolveSelector
+		"resolve-selector",
+		// x-jumpstarter-shared-lease
+		"shared-leases",
+		// api.ClientServiceName PauseLease and ResumeLease
+		"pause-leases",
+		// api.ClientServiceName ExtendLease
+		"extend-leases",
+		// api.ClientServiceName and api.ExporterServiceName lease metadata methods
+		"lease-metadata",
+		// api.ClientServiceName CheckLease
+		"check-lease",
+		// x-jumpstarter-clamp-duration
+		"clamp-duration",
+		// x-jumpstarter-dial-timeout
+		"dial-timeout",
+		// api.ClientServiceName ListLeasableExporters
+		"leasable-exporters",
+		// x-jumpstarter-multiple-leases
+		"multiple-leases",
+		// api.AdminServiceName ListExporterLabelHistory, RestoreExporterLabels and UnpinExporterLabels
+		"exporter-label-history",
+		// api.AdminServiceName CordonExporter, UncordonExporter and DrainExporter
+		"exporter-cordon",
+	}
+	if s.RestrictExporterVisibility {
+		enabled = append(enabled, "restricted-visibility")
+	}
+	if s.Transfers != nil {
+		// api.TransferServiceName
+		enabled = append(enabled, "transfer-offload")
+	}
+	return api.ServerInfo{
+		Version:          serverVersion(),
+		ProtocolVersions: ProtocolVersions,
+		Features:         enabled,
+		FeatureGates:     features.DefaultGate.String(),
+	}
+}
+
+// serverVersion returns the module version of the build, or its vcs revision for development builds
+func serverVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			return setting.Value
+		}
+	}
+	return "devel"
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/pem"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// the ConfigMaps of the namespace of the controller are granted by the manager-namespace-role
+// of the chart, not by the cluster role generated from the markers
+
+// publishServingCA stores the PEM encoded serving certificate in a ConfigMap under the ca.crt key,
+// so that proxies re-encrypting TLS in front of the service, e.g. OpenShift routes, can verify it
+func publishServingCA(ctx context.Context, c client.Client, name string, cert *tls.Certificate) error {
+	namespace := os.Getenv("NAMESPACE")
+	if namespace == "" {
+		return nil
+	}
+
+	var ca []byte
+	for _, der := range cert.Certificate {
+		ca = append(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+
+	configmap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+	}
+	_, err := controllerutil.CreateOrUpdate(ctx, c, configmap, func() error {
+		configmap.Data = map[string]string{
+			"ca.crt": string(ca),
+		}
+		return nil
+	})
+	return err
+}
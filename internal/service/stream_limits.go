@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/internal/controller"
+)
+
+// streamLimits returns the stream limits of the access policy granting jclient, with the groups
+// frozen onto lease, the exporter of lease, or nil if the streams of the lease are not limited
+func (s *ControllerService) streamLimits(
+	ctx context.Context,
+	jclient *jumpstarterdevv1alpha1.Client,
+	lease *jumpstarterdevv1alpha1.Lease,
+) (*StreamLimitClaims, error) {
+	if lease.Status.ExporterRef == nil {
+		return nil, nil
+	}
+
+	policies, err := controller.ListAccessPolicies(ctx, s.Client, lease.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("streamLimits: %w", err)
+	}
+	if len(policies) == 0 {
+		return nil, nil
+	}
+
+	var exporter jumpstarterdevv1alpha1.Exporter
+	if err := s.Client.Get(ctx, types.NamespacedName{
+		Namespace: lease.Namespace,
+		Name:      lease.Status.ExporterRef.Name,
+	}, &exporter); err != nil {
+		return nil, fmt.Errorf("streamLimits: failed to get exporter: %w", err)
+	}
+
+	decision, err := controller.EvaluateAccessPolicies(
+		policies, s.AccessPolicyTieBreak, controller.LeaseClient(jclient, lease), &exporter,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("streamLimits: %w", err)
+	}
+	if decision.Policy == nil || decision.Policy.StreamLimits == nil {
+		return nil, nil
+	}
+
+	limits := decision.Policy.StreamLimits
+	claims := &StreamLimitClaims{
+		MaxConcurrentDials: limits.MaxConcurrentDials,
+	}
+	if limits.MaxBandwidth != nil {
+		claims.MaxBandwidth = limits.MaxBandwidth.Value()
+	}
+	if limits.MaxSessionDuration != nil {
+		claims.MaxSessionDuration = int64(limits.MaxSessionDuration.Seconds())
+	}
+	return claims, nil
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/metadata"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/internal/controller"
+)
+
+// These tests cover the parts of the service not needing a real API server, the handlers run
+// against a fake one, their interactions with the controllers are covered by the e2e tests
+
+func TestService(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Service Suite")
+}
+
+var _ = BeforeSuite(func() {
+	Expect(os.Setenv("CONTROLLER_KEY", "test-key")).To(Succeed())
+})
+
+// tokenContext returns ctx carrying the token the controller issues to object
+func tokenContext(ctx context.Context, s *ControllerService, object client.Object) context.Context {
+	token, err := controller.SignObjectToken(
+		"https://jumpstarter.dev/controller",
+		[]string{"https://jumpstarter.dev/controller"},
+		object,
+		s.Scheme,
+	)
+	Expect(err).NotTo(HaveOccurred())
+	return metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+token))
+}
+
+// newTestService returns a ControllerService on a fake API server holding objects
+func newTestService(objects ...client.Object) *ControllerService {
+	scheme := runtime.NewScheme()
+	Expect(jumpstarterdevv1alpha1.AddToScheme(scheme)).To(Succeed())
+	return &ControllerService{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).WithStatusSubresource(
+			&jumpstarterdevv1alpha1.Lease{},
+			&jumpstarterdevv1alpha1.Exporter{},
+			&jumpstarterdevv1alpha1.Client{},
+		).Build(),
+		Scheme:    scheme,
+		RouterKey: &RouterKey{File: "test", current: []byte("test-key")},
+	}
+}
+
+// newTestLease returns an active lease of the client named client on the exporter named exporter
+func newTestLease(name string, client string, exporter string) *jumpstarterdevv1alpha1.Lease {
+	return &jumpstarterdevv1alpha1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      name,
+			UID:       types.UID(name + "-uid"),
+		},
+		Spec: jumpstarterdevv1alpha1.LeaseSpec{
+			ClientRef: corev1.LocalObjectReference{Name: client},
+			Duration:  metav1.Duration{Duration: time.Hour},
+		},
+		Status: jumpstarterdevv1alpha1.LeaseStatus{
+			ExporterRef: &corev1.LocalObjectReference{Name: exporter},
+			BeginTime:   &metav1.Time{Time: time.Now()},
+		},
+	}
+}
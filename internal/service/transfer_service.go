@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/internal/controller"
+	"github.com/jumpstarter-dev/jumpstarter-controller/pkg/api"
+)
+
+// maxTransferObjectLength bounds the names of the objects of the leases
+const maxTransferObjectLength = 512
+
+type transferServer interface {
+	NegotiateTransfer(context.Context, *structpb.Struct) (*structpb.Struct, error)
+}
+
+var transferServiceDesc = grpc.ServiceDesc{
+	ServiceName: api.TransferServiceName,
+	HandlerType: (*transferServer)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "NegotiateTransfer",
+		Handler:    negotiateTransferHandler,
+	}},
+	Metadata: "transfer",
+}
+
+func negotiateTransferHandler(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	in := new(structpb.Struct)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(transferServer).NegotiateTransfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/" + api.TransferServiceName + "/NegotiateTransfer",
+	}
+	return interceptor(ctx, in, info, func(ctx context.Context, req any) (any, error) {
+		return srv.(transferServer).NegotiateTransfer(ctx, req.(*structpb.Struct))
+	})
+}
+
+// NegotiateTransfer returns the presigned URL the exporter or the client of an active lease
+// transfers an object of the lease with, scoped to the objects of the lease and expiring at
+// most when the lease ends
+func (s *ControllerService) NegotiateTransfer(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	logger := log.FromContext(ctx)
+
+	var req api.TransferRequest
+	content, err := json.Marshal(in.AsMap())
+	if err == nil {
+		err = json.Unmarshal(content, &req)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid transfer request: %s", err)
+	}
+	if req.Peer == "" {
+		req.Peer = api.TransferPeerExporter
+	}
+
+	method := http.MethodPut
+	switch req.Direction {
+	case api.TransferUpload:
+	case api.TransferDownload:
+		method = http.MethodGet
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "invalid transfer direction %q", req.Direction)
+	}
+	if !validTransferObject(req.Object) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid object name %q", req.Object)
+	}
+	if req.Lease == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "empty lease name")
+	}
+
+	lease, err := s.transferLease(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	storage := s.Transfers.For(lease.Namespace)
+	if storage == nil {
+		return nil, status.Errorf(codes.FailedPrecondition,
+			"transfers are not offloaded in namespace %s", lease.Namespace)
+	}
+
+	var secret corev1.Secret
+	if err := s.Client.Get(ctx, types.NamespacedName{
+		Namespace: lease.Namespace,
+		Name:      storage.CredentialsSecret,
+	}, &secret); err != nil {
+		logger.Error(err, "unable to get transfer credentials")
+		return nil, status.Errorf(codes.Internal, "unable to get transfer credentials")
+	}
+	credentials := transferCredentials{
+		AccessKeyID:     string(secret.Data["accessKeyID"]),
+		SecretAccessKey: string(secret.Data["secretAccessKey"]),
+	}
+	if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+		return nil, status.Errorf(codes.FailedPrecondition,
+			"secret %s does not hold accessKeyID and secretAccessKey", storage.CredentialsSecret)
+	}
+
+	now := time.Now()
+	expires := min(storage.expiry(), controller.LeaseExpiration(lease, now).Sub(now)).Truncate(time.Second)
+	if expires < time.Second {
+		return nil, status.Errorf(codes.FailedPrecondition, "lease %s is ending", lease.Name)
+	}
+
+	key := storage.key(lease.Namespace, lease.Name, req.Object)
+	url, err := storage.presign(method, key, credentials, expires, now)
+	if err != nil {
+		logger.Error(err, "unable to presign transfer")
+		return nil, status.Errorf(codes.Internal, "unable to presign transfer")
+	}
+
+	logger.Info("Negotiated transfer", "lease", lease.Name, "peer", req.Peer,
+		"direction", req.Direction, "key", key, "expires", expires)
+
+	content, err = json.Marshal(api.TransferResponse{
+		URL:        url,
+		Method:     method,
+		Key:        key,
+		ExpireTime: now.Add(expires).UTC(),
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to encode transfer response")
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(content, &fields); err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to encode transfer response")
+	}
+	return structpb.NewStruct(fields)
+}
+
+// transferLease authenticates the peer of req and returns its lease, which must be active
+// and held by the peer
+func (s *ControllerService) transferLease(
+	ctx context.Context,
+	req api.TransferRequest,
+) (*jumpstarterdevv1alpha1.Lease, error) {
+	var namespace string
+	var holds func(*jumpstarterdevv1alpha1.Lease) bool
+	switch req.Peer {
+	case api.TransferPeerExporter:
+		exporter, err := s.authenticateExporter(ctx)
+		if err != nil {
+			return nil, err
+		}
+		namespace = exporter.Namespace
+		holds = func(lease *jumpstarterdevv1alpha1.Lease) bool {
+			return controller.LeaseHoldsExporter(lease, exporter.Name)
+		}
+	case api.TransferPeerClient:
+		client, err := s.authenticateClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		namespace = client.Namespace
+		holds = func(lease *jumpstarterdevv1alpha1.Lease) bool {
+			return lease.Spec.ClientRef.Name == client.Name
+		}
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "invalid transfer peer %q", req.Peer)
+	}
+
+	var lease jumpstarterdevv1alpha1.Lease
+	if err := s.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: req.Lease}, &lease); err != nil {
+		return nil, status.Errorf(codes.NotFound, "lease %s not found", req.Lease)
+	}
+	if !holds(&lease) {
+		return nil, status.Errorf(codes.PermissionDenied, "lease %s not held by %s", req.Lease, req.Peer)
+	}
+	if lease.Status.Ended || lease.Status.BeginTime == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "lease %s is not active", req.Lease)
+	}
+	return &lease, nil
+}
+
+// validTransferObject reports whether object names an object under the objects of a lease
+func validTransferObject(object string) bool {
+	return object != "" && len(object) <= maxTransferObjectLength && path.Clean(object) == object &&
+		!strings.HasPrefix(object, "/") && object != ".." && !strings.HasPrefix(object, "../")
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// defaultTransferExpiry is how long the presigned URLs are valid when not configured
+	defaultTransferExpiry = time.Hour
+	// maxTransferExpiry is the longest validity of presigned URLs accepted by object stores
+	maxTransferExpiry = 7 * 24 * time.Hour
+)
+
+// TransferStorage is an S3 compatible object store the transfers of the leases are offloaded to
+type TransferStorage struct {
+	// The URL of the object store, e.g. https://s3.us-east-1.amazonaws.com, the buckets are
+	// addressed by path
+	Endpoint string `json:"endpoint"`
+	Region   string `json:"region"`
+	Bucket   string `json:"bucket"`
+	// The prefix of the object keys, the objects of a lease are stored under PREFIX/NAMESPACE/LEASE/
+	Prefix string `json:"prefix,omitempty"`
+	// The name of the secret of the namespace of the leases holding the accessKeyID and the
+	// secretAccessKey the URLs are presigned with
+	CredentialsSecret string `json:"credentialsSecret"`
+	// How long the presigned URLs are valid, at most until the end of their lease, defaults to 1h
+	Expiry metav1.Duration `json:"expiry,omitempty"`
+}
+
+// TransferConfig is the configuration file of the object stores of the namespaces
+type TransferConfig struct {
+	// The object store of the namespaces not in Namespaces, transfers are not offloaded in them if unset
+	Default *TransferStorage `json:"default,omitempty"`
+	// The object stores of the namespaces
+	Namespaces map[string]TransferStorage `json:"namespaces,omitempty"`
+}
+
+// LoadTransferConfig reads a TransferConfig from path
+func LoadTransferConfig(path string) (*TransferConfig, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("LoadTransferConfig: %w", err)
+	}
+	var config TransferConfig
+	if err := yaml.UnmarshalStrict(content, &config); err != nil {
+		return nil, fmt.Errorf("LoadTransferConfig: invalid configuration: %w", err)
+	}
+	if config.Default != nil {
+		if err := config.Default.validate(); err != nil {
+			return nil, fmt.Errorf("LoadTransferConfig: default: %w", err)
+		}
+	}
+	for namespace, storage := range config.Namespaces {
+		if err := storage.validate(); err != nil {
+			return nil, fmt.Errorf("LoadTransferConfig: namespace %s: %w", namespace, err)
+		}
+	}
+	return &config, nil
+}
+
+// For returns the object store of namespace, nil if transfers are not offloaded in it
+func (c *TransferConfig) For(namespace string) *TransferStorage {
+	if c == nil {
+		return nil
+	}
+	if storage, ok := c.Namespaces[namespace]; ok {
+		return &storage
+	}
+	return c.Default
+}
+
+func (t *TransferStorage) validate() error {
+	endpoint, err := url.Parse(t.Endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint: %w", err)
+	}
+	if (endpoint.Scheme != "https" && endpoint.Scheme != "http") || endpoint.Host == "" ||
+		strings.Trim(endpoint.Path, "/") != "" {
+		return fmt.Errorf("invalid endpoint %s, expected http(s)://HOST[:PORT]", t.Endpoint)
+	}
+	if t.Region == "" || t.Bucket == "" || t.CredentialsSecret == "" {
+		return fmt.Errorf("region, bucket and credentialsSecret are required")
+	}
+	if t.Expiry.Duration < 0 || t.Expiry.Duration > maxTransferExpiry {
+		return fmt.Errorf("expiry must be between 0 and %s", maxTransferExpiry)
+	}
+	return nil
+}
+
+// expiry returns how long the presigned URLs are valid
+func (t *TransferStorage) expiry() time.Duration {
+	if t.Expiry.Duration == 0 {
+		return defaultTransferExpiry
+	}
+	return t.Expiry.Duration
+}
+
+// key returns the key of the object of the lease namespace/lease
+func (t *TransferStorage) key(namespace string, lease string, object string) string {
+	return strings.TrimPrefix(strings.Join([]string{strings.Trim(t.Prefix, "/"), namespace, lease, object}, "/"), "/")
+}
+
+// transferCredentials are the credentials of an object store
+type transferCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// presign returns the URL the holder can method the object key with until now+expires, signed
+// with the AWS signature version 4 as a query string
+func (t *TransferStorage) presign(
+	method string,
+	key string,
+	credentials transferCredentials,
+	expires time.Duration,
+	now time.Time,
+) (string, error) {
+	endpoint, err := url.Parse(t.Endpoint)
+	if err != nil {
+		return "", fmt.Errorf("presign: invalid endpoint: %w", err)
+	}
+
+	now = now.UTC()
+	date := now.Format("20060102")
+	timestamp := now.Format("20060102T150405Z")
+	scope := date + "/" + t.Region + "/s3/aws4_request"
+
+	path := awsURIEncode("/"+t.Bucket+"/"+key, false)
+	query := awsCanonicalQuery(map[string]string{
+		"X-Amz-Algorithm":     "AWS4-HMAC-SHA256",
+		"X-Amz-Credential":    credentials.AccessKeyID + "/" + scope,
+		"X-Amz-Date":          timestamp,
+		"X-Amz-Expires":       strconv.Itoa(int(expires.Seconds())),
+		"X-Amz-SignedHeaders": "host",
+	})
+
+	request := strings.Join([]string{
+		method,
+		path,
+		query,
+		"host:" + endpoint.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(request))
+	toSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		timestamp,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	signingKey := []byte("AWS4" + credentials.SecretAccessKey)
+	for _, part := range []string{date, t.Region, "s3", "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(signingKey, toSign))
+
+	return endpoint.Scheme + "://" + endpoint.Host + path + "?" + query + "&X-Amz-Signature=" + signature, nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsCanonicalQuery encodes params sorted by name, as signed by the AWS signature version 4
+func awsCanonicalQuery(params map[string]string) string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, awsURIEncode(name, true)+"="+awsURIEncode(params[name], true))
+	}
+	return strings.Join(pairs, "&")
+}
+
+// awsURIEncode percent-encodes every byte of s but the unreserved characters, and the slashes
+// unless encodeSlash is set
+func awsURIEncode(s string, encodeSlash bool) string {
+	var encoded strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			encoded.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			encoded.WriteByte(c)
+		default:
+			fmt.Fprintf(&encoded, "%%%02X", c)
+		}
+	}
+	return encoded.String()
+}
@@ -0,0 +1,54 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/internal/controller"
+)
+
+// +kubebuilder:rbac:groups=jumpstarter.dev,resources=leasetemplates,verbs=get;list;watch
+
+// SetupLeaseWebhookWithManager registers the defaulting webhook of the leases in the manager,
+// defaulting their durations to the defaults of limits if set
+func SetupLeaseWebhookWithManager(mgr ctrl.Manager, limits *controller.LeaseDurationLimits) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&jumpstarterdevv1alpha1.Lease{}).
+		WithDefaulter(&LeaseCustomDefaulter{Client: mgr.GetClient(), DurationLimits: limits}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-jumpstarter-dev-v1alpha1-lease,mutating=true,failurePolicy=fail,sideEffects=None,groups=jumpstarter.dev,resources=leases,verbs=create,versions=v1alpha1,name=mlease-v1alpha1.jumpstarter.dev,admissionReviewVersions=v1
+
+// LeaseCustomDefaulter sets the unset fields of the leases created with a LeaseTemplate to its defaults,
+// and the unset durations to the default lease duration of their namespace
+type LeaseCustomDefaulter struct {
+	Client         client.Reader
+	DurationLimits *controller.LeaseDurationLimits
+}
+
+var _ admission.CustomDefaulter = &LeaseCustomDefaulter{}
+
+// Default implements admission.CustomDefaulter
+func (d *LeaseCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	lease, ok := obj.(*jumpstarterdevv1alpha1.Lease)
+	if !ok {
+		return fmt.Errorf("expected a Lease object but got %T", obj)
+	}
+	if lease.Spec.TemplateName != "" {
+		log.FromContext(ctx).Info("defaulting lease from template", "lease", lease.Name, "template", lease.Spec.TemplateName)
+	}
+	if err := controller.DefaultLeaseFromTemplate(ctx, d.Client, lease); err != nil {
+		return err
+	}
+	if lease.Spec.Duration.Duration == 0 {
+		lease.Spec.Duration = d.DurationLimits.For(lease.Namespace).Default
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package v1alpha1
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/jumpstarter-dev/jumpstarter-controller/internal/service"
+)
+
+const (
+	// WebhookServiceName is the Service in front of the webhook server, in the namespace of the controller
+	WebhookServiceName = "jumpstarter-webhook"
+	// WebhookConfigurationName is the MutatingWebhookConfiguration of the webhooks of the controller
+	WebhookConfigurationName = "jumpstarter-lease-defaulting"
+	// webhookCertificateSecretName stores the serving certificate shared by the replicas of the controller
+	webhookCertificateSecretName = "jumpstarter-webhook-serving-cert"
+)
+
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;create
+// +kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=mutatingwebhookconfigurations,verbs=get;update
+
+// ProvisionServingCertificate writes the serving certificate of the webhook server to a new directory,
+// the one stored in the serving certificate Secret of namespace, self-signed and stored there if it is
+// missing or about to expire, and returns the directory and the PEM encoded certificate to trust
+func ProvisionServingCertificate(ctx context.Context, c client.Client, namespace string) (string, []byte, error) {
+	secret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      webhookCertificateSecretName,
+		},
+	}
+	err := c.Get(ctx, client.ObjectKeyFromObject(&secret), &secret)
+	switch {
+	case apierrors.IsNotFound(err):
+		err = storeServingCertificate(ctx, c, &secret, true)
+	case err == nil && !servingCertificateFresh(&secret, time.Now()):
+		err = storeServingCertificate(ctx, c, &secret, false)
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("ProvisionServingCertificate: %w", err)
+	}
+
+	dir, err := os.MkdirTemp("", "webhook-serving-certs")
+	if err != nil {
+		return "", nil, fmt.Errorf("ProvisionServingCertificate: %w", err)
+	}
+	for _, key := range []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey} {
+		if err := os.WriteFile(filepath.Join(dir, key), secret.Data[key], 0o600); err != nil {
+			return "", nil, fmt.Errorf("ProvisionServingCertificate: %w", err)
+		}
+	}
+	return dir, secret.Data[corev1.TLSCertKey], nil
+}
+
+// servingCertificateFresh reports whether the certificate of secret is valid for another month
+func servingCertificateFresh(secret *corev1.Secret, now time.Time) bool {
+	pair, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return false
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return false
+	}
+	return now.Add(30 * 24 * time.Hour).Before(leaf.NotAfter)
+}
+
+// storeServingCertificate sets a new self-signed certificate in secret and creates or updates it,
+// if another replica wrote it first, theirs is read back into secret instead
+func storeServingCertificate(
+	ctx context.Context,
+	c client.Client,
+	secret *corev1.Secret,
+	create bool,
+) error {
+	name := WebhookServiceName + "." + secret.Namespace + ".svc"
+	cert, err := service.NewSelfSignedCertificate(name, []string{name, name + ".cluster.local"}, nil)
+	if err != nil {
+		return err
+	}
+	key, ok := cert.PrivateKey.(*rsa.PrivateKey)
+	if !ok {
+		return fmt.Errorf("unexpected private key type %T", cert.PrivateKey)
+	}
+
+	secret.Type = corev1.SecretTypeTLS
+	secret.Data = map[string][]byte{
+		corev1.TLSCertKey:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}),
+		corev1.TLSPrivateKeyKey: pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+	}
+	if create {
+		err = c.Create(ctx, secret)
+	} else {
+		err = c.Update(ctx, secret)
+	}
+	if apierrors.IsAlreadyExists(err) || apierrors.IsConflict(err) {
+		return c.Get(ctx, client.ObjectKeyFromObject(secret), secret)
+	}
+	return err
+}
+
+// CABundleInjector sets the caBundle of the webhooks of the MutatingWebhookConfiguration
+// to the serving certificate, retrying until the configuration exists
+type CABundleInjector struct {
+	Client client.Client
+	CA     []byte
+}
+
+var _ manager.Runnable = &CABundleInjector{}
+
+// Start implements manager.Runnable
+func (i *CABundleInjector) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithValues("configuration", WebhookConfigurationName)
+	return wait.PollUntilContextCancel(ctx, 10*time.Second, true, func(ctx context.Context) (bool, error) {
+		if err := i.inject(ctx); err != nil {
+			logger.Error(err, "unable to inject webhook CA bundle")
+			return false, nil
+		}
+		logger.Info("injected webhook CA bundle")
+		return true, nil
+	})
+}
+
+func (i *CABundleInjector) inject(ctx context.Context) error {
+	var configuration admissionregistrationv1.MutatingWebhookConfiguration
+	if err := i.Client.Get(ctx, types.NamespacedName{Name: WebhookConfigurationName}, &configuration); err != nil {
+		return err
+	}
+	for j := range configuration.Webhooks {
+		configuration.Webhooks[j].ClientConfig.CABundle = i.CA
+	}
+	return i.Client.Update(ctx, &configuration)
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ExporterRequest names the exporter a method of AdminServiceName applies to
+type ExporterRequest struct {
+	Namespace string `json:"namespace"`
+	Exporter  string `json:"exporter"`
+}
+
+// LabelSetRevision is a set of the jumpstarter.dev/ labels of an exporter as it was applied
+type LabelSetRevision struct {
+	Revision int64             `json:"revision"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Time     metav1.Time       `json:"time"`
+	// What applied the labels, e.g. Register or Restore
+	Source string `json:"source"`
+}
+
+// ExporterLabelsResponse is the label history of an exporter, returned by ListExporterLabelHistory,
+// RestoreExporterLabels and UnpinExporterLabels once they applied
+type ExporterLabelsResponse struct {
+	// The current labels of the exporter
+	Labels map[string]string `json:"labels,omitempty"`
+	// The revisions of its jumpstarter.dev/ labels, oldest first
+	Revisions []LabelSetRevision `json:"revisions"`
+	// Whether its labels are pinned, Register keeps them instead of the reported ones until unpinned
+	Pinned bool `json:"pinned,omitempty"`
+	// The revision the labels are pinned to, if pinned
+	PinnedRevision int64 `json:"pinnedRevision,omitempty"`
+}
+
+// RestoreExporterLabelsRequest restores the jumpstarter.dev/ labels of an exporter to a revision of its
+// label history, and pins them to it
+type RestoreExporterLabelsRequest struct {
+	Namespace string `json:"namespace"`
+	Exporter  string `json:"exporter"`
+	Revision  int64  `json:"revision"`
+}
+
+// DrainExporterRequest drains an exporter, new leases do not acquire it and it is disconnected once
+// its active leases ended
+type DrainExporterRequest struct {
+	Namespace string `json:"namespace"`
+	Exporter  string `json:"exporter"`
+	// Release the active leases of the exporter instead of waiting for them to end
+	Release bool `json:"release,omitempty"`
+}
+
+// ExporterSchedulingResponse is whether an exporter is cordoned or drained, returned by CordonExporter,
+// UncordonExporter and DrainExporter once they applied
+type ExporterSchedulingResponse struct {
+	// Whether new leases do not acquire the exporter
+	Unschedulable bool `json:"unschedulable,omitempty"`
+	// Whether the exporter is draining, it is disconnected once its active leases ended
+	Drain bool `json:"drain,omitempty"`
+	// Whether the exporter is drained, disconnected and kept offline until uncordoned
+	Drained bool `json:"drained,omitempty"`
+	// The active leases of the exporter released by DrainExporter
+	ReleasedLeases []string `json:"releasedLeases,omitempty"`
+}
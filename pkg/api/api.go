@@ -0,0 +1,68 @@
+// Package api holds the wire types and the headers of the extensions of jumpstarter-protocol
+// served by the controller, shared by the controller and its clients
+package api
+
+// ClientServiceName is the gRPC service answering the questions of the clients about their access
+// to the exporters. Its messages are google.protobuf.Struct holding the JSON request and response
+// types of each method, e.g. a LeasableExportersRequest and a LeasableExportersResponse
+const ClientServiceName = "jumpstarter.controller.v1alpha1.ClientService"
+
+// ExporterServiceName is the gRPC service of the exporters, for what the protocol has no room for
+// yet, e.g. the metadata of their leases. Its messages are google.protobuf.Struct holding the JSON
+// request and response types of each method, like the ones of ClientServiceName
+const ExporterServiceName = "jumpstarter.controller.v1alpha1.ExporterService"
+
+// TransferServiceName is the gRPC service negotiating the transfers of large artifacts, e.g. images
+// and logs, through object stores instead of the routers. Its messages are google.protobuf.Struct
+// holding a TransferRequest and a TransferResponse
+const TransferServiceName = "jumpstarter.controller.v1alpha1.TransferService"
+
+// AdminServiceName is the gRPC service of the cluster users administering the exporters, e.g. their
+// labels and their cordons. It authenticates them by their Kubernetes bearer token and authorizes
+// them on the Exporter they act on. Its messages are google.protobuf.Struct holding the JSON request
+// and response types of each method, like the ones of ClientServiceName
+const AdminServiceName = "jumpstarter.controller.v1alpha1.AdminService"
+
+// PriorityClassHeader names the LeasePriorityClass of the lease requested by RequestLease
+const PriorityClassHeader = "x-jumpstarter-priority-class"
+
+// FailIfUnsatisfiableHeader makes RequestLease fail with FAILED_PRECONDITION right away, instead
+// of creating a lease, when no exporter could ever satisfy the selector of the requested lease
+const FailIfUnsatisfiableHeader = "x-jumpstarter-fail-if-unsatisfiable"
+
+// SharedLeaseHeader makes the lease requested by RequestLease shared read-only, the clients
+// allowed to lease its exporter may observe its streams by dialing it in the observe mode
+const SharedLeaseHeader = "x-jumpstarter-shared-lease"
+
+// ClampDurationHeader makes RequestLease shorten the requested duration to the maximum lease duration,
+// or to the maximum duration of the ExporterAccessPolicies of the matching exporters, instead of
+// rejecting the lease
+const ClampDurationHeader = "x-jumpstarter-clamp-duration"
+
+const (
+	// QueuePositionHeader is sent by GetLease for pending leases, their position in the queue
+	QueuePositionHeader = "x-jumpstarter-queue-position"
+	// EstimatedBeginTimeHeader is sent by GetLease for pending leases, in RFC 3339 format,
+	// when they are estimated to acquire an exporter
+	EstimatedBeginTimeHeader = "x-jumpstarter-estimated-begin-time"
+)
+
+// Stream handshake, sent by both sides as request metadata of the router Stream call
+const (
+	// PeerHeader identifies the side of the stream, either PeerClient or PeerExporter
+	PeerHeader = "x-jumpstarter-peer"
+	// LeaseHeader is the namespaced name of the lease the stream belongs to
+	LeaseHeader = "x-jumpstarter-lease"
+	// OptionHeaderPrefix prefixes the stream options offered by a side, e.g. compression,
+	// chunk-size or resumption, the router replies with the options both sides agree on
+	OptionHeaderPrefix = "x-jumpstarter-option-"
+	// ObserveHeader selects the side whose frames an observer receives, defaults to PeerExporter
+	ObserveHeader = "x-jumpstarter-observe"
+)
+
+const (
+	PeerClient   = "client"
+	PeerExporter = "exporter"
+	// PeerObserver is an additional receive-only party attached to a stream
+	PeerObserver = "observer"
+)
@@ -0,0 +1,142 @@
+package api
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LeasableExportersRequest lists the exporters the caller may lease
+type LeasableExportersRequest struct {
+	// The label selector of the exporters, e.g. dut=a,board in (x,y), every exporter if empty
+	Selector string `json:"selector,omitempty"`
+}
+
+// LeasableExporter is an exporter the caller may lease and the policy its leases are granted by
+type LeasableExporter struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+	// The ExporterAccessPolicy granting access, empty if no ExporterAccessPolicy selects the exporter
+	// or it is reserved to the caller
+	Policy string `json:"policy,omitempty"`
+	// The priority of the policy granting access
+	Priority int `json:"priority"`
+	// Whether access is only granted by a current reservation of the exporter to the caller
+	Reserved bool `json:"reserved,omitempty"`
+	// The maximum duration of the leases of the exporter, from the policy or the lease duration
+	// limits of the namespace, unlimited if unset
+	MaximumDuration *metav1.Duration `json:"maximumDuration,omitempty"`
+}
+
+// LeasableExportersResponse are the exporters the caller may lease
+type LeasableExportersResponse struct {
+	Exporters []LeasableExporter `json:"exporters"`
+}
+
+// ResolveSelectorRequest resolves the exporters a lease of the caller could acquire
+type ResolveSelectorRequest struct {
+	// The label selector of the lease, e.g. dut=a,board in (x,y), every exporter if empty
+	Selector string `json:"selector,omitempty"`
+	// The LeaseTemplate the lease is defaulted from, if any
+	Template string `json:"template,omitempty"`
+}
+
+// ResolvedExporter is an exporter a lease of the caller could acquire, the priority being the
+// one of the LeasePriorityClass of the lease if it has one
+type ResolvedExporter struct {
+	LeasableExporter
+	// Whether the exporter could be acquired right now
+	Available bool `json:"available"`
+	// Why the exporter cannot be acquired right now, the name of the allocator filter it does
+	// not pass, or Leased
+	Reason string `json:"reason,omitempty"`
+}
+
+// ResolveSelectorResponse are the exporters a lease of the caller could acquire
+type ResolveSelectorResponse struct {
+	Exporters []ResolvedExporter `json:"exporters"`
+}
+
+// CheckLeaseRequest describes the lease CheckLease evaluates, as RequestLease would create it
+type CheckLeaseRequest struct {
+	// The label selector of the lease, e.g. dut=a,board in (x,y), every exporter if empty
+	Selector string `json:"selector,omitempty"`
+	// The duration of the lease, the default lease duration of the namespace if unset
+	Duration metav1.Duration `json:"duration,omitempty"`
+	// The LeasePriorityClass of the lease, if any
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+	// The roles of the lease, as NAME:COUNT:SELECTOR, e.g. "generator:1:type=traffic-generator"
+	Roles []string `json:"roles,omitempty"`
+	// Whether the lease is shared
+	Shared bool `json:"shared,omitempty"`
+	// The LeaseTemplate the lease is defaulted from, if any
+	Template string `json:"template,omitempty"`
+	// Whether the duration is shortened to the maximum duration allowed instead of failing the check
+	ClampDuration bool `json:"clampDuration,omitempty"`
+}
+
+// CheckLeaseResponse is whether the lease would acquire an exporter right away, be queued, or be
+// unsatisfiable, and the duration it would have
+type CheckLeaseResponse struct {
+	LeaseCheck
+	Duration metav1.Duration `json:"duration"`
+}
+
+// LeaseCheckResult is what would happen to a lease if it was created
+type LeaseCheckResult string
+
+const (
+	// LeaseCheckImmediate means the lease would acquire an exporter right away
+	LeaseCheckImmediate LeaseCheckResult = "Immediate"
+	// LeaseCheckQueued means the lease would wait for an exporter, or for its quotas to free up
+	LeaseCheckQueued LeaseCheckResult = "Queued"
+	// LeaseCheckUnsatisfiable means no exporter could ever satisfy the lease
+	LeaseCheckUnsatisfiable LeaseCheckResult = "Unsatisfiable"
+)
+
+// LeaseCheck is the outcome of the dry run of the allocation of a lease
+type LeaseCheck struct {
+	Result LeaseCheckResult `json:"result"`
+	// Why the lease would be queued or unsatisfiable, the reason of the condition it would get
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+	// The exporter the lease would acquire right away
+	Exporter string `json:"exporter,omitempty"`
+	// The position of the queued lease in the queue for exporters
+	QueuePosition *int32 `json:"queuePosition,omitempty"`
+	// When the queued lease is estimated to acquire an exporter
+	EstimatedBeginTime *metav1.Time `json:"estimatedBeginTime,omitempty"`
+}
+
+// PauseLeaseRequest names the lease PauseLease or ResumeLease applies to
+type PauseLeaseRequest struct {
+	// The name of the lease, in the namespace of the caller
+	Lease string `json:"lease"`
+}
+
+// PauseLeaseResponse is the empty response of PauseLease and ResumeLease
+type PauseLeaseResponse struct{}
+
+// ExtendLeaseRequest is the new duration of a running lease of the caller
+type ExtendLeaseRequest struct {
+	// The name of the lease, in the namespace of the caller
+	Lease string `json:"lease"`
+	// The new duration of the lease, counted from its begin time, longer than its current one
+	Duration metav1.Duration `json:"duration"`
+}
+
+// ExtendLeaseResponse is the duration of the extended lease
+type ExtendLeaseResponse struct {
+	Duration metav1.Duration `json:"duration"`
+}
+
+// ServerInfo describes the controller to clients, so they can adapt to what it supports, it is
+// the response of GetServerInfo
+type ServerInfo struct {
+	Version          string   `json:"version"`
+	ProtocolVersions []string `json:"protocolVersions"`
+	Features         []string `json:"features"`
+	// The state of the feature gates, as comma separated feature=bool pairs
+	FeatureGates string `json:"featureGates"`
+}
+
+// ServerInfoRequest is the empty request of GetServerInfo
+type ServerInfoRequest struct{}
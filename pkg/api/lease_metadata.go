@@ -0,0 +1,18 @@
+package api
+
+// LeaseMetadataRequest names the lease and the metadata key ListLeaseMetadata, GetLeaseMetadata,
+// SetLeaseMetadata and DeleteLeaseMetadata apply to, of both ClientServiceName and ExporterServiceName
+type LeaseMetadataRequest struct {
+	// The name of the lease, in the namespace of the caller
+	Lease string `json:"lease"`
+	// The key, a qualified name like a label key, unused by ListLeaseMetadata
+	Key string `json:"key,omitempty"`
+	// The value of the key, only used by SetLeaseMetadata
+	Value string `json:"value,omitempty"`
+}
+
+// LeaseMetadataResponse is the metadata of the lease once the request applied, only the requested
+// key for GetLeaseMetadata
+type LeaseMetadataResponse struct {
+	Metadata map[string]string `json:"metadata"`
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"time"
+)
+
+// TransferDirection is whether the peer negotiating a transfer uploads or downloads the object
+type TransferDirection string
+
+const (
+	TransferUpload   TransferDirection = "upload"
+	TransferDownload TransferDirection = "download"
+)
+
+// TransferPeer is the side of a lease negotiating a transfer
+type TransferPeer string
+
+const (
+	TransferPeerExporter TransferPeer = "exporter"
+	TransferPeerClient   TransferPeer = "client"
+)
+
+// TransferRequest negotiates the transfer of an object of an active lease
+type TransferRequest struct {
+	// The name of the lease, in the namespace of the peer
+	Lease     string            `json:"lease"`
+	Direction TransferDirection `json:"direction"`
+	// The name of the object, relative to the objects of the lease, e.g. images/rootfs.img
+	Object string `json:"object"`
+	// The side of the lease the caller authenticates as, defaults to exporter
+	Peer TransferPeer `json:"peer,omitempty"`
+}
+
+// TransferResponse is the presigned URL the peer transfers the object with
+type TransferResponse struct {
+	URL string `json:"url"`
+	// The HTTP method of the transfer, PUT to upload and GET to download
+	Method string `json:"method"`
+	// The key of the object in the object store
+	Key string `json:"key"`
+	// When the URL expires, at most when the lease ends
+	ExpireTime time.Time `json:"expireTime"`
+}
@@ -0,0 +1,109 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jumpstarter-dev/jumpstarter-controller/pkg/api"
+)
+
+// ExporterLabels are the labels of an exporter, the history of its jumpstarter.dev/ labels and the
+// revision they are pinned to, the methods of the admin service require the client to authenticate
+// with the Kubernetes token of a user allowed to get or patch the exporter
+type ExporterLabels = api.ExporterLabelsResponse
+
+// ListExporterLabelHistory returns the label history of the exporter named name in namespace
+func (c *Client) ListExporterLabelHistory(ctx context.Context, namespace string, name string) (*ExporterLabels, error) {
+	var response ExporterLabels
+	if err := c.invokeAdminService(ctx, "ListExporterLabelHistory", api.ExporterRequest{
+		Namespace: namespace,
+		Exporter:  name,
+	}, &response); err != nil {
+		return nil, fmt.Errorf("ListExporterLabelHistory: %w", err)
+	}
+	return &response, nil
+}
+
+// RestoreExporterLabels restores the jumpstarter.dev/ labels of the exporter named name in namespace to
+// a revision of its label history, and pins them until UnpinExporterLabels
+func (c *Client) RestoreExporterLabels(
+	ctx context.Context,
+	namespace string,
+	name string,
+	revision int64,
+) (*ExporterLabels, error) {
+	var response ExporterLabels
+	if err := c.invokeAdminService(ctx, "RestoreExporterLabels", api.RestoreExporterLabelsRequest{
+		Namespace: namespace,
+		Exporter:  name,
+		Revision:  revision,
+	}, &response); err != nil {
+		return nil, fmt.Errorf("RestoreExporterLabels: %w", err)
+	}
+	return &response, nil
+}
+
+// UnpinExporterLabels unpins the labels of the exporter named name in namespace, its next registration
+// applies its own labels again
+func (c *Client) UnpinExporterLabels(ctx context.Context, namespace string, name string) (*ExporterLabels, error) {
+	var response ExporterLabels
+	if err := c.invokeAdminService(ctx, "UnpinExporterLabels", api.ExporterRequest{
+		Namespace: namespace,
+		Exporter:  name,
+	}, &response); err != nil {
+		return nil, fmt.Errorf("UnpinExporterLabels: %w", err)
+	}
+	return &response, nil
+}
+
+// ExporterScheduling is whether an exporter is cordoned or drained, and the leases a drain released
+type ExporterScheduling = api.ExporterSchedulingResponse
+
+// CordonExporter marks the exporter named name in namespace as unschedulable, new leases do not acquire it
+func (c *Client) CordonExporter(ctx context.Context, namespace string, name string) (*ExporterScheduling, error) {
+	var response ExporterScheduling
+	if err := c.invokeAdminService(ctx, "CordonExporter", api.ExporterRequest{
+		Namespace: namespace,
+		Exporter:  name,
+	}, &response); err != nil {
+		return nil, fmt.Errorf("CordonExporter: %w", err)
+	}
+	return &response, nil
+}
+
+// UncordonExporter marks the exporter named name in namespace as schedulable again, and removes its drain
+func (c *Client) UncordonExporter(ctx context.Context, namespace string, name string) (*ExporterScheduling, error) {
+	var response ExporterScheduling
+	if err := c.invokeAdminService(ctx, "UncordonExporter", api.ExporterRequest{
+		Namespace: namespace,
+		Exporter:  name,
+	}, &response); err != nil {
+		return nil, fmt.Errorf("UncordonExporter: %w", err)
+	}
+	return &response, nil
+}
+
+// DrainExporter drains the exporter named name in namespace, releasing its active leases if release,
+// it is disconnected once they ended and kept offline until uncordoned
+func (c *Client) DrainExporter(
+	ctx context.Context,
+	namespace string,
+	name string,
+	release bool,
+) (*ExporterScheduling, error) {
+	var response ExporterScheduling
+	if err := c.invokeAdminService(ctx, "DrainExporter", api.DrainExporterRequest{
+		Namespace: namespace,
+		Exporter:  name,
+		Release:   release,
+	}, &response); err != nil {
+		return nil, fmt.Errorf("DrainExporter: %w", err)
+	}
+	return &response, nil
+}
+
+// invokeAdminService calls method of the admin service with the JSON request req, and decodes its
+// JSON response into resp
+func (c *Client) invokeAdminService(ctx context.Context, method string, req any, resp any) error {
+	return c.invokeStruct(ctx, "/"+api.AdminServiceName+"/"+method, req, resp)
+}
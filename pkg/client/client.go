@@ -0,0 +1,206 @@
+// Package client is a Go SDK for the client API of the jumpstarter controller: it requests and
+// releases leases, and dials the leased exporters through the routers, so that Go test harnesses
+// do not have to implement the protocol themselves
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	pb "github.com/jumpstarter-dev/jumpstarter-controller/internal/protocol/jumpstarter/v1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/pkg/api"
+)
+
+// TokenSource returns the token the client authenticates with, it is called again when the
+// last token returned is about to expire or is rejected by the controller
+type TokenSource func(ctx context.Context) (string, error)
+
+// StaticToken returns a TokenSource always returning token
+func StaticToken(token string) TokenSource {
+	return func(context.Context) (string, error) {
+		return token, nil
+	}
+}
+
+// tokenExpiryMargin is how long before its expiration a token is refreshed
+const tokenExpiryMargin = time.Minute
+
+// Options configure a Client
+type Options struct {
+	// Token authenticates the client to the controller
+	Token TokenSource
+	// TLSConfig to connect to the controller and the routers with, the system certificates if nil
+	TLSConfig *tls.Config
+	// Insecure connects to the controller and the routers without TLS, e.g. to a mock service
+	Insecure bool
+	// DialOptions are added to the options of the connections to the controller and the routers
+	DialOptions []grpc.DialOption
+}
+
+// Client of the controller
+type Client struct {
+	conn        *grpc.ClientConn
+	controller  pb.ControllerServiceClient
+	dialOptions []grpc.DialOption
+}
+
+// New returns a client of the controller at endpoint, the connection is established lazily
+func New(endpoint string, opts Options) (*Client, error) {
+	if opts.Token == nil {
+		return nil, fmt.Errorf("New: no token source")
+	}
+
+	creds := credentials.NewTLS(opts.TLSConfig)
+	if opts.Insecure {
+		creds = insecure.NewCredentials()
+	}
+	dialOptions := append([]grpc.DialOption{grpc.WithTransportCredentials(creds)}, opts.DialOptions...)
+
+	tokens := &tokenCredentials{source: opts.Token, insecure: opts.Insecure}
+	conn, err := grpc.NewClient(endpoint, append([]grpc.DialOption{
+		grpc.WithPerRPCCredentials(tokens),
+		grpc.WithChainUnaryInterceptor(tokens.unaryInterceptor),
+	}, dialOptions...)...)
+	if err != nil {
+		return nil, fmt.Errorf("New: %w", err)
+	}
+
+	return &Client{
+		conn:        conn,
+		controller:  pb.NewControllerServiceClient(conn),
+		dialOptions: dialOptions,
+	}, nil
+}
+
+// Close closes the connection to the controller, the streams already dialed stay open
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// invokeStruct calls the method taking and returning a google.protobuf.Struct with the JSON
+// request req, and decodes its JSON response into resp
+func (c *Client) invokeStruct(ctx context.Context, method string, req any, resp any) error {
+	content, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(content, &fields); err != nil {
+		return err
+	}
+	in, err := structpb.NewStruct(fields)
+	if err != nil {
+		return err
+	}
+
+	out := new(structpb.Struct)
+	if err := c.conn.Invoke(ctx, method, in, out); err != nil {
+		return err
+	}
+
+	content, err = json.Marshal(out.AsMap())
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(content, resp); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
+
+// tokenCredentials authenticates the calls to the controller with the token of a TokenSource,
+// cached until it is about to expire
+type tokenCredentials struct {
+	source   TokenSource
+	insecure bool
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+func (t *tokenCredentials) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	token, err := t.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"authorization": "Bearer " + token}, nil
+}
+
+func (t *tokenCredentials) RequireTransportSecurity() bool {
+	return !t.insecure
+}
+
+func (t *tokenCredentials) get(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token != "" && (t.expiry.IsZero() || time.Until(t.expiry) > tokenExpiryMargin) {
+		return t.token, nil
+	}
+
+	token, err := t.source(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get token: %w", err)
+	}
+	t.token, t.expiry = token, tokenExpiry(token)
+	return token, nil
+}
+
+// invalidate forgets the cached token, the next call gets a new one from the source
+func (t *tokenCredentials) invalidate() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.token = ""
+}
+
+// unaryInterceptor retries the calls rejected as unauthenticated once, with a new token
+func (t *tokenCredentials) unaryInterceptor(
+	ctx context.Context,
+	method string,
+	req, reply any,
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption,
+) error {
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	if status.Code(err) != codes.Unauthenticated {
+		return err
+	}
+	t.invalidate()
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// tokenExpiry returns the expiration time of token if it is a JWT with one, the zero time otherwise
+func tokenExpiry(token string) time.Time {
+	var claims jwt.RegisteredClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(token, &claims); err != nil || claims.ExpiresAt == nil {
+		return time.Time{}
+	}
+	return claims.ExpiresAt.Time
+}
+
+// ServerInfo is the version and the enabled features of the controller
+type ServerInfo = api.ServerInfo
+
+// GetServerInfo returns the version and the enabled features of the controller
+func (c *Client) GetServerInfo(ctx context.Context) (*ServerInfo, error) {
+	var info ServerInfo
+	if err := c.invokeClientService(ctx, "GetServerInfo", api.ServerInfoRequest{}, &info); err != nil {
+		return nil, fmt.Errorf("GetServerInfo: %w", err)
+	}
+	return &info, nil
+}
@@ -0,0 +1,37 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	pb "github.com/jumpstarter-dev/jumpstarter-controller/internal/protocol/jumpstarter/v1"
+)
+
+// Exporter as seen by clients
+type Exporter struct {
+	UUID   string
+	Labels map[string]string
+}
+
+// ListExporters returns the exporters visible to the client with all of labels
+func (c *Client) ListExporters(ctx context.Context, labels map[string]string) ([]Exporter, error) {
+	resp, err := c.controller.ListExporters(ctx, &pb.ListExportersRequest{Labels: labels})
+	if err != nil {
+		return nil, fmt.Errorf("ListExporters: %w", err)
+	}
+
+	exporters := make([]Exporter, 0, len(resp.Exporters))
+	for _, exporter := range resp.Exporters {
+		exporters = append(exporters, Exporter{UUID: exporter.Uuid, Labels: exporter.Labels})
+	}
+	return exporters, nil
+}
+
+// GetExporter returns the exporter with uuid
+func (c *Client) GetExporter(ctx context.Context, uuid string) (*Exporter, error) {
+	resp, err := c.controller.GetExporter(ctx, &pb.GetExporterRequest{Uuid: uuid})
+	if err != nil {
+		return nil, fmt.Errorf("GetExporter: %w", err)
+	}
+	return &Exporter{UUID: resp.GetExporter().GetUuid(), Labels: resp.GetExporter().GetLabels()}, nil
+}
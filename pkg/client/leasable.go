@@ -0,0 +1,46 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jumpstarter-dev/jumpstarter-controller/pkg/api"
+)
+
+// LeasableExporter is an exporter the client may lease and the policy its leases are granted by
+type LeasableExporter = api.LeasableExporter
+
+// ResolvedExporter is an exporter a lease of the client could acquire and its availability
+type ResolvedExporter = api.ResolvedExporter
+
+// ListLeasableExporters returns the exporters matching the label selector the client may lease,
+// every exporter of its namespace if empty
+func (c *Client) ListLeasableExporters(ctx context.Context, selector string) ([]LeasableExporter, error) {
+	var response api.LeasableExportersResponse
+	if err := c.invokeClientService(ctx, "ListLeasableExporters", api.LeasableExportersRequest{
+		Selector: selector,
+	}, &response); err != nil {
+		return nil, fmt.Errorf("ListLeasableExporters: %w", err)
+	}
+	return response.Exporters, nil
+}
+
+// ResolveSelector returns the exporters a lease of the client with the label selector, defaulted
+// from the LeaseTemplate named template if not empty, could acquire, with their availability and
+// the constraints of the access policies on the leases of the client
+func (c *Client) ResolveSelector(ctx context.Context, selector string, template string) ([]ResolvedExporter, error) {
+	var response api.ResolveSelectorResponse
+	if err := c.invokeClientService(ctx, "ResolveSelector", api.ResolveSelectorRequest{
+		Selector: selector,
+		Template: template,
+	}, &response); err != nil {
+		return nil, fmt.Errorf("ResolveSelector: %w", err)
+	}
+	return response.Exporters, nil
+}
+
+// invokeClientService calls method of the client service with the JSON request req, and decodes
+// its JSON response into resp
+func (c *Client) invokeClientService(ctx context.Context, method string, req any, resp any) error {
+	return c.invokeStruct(ctx, "/"+api.ClientServiceName+"/"+method, req, resp)
+}
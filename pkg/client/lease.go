@@ -0,0 +1,317 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	jumpstarterdevv1alpha1 "github.com/jumpstarter-dev/jumpstarter-controller/api/v1alpha1"
+	pb "github.com/jumpstarter-dev/jumpstarter-controller/internal/protocol/jumpstarter/v1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/pkg/api"
+)
+
+// LeasePollInterval is how often RequestAndWaitLease polls the lease it waits for
+const LeasePollInterval = time.Second
+
+// LeaseRequest describes the lease requested by RequestLease
+type LeaseRequest struct {
+	// Selector of the exporters the lease may acquire
+	Selector metav1.LabelSelector
+	// Duration of the lease, once it acquires an exporter
+	Duration time.Duration
+	// PriorityClassName names the LeasePriorityClass of the lease, if any
+	PriorityClassName string
+	// FailIfUnsatisfiable fails the request right away if no exporter could ever satisfy it,
+	// instead of creating a lease that would stay pending
+	FailIfUnsatisfiable bool
+	// Shared lets the clients allowed to lease the exporter observe the streams of the lease
+	Shared bool
+	// ClampDuration shortens Duration to the maximum duration of the ExporterAccessPolicies of the
+	// matching exporters, instead of failing the request when it exceeds it
+	ClampDuration bool
+}
+
+// Lease as seen by its client
+type Lease struct {
+	Name       string
+	Selector   metav1.LabelSelector
+	Duration   time.Duration
+	BeginTime  *time.Time
+	EndTime    *time.Time
+	Conditions []metav1.Condition
+	// ExporterUUID is the exporter acquired by the lease, empty while it is pending
+	ExporterUUID string
+	// QueuePosition and EstimatedBeginTime are reported while the lease is pending
+	QueuePosition      *int
+	EstimatedBeginTime *time.Time
+}
+
+// Ended reports whether the lease has ended
+func (l *Lease) Ended() bool {
+	return l.EndTime != nil
+}
+
+// RequestLease requests a lease and returns its name, without waiting for it to acquire an exporter
+func (c *Client) RequestLease(ctx context.Context, req LeaseRequest) (string, error) {
+	resp, err := c.requestLease(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("RequestLease: %w", err)
+	}
+	return resp.Name, nil
+}
+
+// LeaseCheck is whether a lease would acquire an exporter right away, be queued, or be
+// unsatisfiable, and the duration it would have
+type LeaseCheck = api.CheckLeaseResponse
+
+// CheckLease returns whether the lease requested by req would acquire an exporter right away,
+// be queued, or be unsatisfiable, without creating it
+func (c *Client) CheckLease(ctx context.Context, req LeaseRequest) (*LeaseCheck, error) {
+	selector, err := metav1.LabelSelectorAsSelector(&req.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("CheckLease: %w", err)
+	}
+
+	var check LeaseCheck
+	if err := c.invokeClientService(ctx, "CheckLease", api.CheckLeaseRequest{
+		Selector:          selector.String(),
+		Duration:          metav1.Duration{Duration: req.Duration},
+		PriorityClassName: req.PriorityClassName,
+		Shared:            req.Shared,
+		ClampDuration:     req.ClampDuration,
+	}, &check); err != nil {
+		return nil, fmt.Errorf("CheckLease: %w", err)
+	}
+	return &check, nil
+}
+
+func (c *Client) requestLease(ctx context.Context, req LeaseRequest) (*pb.RequestLeaseResponse, error) {
+	var matchExpressions []*pb.LabelSelectorRequirement
+	for _, exp := range req.Selector.MatchExpressions {
+		matchExpressions = append(matchExpressions, &pb.LabelSelectorRequirement{
+			Key:      exp.Key,
+			Operator: string(exp.Operator),
+			Values:   exp.Values,
+		})
+	}
+
+	if req.PriorityClassName != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, api.PriorityClassHeader, req.PriorityClassName)
+	}
+	if req.FailIfUnsatisfiable {
+		ctx = metadata.AppendToOutgoingContext(ctx, api.FailIfUnsatisfiableHeader, "true")
+	}
+	if req.Shared {
+		ctx = metadata.AppendToOutgoingContext(ctx, api.SharedLeaseHeader, "true")
+	}
+	if req.ClampDuration {
+		ctx = metadata.AppendToOutgoingContext(ctx, api.ClampDurationHeader, "true")
+	}
+
+	return c.controller.RequestLease(ctx, &pb.RequestLeaseRequest{
+		Duration: durationpb.New(req.Duration),
+		Selector: &pb.LabelSelector{MatchExpressions: matchExpressions, MatchLabels: req.Selector.MatchLabels},
+	})
+}
+
+// GetLease returns the lease named name
+func (c *Client) GetLease(ctx context.Context, name string) (*Lease, error) {
+	var header metadata.MD
+	resp, err := c.controller.GetLease(ctx, &pb.GetLeaseRequest{Name: name}, grpc.Header(&header))
+	if err != nil {
+		return nil, fmt.Errorf("GetLease: %w", err)
+	}
+
+	lease := &Lease{
+		Name:         name,
+		Duration:     resp.GetDuration().AsDuration(),
+		ExporterUUID: resp.GetExporterUuid(),
+	}
+	if selector := resp.GetSelector(); selector != nil {
+		lease.Selector.MatchLabels = selector.MatchLabels
+		for _, exp := range selector.MatchExpressions {
+			lease.Selector.MatchExpressions = append(lease.Selector.MatchExpressions, metav1.LabelSelectorRequirement{
+				Key:      exp.Key,
+				Operator: metav1.LabelSelectorOperator(exp.Operator),
+				Values:   exp.Values,
+			})
+		}
+	}
+	if resp.BeginTime != nil {
+		beginTime := resp.BeginTime.AsTime()
+		lease.BeginTime = &beginTime
+	}
+	if resp.EndTime != nil {
+		endTime := resp.EndTime.AsTime()
+		lease.EndTime = &endTime
+	}
+	for _, condition := range resp.Conditions {
+		lease.Conditions = append(lease.Conditions, metav1.Condition{
+			Type:               condition.GetType(),
+			Status:             metav1.ConditionStatus(condition.GetStatus()),
+			ObservedGeneration: condition.GetObservedGeneration(),
+			LastTransitionTime: metav1.Unix(
+				condition.GetLastTransitionTime().GetSeconds(),
+				int64(condition.GetLastTransitionTime().GetNanos()),
+			),
+			Reason:  condition.GetReason(),
+			Message: condition.GetMessage(),
+		})
+	}
+
+	if values := header.Get(api.QueuePositionHeader); len(values) == 1 {
+		if position, err := strconv.Atoi(values[0]); err == nil {
+			lease.QueuePosition = &position
+		}
+	}
+	if values := header.Get(api.EstimatedBeginTimeHeader); len(values) == 1 {
+		if estimate, err := time.Parse(time.RFC3339, values[0]); err == nil {
+			lease.EstimatedBeginTime = &estimate
+		}
+	}
+	return lease, nil
+}
+
+// ReleaseLease releases the lease named name
+func (c *Client) ReleaseLease(ctx context.Context, name string) error {
+	if _, err := c.controller.ReleaseLease(ctx, &pb.ReleaseLeaseRequest{Name: name}); err != nil {
+		return fmt.Errorf("ReleaseLease: %w", err)
+	}
+	return nil
+}
+
+// PauseLease pauses the countdown of the duration of the running lease named name, it keeps
+// its exporters until it is resumed or reaches the maximum pause of its policies
+func (c *Client) PauseLease(ctx context.Context, name string) error {
+	var response api.PauseLeaseResponse
+	if err := c.invokeClientService(ctx, "PauseLease", api.PauseLeaseRequest{Lease: name}, &response); err != nil {
+		return fmt.Errorf("PauseLease: %w", err)
+	}
+	return nil
+}
+
+// ResumeLease resumes the countdown of the paused lease named name
+func (c *Client) ResumeLease(ctx context.Context, name string) error {
+	var response api.PauseLeaseResponse
+	if err := c.invokeClientService(ctx, "ResumeLease", api.PauseLeaseRequest{Lease: name}, &response); err != nil {
+		return fmt.Errorf("ResumeLease: %w", err)
+	}
+	return nil
+}
+
+// ExtendLease sets the duration of the running lease named name to duration, counted from its
+// begin time, if its access policies allow it and its exporters are not needed before it ends
+func (c *Client) ExtendLease(ctx context.Context, name string, duration time.Duration) error {
+	var response api.ExtendLeaseResponse
+	if err := c.invokeClientService(ctx, "ExtendLease", api.ExtendLeaseRequest{
+		Lease:    name,
+		Duration: metav1.Duration{Duration: duration},
+	}, &response); err != nil {
+		return fmt.Errorf("ExtendLease: %w", err)
+	}
+	return nil
+}
+
+// ListLeaseMetadata returns the metadata of the lease named name
+func (c *Client) ListLeaseMetadata(ctx context.Context, name string) (map[string]string, error) {
+	var response api.LeaseMetadataResponse
+	err := c.invokeClientService(ctx, "ListLeaseMetadata", api.LeaseMetadataRequest{Lease: name}, &response)
+	if err != nil {
+		return nil, fmt.Errorf("ListLeaseMetadata: %w", err)
+	}
+	return response.Metadata, nil
+}
+
+// GetLeaseMetadata returns the value of the metadata key of the lease named name
+func (c *Client) GetLeaseMetadata(ctx context.Context, name string, key string) (string, error) {
+	var response api.LeaseMetadataResponse
+	if err := c.invokeClientService(ctx, "GetLeaseMetadata", api.LeaseMetadataRequest{
+		Lease: name,
+		Key:   key,
+	}, &response); err != nil {
+		return "", fmt.Errorf("GetLeaseMetadata: %w", err)
+	}
+	return response.Metadata[key], nil
+}
+
+// SetLeaseMetadata sets the metadata key of the active lease named name to value
+func (c *Client) SetLeaseMetadata(ctx context.Context, name string, key string, value string) error {
+	var response api.LeaseMetadataResponse
+	if err := c.invokeClientService(ctx, "SetLeaseMetadata", api.LeaseMetadataRequest{
+		Lease: name,
+		Key:   key,
+		Value: value,
+	}, &response); err != nil {
+		return fmt.Errorf("SetLeaseMetadata: %w", err)
+	}
+	return nil
+}
+
+// DeleteLeaseMetadata deletes the metadata key of the active lease named name
+func (c *Client) DeleteLeaseMetadata(ctx context.Context, name string, key string) error {
+	var response api.LeaseMetadataResponse
+	if err := c.invokeClientService(ctx, "DeleteLeaseMetadata", api.LeaseMetadataRequest{
+		Lease: name,
+		Key:   key,
+	}, &response); err != nil {
+		return fmt.Errorf("DeleteLeaseMetadata: %w", err)
+	}
+	return nil
+}
+
+// ListLeases returns the names of the leases of the client
+func (c *Client) ListLeases(ctx context.Context) ([]string, error) {
+	resp, err := c.controller.ListLeases(ctx, &pb.ListLeasesRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("ListLeases: %w", err)
+	}
+	return resp.Names, nil
+}
+
+// RequestAndWaitLease requests a lease and waits for it to acquire an exporter. The lease is
+// released if it cannot be acquired, e.g. because it is unsatisfiable or ctx is done first
+func (c *Client) RequestAndWaitLease(ctx context.Context, req LeaseRequest) (*Lease, error) {
+	name, err := c.RequestLease(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("RequestAndWaitLease: %w", err)
+	}
+
+	var lease *Lease
+	err = wait.PollUntilContextCancel(ctx, LeasePollInterval, true, func(ctx context.Context) (bool, error) {
+		lease, err = c.GetLease(ctx, name)
+		if err != nil {
+			return false, err
+		}
+		for _, conditionType := range []jumpstarterdevv1alpha1.LeaseConditionType{
+			jumpstarterdevv1alpha1.LeaseConditionTypeUnsatisfiable,
+			jumpstarterdevv1alpha1.LeaseConditionTypeFailed,
+		} {
+			if condition := meta.FindStatusCondition(lease.Conditions, string(conditionType)); condition != nil &&
+				condition.Status == metav1.ConditionTrue {
+				return false, fmt.Errorf("lease %s is %s: %s", name, conditionType, condition.Message)
+			}
+		}
+		if lease.Ended() {
+			return false, fmt.Errorf("lease %s ended before acquiring an exporter", name)
+		}
+		return lease.ExporterUUID != "", nil
+	})
+	if err != nil {
+		// the lease is released whatever the reason, past the deadline of ctx
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+		if releaseErr := c.ReleaseLease(ctx, name); releaseErr != nil {
+			return nil, fmt.Errorf("RequestAndWaitLease: %w, and %w", err, releaseErr)
+		}
+		return nil, fmt.Errorf("RequestAndWaitLease: %w", err)
+	}
+	return lease, nil
+}
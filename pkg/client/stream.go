@@ -0,0 +1,97 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	pb "github.com/jumpstarter-dev/jumpstarter-controller/internal/protocol/jumpstarter/v1"
+	"github.com/jumpstarter-dev/jumpstarter-controller/pkg/api"
+)
+
+// DialStream dials the exporter of the lease named lease through its router, and returns the
+// stream to it as a net.Conn. The stream outlives ctx, it lasts until either side closes it
+func (c *Client) DialStream(ctx context.Context, lease string) (net.Conn, error) {
+	dial, err := c.controller.Dial(ctx, &pb.DialRequest{LeaseName: lease})
+	if err != nil {
+		return nil, fmt.Errorf("DialStream: failed to dial: %w", err)
+	}
+
+	routerConn, err := grpc.NewClient(dial.RouterEndpoint, c.dialOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("DialStream: %w", err)
+	}
+
+	streamCtx, cancel := context.WithCancel(context.Background())
+	stream, err := pb.NewRouterServiceClient(routerConn).Stream(metadata.AppendToOutgoingContext(streamCtx,
+		"authorization", "Bearer "+dial.RouterToken,
+		api.PeerHeader, api.PeerClient,
+	))
+	if err != nil {
+		cancel()
+		routerConn.Close()
+		return nil, fmt.Errorf("DialStream: failed to open stream: %w", err)
+	}
+
+	local, remote := net.Pipe()
+	go func() {
+		defer routerConn.Close()
+		defer cancel()
+		defer remote.Close()
+		_ = forward(stream, remote)
+	}()
+	return local, nil
+}
+
+// forward copies the frames of stream to conn and back until either side ends
+func forward(stream pb.RouterService_StreamClient, conn net.Conn) error {
+	errs := make(chan error, 2)
+	go func() {
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := conn.Read(buf)
+			if n > 0 {
+				if err := stream.Send(&pb.StreamRequest{Payload: append([]byte(nil), buf[:n]...)}); err != nil {
+					errs <- err
+					return
+				}
+			}
+			if err != nil {
+				errs <- errors.Join(stream.CloseSend(), ignoreEOF(err))
+				return
+			}
+		}
+	}()
+	go func() {
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				errs <- ignoreEOF(err)
+				return
+			}
+			switch msg.GetFrameType() {
+			case pb.FrameType_FRAME_TYPE_DATA:
+				if _, err := conn.Write(msg.GetPayload()); err != nil {
+					errs <- err
+					return
+				}
+			case pb.FrameType_FRAME_TYPE_RST_STREAM, pb.FrameType_FRAME_TYPE_GOAWAY:
+				errs <- nil
+				return
+			}
+		}
+	}()
+	return <-errs
+}
+
+func ignoreEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
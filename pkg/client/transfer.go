@@ -0,0 +1,31 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jumpstarter-dev/jumpstarter-controller/pkg/api"
+)
+
+// Transfer is a presigned URL to upload or download an object of a lease with
+type Transfer = api.TransferResponse
+
+// NegotiateTransfer returns the presigned URL to transfer object of the lease through the object
+// store of its namespace, instead of streaming it through the routers
+func (c *Client) NegotiateTransfer(
+	ctx context.Context,
+	lease string,
+	direction api.TransferDirection,
+	object string,
+) (*Transfer, error) {
+	var transfer Transfer
+	if err := c.invokeStruct(ctx, "/"+api.TransferServiceName+"/NegotiateTransfer", api.TransferRequest{
+		Lease:     lease,
+		Direction: direction,
+		Object:    object,
+		Peer:      api.TransferPeerClient,
+	}, &transfer); err != nil {
+		return nil, fmt.Errorf("NegotiateTransfer: %w", err)
+	}
+	return &transfer, nil
+}
@@ -0,0 +1,166 @@
+// Package interceptors adds gRPC server interceptors to the controller service from a configuration
+// file, the interceptors being registered by name, so that downstream builds add theirs from an init
+// function without patching the controller
+package interceptors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"sigs.k8s.io/yaml"
+)
+
+// Interceptor is a pair of gRPC server interceptors added to the controller service, either may be nil
+type Interceptor struct {
+	Unary  grpc.UnaryServerInterceptor
+	Stream grpc.StreamServerInterceptor
+}
+
+// Factory builds an Interceptor from the config of its Declaration in a Config, nil if it has none
+type Factory func(config json.RawMessage) (Interceptor, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = map[string]Factory{}
+)
+
+// Register makes the interceptors built by factory available to the Config as name, it panics if
+// name is already registered
+func Register(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	if _, ok := factories[name]; ok {
+		panic(fmt.Sprintf("Register: interceptor %s registered twice", name))
+	}
+	factories[name] = factory
+}
+
+// Registered returns the sorted names of the registered interceptors
+func Registered() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Declaration declares an interceptor of a Config
+type Declaration struct {
+	// The name the interceptor is registered as, e.g. audit, rate-limit or tenant-header
+	Name string `json:"name"`
+	// The configuration of the interceptor, specific to it
+	Config json.RawMessage `json:"config,omitempty"`
+}
+
+// Config is the configuration file of the interceptors added to the controller service
+type Config struct {
+	// The interceptors, in the order they run, after the ones of the controller recording the listener
+	// of the calls and rejecting the calls without credentials
+	Interceptors []Declaration `json:"interceptors"`
+}
+
+// Load reads a Config from path and builds its interceptors
+func Load(path string) ([]Interceptor, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Load: %w", err)
+	}
+	var config Config
+	if err := yaml.UnmarshalStrict(content, &config); err != nil {
+		return nil, fmt.Errorf("Load: invalid configuration: %w", err)
+	}
+	return New(config)
+}
+
+// New builds the interceptors declared by config, in order
+func New(config Config) ([]Interceptor, error) {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	interceptors := make([]Interceptor, 0, len(config.Interceptors))
+	for i, declared := range config.Interceptors {
+		factory, ok := factories[declared.Name]
+		if !ok {
+			return nil, fmt.Errorf("New: interceptor %d: unknown interceptor %q", i, declared.Name)
+		}
+		interceptor, err := factory(declared.Config)
+		if err != nil {
+			return nil, fmt.Errorf("New: interceptor %d (%s): %w", i, declared.Name, err)
+		}
+		interceptors = append(interceptors, interceptor)
+	}
+	return interceptors, nil
+}
+
+// DecodeConfig decodes the config of an interceptor into v, rejecting unknown fields
+func DecodeConfig(config json.RawMessage, v any) error {
+	if len(config) == 0 {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(config))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	return nil
+}
+
+// ServerOptions chains interceptors, in order
+func ServerOptions(interceptors []Interceptor) []grpc.ServerOption {
+	var unary []grpc.UnaryServerInterceptor
+	var stream []grpc.StreamServerInterceptor
+	for _, interceptor := range interceptors {
+		if interceptor.Unary != nil {
+			unary = append(unary, interceptor.Unary)
+		}
+		if interceptor.Stream != nil {
+			stream = append(stream, interceptor.Stream)
+		}
+	}
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	}
+}
+
+// ValidateMethodPattern checks method is a full method name or every method of a service
+func ValidateMethodPattern(method string) error {
+	service, name, found := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	if !strings.HasPrefix(method, "/") || !found || service == "" || name == "" || strings.Contains(name, "/") {
+		return fmt.Errorf("invalid method %q, expected /SERVICE/METHOD or /SERVICE/*", method)
+	}
+	return nil
+}
+
+// MatchesMethod reports whether the method fullMethod matches any of the method patterns
+func MatchesMethod(patterns []string, fullMethod string) bool {
+	for _, method := range patterns {
+		if method == fullMethod {
+			return true
+		}
+		if service, ok := strings.CutSuffix(method, "*"); ok && strings.HasPrefix(fullMethod, service) {
+			return true
+		}
+	}
+	return false
+}
+
+// contextServerStream replaces the context of a ServerStream
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextServerStream) Context() context.Context {
+	return s.ctx
+}
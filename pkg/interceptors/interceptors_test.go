@@ -0,0 +1,172 @@
+package interceptors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+)
+
+// orderConfig is the config of the order interceptor
+type orderConfig struct {
+	// The name the interceptor appends to the calls it intercepts
+	Mark string `json:"mark"`
+}
+
+// callMarks are the marks of the order interceptors a call went through, in order
+type callMarks struct{}
+
+func init() {
+	Register("test-order", func(raw json.RawMessage) (Interceptor, error) {
+		var config orderConfig
+		if err := DecodeConfig(raw, &config); err != nil {
+			return Interceptor{}, err
+		}
+		if config.Mark == "" {
+			return Interceptor{}, fmt.Errorf("mark is required")
+		}
+		return Interceptor{
+			Unary: func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+				marks, _ := ctx.Value(callMarks{}).([]string)
+				return handler(context.WithValue(ctx, callMarks{}, append(marks, config.Mark)), req)
+			},
+		}, nil
+	})
+}
+
+// writeConfig writes the interceptors configuration file content and returns its path
+func writeConfig(content string) string {
+	path := filepath.Join(GinkgoT().TempDir(), "interceptors.yaml")
+	Expect(os.WriteFile(path, []byte(content), 0o600)).To(Succeed())
+	return path
+}
+
+// marks returns the marks of the order interceptors a unary call goes through
+func marks(interceptors []Interceptor) []string {
+	handler := func(ctx context.Context, _ any) (any, error) {
+		marks, _ := ctx.Value(callMarks{}).([]string)
+		return marks, nil
+	}
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		unary, next := interceptors[i].Unary, handler
+		handler = func(ctx context.Context, req any) (any, error) {
+			return unary(ctx, req, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Call"}, next)
+		}
+	}
+	resp, err := handler(context.Background(), nil)
+	Expect(err).NotTo(HaveOccurred())
+	return resp.([]string)
+}
+
+var _ = Describe("Load", func() {
+	It("should build the interceptors in the order of the configuration", func() {
+		interceptors, err := Load(writeConfig(`
+interceptors:
+- name: test-order
+  config:
+    mark: first
+- name: test-order
+  config:
+    mark: second
+- name: audit
+`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(interceptors).To(HaveLen(3))
+		Expect(marks(interceptors[:2])).To(Equal([]string{"first", "second"}))
+	})
+
+	It("should build no interceptors from an empty configuration", func() {
+		interceptors, err := Load(writeConfig("interceptors: []\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(interceptors).To(BeEmpty())
+	})
+
+	DescribeTable("should reject invalid configurations",
+		func(content string, message string) {
+			_, err := Load(writeConfig(content))
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("unknown field", "interceptors: []\nextra: true\n", "invalid configuration"),
+		Entry("unknown interceptor", "interceptors:\n- name: unknown\n", `interceptor 0: unknown interceptor "unknown"`),
+		Entry("unknown config field", "interceptors:\n- name: test-order\n  config:\n    mark: a\n    extra: b\n",
+			"interceptor 0 (test-order): invalid config"),
+		Entry("invalid config", "interceptors:\n- name: audit\n- name: test-order\n",
+			"interceptor 1 (test-order): mark is required"),
+		Entry("invalid builtin config", "interceptors:\n- name: rate-limit\n  config:\n    requestsPerSecond: 0\n",
+			"requestsPerSecond must be positive"),
+	)
+
+	It("should fail on a missing file", func() {
+		_, err := Load(filepath.Join(GinkgoT().TempDir(), "missing.yaml"))
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Registered", func() {
+	It("should list the builtin interceptors", func() {
+		Expect(Registered()).To(ContainElements("audit", "rate-limit", "tenant-header"))
+	})
+
+	It("should panic on interceptors registered twice", func() {
+		Expect(func() { Register("audit", newAuditInterceptor) }).To(Panic())
+	})
+})
+
+var _ = Describe("peerLimiters", func() {
+	var limiters *peerLimiters
+	var now time.Time
+
+	BeforeEach(func() {
+		now = time.Now()
+		limiters = &peerLimiters{
+			limit:    rate.Limit(1),
+			burst:    1,
+			limiters: map[string]*peerLimiter{},
+			pruned:   now,
+		}
+	})
+
+	It("should limit each peer address on its own", func() {
+		Expect(limiters.allow("10.0.0.1", now)).To(BeTrue())
+		Expect(limiters.allow("10.0.0.1", now)).To(BeFalse())
+		Expect(limiters.allow("10.0.0.2", now)).To(BeTrue())
+		Expect(limiters.allow("10.0.0.1", now.Add(time.Second))).To(BeTrue())
+	})
+
+	It("should drop the limiters idle for longer than peerLimiterIdle", func() {
+		Expect(limiters.allow("10.0.0.1", now)).To(BeTrue())
+		Expect(limiters.allow("10.0.0.2", now.Add(peerLimiterIdle/2))).To(BeTrue())
+
+		later := now.Add(peerLimiterIdle + time.Second)
+		Expect(limiters.allow("10.0.0.3", later)).To(BeTrue())
+		Expect(limiters.limiters).NotTo(HaveKey("10.0.0.1"))
+		Expect(limiters.limiters).To(HaveKey("10.0.0.2"))
+		Expect(limiters.limiters).To(HaveKey("10.0.0.3"))
+		Expect(limiters.pruned).To(Equal(later))
+	})
+
+	It("should not prune before peerLimiterIdle since the last pruning", func() {
+		Expect(limiters.allow("10.0.0.1", now)).To(BeTrue())
+
+		// idle for long enough, but pruned less than peerLimiterIdle ago
+		limiters.pruned = now.Add(peerLimiterIdle)
+		Expect(limiters.allow("10.0.0.2", now.Add(peerLimiterIdle+time.Second))).To(BeTrue())
+		Expect(limiters.limiters).To(HaveKey("10.0.0.1"))
+	})
+
+	It("should start over the limit of a pruned peer address", func() {
+		Expect(limiters.allow("10.0.0.1", now)).To(BeTrue())
+		Expect(limiters.allow("10.0.0.1", now)).To(BeFalse())
+
+		later := now.Add(peerLimiterIdle + time.Second)
+		Expect(limiters.allow("10.0.0.1", later)).To(BeTrue())
+		Expect(limiters.limiters).To(HaveLen(1))
+	})
+})
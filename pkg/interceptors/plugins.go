@@ -0,0 +1,223 @@
+package interceptors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func init() {
+	Register("audit", newAuditInterceptor)
+	Register("rate-limit", newRateLimitInterceptor)
+	Register("tenant-header", newTenantHeaderInterceptor)
+}
+
+// validateMethodPatterns checks each of methods is a full method name or every method of a service
+func validateMethodPatterns(methods []string) error {
+	for _, method := range methods {
+		if err := ValidateMethodPattern(method); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// peerHost returns the host of the address of the peer of ctx, "" if unknown
+func peerHost(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// auditConfig is the config of the audit interceptor
+type auditConfig struct {
+	// The methods not audited, /SERVICE/METHOD or /SERVICE/*, e.g. /grpc.health.v1.Health/*
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// newAuditInterceptor logs every call with its peer, outcome and duration
+func newAuditInterceptor(raw json.RawMessage) (Interceptor, error) {
+	var config auditConfig
+	if err := DecodeConfig(raw, &config); err != nil {
+		return Interceptor{}, err
+	}
+	if err := validateMethodPatterns(config.Exclude); err != nil {
+		return Interceptor{}, err
+	}
+
+	logger := log.Log.WithName("audit")
+	audit := func(ctx context.Context, fullMethod string, start time.Time, err error) {
+		logger.Info("call", "method", fullMethod, "peer", peerHost(ctx),
+			"code", status.Code(err).String(), "duration", time.Since(start))
+	}
+	return Interceptor{
+		Unary: func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if MatchesMethod(config.Exclude, info.FullMethod) {
+				return handler(ctx, req)
+			}
+			start := time.Now()
+			resp, err := handler(ctx, req)
+			audit(ctx, info.FullMethod, start, err)
+			return resp, err
+		},
+		Stream: func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if MatchesMethod(config.Exclude, info.FullMethod) {
+				return handler(srv, ss)
+			}
+			start := time.Now()
+			err := handler(srv, ss)
+			audit(ss.Context(), info.FullMethod, start, err)
+			return err
+		},
+	}, nil
+}
+
+// rateLimitConfig is the config of the rate-limit interceptor
+type rateLimitConfig struct {
+	// The sustained calls per second of each peer address, streams counting once
+	RequestsPerSecond float64 `json:"requestsPerSecond"`
+	// The calls a peer address may make at once, defaults to RequestsPerSecond rounded up
+	Burst int `json:"burst,omitempty"`
+	// The methods not limited, /SERVICE/METHOD or /SERVICE/*, e.g. /grpc.health.v1.Health/*
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// peerLimiterIdle is how long the limiter of a peer address is kept without calls
+const peerLimiterIdle = 10 * time.Minute
+
+type peerLimiter struct {
+	limiter  *rate.Limiter
+	lastCall time.Time
+}
+
+// peerLimiters are the rate limiters of the peer addresses, the idle ones being dropped
+type peerLimiters struct {
+	mu       sync.Mutex
+	limit    rate.Limit
+	burst    int
+	limiters map[string]*peerLimiter
+	pruned   time.Time
+}
+
+// allow reports whether the peer address host may make a call at now
+func (l *peerLimiters) allow(host string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.pruned) > peerLimiterIdle {
+		for key, limiter := range l.limiters {
+			if now.Sub(limiter.lastCall) > peerLimiterIdle {
+				delete(l.limiters, key)
+			}
+		}
+		l.pruned = now
+	}
+	limiter, ok := l.limiters[host]
+	if !ok {
+		limiter = &peerLimiter{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.limiters[host] = limiter
+	}
+	limiter.lastCall = now
+	return limiter.limiter.AllowN(now, 1)
+}
+
+// newRateLimitInterceptor returns RESOURCE_EXHAUSTED to the peers calling faster than configured
+func newRateLimitInterceptor(raw json.RawMessage) (Interceptor, error) {
+	var config rateLimitConfig
+	if err := DecodeConfig(raw, &config); err != nil {
+		return Interceptor{}, err
+	}
+	if config.RequestsPerSecond <= 0 {
+		return Interceptor{}, fmt.Errorf("requestsPerSecond must be positive")
+	}
+	if config.Burst < 0 {
+		return Interceptor{}, fmt.Errorf("burst must not be negative")
+	}
+	if config.Burst == 0 {
+		config.Burst = int(math.Ceil(config.RequestsPerSecond))
+	}
+	if err := validateMethodPatterns(config.Exclude); err != nil {
+		return Interceptor{}, err
+	}
+
+	limiters := &peerLimiters{
+		limit:    rate.Limit(config.RequestsPerSecond),
+		burst:    config.Burst,
+		limiters: map[string]*peerLimiter{},
+	}
+	check := func(ctx context.Context, fullMethod string) error {
+		if MatchesMethod(config.Exclude, fullMethod) || limiters.allow(peerHost(ctx), time.Now()) {
+			return nil
+		}
+		return status.Errorf(codes.ResourceExhausted, "rate limit of %g calls per second exceeded",
+			config.RequestsPerSecond)
+	}
+	return Interceptor{
+		Unary: func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if err := check(ctx, info.FullMethod); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		},
+		Stream: func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := check(ss.Context(), info.FullMethod); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		},
+	}, nil
+}
+
+// tenantHeaderConfig is the config of the tenant-header interceptor
+type tenantHeaderConfig struct {
+	// The request header set, e.g. x-tenant
+	Header string `json:"header"`
+	// The value of the header, replacing the ones sent by the callers
+	Value string `json:"value"`
+}
+
+// newTenantHeaderInterceptor sets a request header of every call, for the interceptors and the
+// handlers after it, e.g. to tell the tenant served by the controller to downstream interceptors
+func newTenantHeaderInterceptor(raw json.RawMessage) (Interceptor, error) {
+	var config tenantHeaderConfig
+	if err := DecodeConfig(raw, &config); err != nil {
+		return Interceptor{}, err
+	}
+	if config.Header == "" {
+		return Interceptor{}, fmt.Errorf("header is required")
+	}
+	header := strings.ToLower(config.Header)
+
+	inject := func(ctx context.Context) context.Context {
+		md, _ := metadata.FromIncomingContext(ctx)
+		md = md.Copy()
+		md.Set(header, config.Value)
+		return metadata.NewIncomingContext(ctx, md)
+	}
+	return Interceptor{
+		Unary: func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			return handler(inject(ctx), req)
+		},
+		Stream: func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			return handler(srv, &contextServerStream{ServerStream: ss, ctx: inject(ss.Context())})
+		},
+	}, nil
+}